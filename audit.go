@@ -0,0 +1,121 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// Actions of audit events.
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+	AuditActionWrite  = "write"
+)
+
+// AuditEvent records a call of the API that changed a resource or the data of
+// a bucket: who made it, from where, what it changed and when.
+type AuditEvent struct {
+	ID   ID        `json:"id,omitempty"`
+	Time time.Time `json:"time"`
+
+	// AuthorizerKind and AuthorizerID identify the authorization or session
+	// the call was made with, and UserID the user it belongs to. They are
+	// not set for calls that need no authorization, such as the setup.
+	AuthorizerKind string `json:"authorizerKind,omitempty"`
+	AuthorizerID   ID     `json:"authorizerID,omitempty"`
+	UserID         ID     `json:"userID,omitempty"`
+
+	// RemoteAddr is the address the call came from, and ForwardedFor the
+	// addresses listed by proxies in front of the instance, if any.
+	RemoteAddr   string `json:"remoteAddr"`
+	ForwardedFor string `json:"forwardedFor,omitempty"`
+
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+
+	Action       string `json:"action"`
+	ResourceType string `json:"resourceType"`
+	ResourceID   ID     `json:"resourceID,omitempty"`
+	OrgID        ID     `json:"orgID,omitempty"`
+
+	// Changes lists the fields of the resource the call changed. Writes
+	// and deletes of data record Metadata instead, such as the bucket and
+	// the size of the write.
+	Changes  []AuditChange     `json:"changes,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// AuditChange is a change of a field of a resource. Path is the JSON pointer
+// of the field, and Old or New is nil if the field was added or removed.
+type AuditChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// AuditEventFilter represents a set of filters that restrict the returned
+// audit events.
+type AuditEventFilter struct {
+	Action       string
+	ResourceType string
+	ResourceID   *ID
+	UserID       *ID
+	OrgID        *ID
+
+	// Since and Until restrict the events to those recorded at or after
+	// Since and before Until, when they are not zero.
+	Since time.Time
+	Until time.Time
+}
+
+// QueryParams converts AuditEventFilter fields to url query params.
+func (f AuditEventFilter) QueryParams() map[string][]string {
+	qp := map[string][]string{}
+	if f.Action != "" {
+		qp["action"] = []string{f.Action}
+	}
+	if f.ResourceType != "" {
+		qp["resourceType"] = []string{f.ResourceType}
+	}
+	if f.ResourceID != nil {
+		qp["resourceID"] = []string{f.ResourceID.String()}
+	}
+	if f.UserID != nil {
+		qp["userID"] = []string{f.UserID.String()}
+	}
+	if f.OrgID != nil {
+		qp["orgID"] = []string{f.OrgID.String()}
+	}
+	if !f.Since.IsZero() {
+		qp["since"] = []string{f.Since.Format(time.RFC3339Nano)}
+	}
+	if !f.Until.IsZero() {
+		qp["until"] = []string{f.Until.Format(time.RFC3339Nano)}
+	}
+	return qp
+}
+
+// ops for audit events.
+const (
+	OpAppendAuditEvent = "AppendAuditEvent"
+	OpFindAuditEvents  = "FindAuditEvents"
+)
+
+// AuditLogService records audit events and finds them.
+type AuditLogService interface {
+	// AppendAuditEvent records an event, and sets its ID and, if it is
+	// zero, its time.
+	AppendAuditEvent(ctx context.Context, e *AuditEvent) error
+
+	// FindAuditEvents returns the events that match filter, oldest first
+	// unless opt is descending, and the total count of matching events.
+	FindAuditEvents(ctx context.Context, filter AuditEventFilter, opt ...FindOptions) ([]*AuditEvent, int, error)
+}
+
+// DefaultAuditLogFindOptions are the default options for the audit log.
+var DefaultAuditLogFindOptions = FindOptions{
+	Descending: true,
+	Limit:      100,
+}
@@ -0,0 +1,182 @@
+// Package audit compares the states of resources changed by calls of the API,
+// exports the audit log to a bucket and removes old events from it.
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+)
+
+// Redacted replaces the values of sensitive fields in changes.
+const Redacted = "[REDACTED]"
+
+// Diff returns the changes between two JSON documents, the states of a
+// resource before and after a call. Either may be empty if the resource did
+// not exist.
+//
+// Links are ignored, and the values of tokens, passwords and secrets are
+// redacted. Elements of arrays of objects are matched by their id, so that a
+// change of an element is reported at the path of its id rather than its
+// index.
+func Diff(before, after []byte) ([]influxdb.AuditChange, error) {
+	var b, a interface{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &a); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := []influxdb.AuditChange{}
+	diff(&changes, "", b, a, false)
+	return changes, nil
+}
+
+func diff(changes *[]influxdb.AuditChange, path string, before, after interface{}, sensitive bool) {
+	if sensitive {
+		if !reflect.DeepEqual(before, after) {
+			*changes = append(*changes, influxdb.AuditChange{
+				Path: path,
+				Old:  redact(before),
+				New:  redact(after),
+			})
+		}
+		return
+	}
+
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			diffObjects(changes, path, b, a)
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			if bs, ok := byID(b); ok {
+				if as, ok := byID(a); ok {
+					diffElements(changes, path, b, bs, a, as)
+					return
+				}
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, influxdb.AuditChange{
+			Path: path,
+			Old:  scrub(before),
+			New:  scrub(after),
+		})
+	}
+}
+
+func diffObjects(changes *[]influxdb.AuditChange, path string, before, after map[string]interface{}) {
+	keys := make([]string, 0, len(before)+len(after))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if k == "links" {
+			continue
+		}
+		diff(changes, path+"/"+escape(k), before[k], after[k], isSensitive(k))
+	}
+}
+
+func diffElements(changes *[]influxdb.AuditChange, path string, before []interface{}, beforeIDs map[string]interface{}, after []interface{}, afterIDs map[string]interface{}) {
+	for _, e := range before {
+		id := elementID(e)
+		diff(changes, path+"/"+escape(id), e, afterIDs[id], false)
+	}
+	for _, e := range after {
+		id := elementID(e)
+		if _, ok := beforeIDs[id]; !ok {
+			diff(changes, path+"/"+escape(id), nil, e, false)
+		}
+	}
+}
+
+// byID indexes the elements of an array by their id, if they all are
+// objects with a distinct id.
+func byID(es []interface{}) (map[string]interface{}, bool) {
+	m := make(map[string]interface{}, len(es))
+	for _, e := range es {
+		id := elementID(e)
+		if id == "" {
+			return nil, false
+		}
+		if _, ok := m[id]; ok {
+			return nil, false
+		}
+		m[id] = e
+	}
+	return m, true
+}
+
+func elementID(e interface{}) string {
+	o, ok := e.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := o["id"].(string)
+	return id
+}
+
+// isSensitive returns whether the value of a field must not be recorded.
+func isSensitive(key string) bool {
+	k := strings.ToLower(key)
+	return strings.Contains(k, "token") || strings.Contains(k, "password") || strings.HasSuffix(k, "secret")
+}
+
+func redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	return Redacted
+}
+
+// scrub removes the links and redacts the sensitive fields of a value
+// recorded whole.
+func scrub(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		o := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			switch {
+			case k == "links":
+			case isSensitive(k):
+				o[k] = redact(e)
+			default:
+				o[k] = scrub(e)
+			}
+		}
+		return o
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = scrub(e)
+		}
+		return a
+	}
+	return v
+}
+
+// escape escapes a reference token of a JSON pointer.
+func escape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
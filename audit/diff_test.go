@@ -0,0 +1,98 @@
+package audit_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/audit"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []influxdb.AuditChange
+	}{
+		{
+			name:   "unchanged",
+			before: `{"id":"1","name":"a"}`,
+			after:  `{"id":"1","name":"a"}`,
+			want:   []influxdb.AuditChange{},
+		},
+		{
+			name:  "created",
+			after: `{"id":"1","name":"a","links":{"self":"/api/v2/buckets/1"}}`,
+			want: []influxdb.AuditChange{
+				{Path: "", New: map[string]interface{}{"id": "1", "name": "a"}},
+			},
+		},
+		{
+			name:   "deleted",
+			before: `{"id":"1"}`,
+			want: []influxdb.AuditChange{
+				{Path: "", Old: map[string]interface{}{"id": "1"}},
+			},
+		},
+		{
+			name:   "fields",
+			before: `{"name":"a","retentionRules":[{"everySeconds":60}],"links":{"self":"x"}}`,
+			after:  `{"name":"b","retentionRules":[{"everySeconds":120}],"description":"c/d","links":{"self":"y"}}`,
+			want: []influxdb.AuditChange{
+				{Path: "/description", New: "c/d"},
+				{Path: "/name", Old: "a", New: "b"},
+				{
+					Path: "/retentionRules",
+					Old:  []interface{}{map[string]interface{}{"everySeconds": float64(60)}},
+					New:  []interface{}{map[string]interface{}{"everySeconds": float64(120)}},
+				},
+			},
+		},
+		{
+			name:   "elements by id",
+			before: `{"labels":[{"id":"1","name":"a"},{"id":"2","name":"b"}]}`,
+			after:  `{"labels":[{"id":"2","name":"c"},{"id":"3","name":"d"}]}`,
+			want: []influxdb.AuditChange{
+				{Path: "/labels/1", Old: map[string]interface{}{"id": "1", "name": "a"}},
+				{Path: "/labels/2/name", Old: "b", New: "c"},
+				{Path: "/labels/3", New: map[string]interface{}{"id": "3", "name": "d"}},
+			},
+		},
+		{
+			name:   "sensitive fields",
+			before: `{"token":"a","remoteToken":"b","clientSecret":"c"}`,
+			after:  `{"token":"a","remoteToken":"d","clientSecret":"c","password":"e"}`,
+			want: []influxdb.AuditChange{
+				{Path: "/password", New: audit.Redacted},
+				{Path: "/remoteToken", Old: audit.Redacted, New: audit.Redacted},
+			},
+		},
+		{
+			name:  "sensitive fields of created resources",
+			after: `{"id":"1","token":"secret"}`,
+			want: []influxdb.AuditChange{
+				{Path: "", New: map[string]interface{}{"id": "1", "token": audit.Redacted}},
+			},
+		},
+		{
+			name:   "escaped keys",
+			before: `{"a/b":1}`,
+			after:  `{"a/b":2}`,
+			want: []influxdb.AuditChange{
+				{Path: "/a~1b", Old: float64(1), New: float64(2)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := audit.Diff([]byte(tt.before), []byte(tt.after))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unexpected changes:\ngot  %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
+)
+
+// Measurement is the measurement of the points of exported audit events.
+const Measurement = "audit"
+
+var _ influxdb.AuditLogService = (*ExportService)(nil)
+
+// ExportService records audit events, and exports them as points to a
+// bucket so that they can be queried and retained like other data.
+type ExportService struct {
+	influxdb.AuditLogService
+
+	PointsWriter storage.PointsWriter
+	OrgID        influxdb.ID
+	BucketID     influxdb.ID
+
+	Logger *zap.Logger
+}
+
+// NewExportService returns an ExportService that records events with s and
+// writes them to the bucket with w.
+func NewExportService(s influxdb.AuditLogService, w storage.PointsWriter, orgID, bucketID influxdb.ID) *ExportService {
+	return &ExportService{
+		AuditLogService: s,
+		PointsWriter:    w,
+		OrgID:           orgID,
+		BucketID:        bucketID,
+		Logger:          zap.NewNop(),
+	}
+}
+
+// AppendAuditEvent records an event and exports it. The event is recorded
+// even if exporting it fails.
+func (s *ExportService) AppendAuditEvent(ctx context.Context, e *influxdb.AuditEvent) error {
+	if err := s.AuditLogService.AppendAuditEvent(ctx, e); err != nil {
+		return err
+	}
+
+	if err := s.export(ctx, e); err != nil {
+		s.Logger.Error("Failed to export audit event",
+			zap.String("id", e.ID.String()),
+			zap.Error(err))
+	}
+	return nil
+}
+
+func (s *ExportService) export(ctx context.Context, e *influxdb.AuditEvent) error {
+	pt, err := NewPoint(e)
+	if err != nil {
+		return err
+	}
+	pts, err := tsdb.ExplodePoints(s.OrgID, s.BucketID, []models.Point{pt})
+	if err != nil {
+		return err
+	}
+	return s.PointsWriter.WritePoints(ctx, pts)
+}
+
+// NewPoint returns the point of an audit event. The action, the type of the
+// resource, the method and the kind of authorizer are tags, the rest fields.
+func NewPoint(e *influxdb.AuditEvent) (models.Point, error) {
+	tags := map[string]string{}
+	for k, v := range map[string]string{
+		"action":         e.Action,
+		"resourceType":   e.ResourceType,
+		"method":         e.Method,
+		"authorizerKind": e.AuthorizerKind,
+	} {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+
+	fields := models.Fields{
+		"id":         e.ID.String(),
+		"path":       e.Path,
+		"status":     int64(e.Status),
+		"remoteAddr": e.RemoteAddr,
+	}
+	if e.ForwardedFor != "" {
+		fields["forwardedFor"] = e.ForwardedFor
+	}
+	for k, id := range map[string]influxdb.ID{
+		"authorizerID": e.AuthorizerID,
+		"userID":       e.UserID,
+		"resourceID":   e.ResourceID,
+		"orgID":        e.OrgID,
+	} {
+		if id.Valid() {
+			fields[k] = id.String()
+		}
+	}
+	if len(e.Changes) > 0 {
+		b, err := json.Marshal(e.Changes)
+		if err != nil {
+			return nil, err
+		}
+		fields["changes"] = string(b)
+	}
+	for k, v := range e.Metadata {
+		fields["metadata."+k] = v
+	}
+
+	return models.NewPoint(Measurement, models.NewTags(tags), fields, e.Time)
+}
@@ -0,0 +1,83 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/audit"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/mock"
+)
+
+func TestExportService_AppendAuditEvent(t *testing.T) {
+	ctx := context.Background()
+	store := kv.NewService(inmem.NewKVStore())
+	if err := store.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	w := &mock.PointsWriter{}
+	s := audit.NewExportService(store, w, influxdb.ID(1), influxdb.ID(2))
+
+	e := &influxdb.AuditEvent{
+		Time:           time.Unix(10, 0),
+		AuthorizerKind: "authorization",
+		AuthorizerID:   influxdb.ID(3),
+		RemoteAddr:     "127.0.0.1",
+		Method:         "PATCH",
+		Path:           "/api/v2/buckets/0000000000000004",
+		Status:         200,
+		Action:         influxdb.AuditActionUpdate,
+		ResourceType:   "buckets",
+		ResourceID:     influxdb.ID(4),
+		Changes:        []influxdb.AuditChange{{Path: "/name", Old: "a", New: "b"}},
+	}
+	if err := s.AppendAuditEvent(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	if !e.ID.Valid() {
+		t.Fatal("expected the event to be recorded")
+	}
+
+	// Points are exploded into a point per field.
+	fields := map[string]interface{}{}
+	for _, pt := range w.Points {
+		if got := pt.Tags().GetString("action"); got != influxdb.AuditActionUpdate {
+			t.Fatalf("unexpected action tag: %q", got)
+		}
+		if got := pt.Tags().GetString("resourceType"); got != "buckets" {
+			t.Fatalf("unexpected resource type tag: %q", got)
+		}
+		if !pt.Time().Equal(e.Time) {
+			t.Fatalf("unexpected time: %v", pt.Time())
+		}
+		fs, err := pt.Fields()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range fs {
+			fields[k] = v
+		}
+	}
+	if fields["changes"] != `[{"path":"/name","old":"a","new":"b"}]` {
+		t.Fatalf("unexpected changes field: %v", fields["changes"])
+	}
+	if fields["authorizerID"] != influxdb.ID(3).String() {
+		t.Fatalf("unexpected authorizer field: %v", fields["authorizerID"])
+	}
+
+	// The event is recorded even if it cannot be exported.
+	w.ForceError(&influxdb.Error{Code: influxdb.EInternal, Msg: "disk full"})
+	if err := s.AppendAuditEvent(ctx, &influxdb.AuditEvent{Action: influxdb.AuditActionCreate, Path: "/api/v2/labels"}); err != nil {
+		t.Fatal(err)
+	}
+	_, n, err := s.FindAuditEvents(ctx, influxdb.AuditEventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 events, got %d", n)
+	}
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultPruneInterval is how often old audit events are removed by default.
+const DefaultPruneInterval = time.Hour
+
+// EventDeleter deletes the audit events recorded before a time.
+type EventDeleter interface {
+	DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int, error)
+}
+
+// Pruner removes the audit events older than a retention period.
+type Pruner struct {
+	Deleter   EventDeleter
+	Retention time.Duration
+	Interval  time.Duration
+
+	Logger *zap.Logger
+	now    func() time.Time
+}
+
+// NewPruner returns a Pruner removing the events of d older than retention
+// every DefaultPruneInterval.
+func NewPruner(d EventDeleter, retention time.Duration) *Pruner {
+	return &Pruner{
+		Deleter:   d,
+		Retention: retention,
+		Interval:  DefaultPruneInterval,
+		Logger:    zap.NewNop(),
+		now:       time.Now,
+	}
+}
+
+// Prune removes the events older than the retention period once.
+func (p *Pruner) Prune(ctx context.Context) error {
+	n, err := p.Deleter.DeleteAuditEventsBefore(ctx, p.now().Add(-p.Retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		p.Logger.Info("Removed old audit events", zap.Int("count", n))
+	}
+	return nil
+}
+
+// Run prunes events every interval until ctx is done.
+func (p *Pruner) Run(ctx context.Context) {
+	t := time.NewTicker(p.Interval)
+	defer t.Stop()
+	for {
+		if err := p.Prune(ctx); err != nil {
+			p.Logger.Error("Failed to remove old audit events", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
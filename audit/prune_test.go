@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+)
+
+func TestPruner_Prune(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2006, 5, 4, 0, 0, 0, 0, time.UTC)
+	for _, d := range []time.Duration{48 * time.Hour, 25 * time.Hour, time.Hour} {
+		if err := svc.AppendAuditEvent(ctx, &influxdb.AuditEvent{Time: now.Add(-d)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewPruner(svc, 24*time.Hour)
+	p.now = func() time.Time { return now }
+	if err := p.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	es, _, err := svc.FindAuditEvents(ctx, influxdb.AuditEventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || !es[0].Time.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("unexpected events left: %v", es)
+	}
+}
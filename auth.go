@@ -3,6 +3,7 @@ package influxdb
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
 // AuthorizationKind is returned by (*Authorization).Kind().
@@ -25,8 +26,17 @@ type Authorization struct {
 	OrgID       ID           `json:"orgID"`
 	UserID      ID           `json:"userID,omitempty"`
 	Permissions []Permission `json:"permissions"`
+
+	// ExpiresAt is when the token stops being accepted, or nil if it never expires.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// LastUsedAt is about when the token last authenticated a request. It is
+	// only updated about once every LastUsedInterval.
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
 }
 
+// LastUsedInterval is how often the last use of a token is recorded.
+const LastUsedInterval = time.Minute
+
 // Valid ensures that the authorization is valid.
 func (a *Authorization) Valid() error {
 	for _, p := range a.Permissions {
@@ -56,9 +66,14 @@ func IsActive(a *Authorization) bool {
 	return a.IsActive()
 }
 
-// IsActive returns true if the authorization active.
+// IsActive returns true if the authorization active and not expired.
 func (a *Authorization) IsActive() bool {
-	return a.Status == Active
+	return a.Status == Active && !a.IsExpired(time.Now())
+}
+
+// IsExpired returns true if the authorization has expired by now.
+func (a *Authorization) IsExpired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
 }
 
 // GetUserID returns the user id.
@@ -79,6 +94,8 @@ const (
 	OpFindAuthorizations       = "FindAuthorizations"
 	OpCreateAuthorization      = "CreateAuthorization"
 	OpSetAuthorizationStatus   = "SetAuthorizationStatus"
+	OpUpdateAuthorization      = "UpdateAuthorization"
+	OpRotateAuthorization      = "RotateAuthorization"
 	OpDeleteAuthorization      = "DeleteAuthorization"
 )
 
@@ -101,10 +118,60 @@ type AuthorizationService interface {
 	// for setting an authorization to inactive or active.
 	SetAuthorizationStatus(ctx context.Context, id ID, status Status) error
 
+	// UpdateAuthorization updates the status, description, expiry or last use
+	// of the authorization.
+	UpdateAuthorization(ctx context.Context, id ID, upd *AuthorizationUpdate) (*Authorization, error)
+
+	// RotateAuthorization replaces the token of the authorization with a new
+	// one, returned in the Token of the result. The old token keeps working
+	// for the grace period.
+	RotateAuthorization(ctx context.Context, id ID, grace time.Duration) (*Authorization, error)
+
 	// Removes a authorization by token.
 	DeleteAuthorization(ctx context.Context, id ID) error
 }
 
+// AuthorizationUpdate is the set of changes to an authorization. Nil fields
+// are left unchanged.
+type AuthorizationUpdate struct {
+	Status      *Status
+	Description *string
+	// ExpiresAt sets when the token expires. The zero time removes the expiry.
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// Apply applies the update to an authorization.
+func (u *AuthorizationUpdate) Apply(a *Authorization) error {
+	if u.Status != nil {
+		switch *u.Status {
+		case Active, Inactive:
+		default:
+			return &Error{
+				Code: EInvalid,
+				Msg:  "unknown authorization status",
+			}
+		}
+		a.Status = *u.Status
+	}
+	if u.Description != nil {
+		a.Description = *u.Description
+	}
+	if u.ExpiresAt != nil {
+		if u.ExpiresAt.IsZero() {
+			a.ExpiresAt = nil
+		} else {
+			t := *u.ExpiresAt
+			a.ExpiresAt = &t
+		}
+	}
+	if u.LastUsedAt != nil {
+		t := *u.LastUsedAt
+		a.LastUsedAt = &t
+	}
+	return nil
+}
+
 // AuthorizationFilter represents a set of filter that restrict the returned results.
 type AuthorizationFilter struct {
 	Token *string
@@ -0,0 +1,54 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.AuditLogService = (*AuditLogService)(nil)
+
+// AuditLogService wraps a influxdb.AuditLogService and authorizes actions
+// against it appropriately.
+type AuditLogService struct {
+	s influxdb.AuditLogService
+}
+
+// NewAuditLogService constructs an instance of an authorizing audit log service.
+func NewAuditLogService(s influxdb.AuditLogService) *AuditLogService {
+	return &AuditLogService{
+		s: s,
+	}
+}
+
+// AppendAuditEvent checks to see if the authorizer on context has write access
+// to the audit log of the instance.
+func (s *AuditLogService) AppendAuditEvent(ctx context.Context, e *influxdb.AuditEvent) error {
+	p := influxdb.Permission{
+		Action:   influxdb.WriteAction,
+		Resource: influxdb.Resource{Type: influxdb.AuditResourceType},
+	}
+	if err := IsAllowed(ctx, p); err != nil {
+		return err
+	}
+
+	return s.s.AppendAuditEvent(ctx, e)
+}
+
+// FindAuditEvents checks to see if the authorizer on context has read access to
+// the audit log of the instance, or to that of the organization of the filter.
+// Events without an organization are only visible to the former.
+func (s *AuditLogService) FindAuditEvents(ctx context.Context, filter influxdb.AuditEventFilter, opt ...influxdb.FindOptions) ([]*influxdb.AuditEvent, int, error) {
+	p := influxdb.Permission{
+		Action:   influxdb.ReadAction,
+		Resource: influxdb.Resource{Type: influxdb.AuditResourceType},
+	}
+	if filter.OrgID != nil {
+		p.Resource.OrgID = filter.OrgID
+	}
+	if err := IsAllowed(ctx, p); err != nil {
+		return nil, 0, err
+	}
+
+	return s.s.FindAuditEvents(ctx, filter, opt...)
+}
@@ -0,0 +1,100 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestAuditLogService_FindAuditEvents(t *testing.T) {
+	type args struct {
+		permission influxdb.Permission
+		filter     influxdb.AuditEventFilter
+	}
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "authorized to access the instance log",
+			args: args{
+				permission: influxdb.Permission{
+					Action:   "read",
+					Resource: influxdb.Resource{Type: influxdb.AuditResourceType},
+				},
+			},
+		},
+		{
+			name: "authorized to access the log of an org",
+			args: args{
+				permission: influxdb.Permission{
+					Action: "read",
+					Resource: influxdb.Resource{
+						Type:  influxdb.AuditResourceType,
+						OrgID: influxdbtesting.IDPtr(10),
+					},
+				},
+				filter: influxdb.AuditEventFilter{OrgID: influxdbtesting.IDPtr(10)},
+			},
+		},
+		{
+			name: "unauthorized to access the instance log",
+			args: args{
+				permission: influxdb.Permission{
+					Action: "read",
+					Resource: influxdb.Resource{
+						Type:  influxdb.AuditResourceType,
+						OrgID: influxdbtesting.IDPtr(10),
+					},
+				},
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "read:audit is unauthorized",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
+		{
+			name: "unauthorized to access the log of another org",
+			args: args{
+				permission: influxdb.Permission{
+					Action: "read",
+					Resource: influxdb.Resource{
+						Type:  influxdb.AuditResourceType,
+						OrgID: influxdbtesting.IDPtr(10),
+					},
+				},
+				filter: influxdb.AuditEventFilter{OrgID: influxdbtesting.IDPtr(11)},
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "read:orgs/000000000000000b/audit is unauthorized",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := authorizer.NewAuditLogService(mock.NewAuditLogService())
+
+			ctx := context.Background()
+			ctx = influxdbcontext.SetAuthorizer(ctx, &Authorizer{[]influxdb.Permission{tt.args.permission}})
+
+			_, _, err := s.FindAuditEvents(ctx, tt.args.filter)
+			influxdbtesting.ErrorsEqual(t, err, tt.wants.err)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package authorizer
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/influxdata/influxdb"
 )
@@ -170,3 +171,31 @@ func (s *AuthorizationService) DeleteAuthorization(ctx context.Context, id influ
 
 	return s.s.DeleteAuthorization(ctx, id)
 }
+
+// UpdateAuthorization checks to see if the authorizer on context has write access to the authorization provided.
+func (s *AuthorizationService) UpdateAuthorization(ctx context.Context, id influxdb.ID, upd *influxdb.AuthorizationUpdate) (*influxdb.Authorization, error) {
+	a, err := s.s.FindAuthorizationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeWriteAuthorization(ctx, a.UserID); err != nil {
+		return nil, err
+	}
+
+	return s.s.UpdateAuthorization(ctx, id, upd)
+}
+
+// RotateAuthorization checks to see if the authorizer on context has write access to the authorization provided.
+func (s *AuthorizationService) RotateAuthorization(ctx context.Context, id influxdb.ID, grace time.Duration) (*influxdb.Authorization, error) {
+	a, err := s.s.FindAuthorizationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeWriteAuthorization(ctx, a.UserID); err != nil {
+		return nil, err
+	}
+
+	return s.s.RotateAuthorization(ctx, id, grace)
+}
@@ -0,0 +1,79 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.MFAService = (*MFAService)(nil)
+
+// MFAService wraps a influxdb.MFAService and authorizes actions
+// against it appropriately. The multi-factor authentication of a user
+// is authorized as the user.
+type MFAService struct {
+	s influxdb.MFAService
+}
+
+// NewMFAService constructs an instance of an authorizing mfa service.
+func NewMFAService(s influxdb.MFAService) *MFAService {
+	return &MFAService{
+		s: s,
+	}
+}
+
+// FindMFA checks to see if the authorizer on context has read access to the user.
+func (s *MFAService) FindMFA(ctx context.Context, userID influxdb.ID) (*influxdb.MFA, error) {
+	if err := authorizeReadUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.s.FindMFA(ctx, userID)
+}
+
+// EnrollTOTP checks to see if the authorizer on context has write access to the user.
+func (s *MFAService) EnrollTOTP(ctx context.Context, userID influxdb.ID) (*influxdb.TOTPEnrollment, error) {
+	if err := authorizeWriteUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.s.EnrollTOTP(ctx, userID)
+}
+
+// ConfirmTOTP checks to see if the authorizer on context has write access to the user.
+func (s *MFAService) ConfirmTOTP(ctx context.Context, userID influxdb.ID, code string) ([]string, error) {
+	if err := authorizeWriteUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.s.ConfirmTOTP(ctx, userID, code)
+}
+
+// VerifyMFA checks to see if the authorizer on context has write access to the user.
+func (s *MFAService) VerifyMFA(ctx context.Context, userID influxdb.ID, code string) error {
+	if err := authorizeWriteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.s.VerifyMFA(ctx, userID, code)
+}
+
+// GenerateRecoveryCodes checks to see if the authorizer on context has write access to the user.
+func (s *MFAService) GenerateRecoveryCodes(ctx context.Context, userID influxdb.ID) ([]string, error) {
+	if err := authorizeWriteUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.s.GenerateRecoveryCodes(ctx, userID)
+}
+
+// DeleteMFA checks to see if the authorizer on context has write access to the user.
+// The HTTP API further requires users to give a current code, and limits resetting
+// the multi-factor authentication of other users to operators.
+func (s *MFAService) DeleteMFA(ctx context.Context, userID influxdb.ID) error {
+	if err := authorizeWriteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.s.DeleteMFA(ctx, userID)
+}
@@ -0,0 +1,156 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestReplicationService_FindReplicationByID(t *testing.T) {
+	type args struct {
+		permission influxdb.Permission
+		id         influxdb.ID
+	}
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "authorized to access id",
+			args: args{
+				permission: influxdb.Permission{
+					Action: "read",
+					Resource: influxdb.Resource{
+						Type: influxdb.ReplicationsResourceType,
+						ID:   influxdbtesting.IDPtr(1),
+					},
+				},
+				id: 1,
+			},
+			wants: wants{
+				err: nil,
+			},
+		},
+		{
+			name: "unauthorized to access id",
+			args: args{
+				permission: influxdb.Permission{
+					Action: "read",
+					Resource: influxdb.Resource{
+						Type: influxdb.ReplicationsResourceType,
+						ID:   influxdbtesting.IDPtr(2),
+					},
+				},
+				id: 1,
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "read:orgs/000000000000000a/replications/0000000000000001 is unauthorized",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := mock.NewReplicationService()
+			rs.FindReplicationByIDFn = func(ctx context.Context, id influxdb.ID) (*influxdb.Replication, error) {
+				return &influxdb.Replication{ID: id, OrgID: 10}, nil
+			}
+			s := authorizer.NewReplicationService(rs)
+
+			ctx := context.Background()
+			ctx = influxdbcontext.SetAuthorizer(ctx, &Authorizer{[]influxdb.Permission{tt.args.permission}})
+
+			_, err := s.FindReplicationByID(ctx, tt.args.id)
+			influxdbtesting.ErrorsEqual(t, err, tt.wants.err)
+		})
+	}
+}
+
+func TestReplicationService_CreateReplication(t *testing.T) {
+	type args struct {
+		permissions []influxdb.Permission
+	}
+	type wants struct {
+		err error
+	}
+
+	writeReplications := influxdb.Permission{
+		Action: "write",
+		Resource: influxdb.Resource{
+			Type:  influxdb.ReplicationsResourceType,
+			OrgID: influxdbtesting.IDPtr(10),
+		},
+	}
+	readBucket := influxdb.Permission{
+		Action: "read",
+		Resource: influxdb.Resource{
+			Type: influxdb.BucketsResourceType,
+			ID:   influxdbtesting.IDPtr(2),
+		},
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "authorized to create replication",
+			args: args{
+				permissions: []influxdb.Permission{writeReplications, readBucket},
+			},
+			wants: wants{
+				err: nil,
+			},
+		},
+		{
+			name: "unauthorized to create replication",
+			args: args{
+				permissions: []influxdb.Permission{readBucket},
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "write:orgs/000000000000000a/replications is unauthorized",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
+		{
+			name: "unauthorized to read source bucket",
+			args: args{
+				permissions: []influxdb.Permission{writeReplications},
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "read:orgs/000000000000000a/buckets/0000000000000002 is unauthorized",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := authorizer.NewReplicationService(mock.NewReplicationService())
+
+			ctx := context.Background()
+			ctx = influxdbcontext.SetAuthorizer(ctx, &Authorizer{tt.args.permissions})
+
+			err := s.CreateReplication(ctx, &influxdb.Replication{OrgID: 10, SourceBucketID: 2})
+			influxdbtesting.ErrorsEqual(t, err, tt.wants.err)
+		})
+	}
+}
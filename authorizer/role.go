@@ -0,0 +1,246 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.RoleService = (*RoleService)(nil)
+
+// RoleService wraps a influxdb.RoleService and authorizes actions
+// against it appropriately.
+type RoleService struct {
+	s influxdb.RoleService
+}
+
+// NewRoleService constructs an instance of an authorizing role service.
+func NewRoleService(s influxdb.RoleService) *RoleService {
+	return &RoleService{
+		s: s,
+	}
+}
+
+func newRolePermission(a influxdb.Action, orgID, id influxdb.ID) (*influxdb.Permission, error) {
+	return influxdb.NewPermissionAtID(id, a, influxdb.RolesResourceType, orgID)
+}
+
+func authorizeReadRole(ctx context.Context, orgID, id influxdb.ID) error {
+	p, err := newRolePermission(influxdb.ReadAction, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := IsAllowed(ctx, *p); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func authorizeWriteRole(ctx context.Context, orgID, id influxdb.ID) error {
+	p, err := newRolePermission(influxdb.WriteAction, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := IsAllowed(ctx, *p); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindRoleByID checks to see if the authorizer on context has read access to the id provided.
+func (s *RoleService) FindRoleByID(ctx context.Context, id influxdb.ID) (*influxdb.Role, error) {
+	r, err := s.s.FindRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeReadRole(ctx, r.OrgID, id); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// FindRoles retrieves all roles that match the provided filter and then filters the list down to only the resources that are authorized.
+func (s *RoleService) FindRoles(ctx context.Context, filter influxdb.RoleFilter, opt ...influxdb.FindOptions) ([]*influxdb.Role, int, error) {
+	// TODO: we'll likely want to push this operation into the database eventually since fetching the whole list of data
+	// will likely be expensive.
+	rs, _, err := s.s.FindRoles(ctx, filter, opt...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// This filters without allocating
+	// https://github.com/golang/go/wiki/SliceTricks#filtering-without-allocating
+	roles := rs[:0]
+	for _, r := range rs {
+		err := authorizeReadRole(ctx, r.OrgID, r.ID)
+		if err != nil && influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			return nil, 0, err
+		}
+
+		if influxdb.ErrorCode(err) == influxdb.EUnauthorized {
+			continue
+		}
+
+		roles = append(roles, r)
+	}
+
+	return roles, len(roles), nil
+}
+
+// CreateRole checks to see if the authorizer on context has write access to the roles of the
+// organization, and holds every permission of the role, so that roles cannot escalate privileges.
+func (s *RoleService) CreateRole(ctx context.Context, r *influxdb.Role) error {
+	p, err := influxdb.NewPermission(influxdb.WriteAction, influxdb.RolesResourceType, r.OrgID)
+	if err != nil {
+		return err
+	}
+
+	if err := IsAllowed(ctx, *p); err != nil {
+		return err
+	}
+
+	if err := VerifyPermissions(ctx, r.Permissions); err != nil {
+		return err
+	}
+
+	return s.s.CreateRole(ctx, r)
+}
+
+// UpdateRole checks to see if the authorizer on context has write access to the role provided,
+// and holds every permission it is updated with.
+func (s *RoleService) UpdateRole(ctx context.Context, id influxdb.ID, upd influxdb.RoleUpdate) (*influxdb.Role, error) {
+	r, err := s.s.FindRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeWriteRole(ctx, r.OrgID, id); err != nil {
+		return nil, err
+	}
+
+	if upd.Permissions != nil {
+		if err := VerifyPermissions(ctx, *upd.Permissions); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.s.UpdateRole(ctx, id, upd)
+}
+
+// DeleteRole checks to see if the authorizer on context has write access to the role provided.
+func (s *RoleService) DeleteRole(ctx context.Context, id influxdb.ID) error {
+	r, err := s.s.FindRoleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeWriteRole(ctx, r.OrgID, id); err != nil {
+		return err
+	}
+
+	return s.s.DeleteRole(ctx, id)
+}
+
+// FindRoleMappings retrieves all assignments of roles that match the provided filter and then filters the list
+// down to the assignments of roles the authorizer on context has read access to.
+func (s *RoleService) FindRoleMappings(ctx context.Context, filter influxdb.RoleMappingFilter) ([]*influxdb.RoleMapping, int, error) {
+	ms, _, err := s.s.FindRoleMappings(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	orgs := map[influxdb.ID]influxdb.ID{}
+	mappings := ms[:0]
+	for _, m := range ms {
+		orgID, ok := orgs[m.RoleID]
+		if !ok {
+			r, err := s.s.FindRoleByID(ctx, m.RoleID)
+			if err != nil {
+				return nil, 0, err
+			}
+			orgID = r.OrgID
+			orgs[m.RoleID] = orgID
+		}
+
+		err := authorizeReadRole(ctx, orgID, m.RoleID)
+		if err != nil && influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			return nil, 0, err
+		}
+
+		if influxdb.ErrorCode(err) == influxdb.EUnauthorized {
+			continue
+		}
+
+		mappings = append(mappings, m)
+	}
+
+	return mappings, len(mappings), nil
+}
+
+// CreateRoleMapping checks to see if the authorizer on context has write access to the role assigned,
+// and holds every permission of it.
+func (s *RoleService) CreateRoleMapping(ctx context.Context, m *influxdb.RoleMapping) error {
+	r, err := s.s.FindRoleByID(ctx, m.RoleID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeWriteRole(ctx, r.OrgID, r.ID); err != nil {
+		return err
+	}
+
+	if err := VerifyPermissions(ctx, r.Permissions); err != nil {
+		return err
+	}
+
+	return s.s.CreateRoleMapping(ctx, m)
+}
+
+// DeleteRoleMapping checks to see if the authorizer on context has write access to the role unassigned.
+func (s *RoleService) DeleteRoleMapping(ctx context.Context, roleID influxdb.ID, subjectType influxdb.RoleSubjectType, subjectID influxdb.ID) error {
+	r, err := s.s.FindRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeWriteRole(ctx, r.OrgID, roleID); err != nil {
+		return err
+	}
+
+	return s.s.DeleteRoleMapping(ctx, roleID, subjectType, subjectID)
+}
+
+// AddRolePermissions grants a the permissions of the roles assigned to the token.
+// Roles are looked up on every request, so that changes of a role apply to everyone
+// holding it right away. The roles of the user of a session are granted by the
+// SessionService, along with the other permissions of the user.
+// s must not be an authorizing RoleService, as a is not yet on context.
+func AddRolePermissions(ctx context.Context, s influxdb.RoleService, a *influxdb.Authorization) error {
+	filter := influxdb.RoleMappingFilter{
+		SubjectType: influxdb.AuthorizationRoleSubject,
+		SubjectID:   &a.ID,
+	}
+
+	ms, _, err := s.FindRoleMappings(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	var ps []influxdb.Permission
+	for _, m := range ms {
+		r, err := s.FindRoleByID(ctx, m.RoleID)
+		if err != nil {
+			return err
+		}
+		ps = append(ps, r.Permissions...)
+	}
+
+	a.Permissions = append(a.Permissions, ps...)
+	return nil
+}
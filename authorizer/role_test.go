@@ -0,0 +1,139 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestRoleService_CreateRole(t *testing.T) {
+	readBuckets := influxdb.Permission{
+		Action: "read",
+		Resource: influxdb.Resource{
+			Type:  influxdb.BucketsResourceType,
+			OrgID: influxdbtesting.IDPtr(10),
+		},
+	}
+	writeRoles := influxdb.Permission{
+		Action: "write",
+		Resource: influxdb.Resource{
+			Type:  influxdb.RolesResourceType,
+			OrgID: influxdbtesting.IDPtr(10),
+		},
+	}
+
+	type args struct {
+		permissions []influxdb.Permission
+		role        *influxdb.Role
+	}
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "authorized to create a role with permissions held",
+			args: args{
+				permissions: []influxdb.Permission{writeRoles, readBuckets},
+				role: &influxdb.Role{
+					OrgID:       10,
+					Name:        "reader",
+					Permissions: []influxdb.Permission{readBuckets},
+				},
+			},
+		},
+		{
+			name: "unauthorized to create a role",
+			args: args{
+				permissions: []influxdb.Permission{readBuckets},
+				role: &influxdb.Role{
+					OrgID: 10,
+					Name:  "reader",
+				},
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "write:orgs/000000000000000a/roles is unauthorized",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
+		{
+			name: "forbidden to create a role with permissions not held",
+			args: args{
+				permissions: []influxdb.Permission{writeRoles},
+				role: &influxdb.Role{
+					OrgID:       10,
+					Name:        "reader",
+					Permissions: []influxdb.Permission{readBuckets},
+				},
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "permission read:orgs/000000000000000a/buckets is not allowed",
+					Code: influxdb.EForbidden,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := authorizer.NewRoleService(mock.NewRoleService())
+
+			ctx := context.Background()
+			ctx = influxdbcontext.SetAuthorizer(ctx, &Authorizer{tt.args.permissions})
+
+			err := s.CreateRole(ctx, tt.args.role)
+			influxdbtesting.ErrorsEqual(t, err, tt.wants.err)
+		})
+	}
+}
+
+func TestAddRolePermissions(t *testing.T) {
+	readBuckets := influxdb.Permission{
+		Action: "read",
+		Resource: influxdb.Resource{
+			Type:  influxdb.BucketsResourceType,
+			OrgID: influxdbtesting.IDPtr(10),
+		},
+	}
+
+	svc := mock.NewRoleService()
+	svc.FindRoleMappingsFn = func(ctx context.Context, filter influxdb.RoleMappingFilter) ([]*influxdb.RoleMapping, int, error) {
+		if filter.SubjectType != influxdb.AuthorizationRoleSubject || *filter.SubjectID != 1 {
+			return nil, 0, nil
+		}
+		return []*influxdb.RoleMapping{{RoleID: 3, SubjectType: filter.SubjectType, SubjectID: *filter.SubjectID}}, 1, nil
+	}
+	svc.FindRoleByIDFn = func(ctx context.Context, id influxdb.ID) (*influxdb.Role, error) {
+		return &influxdb.Role{ID: id, OrgID: 10, Name: "reader", Permissions: []influxdb.Permission{readBuckets}}, nil
+	}
+
+	// The roles of a token apply to it.
+	a := &influxdb.Authorization{ID: 1, UserID: 2}
+	if err := authorizer.AddRolePermissions(context.Background(), svc, a); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Permissions) != 1 || a.Permissions[0] != readBuckets {
+		t.Fatalf("unexpected permissions: %v", a.Permissions)
+	}
+
+	// The roles of other tokens do not.
+	other := &influxdb.Authorization{ID: 4, UserID: 2}
+	if err := authorizer.AddRolePermissions(context.Background(), svc, other); err != nil {
+		t.Fatal(err)
+	}
+	if len(other.Permissions) != 0 {
+		t.Fatalf("unexpected permissions: %v", other.Permissions)
+	}
+}
@@ -0,0 +1,40 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.UserSessionService = (*UserSessionService)(nil)
+
+// UserSessionService wraps a influxdb.UserSessionService and authorizes actions
+// against it appropriately. The sessions of a user are authorized as the user.
+type UserSessionService struct {
+	s influxdb.UserSessionService
+}
+
+// NewUserSessionService constructs an instance of an authorizing user session service.
+func NewUserSessionService(s influxdb.UserSessionService) *UserSessionService {
+	return &UserSessionService{
+		s: s,
+	}
+}
+
+// FindUserSessions checks to see if the authorizer on context has read access to the user.
+func (s *UserSessionService) FindUserSessions(ctx context.Context, userID influxdb.ID) ([]*influxdb.Session, error) {
+	if err := authorizeReadUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return s.s.FindUserSessions(ctx, userID)
+}
+
+// DeleteUserSession checks to see if the authorizer on context has write access to the user.
+func (s *UserSessionService) DeleteUserSession(ctx context.Context, userID, sessionID influxdb.ID) error {
+	if err := authorizeWriteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.s.DeleteUserSession(ctx, userID, sessionID)
+}
@@ -0,0 +1,73 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestUserSessionService_DeleteUserSession(t *testing.T) {
+	type args struct {
+		permissions []influxdb.Permission
+		userID      influxdb.ID
+	}
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "authorized to revoke their own session",
+			args: args{
+				permissions: influxdb.MePermissions(1),
+				userID:      1,
+			},
+		},
+		{
+			name: "authorized to revoke the session of another user",
+			args: args{
+				permissions: []influxdb.Permission{
+					{
+						Action:   influxdb.WriteAction,
+						Resource: influxdb.Resource{Type: influxdb.UsersResourceType},
+					},
+				},
+				userID: 2,
+			},
+		},
+		{
+			name: "unauthorized to revoke the session of another user",
+			args: args{
+				permissions: influxdb.MePermissions(1),
+				userID:      2,
+			},
+			wants: wants{
+				err: &influxdb.Error{
+					Msg:  "write:users/0000000000000002 is unauthorized",
+					Code: influxdb.EUnauthorized,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := authorizer.NewUserSessionService(mock.NewUserSessionService())
+
+			ctx := context.Background()
+			ctx = influxdbcontext.SetAuthorizer(ctx, &Authorizer{tt.args.permissions})
+
+			err := s.DeleteUserSession(ctx, tt.args.userID, 10)
+			influxdbtesting.ErrorsEqual(t, err, tt.wants.err)
+		})
+	}
+}
@@ -122,6 +122,12 @@ const (
 	// ViewsResourceType gives permission to one or more views.
 	ViewsResourceType     = ResourceType("views")     // 12
 	DocumentsResourceType = ResourceType("documents") // 13
+	// ReplicationsResourceType gives permission to one or more replications.
+	ReplicationsResourceType = ResourceType("replications") // 14
+	// AuditResourceType gives permission to read the audit log.
+	AuditResourceType = ResourceType("audit") // 15
+	// RolesResourceType gives permission to one or more roles.
+	RolesResourceType = ResourceType("roles") // 16
 )
 
 // AllResourceTypes is the list of all known resource types.
@@ -140,19 +146,25 @@ var AllResourceTypes = []ResourceType{
 	LabelsResourceType,         // 11
 	ViewsResourceType,          // 12
 	DocumentsResourceType,      // 13
+	ReplicationsResourceType,   // 14
+	AuditResourceType,          // 15
+	RolesResourceType,          // 16
 }
 
 // OrgResourceTypes is the list of all known resource types that belong to an organization.
 var OrgResourceTypes = []ResourceType{
-	BucketsResourceType,    // 1
-	DashboardsResourceType, // 2
-	SourcesResourceType,    // 4
-	TasksResourceType,      // 5
-	TelegrafsResourceType,  // 6
-	UsersResourceType,      // 7
-	VariablesResourceType,  // 8
-	SecretsResourceType,    // 10
-	DocumentsResourceType,  //13
+	BucketsResourceType,      // 1
+	DashboardsResourceType,   // 2
+	SourcesResourceType,      // 4
+	TasksResourceType,        // 5
+	TelegrafsResourceType,    // 6
+	UsersResourceType,        // 7
+	VariablesResourceType,    // 8
+	SecretsResourceType,      // 10
+	DocumentsResourceType,    //13
+	ReplicationsResourceType, // 14
+	AuditResourceType,        // 15
+	RolesResourceType,        // 16
 }
 
 // Valid checks if the resource type is a member of the ResourceType enum.
@@ -177,6 +189,9 @@ func (t ResourceType) Valid() (err error) {
 	case LabelsResourceType: // 11
 	case ViewsResourceType: // 12
 	case DocumentsResourceType: // 13
+	case ReplicationsResourceType: // 14
+	case AuditResourceType: // 15
+	case RolesResourceType: // 16
 	default:
 		err = ErrInvalidResourceType
 	}
@@ -1,20 +1,65 @@
 package bolt
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/coreos/bbolt"
 	platform "github.com/influxdata/influxdb"
+	"go.uber.org/zap"
 )
 
 var (
 	authorizationBucket = []byte("authorizationsv1")
-	authorizationIndex  = []byte("authorizationindexv1")
+	// authorizationIndex indexed authorizations by their token before
+	// tokens were hashed. It is emptied by the migration in
+	// initializeAuthorizations.
+	authorizationIndex = []byte("authorizationindexv1")
+	// authorizationPrefixIndex indexes authorizations by the prefix of
+	// their token followed by their ID.
+	authorizationPrefixIndex = []byte("authorizationprefixindexv1")
 )
 
 var _ platform.AuthorizationService = (*Client)(nil)
 
+// storedAuthorization is an authorization as it is stored: its token is
+// replaced by a salted hash and the prefix it is indexed by.
+type storedAuthorization struct {
+	platform.Authorization
+	TokenHash   string `json:"tokenHash,omitempty"`
+	TokenPrefix string `json:"tokenPrefix,omitempty"`
+
+	// The token replaced by the last rotation is accepted until
+	// PreviousTokenExpiresAt.
+	PreviousTokenHash      string     `json:"previousTokenHash,omitempty"`
+	PreviousTokenPrefix    string     `json:"previousTokenPrefix,omitempty"`
+	PreviousTokenExpiresAt *time.Time `json:"previousTokenExpiresAt,omitempty"`
+}
+
+// tokenPrefixes returns the prefixes the authorization is indexed by.
+func (a *storedAuthorization) tokenPrefixes() []string {
+	var prefixes []string
+	if a.TokenHash != "" {
+		prefixes = append(prefixes, a.TokenPrefix)
+	}
+	if a.PreviousTokenHash != "" && a.PreviousTokenPrefix != a.TokenPrefix {
+		prefixes = append(prefixes, a.PreviousTokenPrefix)
+	}
+	return prefixes
+}
+
+// matchToken reports whether token is the token of the authorization, or
+// the token it replaced if that is still accepted at now.
+func (a *storedAuthorization) matchToken(token string, now time.Time) bool {
+	if platform.CompareTokenHash(a.TokenHash, token) {
+		return true
+	}
+	return a.PreviousTokenExpiresAt != nil && now.Before(*a.PreviousTokenExpiresAt) &&
+		platform.CompareTokenHash(a.PreviousTokenHash, token)
+}
+
 func (c *Client) initializeAuthorizations(ctx context.Context, tx *bolt.Tx) error {
 	if _, err := tx.CreateBucketIfNotExists([]byte(authorizationBucket)); err != nil {
 		return err
@@ -22,6 +67,50 @@ func (c *Client) initializeAuthorizations(ctx context.Context, tx *bolt.Tx) erro
 	if _, err := tx.CreateBucketIfNotExists([]byte(authorizationIndex)); err != nil {
 		return err
 	}
+	if _, err := tx.CreateBucketIfNotExists(authorizationPrefixIndex); err != nil {
+		return err
+	}
+	return c.hashAuthorizationTokens(ctx, tx)
+}
+
+// hashAuthorizationTokens migrates authorizations stored with their token
+// in the clear: it replaces the token with its hash, moves the index entry
+// to the prefix index, and empties the old token index. It does nothing
+// once every token is hashed.
+func (c *Client) hashAuthorizationTokens(ctx context.Context, tx *bolt.Tx) error {
+	var plain []*storedAuthorization
+	cur := tx.Bucket(authorizationBucket).Cursor()
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		a := &storedAuthorization{}
+		if err := decodeAuthorization(v, a); err != nil {
+			return err
+		}
+		if a.Token != "" {
+			plain = append(plain, a)
+		}
+	}
+
+	for _, a := range plain {
+		if pe := c.putAuthorization(ctx, tx, &a.Authorization); pe != nil {
+			return pe
+		}
+	}
+
+	idx := tx.Bucket(authorizationIndex)
+	var keys [][]byte
+	cur = idx.Cursor()
+	for k, _ := cur.First(); k != nil; k, _ = cur.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		if err := idx.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	if len(plain) > 0 {
+		c.Logger.Info("Hashed authorization tokens", zap.Int("count", len(plain)))
+	}
 	return nil
 }
 
@@ -43,6 +132,14 @@ func (c *Client) FindAuthorizationByID(ctx context.Context, id platform.ID) (*pl
 }
 
 func (c *Client) findAuthorizationByID(ctx context.Context, tx *bolt.Tx, id platform.ID) (*platform.Authorization, *platform.Error) {
+	a, pe := c.findStoredAuthorization(ctx, tx, id)
+	if pe != nil {
+		return nil, pe
+	}
+	return &a.Authorization, nil
+}
+
+func (c *Client) findStoredAuthorization(ctx context.Context, tx *bolt.Tx, id platform.ID) (*storedAuthorization, *platform.Error) {
 	encodedID, err := id.Encode()
 	if err != nil {
 		return nil, &platform.Error{
@@ -51,7 +148,7 @@ func (c *Client) findAuthorizationByID(ctx context.Context, tx *bolt.Tx, id plat
 		}
 	}
 
-	var a platform.Authorization
+	var a storedAuthorization
 	v := tx.Bucket(authorizationBucket).Get(encodedID)
 
 	if len(v) == 0 {
@@ -89,43 +186,60 @@ func (c *Client) FindAuthorizationByToken(ctx context.Context, n string) (*platf
 }
 
 func (c *Client) findAuthorizationByToken(ctx context.Context, tx *bolt.Tx, n string) (*platform.Authorization, *platform.Error) {
-	a := tx.Bucket(authorizationIndex).Get(authorizationIndexKey(n))
-	if a == nil {
-		return nil, &platform.Error{
-			Code: platform.ENotFound,
-			Msg:  "authorization not found",
+	if n != "" {
+		// Tokens shorter than the prefix length are their own prefix, so
+		// only the keys of exactly this prefix and an ID are candidates.
+		prefix := []byte(platform.TokenPrefix(n))
+		cur := tx.Bucket(authorizationPrefixIndex).Cursor()
+		for k, v := cur.Seek(prefix); bytes.HasPrefix(k, prefix); k, v = cur.Next() {
+			if len(k) != len(prefix)+platform.IDLength {
+				continue
+			}
+
+			var id platform.ID
+			if err := id.Decode(v); err != nil {
+				return nil, &platform.Error{
+					Code: platform.EInvalid,
+					Err:  err,
+				}
+			}
+
+			a, pe := c.findStoredAuthorization(ctx, tx, id)
+			if pe != nil {
+				return nil, pe
+			}
+			if a.matchToken(n, c.time()) {
+				return &a.Authorization, nil
+			}
 		}
 	}
-	var id platform.ID
-	if err := id.Decode(a); err != nil {
-		return nil, &platform.Error{
-			Code: platform.EInvalid,
-			Err:  err,
-		}
+
+	return nil, &platform.Error{
+		Code: platform.ENotFound,
+		Msg:  "authorization not found",
 	}
-	return c.findAuthorizationByID(ctx, tx, id)
 }
 
-func filterAuthorizationsFn(filter platform.AuthorizationFilter) func(a *platform.Authorization) bool {
+func filterAuthorizationsFn(filter platform.AuthorizationFilter, now time.Time) func(a *storedAuthorization) bool {
 	if filter.ID != nil {
-		return func(a *platform.Authorization) bool {
+		return func(a *storedAuthorization) bool {
 			return a.ID == *filter.ID
 		}
 	}
 
 	if filter.Token != nil {
-		return func(a *platform.Authorization) bool {
-			return a.Token == *filter.Token
+		return func(a *storedAuthorization) bool {
+			return a.matchToken(*filter.Token, now)
 		}
 	}
 
 	if filter.UserID != nil {
-		return func(a *platform.Authorization) bool {
+		return func(a *storedAuthorization) bool {
 			return a.UserID == *filter.UserID
 		}
 	}
 
-	return func(a *platform.Authorization) bool { return true }
+	return func(a *storedAuthorization) bool { return true }
 }
 
 // FindAuthorizations retrives all authorizations that match an arbitrary authorization filter.
@@ -187,10 +301,10 @@ func (c *Client) findAuthorizations(ctx context.Context, tx *bolt.Tx, f platform
 	}
 
 	as := []*platform.Authorization{}
-	filterFn := filterAuthorizationsFn(f)
-	err := c.forEachAuthorization(ctx, tx, func(a *platform.Authorization) bool {
+	filterFn := filterAuthorizationsFn(f, c.time())
+	err := c.forEachAuthorization(ctx, tx, func(a *storedAuthorization) bool {
 		if filterFn(a) {
-			as = append(as, a)
+			as = append(as, &a.Authorization)
 		}
 		return true
 	})
@@ -202,6 +316,7 @@ func (c *Client) findAuthorizations(ctx context.Context, tx *bolt.Tx, f platform
 }
 
 // CreateAuthorization creates a platform authorization and sets b.ID, and b.UserID if not provided.
+// Only a hash of the token is stored, so a.Token is the only time the token can be read.
 func (c *Client) CreateAuthorization(ctx context.Context, a *platform.Authorization) error {
 	op := getOp(platform.OpCreateAuthorization)
 	if err := a.Valid(); err != nil {
@@ -222,10 +337,6 @@ func (c *Client) CreateAuthorization(ctx context.Context, a *platform.Authorizat
 			return platform.ErrUnableToCreateToken
 		}
 
-		if unique := c.uniqueAuthorizationToken(ctx, tx, a); !unique {
-			return platform.ErrUnableToCreateToken
-		}
-
 		if a.Token == "" {
 			token, err := c.TokenGenerator.Token()
 			if err != nil {
@@ -237,6 +348,10 @@ func (c *Client) CreateAuthorization(ctx context.Context, a *platform.Authorizat
 			a.Token = token
 		}
 
+		if unique := c.uniqueAuthorizationToken(ctx, tx, a); !unique {
+			return platform.ErrUnableToCreateToken
+		}
+
 		a.ID = c.IDGenerator.ID()
 
 		pe := c.putAuthorization(ctx, tx, a)
@@ -250,6 +365,7 @@ func (c *Client) CreateAuthorization(ctx context.Context, a *platform.Authorizat
 }
 
 // PutAuthorization will put a authorization without setting an ID.
+// The token of a is stored hashed; if it is empty the stored hash is kept.
 func (c *Client) PutAuthorization(ctx context.Context, a *platform.Authorization) (err error) {
 	return c.db.Update(func(tx *bolt.Tx) error {
 		pe := c.putAuthorization(ctx, tx, a)
@@ -260,7 +376,7 @@ func (c *Client) PutAuthorization(ctx context.Context, a *platform.Authorization
 	})
 }
 
-func encodeAuthorization(a *platform.Authorization) ([]byte, error) {
+func encodeAuthorization(a *storedAuthorization) ([]byte, error) {
 	switch a.Status {
 	case platform.Active, platform.Inactive:
 	case "":
@@ -276,6 +392,45 @@ func encodeAuthorization(a *platform.Authorization) ([]byte, error) {
 }
 
 func (c *Client) putAuthorization(ctx context.Context, tx *bolt.Tx, a *platform.Authorization) *platform.Error {
+	if a.Status == "" {
+		a.Status = platform.Active
+	}
+
+	if _, err := a.ID.Encode(); err != nil {
+		return &platform.Error{
+			Code: platform.ENotFound,
+			Err:  err,
+		}
+	}
+
+	prev, pe := c.findStoredAuthorization(ctx, tx, a.ID)
+	if pe != nil && pe.Code != platform.ENotFound {
+		return pe
+	}
+
+	sa := &storedAuthorization{Authorization: *a}
+	sa.Token = ""
+	if a.Token != "" {
+		hash, err := platform.HashToken(a.Token)
+		if err != nil {
+			return &platform.Error{
+				Code: platform.EInternal,
+				Err:  err,
+			}
+		}
+		sa.TokenHash, sa.TokenPrefix = hash, platform.TokenPrefix(a.Token)
+	} else if prev != nil {
+		sa.TokenHash, sa.TokenPrefix = prev.TokenHash, prev.TokenPrefix
+		sa.PreviousTokenHash, sa.PreviousTokenPrefix = prev.PreviousTokenHash, prev.PreviousTokenPrefix
+		sa.PreviousTokenExpiresAt = prev.PreviousTokenExpiresAt
+	}
+
+	return c.putStoredAuthorization(ctx, tx, sa, prev)
+}
+
+// putStoredAuthorization stores a in place of prev, which is nil if a is new,
+// and indexes it by the prefixes of its tokens.
+func (c *Client) putStoredAuthorization(ctx context.Context, tx *bolt.Tx, a, prev *storedAuthorization) *platform.Error {
 	v, err := encodeAuthorization(a)
 	if err != nil {
 		return &platform.Error{
@@ -292,10 +447,28 @@ func (c *Client) putAuthorization(ctx context.Context, tx *bolt.Tx, a *platform.
 		}
 	}
 
-	if err := tx.Bucket(authorizationIndex).Put(authorizationIndexKey(a.Token), encodedID); err != nil {
-		return &platform.Error{
-			Code: platform.EInternal,
-			Err:  err,
+	idx := tx.Bucket(authorizationPrefixIndex)
+	prefixes := a.tokenPrefixes()
+	if prev != nil {
+		for _, p := range prev.tokenPrefixes() {
+			if containsString(prefixes, p) {
+				continue
+			}
+			if err := idx.Delete(authorizationPrefixIndexKey(p, encodedID)); err != nil {
+				return &platform.Error{
+					Code: platform.EInternal,
+					Err:  err,
+				}
+			}
+		}
+	}
+
+	for _, p := range prefixes {
+		if err := idx.Put(authorizationPrefixIndexKey(p, encodedID), encodedID); err != nil {
+			return &platform.Error{
+				Code: platform.EInternal,
+				Err:  err,
+			}
 		}
 	}
 
@@ -308,11 +481,22 @@ func (c *Client) putAuthorization(ctx context.Context, tx *bolt.Tx, a *platform.
 	return nil
 }
 
-func authorizationIndexKey(n string) []byte {
-	return []byte(n)
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func authorizationPrefixIndexKey(prefix string, encodedID []byte) []byte {
+	k := make([]byte, 0, len(prefix)+len(encodedID))
+	k = append(k, prefix...)
+	return append(k, encodedID...)
 }
 
-func decodeAuthorization(b []byte, a *platform.Authorization) error {
+func decodeAuthorization(b []byte, a *storedAuthorization) error {
 	if err := json.Unmarshal(b, a); err != nil {
 		return err
 	}
@@ -323,10 +507,10 @@ func decodeAuthorization(b []byte, a *platform.Authorization) error {
 }
 
 // forEachAuthorization will iterate through all authorizations while fn returns true.
-func (c *Client) forEachAuthorization(ctx context.Context, tx *bolt.Tx, fn func(*platform.Authorization) bool) error {
+func (c *Client) forEachAuthorization(ctx context.Context, tx *bolt.Tx, fn func(*storedAuthorization) bool) error {
 	cur := tx.Bucket(authorizationBucket).Cursor()
 	for k, v := cur.First(); k != nil; k, v = cur.Next() {
-		a := &platform.Authorization{}
+		a := &storedAuthorization{}
 
 		if err := decodeAuthorization(v, a); err != nil {
 			return err
@@ -340,8 +524,8 @@ func (c *Client) forEachAuthorization(ctx context.Context, tx *bolt.Tx, fn func(
 }
 
 func (c *Client) uniqueAuthorizationToken(ctx context.Context, tx *bolt.Tx, a *platform.Authorization) bool {
-	v := tx.Bucket(authorizationIndex).Get(authorizationIndexKey(a.Token))
-	return len(v) == 0
+	_, pe := c.findAuthorizationByToken(ctx, tx, a.Token)
+	return pe != nil
 }
 
 // DeleteAuthorization deletes a authorization and prunes it from the index.
@@ -358,21 +542,23 @@ func (c *Client) DeleteAuthorization(ctx context.Context, id platform.ID) error
 }
 
 func (c *Client) deleteAuthorization(ctx context.Context, tx *bolt.Tx, id platform.ID) *platform.Error {
-	a, pe := c.findAuthorizationByID(ctx, tx, id)
+	a, pe := c.findStoredAuthorization(ctx, tx, id)
 	if pe != nil {
 		return pe
 	}
-	if err := tx.Bucket(authorizationIndex).Delete(authorizationIndexKey(a.Token)); err != nil {
-		return &platform.Error{
-			Err: err,
-		}
-	}
 	encodedID, err := id.Encode()
 	if err != nil {
 		return &platform.Error{
 			Err: err,
 		}
 	}
+	for _, p := range a.tokenPrefixes() {
+		if err := tx.Bucket(authorizationPrefixIndex).Delete(authorizationPrefixIndexKey(p, encodedID)); err != nil {
+			return &platform.Error{
+				Err: err,
+			}
+		}
+	}
 
 	if err := tx.Bucket(authorizationBucket).Delete(encodedID); err != nil {
 		return &platform.Error{
@@ -397,7 +583,7 @@ func (c *Client) SetAuthorizationStatus(ctx context.Context, id platform.ID, sta
 }
 
 func (c *Client) updateAuthorization(ctx context.Context, tx *bolt.Tx, id platform.ID, status platform.Status) *platform.Error {
-	a, pe := c.findAuthorizationByID(ctx, tx, id)
+	a, pe := c.findStoredAuthorization(ctx, tx, id)
 	if pe != nil {
 		return pe
 	}
@@ -424,3 +610,91 @@ func (c *Client) updateAuthorization(ctx context.Context, tx *bolt.Tx, id platfo
 	}
 	return nil
 }
+
+// UpdateAuthorization updates the status, description, expiry or last use
+// of the authorization.
+func (c *Client) UpdateAuthorization(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+	var a *platform.Authorization
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		prev, pe := c.findStoredAuthorization(ctx, tx, id)
+		if pe != nil {
+			return pe
+		}
+
+		sa := *prev
+		if err := upd.Apply(&sa.Authorization); err != nil {
+			return err
+		}
+
+		if pe := c.putStoredAuthorization(ctx, tx, &sa, prev); pe != nil {
+			return pe
+		}
+
+		a = &sa.Authorization
+		return nil
+	})
+
+	if err != nil {
+		return nil, &platform.Error{
+			Err: err,
+			Op:  getOp(platform.OpUpdateAuthorization),
+		}
+	}
+
+	return a, nil
+}
+
+// RotateAuthorization replaces the token of the authorization with a new
+// one, returned in the Token of the result. The old token keeps working
+// for the grace period.
+func (c *Client) RotateAuthorization(ctx context.Context, id platform.ID, grace time.Duration) (*platform.Authorization, error) {
+	var a *platform.Authorization
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		prev, pe := c.findStoredAuthorization(ctx, tx, id)
+		if pe != nil {
+			return pe
+		}
+
+		token, err := c.TokenGenerator.Token()
+		if err != nil {
+			return err
+		}
+		if unique := c.uniqueAuthorizationToken(ctx, tx, &platform.Authorization{Token: token}); !unique {
+			return platform.ErrUnableToCreateToken
+		}
+
+		hash, err := platform.HashToken(token)
+		if err != nil {
+			return &platform.Error{
+				Code: platform.EInternal,
+				Err:  err,
+			}
+		}
+
+		sa := *prev
+		sa.TokenHash, sa.TokenPrefix = hash, platform.TokenPrefix(token)
+		sa.PreviousTokenHash, sa.PreviousTokenPrefix, sa.PreviousTokenExpiresAt = "", "", nil
+		if grace > 0 {
+			expiresAt := c.time().Add(grace)
+			sa.PreviousTokenHash, sa.PreviousTokenPrefix = prev.TokenHash, prev.TokenPrefix
+			sa.PreviousTokenExpiresAt = &expiresAt
+		}
+
+		if pe := c.putStoredAuthorization(ctx, tx, &sa, prev); pe != nil {
+			return pe
+		}
+
+		a = &sa.Authorization
+		a.Token = token
+		return nil
+	})
+
+	if err != nil {
+		return nil, &platform.Error{
+			Err: err,
+			Op:  getOp(platform.OpRotateAuthorization),
+		}
+	}
+
+	return a, nil
+}
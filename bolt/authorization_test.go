@@ -1,9 +1,12 @@
 package bolt_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"testing"
 
+	bbolt "github.com/coreos/bbolt"
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/bolt"
 	platformtesting "github.com/influxdata/influxdb/testing"
@@ -61,3 +64,67 @@ func initAuthorizationService(f platformtesting.AuthorizationFields, t *testing.
 func TestAuthorizationService(t *testing.T) {
 	platformtesting.AuthorizationService(initAuthorizationService, t)
 }
+
+func TestClient_HashAuthorizationTokens(t *testing.T) {
+	c, closeFn, err := NewTestClient()
+	if err != nil {
+		t.Fatalf("failed to create new bolt client: %v", err)
+	}
+	defer closeFn()
+
+	// Store an authorization as it was stored before tokens were hashed.
+	legacy := &platform.Authorization{
+		ID:     platformtesting.MustIDBase16("020f755c3c082000"),
+		OrgID:  platformtesting.MustIDBase16("41a9f7288d4e2d64"),
+		UserID: platformtesting.MustIDBase16("020f755c3c082000"),
+		Token:  "legacy-token",
+		Status: platform.Active,
+	}
+	id, _ := legacy.ID.Encode()
+	err = c.DB().Update(func(tx *bbolt.Tx) error {
+		v, err := json.Marshal(legacy)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket([]byte("authorizationsv1")).Put(id, v); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("authorizationindexv1")).Put([]byte(legacy.Token), id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening the client migrates the token, and opening it again
+	// leaves it as it is.
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Open(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := c.FindAuthorizationByToken(ctx, "legacy-token")
+		if err != nil {
+			t.Fatalf("failed to find migrated authorization by token: %v", err)
+		}
+		if a.ID != legacy.ID || a.Token != "" {
+			t.Fatalf("unexpected migrated authorization: %+v", a)
+		}
+	}
+
+	err = c.DB().View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket([]byte("authorizationsv1")).Get(id); bytes.Contains(v, []byte(legacy.Token)) {
+			t.Errorf("token stored in the clear: %s", v)
+		}
+		if v := tx.Bucket([]byte("authorizationindexv1")).Get([]byte(legacy.Token)); v != nil {
+			t.Error("expected token index entry to be removed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
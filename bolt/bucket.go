@@ -349,6 +349,13 @@ func (c *Client) CreateBucket(ctx context.Context, b *platform.Bucket) error {
 
 	var err error
 	op := getOp(platform.OpCreateBucket)
+	if err := platform.ValidateDefaultTags(b.DefaultTags); err != nil {
+		return &platform.Error{
+			Op:  op,
+			Err: err,
+		}
+	}
+
 	return c.db.Update(func(tx *bolt.Tx) error {
 		if b.OrganizationID.Valid() {
 			_, pe := c.findOrganizationByID(ctx, tx, b.OrganizationID)
@@ -555,6 +562,13 @@ func (c *Client) updateBucket(ctx context.Context, tx *bolt.Tx, id platform.ID,
 		b.RetentionPeriod = *upd.RetentionPeriod
 	}
 
+	if upd.DefaultTags != nil {
+		if err := platform.ValidateDefaultTags(*upd.DefaultTags); err != nil {
+			return nil, err
+		}
+		b.DefaultTags = *upd.DefaultTags
+	}
+
 	if upd.Name != nil {
 		b0, err := c.findBucketByName(ctx, tx, b.OrganizationID, *upd.Name)
 		if err == nil && b0.ID != id {
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/influxdata/influxdb/models"
 )
 
 // BucketType defines known system-buckets.
@@ -26,6 +28,10 @@ type Bucket struct {
 	Name                string        `json:"name"`
 	RetentionPolicyName string        `json:"rp,omitempty"` // This to support v1 sources
 	RetentionPeriod     time.Duration `json:"retentionPeriod"`
+
+	// DefaultTags are added to the points written to the bucket that do not
+	// have tags with the same keys.
+	DefaultTags map[string]string `json:"defaultTags,omitempty"`
 }
 
 // ops for buckets error and buckets op logs.
@@ -64,8 +70,28 @@ type BucketService interface {
 // BucketUpdate represents updates to a bucket.
 // Only fields which are set are updated.
 type BucketUpdate struct {
-	Name            *string        `json:"name,omitempty"`
-	RetentionPeriod *time.Duration `json:"retentionPeriod,omitempty"`
+	Name            *string            `json:"name,omitempty"`
+	RetentionPeriod *time.Duration     `json:"retentionPeriod,omitempty"`
+	DefaultTags     *map[string]string `json:"defaultTags,omitempty"`
+}
+
+// ValidateDefaultTags returns an error if tags cannot be added to points.
+func ValidateDefaultTags(tags map[string]string) error {
+	for k, v := range tags {
+		if k == "" || v == "" {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("default tag %q=%q must have a key and a value", k, v),
+			}
+		}
+		if !models.ValidToken([]byte(k)) || !models.ValidToken([]byte(v)) {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("default tag %q=%q must only contain printable characters", k, v),
+			}
+		}
+	}
+	return nil
 }
 
 // BucketFilter represents a set of filter that restrict the returned results.
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
+	"sort"
+	"time"
 
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/bolt"
@@ -46,6 +49,8 @@ type AuthorizationCreateFlags struct {
 
 	writeDashboardsPermission bool
 	readDashboardsPermission  bool
+
+	expiresIn time.Duration
 }
 
 var authorizationCreateFlags AuthorizationCreateFlags
@@ -83,6 +88,8 @@ func init() {
 	authorizationCreateCmd.Flags().BoolVarP(&authorizationCreateFlags.writeDashboardsPermission, "write-dashboards", "", false, "Grants the permission to create dashboards")
 	authorizationCreateCmd.Flags().BoolVarP(&authorizationCreateFlags.readDashboardsPermission, "read-dashboards", "", false, "Grants the permission to read dashboards")
 
+	authorizationCreateCmd.Flags().DurationVarP(&authorizationCreateFlags.expiresIn, "expires-in", "", 0, "The duration after which the token expires; it never expires if unset")
+
 	authorizationCmd.AddCommand(authorizationCreateCmd)
 }
 
@@ -227,6 +234,13 @@ func authorizationCreateF(cmd *cobra.Command, args []string) error {
 		Permissions: permissions,
 		OrgID:       o.ID,
 	}
+	if authorizationCreateFlags.expiresIn < 0 {
+		return errors.New("expires-in must not be negative")
+	}
+	if authorizationCreateFlags.expiresIn > 0 {
+		expiresAt := time.Now().Add(authorizationCreateFlags.expiresIn).UTC()
+		authorization.ExpiresAt = &expiresAt
+	}
 
 	s, err := newAuthorizationService(flags)
 	if err != nil {
@@ -242,6 +256,7 @@ func authorizationCreateF(cmd *cobra.Command, args []string) error {
 		"ID",
 		"Token",
 		"Status",
+		"ExpiresAt",
 		"UserID",
 		"Permissions",
 	)
@@ -255,6 +270,7 @@ func authorizationCreateF(cmd *cobra.Command, args []string) error {
 		"ID":          authorization.ID.String(),
 		"Token":       authorization.Token,
 		"Status":      authorization.Status,
+		"ExpiresAt":   formatAuthorizationTime(authorization.ExpiresAt),
 		"UserID":      authorization.UserID.String(),
 		"Permissions": ps,
 	})
@@ -340,8 +356,9 @@ func authorizationFindF(cmd *cobra.Command, args []string) error {
 	w := internal.NewTabWriter(os.Stdout)
 	w.WriteHeaders(
 		"ID",
-		"Token",
 		"Status",
+		"ExpiresAt",
+		"LastUsedAt",
 		"User",
 		"UserID",
 		"Permissions",
@@ -355,8 +372,9 @@ func authorizationFindF(cmd *cobra.Command, args []string) error {
 
 		w.Write(map[string]interface{}{
 			"ID":          a.ID,
-			"Token":       a.Token,
 			"Status":      a.Status,
+			"ExpiresAt":   formatAuthorizationTime(a.ExpiresAt),
+			"LastUsedAt":  formatAuthorizationTime(a.LastUsedAt),
 			"UserID":      a.UserID.String(),
 			"Permissions": permissions,
 		})
@@ -411,7 +429,6 @@ func authorizationDeleteF(cmd *cobra.Command, args []string) error {
 	w := internal.NewTabWriter(os.Stdout)
 	w.WriteHeaders(
 		"ID",
-		"Token",
 		"User",
 		"UserID",
 		"Permissions",
@@ -425,7 +442,6 @@ func authorizationDeleteF(cmd *cobra.Command, args []string) error {
 
 	w.Write(map[string]interface{}{
 		"ID":          a.ID.String(),
-		"Token":       a.Token,
 		"UserID":      a.UserID.String(),
 		"Permissions": ps,
 		"Deleted":     true,
@@ -480,7 +496,6 @@ func authorizationActiveF(cmd *cobra.Command, args []string) error {
 	w := internal.NewTabWriter(os.Stdout)
 	w.WriteHeaders(
 		"ID",
-		"Token",
 		"Status",
 		"User",
 		"UserID",
@@ -494,7 +509,6 @@ func authorizationActiveF(cmd *cobra.Command, args []string) error {
 
 	w.Write(map[string]interface{}{
 		"ID":          a.ID.String(),
-		"Token":       a.Token,
 		"Status":      a.Status,
 		"UserID":      a.UserID.String(),
 		"Permissions": ps,
@@ -549,7 +563,6 @@ func authorizationInactiveF(cmd *cobra.Command, args []string) error {
 	w := internal.NewTabWriter(os.Stdout)
 	w.WriteHeaders(
 		"ID",
-		"Token",
 		"Status",
 		"User",
 		"UserID",
@@ -563,7 +576,6 @@ func authorizationInactiveF(cmd *cobra.Command, args []string) error {
 
 	w.Write(map[string]interface{}{
 		"ID":          a.ID.String(),
-		"Token":       a.Token,
 		"Status":      a.Status,
 		"UserID":      a.UserID.String(),
 		"Permissions": ps,
@@ -573,3 +585,235 @@ func authorizationInactiveF(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// AuthorizationExpireFlags are command line args used when setting the expiry of an authorization
+type AuthorizationExpireFlags struct {
+	id    string
+	in    time.Duration
+	at    string
+	never bool
+}
+
+var authorizationExpireFlags AuthorizationExpireFlags
+
+func init() {
+	authorizationExpireCmd := &cobra.Command{
+		Use:   "expire",
+		Short: "Set when an authorization expires",
+		RunE:  wrapCheckSetup(authorizationExpireF),
+	}
+
+	authorizationExpireCmd.Flags().StringVarP(&authorizationExpireFlags.id, "id", "i", "", "The authorization ID (required)")
+	authorizationExpireCmd.MarkFlagRequired("id")
+	authorizationExpireCmd.Flags().DurationVarP(&authorizationExpireFlags.in, "in", "", 0, "The duration from now after which the token expires")
+	authorizationExpireCmd.Flags().StringVarP(&authorizationExpireFlags.at, "at", "", "", "The time at which the token expires, in RFC3339 format")
+	authorizationExpireCmd.Flags().BoolVarP(&authorizationExpireFlags.never, "never", "", false, "Remove the expiry of the token")
+
+	authorizationCmd.AddCommand(authorizationExpireCmd)
+}
+
+func authorizationExpireF(cmd *cobra.Command, args []string) error {
+	var expiresAt time.Time
+	switch {
+	case authorizationExpireFlags.never:
+		if authorizationExpireFlags.in != 0 || authorizationExpireFlags.at != "" {
+			return errors.New("never cannot be combined with in or at")
+		}
+	case authorizationExpireFlags.in != 0:
+		if authorizationExpireFlags.at != "" {
+			return errors.New("in cannot be combined with at")
+		}
+		if authorizationExpireFlags.in < 0 {
+			return errors.New("in must not be negative")
+		}
+		expiresAt = time.Now().Add(authorizationExpireFlags.in).UTC()
+	case authorizationExpireFlags.at != "":
+		t, err := time.Parse(time.RFC3339, authorizationExpireFlags.at)
+		if err != nil {
+			return err
+		}
+		expiresAt = t.UTC()
+	default:
+		return errors.New("one of in, at or never is required")
+	}
+
+	s, err := newAuthorizationService(flags)
+	if err != nil {
+		return err
+	}
+
+	var id platform.ID
+	if err := id.DecodeFromString(authorizationExpireFlags.id); err != nil {
+		return err
+	}
+
+	a, err := s.UpdateAuthorization(context.Background(), id, &platform.AuthorizationUpdate{ExpiresAt: &expiresAt})
+	if err != nil {
+		return err
+	}
+
+	w := internal.NewTabWriter(os.Stdout)
+	w.WriteHeaders(
+		"ID",
+		"Status",
+		"ExpiresAt",
+		"UserID",
+	)
+
+	w.Write(map[string]interface{}{
+		"ID":        a.ID.String(),
+		"Status":    a.Status,
+		"ExpiresAt": formatAuthorizationTime(a.ExpiresAt),
+		"UserID":    a.UserID.String(),
+	})
+
+	w.Flush()
+
+	return nil
+}
+
+// AuthorizationRotateFlags are command line args used when rotating the token of an authorization
+type AuthorizationRotateFlags struct {
+	id    string
+	grace time.Duration
+}
+
+var authorizationRotateFlags AuthorizationRotateFlags
+
+func init() {
+	authorizationRotateCmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Replace the token of an authorization",
+		RunE:  wrapCheckSetup(authorizationRotateF),
+	}
+
+	authorizationRotateCmd.Flags().StringVarP(&authorizationRotateFlags.id, "id", "i", "", "The authorization ID (required)")
+	authorizationRotateCmd.MarkFlagRequired("id")
+	authorizationRotateCmd.Flags().DurationVarP(&authorizationRotateFlags.grace, "grace", "", 0, "The duration for which the previous token remains valid")
+
+	authorizationCmd.AddCommand(authorizationRotateCmd)
+}
+
+func authorizationRotateF(cmd *cobra.Command, args []string) error {
+	if authorizationRotateFlags.grace < 0 {
+		return errors.New("grace must not be negative")
+	}
+
+	s, err := newAuthorizationService(flags)
+	if err != nil {
+		return err
+	}
+
+	var id platform.ID
+	if err := id.DecodeFromString(authorizationRotateFlags.id); err != nil {
+		return err
+	}
+
+	a, err := s.RotateAuthorization(context.Background(), id, authorizationRotateFlags.grace)
+	if err != nil {
+		return err
+	}
+
+	w := internal.NewTabWriter(os.Stdout)
+	w.WriteHeaders(
+		"ID",
+		"Token",
+		"Status",
+		"ExpiresAt",
+		"UserID",
+	)
+
+	w.Write(map[string]interface{}{
+		"ID":        a.ID.String(),
+		"Token":     a.Token,
+		"Status":    a.Status,
+		"ExpiresAt": formatAuthorizationTime(a.ExpiresAt),
+		"UserID":    a.UserID.String(),
+	})
+
+	w.Flush()
+
+	return nil
+}
+
+// AuthorizationLastUsedFlags are command line args used when listing when authorizations were last used
+type AuthorizationLastUsedFlags struct {
+	unusedFor time.Duration
+}
+
+var authorizationLastUsedFlags AuthorizationLastUsedFlags
+
+func init() {
+	authorizationLastUsedCmd := &cobra.Command{
+		Use:   "last-used",
+		Short: "List when authorizations were last used, least recently used first",
+		RunE:  wrapCheckSetup(authorizationLastUsedF),
+	}
+
+	authorizationLastUsedCmd.Flags().DurationVarP(&authorizationLastUsedFlags.unusedFor, "unused-for", "", 0, "Only list authorizations not used for at least this duration")
+
+	authorizationCmd.AddCommand(authorizationLastUsedCmd)
+}
+
+func authorizationLastUsedF(cmd *cobra.Command, args []string) error {
+	s, err := newAuthorizationService(flags)
+	if err != nil {
+		return err
+	}
+
+	authorizations, _, err := s.FindAuthorizations(context.Background(), platform.AuthorizationFilter{})
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-authorizationLastUsedFlags.unusedFor)
+	unused := authorizations[:0]
+	for _, a := range authorizations {
+		if a.LastUsedAt == nil || !a.LastUsedAt.After(cutoff) {
+			unused = append(unused, a)
+		}
+	}
+
+	// Authorizations never used sort first.
+	sort.SliceStable(unused, func(i, j int) bool {
+		ti, tj := unused[i].LastUsedAt, unused[j].LastUsedAt
+		if ti == nil || tj == nil {
+			return ti == nil && tj != nil
+		}
+		return ti.Before(*tj)
+	})
+
+	w := internal.NewTabWriter(os.Stdout)
+	w.WriteHeaders(
+		"ID",
+		"Description",
+		"Status",
+		"LastUsedAt",
+		"ExpiresAt",
+		"UserID",
+	)
+
+	for _, a := range unused {
+		w.Write(map[string]interface{}{
+			"ID":          a.ID.String(),
+			"Description": a.Description,
+			"Status":      a.Status,
+			"LastUsedAt":  formatAuthorizationTime(a.LastUsedAt),
+			"ExpiresAt":   formatAuthorizationTime(a.ExpiresAt),
+			"UserID":      a.UserID.String(),
+		})
+	}
+
+	w.Flush()
+
+	return nil
+}
+
+// formatAuthorizationTime formats an optional authorization time, with an
+// empty string for an unset time.
+func formatAuthorizationTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
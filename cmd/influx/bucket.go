@@ -31,6 +31,7 @@ type BucketCreateFlags struct {
 	org       string
 	orgID     string
 	retention time.Duration
+	tags      []string
 }
 
 var bucketCreateFlags BucketCreateFlags
@@ -46,6 +47,7 @@ func init() {
 	bucketCreateCmd.Flags().DurationVarP(&bucketCreateFlags.retention, "retention", "r", 0, "Duration in nanoseconds data will live in bucket")
 	bucketCreateCmd.Flags().StringVarP(&bucketCreateFlags.org, "org", "o", "", "Name of the organization that owns the bucket")
 	bucketCreateCmd.Flags().StringVarP(&bucketCreateFlags.orgID, "org-id", "", "", "The ID of the organization that owns the bucket")
+	bucketCreateCmd.Flags().StringArrayVar(&bucketCreateFlags.tags, "default-tag", nil, "Tag as key=value added to the points written to the bucket that do not have a tag with the key; may be given more than once")
 	bucketCreateCmd.MarkFlagRequired("name")
 
 	bucketCmd.AddCommand(bucketCreateCmd)
@@ -82,9 +84,15 @@ func bucketCreateF(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to initialize bucket service client: %v", err)
 	}
 
+	tags, err := parseTags(bucketCreateFlags.tags)
+	if err != nil {
+		return err
+	}
+
 	b := &platform.Bucket{
 		Name:            bucketCreateFlags.name,
 		RetentionPeriod: bucketCreateFlags.retention,
+		DefaultTags:     tags,
 	}
 
 	if bucketCreateFlags.org != "" {
@@ -215,6 +223,7 @@ type BucketUpdateFlags struct {
 	id        string
 	name      string
 	retention time.Duration
+	tags      []string
 }
 
 var bucketUpdateFlags BucketUpdateFlags
@@ -229,6 +238,7 @@ func init() {
 	bucketUpdateCmd.Flags().StringVarP(&bucketUpdateFlags.id, "id", "i", "", "The bucket ID (required)")
 	bucketUpdateCmd.Flags().StringVarP(&bucketUpdateFlags.name, "name", "n", "", "New bucket name")
 	bucketUpdateCmd.Flags().DurationVarP(&bucketUpdateFlags.retention, "retention", "r", 0, "New duration data will live in bucket")
+	bucketUpdateCmd.Flags().StringArrayVar(&bucketUpdateFlags.tags, "default-tag", nil, "New default tag as key=value, replacing all default tags of the bucket; may be given more than once")
 	bucketUpdateCmd.MarkFlagRequired("id")
 
 	bucketCmd.AddCommand(bucketUpdateCmd)
@@ -252,6 +262,13 @@ func bucketUpdateF(cmd *cobra.Command, args []string) error {
 	if bucketUpdateFlags.retention != 0 {
 		update.RetentionPeriod = &bucketUpdateFlags.retention
 	}
+	if len(bucketUpdateFlags.tags) > 0 {
+		tags, err := parseTags(bucketUpdateFlags.tags)
+		if err != nil {
+			return err
+		}
+		update.DefaultTags = &tags
+	}
 
 	b, err := s.UpdateBucket(context.Background(), id, update)
 	if err != nil {
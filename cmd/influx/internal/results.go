@@ -0,0 +1,316 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/influxdb/models"
+)
+
+// Formats of query results.
+const (
+	FormatTable        = "table"
+	FormatCSV          = "csv"
+	FormatJSON         = "json"
+	FormatLineProtocol = "line-protocol"
+)
+
+// ResultFormats lists the formats of query results.
+var ResultFormats = []string{FormatTable, FormatCSV, FormatJSON, FormatLineProtocol}
+
+// WriteResults writes the tables of query results to w in a format:
+//
+//   - table prints each table with its group key, for reading;
+//   - csv writes a header row, then a row per record, with the result and
+//     table of each record in the first columns. Tables with different
+//     columns are preceded by a new header row;
+//   - json writes an object per line for each record, with the result and
+//     table of the record;
+//   - line-protocol writes a point per record, with the string columns of
+//     the group key as tags. The field is named by the _field column and
+//     valued by the _value column, or if there is none, every column not in
+//     the group key and not starting with an underscore is a field.
+func WriteResults(w io.Writer, format string, results flux.ResultIterator) error {
+	var rw resultWriter
+	switch format {
+	case FormatTable:
+		rw = &tableResultWriter{w: w}
+	case FormatCSV:
+		rw = &csvResultWriter{w: csv.NewWriter(w)}
+	case FormatJSON:
+		rw = &jsonResultWriter{enc: json.NewEncoder(w)}
+	case FormatLineProtocol:
+		rw = &lineProtocolResultWriter{w: w}
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	for results.More() {
+		res := results.Next()
+		if err := rw.writeResult(res); err != nil {
+			return err
+		}
+	}
+	if err := results.Err(); err != nil {
+		return err
+	}
+	return rw.flush()
+}
+
+type resultWriter interface {
+	writeResult(flux.Result) error
+	flush() error
+}
+
+// tableResultWriter writes results as the flux REPL prints them.
+type tableResultWriter struct {
+	w io.Writer
+}
+
+func (rw *tableResultWriter) writeResult(res flux.Result) error {
+	if _, err := fmt.Fprintln(rw.w, "Result:", res.Name()); err != nil {
+		return err
+	}
+	return res.Tables().Do(func(tbl flux.Table) error {
+		_, err := execute.NewFormatter(tbl, nil).WriteTo(rw.w)
+		return err
+	})
+}
+
+func (rw *tableResultWriter) flush() error { return nil }
+
+type csvResultWriter struct {
+	w      *csv.Writer
+	header []string
+	record []string
+}
+
+func (rw *csvResultWriter) writeResult(res flux.Result) error {
+	table := 0
+	return res.Tables().Do(func(tbl flux.Table) error {
+		defer func() { table++ }()
+		return tbl.Do(func(cr flux.ColReader) error {
+			cols := cr.Cols()
+			if !rw.sameHeader(cols) {
+				rw.header = append(rw.header[:0], "result", "table")
+				for _, c := range cols {
+					rw.header = append(rw.header, c.Label)
+				}
+				if err := rw.w.Write(rw.header); err != nil {
+					return err
+				}
+			}
+
+			for i := 0; i < cr.Len(); i++ {
+				rw.record = append(rw.record[:0], res.Name(), strconv.Itoa(table))
+				for j := range cols {
+					rw.record = append(rw.record, formatValue(colValue(cr, j, i)))
+				}
+				if err := rw.w.Write(rw.record); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (rw *csvResultWriter) sameHeader(cols []flux.ColMeta) bool {
+	if len(rw.header) != len(cols)+2 {
+		return false
+	}
+	for j, c := range cols {
+		if rw.header[j+2] != c.Label {
+			return false
+		}
+	}
+	return true
+}
+
+func (rw *csvResultWriter) flush() error {
+	rw.w.Flush()
+	return rw.w.Error()
+}
+
+type jsonResultWriter struct {
+	enc *json.Encoder
+}
+
+func (rw *jsonResultWriter) writeResult(res flux.Result) error {
+	table := 0
+	return res.Tables().Do(func(tbl flux.Table) error {
+		defer func() { table++ }()
+		return tbl.Do(func(cr flux.ColReader) error {
+			cols := cr.Cols()
+			for i := 0; i < cr.Len(); i++ {
+				record := make(map[string]interface{}, len(cols)+2)
+				for j, c := range cols {
+					v := colValue(cr, j, i)
+					if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+						v = nil
+					}
+					record[c.Label] = v
+				}
+				record["result"], record["table"] = res.Name(), table
+				if err := rw.enc.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (rw *jsonResultWriter) flush() error { return nil }
+
+type lineProtocolResultWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (rw *lineProtocolResultWriter) writeResult(res flux.Result) error {
+	return res.Tables().Do(func(tbl flux.Table) error {
+		key := tbl.Key()
+		return tbl.Do(func(cr flux.ColReader) error {
+			cols := cr.Cols()
+			var (
+				measurement, timeCol, field, value = -1, -1, -1, -1
+				tags, fields                       []int
+			)
+			for j, c := range cols {
+				switch c.Label {
+				case "_measurement":
+					measurement = j
+				case "_time":
+					timeCol = j
+				case "_field":
+					field = j
+				case "_value":
+					value = j
+				case "_start", "_stop", "result", "table":
+				default:
+					if key.HasCol(c.Label) {
+						if c.Type == flux.TString {
+							tags = append(tags, j)
+						}
+					} else if c.Label != "" && c.Label[0] != '_' {
+						fields = append(fields, j)
+					}
+				}
+			}
+			if measurement < 0 {
+				return fmt.Errorf("cannot write table without a _measurement column as line protocol")
+			}
+
+			for i := 0; i < cr.Len(); i++ {
+				name, _ := colValue(cr, measurement, i).(string)
+				if name == "" {
+					continue
+				}
+
+				var pointTags models.Tags
+				for _, j := range tags {
+					if v, _ := colValue(cr, j, i).(string); v != "" {
+						pointTags = append(pointTags, models.NewTag([]byte(cols[j].Label), []byte(v)))
+					}
+				}
+
+				sort.Sort(pointTags)
+
+				pointFields := make(models.Fields)
+				if field >= 0 && value >= 0 {
+					k, _ := colValue(cr, field, i).(string)
+					if v := colValue(cr, value, i); k != "" && v != nil {
+						pointFields[k] = v
+					}
+				} else {
+					for _, j := range fields {
+						if v := colValue(cr, j, i); v != nil {
+							pointFields[cols[j].Label] = v
+						}
+					}
+				}
+				if len(pointFields) == 0 {
+					continue
+				}
+
+				var ts time.Time
+				if timeCol >= 0 {
+					ts, _ = colValue(cr, timeCol, i).(time.Time)
+				}
+
+				pt, err := models.NewPoint(name, pointTags, pointFields, ts)
+				if err != nil {
+					return err
+				}
+				rw.buf = append(pt.AppendString(rw.buf[:0]), '\n')
+				if _, err := rw.w.Write(rw.buf); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (rw *lineProtocolResultWriter) flush() error { return nil }
+
+// colValue returns the value of column j of row i, or nil if it is null.
+func colValue(cr flux.ColReader, j, i int) interface{} {
+	switch cr.Cols()[j].Type {
+	case flux.TBool:
+		if a := cr.Bools(j); !a.IsNull(i) {
+			return a.Value(i)
+		}
+	case flux.TInt:
+		if a := cr.Ints(j); !a.IsNull(i) {
+			return a.Value(i)
+		}
+	case flux.TUInt:
+		if a := cr.UInts(j); !a.IsNull(i) {
+			return a.Value(i)
+		}
+	case flux.TFloat:
+		if a := cr.Floats(j); !a.IsNull(i) {
+			return a.Value(i)
+		}
+	case flux.TString:
+		if a := cr.Strings(j); !a.IsNull(i) {
+			return a.ValueString(i)
+		}
+	case flux.TTime:
+		if a := cr.Times(j); !a.IsNull(i) {
+			return time.Unix(0, a.Value(i)).UTC()
+		}
+	}
+	return nil
+}
+
+// formatValue returns the text of a value in CSV.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,105 @@
+package internal_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
+)
+
+func TestWriteResults(t *testing.T) {
+	results := func() flux.ResultIterator {
+		tables := []*executetest.Table{
+			{
+				KeyCols: []string{"_measurement", "_field", "host"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "_field", Type: flux.TString},
+					{Label: "_measurement", Type: flux.TString},
+					{Label: "host", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(10), 1.5, "usage", "cpu", "a"},
+					{execute.Time(20), nil, "usage", "cpu", "a"},
+				},
+			},
+			{
+				KeyCols: []string{"_measurement", "_field", "host"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "_field", Type: flux.TString},
+					{Label: "_measurement", Type: flux.TString},
+					{Label: "host", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{execute.Time(10), 2.0, "usage", "cpu", "b"},
+				},
+			},
+			{
+				KeyCols: []string{"_measurement"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_measurement", Type: flux.TString},
+					{Label: "free", Type: flux.TInt},
+					{Label: "ok", Type: flux.TBool},
+					{Label: "msg", Type: flux.TString},
+				},
+				Data: [][]interface{}{
+					{"mem", int64(3), true, "a,b"},
+				},
+			},
+		}
+		return flux.NewSliceResultIterator([]flux.Result{&executetest.Result{Nm: "_result", Tbls: tables}})
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: internal.FormatCSV,
+			want: `result,table,_time,_value,_field,_measurement,host
+_result,0,1970-01-01T00:00:00.00000001Z,1.5,usage,cpu,a
+_result,0,1970-01-01T00:00:00.00000002Z,,usage,cpu,a
+_result,1,1970-01-01T00:00:00.00000001Z,2,usage,cpu,b
+result,table,_measurement,free,ok,msg
+_result,2,mem,3,true,"a,b"
+`,
+		},
+		{
+			format: internal.FormatJSON,
+			want: `{"_field":"usage","_measurement":"cpu","_time":"1970-01-01T00:00:00.00000001Z","_value":1.5,"host":"a","result":"_result","table":0}
+{"_field":"usage","_measurement":"cpu","_time":"1970-01-01T00:00:00.00000002Z","_value":null,"host":"a","result":"_result","table":0}
+{"_field":"usage","_measurement":"cpu","_time":"1970-01-01T00:00:00.00000001Z","_value":2,"host":"b","result":"_result","table":1}
+{"_measurement":"mem","free":3,"msg":"a,b","ok":true,"result":"_result","table":2}
+`,
+		},
+		{
+			format: internal.FormatLineProtocol,
+			want: `cpu,host=a usage=1.5 10
+cpu,host=b usage=2 10
+mem free=3i,msg="a,b",ok=true
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := internal.WriteResults(&buf, tt.format, results()); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("unexpected output:\ngot:\n%s\nexp:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if err := internal.WriteResults(&bytes.Buffer{}, "xml", results()); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
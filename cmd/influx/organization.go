@@ -156,8 +156,9 @@ func organizationFindF(cmd *cobra.Command, args []string) error {
 
 // Update Command
 type OrganizationUpdateFlags struct {
-	id   string
-	name string
+	id         string
+	name       string
+	requireMFA bool
 }
 
 var organizationUpdateFlags OrganizationUpdateFlags
@@ -171,6 +172,7 @@ func init() {
 
 	organizationUpdateCmd.Flags().StringVarP(&organizationUpdateFlags.id, "id", "i", "", "The organization ID (required)")
 	organizationUpdateCmd.Flags().StringVarP(&organizationUpdateFlags.name, "name", "n", "", "The organization name")
+	organizationUpdateCmd.Flags().BoolVar(&organizationUpdateFlags.requireMFA, "require-mfa", false, "Require members to enroll in multi-factor authentication")
 	organizationUpdateCmd.MarkFlagRequired("id")
 
 	organizationCmd.AddCommand(organizationUpdateCmd)
@@ -191,6 +193,9 @@ func organizationUpdateF(cmd *cobra.Command, args []string) error {
 	if organizationUpdateFlags.name != "" {
 		update.Name = &organizationUpdateFlags.name
 	}
+	if cmd.Flags().Changed("require-mfa") {
+		update.RequireMFA = &organizationUpdateFlags.requireMFA
+	}
 
 	o, err := orgSvc.UpdateOrganization(context.Background(), id, update)
 	if err != nil {
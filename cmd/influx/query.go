@@ -1,11 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/lang"
 	"github.com/influxdata/flux/repl"
 	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
+	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/kit/signals"
+	"github.com/influxdata/influxdb/query"
 	_ "github.com/influxdata/influxdb/query/builtin"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,14 +25,21 @@ var queryCmd = &cobra.Command{
 	Use:   "query [query literal or @/path/to/query.flux]",
 	Short: "Execute a Flux query",
 	Long: `Execute a literal Flux query provided as a string,
-or execute a literal Flux query contained in a file by specifying the file prefixed with an @ sign.`,
+or execute a literal Flux query contained in a file by specifying the file prefixed with an @ sign.
+
+Results are printed as tables, or written as CSV, JSON or line protocol with
+--format. With --raw, the annotated CSV returned by the server is written as is,
+and can be written back with influx write.`,
 	Args: cobra.ExactArgs(1),
 	RunE: wrapCheckSetup(fluxQueryF),
 }
 
 var queryFlags struct {
-	OrgID string
-	Org   string
+	OrgID  string
+	Org    string
+	Format string
+	Raw    bool
+	Out    string
 }
 
 func init() {
@@ -37,6 +54,10 @@ func init() {
 	if h := viper.GetString("ORG"); h != "" {
 		queryFlags.Org = h
 	}
+
+	queryCmd.PersistentFlags().StringVar(&queryFlags.Format, "format", internal.FormatTable, "Format of the results: "+strings.Join(internal.ResultFormats, ", "))
+	queryCmd.PersistentFlags().BoolVar(&queryFlags.Raw, "raw", false, "Write the annotated CSV returned by the server")
+	queryCmd.PersistentFlags().StringVar(&queryFlags.Out, "out", "", "File to write the results to, instead of standard output")
 }
 
 func fluxQueryF(cmd *cobra.Command, args []string) error {
@@ -48,6 +69,18 @@ func fluxQueryF(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("must specify exactly one of org or org-id")
 	}
 
+	if queryFlags.Raw && cmd.Flags().Changed("format") {
+		return fmt.Errorf("please specify one of format or raw")
+	}
+
+	validFormat := false
+	for _, f := range internal.ResultFormats {
+		validFormat = validFormat || f == queryFlags.Format
+	}
+	if !validFormat {
+		return fmt.Errorf("invalid format %q: expected one of %s", queryFlags.Format, strings.Join(internal.ResultFormats, ", "))
+	}
+
 	q, err := repl.LoadQuery(args[0])
 	if err != nil {
 		return fmt.Errorf("failed to load query: %v", err)
@@ -76,14 +109,46 @@ func fluxQueryF(cmd *cobra.Command, args []string) error {
 		orgID = o.ID
 	}
 
-	r, err := getFluxREPL(flags.host, flags.token, orgID)
-	if err != nil {
-		return fmt.Errorf("failed to get the flux REPL: %v", err)
+	var w io.Writer = os.Stdout
+	if queryFlags.Out != "" {
+		f, err := os.Create(queryFlags.Out)
+		if err != nil {
+			return fmt.Errorf("failed to create %q: %v", queryFlags.Out, err)
+		}
+		defer f.Close()
+		w = f
 	}
+	bw := bufio.NewWriter(w)
 
-	if err := r.Input(q); err != nil {
+	ctx := signals.WithStandardSignals(context.Background())
+	req := query.Request{
+		OrganizationID: orgID,
+		Compiler:       lang.FluxCompiler{Query: q},
+	}
+
+	if queryFlags.Raw {
+		s := &http.FluxService{
+			Addr:  flags.host,
+			Token: flags.token,
+		}
+		if _, err := s.Query(ctx, bw, &query.ProxyRequest{Request: req, Dialect: csv.DefaultDialect()}); err != nil {
+			return fmt.Errorf("failed to execute query: %v", err)
+		}
+		return bw.Flush()
+	}
+
+	s := &http.FluxQueryService{
+		Addr:  flags.host,
+		Token: flags.token,
+	}
+	results, err := s.Query(ctx, &req)
+	if err != nil {
 		return fmt.Errorf("failed to execute query: %v", err)
 	}
+	defer results.Release()
 
-	return nil
+	if err := internal.WriteResults(bw, queryFlags.Format, results); err != nil {
+		return fmt.Errorf("failed to write results: %v", err)
+	}
+	return bw.Flush()
 }
@@ -3,9 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/http"
@@ -17,11 +18,17 @@ import (
 )
 
 var writeCmd = &cobra.Command{
-	Use:   "write line protocol or @/path/to/points.txt",
+	Use:   "write [line protocol or @/path/to/points.txt]",
 	Short: "Write points to InfluxDB",
 	Long: `Write a single line of line protocol to InfluxDB,
-or add an entire file specified with an @ prefix.`,
-	Args: cobra.ExactArgs(1),
+or add an entire file specified with an @ prefix.
+
+Files and directories given with --file are written in batches, and so
+is standard input given as -. Gzipped data is decompressed. Files ending
+in .csv or .csv.gz are read as CSV, and other files as line protocol.
+CSV is read as Flux annotated CSV, unless its columns are mapped with
+the --csv-* flags. Lines that cannot be parsed are skipped and reported.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: wrapCheckSetup(fluxWriteF),
 }
 
@@ -31,6 +38,21 @@ var writeFlags struct {
 	BucketID  string
 	Bucket    string
 	Precision string
+	Tags      []string
+
+	Files      []string
+	Format     string
+	BatchSize  int
+	MaxRetries int
+	RateLimit  string
+	Checkpoint string
+	Progress   bool
+
+	CSVMeasurement       string
+	CSVMeasurementColumn string
+	CSVTimeColumn        string
+	CSVTagColumns        []string
+	CSVFieldColumns      []string
 }
 
 func init() {
@@ -63,6 +85,22 @@ func init() {
 	if p := viper.GetString("PRECISION"); p != "" {
 		writeFlags.Precision = p
 	}
+
+	writeCmd.PersistentFlags().StringArrayVar(&writeFlags.Tags, "tag", nil, "Tag as key=value added to the points that do not have a tag with the key; may be given more than once")
+
+	writeCmd.PersistentFlags().StringArrayVarP(&writeFlags.Files, "file", "f", nil, "File or directory of files to write; may be given more than once")
+	writeCmd.PersistentFlags().StringVar(&writeFlags.Format, "format", "", "Format of the data: lp or csv (detected from file extensions by default)")
+	writeCmd.PersistentFlags().IntVar(&writeFlags.BatchSize, "batch-size", write.DefaultMaxBytes, "Maximum size in bytes of each write")
+	writeCmd.PersistentFlags().IntVar(&writeFlags.MaxRetries, "max-retries", write.DefaultMaxRetries, "Number of times a write is retried when the server is unavailable")
+	writeCmd.PersistentFlags().StringVar(&writeFlags.RateLimit, "rate-limit", "", "Maximum rate of writes, in bytes per second, such as 5MB/s (unlimited by default)")
+	writeCmd.PersistentFlags().StringVar(&writeFlags.Checkpoint, "checkpoint", "", "File recording the progress of the write, to resume it from if it is interrupted")
+	writeCmd.PersistentFlags().BoolVar(&writeFlags.Progress, "progress", false, "Report the progress of the write")
+
+	writeCmd.PersistentFlags().StringVar(&writeFlags.CSVMeasurement, "csv-measurement", "", "Measurement of the points of CSV data")
+	writeCmd.PersistentFlags().StringVar(&writeFlags.CSVMeasurementColumn, "csv-measurement-column", "", "Column of CSV data holding the measurement")
+	writeCmd.PersistentFlags().StringVar(&writeFlags.CSVTimeColumn, "csv-time-column", "", "Column of CSV data holding the timestamp, as an integer or RFC3339 time")
+	writeCmd.PersistentFlags().StringArrayVar(&writeFlags.CSVTagColumns, "csv-tag-column", nil, "Column of CSV data that is a tag; may be given more than once")
+	writeCmd.PersistentFlags().StringArrayVar(&writeFlags.CSVFieldColumns, "csv-field-column", nil, "Column of CSV data that is a field, as name or name:datatype; may be given more than once (all other columns by default)")
 }
 
 func fluxWriteF(cmd *cobra.Command, args []string) error {
@@ -83,12 +121,31 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid precision")
 	}
 
+	if len(args) == 0 && len(writeFlags.Files) == 0 {
+		cmd.Usage()
+		return fmt.Errorf("please specify data to write, or files with --file")
+	}
+
+	tags, err := parseTags(writeFlags.Tags)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := parseCSVMapping()
+	if err != nil {
+		return err
+	}
+
+	rateLimit, err := parseRate(writeFlags.RateLimit)
+	if err != nil {
+		return err
+	}
+
 	bs := &http.BucketService{
 		Addr:  flags.host,
 		Token: flags.token,
 	}
 
-	var err error
 	filter := platform.BucketFilter{}
 
 	if writeFlags.BucketID != "" {
@@ -128,32 +185,148 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 
 	bucketID, orgID := buckets[0].ID, buckets[0].OrganizationID
 
-	var r io.Reader
-	if args[0] == "-" {
-		r = os.Stdin
-	} else if len(args[0]) > 0 && args[0][0] == '@' {
-		f, err := os.Open(args[0][1:])
-		if err != nil {
-			return fmt.Errorf("failed to open %q: %v", args[0][1:], err)
+	var sources []write.Source
+	if len(args) == 1 {
+		switch {
+		case args[0] == "-":
+			sources = append(sources, write.ReaderSource("-", writeFlags.Format, os.Stdin))
+		case strings.HasPrefix(args[0], "@"):
+			writeFlags.Files = append([]string{args[0][1:]}, writeFlags.Files...)
+		default:
+			sources = append(sources, write.ReaderSource("argument", write.FormatLineProtocol, strings.NewReader(args[0])))
 		}
-		defer f.Close()
-		r = f
-	} else {
-		r = strings.NewReader(args[0])
 	}
+	files, err := write.FileSources(writeFlags.Files, writeFlags.Format)
+	if err != nil {
+		return fmt.Errorf("failed to open files: %v", err)
+	}
+	sources = append(sources, files...)
 
-	s := write.Batcher{
+	loader := &write.Loader{
 		Service: &http.WriteService{
-			Addr:      flags.host,
-			Token:     flags.token,
-			Precision: writeFlags.Precision,
+			Addr:        flags.host,
+			Token:       flags.token,
+			Precision:   "ns",
+			DefaultTags: tags,
 		},
+		Precision:      writeFlags.Precision,
+		CSVMapping:     mapping,
+		MaxBatchBytes:  writeFlags.BatchSize,
+		MaxRetries:     writeFlags.MaxRetries,
+		RateLimit:      rateLimit,
+		CheckpointPath: writeFlags.Checkpoint,
+		Skipped: func(source string, line int, err error) {
+			fmt.Fprintf(os.Stderr, "skipped %s:%d: %v\n", source, line, err)
+		},
+	}
+	if writeFlags.MaxRetries == 0 {
+		loader.MaxRetries = -1
+	}
+
+	start := time.Now()
+	if writeFlags.Progress {
+		var last time.Time
+		loader.Progress = func(s write.LoadStats) {
+			if time.Since(last) >= time.Second {
+				last = time.Now()
+				printLoadStats(s, time.Since(start))
+			}
+		}
 	}
 
 	ctx = signals.WithStandardSignals(ctx)
-	if err := s.Write(ctx, orgID, bucketID, r); err != nil && err != context.Canceled {
+	stats, err := loader.Load(ctx, orgID, bucketID, sources)
+	if writeFlags.Progress || stats.Skipped > 0 {
+		printLoadStats(stats, time.Since(start))
+	}
+	if err != nil && err != context.Canceled {
+		if writeFlags.Checkpoint != "" {
+			return fmt.Errorf("failed to write data: %v; run the command again to resume from %s", err, writeFlags.Checkpoint)
+		}
 		return fmt.Errorf("failed to write data: %v", err)
 	}
 
 	return nil
 }
+
+// printLoadStats reports the progress of a write.
+func printLoadStats(s write.LoadStats, elapsed time.Duration) {
+	var rate float64
+	if secs := elapsed.Seconds(); secs > 0 {
+		rate = float64(s.Bytes) / secs
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d lines (%d bytes, %.0f bytes/s) in %d batches from %d sources; %d lines skipped, %d retries\n",
+		s.Lines, s.Bytes, rate, s.Batches, s.Sources, s.Skipped, s.Retries)
+}
+
+// parseCSVMapping returns the mapping of CSV columns set by flags, or nil if
+// CSV is read as annotated CSV.
+func parseCSVMapping() (*write.CSVMapping, error) {
+	if writeFlags.CSVMeasurement == "" && writeFlags.CSVMeasurementColumn == "" &&
+		writeFlags.CSVTimeColumn == "" && len(writeFlags.CSVTagColumns) == 0 && len(writeFlags.CSVFieldColumns) == 0 {
+		return nil, nil
+	}
+
+	m := &write.CSVMapping{
+		Measurement:       writeFlags.CSVMeasurement,
+		MeasurementColumn: writeFlags.CSVMeasurementColumn,
+		TimeColumn:        writeFlags.CSVTimeColumn,
+		TagColumns:        writeFlags.CSVTagColumns,
+	}
+	if len(writeFlags.CSVFieldColumns) > 0 {
+		m.FieldColumns = make(map[string]string, len(writeFlags.CSVFieldColumns))
+		for _, c := range writeFlags.CSVFieldColumns {
+			name, typ := c, "double"
+			if i := strings.LastIndexByte(c, ':'); i > 0 {
+				name, typ = c[:i], c[i+1:]
+			}
+			m.FieldColumns[name] = typ
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid CSV columns: %v", err)
+	}
+	return m, nil
+}
+
+// parseRate returns the bytes per second of a rate such as 500KB/s.
+func parseRate(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	v := strings.ToUpper(strings.TrimSuffix(s, "/s"))
+	mult := 1
+	for _, u := range []struct {
+		suffix string
+		mult   int
+	}{{"GB", 1000 * 1000 * 1000}, {"MB", 1000 * 1000}, {"KB", 1000}, {"B", 1}} {
+		if strings.HasSuffix(v, u.suffix) {
+			v, mult = strings.TrimSuffix(v, u.suffix), u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate limit %q: expected bytes per second, such as 5MB/s", s)
+	}
+	return int(n * float64(mult)), nil
+}
+
+// parseTags returns the tags of key=value pairs.
+func parseTags(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 || i == len(pair)-1 {
+			return nil, fmt.Errorf("invalid tag %q: expected key=value", pair)
+		}
+		tags[pair[:i]] = pair[i+1:]
+	}
+	return tags, nil
+}
@@ -0,0 +1,428 @@
+// Package buildtsm converts line protocol or annotated CSV into TSM files and
+// index entries for an offline engine.
+package buildtsm
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/file"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxdb/write"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize     = 5000
+	defaultMaxFileValues = 10000000
+)
+
+// Supported input formats.
+const (
+	FormatLineProtocol = "lp"
+	FormatCSV          = "csv"
+)
+
+// Command represents the program execution for "influx_inspect build-tsm".
+type Command struct {
+	Stdin  io.Reader
+	Stderr io.Writer
+	Stdout io.Writer
+	Logger *zap.Logger
+
+	dataDir        string
+	indexPath      string
+	seriesFilePath string
+	format         string
+	precision      string
+	orgID          platform.ID
+	bucketID       platform.ID
+	batchSize      int
+	maxFileValues  int
+
+	sfile *tsdb.SeriesFile
+	index *tsi1.Index
+
+	generation int // generation of the next TSM file.
+	values     map[string][]tsm1.Value
+	n          int      // number of values buffered.
+	tmpFiles   []string // TSM files written so far.
+	points     int      // number of points written.
+	skipped    int      // number of lines or values skipped.
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stdin:         os.Stdin,
+		Stderr:        os.Stderr,
+		Stdout:        os.Stdout,
+		Logger:        zap.NewNop(),
+		format:        FormatLineProtocol,
+		precision:     "ns",
+		batchSize:     defaultBatchSize,
+		maxFileValues: defaultMaxFileValues,
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	var enginePath, orgID, bucketID string
+
+	fs := flag.NewFlagSet("build-tsm", flag.ExitOnError)
+	fs.StringVar(&enginePath, "engine-path", defaultEnginePath(), "path to persistent engine files")
+	fs.StringVar(&orgID, "org-id", "", "organization ID the data is written to")
+	fs.StringVar(&bucketID, "bucket-id", "", "bucket ID the data is written to")
+	fs.StringVar(&cmd.format, "format", FormatLineProtocol, "optional: input format, one of lp or csv")
+	fs.StringVar(&cmd.precision, "precision", "ns", "optional: precision of integer timestamps, one of ns, us, ms or s")
+	fs.IntVar(&cmd.batchSize, "batch-size", defaultBatchSize, "optional: number of points added to the index at once")
+	fs.IntVar(&cmd.maxFileValues, "max-file-values", defaultMaxFileValues, "optional: maximum number of values buffered in memory before a TSM file is written")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = func() {
+		fmt.Fprintln(cmd.Stdout, "Writes line protocol or annotated CSV directly to TSM files and the index.")
+		fmt.Fprintln(cmd.Stdout, "influxd must not be running against the engine path.")
+		fmt.Fprintf(cmd.Stdout, "Usage: %s build-tsm [flags] [file ...]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(cmd.Stdout, "Files ending in .gz are decompressed. With no files, or a file of -, stdin is read.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cmd.Logger = logger.New(cmd.Stderr)
+
+	if orgID == "" || bucketID == "" {
+		fs.Usage()
+		return fmt.Errorf("org-id and bucket-id are required")
+	}
+	id, err := platform.IDFromString(orgID)
+	if err != nil {
+		return fmt.Errorf("invalid org-id: %v", err)
+	}
+	cmd.orgID = *id
+	if id, err = platform.IDFromString(bucketID); err != nil {
+		return fmt.Errorf("invalid bucket-id: %v", err)
+	}
+	cmd.bucketID = *id
+
+	switch cmd.format {
+	case FormatLineProtocol, FormatCSV:
+	default:
+		return fmt.Errorf("unsupported format %q", cmd.format)
+	}
+	if models.GetPrecisionMultiplier(cmd.precision) == 1 && cmd.precision != "ns" && cmd.precision != "n" {
+		return fmt.Errorf("unsupported precision %q", cmd.precision)
+	}
+
+	c := storage.NewConfig()
+	cmd.dataDir = c.GetEnginePath(enginePath)
+	cmd.indexPath = c.GetIndexPath(enginePath)
+	cmd.seriesFilePath = c.GetSeriesFilePath(enginePath)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	return cmd.run(paths)
+}
+
+func (cmd *Command) run(paths []string) (err error) {
+	if err := os.MkdirAll(cmd.dataDir, 0777); err != nil {
+		return err
+	}
+
+	generation, err := nextGeneration(cmd.dataDir)
+	if err != nil {
+		return err
+	}
+	cmd.generation = generation
+	cmd.values = make(map[string][]tsm1.Value)
+
+	// Remove any partially written TSM files if the build doesn't complete.
+	defer func() {
+		if err != nil {
+			for _, path := range cmd.tmpFiles {
+				os.Remove(path)
+				os.Remove(tsm1.StatsFilename(path))
+			}
+		}
+	}()
+
+	cmd.sfile = tsdb.NewSeriesFile(cmd.seriesFilePath)
+	cmd.sfile.Logger = cmd.Logger
+	if err := cmd.sfile.Open(context.Background()); err != nil {
+		return err
+	}
+	defer cmd.sfile.Close()
+
+	cmd.index = tsi1.NewIndex(cmd.sfile, tsi1.NewConfig(),
+		tsi1.WithPath(cmd.indexPath),
+		tsi1.DisableMetrics(),
+	)
+	cmd.index.WithLogger(cmd.Logger)
+	if err := cmd.index.Open(context.Background()); err != nil {
+		return err
+	}
+	defer cmd.index.Close()
+
+	for _, path := range paths {
+		if err := cmd.processFile(path); err != nil {
+			return err
+		}
+	}
+
+	if err := cmd.flush(); err != nil {
+		return err
+	}
+
+	cmd.Logger.Info("Compacting index")
+	cmd.index.Compact()
+	cmd.index.Wait()
+	if err := cmd.index.Close(); err != nil {
+		return err
+	}
+
+	// The TSM files are only made visible to the engine once every file has
+	// been written successfully.
+	for _, path := range cmd.tmpFiles {
+		if err := file.RenameFile(path, strings.TrimSuffix(path, "."+tsm1.TmpTSMFileExtension)); err != nil {
+			return err
+		}
+	}
+	if err := file.SyncDir(cmd.dataDir); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "wrote %d points to %d TSM files, skipped %d\n", cmd.points, len(cmd.tmpFiles), cmd.skipped)
+	return nil
+}
+
+func (cmd *Command) processFile(path string) error {
+	var r io.Reader = cmd.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if strings.HasSuffix(path, ".gz") {
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+		r = gzr
+	}
+
+	cmd.Logger.Info("Processing file", zap.String("path", path))
+	if cmd.format == FormatCSV {
+		return cmd.processCSV(path, r)
+	}
+	return cmd.processLineProtocol(path, r)
+}
+
+func (cmd *Command) processLineProtocol(path string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), models.MaxKeyLength*16)
+	scanner.Split(write.ScanLines)
+
+	var (
+		line   int
+		now    = time.Now()
+		points = make([]models.Point, 0, cmd.batchSize)
+	)
+	for scanner.Scan() {
+		line++
+
+		// Lines are parsed individually so that a malformed line only skips itself.
+		pts, err := models.ParsePointsWithPrecision(scanner.Bytes(), now, cmd.precision)
+		if err != nil {
+			cmd.skipped++
+			cmd.Logger.Warn("Skipping line", zap.String("path", path), zap.Int("line", line), zap.Error(err))
+			continue
+		}
+
+		points = append(points, pts...)
+		if len(points) >= cmd.batchSize {
+			if err := cmd.writePoints(points); err != nil {
+				return err
+			}
+			points = points[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return cmd.writePoints(points)
+}
+
+func (cmd *Command) processCSV(path string, r io.Reader) error {
+	cr := write.NewCSVReader(r)
+	cr.SetPrecision(cmd.precision)
+
+	points := make([]models.Point, 0, cmd.batchSize)
+	for {
+		pt, err := cr.Read()
+		if err == io.EOF {
+			break
+		} else if cerr, ok := err.(*write.CSVError); ok {
+			cmd.skipped++
+			cmd.Logger.Warn("Skipping record", zap.String("path", path), zap.Int("line", cerr.Line), zap.Error(cerr.Err))
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		points = append(points, pt)
+		if len(points) >= cmd.batchSize {
+			if err := cmd.writePoints(points); err != nil {
+				return err
+			}
+			points = points[:0]
+		}
+	}
+	return cmd.writePoints(points)
+}
+
+// writePoints adds the series of points to the index and buffers their values,
+// writing a TSM file once enough values have been buffered.
+func (cmd *Command) writePoints(points []models.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	points, err := tsdb.ExplodePoints(cmd.orgID, cmd.bucketID, points)
+	if err != nil {
+		return err
+	}
+
+	collection := tsdb.NewSeriesCollection(points)
+	if err := cmd.index.CreateSeriesListIfNotExists(collection); err != nil {
+		return err
+	}
+	if err := collection.PartialWriteError(); err != nil {
+		cmd.skipped += int(collection.Dropped)
+		cmd.Logger.Warn("Skipping series", zap.Error(err))
+	}
+
+	values, err := tsm1.CollectionToValues(collection)
+	if err != nil {
+		return err
+	}
+
+	// The index drops any series whose field type conflicts with an existing
+	// series, so the values of each key all share the same type.
+	for key, vs := range values {
+		cmd.values[key] = append(cmd.values[key], vs...)
+		cmd.n += len(vs)
+	}
+	cmd.points += collection.Length()
+
+	if cmd.n >= cmd.maxFileValues {
+		return cmd.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered values to a new temporary TSM file.
+func (cmd *Command) flush() error {
+	if len(cmd.values) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(cmd.values))
+	for k := range cmd.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	path := filepath.Join(cmd.dataDir, tsm1.DefaultFormatFileName(cmd.generation, 1)+"."+tsm1.TSMFileExtension+"."+tsm1.TmpTSMFileExtension)
+	cmd.Logger.Info("Writing tsm file", zap.String("path", path), zap.Int("keys", len(keys)), zap.Int("values", cmd.n))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	cmd.tmpFiles = append(cmd.tmpFiles, path)
+	defer f.Close()
+
+	w, err := tsm1.NewTSMWriterWithDiskBuffer(f)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		values := tsm1.Values(cmd.values[key]).Deduplicate()
+		for len(values) > 0 {
+			n := len(values)
+			if n > tsm1.MaxPointsPerBlock {
+				n = tsm1.MaxPointsPerBlock
+			}
+			if err := w.Write([]byte(key), values[:n]); err != nil {
+				w.Remove()
+				return err
+			}
+			values = values[n:]
+		}
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		w.Remove()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	cmd.generation++
+	cmd.values = make(map[string][]tsm1.Value)
+	cmd.n = 0
+	return nil
+}
+
+// nextGeneration returns the generation following the highest generation of
+// the TSM files in dir.
+func nextGeneration(dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		return 0, err
+	}
+
+	var max int
+	for _, path := range paths {
+		generation, _, err := tsm1.DefaultParseFileName(path)
+		if err != nil {
+			return 0, err
+		}
+		if generation > max {
+			max = generation
+		}
+	}
+	return max + 1, nil
+}
+
+func defaultEnginePath() string {
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "engine")
+}
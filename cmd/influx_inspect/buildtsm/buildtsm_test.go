@@ -0,0 +1,189 @@
+package buildtsm
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+const (
+	orgID    = platform.ID(0x1000)
+	bucketID = platform.ID(0x2000)
+)
+
+func TestCommand_LineProtocol(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	input := mustWriteFile(t, dir, "input.lp", `
+cpu,host=b value=2 20
+cpu,host=a value=1 10
+this is not line protocol
+mem,host=a free=3i 10
+cpu,host=a value=5i 30
+cpu,host=a value=4 10
+`)
+
+	cmd := newTestCommand(dir)
+	cmd.batchSize, cmd.maxFileValues = 2, 3
+	if err := cmd.run([]string{input}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := cmd.points, 4; got != exp {
+		t.Fatalf("unexpected points: got %d, exp %d", got, exp)
+	}
+	// The malformed line and the series with a conflicting field type are skipped.
+	if got, exp := cmd.skipped, 2; got != exp {
+		t.Fatalf("unexpected skipped: got %d, exp %d", got, exp)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cmd.dataDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "."+tsm1.TmpTSMFileExtension) {
+			t.Fatalf("temporary file left behind: %s", path)
+		}
+	}
+
+	tsmPaths, err := filepath.Glob(filepath.Join(cmd.dataDir, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tsmPaths) != 2 {
+		t.Fatalf("unexpected TSM files: %v", tsmPaths)
+	}
+
+	var values int
+	for _, path := range tsmPaths {
+		values += readValues(t, path)
+	}
+	if values != 4 {
+		t.Fatalf("unexpected number of values: got %d, exp 4", values)
+	}
+
+	// The engine must open the new files and know about every series.
+	e := storage.NewEngine(dir, storage.NewConfig())
+	e.WithLogger(zap.NewNop())
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if got, exp := e.SeriesCardinality(), int64(3); got != exp {
+		t.Fatalf("unexpected series cardinality: got %d, exp %d", got, exp)
+	}
+}
+
+func TestCommand_CSV(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	input := mustWriteFile(t, dir, "input.csv", `#datatype,string,long,dateTime:RFC3339Nano,double,string,string,string
+#group,false,false,false,false,true,true,true
+#default,_result,,,,,,
+,result,table,_time,_value,_field,_measurement,host
+,,0,1970-01-01T00:00:00.00000001Z,1,value,cpu,a
+,,1,1970-01-01T00:00:00.00000001Z,2,value,cpu,b
+`)
+
+	cmd := newTestCommand(dir)
+	cmd.format = FormatCSV
+	if err := cmd.run([]string{input}); err != nil {
+		t.Fatal(err)
+	}
+
+	tsmPaths, err := filepath.Glob(filepath.Join(cmd.dataDir, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tsmPaths) != 1 {
+		t.Fatalf("unexpected TSM files: %v", tsmPaths)
+	}
+	if got := readValues(t, tsmPaths[0]); got != 2 {
+		t.Fatalf("unexpected number of values: got %d, exp 2", got)
+	}
+}
+
+func TestNextGeneration(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	if got, err := nextGeneration(dir); err != nil {
+		t.Fatal(err)
+	} else if got != 1 {
+		t.Fatalf("unexpected generation: got %d, exp 1", got)
+	}
+
+	for _, name := range []string{"000000000000004-000000002.tsm", "000000000000009-000000001.tsm"} {
+		mustWriteFile(t, dir, name, "")
+	}
+	if got, err := nextGeneration(dir); err != nil {
+		t.Fatal(err)
+	} else if got != 10 {
+		t.Fatalf("unexpected generation: got %d, exp 10", got)
+	}
+}
+
+func newTestCommand(dir string) *Command {
+	c := storage.NewConfig()
+	cmd := NewCommand()
+	cmd.Stdout = &bytes.Buffer{}
+	cmd.Logger = zap.NewNop()
+	cmd.orgID, cmd.bucketID = orgID, bucketID
+	cmd.dataDir = c.GetEnginePath(dir)
+	cmd.indexPath = c.GetIndexPath(dir)
+	cmd.seriesFilePath = c.GetSeriesFilePath(dir)
+	return cmd
+}
+
+func readValues(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var n int
+	iter := r.Iterator(nil)
+	for iter.Next() {
+		values, err := r.ReadAll(iter.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		n += len(values)
+	}
+	return n
+}
+
+func mustTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "influx_inspect-buildtsm-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func mustWriteFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(strings.TrimPrefix(data, "\n")), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,544 @@
+// Package export exports TSM and WAL data from an engine directory as line protocol or annotated CSV.
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+// Supported output formats.
+const (
+	FormatLineProtocol = "lp"
+	FormatCSV          = "csv"
+)
+
+// Command represents the program execution for "influx_inspect export".
+type Command struct {
+	Stderr io.Writer
+	Stdout io.Writer
+	Logger *zap.Logger
+
+	dataDir     string
+	walDir      string
+	out         string
+	format      string
+	compress    bool
+	orgID       platform.ID
+	bucketID    platform.ID
+	measurement string
+	startTime   int64
+	endTime     int64
+
+	encoder encoder
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stderr:    os.Stderr,
+		Stdout:    os.Stdout,
+		Logger:    zap.NewNop(),
+		format:    FormatLineProtocol,
+		startTime: math.MinInt64,
+		endTime:   math.MaxInt64,
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	var enginePath, orgID, bucketID, start, end string
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.StringVar(&enginePath, "engine-path", defaultEnginePath(), "path to persistent engine files")
+	fs.StringVar(&cmd.dataDir, "datadir", "", "optional: TSM data directory; overrides the one derived from -engine-path")
+	fs.StringVar(&cmd.walDir, "waldir", "", "optional: WAL directory; overrides the one derived from -engine-path")
+	fs.StringVar(&cmd.out, "out", "", "optional: destination file; defaults to stdout")
+	fs.StringVar(&cmd.format, "format", FormatLineProtocol, "optional: output format, one of lp or csv")
+	fs.BoolVar(&cmd.compress, "compress", false, "optional: compress the output with gzip")
+	fs.StringVar(&orgID, "org-id", "", "optional: only export data belonging to this organization ID")
+	fs.StringVar(&bucketID, "bucket-id", "", "optional: only export data belonging to this bucket ID")
+	fs.StringVar(&cmd.measurement, "measurement", "", "optional: only export data for this measurement")
+	fs.StringVar(&start, "start", "", "optional: the start time to export (RFC3339 format)")
+	fs.StringVar(&end, "end", "", "optional: the end time to export (RFC3339 format)")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = func() {
+		fmt.Fprintln(cmd.Stdout, "Exports TSM and WAL data as line protocol or annotated CSV.")
+		fmt.Fprintf(cmd.Stdout, "Usage: %s export [flags]\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		fs.Usage()
+		return nil
+	}
+	cmd.Logger = logger.New(cmd.Stderr)
+
+	if cmd.dataDir == "" {
+		cmd.dataDir = storage.NewConfig().GetEnginePath(enginePath)
+	}
+	if cmd.walDir == "" {
+		cmd.walDir = storage.NewConfig().GetWALPath(enginePath)
+	}
+
+	if err := cmd.parseFilters(orgID, bucketID, start, end); err != nil {
+		return err
+	}
+
+	switch cmd.format {
+	case FormatLineProtocol, FormatCSV:
+	default:
+		return fmt.Errorf("unsupported format %q", cmd.format)
+	}
+
+	return cmd.export()
+}
+
+func (cmd *Command) parseFilters(orgID, bucketID, start, end string) error {
+	if orgID != "" {
+		id, err := platform.IDFromString(orgID)
+		if err != nil {
+			return fmt.Errorf("invalid org-id: %v", err)
+		}
+		cmd.orgID = *id
+	}
+
+	if bucketID != "" {
+		if orgID == "" {
+			return fmt.Errorf("bucket-id requires org-id to be set")
+		}
+		id, err := platform.IDFromString(bucketID)
+		if err != nil {
+			return fmt.Errorf("invalid bucket-id: %v", err)
+		}
+		cmd.bucketID = *id
+	}
+
+	if start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %v", err)
+		}
+		cmd.startTime = t.UnixNano()
+	}
+
+	if end != "" {
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %v", err)
+		}
+		cmd.endTime = t.UnixNano()
+	}
+
+	if cmd.startTime > cmd.endTime {
+		return fmt.Errorf("end time before start time")
+	}
+	return nil
+}
+
+func (cmd *Command) export() error {
+	var w io.Writer = cmd.Stdout
+	if cmd.out != "" {
+		f, err := os.Create(cmd.out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	w = bw
+
+	var gzw *gzip.Writer
+	if cmd.compress {
+		gzw = gzip.NewWriter(bw)
+		w = gzw
+	}
+
+	switch cmd.format {
+	case FormatCSV:
+		cmd.encoder = newCSVEncoder(w)
+	default:
+		cmd.encoder = newLineProtocolEncoder(w)
+	}
+
+	if err := cmd.exportTSMFiles(); err != nil {
+		return err
+	}
+	if err := cmd.exportWALFiles(); err != nil {
+		return err
+	}
+
+	if err := cmd.encoder.Flush(); err != nil {
+		return err
+	}
+	if gzw != nil {
+		if err := gzw.Close(); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// prefix returns the TSM key prefix matching the org and bucket filters.
+func (cmd *Command) prefix() []byte {
+	if !cmd.orgID.Valid() {
+		return nil
+	}
+
+	name := tsdb.EncodeName(cmd.orgID, cmd.bucketID)
+	if !cmd.bucketID.Valid() {
+		return models.EscapeMeasurement(append([]byte(nil), name[:8]...))
+	}
+	return models.EscapeMeasurement(append([]byte(nil), name[:]...))
+}
+
+func (cmd *Command) exportTSMFiles() error {
+	paths, err := filepath.Glob(filepath.Join(cmd.dataDir, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := cmd.exportTSMFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cmd *Command) exportTSMFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		cmd.Logger.Warn("Unable to read, skipping", zap.String("path", path), zap.Error(err))
+		return nil
+	}
+	defer r.Close()
+
+	if !r.OverlapsTimeRange(cmd.startTime, cmd.endTime) {
+		return nil
+	}
+	cmd.Logger.Info("Exporting tsm file", zap.String("path", path))
+
+	prefix := prefixKey(cmd.prefix())
+	iter := r.Iterator(prefix)
+	for iter.Next() {
+		key := iter.Key()
+		if !prefix.matches(key) {
+			break
+		}
+
+		// ReadAll excludes any values covered by the file's tombstones.
+		values, err := r.ReadAll(key)
+		if err != nil {
+			cmd.Logger.Warn("Unable to read key, skipping", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if err := cmd.writeValues(key, values); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func (cmd *Command) exportWALFiles() error {
+	paths, err := wal.SegmentFileNames(cmd.walDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	// Loading the segments into a cache applies any bucket deletes recorded
+	// in the WAL to the writes that preceded them.
+	cmd.Logger.Info("Exporting wal files", zap.String("path", cmd.walDir))
+	cache := tsm1.NewCache(0)
+	loader := tsm1.NewCacheLoader(paths)
+	loader.WithLogger(cmd.Logger)
+	if err := loader.Load(cache); err != nil {
+		return err
+	}
+
+	prefix := prefixKey(cmd.prefix())
+	for _, key := range cache.Keys() {
+		if !prefix.matches(key) {
+			continue
+		}
+		if err := cmd.writeValues(key, cache.Values(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cmd *Command) writeValues(key []byte, values []tsm1.Value) error {
+	s, ok := parseSeries(key)
+	if !ok {
+		cmd.Logger.Warn("Invalid series key, skipping", zap.ByteString("key", key))
+		return nil
+	}
+
+	if cmd.orgID.Valid() && s.org != cmd.orgID {
+		return nil
+	} else if cmd.bucketID.Valid() && s.bucket != cmd.bucketID {
+		return nil
+	} else if cmd.measurement != "" && s.measurement != cmd.measurement {
+		return nil
+	}
+
+	values = tsm1.Values(values).Include(cmd.startTime, cmd.endTime)
+	if len(values) == 0 {
+		return nil
+	}
+	return cmd.encoder.Encode(s, values)
+}
+
+// series is a decoded TSM key.
+type series struct {
+	org, bucket platform.ID
+	measurement string
+	field       string
+	tags        models.Tags // excludes the measurement and field tags.
+}
+
+// parseSeries decodes a TSM key into its organization, bucket, measurement,
+// tags and field.
+func parseSeries(key []byte) (series, bool) {
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	name, tags := models.ParseKeyBytes(seriesKey)
+	var nameBytes [16]byte
+	if len(name) != len(nameBytes) || len(field) == 0 {
+		return series{}, false
+	}
+
+	var s series
+	copy(nameBytes[:], name)
+	s.org, s.bucket = tsdb.DecodeName(nameBytes)
+	s.field = string(field)
+
+	s.tags = make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		switch string(t.Key) {
+		case models.MeasurementTagKey:
+			s.measurement = string(t.Value)
+		case models.FieldKeyTagKey:
+		default:
+			s.tags = append(s.tags, t)
+		}
+	}
+	return s, s.measurement != ""
+}
+
+// prefixKey is a TSM key prefix. A nil prefix matches all keys.
+type prefixKey []byte
+
+func (p prefixKey) matches(key []byte) bool {
+	return len(key) >= len(p) && string(key[:len(p)]) == string(p)
+}
+
+// encoder writes the values of a single series field to an output.
+type encoder interface {
+	Encode(s series, values []tsm1.Value) error
+	Flush() error
+}
+
+// lineProtocolEncoder writes values as line protocol. A context comment is
+// written whenever the org or bucket of the exported data changes.
+type lineProtocolEncoder struct {
+	w           io.Writer
+	org, bucket platform.ID
+	buf         []byte
+}
+
+func newLineProtocolEncoder(w io.Writer) *lineProtocolEncoder {
+	return &lineProtocolEncoder{w: w}
+}
+
+func (e *lineProtocolEncoder) Encode(s series, values []tsm1.Value) error {
+	if s.org != e.org || s.bucket != e.bucket {
+		e.org, e.bucket = s.org, s.bucket
+		if _, err := fmt.Fprintf(e.w, "# CONTEXT-ORG: %s\n# CONTEXT-BUCKET: %s\n", s.org, s.bucket); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range values {
+		pt, err := models.NewPoint(s.measurement, s.tags, models.Fields{s.field: v.Value()}, time.Unix(0, v.UnixNano()))
+		if err != nil {
+			return err
+		}
+
+		e.buf = pt.AppendString(e.buf[:0])
+		e.buf = append(e.buf, '\n')
+		if _, err := e.w.Write(e.buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *lineProtocolEncoder) Flush() error { return nil }
+
+// csvEncoder writes values as Flux annotated CSV. Every series field is
+// written as its own table, and annotations are written whenever the columns
+// of the table differ from the previous one.
+type csvEncoder struct {
+	w      *csv.Writer
+	table  int
+	header []string
+	types  []string
+	row    []string
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Encode(s series, values []tsm1.Value) error {
+	header := append(make([]string, 0, 7+len(s.tags)), "", "result", "table", "_time", "_value", "_field", "_measurement")
+	for _, t := range s.tags {
+		header = append(header, string(t.Key))
+	}
+	types := append(make([]string, 0, len(header)), "#datatype", "string", "long", "dateTime:RFC3339Nano", csvDataType(values[0]), "string", "string")
+	for range s.tags {
+		types = append(types, "string")
+	}
+
+	if !equalStrings(header, e.header) || !equalStrings(types, e.types) {
+		if err := e.writeAnnotations(header, types); err != nil {
+			return err
+		}
+	}
+
+	row := append(e.row[:0], "", "", strconv.Itoa(e.table), "", "", s.field, s.measurement)
+	for _, t := range s.tags {
+		row = append(row, string(t.Value))
+	}
+	e.row = row
+
+	for _, v := range values {
+		row[3] = time.Unix(0, v.UnixNano()).UTC().Format(time.RFC3339Nano)
+		row[4] = csvValue(v)
+		if err := e.w.Write(row); err != nil {
+			return err
+		}
+	}
+	e.table++
+	return nil
+}
+
+func (e *csvEncoder) writeAnnotations(header, types []string) error {
+	if e.header != nil {
+		// A blank line separates tables with different schemas.
+		e.w.Flush()
+		if err := e.w.Write(nil); err != nil {
+			return err
+		}
+	}
+	e.header, e.types = header, types
+
+	group := make([]string, len(header))
+	group[0] = "#group"
+	for i := 1; i < len(group); i++ {
+		group[i] = strconv.FormatBool(i > 4)
+	}
+
+	defaults := make([]string, len(header))
+	defaults[0], defaults[1] = "#default", "_result"
+
+	for _, record := range [][]string{types, group, defaults, header} {
+		if err := e.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func csvDataType(v tsm1.Value) string {
+	switch v.(type) {
+	case tsm1.FloatValue:
+		return "double"
+	case tsm1.IntegerValue:
+		return "long"
+	case tsm1.UnsignedValue:
+		return "unsignedLong"
+	case tsm1.BooleanValue:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+func csvValue(v tsm1.Value) string {
+	switch v := v.(type) {
+	case tsm1.FloatValue:
+		return strconv.FormatFloat(v.RawValue(), 'f', -1, 64)
+	case tsm1.IntegerValue:
+		return strconv.FormatInt(v.RawValue(), 10)
+	case tsm1.UnsignedValue:
+		return strconv.FormatUint(v.RawValue(), 10)
+	case tsm1.BooleanValue:
+		return strconv.FormatBool(v.RawValue())
+	case tsm1.StringValue:
+		return v.RawValue()
+	default:
+		return fmt.Sprint(v.Value())
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func defaultEnginePath() string {
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "engine")
+}
@@ -0,0 +1,286 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+const (
+	orgID    = platform.ID(0x1000)
+	bucketID = platform.ID(0x2000)
+	otherID  = platform.ID(0x3000)
+)
+
+func TestCommand_Export_LineProtocol(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	mustWriteTSM(t, filepath.Join(dir, "data", "000000001-000000001.tsm"), orgID, bucketID, `
+cpu,host=a value=1 10
+cpu,host=a value=2 20
+mem,host=a free=3i 10
+`)
+	mustWriteTSM(t, filepath.Join(dir, "data", "000000002-000000001.tsm"), orgID, otherID, `
+cpu,host=b value=4 10
+`)
+	mustWriteWAL(t, filepath.Join(dir, "wal"), orgID, bucketID, `
+cpu,host=c value=5 30
+`)
+
+	cmd := newTestCommand(dir)
+	cmd.orgID, cmd.bucketID = orgID, bucketID
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	if err := cmd.export(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `# CONTEXT-ORG: 0000000000001000
+# CONTEXT-BUCKET: 0000000000002000
+cpu,host=a value=1 10
+cpu,host=a value=2 20
+mem,host=a free=3i 10
+cpu,host=c value=5 30
+`
+	if got := buf.String(); got != exp {
+		t.Fatalf("unexpected output:\n-- got --\n%s\n-- exp --\n%s", got, exp)
+	}
+}
+
+func TestCommand_Export_Filters(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	mustWriteTSM(t, filepath.Join(dir, "data", "000000001-000000001.tsm"), orgID, bucketID, `
+cpu,host=a value=1 10
+cpu,host=a value=2 20
+cpu,host=a value=3 30
+mem,host=a free=3i 20
+`)
+
+	cmd := newTestCommand(dir)
+	cmd.orgID = orgID
+	cmd.measurement = "cpu"
+	cmd.startTime, cmd.endTime = 15, 25
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	if err := cmd.export(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := lines(buf.String()), []string{"cpu,host=a value=2 20"}; !equalStrings(got, exp) {
+		t.Fatalf("unexpected output: got %q, exp %q", got, exp)
+	}
+}
+
+func TestCommand_Export_Tombstones(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data", "000000001-000000001.tsm")
+	mustWriteTSM(t, path, orgID, bucketID, `
+cpu,host=a value=1 10
+cpu,host=a value=2 20
+`)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter := r.Iterator(nil)
+	var keys [][]byte
+	for iter.Next() {
+		keys = append(keys, append([]byte(nil), iter.Key()...))
+	}
+	if err := r.DeleteRange(keys, 0, 15); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	cmd := newTestCommand(dir)
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	if err := cmd.export(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := lines(buf.String()), []string{"cpu,host=a value=2 20"}; !equalStrings(got, exp) {
+		t.Fatalf("unexpected output: got %q, exp %q", got, exp)
+	}
+}
+
+func TestCommand_Export_CSV(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	mustWriteTSM(t, filepath.Join(dir, "data", "000000001-000000001.tsm"), orgID, bucketID, `
+cpu,host=a value=1 10
+cpu,host=b value=2 10
+mem free=3i 10
+`)
+
+	cmd := newTestCommand(dir)
+	cmd.format = FormatCSV
+	cmd.compress = true
+
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	if err := cmd.export(); err != nil {
+		t.Fatal(err)
+	}
+
+	gzr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gzr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `#datatype,string,long,dateTime:RFC3339Nano,double,string,string,string
+#group,false,false,false,false,true,true,true
+#default,_result,,,,,,
+,result,table,_time,_value,_field,_measurement,host
+,,0,1970-01-01T00:00:00.00000001Z,1,value,cpu,a
+,,1,1970-01-01T00:00:00.00000001Z,2,value,cpu,b
+
+#datatype,string,long,dateTime:RFC3339Nano,long,string,string
+#group,false,false,false,false,true,true
+#default,_result,,,,,
+,result,table,_time,_value,_field,_measurement
+,,2,1970-01-01T00:00:00.00000001Z,3,free,mem
+`
+	if got := string(b); got != exp {
+		t.Fatalf("unexpected output:\n-- got --\n%s\n-- exp --\n%s", got, exp)
+	}
+}
+
+func TestCommand_parseFilters(t *testing.T) {
+	cmd := NewCommand()
+	if err := cmd.parseFilters("", bucketID.String(), "", ""); err == nil {
+		t.Fatal("expected error when bucket-id is set without org-id")
+	}
+	if err := cmd.parseFilters("", "", "2019-01-02T00:00:00Z", "2019-01-01T00:00:00Z"); err == nil {
+		t.Fatal("expected error when end is before start")
+	}
+	if err := cmd.parseFilters(orgID.String(), bucketID.String(), "2019-01-01T00:00:00Z", ""); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.orgID != orgID || cmd.bucketID != bucketID {
+		t.Fatalf("unexpected ids: %s %s", cmd.orgID, cmd.bucketID)
+	}
+}
+
+func newTestCommand(dir string) *Command {
+	cmd := NewCommand()
+	cmd.Logger = zap.NewNop()
+	cmd.dataDir = filepath.Join(dir, "data")
+	cmd.walDir = filepath.Join(dir, "wal")
+	return cmd
+}
+
+func mustTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "influx_inspect-export-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func mustValues(t *testing.T, org, bucket platform.ID, lp string) map[string][]tsm1.Value {
+	t.Helper()
+	points, err := models.ParsePointsString(strings.TrimSpace(lp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err = tsdb.ExplodePoints(org, bucket, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := tsm1.CollectionToValues(tsdb.NewSeriesCollection(points))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func mustWriteTSM(t *testing.T, path string, org, bucket platform.ID, lp string) {
+	t.Helper()
+	values := mustValues(t, org, bucket, lp)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := w.Write([]byte(k), values[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustWriteWAL(t *testing.T, dir string, org, bucket platform.ID, lp string) {
+	t.Helper()
+	w := wal.NewWAL(dir)
+	if err := w.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteMulti(context.Background(), mustValues(t, org, bucket, lp)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lines(s string) []string {
+	var a []string
+	for _, l := range strings.Split(s, "\n") {
+		if l != "" && !strings.HasPrefix(l, "#") {
+			a = append(a, l)
+		}
+	}
+	return a
+}
@@ -0,0 +1,85 @@
+// The influx_inspect command provides tools for inspecting and operating on
+// the on-disk storage of an influxd instance.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/buildtsi"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/buildtsm"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/export"
+)
+
+func main() {
+	m := NewMain()
+	if err := m.Run(os.Args[1:]...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Main represents the program execution.
+type Main struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewMain returns a new instance of Main.
+func NewMain() *Main {
+	return &Main{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run determines and runs the command specified by the CLI args.
+func (m *Main) Run(args ...string) error {
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	switch name {
+	case "", "help":
+		fmt.Fprint(m.Stdout, usage)
+	case "buildtsi":
+		cmd := buildtsi.NewCommand()
+		cmd.Stdout, cmd.Stderr = m.Stdout, m.Stderr
+		if err := cmd.Run(args...); err != nil {
+			return fmt.Errorf("buildtsi: %s", err)
+		}
+	case "build-tsm":
+		cmd := buildtsm.NewCommand()
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = m.Stdin, m.Stdout, m.Stderr
+		if err := cmd.Run(args...); err != nil {
+			return fmt.Errorf("build-tsm: %s", err)
+		}
+	case "export":
+		cmd := export.NewCommand()
+		cmd.Stdout, cmd.Stderr = m.Stdout, m.Stderr
+		if err := cmd.Run(args...); err != nil {
+			return fmt.Errorf("export: %s", err)
+		}
+	default:
+		return fmt.Errorf(`unknown command "%s"`+"\n"+`Run 'influx_inspect help' for usage`+"\n\n", name)
+	}
+
+	return nil
+}
+
+const usage = `Usage: influx_inspect [command] [arguments]
+
+The commands are:
+
+    buildtsi             converts in-memory (TSM-based) shards to TSI
+    build-tsm            writes line protocol or annotated CSV directly to TSM files
+    export               exports raw data from a bucket to line protocol or annotated CSV
+    help                 display this help message
+
+Use "influx_inspect [command] -help" for more information about a command.
+`
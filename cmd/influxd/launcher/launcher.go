@@ -2,6 +2,7 @@ package launcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -9,8 +10,10 @@ import (
 	_ "net/http/pprof" // needed to add pprof to our binary.
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/influxdata/flux/control"
 	"github.com/influxdata/flux/execute"
@@ -21,8 +24,10 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/audit"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/chronograf/server"
+	"github.com/influxdata/influxdb/encrypted"
 	protofs "github.com/influxdata/influxdb/fs"
 	"github.com/influxdata/influxdb/gather"
 	"github.com/influxdata/influxdb/http"
@@ -32,16 +37,21 @@ import (
 	"github.com/influxdata/influxdb/kit/prom"
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/ldap"
 	influxlogger "github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/membership"
 	"github.com/influxdata/influxdb/nats"
+	"github.com/influxdata/influxdb/oidc"
 	infprom "github.com/influxdata/influxdb/prometheus"
 	"github.com/influxdata/influxdb/proto"
 	"github.com/influxdata/influxdb/query"
 	pcontrol "github.com/influxdata/influxdb/query/control"
+	"github.com/influxdata/influxdb/replication"
 	"github.com/influxdata/influxdb/snowflake"
 	"github.com/influxdata/influxdb/source"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/influxdata/influxdb/storage/readservice"
+	"github.com/influxdata/influxdb/storage/wal"
 	"github.com/influxdata/influxdb/task"
 	taskbackend "github.com/influxdata/influxdb/task/backend"
 	taskbolt "github.com/influxdata/influxdb/task/backend/bolt"
@@ -79,15 +89,64 @@ type Launcher struct {
 	tracingType       string
 	reportingDisabled bool
 
-	httpBindAddress string
-	boltPath        string
-	enginePath      string
-	protosPath      string
-	secretStore     string
-
-	boltClient *bolt.Client
-	kvService  *kv.Service
-	engine     *storage.Engine
+	httpBindAddress  string
+	boltPath         string
+	enginePath       string
+	protosPath       string
+	replicationsPath string
+	secretStore      string
+	secretKey        string
+	secretKeyPath    string
+
+	maxSeriesPerBucket int
+	maxValuesPerTag    int
+
+	maxWriteBodySize int
+	maxWritePoints   int
+	writeBatchSize   int
+	maxWriteFuture   time.Duration
+
+	writeBufferMaxLatency time.Duration
+	writeBufferMaxPoints  int
+
+	walReplicationURLs               []string
+	walReplicationToken              string
+	walReplicationInsecureSkipVerify bool
+	walReplicationMaxBatchSize       int
+
+	oidcIssuer        string
+	oidcClientID      string
+	oidcClientSecret  string
+	oidcRedirectURL   string
+	oidcScopes        []string
+	oidcUsernameClaim string
+	oidcGroupsClaim   string
+	oidcGroupMappings []string
+
+	ldapURL                string
+	ldapStartTLS           bool
+	ldapInsecureSkipVerify bool
+	ldapBindDN             string
+	ldapBindPassword       string
+	ldapUserBaseDN         string
+	ldapUserFilter         string
+	ldapGroupAttribute     string
+	ldapGroupBaseDN        string
+	ldapGroupFilter        string
+	ldapGroupMappings      []string
+	ldapLocalUsers         []string
+
+	auditLogDisabled    bool
+	auditLogRetention   time.Duration
+	auditExportOrgID    string
+	auditExportBucketID string
+
+	boltClient  *bolt.Client
+	kvService   *kv.Service
+	engine      *storage.Engine
+	writeBuffer *storage.WriteBuffer
+
+	replicationSvc *replication.Service
 
 	queryController *pcontrol.Controller
 
@@ -179,6 +238,20 @@ func (m *Launcher) Shutdown(ctx context.Context) {
 		m.logger.Info("Failed closing query service", zap.Error(err))
 	}
 
+	if m.replicationSvc != nil {
+		m.logger.Info("Stopping", zap.String("service", "replication"))
+		if err := m.replicationSvc.Close(); err != nil {
+			m.logger.Error("failed to close replication service", zap.Error(err))
+		}
+	}
+
+	if m.writeBuffer != nil {
+		m.logger.Info("Stopping", zap.String("service", "storage-write-buffer"))
+		if err := m.writeBuffer.Close(); err != nil {
+			m.logger.Error("failed to close write buffer", zap.Error(err))
+		}
+	}
+
 	m.logger.Info("Stopping", zap.String("service", "storage-engine"))
 	if err := m.engine.Close(); err != nil {
 		m.logger.Error("failed to close engine", zap.Error(err))
@@ -256,11 +329,221 @@ func (m *Launcher) Run(ctx context.Context, args ...string) error {
 				Default: filepath.Join(dir, "engine"),
 				Desc:    "path to persistent engine files",
 			},
+			{
+				DestP:   &m.maxSeriesPerBucket,
+				Flag:    "storage-max-series-per-bucket",
+				Default: 0,
+				Desc:    "maximum number of series per bucket; writes creating new series beyond the limit are dropped (0 disables the limit)",
+			},
+			{
+				DestP:   &m.maxValuesPerTag,
+				Flag:    "storage-max-values-per-tag",
+				Default: 0,
+				Desc:    "maximum number of values per tag key in a bucket; writes adding new values beyond the limit are dropped (0 disables the limit)",
+			},
+			{
+				DestP:   &m.maxWriteBodySize,
+				Flag:    "http-max-write-body-size",
+				Default: http.DefaultMaxWriteBodySize,
+				Desc:    "maximum size in bytes of the body of a write request, after decompression (0 disables the limit)",
+			},
+			{
+				DestP:   &m.maxWritePoints,
+				Flag:    "http-max-write-points",
+				Default: http.DefaultMaxWritePoints,
+				Desc:    "maximum number of points in a write request (0 disables the limit)",
+			},
+			{
+				DestP:   &m.writeBatchSize,
+				Flag:    "http-write-batch-size",
+				Default: http.DefaultWriteBatchSize,
+				Desc:    "number of points of a write request written to storage at once",
+			},
+			{
+				DestP:   &m.maxWriteFuture,
+				Flag:    "http-max-write-future",
+				Default: time.Duration(0),
+				Desc:    "reject written points with a time further than this in the future (0 disables the limit)",
+			},
+			{
+				DestP:   &m.writeBufferMaxLatency,
+				Flag:    "storage-write-buffer-max-latency",
+				Default: time.Duration(0),
+				Desc:    "combine concurrent writes, waiting at most this long for others to join each write (0 disables combining writes)",
+			},
+			{
+				DestP:   &m.writeBufferMaxPoints,
+				Flag:    "storage-write-buffer-max-points",
+				Default: storage.DefaultWriteBufferMaxPoints,
+				Desc:    "maximum number of points in a combined write; larger writes are not combined",
+			},
+			{
+				DestP: &m.walReplicationURLs,
+				Flag:  "storage-wal-replication-url",
+				Desc:  "URL of an influxd to replicate writes, bucket deletes and series deletes to through the WAL; may be given more than once",
+			},
+			{
+				DestP: &m.walReplicationToken,
+				Flag:  "storage-wal-replication-token",
+				Desc:  "token used to replicate the WAL, with write access to all buckets of the remote influxd",
+			},
+			{
+				DestP:   &m.walReplicationInsecureSkipVerify,
+				Flag:    "storage-wal-replication-skip-verify",
+				Default: false,
+				Desc:    "skip TLS certificate verification when replicating the WAL",
+			},
+			{
+				DestP:   &m.walReplicationMaxBatchSize,
+				Flag:    "storage-wal-replication-max-batch-size",
+				Default: http.DefaultMaxWALBatchSize,
+				Desc:    "maximum size in bytes of a batch of WAL entries replicated to this influxd",
+			},
+			{
+				DestP: &m.oidcIssuer,
+				Flag:  "oidc-issuer",
+				Desc:  "URL of an OpenID Connect identity provider users may sign in with",
+			},
+			{
+				DestP: &m.oidcClientID,
+				Flag:  "oidc-client-id",
+				Desc:  "client ID of influxd at the OpenID Connect identity provider",
+			},
+			{
+				DestP: &m.oidcClientSecret,
+				Flag:  "oidc-client-secret",
+				Desc:  "client secret of influxd at the OpenID Connect identity provider",
+			},
+			{
+				DestP: &m.oidcRedirectURL,
+				Flag:  "oidc-redirect-url",
+				Desc:  "public URL of the /api/v2/signin/oidc/callback route of influxd, registered at the OpenID Connect identity provider",
+			},
+			{
+				DestP:   &m.oidcScopes,
+				Flag:    "oidc-scopes",
+				Default: []string{"profile", "email"},
+				Desc:    "scopes requested from the OpenID Connect identity provider besides openid",
+			},
+			{
+				DestP:   &m.oidcUsernameClaim,
+				Flag:    "oidc-username-claim",
+				Default: oidc.DefaultUsernameClaim,
+				Desc:    "claim of the ID token holding the name given to users created when they first sign in with OpenID Connect",
+			},
+			{
+				DestP:   &m.oidcGroupsClaim,
+				Flag:    "oidc-groups-claim",
+				Default: oidc.DefaultGroupsClaim,
+				Desc:    "claim of the ID token listing the groups of users signing in with OpenID Connect",
+			},
+			{
+				DestP: &m.oidcGroupMappings,
+				Flag:  "oidc-group-mapping",
+				Desc:  "grant the members of a group a role in an organization, as group=org[:member|owner]; membership of the organizations mapped is managed by the identity provider; may be given more than once, or comma separated",
+			},
+			{
+				DestP: &m.ldapURL,
+				Flag:  "ldap-url",
+				Desc:  "URL of an LDAP directory to check the passwords of users against, as ldap://host[:port] or ldaps://host[:port]",
+			},
+			{
+				DestP:   &m.ldapStartTLS,
+				Flag:    "ldap-start-tls",
+				Default: false,
+				Desc:    "upgrade ldap:// connections to the directory to TLS",
+			},
+			{
+				DestP:   &m.ldapInsecureSkipVerify,
+				Flag:    "ldap-skip-verify",
+				Default: false,
+				Desc:    "skip TLS certificate verification of the directory",
+			},
+			{
+				DestP: &m.ldapBindDN,
+				Flag:  "ldap-bind-dn",
+				Desc:  "DN of the account searching the directory for users and groups; searches are anonymous if unset",
+			},
+			{
+				DestP: &m.ldapBindPassword,
+				Flag:  "ldap-bind-password",
+				Desc:  "password of the account searching the directory",
+			},
+			{
+				DestP: &m.ldapUserBaseDN,
+				Flag:  "ldap-user-base-dn",
+				Desc:  "DN under which users are searched in the directory",
+			},
+			{
+				DestP:   &m.ldapUserFilter,
+				Flag:    "ldap-user-filter",
+				Default: ldap.DefaultUserFilter,
+				Desc:    "filter searching a user in the directory, in which %s is replaced by their name",
+			},
+			{
+				DestP:   &m.ldapGroupAttribute,
+				Flag:    "ldap-group-attribute",
+				Default: ldap.DefaultGroupAttribute,
+				Desc:    "attribute of users listing the DNs of their groups, unless groups are searched",
+			},
+			{
+				DestP: &m.ldapGroupBaseDN,
+				Flag:  "ldap-group-base-dn",
+				Desc:  "DN under which the groups of users are searched; if unset, groups are listed by the group attribute of users",
+			},
+			{
+				DestP:   &m.ldapGroupFilter,
+				Flag:    "ldap-group-filter",
+				Default: ldap.DefaultGroupFilter,
+				Desc:    "filter searching the groups of a user, in which %s is replaced by their DN",
+			},
+			{
+				DestP: &m.ldapGroupMappings,
+				Flag:  "ldap-group-mapping",
+				Desc:  "grant the members of a directory group, named by its full DN, a role in an organization, as group=org[:member|owner]; membership of the organizations mapped is managed by the directory. May be given more than once",
+			},
+			{
+				DestP: &m.ldapLocalUsers,
+				Flag:  "ldap-local-user",
+				Desc:  "name of a user, such as the initial operator, whose password is checked locally rather than against the directory; may be given more than once",
+			},
+			{
+				DestP:   &m.auditLogDisabled,
+				Flag:    "audit-log-disabled",
+				Default: false,
+				Desc:    "disable recording the calls of the API changing resources or writing or deleting data in the audit log",
+			},
+			{
+				DestP:   &m.auditLogRetention,
+				Flag:    "audit-log-retention",
+				Default: 30 * 24 * time.Hour,
+				Desc:    "how long events are kept in the audit log; they are kept forever if 0",
+			},
+			{
+				DestP: &m.auditExportOrgID,
+				Flag:  "audit-export-org-id",
+				Desc:  "ID of the organization of the bucket audit events are exported to",
+			},
+			{
+				DestP: &m.auditExportBucketID,
+				Flag:  "audit-export-bucket-id",
+				Desc:  "ID of a bucket audit events are also written to, as points of the audit measurement",
+			},
 			{
 				DestP:   &m.secretStore,
 				Flag:    "secret-store",
 				Default: "bolt",
-				Desc:    "data store for secrets (bolt or vault)",
+				Desc:    "data store for secrets (bolt, encrypted or vault)",
+			},
+			{
+				DestP: &m.secretKey,
+				Flag:  "secret-key",
+				Desc:  "base64 encoded AES-256 keys of the encrypted secret store, separated by commas; the first encrypts new secrets",
+			},
+			{
+				DestP: &m.secretKeyPath,
+				Flag:  "secret-key-path",
+				Desc:  "path to a file of the keys of the encrypted secret store, one per line, if --secret-key is not set",
 			},
 			{
 				DestP:   &m.protosPath,
@@ -268,6 +551,12 @@ func (m *Launcher) Run(ctx context.Context, args ...string) error {
 				Default: filepath.Join(dir, "protos"),
 				Desc:    "path to protos on the filesystem",
 			},
+			{
+				DestP:   &m.replicationsPath,
+				Flag:    "replications-path",
+				Default: filepath.Join(dir, "replications"),
+				Desc:    "path to the queues of points to be forwarded by bucket replications",
+			},
 			{
 				DestP:   &m.reportingDisabled,
 				Flag:    "reporting-disabled",
@@ -413,12 +702,63 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 			return err
 		}
 		secretSvc = svc
+	case "encrypted":
+		// Secrets are encrypted with keys read from the environment or a
+		// file, and stored in bolt.
+		svc, err := m.encryptedSecretService(ctx)
+		if err != nil {
+			m.logger.Error("failed initializing encrypted secret service", zap.Error(err))
+			return err
+		}
+		secretSvc = svc
 	default:
-		err := fmt.Errorf("unknown secret service %q, expected \"bolt\" or \"vault\"", m.secretStore)
+		err := fmt.Errorf("unknown secret service %q, expected \"bolt\", \"encrypted\" or \"vault\"", m.secretStore)
 		m.logger.Error("failed setting secret service", zap.Error(err))
 		return err
 	}
 
+	if m.ldapURL != "" {
+		dir, err := ldap.NewDirectory(ldap.Config{
+			URL:                m.ldapURL,
+			StartTLS:           m.ldapStartTLS,
+			InsecureSkipVerify: m.ldapInsecureSkipVerify,
+			BindDN:             m.ldapBindDN,
+			BindPassword:       m.ldapBindPassword,
+			UserBaseDN:         m.ldapUserBaseDN,
+			UserFilter:         m.ldapUserFilter,
+			GroupAttribute:     m.ldapGroupAttribute,
+			GroupBaseDN:        m.ldapGroupBaseDN,
+			GroupFilter:        m.ldapGroupFilter,
+		})
+		if err != nil {
+			m.logger.Error("failed to set up LDAP directory", zap.Error(err))
+			return err
+		}
+
+		groups := make([]membership.Mapping, 0, len(m.ldapGroupMappings))
+		for _, s := range m.ldapGroupMappings {
+			g, err := membership.ParseMapping(s)
+			if err != nil {
+				m.logger.Error("failed to parse LDAP group mapping", zap.Error(err))
+				return err
+			}
+			groups = append(groups, g)
+		}
+
+		passwdsSvc = &ldap.PasswordsService{
+			Directory:  dir,
+			LocalUsers: m.ldapLocalUsers,
+			Local:      m.kvService,
+			Membership: &membership.Syncer{
+				Mappings:                   groups,
+				OrganizationService:        orgSvc,
+				UserResourceMappingService: userResourceSvc,
+			},
+			UserService:         userSvc,
+			UserIdentityService: m.kvService,
+		}
+	}
+
 	// Load proto examples from the user data.
 	protoSvc := protofs.NewProtoService(m.protosPath, m.logger, dashboardSvc)
 	if err := protoSvc.Open(ctx); err != nil {
@@ -443,7 +783,29 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 
 	var pointsWriter storage.PointsWriter
 	{
-		m.engine = storage.NewEngine(m.enginePath, storage.NewConfig(), storage.WithRetentionEnforcer(bucketSvc))
+		config := storage.NewConfig()
+		config.Index.MaxSeriesPerBucket = m.maxSeriesPerBucket
+		config.Index.MaxValuesPerTag = m.maxValuesPerTag
+
+		var options []storage.Option
+		if len(m.walReplicationURLs) > 0 {
+			var remotes []wal.ReplicationRemote
+			for _, u := range m.walReplicationURLs {
+				remotes = append(remotes, wal.ReplicationRemote{
+					Name: walReplicationRemoteName(u),
+					Client: &http.WALReplicationService{
+						Addr:               u,
+						Token:              m.walReplicationToken,
+						InsecureSkipVerify: m.walReplicationInsecureSkipVerify,
+					},
+				})
+			}
+			options = append(options, storage.WithWALReplication(remotes...))
+		}
+		// The retention enforcer must be the last option.
+		options = append(options, storage.WithRetentionEnforcer(bucketSvc))
+
+		m.engine = storage.NewEngine(m.enginePath, config, options...)
 		m.engine.WithLogger(m.logger)
 
 		if err := m.engine.Open(ctx); err != nil {
@@ -454,6 +816,20 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		m.reg.MustRegister(m.engine.PrometheusCollectors()...)
 
 		pointsWriter = m.engine
+		if m.writeBufferMaxLatency > 0 {
+			m.writeBuffer = storage.NewWriteBuffer(m.engine, m.writeBufferMaxLatency, m.writeBufferMaxPoints)
+			m.reg.MustRegister(m.writeBuffer.PrometheusCollectors()...)
+			pointsWriter = m.writeBuffer
+		}
+
+		m.replicationSvc = replication.NewService(m.kvService, secretSvc, m.replicationsPath)
+		m.replicationSvc.WithLogger(m.logger)
+		if err := m.replicationSvc.Open(ctx); err != nil {
+			m.logger.Error("failed to open replication service", zap.Error(err))
+			return err
+		}
+		m.reg.MustRegister(m.replicationSvc.PrometheusCollectors()...)
+		pointsWriter = m.replicationSvc.NewPointsWriter(pointsWriter)
 
 		const (
 			concurrencyQuota = 10
@@ -475,6 +851,7 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		}
 
 		m.queryController = pcontrol.New(cc)
+		m.queryController.SecretService = secretSvc
 		m.reg.MustRegister(m.queryController.PrometheusCollectors()...)
 	}
 
@@ -560,7 +937,15 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		Logger:               m.logger,
 		NewBucketService:     source.NewBucketService,
 		NewQueryService:      source.NewQueryService,
+		MaxWriteBodySize:     int64(m.maxWriteBodySize),
+		MaxWritePoints:       m.maxWritePoints,
+		WriteBatchSize:       m.writeBatchSize,
+		MaxWriteFuture:       m.maxWriteFuture,
+		MaxWALBatchSize:      int64(m.walReplicationMaxBatchSize),
 		PointsWriter:         pointsWriter,
+		SeriesDeleter:        m.engine,
+		SchemaReader:         m.engine,
+		WALApplier:           m.engine,
 		AuthorizationService: authSvc,
 		// Wrap the BucketService in a storage backed one that will ensure deleted buckets are removed from the storage engine.
 		BucketService:                   storage.NewBucketService(bucketSvc, m.engine),
@@ -583,6 +968,10 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		TaskService:                     taskSvc,
 		TelegrafService:                 telegrafSvc,
 		ScraperTargetStoreService:       scraperTargetSvc,
+		ReplicationService:              m.replicationSvc,
+		RoleService:                     m.kvService,
+		MFAService:                      m.kvService,
+		UserSessionService:              m.kvService,
 		ChronografService:               chronografSvc,
 		SecretService:                   secretSvc,
 		LookupService:                   lookupSvc,
@@ -591,6 +980,72 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		OrgLookupService:                m.kvService,
 	}
 
+	if m.oidcIssuer != "" {
+		groups := make([]membership.Mapping, 0, len(m.oidcGroupMappings))
+		for _, s := range m.oidcGroupMappings {
+			g, err := membership.ParseMapping(s)
+			if err != nil {
+				m.logger.Error("failed to parse OpenID Connect group mapping", zap.Error(err))
+				return err
+			}
+			groups = append(groups, g)
+		}
+
+		provider, err := oidc.NewProvider(ctx, oidc.Config{
+			Issuer:       m.oidcIssuer,
+			ClientID:     m.oidcClientID,
+			ClientSecret: m.oidcClientSecret,
+			RedirectURL:  m.oidcRedirectURL,
+			Scopes:       m.oidcScopes,
+		})
+		if err != nil {
+			m.logger.Error("failed to set up OpenID Connect identity provider", zap.Error(err))
+			return err
+		}
+		m.apibackend.OIDCProvider = provider
+		m.apibackend.OIDCUserProvisioner = &oidc.UserProvisioner{
+			UsernameClaim: m.oidcUsernameClaim,
+			GroupsClaim:   m.oidcGroupsClaim,
+			Membership: &membership.Syncer{
+				Mappings:                   groups,
+				OrganizationService:        orgSvc,
+				UserResourceMappingService: userResourceSvc,
+			},
+			UserService:         userSvc,
+			UserIdentityService: m.kvService,
+		}
+	}
+
+	if !m.auditLogDisabled {
+		var auditSvc platform.AuditLogService = m.kvService
+		if m.auditExportBucketID != "" {
+			orgID, err := platform.IDFromString(m.auditExportOrgID)
+			if err != nil {
+				m.logger.Error("failed to parse audit export organization ID", zap.Error(err))
+				return err
+			}
+			bucketID, err := platform.IDFromString(m.auditExportBucketID)
+			if err != nil {
+				m.logger.Error("failed to parse audit export bucket ID", zap.Error(err))
+				return err
+			}
+			exportSvc := audit.NewExportService(m.kvService, pointsWriter, *orgID, *bucketID)
+			exportSvc.Logger = m.logger.With(zap.String("service", "audit-export"))
+			auditSvc = exportSvc
+		}
+		m.apibackend.AuditLogService = auditSvc
+
+		if m.auditLogRetention > 0 {
+			pruner := audit.NewPruner(m.kvService, m.auditLogRetention)
+			pruner.Logger = m.logger.With(zap.String("service", "audit-prune"))
+			m.wg.Add(1)
+			go func() {
+				defer m.wg.Done()
+				pruner.Run(ctx)
+			}()
+		}
+	}
+
 	// HTTP server
 	httpLogger := m.logger.With(zap.String("service", "http"))
 	platformHandler := http.NewPlatformHandler(m.apibackend)
@@ -632,6 +1087,43 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 	return nil
 }
 
+// encryptedSecretService returns a secret service encrypting the secrets it
+// stores in the kv service. The secrets of all organizations are rotated to
+// the primary key, and the secrets stored before are encrypted.
+func (m *Launcher) encryptedSecretService(ctx context.Context) (*encrypted.SecretService, error) {
+	var (
+		keys *encrypted.Keyring
+		err  error
+	)
+	switch {
+	case m.secretKey != "":
+		keys, err = encrypted.ParseKeyring(m.secretKey)
+	case m.secretKeyPath != "":
+		keys, err = encrypted.LoadKeyring(m.secretKeyPath)
+	default:
+		err = errors.New("the encrypted secret store requires --secret-key or --secret-key-path")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	svc := encrypted.NewSecretService(m.kvService, keys)
+	orgs, _, err := m.kvService.FindOrganizations(ctx, platform.OrganizationFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orgs {
+		n, err := svc.Rotate(ctx, o.ID)
+		if err != nil {
+			return nil, fmt.Errorf("rotating secrets of org %s: %v", o.ID, err)
+		}
+		if n > 0 {
+			m.logger.Info("Rotated secrets", zap.Stringer("org_id", o.ID), zap.Int("secrets", n))
+		}
+	}
+	return svc, nil
+}
+
 // OrganizationService returns the internal organization service.
 func (m *Launcher) OrganizationService() platform.OrganizationService {
 	return m.apibackend.OrganizationService
@@ -676,3 +1168,15 @@ func (m *Launcher) TaskScheduler() taskbackend.Scheduler {
 func (m *Launcher) KeyValueService() *kv.Service {
 	return m.kvService
 }
+
+// walReplicationRemoteName returns the name of the WAL replication remote at the
+// URL, which is used to name its position file.
+func walReplicationRemoteName(u string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(u, "http://"), "https://")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSuffix(name, "/"))
+}
@@ -0,0 +1,185 @@
+package encrypted
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+// envelopePrefix starts the encrypted values of secrets, so that they are
+// told apart from the plaintext values stored before encryption was enabled.
+const envelopePrefix = "aesgcm1:"
+
+// An envelope is the encrypted value of a secret. The value is encrypted with
+// a data key generated for the secret, which is encrypted with a key of the
+// keyring. Rotating the keyring only encrypts the data key again.
+//
+// It is encoded as the envelope prefix followed by the base64 encoding of
+//
+//	key ID | nonce and encrypted data key | nonce and encrypted value
+//
+// The organization and the key of the secret are authenticated with its value,
+// so that encrypted values cannot be moved to other secrets.
+type envelope struct {
+	keyID   []byte
+	dataKey []byte
+	value   []byte
+}
+
+func isEnvelope(v string) bool {
+	return strings.HasPrefix(v, envelopePrefix)
+}
+
+func (e *envelope) encode() string {
+	b := make([]byte, 0, len(e.keyID)+len(e.dataKey)+len(e.value))
+	b = append(b, e.keyID...)
+	b = append(b, e.dataKey...)
+	b = append(b, e.value...)
+	return envelopePrefix + base64.StdEncoding.EncodeToString(b)
+}
+
+func decodeEnvelope(k *Keyring, v string) (*envelope, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, envelopePrefix))
+	if err != nil {
+		return nil, corruptError()
+	}
+	// All keys have the same nonce and overhead sizes.
+	dataKeySize := k.primary().aead.NonceSize() + KeySize + k.primary().aead.Overhead()
+	if len(b) < keyIDSize+dataKeySize {
+		return nil, corruptError()
+	}
+	return &envelope{
+		keyID:   b[:keyIDSize],
+		dataKey: b[keyIDSize : keyIDSize+dataKeySize],
+		value:   b[keyIDSize+dataKeySize:],
+	}, nil
+}
+
+// seal encrypts the value v of the secret name of orgID.
+func (k *Keyring) seal(orgID platform.ID, name, v string) (string, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	value, err := sealWith(aead, []byte(v), additionalData(orgID, name))
+	if err != nil {
+		return "", err
+	}
+
+	primary := k.primary()
+	wrapped, err := sealWith(primary.aead, dataKey, primary.id)
+	if err != nil {
+		return "", err
+	}
+
+	e := &envelope{keyID: primary.id, dataKey: wrapped, value: value}
+	return e.encode(), nil
+}
+
+// open decrypts the encrypted value v of the secret name of orgID.
+func (k *Keyring) open(orgID platform.ID, name, v string) (string, error) {
+	if !isEnvelope(v) {
+		return "", &platform.Error{
+			Code: platform.EInternal,
+			Msg:  "secret is not encrypted; restart influxd to encrypt existing secrets",
+		}
+	}
+	e, err := decodeEnvelope(k, v)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := k.unwrap(e)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	b, err := openWith(aead, e.value, additionalData(orgID, name))
+	if err != nil {
+		return "", corruptError()
+	}
+	return string(b), nil
+}
+
+// rotate encrypts the data key of the value v of the secret name of orgID
+// with the primary key, and reports whether it changed. Plaintext values are
+// encrypted, including those that start with the envelope prefix but cannot
+// be decoded as an envelope, which every encrypted value can.
+func (k *Keyring) rotate(orgID platform.ID, name, v string) (string, bool, error) {
+	var e *envelope
+	if isEnvelope(v) {
+		e, _ = decodeEnvelope(k, v)
+	}
+	if e == nil {
+		sealed, err := k.seal(orgID, name, v)
+		return sealed, err == nil, err
+	}
+
+	primary := k.primary()
+	if string(e.keyID) == string(primary.id) {
+		return v, false, nil
+	}
+
+	dataKey, err := k.unwrap(e)
+	if err != nil {
+		return "", false, err
+	}
+	wrapped, err := sealWith(primary.aead, dataKey, primary.id)
+	if err != nil {
+		return "", false, err
+	}
+	e.keyID, e.dataKey = primary.id, wrapped
+	return e.encode(), true, nil
+}
+
+func (k *Keyring) unwrap(e *envelope) ([]byte, error) {
+	key := k.find(e.keyID)
+	if key == nil {
+		return nil, &platform.Error{
+			Code: platform.EInternal,
+			Msg:  "secret is encrypted with a key missing from the keyring",
+		}
+	}
+	dataKey, err := openWith(key.aead, e.dataKey, key.id)
+	if err != nil {
+		return nil, corruptError()
+	}
+	return dataKey, nil
+}
+
+func sealWith(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func openWith(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	n := aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, corruptError()
+	}
+	return aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+}
+
+func additionalData(orgID platform.ID, name string) []byte {
+	return []byte(orgID.String() + "/" + name)
+}
+
+func corruptError() error {
+	return &platform.Error{
+		Code: platform.EInternal,
+		Msg:  "encrypted secret cannot be decrypted",
+	}
+}
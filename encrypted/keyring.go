@@ -0,0 +1,112 @@
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// KeySize is the size in bytes of the keys of a Keyring, which are
+	// AES-256 keys.
+	KeySize = 32
+
+	// keyIDSize is the size in bytes of the IDs of keys.
+	keyIDSize = 8
+)
+
+// Keyring holds the keys encrypting the keys of secrets. Its first key, the
+// primary key, encrypts the keys of new secrets. The others only decrypt the
+// keys of secrets encrypted before the primary key was rotated.
+type Keyring struct {
+	keys []*key
+}
+
+type key struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// NewKeyring returns a Keyring of keys, the first being the primary key.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring requires at least one key")
+	}
+
+	k := &Keyring{}
+	for i, b := range keys {
+		if len(b) != KeySize {
+			return nil, fmt.Errorf("key %d is %d bytes long, expected %d", i, len(b), KeySize)
+		}
+		aead, err := newAEAD(b)
+		if err != nil {
+			return nil, err
+		}
+		// Keys are identified by a hash, so that they don't need to be named
+		// and rotating them only requires adding the new primary key first.
+		sum := sha256.Sum256(b)
+		k.keys = append(k.keys, &key{id: sum[:keyIDSize], aead: aead})
+	}
+	return k, nil
+}
+
+// ParseKeyring parses a Keyring from base64 encoded keys separated by commas
+// or newlines, the first being the primary key. Lines starting with # are
+// ignored.
+func ParseKeyring(s string) (*Keyring, error) {
+	var keys [][]byte
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, v := range strings.Split(line, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			b, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("key %d is not base64 encoded: %v", len(keys), err)
+			}
+			keys = append(keys, b)
+		}
+	}
+	return NewKeyring(keys...)
+}
+
+// LoadKeyring reads a Keyring from a file of keys in the format read by
+// ParseKeyring.
+func LoadKeyring(path string) (*Keyring, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyring(string(b))
+}
+
+func (k *Keyring) primary() *key {
+	return k.keys[0]
+}
+
+func (k *Keyring) find(id []byte) *key {
+	for _, key := range k.keys {
+		if string(key.id) == string(id) {
+			return key
+		}
+	}
+	return nil
+}
+
+func newAEAD(b []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(b)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,125 @@
+// Package encrypted implements platform.SecretService by encrypting the values
+// of the secrets stored by another SecretService, such as the kv service, so
+// that single node deployments don't need an external secret store.
+//
+// Secrets are encrypted with AES-GCM envelope encryption: every value is
+// encrypted with its own data key, which is encrypted with the primary key of
+// a Keyring.
+package encrypted
+
+import (
+	"context"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+var _ platform.SecretService = (*SecretService)(nil)
+
+// SecretService encrypts the values of the secrets stored by another
+// SecretService.
+type SecretService struct {
+	store platform.SecretService
+	keys  *Keyring
+}
+
+// NewSecretService returns a SecretService encrypting the secrets it stores
+// in s with keys.
+func NewSecretService(s platform.SecretService, keys *Keyring) *SecretService {
+	return &SecretService{
+		store: s,
+		keys:  keys,
+	}
+}
+
+// LoadSecret retrieves the secret value v found at key k for organization orgID.
+func (s *SecretService) LoadSecret(ctx context.Context, orgID platform.ID, k string) (string, error) {
+	v, err := s.store.LoadSecret(ctx, orgID, k)
+	if err != nil {
+		return "", err
+	}
+	return s.keys.open(orgID, k, v)
+}
+
+// GetSecretKeys retrieves all secret keys that are stored for the organization orgID.
+func (s *SecretService) GetSecretKeys(ctx context.Context, orgID platform.ID) ([]string, error) {
+	return s.store.GetSecretKeys(ctx, orgID)
+}
+
+// PutSecret stores the secret pair (k,v) for the organization orgID.
+func (s *SecretService) PutSecret(ctx context.Context, orgID platform.ID, k string, v string) error {
+	sealed, err := s.keys.seal(orgID, k, v)
+	if err != nil {
+		return err
+	}
+	return s.store.PutSecret(ctx, orgID, k, sealed)
+}
+
+// PutSecrets puts all provided secrets and overwrites any previous values.
+func (s *SecretService) PutSecrets(ctx context.Context, orgID platform.ID, m map[string]string) error {
+	sealed, err := s.seal(orgID, m)
+	if err != nil {
+		return err
+	}
+	return s.store.PutSecrets(ctx, orgID, sealed)
+}
+
+// PatchSecrets patches all provided secrets and updates any previous values.
+func (s *SecretService) PatchSecrets(ctx context.Context, orgID platform.ID, m map[string]string) error {
+	sealed, err := s.seal(orgID, m)
+	if err != nil {
+		return err
+	}
+	return s.store.PatchSecrets(ctx, orgID, sealed)
+}
+
+// DeleteSecret removes a single secret from the secret store.
+func (s *SecretService) DeleteSecret(ctx context.Context, orgID platform.ID, ks ...string) error {
+	return s.store.DeleteSecret(ctx, orgID, ks...)
+}
+
+func (s *SecretService) seal(orgID platform.ID, m map[string]string) (map[string]string, error) {
+	sealed := make(map[string]string, len(m))
+	for k, v := range m {
+		sv, err := s.keys.seal(orgID, k, v)
+		if err != nil {
+			return nil, err
+		}
+		sealed[k] = sv
+	}
+	return sealed, nil
+}
+
+// Rotate encrypts the data keys of the secrets of orgID with the primary key
+// of the keyring, if they were encrypted with another key, and encrypts the
+// secrets stored in plaintext. It returns the number of secrets updated.
+// Once the secrets of all organizations are rotated, the other keys can be
+// removed from the keyring.
+func (s *SecretService) Rotate(ctx context.Context, orgID platform.ID) (int, error) {
+	keys, err := s.store.GetSecretKeys(ctx, orgID)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := make(map[string]string)
+	for _, k := range keys {
+		v, err := s.store.LoadSecret(ctx, orgID, k)
+		if err != nil {
+			return 0, err
+		}
+		rv, ok, err := s.keys.rotate(orgID, k, v)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			rotated[k] = rv
+		}
+	}
+	if len(rotated) == 0 {
+		return 0, nil
+	}
+
+	if err := s.store.PatchSecrets(ctx, orgID, rotated); err != nil {
+		return 0, err
+	}
+	return len(rotated), nil
+}
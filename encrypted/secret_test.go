@@ -0,0 +1,160 @@
+package encrypted_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/encrypted"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, encrypted.KeySize)
+	key2 = bytes.Repeat([]byte{2}, encrypted.KeySize)
+)
+
+func newKeyring(t *testing.T, keys ...[]byte) *encrypted.Keyring {
+	t.Helper()
+	k, err := encrypted.NewKeyring(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func newStore(t *testing.T) *kv.Service {
+	t.Helper()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(context.Background()); err != nil {
+		t.Fatalf("error initializing kv service: %v", err)
+	}
+	return svc
+}
+
+func initSecretService(f influxdbtesting.SecretServiceFields, t *testing.T) (influxdb.SecretService, func()) {
+	svc := encrypted.NewSecretService(newStore(t), newKeyring(t, key1))
+	ctx := context.Background()
+	for _, s := range f.Secrets {
+		for k, v := range s.Env {
+			if err := svc.PutSecret(ctx, s.OrganizationID, k, v); err != nil {
+				t.Fatalf("failed to populate secrets: %v", err)
+			}
+		}
+	}
+	return svc, func() {}
+}
+
+func TestSecretService(t *testing.T) {
+	influxdbtesting.SecretService(initSecretService, t)
+}
+
+func TestSecretService_Encrypts(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	svc := encrypted.NewSecretService(store, newKeyring(t, key1))
+
+	if err := svc.PutSecret(ctx, 1, "api_key", "abc123xyz"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := store.LoadSecret(ctx, 1, "api_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(v, "abc123xyz") {
+		t.Fatalf("secret is stored in plaintext: %q", v)
+	}
+
+	// Encrypted values cannot be moved to other secrets.
+	if err := store.PutSecret(ctx, 2, "api_key", v); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.LoadSecret(ctx, 2, "api_key"); influxdb.ErrorCode(err) != influxdb.EInternal {
+		t.Fatalf("expected an internal error decrypting a moved secret, got %v", err)
+	}
+
+	// Plaintext values are not read.
+	if err := store.PutSecret(ctx, 1, "plain", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.LoadSecret(ctx, 1, "plain"); influxdb.ErrorCode(err) != influxdb.EInternal {
+		t.Fatalf("expected an internal error reading a plaintext secret, got %v", err)
+	}
+
+	// Secrets cannot be read without their key.
+	other := encrypted.NewSecretService(store, newKeyring(t, key2))
+	if _, err := other.LoadSecret(ctx, 1, "api_key"); influxdb.ErrorCode(err) != influxdb.EInternal {
+		t.Fatalf("expected an internal error reading a secret with another key, got %v", err)
+	}
+}
+
+func TestSecretService_Rotate(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if err := store.PutSecret(ctx, 1, "plain", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	// A plaintext value may start like an encrypted one.
+	if err := store.PutSecret(ctx, 1, "prefixed", "aesgcm1:abc"); err != nil {
+		t.Fatal(err)
+	}
+	old := encrypted.NewSecretService(store, newKeyring(t, key1))
+	if err := old.PutSecret(ctx, 1, "api_key", "abc123xyz"); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := encrypted.NewSecretService(store, newKeyring(t, key2, key1))
+	if v, err := svc.LoadSecret(ctx, 1, "api_key"); err != nil || v != "abc123xyz" {
+		t.Fatalf("unexpected secret before rotation: %q, %v", v, err)
+	}
+
+	n, err := svc.Rotate(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 rotated secrets, got %d", n)
+	}
+	if n, err := svc.Rotate(ctx, 1); err != nil || n != 0 {
+		t.Fatalf("expected rotated secrets not to be rotated again: %d, %v", n, err)
+	}
+
+	// Once rotated, secrets are read with the new key only.
+	rotated := encrypted.NewSecretService(store, newKeyring(t, key2))
+	for k, want := range map[string]string{"api_key": "abc123xyz", "plain": "abc", "prefixed": "aesgcm1:abc"} {
+		if v, err := rotated.LoadSecret(ctx, 1, k); err != nil || v != want {
+			t.Fatalf("unexpected secret %q after rotation: %q, %v", k, v, err)
+		}
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(key1)
+	k2 := base64.StdEncoding.EncodeToString(key2)
+
+	tests := []struct {
+		name    string
+		keys    string
+		wantErr bool
+	}{
+		{name: "single key", keys: k1},
+		{name: "comma separated", keys: k2 + "," + k1},
+		{name: "lines", keys: "# primary\n" + k2 + "\n\n" + k1 + "\n"},
+		{name: "empty", keys: "\n# no keys\n", wantErr: true},
+		{name: "not base64", keys: "not a key!", wantErr: true},
+		{name: "short key", keys: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encrypted.ParseKeyring(tt.keys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
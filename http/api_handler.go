@@ -3,10 +3,12 @@ package http
 import (
 	http "net/http"
 	"strings"
+	"time"
 
 	influxdb "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/authorizer"
 	"github.com/influxdata/influxdb/chronograf/server"
+	"github.com/influxdata/influxdb/oidc"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/storage"
 	"go.uber.org/zap"
@@ -14,26 +16,32 @@ import (
 
 // APIHandler is a collection of all the service handlers.
 type APIHandler struct {
-	BucketHandler        *BucketHandler
-	UserHandler          *UserHandler
-	OrgHandler           *OrgHandler
-	AuthorizationHandler *AuthorizationHandler
-	DashboardHandler     *DashboardHandler
-	LabelHandler         *LabelHandler
-	AssetHandler         *AssetHandler
-	ChronografHandler    *ChronografHandler
-	ScraperHandler       *ScraperHandler
-	SourceHandler        *SourceHandler
-	VariableHandler      *VariableHandler
-	TaskHandler          *TaskHandler
-	TelegrafHandler      *TelegrafHandler
-	QueryHandler         *FluxHandler
-	ProtoHandler         *ProtoHandler
-	WriteHandler         *WriteHandler
-	DocumentHandler      *DocumentHandler
-	SetupHandler         *SetupHandler
-	SessionHandler       *SessionHandler
-	SwaggerHandler       http.Handler
+	BucketHandler         *BucketHandler
+	UserHandler           *UserHandler
+	OrgHandler            *OrgHandler
+	AuthorizationHandler  *AuthorizationHandler
+	DashboardHandler      *DashboardHandler
+	LabelHandler          *LabelHandler
+	AssetHandler          *AssetHandler
+	ChronografHandler     *ChronografHandler
+	ScraperHandler        *ScraperHandler
+	SourceHandler         *SourceHandler
+	VariableHandler       *VariableHandler
+	TaskHandler           *TaskHandler
+	TelegrafHandler       *TelegrafHandler
+	QueryHandler          *FluxHandler
+	ProtoHandler          *ProtoHandler
+	WriteHandler          *WriteHandler
+	DeleteHandler         *DeleteHandler
+	ReplicationHandler    *ReplicationHandler
+	RoleHandler           *RoleHandler
+	WALReplicationHandler *WALReplicationHandler
+	DocumentHandler       *DocumentHandler
+	SetupHandler          *SetupHandler
+	SessionHandler        *SessionHandler
+	OIDCHandler           *OIDCHandler
+	AuditHandler          *AuditHandler
+	SwaggerHandler        http.Handler
 }
 
 // APIBackend is all services and associated parameters required to construct
@@ -45,7 +53,23 @@ type APIBackend struct {
 	NewBucketService func(*influxdb.Source) (influxdb.BucketService, error)
 	NewQueryService  func(*influxdb.Source) (query.ProxyQueryService, error)
 
+	// Limits on the size of writes, and the number of points written at once.
+	MaxWriteBodySize int64
+	MaxWritePoints   int
+	WriteBatchSize   int
+
+	// MaxWriteFuture is how far in the future the time of a written point may
+	// be. It is not limited if zero.
+	MaxWriteFuture time.Duration
+
+	// MaxWALBatchSize is the maximum size in bytes of a batch of replicated WAL
+	// entries. If zero, DefaultMaxWALBatchSize is used.
+	MaxWALBatchSize int64
+
 	PointsWriter                    storage.PointsWriter
+	SeriesDeleter                   storage.SeriesDeleter
+	SchemaReader                    storage.SchemaReader
+	WALApplier                      storage.WALApplier
 	AuthorizationService            influxdb.AuthorizationService
 	BucketService                   influxdb.BucketService
 	SessionService                  influxdb.SessionService
@@ -67,6 +91,8 @@ type APIBackend struct {
 	TaskService                     influxdb.TaskService
 	TelegrafService                 influxdb.TelegrafConfigStore
 	ScraperTargetStoreService       influxdb.ScraperTargetStoreService
+	ReplicationService              influxdb.ReplicationService
+	RoleService                     influxdb.RoleService
 	SecretService                   influxdb.SecretService
 	LookupService                   influxdb.LookupService
 	ChronografService               *server.Service
@@ -74,6 +100,22 @@ type APIBackend struct {
 	OrgLookupService                authorizer.OrganizationService
 	ViewService                     influxdb.ViewService
 	DocumentService                 influxdb.DocumentService
+
+	// OIDCProvider is the identity provider users sign in with, provisioned
+	// by OIDCUserProvisioner. Signing in with it is disabled if it is nil.
+	OIDCProvider        *oidc.Provider
+	OIDCUserProvisioner *oidc.UserProvisioner
+
+	// AuditLogService records the calls of the API changing something.
+	// They are not recorded if it is nil.
+	AuditLogService influxdb.AuditLogService
+
+	// MFAService manages the multi-factor authentication of users. Signing
+	// in does not require codes if it is nil.
+	MFAService influxdb.MFAService
+
+	// UserSessionService lists and revokes the sessions of users.
+	UserSessionService influxdb.UserSessionService
 }
 
 // NewAPIHandler constructs all api handlers beneath it and returns an APIHandler
@@ -89,6 +131,16 @@ func NewAPIHandler(b *APIBackend) *APIHandler {
 	sessionBackend := NewSessionBackend(b)
 	h.SessionHandler = NewSessionHandler(sessionBackend)
 
+	if b.OIDCProvider != nil {
+		h.OIDCHandler = NewOIDCHandler(NewOIDCBackend(b))
+	}
+
+	if b.AuditLogService != nil {
+		auditBackend := NewAuditBackend(b)
+		auditBackend.AuditLogService = authorizer.NewAuditLogService(b.AuditLogService)
+		h.AuditHandler = NewAuditHandler(auditBackend)
+	}
+
 	bucketBackend := NewBucketBackend(b)
 	bucketBackend.BucketService = authorizer.NewBucketService(b.BucketService)
 	h.BucketHandler = NewBucketHandler(bucketBackend)
@@ -99,6 +151,12 @@ func NewAPIHandler(b *APIBackend) *APIHandler {
 
 	userBackend := NewUserBackend(b)
 	userBackend.UserService = authorizer.NewUserService(b.UserService)
+	if b.MFAService != nil {
+		userBackend.MFAService = authorizer.NewMFAService(b.MFAService)
+	}
+	if b.UserSessionService != nil {
+		userBackend.UserSessionService = authorizer.NewUserSessionService(b.UserSessionService)
+	}
 	h.UserHandler = NewUserHandler(userBackend)
 
 	dashboardBackend := NewDashboardBackend(b)
@@ -137,6 +195,20 @@ func NewAPIHandler(b *APIBackend) *APIHandler {
 	writeBackend := NewWriteBackend(b)
 	h.WriteHandler = NewWriteHandler(writeBackend)
 
+	deleteBackend := NewDeleteBackend(b)
+	h.DeleteHandler = NewDeleteHandler(deleteBackend)
+
+	replicationBackend := NewReplicationBackend(b)
+	replicationBackend.ReplicationService = authorizer.NewReplicationService(b.ReplicationService)
+	h.ReplicationHandler = NewReplicationHandler(replicationBackend)
+
+	roleBackend := NewRoleBackend(b)
+	roleBackend.RoleService = authorizer.NewRoleService(b.RoleService)
+	h.RoleHandler = NewRoleHandler(roleBackend)
+
+	walReplicationBackend := NewWALReplicationBackend(b)
+	h.WALReplicationHandler = NewWALReplicationHandler(walReplicationBackend)
+
 	fluxBackend := NewFluxBackend(b)
 	h.QueryHandler = NewFluxHandler(fluxBackend)
 
@@ -151,9 +223,11 @@ func NewAPIHandler(b *APIBackend) *APIHandler {
 var apiLinks = map[string]interface{}{
 	// when adding new links, please take care to keep this list alphabetical
 	// as this makes it easier to verify values against the swagger document.
+	"audit":          "/api/v2/audit",
 	"authorizations": "/api/v2/authorizations",
 	"buckets":        "/api/v2/buckets",
 	"dashboards":     "/api/v2/dashboards",
+	"delete":         "/api/v2/delete",
 	"external": map[string]string{
 		"statusFeed": "https://www.influxdata.com/feed/json",
 	},
@@ -169,12 +243,14 @@ var apiLinks = map[string]interface{}{
 		"spec":        "/api/v2/query/spec",
 		"suggestions": "/api/v2/query/suggestions",
 	},
-	"setup":    "/api/v2/setup",
-	"signin":   "/api/v2/signin",
-	"signout":  "/api/v2/signout",
-	"sources":  "/api/v2/sources",
-	"scrapers": "/api/v2/scrapers",
-	"swagger":  "/api/v2/swagger.json",
+	"replications": "/api/v2/replications",
+	"roles":        "/api/v2/roles",
+	"setup":        "/api/v2/setup",
+	"signin":       "/api/v2/signin",
+	"signout":      "/api/v2/signout",
+	"sources":      "/api/v2/sources",
+	"scrapers":     "/api/v2/scrapers",
+	"swagger":      "/api/v2/swagger.json",
 	"system": map[string]string{
 		"metrics": "/metrics",
 		"debug":   "/debug/pprof",
@@ -207,6 +283,16 @@ func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasPrefix(r.URL.Path, oidcSigninPath) && h.OIDCHandler != nil {
+		h.OIDCHandler.ServeHTTP(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, auditPath) && h.AuditHandler != nil {
+		h.AuditHandler.ServeHTTP(w, r)
+		return
+	}
+
 	if r.URL.Path == "/api/v2/signin" || r.URL.Path == "/api/v2/signout" {
 		h.SessionHandler.ServeHTTP(w, r)
 		return
@@ -222,6 +308,26 @@ func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.HasPrefix(r.URL.Path, "/api/v2/delete") {
+		h.DeleteHandler.ServeHTTP(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/v2/replications") {
+		h.ReplicationHandler.ServeHTTP(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, rolesPath) {
+		h.RoleHandler.ServeHTTP(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/v2/replication/") {
+		h.WALReplicationHandler.ServeHTTP(w, r)
+		return
+	}
+
 	if strings.HasPrefix(r.URL.Path, "/api/v2/query") {
 		h.QueryHandler.ServeHTTP(w, r)
 		return
@@ -0,0 +1,388 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/audit"
+	platcontext "github.com/influxdata/influxdb/context"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxAuditSnapshotSize is the default size above which the states of
+// resources are not compared.
+const DefaultMaxAuditSnapshotSize = 256 * 1024
+
+// AuditMiddleware is a middleware recording the calls of the API that change
+// resources or write or delete data in the audit log. It must be called with
+// the authorizer of the request on its context.
+//
+// The changes of a resource are found by comparing the responses of a GET
+// request to the resource of the call before and after it. Each call reads a
+// single resource: /api/v2/<type>/<id> for calls to a resource, and
+// /api/v2/<type>/<id>/<collection> for calls to its sub-resources, such as
+// its members or labels. The changes are best-effort: they are missing if
+// the resource cannot be read, and since the reads are not atomic with the
+// call, they may include changes made by concurrent calls. Writes and
+// deletes of data only record what they target, never the data.
+type AuditMiddleware struct {
+	Logger *zap.Logger
+
+	AuditLogService influxdb.AuditLogService
+
+	// OrganizationService resolves the organizations data is written to or
+	// deleted from.
+	OrganizationService influxdb.OrganizationService
+
+	// MaxSnapshotSize is the size above which the states of a resource are
+	// not compared.
+	MaxSnapshotSize int
+
+	Handler http.Handler
+}
+
+// NewAuditMiddleware returns an AuditMiddleware recording the calls of h in s.
+func NewAuditMiddleware(h http.Handler, s influxdb.AuditLogService) *AuditMiddleware {
+	return &AuditMiddleware{
+		Logger:          zap.NewNop(),
+		AuditLogService: s,
+		MaxSnapshotSize: DefaultMaxAuditSnapshotSize,
+		Handler:         h,
+	}
+}
+
+// auditedPath returns whether calls to p are recorded, when they change
+// something.
+func auditedPath(p string) bool {
+	if !strings.HasPrefix(p, "/api/v2/") {
+		return false
+	}
+	for _, prefix := range []string{
+		"/api/v2/query",
+		"/api/v2/signin",
+		"/api/v2/signout",
+		// WAL replication is the instance's own writes applied elsewhere.
+		"/api/v2/replication/",
+	} {
+		if strings.HasPrefix(p, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func auditedMethod(m string) bool {
+	switch m {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+// ServeHTTP serves the request with the next handler and records it if it
+// is a call that changes something.
+func (h *AuditMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !auditedMethod(r.Method) || !auditedPath(r.URL.Path) {
+		h.Handler.ServeHTTP(w, r)
+		return
+	}
+
+	e := newAuditEvent(r)
+	switch r.URL.Path {
+	case "/api/v2/write":
+		h.serveWrite(w, r, e)
+	case "/api/v2/delete":
+		h.serveDelete(w, r, e)
+	default:
+		h.serveResource(w, r, e)
+	}
+
+	// The call is recorded even if the client went away.
+	if err := h.AuditLogService.AppendAuditEvent(context.Background(), e); err != nil {
+		h.Logger.Error("Failed to record audit event",
+			zap.String("method", e.Method),
+			zap.String("path", e.Path),
+			zap.Error(err))
+	}
+}
+
+// newAuditEvent returns the event of a call, with who made it and from where.
+func newAuditEvent(r *http.Request) *influxdb.AuditEvent {
+	e := &influxdb.AuditEvent{
+		Time:         time.Now().UTC(),
+		RemoteAddr:   r.RemoteAddr,
+		ForwardedFor: r.Header.Get("X-Forwarded-For"),
+		Method:       r.Method,
+		Path:         r.URL.Path,
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		e.RemoteAddr = host
+	}
+	if a, err := platcontext.GetAuthorizer(r.Context()); err == nil && a != nil {
+		e.AuthorizerKind = a.Kind()
+		e.AuthorizerID = a.Identifier()
+		e.UserID = a.GetUserID()
+	}
+	return e
+}
+
+// serveWrite records the bucket points are written to and the size of the
+// write.
+func (h *AuditMiddleware) serveWrite(w http.ResponseWriter, r *http.Request, e *influxdb.AuditEvent) {
+	e.Action = influxdb.AuditActionWrite
+	e.ResourceType = "write"
+	h.setDataTarget(r, e)
+	if p := r.URL.Query().Get("precision"); p != "" {
+		e.Metadata["precision"] = p
+	}
+
+	body := &countingReader{r: r.Body}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+
+	sw := newStatusResponseWriter(w)
+	h.Handler.ServeHTTP(sw, r)
+	e.Status = sw.code()
+	e.Metadata["bytes"] = strconv.FormatInt(body.n, 10)
+}
+
+// serveDelete records the bucket series are deleted from and the predicate
+// of the deletion.
+func (h *AuditMiddleware) serveDelete(w http.ResponseWriter, r *http.Request, e *influxdb.AuditEvent) {
+	e.Action = influxdb.AuditActionDelete
+	e.ResourceType = "delete"
+	h.setDataTarget(r, e)
+
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(h.MaxSnapshotSize)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+	if err == nil && len(b) <= h.MaxSnapshotSize {
+		var req struct {
+			Predicate string `json:"predicate"`
+		}
+		if json.Unmarshal(b, &req) == nil {
+			e.Metadata["predicate"] = req.Predicate
+		}
+	}
+
+	sw := newStatusResponseWriter(w)
+	h.Handler.ServeHTTP(sw, r)
+	e.Status = sw.code()
+}
+
+// setDataTarget sets the organization and bucket data is written to or
+// deleted from.
+func (h *AuditMiddleware) setDataTarget(r *http.Request, e *influxdb.AuditEvent) {
+	q := r.URL.Query()
+	e.Metadata = map[string]string{
+		"org":    q.Get("org"),
+		"bucket": q.Get("bucket"),
+	}
+	if h.OrganizationService == nil || q.Get("org") == "" {
+		return
+	}
+	if o, err := findOrganization(r.Context(), h.OrganizationService, q.Get("org")); err == nil {
+		e.OrgID = o.ID
+	}
+}
+
+// serveResource records the changes of the resource of the call.
+func (h *AuditMiddleware) serveResource(w http.ResponseWriter, r *http.Request, e *influxdb.AuditEvent) {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v2/"), "/"), "/")
+	e.ResourceType = segments[0]
+	if len(segments) > 1 {
+		if id, err := influxdb.IDFromString(segments[1]); err == nil {
+			e.ResourceID = *id
+		}
+	}
+	if e.ResourceType == "orgs" {
+		e.OrgID = e.ResourceID
+	}
+
+	// A call to a collection creates a resource, whose state is the
+	// response. Otherwise the resource, or the collection of the call,
+	// is read before and after it.
+	collection := len(segments) == 1
+	switch {
+	case collection:
+		e.Action = influxdb.AuditActionCreate
+	case r.Method == "DELETE" && len(segments) == 2:
+		e.Action = influxdb.AuditActionDelete
+	default:
+		e.Action = influxdb.AuditActionUpdate
+	}
+
+	var (
+		snapshotPath string
+		before       []byte
+		tooLarge     bool
+	)
+	if !collection {
+		p := snapshotResourcePath(segments)
+		if b, truncated, ok := h.snapshot(r, p); ok {
+			snapshotPath, before, tooLarge = p, b, truncated
+		}
+	}
+
+	rw := &auditResponseWriter{
+		statusResponseWriter: newStatusResponseWriter(w),
+		max:                  h.MaxSnapshotSize,
+	}
+	if !collection {
+		// Only the response of a creation is kept.
+		rw.max = 0
+	}
+	h.Handler.ServeHTTP(rw, r)
+	e.Status = rw.code()
+	if e.Status >= 400 {
+		return
+	}
+
+	var after []byte
+	switch {
+	case collection:
+		after = rw.body.Bytes()
+		tooLarge = rw.truncated
+	case snapshotPath != "" && e.Action != influxdb.AuditActionDelete:
+		// A deleted resource cannot be read anymore.
+		var truncated bool
+		after, truncated, _ = h.snapshot(r, snapshotPath)
+		tooLarge = tooLarge || truncated
+	}
+	if tooLarge {
+		e.Metadata = map[string]string{"changes": "omitted, the resource is too large"}
+		return
+	}
+
+	if collection {
+		var created struct {
+			ID    string `json:"id"`
+			OrgID string `json:"orgID"`
+		}
+		if json.Unmarshal(after, &created) == nil {
+			if id, err := influxdb.IDFromString(created.ID); err == nil {
+				e.ResourceID = *id
+				if e.ResourceType == "orgs" {
+					e.OrgID = *id
+				}
+			}
+			if id, err := influxdb.IDFromString(created.OrgID); err == nil {
+				e.OrgID = *id
+			}
+		}
+	} else if !e.OrgID.Valid() {
+		e.OrgID = snapshotOrgID(before, after)
+	}
+
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		// The response is not a JSON document.
+		return
+	}
+	e.Changes = changes
+}
+
+// snapshotResourcePath returns the path of the resource read before and after
+// a call to the path of segments: the resource itself, or the collection of
+// its sub-resources the call is to.
+func snapshotResourcePath(segments []string) string {
+	if len(segments) > 3 {
+		segments = segments[:3]
+	}
+	return "/api/v2/" + strings.Join(segments, "/")
+}
+
+// snapshot returns the response to a GET request to p on behalf of the
+// caller of r, if it succeeds, and whether it was larger than the limit.
+func (h *AuditMiddleware) snapshot(r *http.Request, p string) (b []byte, truncated bool, ok bool) {
+	req, err := http.NewRequest("GET", p, nil)
+	if err != nil {
+		return nil, false, false
+	}
+	req = req.WithContext(r.Context())
+	req.RemoteAddr = r.RemoteAddr
+
+	rw := &auditResponseWriter{
+		statusResponseWriter: newStatusResponseWriter(&discardResponseWriter{header: http.Header{}}),
+		max:                  h.MaxSnapshotSize,
+	}
+	h.Handler.ServeHTTP(rw, req)
+	if rw.code() != http.StatusOK {
+		return nil, false, false
+	}
+	return rw.body.Bytes(), rw.truncated, true
+}
+
+// snapshotOrgID returns the organization of a resource from its states.
+func snapshotOrgID(snapshots ...[]byte) influxdb.ID {
+	for _, s := range snapshots {
+		var res struct {
+			OrgID string `json:"orgID"`
+		}
+		if len(s) == 0 || json.Unmarshal(s, &res) != nil {
+			continue
+		}
+		if id, err := influxdb.IDFromString(res.OrgID); err == nil {
+			return *id
+		}
+	}
+	return 0
+}
+
+// auditResponseWriter captures the status of a response and at most max
+// bytes of its body.
+type auditResponseWriter struct {
+	*statusResponseWriter
+	max       int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if n := w.max - w.body.Len(); n > 0 {
+		if len(b) > n {
+			w.body.Write(b[:n])
+			w.truncated = true
+		} else {
+			w.body.Write(b)
+		}
+	} else if w.max > 0 && len(b) > 0 {
+		w.truncated = true
+	}
+	return w.statusResponseWriter.Write(b)
+}
+
+// discardResponseWriter is the response writer of the requests reading the
+// states of resources, whose responses are not sent.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += int64(n)
+	return n, err
+}
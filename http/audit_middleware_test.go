@@ -0,0 +1,190 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	platcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+)
+
+// newAuditTestHandler returns a handler of a collection of labels, which
+// are plain JSON documents.
+func newAuditTestHandler() http.Handler {
+	labels := map[string]map[string]interface{}{}
+	r := NewRouter()
+	r.HandlerFunc("POST", "/api/v2/labels", func(w http.ResponseWriter, r *http.Request) {
+		l := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&l)
+		l["id"] = "0000000000000003"
+		l["orgID"] = "0000000000000004"
+		l["links"] = map[string]string{"self": "/api/v2/labels/0000000000000003"}
+		labels["0000000000000003"] = l
+		encodeResponse(r.Context(), w, http.StatusCreated, l)
+	})
+	r.HandlerFunc("GET", "/api/v2/labels/:id", func(w http.ResponseWriter, r *http.Request) {
+		l, ok := labels[r.URL.Path[len("/api/v2/labels/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		encodeResponse(r.Context(), w, http.StatusOK, l)
+	})
+	r.HandlerFunc("PATCH", "/api/v2/labels/:id", func(w http.ResponseWriter, r *http.Request) {
+		l := labels[r.URL.Path[len("/api/v2/labels/"):]]
+		json.NewDecoder(r.Body).Decode(&l)
+		encodeResponse(r.Context(), w, http.StatusOK, l)
+	})
+	r.HandlerFunc("DELETE", "/api/v2/labels/:id", func(w http.ResponseWriter, r *http.Request) {
+		delete(labels, r.URL.Path[len("/api/v2/labels/"):])
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.HandlerFunc("POST", "/api/v2/write", func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.HandlerFunc("POST", "/api/v2/delete", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Predicate string `json:"predicate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Predicate == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return r
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	org := &platform.Organization{Name: "acme"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+
+	// The paths of the resources read by the middleware.
+	var reads []string
+	next := newAuditTestHandler()
+	h := NewAuditMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			reads = append(reads, r.URL.Path)
+		}
+		next.ServeHTTP(w, r)
+	}), svc)
+	h.OrganizationService = svc
+	auth := &platform.Authorization{ID: 1, UserID: 2}
+
+	do := func(method, path, body string) int {
+		r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		r.RemoteAddr = "10.0.0.1:5000"
+		r.Header.Set("X-Forwarded-For", "192.168.0.1")
+		r = r.WithContext(platcontext.SetAuthorizer(r.Context(), auth))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	last := func() *platform.AuditEvent {
+		es, _, err := svc.FindAuditEvents(ctx, platform.AuditEventFilter{}, platform.FindOptions{Descending: true, Limit: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(es) != 1 {
+			t.Fatalf("expected an event, got %d", len(es))
+		}
+		return es[0]
+	}
+
+	if code := do("POST", "/api/v2/labels", `{"name":"a","token":"t"}`); code != http.StatusCreated {
+		t.Fatalf("unexpected status creating: %d", code)
+	}
+	e := last()
+	if e.Action != platform.AuditActionCreate || e.ResourceType != "labels" || e.ResourceID != 3 || e.OrgID != 4 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if e.AuthorizerKind != platform.AuthorizationKind || e.AuthorizerID != 1 || e.UserID != 2 {
+		t.Fatalf("unexpected authorizer: %+v", e)
+	}
+	if e.RemoteAddr != "10.0.0.1" || e.ForwardedFor != "192.168.0.1" || e.Status != http.StatusCreated {
+		t.Fatalf("unexpected request: %+v", e)
+	}
+	want := map[string]interface{}{"id": "0000000000000003", "orgID": "0000000000000004", "name": "a", "token": "[REDACTED]"}
+	if len(e.Changes) != 1 || e.Changes[0].Path != "" || !reflect.DeepEqual(e.Changes[0].New, want) {
+		t.Fatalf("unexpected changes: %+v", e.Changes)
+	}
+
+	if len(reads) != 0 {
+		t.Fatalf("unexpected reads creating: %v", reads)
+	}
+
+	if code := do("PATCH", "/api/v2/labels/0000000000000003", `{"name":"b"}`); code != http.StatusOK {
+		t.Fatalf("unexpected status updating: %d", code)
+	}
+	if exp := []string{"/api/v2/labels/0000000000000003", "/api/v2/labels/0000000000000003"}; !reflect.DeepEqual(reads, exp) {
+		t.Fatalf("unexpected reads updating: %v", reads)
+	}
+	reads = nil
+	e = last()
+	if e.Action != platform.AuditActionUpdate || e.ResourceID != 3 || e.OrgID != 4 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if len(e.Changes) != 1 || e.Changes[0] != (platform.AuditChange{Path: "/name", Old: "a", New: "b"}) {
+		t.Fatalf("unexpected changes: %+v", e.Changes)
+	}
+
+	// Calls to sub-resources read the collection of the sub-resources only,
+	// without falling back to their parents.
+	do("DELETE", "/api/v2/labels/0000000000000003/members/0000000000000002", "")
+	if exp := []string{"/api/v2/labels/0000000000000003/members"}; !reflect.DeepEqual(reads, exp) {
+		t.Fatalf("unexpected reads of a sub-resource: %v", reads)
+	}
+	reads = nil
+
+	if code := do("DELETE", "/api/v2/labels/0000000000000003", ""); code != http.StatusNoContent {
+		t.Fatalf("unexpected status deleting: %d", code)
+	}
+	if exp := []string{"/api/v2/labels/0000000000000003"}; !reflect.DeepEqual(reads, exp) {
+		t.Fatalf("unexpected reads deleting: %v", reads)
+	}
+	e = last()
+	if e.Action != platform.AuditActionDelete || len(e.Changes) != 1 || e.Changes[0].New != nil || e.Changes[0].Old == nil {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	if code := do("POST", "/api/v2/write?org=acme&bucket=b&precision=s", "m f=1 1\n"); code != http.StatusNoContent {
+		t.Fatalf("unexpected status writing: %d", code)
+	}
+	e = last()
+	if e.Action != platform.AuditActionWrite || e.OrgID != org.ID || len(e.Changes) != 0 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if e.Metadata["bucket"] != "b" || e.Metadata["precision"] != "s" || e.Metadata["bytes"] != "8" {
+		t.Fatalf("unexpected metadata: %v", e.Metadata)
+	}
+
+	if code := do("POST", "/api/v2/delete?org=acme&bucket=b", `{"predicate":"host = 'a'"}`); code != http.StatusNoContent {
+		t.Fatalf("unexpected status deleting data: %d", code)
+	}
+	e = last()
+	if e.Action != platform.AuditActionDelete || e.ResourceType != "delete" || e.Metadata["predicate"] != "host = 'a'" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	// Reads are not recorded.
+	do("GET", "/api/v2/labels/0000000000000003", "")
+	if _, n, _ := svc.FindAuditEvents(ctx, platform.AuditEventFilter{}); n != 6 {
+		t.Fatalf("expected 6 events, got %d", n)
+	}
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+)
+
+// AuditBackend is all services and associated parameters required to
+// construct the AuditHandler.
+type AuditBackend struct {
+	Logger *zap.Logger
+
+	AuditLogService influxdb.AuditLogService
+}
+
+// NewAuditBackend returns a new instance of AuditBackend.
+func NewAuditBackend(b *APIBackend) *AuditBackend {
+	return &AuditBackend{
+		Logger: b.Logger.With(zap.String("handler", "audit")),
+
+		AuditLogService: b.AuditLogService,
+	}
+}
+
+// AuditHandler represents an HTTP API handler for the audit log.
+type AuditHandler struct {
+	*httprouter.Router
+	Logger *zap.Logger
+
+	AuditLogService influxdb.AuditLogService
+}
+
+const (
+	auditPath = "/api/v2/audit"
+)
+
+// NewAuditHandler returns a new instance of AuditHandler.
+func NewAuditHandler(b *AuditBackend) *AuditHandler {
+	h := &AuditHandler{
+		Router: NewRouter(),
+		Logger: b.Logger,
+
+		AuditLogService: b.AuditLogService,
+	}
+
+	h.HandlerFunc("GET", auditPath, h.handleGetAuditEvents)
+	return h
+}
+
+type auditEventsResponse struct {
+	Links  *influxdb.PagingLinks  `json:"links"`
+	Events []*influxdb.AuditEvent `json:"events"`
+}
+
+// handleGetAuditEvents is the HTTP handler for the GET /api/v2/audit route.
+func (h *AuditHandler) handleGetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, opts, err := decodeGetAuditEventsRequest(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	es, _, err := h.AuditLogService.FindAuditEvents(ctx, *filter, *opts)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	res := &auditEventsResponse{
+		Links:  newPagingLinks(auditPath, *opts, *filter, len(es)),
+		Events: es,
+	}
+	if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+func decodeGetAuditEventsRequest(ctx context.Context, r *http.Request) (*influxdb.AuditEventFilter, *influxdb.FindOptions, error) {
+	opts, err := decodeFindOptions(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	q := r.URL.Query()
+	// The newest events come first unless asked otherwise.
+	if q.Get("descending") == "" {
+		opts.Descending = true
+	}
+
+	f := &influxdb.AuditEventFilter{
+		Action:       q.Get("action"),
+		ResourceType: q.Get("resourceType"),
+	}
+	for _, p := range []struct {
+		param string
+		id    **influxdb.ID
+	}{
+		{"resourceID", &f.ResourceID},
+		{"userID", &f.UserID},
+		{"orgID", &f.OrgID},
+	} {
+		v := q.Get(p.param)
+		if v == "" {
+			continue
+		}
+		id, err := influxdb.IDFromString(v)
+		if err != nil {
+			return nil, nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  p.param + " is invalid",
+				Err:  err,
+			}
+		}
+		*p.id = id
+	}
+	for _, p := range []struct {
+		param string
+		t     *time.Time
+	}{
+		{"since", &f.Since},
+		{"until", &f.Until},
+	} {
+		v := q.Get(p.param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  p.param + " must be an RFC3339 time",
+				Err:  err,
+			}
+		}
+		*p.t = t
+	}
+	return f, opts, nil
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -65,14 +66,15 @@ func NewAuthorizationHandler(b *AuthorizationBackend) *AuthorizationHandler {
 	h.HandlerFunc("POST", "/api/v2/authorizations", h.handlePostAuthorization)
 	h.HandlerFunc("GET", "/api/v2/authorizations", h.handleGetAuthorizations)
 	h.HandlerFunc("GET", "/api/v2/authorizations/:id", h.handleGetAuthorization)
-	h.HandlerFunc("PATCH", "/api/v2/authorizations/:id", h.handleSetAuthorizationStatus)
+	h.HandlerFunc("PATCH", "/api/v2/authorizations/:id", h.handlePatchAuthorization)
+	h.HandlerFunc("POST", "/api/v2/authorizations/:id/rotate", h.handleRotateAuthorization)
 	h.HandlerFunc("DELETE", "/api/v2/authorizations/:id", h.handleDeleteAuthorization)
 	return h
 }
 
 type authResponse struct {
 	ID          platform.ID          `json:"id"`
-	Token       string               `json:"token"`
+	Token       string               `json:"token,omitempty"`
 	Status      platform.Status      `json:"status"`
 	Description string               `json:"description"`
 	OrgID       platform.ID          `json:"orgID"`
@@ -80,6 +82,8 @@ type authResponse struct {
 	UserID      platform.ID          `json:"userID"`
 	User        string               `json:"user"`
 	Permissions []permissionResponse `json:"permissions"`
+	ExpiresAt   *time.Time           `json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time           `json:"lastUsedAt,omitempty"`
 	Links       map[string]string    `json:"links"`
 }
 
@@ -94,6 +98,8 @@ func newAuthResponse(a *platform.Authorization, org *platform.Organization, user
 		User:        user.Name,
 		Org:         org.Name,
 		Permissions: ps,
+		ExpiresAt:   a.ExpiresAt,
+		LastUsedAt:  a.LastUsedAt,
 		Links: map[string]string{
 			"self": fmt.Sprintf("/api/v2/authorizations/%s", a.ID),
 			"user": fmt.Sprintf("/api/v2/users/%s", a.UserID),
@@ -110,6 +116,8 @@ func (a *authResponse) toPlatform() *platform.Authorization {
 		Description: a.Description,
 		OrgID:       a.OrgID,
 		UserID:      a.UserID,
+		ExpiresAt:   a.ExpiresAt,
+		LastUsedAt:  a.LastUsedAt,
 	}
 	for _, p := range a.Permissions {
 		res.Permissions = append(res.Permissions, platform.Permission{Action: p.Action, Resource: p.Resource.Resource})
@@ -225,6 +233,7 @@ type postAuthorizationRequest struct {
 	UserID      *platform.ID          `json:"userID,omitempty"`
 	Description string                `json:"description"`
 	Permissions []platform.Permission `json:"permissions"`
+	ExpiresAt   *time.Time            `json:"expiresAt,omitempty"`
 }
 
 func (p *postAuthorizationRequest) toPlatform(userID platform.ID) *platform.Authorization {
@@ -234,6 +243,7 @@ func (p *postAuthorizationRequest) toPlatform(userID platform.ID) *platform.Auth
 		Description: p.Description,
 		Permissions: p.Permissions,
 		UserID:      userID,
+		ExpiresAt:   p.ExpiresAt,
 	}
 }
 
@@ -243,6 +253,7 @@ func newPostAuthorizationRequest(a *platform.Authorization) (*postAuthorizationR
 		Description: a.Description,
 		Permissions: a.Permissions,
 		Status:      a.Status,
+		ExpiresAt:   a.ExpiresAt,
 	}
 
 	if a.UserID.Valid() {
@@ -459,31 +470,49 @@ func decodeGetAuthorizationRequest(ctx context.Context, r *http.Request) (*getAu
 	}, nil
 }
 
-// handleSetAuthorizationStatus is the HTTP handler for the PATCH /api/v2/authorizations/:id route that updates the authorization's status.
-func (h *AuthorizationHandler) handleSetAuthorizationStatus(w http.ResponseWriter, r *http.Request) {
+// handlePatchAuthorization is the HTTP handler for the PATCH /api/v2/authorizations/:id route that updates the authorization's status, description or expiry.
+func (h *AuthorizationHandler) handlePatchAuthorization(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	req, err := decodeSetAuthorizationStatusRequest(ctx, r)
+	req, err := decodePatchAuthorizationRequest(ctx, r)
 	if err != nil {
 		h.Logger.Info("failed to decode request", zap.String("handler", "updateAuthorization"), zap.Error(err))
 		EncodeError(ctx, err, w)
 		return
 	}
 
-	a, err := h.AuthorizationService.FindAuthorizationByID(ctx, req.ID)
+	a, err := h.AuthorizationService.UpdateAuthorization(ctx, req.ID, req.Update)
 	if err != nil {
 		EncodeError(ctx, err, w)
 		return
 	}
 
-	if req.Status != a.Status {
-		a.Status = req.Status
-		if err := h.AuthorizationService.SetAuthorizationStatus(ctx, a.ID, a.Status); err != nil {
-			EncodeError(ctx, err, w)
-			return
-		}
+	h.encodeAuthorization(ctx, w, r, http.StatusOK, a)
+}
+
+// handleRotateAuthorization is the HTTP handler for the POST /api/v2/authorizations/:id/rotate route.
+// The response holds the new token, which is the only time it can be read.
+func (h *AuthorizationHandler) handleRotateAuthorization(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := decodeRotateAuthorizationRequest(ctx, r)
+	if err != nil {
+		h.Logger.Info("failed to decode request", zap.String("handler", "rotateAuthorization"), zap.Error(err))
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	a, err := h.AuthorizationService.RotateAuthorization(ctx, req.ID, req.Grace)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
 	}
 
+	h.encodeAuthorization(ctx, w, r, http.StatusOK, a)
+}
+
+// encodeAuthorization writes an authorization with its org, user and permission names.
+func (h *AuthorizationHandler) encodeAuthorization(ctx context.Context, w http.ResponseWriter, r *http.Request, code int, a *platform.Authorization) {
 	o, err := h.OrganizationService.FindOrganizationByID(ctx, a.OrgID)
 	if err != nil {
 		EncodeError(ctx, err, w)
@@ -502,18 +531,124 @@ func (h *AuthorizationHandler) handleSetAuthorizationStatus(w http.ResponseWrite
 		return
 	}
 
-	if err := encodeResponse(ctx, w, http.StatusOK, newAuthResponse(a, o, u, ps)); err != nil {
-		EncodeError(ctx, err, w)
+	if err := encodeResponse(ctx, w, code, newAuthResponse(a, o, u, ps)); err != nil {
+		logEncodingError(h.Logger, r, err)
 		return
 	}
 }
 
+// patchAuthorizationRequest is the body of an authorization update. An
+// expiresAt of null removes the expiry of the token.
+type patchAuthorizationRequest struct {
+	Status      *platform.Status `json:"status,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	ExpiresAt   json.RawMessage  `json:"expiresAt,omitempty"`
+}
+
+func newPatchAuthorizationRequest(upd *platform.AuthorizationUpdate) (*patchAuthorizationRequest, error) {
+	req := &patchAuthorizationRequest{
+		Status:      upd.Status,
+		Description: upd.Description,
+	}
+	if upd.ExpiresAt != nil {
+		req.ExpiresAt = json.RawMessage("null")
+		if !upd.ExpiresAt.IsZero() {
+			b, err := json.Marshal(upd.ExpiresAt)
+			if err != nil {
+				return nil, err
+			}
+			req.ExpiresAt = b
+		}
+	}
+	return req, nil
+}
+
 type updateAuthorizationRequest struct {
 	ID     platform.ID
-	Status platform.Status
+	Update *platform.AuthorizationUpdate
 }
 
-func decodeSetAuthorizationStatusRequest(ctx context.Context, r *http.Request) (*updateAuthorizationRequest, error) {
+func decodePatchAuthorizationRequest(ctx context.Context, r *http.Request) (*updateAuthorizationRequest, error) {
+	i, err := decodeAuthorizationIDParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &patchAuthorizationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(a); err != nil {
+		return nil, &platform.Error{
+			Code: platform.EInvalid,
+			Msg:  "invalid json structure",
+			Err:  err,
+		}
+	}
+
+	upd := &platform.AuthorizationUpdate{
+		Status:      a.Status,
+		Description: a.Description,
+	}
+	if len(a.ExpiresAt) > 0 {
+		var expiresAt *time.Time
+		if err := json.Unmarshal(a.ExpiresAt, &expiresAt); err != nil {
+			return nil, &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  "expiresAt must be an RFC3339 time or null",
+				Err:  err,
+			}
+		}
+		if expiresAt == nil {
+			expiresAt = &time.Time{}
+		}
+		upd.ExpiresAt = expiresAt
+	}
+
+	return &updateAuthorizationRequest{
+		ID:     *i,
+		Update: upd,
+	}, nil
+}
+
+type rotateAuthorizationRequest struct {
+	ID    platform.ID
+	Grace time.Duration
+}
+
+// rotateAuthorizationBody is the body of a token rotation.
+type rotateAuthorizationBody struct {
+	// GracePeriodSeconds is how long the old token keeps working.
+	GracePeriodSeconds int64 `json:"gracePeriodSeconds"`
+}
+
+func decodeRotateAuthorizationRequest(ctx context.Context, r *http.Request) (*rotateAuthorizationRequest, error) {
+	i, err := decodeAuthorizationIDParam(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &rotateAuthorizationBody{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+			return nil, &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  "invalid json structure",
+				Err:  err,
+			}
+		}
+	}
+	if body.GracePeriodSeconds < 0 {
+		return nil, &platform.Error{
+			Code: platform.EInvalid,
+			Msg:  "gracePeriodSeconds must not be negative",
+		}
+	}
+
+	return &rotateAuthorizationRequest{
+		ID:    *i,
+		Grace: time.Duration(body.GracePeriodSeconds) * time.Second,
+	}, nil
+}
+
+func decodeAuthorizationIDParam(ctx context.Context) (*platform.ID, error) {
 	params := httprouter.ParamsFromContext(ctx)
 	id := params.ByName("id")
 	if id == "" {
@@ -527,16 +662,7 @@ func decodeSetAuthorizationStatusRequest(ctx context.Context, r *http.Request) (
 	if err := i.DecodeFromString(id); err != nil {
 		return nil, err
 	}
-
-	a := &setAuthorizationStatusRequest{}
-	if err := json.NewDecoder(r.Body).Decode(a); err != nil {
-		return nil, err
-	}
-
-	return &updateAuthorizationRequest{
-		ID:     i,
-		Status: a.Status,
-	}, nil
+	return &i, nil
 }
 
 // handleDeleteAuthorization is the HTTP handler for the DELETE /api/v2/authorizations/:id route.
@@ -786,6 +912,96 @@ func (s *AuthorizationService) SetAuthorizationStatus(ctx context.Context, id pl
 	return nil
 }
 
+// UpdateAuthorization updates the status, description or expiry of an
+// authorization. The last use of a token cannot be set over HTTP.
+func (s *AuthorizationService) UpdateAuthorization(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+	u, err := newURL(s.Addr, authorizationIDPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := newPatchAuthorizationRequest(upd)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("PATCH", u.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	SetToken(s.Token, req)
+
+	hc := newClient(u.Scheme, s.InsecureSkipVerify)
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckError(resp); err != nil {
+		return nil, err
+	}
+
+	var res authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	return res.toPlatform(), nil
+}
+
+// RotateAuthorization replaces the token of an authorization with a new one,
+// returned in the Token of the result. The old token keeps working for the
+// grace period, which is rounded down to the second.
+func (s *AuthorizationService) RotateAuthorization(ctx context.Context, id platform.ID, grace time.Duration) (*platform.Authorization, error) {
+	u, err := newURL(s.Addr, path.Join(authorizationIDPath(id), "rotate"))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(rotateAuthorizationBody{
+		GracePeriodSeconds: int64(grace / time.Second),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	SetToken(s.Token, req)
+
+	hc := newClient(u.Scheme, s.InsecureSkipVerify)
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckError(resp); err != nil {
+		return nil, err
+	}
+
+	var res authResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	return res.toPlatform(), nil
+}
+
 // DeleteAuthorization removes a authorization by id.
 func (s *AuthorizationService) DeleteAuthorization(ctx context.Context, id platform.ID) error {
 	u, err := newURL(s.Addr, authorizationIDPath(id))
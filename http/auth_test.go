@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -678,6 +679,151 @@ func TestService_handleDeleteAuthorization(t *testing.T) {
 	}
 }
 
+func TestService_handlePatchAuthorization(t *testing.T) {
+	backend := NewMockAuthorizationBackend()
+	backend.AuthorizationService = &mock.AuthorizationService{
+		UpdateAuthorizationFn: func(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+			if upd.Description == nil || *upd.Description != "d" {
+				return nil, fmt.Errorf("unexpected description %v", upd.Description)
+			}
+			if upd.ExpiresAt == nil || !upd.ExpiresAt.IsZero() {
+				return nil, fmt.Errorf("expected expiry to be removed, got %v", upd.ExpiresAt)
+			}
+			if upd.Status != nil || upd.LastUsedAt != nil {
+				return nil, fmt.Errorf("unexpected update %+v", upd)
+			}
+			return &platform.Authorization{
+				ID:          id,
+				OrgID:       platformtesting.MustIDBase16("020f755c3c083000"),
+				UserID:      platformtesting.MustIDBase16("020f755c3c082000"),
+				Status:      platform.Active,
+				Description: *upd.Description,
+			}, nil
+		},
+	}
+	backend.UserService = &mock.UserService{
+		FindUserByIDFn: func(ctx context.Context, id platform.ID) (*platform.User, error) {
+			return &platform.User{ID: id, Name: "u1"}, nil
+		},
+	}
+	backend.OrganizationService = &mock.OrganizationService{
+		FindOrganizationByIDF: func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+			return &platform.Organization{ID: id, Name: "o1"}, nil
+		},
+	}
+	h := NewAuthorizationHandler(backend)
+
+	r := httptest.NewRequest("PATCH", "http://any.url", bytes.NewReader([]byte(`{"description":"d","expiresAt":null}`)))
+	r = r.WithContext(context.WithValue(
+		context.Background(),
+		httprouter.ParamsKey,
+		httprouter.Params{
+			{
+				Key:   "id",
+				Value: "020f755c3c082001",
+			},
+		}))
+	w := httptest.NewRecorder()
+
+	h.handlePatchAuthorization(w, r)
+
+	res := w.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("handlePatchAuthorization() = %v, want %v: %s", res.StatusCode, http.StatusOK, body)
+	}
+
+	exp := `
+{
+  "description": "d",
+  "id": "020f755c3c082001",
+  "links": {
+    "self": "/api/v2/authorizations/020f755c3c082001",
+    "user": "/api/v2/users/020f755c3c082000"
+  },
+  "org": "o1",
+  "orgID": "020f755c3c083000",
+  "permissions": [],
+  "status": "active",
+  "user": "u1",
+  "userID": "020f755c3c082000"
+}
+`
+	if eq, diff, _ := jsonEqual(string(body), exp); !eq {
+		t.Errorf("handlePatchAuthorization() = ***%s***", diff)
+	}
+}
+
+func TestService_handleRotateAuthorization(t *testing.T) {
+	backend := NewMockAuthorizationBackend()
+	backend.AuthorizationService = &mock.AuthorizationService{
+		RotateAuthorizationFn: func(ctx context.Context, id platform.ID, grace time.Duration) (*platform.Authorization, error) {
+			if grace != time.Minute {
+				return nil, fmt.Errorf("unexpected grace period %v", grace)
+			}
+			return &platform.Authorization{
+				ID:     id,
+				Token:  "rotated",
+				OrgID:  platformtesting.MustIDBase16("020f755c3c083000"),
+				UserID: platformtesting.MustIDBase16("020f755c3c082000"),
+				Status: platform.Active,
+			}, nil
+		},
+	}
+	backend.UserService = &mock.UserService{
+		FindUserByIDFn: func(ctx context.Context, id platform.ID) (*platform.User, error) {
+			return &platform.User{ID: id, Name: "u1"}, nil
+		},
+	}
+	backend.OrganizationService = &mock.OrganizationService{
+		FindOrganizationByIDF: func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+			return &platform.Organization{ID: id, Name: "o1"}, nil
+		},
+	}
+	h := NewAuthorizationHandler(backend)
+
+	r := httptest.NewRequest("POST", "http://any.url", bytes.NewReader([]byte(`{"gracePeriodSeconds":60}`)))
+	r = r.WithContext(context.WithValue(
+		context.Background(),
+		httprouter.ParamsKey,
+		httprouter.Params{
+			{
+				Key:   "id",
+				Value: "020f755c3c082001",
+			},
+		}))
+	w := httptest.NewRecorder()
+
+	h.handleRotateAuthorization(w, r)
+
+	res := w.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("handleRotateAuthorization() = %v, want %v: %s", res.StatusCode, http.StatusOK, body)
+	}
+
+	exp := `
+{
+  "description": "",
+  "id": "020f755c3c082001",
+  "links": {
+    "self": "/api/v2/authorizations/020f755c3c082001",
+    "user": "/api/v2/users/020f755c3c082000"
+  },
+  "org": "o1",
+  "orgID": "020f755c3c083000",
+  "permissions": [],
+  "status": "active",
+  "token": "rotated",
+  "user": "u1",
+  "userID": "020f755c3c082000"
+}
+`
+	if eq, diff, _ := jsonEqual(string(body), exp); !eq {
+		t.Errorf("handleRotateAuthorization() = ***%s***", diff)
+	}
+}
+
 func initAuthorizationService(f platformtesting.AuthorizationFields, t *testing.T) (platform.AuthorizationService, string, func()) {
 	t.Helper()
 	if t.Name() == "TestAuthorizationService_FindAuthorizations/find_authorization_by_token" {
@@ -738,7 +884,14 @@ func initAuthorizationService(f platformtesting.AuthorizationFields, t *testing.
 
 	authZ := NewAuthorizationHandler(authorizationBackend)
 	authN := NewAuthenticationHandler()
-	authN.AuthorizationService = svc
+	authN.AuthorizationService = &mock.AuthorizationService{
+		FindAuthorizationByTokenFn: svc.FindAuthorizationByToken,
+		// Recording the last use of the token would change the
+		// authorizations under test.
+		UpdateAuthorizationFn: func(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+			return nil, nil
+		},
+	}
 	authN.Handler = authZ
 
 	server := httptest.NewServer(authN)
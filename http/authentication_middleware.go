@@ -3,10 +3,12 @@ package http
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
 	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
 	platcontext "github.com/influxdata/influxdb/context"
 	"github.com/julienschmidt/httprouter"
 	"go.uber.org/zap"
@@ -19,6 +21,11 @@ type AuthenticationHandler struct {
 	AuthorizationService platform.AuthorizationService
 	SessionService       platform.SessionService
 
+	// RoleService grants tokens the permissions of the roles assigned to
+	// them. Roles are not evaluated if it is nil. The SessionService grants
+	// sessions the permissions of the roles of their user.
+	RoleService platform.RoleService
+
 	// This is only really used for it's lookup method the specific http
 	// hanlder used to register routes does not matter.
 	noAuthRouter *httprouter.Router
@@ -109,9 +116,39 @@ func (h *AuthenticationHandler) extractAuthorization(ctx context.Context, r *htt
 		return ctx, err
 	}
 
+	now := time.Now()
+	if a.IsExpired(now) {
+		return ctx, &platform.Error{
+			Code: platform.EUnauthorized,
+			Msg:  "token expired",
+		}
+	}
+
+	h.touchAuthorization(ctx, a, now)
+
+	if err := h.addRolePermissions(ctx, a); err != nil {
+		return ctx, err
+	}
+
 	return platcontext.SetAuthorizer(ctx, a), nil
 }
 
+// touchAuthorization records that the token of a was used at now, unless
+// that was already recorded less than LastUsedInterval ago. Failing to
+// record it does not fail the request.
+func (h *AuthenticationHandler) touchAuthorization(ctx context.Context, a *platform.Authorization, now time.Time) {
+	if a.LastUsedAt != nil && now.Sub(*a.LastUsedAt) < platform.LastUsedInterval {
+		return
+	}
+
+	upd := &platform.AuthorizationUpdate{LastUsedAt: &now}
+	if _, err := h.AuthorizationService.UpdateAuthorization(ctx, a.ID, upd); err != nil {
+		h.Logger.Warn("Failed to record last use of token", zap.String("authorizationID", a.ID.String()), zap.Error(err))
+		return
+	}
+	a.LastUsedAt = &now
+}
+
 func (h *AuthenticationHandler) extractSession(ctx context.Context, r *http.Request) (context.Context, error) {
 	k, err := decodeCookieSession(ctx, r)
 	if err != nil {
@@ -124,10 +161,37 @@ func (h *AuthenticationHandler) extractSession(ctx context.Context, r *http.Requ
 	}
 
 	// if the session is not expired, renew the session
-	e = h.SessionService.RenewSession(ctx, s, time.Now().Add(platform.RenewSessionTime))
+	now := time.Now()
+	touchSession(s, r, now)
+	e = h.SessionService.RenewSession(ctx, s, now.Add(platform.RenewSessionTime))
 	if e != nil {
 		return ctx, e
 	}
 
 	return platcontext.SetAuthorizer(ctx, s), nil
 }
+
+// touchSession records on s the client of r using it at now. It is
+// stored when the session is renewed.
+func touchSession(s *platform.Session, r *http.Request, now time.Time) {
+	s.IP = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		s.IP = host
+	}
+	s.UserAgent = r.UserAgent()
+	s.LastSeenAt = &now
+}
+
+// addRolePermissions grants a the permissions of its roles, which are
+// looked up on every request so that changes of roles apply right away.
+func (h *AuthenticationHandler) addRolePermissions(ctx context.Context, a *platform.Authorization) error {
+	if h.RoleService == nil {
+		return nil
+	}
+
+	if err := authorizer.AddRolePermissions(ctx, h.RoleService, a); err != nil {
+		h.Logger.Error("Failed to find roles", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -9,7 +9,10 @@ import (
 	"time"
 
 	platform "github.com/influxdata/influxdb"
+	platcontext "github.com/influxdata/influxdb/context"
 	platformhttp "github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
 	"github.com/influxdata/influxdb/mock"
 )
 
@@ -41,6 +44,9 @@ func TestAuthenticationHandler(t *testing.T) {
 						return &platform.Session{}, nil
 					},
 					RenewSessionFn: func(ctx context.Context, session *platform.Session, expiredAt time.Time) error {
+						if session.IP != "192.0.2.1" || session.LastSeenAt == nil {
+							return fmt.Errorf("client of session not recorded: %+v", session)
+						}
 						return nil
 					},
 				},
@@ -76,6 +82,9 @@ func TestAuthenticationHandler(t *testing.T) {
 					FindAuthorizationByTokenFn: func(ctx context.Context, token string) (*platform.Authorization, error) {
 						return &platform.Authorization{}, nil
 					},
+					UpdateAuthorizationFn: func(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+						return &platform.Authorization{}, nil
+					},
 				},
 				SessionService: mock.NewSessionService(),
 			},
@@ -86,6 +95,24 @@ func TestAuthenticationHandler(t *testing.T) {
 				code: http.StatusOK,
 			},
 		},
+		{
+			name: "token expired",
+			fields: fields{
+				AuthorizationService: &mock.AuthorizationService{
+					FindAuthorizationByTokenFn: func(ctx context.Context, token string) (*platform.Authorization, error) {
+						expiresAt := time.Now().Add(-time.Minute)
+						return &platform.Authorization{ExpiresAt: &expiresAt}, nil
+					},
+				},
+				SessionService: mock.NewSessionService(),
+			},
+			args: args{
+				token: "abc123",
+			},
+			wants: wants{
+				code: http.StatusUnauthorized,
+			},
+		},
 		{
 			name: "token does not exist",
 			fields: fields{
@@ -147,6 +174,122 @@ func TestAuthenticationHandler(t *testing.T) {
 	}
 }
 
+func TestAuthenticationHandler_LastUsed(t *testing.T) {
+	recently := time.Now().Add(-time.Second)
+	tests := []struct {
+		name       string
+		lastUsedAt *time.Time
+		updates    int
+	}{
+		{
+			name:    "never used",
+			updates: 1,
+		},
+		{
+			name:       "used recently",
+			lastUsedAt: &recently,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updates int
+			h := platformhttp.NewAuthenticationHandler()
+			h.AuthorizationService = &mock.AuthorizationService{
+				FindAuthorizationByTokenFn: func(ctx context.Context, token string) (*platform.Authorization, error) {
+					return &platform.Authorization{ID: 1, LastUsedAt: tt.lastUsedAt}, nil
+				},
+				UpdateAuthorizationFn: func(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+					if id != 1 || upd.LastUsedAt == nil {
+						t.Errorf("unexpected update of %s: %+v", id, upd)
+					}
+					updates++
+					return &platform.Authorization{}, nil
+				},
+			}
+			h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			r := httptest.NewRequest("GET", "http://any.url", nil)
+			platformhttp.SetToken("abc123", r)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if updates != tt.updates {
+				t.Errorf("expected %d updates of last use, got %d", tt.updates, updates)
+			}
+		})
+	}
+}
+
+func TestAuthenticationHandler_Roles(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	org := &platform.Organization{Name: "acme"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &platform.User{Name: "alice"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	auth := &platform.Authorization{OrgID: org.ID, UserID: user.ID}
+	if err := svc.CreateAuthorization(ctx, auth); err != nil {
+		t.Fatal(err)
+	}
+
+	readBuckets, _ := platform.NewPermission(platform.ReadAction, platform.BucketsResourceType, org.ID)
+	writeBuckets, _ := platform.NewPermission(platform.WriteAction, platform.BucketsResourceType, org.ID)
+	role := &platform.Role{OrgID: org.ID, Name: "reader", Permissions: []platform.Permission{*readBuckets}}
+	if err := svc.CreateRole(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateRoleMapping(ctx, &platform.RoleMapping{RoleID: role.ID, SubjectType: platform.AuthorizationRoleSubject, SubjectID: auth.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := platformhttp.NewAuthenticationHandler()
+	h.AuthorizationService = svc
+	h.SessionService = svc
+	h.RoleService = svc
+
+	allowed := func(p *platform.Permission) bool {
+		var ok bool
+		h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a, err := platcontext.GetAuthorizer(r.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok = a.Allowed(*p)
+		})
+		r := httptest.NewRequest("GET", "http://any.url", nil)
+		platformhttp.SetToken(auth.Token, r)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		return ok
+	}
+
+	if !allowed(readBuckets) || allowed(writeBuckets) {
+		t.Fatal("expected the token to hold the permissions of its role")
+	}
+
+	// Changing the role changes the permissions of the token.
+	ps := []platform.Permission{*writeBuckets}
+	if _, err := svc.UpdateRole(ctx, role.ID, platform.RoleUpdate{Permissions: &ps}); err != nil {
+		t.Fatal(err)
+	}
+	if allowed(readBuckets) || !allowed(writeBuckets) {
+		t.Fatal("expected the token to hold the updated permissions of its role")
+	}
+
+	if err := svc.DeleteRole(ctx, role.ID); err != nil {
+		t.Fatal(err)
+	}
+	if allowed(writeBuckets) {
+		t.Fatal("expected the token to lose the permissions of its deleted role")
+	}
+}
+
 func TestProbeAuthScheme(t *testing.T) {
 	type args struct {
 		token   string
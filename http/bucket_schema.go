@@ -0,0 +1,265 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxql"
+)
+
+// handleGetBucketMeasurements is the HTTP handler for the GET /api/v2/buckets/:id/schema/measurements route.
+func (h *BucketHandler) handleGetBucketMeasurements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := h.decodeSchemaRequest(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	names, err := h.SchemaReader.MeasurementNames(*req)
+	if err != nil {
+		EncodeError(ctx, schemaError("http/handleGetBucketMeasurements", err), w)
+		return
+	}
+
+	res := &bucketMeasurementsResponse{
+		Links:        newBucketSchemaLinks(req.BucketID, "measurements"),
+		Measurements: nonNilStrings(names),
+	}
+	if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetBucketFields is the HTTP handler for the GET /api/v2/buckets/:id/schema/fields route.
+func (h *BucketHandler) handleGetBucketFields(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := h.decodeSchemaRequest(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	fields, err := h.SchemaReader.MeasurementFields(*req)
+	if err != nil {
+		EncodeError(ctx, schemaError("http/handleGetBucketFields", err), w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBucketFieldsResponse(req.BucketID, fields)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetBucketTagKeys is the HTTP handler for the GET /api/v2/buckets/:id/schema/tags route.
+func (h *BucketHandler) handleGetBucketTagKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := h.decodeSchemaRequest(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	keys, err := h.SchemaReader.TagKeys(*req)
+	if err != nil {
+		EncodeError(ctx, schemaError("http/handleGetBucketTagKeys", err), w)
+		return
+	}
+
+	res := &bucketTagKeysResponse{
+		Links: newBucketSchemaLinks(req.BucketID, "tags"),
+		Tags:  nonNilStrings(keys),
+	}
+	if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetBucketTagValues is the HTTP handler for the GET /api/v2/buckets/:id/schema/tags/:key/values route.
+func (h *BucketHandler) handleGetBucketTagValues(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := h.decodeSchemaRequest(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	key := httprouter.ParamsFromContext(ctx).ByName("key")
+	if key == "" {
+		EncodeError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "url missing tag key",
+		}, w)
+		return
+	}
+
+	values, err := h.SchemaReader.TagValues(*req, key)
+	if err != nil {
+		EncodeError(ctx, schemaError("http/handleGetBucketTagValues", err), w)
+		return
+	}
+
+	res := &bucketTagValuesResponse{
+		Links:  newBucketSchemaLinks(req.BucketID, "tags/"+url.PathEscape(key)+"/values"),
+		Key:    key,
+		Values: nonNilStrings(values),
+	}
+	if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// decodeSchemaRequest returns the schema request for the bucket in the url. Finding
+// the bucket checks that the caller may read it.
+func (h *BucketHandler) decodeSchemaRequest(ctx context.Context, r *http.Request) (*storage.SchemaRequest, error) {
+	breq, err := decodeGetBucketRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	qp := r.URL.Query()
+	req := &storage.SchemaRequest{}
+
+	if start := qp.Get("start"); start != "" {
+		if req.Start, err = time.Parse(time.RFC3339Nano, start); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("invalid start time %q: expected RFC3339 format", start),
+				Err:  err,
+			}
+		}
+	}
+
+	if stop := qp.Get("stop"); stop != "" {
+		if req.Stop, err = time.Parse(time.RFC3339Nano, stop); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("invalid stop time %q: expected RFC3339 format", stop),
+				Err:  err,
+			}
+		}
+	}
+
+	if predicate := qp.Get("predicate"); predicate != "" {
+		if req.Predicate, err = influxql.ParseExpr(predicate); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("invalid predicate: %v", err),
+				Err:  err,
+			}
+		}
+	}
+
+	if limit := qp.Get("limit"); limit != "" {
+		if req.Limit, err = strconv.Atoi(limit); err != nil || req.Limit < 1 {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "limit must be a positive integer",
+			}
+		}
+	}
+
+	b, err := h.BucketService.FindBucketByID(ctx, breq.BucketID)
+	if err != nil {
+		return nil, err
+	}
+	req.OrgID, req.BucketID = b.OrganizationID, b.ID
+
+	return req, nil
+}
+
+// schemaError returns the error for a failure to read the schema. Errors in the
+// predicate are only found once it is applied, so they keep their code.
+func schemaError(op string, err error) error {
+	return &influxdb.Error{
+		Code: influxdb.ErrorCode(err),
+		Op:   op,
+		Msg:  fmt.Sprintf("unable to read bucket schema: %v", err),
+		Err:  err,
+	}
+}
+
+func newBucketSchemaLinks(id influxdb.ID, path string) map[string]string {
+	return map[string]string{
+		"self":   fmt.Sprintf("/api/v2/buckets/%s/schema/%s", id, path),
+		"bucket": fmt.Sprintf("/api/v2/buckets/%s", id),
+	}
+}
+
+// nonNilStrings returns a, or an empty slice if a is nil, so that it is encoded as
+// an empty array.
+func nonNilStrings(a []string) []string {
+	if a == nil {
+		return []string{}
+	}
+	return a
+}
+
+type bucketMeasurementsResponse struct {
+	Links        map[string]string `json:"links"`
+	Measurements []string          `json:"measurements"`
+}
+
+type bucketTagKeysResponse struct {
+	Links map[string]string `json:"links"`
+	Tags  []string          `json:"tags"`
+}
+
+type bucketTagValuesResponse struct {
+	Links  map[string]string `json:"links"`
+	Key    string            `json:"key"`
+	Values []string          `json:"values"`
+}
+
+type bucketFieldsResponse struct {
+	Links        map[string]string           `json:"links"`
+	Measurements []measurementFieldsResponse `json:"measurements"`
+}
+
+type measurementFieldsResponse struct {
+	Name   string          `json:"name"`
+	Fields []fieldResponse `json:"fields"`
+}
+
+type fieldResponse struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// newBucketFieldsResponse groups the sorted fields by their measurement.
+func newBucketFieldsResponse(id influxdb.ID, fields []tsi1.MeasurementField) *bucketFieldsResponse {
+	res := &bucketFieldsResponse{
+		Links:        newBucketSchemaLinks(id, "fields"),
+		Measurements: []measurementFieldsResponse{},
+	}
+
+	for _, f := range fields {
+		if n := len(res.Measurements); n == 0 || res.Measurements[n-1].Name != string(f.Measurement) {
+			res.Measurements = append(res.Measurements, measurementFieldsResponse{Name: string(f.Measurement)})
+		}
+		m := &res.Measurements[len(res.Measurements)-1]
+		m.Fields = append(m.Fields, fieldResponse{
+			Name: string(f.Field),
+			Type: strings.ToLower(f.Type.String()),
+		})
+	}
+	return res
+}
@@ -0,0 +1,226 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+)
+
+// mockSchemaReader is a storage.SchemaReader whose methods call the matching function.
+type mockSchemaReader struct {
+	MeasurementNamesFn  func(req storage.SchemaRequest) ([]string, error)
+	MeasurementFieldsFn func(req storage.SchemaRequest) ([]tsi1.MeasurementField, error)
+	TagKeysFn           func(req storage.SchemaRequest) ([]string, error)
+	TagValuesFn         func(req storage.SchemaRequest, key string) ([]string, error)
+}
+
+func (s *mockSchemaReader) MeasurementNames(req storage.SchemaRequest) ([]string, error) {
+	return s.MeasurementNamesFn(req)
+}
+
+func (s *mockSchemaReader) MeasurementFields(req storage.SchemaRequest) ([]tsi1.MeasurementField, error) {
+	return s.MeasurementFieldsFn(req)
+}
+
+func (s *mockSchemaReader) TagKeys(req storage.SchemaRequest) ([]string, error) {
+	return s.TagKeysFn(req)
+}
+
+func (s *mockSchemaReader) TagValues(req storage.SchemaRequest, key string) ([]string, error) {
+	return s.TagValuesFn(req, key)
+}
+
+func TestService_handleGetBucketSchema(t *testing.T) {
+	const bucketID = "020f755c3c082000"
+
+	foundBucket := &mock.BucketService{
+		FindBucketByIDFn: func(ctx context.Context, id platform.ID) (*platform.Bucket, error) {
+			return &platform.Bucket{ID: id, OrganizationID: platform.ID(1), Name: "hello"}, nil
+		},
+	}
+
+	// checkRequest fails the test if the request does not match the query parameters
+	// used by the tests below.
+	checkRequest := func(t *testing.T, req storage.SchemaRequest) {
+		if req.OrgID != platform.ID(1) || req.BucketID.String() != bucketID {
+			t.Errorf("unexpected org and bucket: %s %s", req.OrgID, req.BucketID)
+		}
+		if !req.Start.Equal(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)) || !req.Stop.IsZero() {
+			t.Errorf("unexpected time range: %s %s", req.Start, req.Stop)
+		}
+		if req.Predicate == nil || req.Predicate.String() != `host = 'a'` {
+			t.Errorf("unexpected predicate: %v", req.Predicate)
+		}
+		if req.Limit != 10 {
+			t.Errorf("unexpected limit: %d", req.Limit)
+		}
+	}
+
+	const query = "?start=2019-01-01T00:00:00Z&predicate=host%20%3D%20%27a%27&limit=10"
+
+	tests := []struct {
+		name          string
+		url           string
+		bucketService platform.BucketService
+		statusCode    int
+		body          string
+	}{
+		{
+			name:          "get measurements",
+			url:           "/api/v2/buckets/" + bucketID + "/schema/measurements" + query,
+			bucketService: foundBucket,
+			statusCode:    http.StatusOK,
+			body: `
+{
+  "links": {
+    "self": "/api/v2/buckets/020f755c3c082000/schema/measurements",
+    "bucket": "/api/v2/buckets/020f755c3c082000"
+  },
+  "measurements": ["cpu", "mem"]
+}
+`,
+		},
+		{
+			name:          "get fields",
+			url:           "/api/v2/buckets/" + bucketID + "/schema/fields" + query,
+			bucketService: foundBucket,
+			statusCode:    http.StatusOK,
+			body: `
+{
+  "links": {
+    "self": "/api/v2/buckets/020f755c3c082000/schema/fields",
+    "bucket": "/api/v2/buckets/020f755c3c082000"
+  },
+  "measurements": [
+    {
+      "name": "cpu",
+      "fields": [
+        {"name": "idle", "type": "boolean"},
+        {"name": "usage", "type": "float"}
+      ]
+    },
+    {
+      "name": "mem",
+      "fields": [
+        {"name": "used", "type": "unsigned"}
+      ]
+    }
+  ]
+}
+`,
+		},
+		{
+			name:          "get tag keys",
+			url:           "/api/v2/buckets/" + bucketID + "/schema/tags" + query,
+			bucketService: foundBucket,
+			statusCode:    http.StatusOK,
+			body: `
+{
+  "links": {
+    "self": "/api/v2/buckets/020f755c3c082000/schema/tags",
+    "bucket": "/api/v2/buckets/020f755c3c082000"
+  },
+  "tags": ["host", "region"]
+}
+`,
+		},
+		{
+			name:          "get tag values",
+			url:           "/api/v2/buckets/" + bucketID + "/schema/tags/host/values" + query,
+			bucketService: foundBucket,
+			statusCode:    http.StatusOK,
+			body: `
+{
+  "links": {
+    "self": "/api/v2/buckets/020f755c3c082000/schema/tags/host/values",
+    "bucket": "/api/v2/buckets/020f755c3c082000"
+  },
+  "key": "host",
+  "values": ["a"]
+}
+`,
+		},
+		{
+			name:          "invalid limit",
+			url:           "/api/v2/buckets/" + bucketID + "/schema/tags?limit=0",
+			bucketService: foundBucket,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "invalid start",
+			url:           "/api/v2/buckets/" + bucketID + "/schema/tags?start=yesterday",
+			bucketService: foundBucket,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name: "bucket not found",
+			url:  "/api/v2/buckets/" + bucketID + "/schema/measurements",
+			bucketService: &mock.BucketService{
+				FindBucketByIDFn: func(ctx context.Context, id platform.ID) (*platform.Bucket, error) {
+					return nil, &platform.Error{
+						Code: platform.ENotFound,
+						Msg:  "bucket not found",
+					}
+				},
+			},
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucketBackend := NewMockBucketBackend()
+			bucketBackend.BucketService = tt.bucketService
+			bucketBackend.SchemaReader = &mockSchemaReader{
+				MeasurementNamesFn: func(req storage.SchemaRequest) ([]string, error) {
+					checkRequest(t, req)
+					return []string{"cpu", "mem"}, nil
+				},
+				MeasurementFieldsFn: func(req storage.SchemaRequest) ([]tsi1.MeasurementField, error) {
+					checkRequest(t, req)
+					return []tsi1.MeasurementField{
+						{Measurement: []byte("cpu"), Field: []byte("idle"), Type: models.Boolean},
+						{Measurement: []byte("cpu"), Field: []byte("usage"), Type: models.Float},
+						{Measurement: []byte("mem"), Field: []byte("used"), Type: models.Unsigned},
+					}, nil
+				},
+				TagKeysFn: func(req storage.SchemaRequest) ([]string, error) {
+					checkRequest(t, req)
+					return []string{"host", "region"}, nil
+				},
+				TagValuesFn: func(req storage.SchemaRequest, key string) ([]string, error) {
+					checkRequest(t, req)
+					if key != "host" {
+						t.Errorf("unexpected tag key: %q", key)
+					}
+					return []string{"a"}, nil
+				},
+			}
+			h := NewBucketHandler(bucketBackend)
+
+			r := httptest.NewRequest("GET", "http://any.url"+tt.url, nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			res := w.Result()
+			body, _ := ioutil.ReadAll(res.Body)
+
+			if res.StatusCode != tt.statusCode {
+				t.Errorf("%q. ServeHTTP() = %v, want %v: %s", tt.name, res.StatusCode, tt.statusCode, body)
+			}
+			if eq, diff, _ := jsonEqual(string(body), tt.body); tt.body != "" && !eq {
+				t.Errorf("%q. ServeHTTP() = ***%s***", tt.name, diff)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/storage"
 )
 
 // BucketBackend is all services and associated parameters required to construct
@@ -27,6 +28,7 @@ type BucketBackend struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	OrganizationService        influxdb.OrganizationService
+	SchemaReader               storage.SchemaReader
 }
 
 // NewBucketBackend returns a new instance of BucketBackend.
@@ -40,6 +42,7 @@ func NewBucketBackend(b *APIBackend) *BucketBackend {
 		LabelService:               b.LabelService,
 		UserService:                b.UserService,
 		OrganizationService:        b.OrganizationService,
+		SchemaReader:               b.SchemaReader,
 	}
 }
 
@@ -55,18 +58,23 @@ type BucketHandler struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	OrganizationService        influxdb.OrganizationService
+	SchemaReader               storage.SchemaReader
 }
 
 const (
-	bucketsPath            = "/api/v2/buckets"
-	bucketsIDPath          = "/api/v2/buckets/:id"
-	bucketsIDLogPath       = "/api/v2/buckets/:id/logs"
-	bucketsIDMembersPath   = "/api/v2/buckets/:id/members"
-	bucketsIDMembersIDPath = "/api/v2/buckets/:id/members/:userID"
-	bucketsIDOwnersPath    = "/api/v2/buckets/:id/owners"
-	bucketsIDOwnersIDPath  = "/api/v2/buckets/:id/owners/:userID"
-	bucketsIDLabelsPath    = "/api/v2/buckets/:id/labels"
-	bucketsIDLabelsIDPath  = "/api/v2/buckets/:id/labels/:lid"
+	bucketsPath                     = "/api/v2/buckets"
+	bucketsIDPath                   = "/api/v2/buckets/:id"
+	bucketsIDLogPath                = "/api/v2/buckets/:id/logs"
+	bucketsIDMembersPath            = "/api/v2/buckets/:id/members"
+	bucketsIDMembersIDPath          = "/api/v2/buckets/:id/members/:userID"
+	bucketsIDOwnersPath             = "/api/v2/buckets/:id/owners"
+	bucketsIDOwnersIDPath           = "/api/v2/buckets/:id/owners/:userID"
+	bucketsIDLabelsPath             = "/api/v2/buckets/:id/labels"
+	bucketsIDLabelsIDPath           = "/api/v2/buckets/:id/labels/:lid"
+	bucketsIDSchemaMeasurementsPath = "/api/v2/buckets/:id/schema/measurements"
+	bucketsIDSchemaFieldsPath       = "/api/v2/buckets/:id/schema/fields"
+	bucketsIDSchemaTagsPath         = "/api/v2/buckets/:id/schema/tags"
+	bucketsIDSchemaTagValuesPath    = "/api/v2/buckets/:id/schema/tags/:key/values"
 )
 
 // NewBucketHandler returns a new instance of BucketHandler.
@@ -81,6 +89,7 @@ func NewBucketHandler(b *BucketBackend) *BucketHandler {
 		LabelService:               b.LabelService,
 		UserService:                b.UserService,
 		OrganizationService:        b.OrganizationService,
+		SchemaReader:               b.SchemaReader,
 	}
 
 	h.HandlerFunc("POST", bucketsPath, h.handlePostBucket)
@@ -89,6 +98,10 @@ func NewBucketHandler(b *BucketBackend) *BucketHandler {
 	h.HandlerFunc("GET", bucketsIDLogPath, h.handleGetBucketLog)
 	h.HandlerFunc("PATCH", bucketsIDPath, h.handlePatchBucket)
 	h.HandlerFunc("DELETE", bucketsIDPath, h.handleDeleteBucket)
+	h.HandlerFunc("GET", bucketsIDSchemaMeasurementsPath, h.handleGetBucketMeasurements)
+	h.HandlerFunc("GET", bucketsIDSchemaFieldsPath, h.handleGetBucketFields)
+	h.HandlerFunc("GET", bucketsIDSchemaTagsPath, h.handleGetBucketTagKeys)
+	h.HandlerFunc("GET", bucketsIDSchemaTagValuesPath, h.handleGetBucketTagValues)
 
 	memberBackend := MemberBackend{
 		Logger:                     b.Logger.With(zap.String("handler", "member")),
@@ -127,12 +140,13 @@ func NewBucketHandler(b *BucketBackend) *BucketHandler {
 
 // bucket is used for serialization/deserialization with duration string syntax.
 type bucket struct {
-	ID                  influxdb.ID     `json:"id,omitempty"`
-	OrganizationID      influxdb.ID     `json:"organizationID,omitempty"`
-	Organization        string          `json:"organization,omitempty"`
-	Name                string          `json:"name"`
-	RetentionPolicyName string          `json:"rp,omitempty"` // This to support v1 sources
-	RetentionRules      []retentionRule `json:"retentionRules"`
+	ID                  influxdb.ID       `json:"id,omitempty"`
+	OrganizationID      influxdb.ID       `json:"organizationID,omitempty"`
+	Organization        string            `json:"organization,omitempty"`
+	Name                string            `json:"name"`
+	RetentionPolicyName string            `json:"rp,omitempty"` // This to support v1 sources
+	RetentionRules      []retentionRule   `json:"retentionRules"`
+	DefaultTags         map[string]string `json:"defaultTags,omitempty"`
 }
 
 // retentionRule is the retention rule action for a bucket.
@@ -166,6 +180,7 @@ func (b *bucket) toInfluxDB() (*influxdb.Bucket, error) {
 		Name:                b.Name,
 		RetentionPolicyName: b.RetentionPolicyName,
 		RetentionPeriod:     d,
+		DefaultTags:         b.DefaultTags,
 	}, nil
 }
 
@@ -190,13 +205,15 @@ func newBucket(pb *influxdb.Bucket) *bucket {
 		Name:                pb.Name,
 		RetentionPolicyName: pb.RetentionPolicyName,
 		RetentionRules:      rules,
+		DefaultTags:         pb.DefaultTags,
 	}
 }
 
 // bucketUpdate is used for serialization/deserialization with retention rules.
 type bucketUpdate struct {
-	Name           *string         `json:"name,omitempty"`
-	RetentionRules []retentionRule `json:"retentionRules,omitempty"`
+	Name           *string            `json:"name,omitempty"`
+	RetentionRules []retentionRule    `json:"retentionRules,omitempty"`
+	DefaultTags    *map[string]string `json:"defaultTags,omitempty"`
 }
 
 func (b *bucketUpdate) toInfluxDB() (*influxdb.BucketUpdate, error) {
@@ -219,6 +236,7 @@ func (b *bucketUpdate) toInfluxDB() (*influxdb.BucketUpdate, error) {
 	return &influxdb.BucketUpdate{
 		Name:            b.Name,
 		RetentionPeriod: &d,
+		DefaultTags:     b.DefaultTags,
 	}, nil
 }
 
@@ -230,6 +248,7 @@ func newBucketUpdate(pb *influxdb.BucketUpdate) *bucketUpdate {
 	up := &bucketUpdate{
 		Name:           pb.Name,
 		RetentionRules: []retentionRule{},
+		DefaultTags:    pb.DefaultTags,
 	}
 
 	if pb.RetentionPeriod != nil {
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+
+	platform "github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxql"
+)
+
+// DeleteBackend is all services and associated parameters required to construct
+// the DeleteHandler.
+type DeleteBackend struct {
+	Logger *zap.Logger
+
+	SeriesDeleter       storage.SeriesDeleter
+	BucketService       platform.BucketService
+	OrganizationService platform.OrganizationService
+}
+
+// NewDeleteBackend returns a new instance of DeleteBackend.
+func NewDeleteBackend(b *APIBackend) *DeleteBackend {
+	return &DeleteBackend{
+		Logger: b.Logger.With(zap.String("handler", "delete")),
+
+		SeriesDeleter:       b.SeriesDeleter,
+		BucketService:       b.BucketService,
+		OrganizationService: b.OrganizationService,
+	}
+}
+
+// DeleteHandler receives requests to drop series from a bucket.
+type DeleteHandler struct {
+	*httprouter.Router
+
+	Logger *zap.Logger
+
+	BucketService       platform.BucketService
+	OrganizationService platform.OrganizationService
+
+	SeriesDeleter storage.SeriesDeleter
+}
+
+const (
+	deletePath = "/api/v2/delete"
+)
+
+// NewDeleteHandler creates a new handler at /api/v2/delete to drop series.
+func NewDeleteHandler(b *DeleteBackend) *DeleteHandler {
+	h := &DeleteHandler{
+		Router: NewRouter(),
+		Logger: b.Logger,
+
+		SeriesDeleter:       b.SeriesDeleter,
+		BucketService:       b.BucketService,
+		OrganizationService: b.OrganizationService,
+	}
+
+	h.HandlerFunc("POST", deletePath, h.handleDelete)
+	return h
+}
+
+func (h *DeleteHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "DeleteHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	defer r.Body.Close()
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	req, err := decodeDeleteRequest(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	org, err := findOrganization(ctx, h.OrganizationService, req.Org)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	bucket, err := findBucket(ctx, h.BucketService, org.ID, req.Bucket)
+	if err != nil {
+		EncodeError(ctx, &platform.Error{
+			Op:  "http/handleDelete",
+			Err: err,
+		}, w)
+		return
+	}
+
+	p, err := platform.NewPermissionAtID(bucket.ID, platform.WriteAction, platform.BucketsResourceType, org.ID)
+	if err != nil {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EInternal,
+			Op:   "http/handleDelete",
+			Msg:  fmt.Sprintf("unable to create permission for bucket: %v", err),
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if !a.Allowed(*p) {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EForbidden,
+			Op:   "http/handleDelete",
+			Msg:  "insufficient permissions for delete",
+		}, w)
+		return
+	}
+
+	if err := h.SeriesDeleter.DeleteSeries(org.ID, bucket.ID, req.Predicate); err != nil {
+		h.Logger.Error("Error deleting series", zap.Stringer("org", org.ID), zap.Stringer("bucket", bucket.ID), zap.Error(err))
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EInternal,
+			Op:   "http/handleDelete",
+			Msg:  fmt.Sprintf("unable to delete series: %v", err),
+			Err:  err,
+		}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type deleteRequest struct {
+	Org       string
+	Bucket    string
+	Predicate influxql.Expr
+}
+
+func decodeDeleteRequest(ctx context.Context, r *http.Request) (*deleteRequest, error) {
+	qp := r.URL.Query()
+	req := &deleteRequest{
+		Org:    qp.Get("org"),
+		Bucket: qp.Get("bucket"),
+	}
+
+	var body struct {
+		Predicate string `json:"predicate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, &platform.Error{
+			Code: platform.EInvalid,
+			Op:   "http/decodeDeleteRequest",
+			Msg:  "invalid request body",
+			Err:  err,
+		}
+	}
+
+	// Require a predicate so that a request can never drop every series in the
+	// bucket by accident. Deleting the bucket is the way to do that.
+	if body.Predicate == "" {
+		return nil, &platform.Error{
+			Code: platform.EInvalid,
+			Op:   "http/decodeDeleteRequest",
+			Msg:  "predicate is required",
+		}
+	}
+
+	expr, err := influxql.ParseExpr(body.Predicate)
+	if err != nil {
+		return nil, &platform.Error{
+			Code: platform.EInvalid,
+			Op:   "http/decodeDeleteRequest",
+			Msg:  fmt.Sprintf("invalid predicate: %v", err),
+			Err:  err,
+		}
+	}
+	req.Predicate = expr
+
+	return req, nil
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+type seriesDeleterFunc func(orgID, bucketID platform.ID, predicate influxql.Expr) error
+
+func (fn seriesDeleterFunc) DeleteSeries(orgID, bucketID platform.ID, predicate influxql.Expr) error {
+	return fn(orgID, bucketID, predicate)
+}
+
+func TestDeleteHandler_handleDelete(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	write, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := platform.NewPermissionAtID(bucketID, platform.ReadAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		permissions []platform.Permission
+		status      int
+		predicate   string
+	}{
+		{
+			name:        "drop series",
+			body:        `{"predicate": "_measurement = 'cpu' AND host = 'a'"}`,
+			permissions: []platform.Permission{*write},
+			status:      http.StatusNoContent,
+			predicate:   `_measurement = 'cpu' AND host = 'a'`,
+		},
+		{
+			name:        "missing predicate",
+			body:        `{}`,
+			permissions: []platform.Permission{*write},
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "invalid predicate",
+			body:        `{"predicate": "host = "}`,
+			permissions: []platform.Permission{*write},
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "insufficient permissions",
+			body:        `{"predicate": "host = 'a'"}`,
+			permissions: []platform.Permission{*read},
+			status:      http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgSvc := mock.NewOrganizationService()
+			orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+				return &platform.Organization{ID: id}, nil
+			}
+			bucketSvc := mock.NewBucketService()
+			bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+				return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID}, nil
+			}
+
+			var got influxql.Expr
+			h := NewDeleteHandler(&DeleteBackend{
+				Logger:              zap.NewNop(),
+				BucketService:       bucketSvc,
+				OrganizationService: orgSvc,
+				SeriesDeleter: seriesDeleterFunc(func(o, b platform.ID, predicate influxql.Expr) error {
+					if o != orgID || b != bucketID {
+						t.Errorf("unexpected org and bucket: %s %s", o, b)
+					}
+					got = predicate
+					return nil
+				}),
+			})
+
+			r := httptest.NewRequest("POST", "/api/v2/delete?org="+orgID.String()+"&bucket="+bucketID.String(), strings.NewReader(tt.body))
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: tt.permissions}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("unexpected status: got %d, exp %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.predicate == "" {
+				if got != nil {
+					t.Fatalf("unexpected delete with predicate %s", got)
+				}
+			} else if got == nil || got.String() != influxql.MustParseExpr(tt.predicate).String() {
+				t.Fatalf("unexpected predicate: got %v, exp %s", got, tt.predicate)
+			}
+		})
+	}
+}
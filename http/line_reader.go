@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// lineReaderChunkSize is the number of bytes of line protocol a lineReader tries
+// to return at once. Chunks are larger when a single line does not fit.
+const lineReaderChunkSize = 256 * 1024
+
+// lineReader reads line protocol from a stream in chunks of complete lines, so
+// that a write can be parsed and written without holding the whole body in memory.
+type lineReader struct {
+	r   io.Reader
+	eof bool
+
+	buf  []byte // data read but not yet returned
+	line int    // the line number of the first line in buf
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: r, line: 1}
+}
+
+// next returns the next chunk of complete lines and the line number of its first
+// line, starting at 1. It returns io.EOF once all lines have been returned.
+//
+// Chunks are not modified by later calls, so they may be referred to by points
+// parsed from them.
+func (lr *lineReader) next() ([]byte, int, error) {
+	// Only look for the end of the lines once buf is full, as scanning it again
+	// after every read would be slow if the lines are long.
+	n := 0
+	for !lr.eof {
+		if len(lr.buf) > 0 && len(lr.buf) == cap(lr.buf) {
+			if n = models.CompleteLines(lr.buf); n > 0 {
+				break
+			}
+		}
+		if err := lr.fill(); err != nil {
+			return nil, 0, err
+		}
+	}
+	if lr.eof {
+		n = len(lr.buf)
+	}
+	if n == 0 {
+		return nil, 0, io.EOF
+	}
+
+	chunk, line := lr.buf[:n:n], lr.line
+	lr.line += bytes.Count(chunk, []byte{'\n'})
+
+	// Move the rest of the data to a new buffer, as the chunk must not be overwritten.
+	rest := lr.buf[n:]
+	lr.buf = make([]byte, len(rest), lineReaderChunkSize+len(rest))
+	copy(lr.buf, rest)
+
+	return chunk, line, nil
+}
+
+// fill reads more data from the stream into buf, growing it if it is full.
+func (lr *lineReader) fill() error {
+	if len(lr.buf) == cap(lr.buf) {
+		buf := make([]byte, len(lr.buf), 2*cap(lr.buf)+lineReaderChunkSize)
+		copy(buf, lr.buf)
+		lr.buf = buf
+	}
+
+	n, err := lr.r.Read(lr.buf[len(lr.buf):cap(lr.buf)])
+	lr.buf = lr.buf[:len(lr.buf)+n]
+
+	if err == io.EOF {
+		lr.eof = true
+		return nil
+	}
+	return err
+}
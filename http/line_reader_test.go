@@ -0,0 +1,64 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineReader(t *testing.T) {
+	// Write enough lines to need several chunks, with some string fields that
+	// span lines.
+	var buf bytes.Buffer
+	var lines int
+	for i := 0; buf.Len() < 3*lineReaderChunkSize; i++ {
+		if i%1000 == 999 {
+			fmt.Fprintf(&buf, "mem text=\"line %d\nnext line\" %d\n", i, i)
+			lines += 2
+		} else {
+			fmt.Fprintf(&buf, "cpu,host=server%d value=%d %d\n", i, i, i)
+			lines++
+		}
+	}
+	buf.WriteString("cpu value=1 1") // no trailing newline
+	lines++
+
+	lr := newLineReader(iotest.HalfReader(bytes.NewReader(buf.Bytes())))
+
+	var got []byte
+	var chunks int
+	expLine := 1
+	for {
+		chunk, line, err := lr.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		chunks++
+
+		if line != expLine {
+			t.Fatalf("chunk %d: got line %d, expected %d", chunks, line, expLine)
+		}
+		expLine += bytes.Count(chunk, []byte{'\n'})
+
+		// Every chunk but the last must end with a complete line.
+		if n := len(got) + len(chunk); n < buf.Len() && chunk[len(chunk)-1] != '\n' {
+			t.Fatalf("chunk %d does not end with a newline", chunks)
+		} else if strings.HasPrefix(string(chunk), "next line") {
+			t.Fatalf("chunk %d splits a line", chunks)
+		}
+		got = append(got, chunk...)
+	}
+
+	if !bytes.Equal(got, buf.Bytes()) {
+		t.Fatal("chunks do not match the input")
+	} else if chunks < 3 {
+		t.Fatalf("got %d chunks, expected at least 3", chunks)
+	} else if expLine != lines {
+		t.Fatalf("got %d lines, expected %d", expLine, lines)
+	}
+}
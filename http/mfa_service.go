@@ -0,0 +1,233 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+)
+
+const (
+	usersMFAPath           = "/api/v2/users/:id/mfa"
+	meMFAPath              = "/api/v2/me/mfa"
+	meMFATOTPPath          = "/api/v2/me/mfa/totp"
+	meMFATOTPConfirmPath   = "/api/v2/me/mfa/totp/confirm"
+	meMFARecoveryCodesPath = "/api/v2/me/mfa/recovery-codes"
+)
+
+type mfaResponse struct {
+	Links map[string]string `json:"links"`
+	influxdb.MFA
+}
+
+func newMFAResponse(m *influxdb.MFA) *mfaResponse {
+	return &mfaResponse{
+		Links: map[string]string{
+			"self": fmt.Sprintf("/api/v2/users/%s/mfa", m.UserID),
+			"user": fmt.Sprintf("/api/v2/users/%s", m.UserID),
+		},
+		MFA: *m,
+	}
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// handleGetMFA is the HTTP handler for the GET /api/v2/users/:id/mfa and
+// GET /api/v2/me/mfa routes.
+func (h *UserHandler) handleGetMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	m, err := h.MFAService.FindMFA(ctx, id)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newMFAResponse(m)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// verifyMFACode returns an error unless the body of r holds a valid code of
+// the user id. Changing the multi-factor authentication of an enrolled user
+// requires a code, so that it cannot be removed or replaced with a stolen
+// session or token alone.
+func (h *UserHandler) verifyMFACode(ctx context.Context, r *http.Request, id influxdb.ID) error {
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	if req.Code == "" {
+		return &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  influxdb.ErrMFACodeRequired,
+		}
+	}
+	return h.MFAService.VerifyMFA(ctx, id, req.Code)
+}
+
+// handleDeleteUserMFA is the HTTP handler for the DELETE /api/v2/users/:id/mfa
+// route. Operators reset the multi-factor authentication of users who lost
+// their authenticator and recovery codes.
+func (h *UserHandler) handleDeleteUserMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	p, err := influxdb.NewGlobalPermission(influxdb.WriteAction, influxdb.UsersResourceType)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+	if err := authorizer.IsAllowed(ctx, *p); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.MFAService.DeleteMFA(ctx, id); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteMeMFA is the HTTP handler for the DELETE /api/v2/me/mfa route.
+func (h *UserHandler) handleDeleteMeMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.verifyMFACode(ctx, r, id); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.MFAService.DeleteMFA(ctx, id); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePostTOTP is the HTTP handler for the POST /api/v2/me/mfa/totp route.
+// Enrolled users replacing their authenticator give a code of the current one.
+func (h *UserHandler) handlePostTOTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	m, err := h.MFAService.FindMFA(ctx, id)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+	if m.Enrolled {
+		if err := h.verifyMFACode(ctx, r, id); err != nil {
+			EncodeError(ctx, err, w)
+			return
+		}
+	}
+
+	e, err := h.MFAService.EnrollTOTP(ctx, id)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, e); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handlePostTOTPConfirm is the HTTP handler for the POST /api/v2/me/mfa/totp/confirm route.
+func (h *UserHandler) handlePostTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		EncodeError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}, w)
+		return
+	}
+
+	codes, err := h.MFAService.ConfirmTOTP(ctx, id, req.Code)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handlePostRecoveryCodes is the HTTP handler for the POST /api/v2/me/mfa/recovery-codes route.
+func (h *UserHandler) handlePostRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.verifyMFACode(ctx, r, id); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	codes, err := h.MFAService.GenerateRecoveryCodes(ctx, id)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, recoveryCodesResponse{RecoveryCodes: codes}); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
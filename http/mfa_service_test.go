@@ -0,0 +1,161 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap"
+)
+
+func TestUserHandler_MFA(t *testing.T) {
+	svc := mock.NewMFAService()
+	svc.FindMFAFn = func(ctx context.Context, userID platform.ID) (*platform.MFA, error) {
+		return &platform.MFA{UserID: userID, Enrolled: true, RecoveryCodes: 10}, nil
+	}
+	svc.ConfirmTOTPFn = func(ctx context.Context, userID platform.ID, code string) ([]string, error) {
+		if userID != 1 || code != "123456" {
+			return nil, &platform.Error{Code: platform.EInvalid, Msg: platform.ErrMFAInvalidCode}
+		}
+		return []string{"abcd-efgh"}, nil
+	}
+
+	h := NewUserHandler(&UserBackend{
+		Logger:      zap.NewNop(),
+		UserService: mock.NewUserService(),
+		MFAService:  svc,
+	})
+	session := &platform.Session{UserID: 1}
+
+	r := httptest.NewRequest("GET", "http://any.url/api/v2/me/mfa", nil)
+	r = r.WithContext(pcontext.SetAuthorizer(r.Context(), session))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	res := w.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 {
+		t.Fatalf("unexpected status code: %d: %s", res.StatusCode, body)
+	}
+	want := `
+{
+  "userID": "0000000000000001",
+  "enrolled": true,
+  "recoveryCodes": 10,
+  "links": {
+    "self": "/api/v2/users/0000000000000001/mfa",
+    "user": "/api/v2/users/0000000000000001"
+  }
+}
+`
+	if eq, diff, _ := jsonEqual(string(body), want); !eq {
+		t.Errorf("unexpected response body -got/+want\n%s", diff)
+	}
+
+	r = httptest.NewRequest("POST", "http://any.url/api/v2/me/mfa/totp/confirm", strings.NewReader(`{"code":"123456"}`))
+	r = r.WithContext(pcontext.SetAuthorizer(r.Context(), session))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	res = w.Result()
+	body, _ = ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 {
+		t.Fatalf("unexpected status code confirming enrollment: %d: %s", res.StatusCode, body)
+	}
+	if eq, diff, _ := jsonEqual(string(body), `{"recoveryCodes":["abcd-efgh"]}`); !eq {
+		t.Errorf("unexpected response body -got/+want\n%s", diff)
+	}
+}
+
+func TestUserHandler_MFACodeRequired(t *testing.T) {
+	var deleted, enrolled, generated int
+	isEnrolled := true
+	svc := mock.NewMFAService()
+	svc.FindMFAFn = func(ctx context.Context, userID platform.ID) (*platform.MFA, error) {
+		return &platform.MFA{UserID: userID, Enrolled: isEnrolled}, nil
+	}
+	svc.VerifyMFAFn = func(ctx context.Context, userID platform.ID, code string) error {
+		if userID != 1 || code != "123456" {
+			return &platform.Error{Code: platform.EUnauthorized, Msg: platform.ErrMFAInvalidCode}
+		}
+		return nil
+	}
+	svc.DeleteMFAFn = func(ctx context.Context, userID platform.ID) error {
+		deleted++
+		return nil
+	}
+	svc.EnrollTOTPFn = func(ctx context.Context, userID platform.ID) (*platform.TOTPEnrollment, error) {
+		enrolled++
+		return &platform.TOTPEnrollment{Secret: "secret"}, nil
+	}
+	svc.GenerateRecoveryCodesFn = func(ctx context.Context, userID platform.ID) ([]string, error) {
+		generated++
+		return []string{"abcd-efgh"}, nil
+	}
+
+	h := NewUserHandler(&UserBackend{
+		Logger:      zap.NewNop(),
+		UserService: mock.NewUserService(),
+		MFAService:  svc,
+	})
+	session := &platform.Session{UserID: 1, Permissions: platform.MePermissions(1)}
+
+	do := func(method, path, body string, a platform.Authorizer) int {
+		t.Helper()
+		r := httptest.NewRequest(method, "http://any.url"+path, strings.NewReader(body))
+		r = r.WithContext(pcontext.SetAuthorizer(r.Context(), a))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result().StatusCode
+	}
+
+	tests := []struct {
+		method, path string
+		count        *int
+	}{
+		{"DELETE", "/api/v2/me/mfa", &deleted},
+		{"POST", "/api/v2/me/mfa/totp", &enrolled},
+		{"POST", "/api/v2/me/mfa/recovery-codes", &generated},
+	}
+	for _, tt := range tests {
+		if code := do(tt.method, tt.path, "", session); code != 401 {
+			t.Errorf("%s %s without a code: unexpected status code %d", tt.method, tt.path, code)
+		}
+		if code := do(tt.method, tt.path, `{"code":"654321"}`, session); code != 401 {
+			t.Errorf("%s %s with an invalid code: unexpected status code %d", tt.method, tt.path, code)
+		}
+		if *tt.count != 0 {
+			t.Errorf("%s %s was applied without a valid code", tt.method, tt.path)
+		}
+		if code := do(tt.method, tt.path, `{"code":"123456"}`, session); code >= 300 {
+			t.Errorf("%s %s with a valid code: unexpected status code %d", tt.method, tt.path, code)
+		}
+		if *tt.count != 1 {
+			t.Errorf("%s %s was not applied with a valid code", tt.method, tt.path)
+		}
+	}
+
+	// Only operators reset the multi-factor authentication of a user, even
+	// their own.
+	if code := do("DELETE", "/api/v2/users/0000000000000001/mfa", "", session); code != 401 {
+		t.Errorf("unexpected status code resetting mfa as the user: %d", code)
+	}
+	oper := &platform.Authorization{Status: platform.Active, Permissions: platform.OperPermissions()}
+	if code := do("DELETE", "/api/v2/users/0000000000000001/mfa", "", oper); code != 204 {
+		t.Errorf("unexpected status code resetting mfa as an operator: %d", code)
+	}
+	if deleted != 2 {
+		t.Errorf("expected the operator to reset mfa")
+	}
+
+	// Users enroll without a code the first time.
+	isEnrolled = false
+	if code := do("POST", "/api/v2/me/mfa/totp", "", session); code != 201 {
+		t.Errorf("unexpected status code enrolling: %d", code)
+	}
+}
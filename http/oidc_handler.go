@@ -0,0 +1,351 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/oidc"
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+)
+
+const (
+	oidcSigninPath   = "/api/v2/signin/oidc"
+	oidcCallbackPath = "/api/v2/signin/oidc/callback"
+	oidcMFAPath      = "/api/v2/signin/oidc/mfa"
+
+	// cookieOIDCName is the cookie holding the state and nonce of a sign in
+	// until the identity provider redirects back to the callback.
+	cookieOIDCName = "oidc"
+
+	// cookieOIDCMFAName is the cookie identifying a sign in waiting for the
+	// multi-factor authentication code of the user.
+	cookieOIDCMFAName = "oidc_mfa"
+
+	// oidcSigninTimeout is how long users have to sign in with the identity
+	// provider.
+	oidcSigninTimeout = 10 * time.Minute
+)
+
+// OIDCBackend is all services and associated parameters required to construct
+// the OIDCHandler.
+type OIDCBackend struct {
+	Logger *zap.Logger
+
+	Provider        *oidc.Provider
+	UserProvisioner *oidc.UserProvisioner
+	SessionService  platform.SessionService
+
+	// MFAService verifies the codes of users enrolled in multi-factor
+	// authentication. Codes are not required if it is nil.
+	MFAService platform.MFAService
+}
+
+// NewOIDCBackend creates a new OIDCBackend with associated logger.
+func NewOIDCBackend(b *APIBackend) *OIDCBackend {
+	return &OIDCBackend{
+		Logger: b.Logger.With(zap.String("handler", "oidc")),
+
+		Provider:        b.OIDCProvider,
+		UserProvisioner: b.OIDCUserProvisioner,
+		SessionService:  b.SessionService,
+		MFAService:      b.MFAService,
+	}
+}
+
+// OIDCHandler signs users in with an OpenID Connect identity provider, and
+// starts a session for them. Users enrolled in multi-factor authentication
+// then give their code, as they do when signing in with a password.
+type OIDCHandler struct {
+	*httprouter.Router
+	Logger *zap.Logger
+
+	Provider        *oidc.Provider
+	UserProvisioner *oidc.UserProvisioner
+	SessionService  platform.SessionService
+	MFAService      platform.MFAService
+
+	mu      sync.Mutex
+	pending map[string]*pendingSignin
+}
+
+// pendingSignin is a sign in waiting for the multi-factor authentication code
+// of the user.
+type pendingSignin struct {
+	user    *platform.User
+	expires time.Time
+}
+
+// NewOIDCHandler returns a new instance of OIDCHandler.
+func NewOIDCHandler(b *OIDCBackend) *OIDCHandler {
+	h := &OIDCHandler{
+		Router: NewRouter(),
+		Logger: b.Logger,
+
+		Provider:        b.Provider,
+		UserProvisioner: b.UserProvisioner,
+		SessionService:  b.SessionService,
+		MFAService:      b.MFAService,
+
+		pending: make(map[string]*pendingSignin),
+	}
+
+	h.HandlerFunc("GET", oidcSigninPath, h.handleSignin)
+	h.HandlerFunc("GET", oidcCallbackPath, h.handleCallback)
+	h.HandlerFunc("POST", oidcMFAPath, h.handleMFA)
+	return h
+}
+
+// handleSignin is the HTTP handler for the GET /api/v2/signin/oidc route. It
+// redirects users to the identity provider.
+func (h *OIDCHandler) handleSignin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	state, err := randomHex()
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+	nonce, err := randomHex()
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieOIDCName,
+		Value:    state + "." + nonce,
+		Path:     oidcCallbackPath,
+		MaxAge:   int(oidcSigninTimeout / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.Provider.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+// handleCallback is the HTTP handler for the GET /api/v2/signin/oidc/callback
+// route, that the identity provider redirects users to once signed in.
+func (h *OIDCHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   cookieOIDCName,
+		Path:   oidcCallbackPath,
+		MaxAge: -1,
+	})
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		h.Logger.Info("Identity provider refused sign in", zap.String("error", e), zap.String("description", q.Get("error_description")))
+		UnauthorizedError(ctx, w)
+		return
+	}
+
+	c, err := r.Cookie(cookieOIDCName)
+	if err != nil {
+		UnauthorizedError(ctx, w)
+		return
+	}
+	i := strings.IndexByte(c.Value, '.')
+	if i < 0 || subtle.ConstantTimeCompare([]byte(c.Value[:i]), []byte(q.Get("state"))) != 1 {
+		UnauthorizedError(ctx, w)
+		return
+	}
+	nonce := c.Value[i+1:]
+
+	claims, err := h.Provider.Exchange(ctx, q.Get("code"), nonce)
+	if err != nil {
+		h.Logger.Info("Failed to exchange code with identity provider", zap.Error(err))
+		UnauthorizedError(ctx, w)
+		return
+	}
+
+	u, err := h.UserProvisioner.Provision(ctx, claims)
+	if err != nil {
+		h.Logger.Info("Failed to provision user signing in", zap.Error(err))
+		if platform.ErrorCode(err) == platform.EUnauthorized {
+			UnauthorizedError(ctx, w)
+			return
+		}
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	enrolled, err := h.mfaEnrolled(ctx, u.ID)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+	if enrolled {
+		key, err := h.addPending(u)
+		if err != nil {
+			EncodeError(ctx, err, w)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieOIDCMFAName,
+			Value:    key,
+			Path:     oidcMFAPath,
+			MaxAge:   int(oidcSigninTimeout / time.Second),
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EUnauthorized,
+			Msg:  platform.ErrMFACodeRequired,
+		}, w)
+		return
+	}
+
+	if err := h.startSession(w, r, u); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// handleMFA is the HTTP handler for the POST /api/v2/signin/oidc/mfa route. It
+// starts the session of a user signed in by the identity provider once they
+// give their multi-factor authentication code.
+func (h *OIDCHandler) handleMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	c, err := r.Cookie(cookieOIDCMFAName)
+	if err != nil {
+		UnauthorizedError(ctx, w)
+		return
+	}
+	u := h.findPending(c.Value)
+	if u == nil {
+		UnauthorizedError(ctx, w)
+		return
+	}
+
+	var req signinRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EInvalid,
+			Err:  err,
+		}, w)
+		return
+	}
+	if req.Code == "" {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EUnauthorized,
+			Msg:  platform.ErrMFACodeRequired,
+		}, w)
+		return
+	}
+	if err := h.MFAService.VerifyMFA(ctx, u.ID, req.Code); err != nil {
+		// Clients are told when they are locked out, but not why a code is
+		// invalid.
+		if platform.ErrorMessage(err) == platform.ErrMFALocked {
+			EncodeError(ctx, err, w)
+			return
+		}
+		UnauthorizedError(ctx, w)
+		return
+	}
+	h.deletePending(c.Value)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   cookieOIDCMFAName,
+		Path:   oidcMFAPath,
+		MaxAge: -1,
+	})
+	if err := h.startSession(w, r, u); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startSession creates a session for the user and sets its cookie.
+func (h *OIDCHandler) startSession(w http.ResponseWriter, r *http.Request, u *platform.User) error {
+	ctx := r.Context()
+
+	s, err := h.SessionService.CreateSession(ctx, u.Name)
+	if err != nil {
+		return err
+	}
+	recordSessionClient(ctx, h.Logger, h.SessionService, s, r)
+
+	// The session cookie is scoped as the one set by /api/v2/signin.
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieSessionName,
+		Value:    s.Key,
+		Path:     "/api/v2",
+		HttpOnly: true,
+	})
+	return nil
+}
+
+// mfaEnrolled reports whether the user is enrolled in multi-factor
+// authentication.
+func (h *OIDCHandler) mfaEnrolled(ctx context.Context, userID platform.ID) (bool, error) {
+	if h.MFAService == nil {
+		return false, nil
+	}
+	m, err := h.MFAService.FindMFA(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return m.Enrolled, nil
+}
+
+// addPending adds a sign in waiting for the code of the user, and returns its
+// key. Sign ins expire after oidcSigninTimeout.
+func (h *OIDCHandler) addPending(u *platform.User) (string, error) {
+	key, err := randomHex()
+	if err != nil {
+		return "", err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for k, p := range h.pending {
+		if now.After(p.expires) {
+			delete(h.pending, k)
+		}
+	}
+	h.pending[key] = &pendingSignin{user: u, expires: now.Add(oidcSigninTimeout)}
+	return key, nil
+}
+
+// findPending returns the user of a sign in waiting for their code, or nil if
+// there is no such sign in or it expired.
+func (h *OIDCHandler) findPending(key string) *platform.User {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.pending[key]
+	if !ok || time.Now().After(p.expires) {
+		return nil
+	}
+	return p.user
+}
+
+func (h *OIDCHandler) deletePending(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.pending, key)
+}
+
+func randomHex() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,249 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/membership"
+	"github.com/influxdata/influxdb/oidc"
+	"github.com/influxdata/influxdb/oidc/oidctest"
+	"github.com/influxdata/influxdb/totp"
+	"go.uber.org/zap"
+)
+
+func TestOIDCHandler(t *testing.T) {
+	idp := oidctest.NewServer("influxdb", "secret")
+	defer idp.Close()
+	idp.SetClaims(map[string]interface{}{
+		"sub":                "1",
+		"preferred_username": "jane",
+		"groups":             []string{"devs"},
+	})
+
+	ctx := context.Background()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	org := &platform.Organization{Name: "acme"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := oidc.NewProvider(ctx, oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     "influxdb",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:9999" + oidcCallbackPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewOIDCHandler(&OIDCBackend{
+		Logger:   zap.NewNop(),
+		Provider: provider,
+		UserProvisioner: &oidc.UserProvisioner{
+			Membership: &membership.Syncer{
+				Mappings:                   []membership.Mapping{{Group: "devs", Org: "acme", Role: platform.Member}},
+				OrganizationService:        svc,
+				UserResourceMappingService: svc,
+			},
+			UserService:         svc,
+			UserIdentityService: svc,
+		},
+		SessionService: svc,
+		MFAService:     svc,
+	})
+
+	// signin redirects to the identity provider, which redirects back to
+	// the callback.
+	signin := func() (*url.URL, *http.Cookie) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", oidcSigninPath, nil))
+		resp := w.Result()
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("unexpected status signing in: %d", resp.StatusCode)
+		}
+		cookies := resp.Cookies()
+		if len(cookies) != 1 || cookies[0].Name != cookieOIDCName {
+			t.Fatalf("unexpected cookies: %v", cookies)
+		}
+
+		client := &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		idpResp, err := client.Get(resp.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		idpResp.Body.Close()
+		callback, err := url.Parse(idpResp.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return callback, cookies[0]
+	}
+
+	t.Run("signs in", func(t *testing.T) {
+		callback, cookie := signin()
+		r := httptest.NewRequest("GET", callback.RequestURI(), nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("unexpected status: %d", resp.StatusCode)
+		}
+		var key string
+		for _, c := range resp.Cookies() {
+			if c.Name == cookieSessionName {
+				key = c.Value
+			}
+		}
+		s, err := svc.FindSession(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := svc.FindUserByID(ctx, s.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Name != "jane" {
+			t.Fatalf("unexpected user: %+v", u)
+		}
+		ms, _, err := svc.FindUserResourceMappings(ctx, platform.UserResourceMappingFilter{UserID: u.ID, ResourceID: org.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ms) != 1 || ms[0].UserType != platform.Member {
+			t.Fatalf("unexpected memberships: %v", ms)
+		}
+	})
+
+	t.Run("requires the code of users enrolled in MFA", func(t *testing.T) {
+		name := "jane"
+		u, err := svc.FindUser(ctx, platform.UserFilter{Name: &name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := svc.EnrollTOTP(ctx, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := totp.Code(e.Secret, totp.Step(time.Now()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		recoveryCodes, err := svc.ConfirmTOTP(ctx, u.ID, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer svc.DeleteMFA(ctx, u.ID)
+
+		callback, cookie := signin()
+		r := httptest.NewRequest("GET", callback.RequestURI(), nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("unexpected status: %d", resp.StatusCode)
+		}
+		var pending *http.Cookie
+		for _, c := range resp.Cookies() {
+			switch c.Name {
+			case cookieSessionName:
+				t.Fatal("unexpected session cookie without a code")
+			case cookieOIDCMFAName:
+				pending = c
+			}
+		}
+		if pending == nil {
+			t.Fatal("expected a cookie for the code")
+		}
+
+		submit := func(code string) *http.Response {
+			r := httptest.NewRequest("POST", oidcMFAPath, strings.NewReader(`{"code":"`+code+`"}`))
+			r.AddCookie(pending)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			return w.Result()
+		}
+
+		if resp := submit("000000"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("unexpected status with an invalid code: %d", resp.StatusCode)
+		}
+
+		resp = submit(recoveryCodes[0])
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("unexpected status: %d", resp.StatusCode)
+		}
+		var key string
+		for _, c := range resp.Cookies() {
+			if c.Name == cookieSessionName {
+				key = c.Value
+			}
+		}
+		s, err := svc.FindSession(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.UserID != u.ID {
+			t.Fatalf("unexpected session user: %s", s.UserID)
+		}
+
+		// The sign in cannot be completed twice.
+		if resp := submit(recoveryCodes[1]); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("unexpected status completing a sign in twice: %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("state does not match", func(t *testing.T) {
+		callback, cookie := signin()
+		q := callback.Query()
+		q.Set("state", "other")
+		callback.RawQuery = q.Encode()
+
+		r := httptest.NewRequest("GET", callback.RequestURI(), nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("unexpected status: %d", w.Code)
+		}
+	})
+
+	t.Run("no cookie", func(t *testing.T) {
+		callback, _ := signin()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", callback.RequestURI(), nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("unexpected status: %d", w.Code)
+		}
+	})
+
+	t.Run("sign in refused", func(t *testing.T) {
+		_, cookie := signin()
+		r := httptest.NewRequest("GET", oidcCallbackPath+"?error=access_denied", nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("unexpected status: %d", w.Code)
+		}
+	})
+}
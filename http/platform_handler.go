@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 )
 
 // PlatformHandler is a collection of all the service handlers.
@@ -12,6 +13,8 @@ type PlatformHandler struct {
 	AssetHandler *AssetHandler
 	DocsHandler  http.HandlerFunc
 	APIHandler   http.Handler
+
+	collectors []prometheus.Collector
 }
 
 func setCORSResponseHeaders(w http.ResponseWriter, r *http.Request) {
@@ -24,14 +27,26 @@ func setCORSResponseHeaders(w http.ResponseWriter, r *http.Request) {
 
 // NewPlatformHandler returns a platform handler that serves the API and associated assets.
 func NewPlatformHandler(b *APIBackend) *PlatformHandler {
+	apiHandler := NewAPIHandler(b)
+
 	h := NewAuthenticationHandler()
-	h.Handler = NewAPIHandler(b)
+	h.Handler = apiHandler
+	if b.AuditLogService != nil {
+		am := NewAuditMiddleware(apiHandler, b.AuditLogService)
+		am.Logger = b.Logger.With(zap.String("handler", "audit_middleware"))
+		am.OrganizationService = b.OrganizationService
+		h.Handler = am
+	}
 	h.AuthorizationService = b.AuthorizationService
 	h.SessionService = b.SessionService
+	h.RoleService = b.RoleService
 
 	h.RegisterNoAuthRoute("GET", "/api/v2")
 	h.RegisterNoAuthRoute("POST", "/api/v2/signin")
 	h.RegisterNoAuthRoute("POST", "/api/v2/signout")
+	h.RegisterNoAuthRoute("GET", oidcSigninPath)
+	h.RegisterNoAuthRoute("GET", oidcCallbackPath)
+	h.RegisterNoAuthRoute("POST", oidcMFAPath)
 	h.RegisterNoAuthRoute("POST", "/api/v2/setup")
 	h.RegisterNoAuthRoute("GET", "/api/v2/setup")
 	h.RegisterNoAuthRoute("GET", "/api/v2/swagger.json")
@@ -43,6 +58,7 @@ func NewPlatformHandler(b *APIBackend) *PlatformHandler {
 		AssetHandler: assetHandler,
 		DocsHandler:  Redoc("/api/v2/swagger.json"),
 		APIHandler:   h,
+		collectors:   apiHandler.WriteHandler.PrometheusCollectors(),
 	}
 }
 
@@ -72,6 +88,5 @@ func (h *PlatformHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // PrometheusCollectors satisfies the prom.PrometheusCollector interface.
 func (h *PlatformHandler) PrometheusCollectors() []prometheus.Collector {
-	// TODO: collect and return relevant metrics.
-	return nil
+	return h.collectors
 }
@@ -28,6 +28,7 @@ func TestFluxService_Query(t *testing.T) {
 		status  int
 		want    flux.Statistics
 		wantW   string
+		wantOrg string
 		wantErr bool
 	}{
 		{
@@ -46,6 +47,24 @@ func TestFluxService_Query(t *testing.T) {
 			want:   flux.Statistics{},
 			wantW:  "howdy\n",
 		},
+		{
+			name:  "query with organization",
+			ctx:   context.Background(),
+			token: "mytoken",
+			r: &query.ProxyRequest{
+				Request: query.Request{
+					OrganizationID: 1,
+					Compiler: lang.FluxCompiler{
+						Query: "from()",
+					},
+				},
+				Dialect: csv.DefaultDialect(),
+			},
+			status:  http.StatusOK,
+			want:    flux.Statistics{},
+			wantW:   "howdy\n",
+			wantOrg: "0000000000000001",
+		},
 		{
 			name:  "error status",
 			token: "mytoken",
@@ -64,7 +83,9 @@ func TestFluxService_Query(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			var orgIDStr string
 			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				orgIDStr = r.URL.Query().Get(OrgID)
 				w.WriteHeader(tt.status)
 				fmt.Fprintln(w, "howdy")
 			}))
@@ -86,6 +107,9 @@ func TestFluxService_Query(t *testing.T) {
 			if gotW := w.String(); gotW != tt.wantW {
 				t.Errorf("FluxService.Query() = %v, want %v", gotW, tt.wantW)
 			}
+			if orgIDStr != tt.wantOrg {
+				t.Errorf("FluxService.Query() orgID = %q, want %q", orgIDStr, tt.wantOrg)
+			}
 		})
 	}
 }
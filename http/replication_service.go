@@ -0,0 +1,393 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"github.com/influxdata/influxdb"
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+)
+
+// ReplicationBackend is all services and associated parameters required to
+// construct the ReplicationHandler.
+type ReplicationBackend struct {
+	Logger *zap.Logger
+
+	ReplicationService influxdb.ReplicationService
+}
+
+// NewReplicationBackend returns a new instance of ReplicationBackend.
+func NewReplicationBackend(b *APIBackend) *ReplicationBackend {
+	return &ReplicationBackend{
+		Logger: b.Logger.With(zap.String("handler", "replication")),
+
+		ReplicationService: b.ReplicationService,
+	}
+}
+
+// ReplicationHandler represents an HTTP API handler for replications.
+type ReplicationHandler struct {
+	*httprouter.Router
+	Logger *zap.Logger
+
+	ReplicationService influxdb.ReplicationService
+}
+
+const (
+	replicationsPath   = "/api/v2/replications"
+	replicationsIDPath = "/api/v2/replications/:id"
+)
+
+// NewReplicationHandler returns a new instance of ReplicationHandler.
+func NewReplicationHandler(b *ReplicationBackend) *ReplicationHandler {
+	h := &ReplicationHandler{
+		Router: NewRouter(),
+		Logger: b.Logger,
+
+		ReplicationService: b.ReplicationService,
+	}
+
+	h.HandlerFunc("POST", replicationsPath, h.handlePostReplication)
+	h.HandlerFunc("GET", replicationsPath, h.handleGetReplications)
+	h.HandlerFunc("GET", replicationsIDPath, h.handleGetReplication)
+	h.HandlerFunc("PATCH", replicationsIDPath, h.handlePatchReplication)
+	h.HandlerFunc("DELETE", replicationsIDPath, h.handleDeleteReplication)
+	return h
+}
+
+type replicationLinks struct {
+	Self         string `json:"self"`
+	Organization string `json:"org"`
+	Bucket       string `json:"sourceBucket"`
+}
+
+// replicationResponse never includes the token used to write to the remote.
+type replicationResponse struct {
+	*influxdb.Replication
+	RemoteToken string           `json:"remoteToken,omitempty"`
+	Links       replicationLinks `json:"links"`
+}
+
+func newReplicationResponse(r *influxdb.Replication) *replicationResponse {
+	return &replicationResponse{
+		Replication: r,
+		Links: replicationLinks{
+			Self:         replicationIDPath(r.ID),
+			Organization: path.Join(organizationsPath, r.OrgID.String()),
+			Bucket:       path.Join(bucketsPath, r.SourceBucketID.String()),
+		},
+	}
+}
+
+type replicationsResponse struct {
+	Links        *influxdb.PagingLinks  `json:"links"`
+	Replications []*replicationResponse `json:"replications"`
+}
+
+func newReplicationsResponse(rs []*influxdb.Replication) *replicationsResponse {
+	res := &replicationsResponse{
+		Links: &influxdb.PagingLinks{
+			Self: replicationsPath,
+		},
+		Replications: make([]*replicationResponse, 0, len(rs)),
+	}
+	for _, r := range rs {
+		res.Replications = append(res.Replications, newReplicationResponse(r))
+	}
+	return res
+}
+
+// handlePostReplication is the HTTP handler for the POST /api/v2/replications route.
+func (h *ReplicationHandler) handlePostReplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rep := &influxdb.Replication{}
+	if err := json.NewDecoder(r.Body).Decode(rep); err != nil {
+		EncodeError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode replication",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.ReplicationService.CreateReplication(ctx, rep); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, newReplicationResponse(rep)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetReplications is the HTTP handler for the GET /api/v2/replications route.
+func (h *ReplicationHandler) handleGetReplications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := decodeReplicationFilter(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	rs, _, err := h.ReplicationService.FindReplications(ctx, *filter)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newReplicationsResponse(rs)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetReplication is the HTTP handler for the GET /api/v2/replications/:id route.
+func (h *ReplicationHandler) handleGetReplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeReplicationIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	rep, err := h.ReplicationService.FindReplicationByID(ctx, id)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newReplicationResponse(rep)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handlePatchReplication is the HTTP handler for the PATCH /api/v2/replications/:id route.
+func (h *ReplicationHandler) handlePatchReplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeReplicationIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	var upd influxdb.ReplicationUpdate
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		EncodeError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode replication update",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	rep, err := h.ReplicationService.UpdateReplication(ctx, id, upd)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newReplicationResponse(rep)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleDeleteReplication is the HTTP handler for the DELETE /api/v2/replications/:id route.
+func (h *ReplicationHandler) handleDeleteReplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeReplicationIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.ReplicationService.DeleteReplication(ctx, id); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeReplicationIDRequest(ctx context.Context) (influxdb.ID, error) {
+	params := httprouter.ParamsFromContext(ctx)
+	id := params.ByName("id")
+	if id == "" {
+		return 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "url missing id",
+		}
+	}
+
+	var i influxdb.ID
+	if err := i.DecodeFromString(id); err != nil {
+		return 0, err
+	}
+	return i, nil
+}
+
+func decodeReplicationFilter(ctx context.Context, r *http.Request) (*influxdb.ReplicationFilter, error) {
+	f := &influxdb.ReplicationFilter{}
+
+	q := r.URL.Query()
+	if orgID := q.Get("orgID"); orgID != "" {
+		id, err := influxdb.IDFromString(orgID)
+		if err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "orgID is invalid",
+				Err:  err,
+			}
+		}
+		f.OrgID = id
+	}
+	if bucketID := q.Get("sourceBucketID"); bucketID != "" {
+		id, err := influxdb.IDFromString(bucketID)
+		if err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "sourceBucketID is invalid",
+				Err:  err,
+			}
+		}
+		f.SourceBucketID = id
+	}
+	return f, nil
+}
+
+// ReplicationService connects to Influx via HTTP using tokens to manage replications.
+type ReplicationService struct {
+	Addr               string
+	Token              string
+	InsecureSkipVerify bool
+}
+
+var _ influxdb.ReplicationService = (*ReplicationService)(nil)
+
+// FindReplicationByID returns a single replication by ID.
+func (s *ReplicationService) FindReplicationByID(ctx context.Context, id influxdb.ID) (*influxdb.Replication, error) {
+	var res replicationResponse
+	if err := s.do(ctx, "GET", replicationIDPath(id), nil, nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Replication, nil
+}
+
+// FindReplications returns a list of replications that match filter and the
+// total count of matching replications.
+func (s *ReplicationService) FindReplications(ctx context.Context, filter influxdb.ReplicationFilter) ([]*influxdb.Replication, int, error) {
+	query := make(map[string]string)
+	if filter.OrgID != nil {
+		query["orgID"] = filter.OrgID.String()
+	}
+	if filter.SourceBucketID != nil {
+		query["sourceBucketID"] = filter.SourceBucketID.String()
+	}
+
+	var res replicationsResponse
+	if err := s.do(ctx, "GET", replicationsPath, query, nil, &res); err != nil {
+		return nil, 0, err
+	}
+
+	rs := make([]*influxdb.Replication, 0, len(res.Replications))
+	for _, r := range res.Replications {
+		rs = append(rs, r.Replication)
+	}
+	return rs, len(rs), nil
+}
+
+// CreateReplication creates a new replication and sets r.ID with the new identifier.
+func (s *ReplicationService) CreateReplication(ctx context.Context, r *influxdb.Replication) error {
+	res := replicationResponse{Replication: &influxdb.Replication{}}
+	if err := s.do(ctx, "POST", replicationsPath, nil, r, &res); err != nil {
+		return err
+	}
+	r.ID = res.ID
+	r.MaxQueueSize = res.MaxQueueSize
+	return nil
+}
+
+// UpdateReplication updates a single replication with changeset.
+// Returns the new replication state after update.
+func (s *ReplicationService) UpdateReplication(ctx context.Context, id influxdb.ID, upd influxdb.ReplicationUpdate) (*influxdb.Replication, error) {
+	var res replicationResponse
+	if err := s.do(ctx, "PATCH", replicationIDPath(id), nil, upd, &res); err != nil {
+		return nil, err
+	}
+	return res.Replication, nil
+}
+
+// DeleteReplication removes a replication by ID.
+func (s *ReplicationService) DeleteReplication(ctx context.Context, id influxdb.ID) error {
+	return s.do(ctx, "DELETE", replicationIDPath(id), nil, nil, nil)
+}
+
+// do sends a request with an optional JSON body and decodes the response into
+// res, if it is not nil.
+func (s *ReplicationService) do(ctx context.Context, method, p string, query map[string]string, body, res interface{}) error {
+	u, err := newURL(s.Addr, p)
+	if err != nil {
+		return err
+	}
+
+	if len(query) > 0 {
+		q := u.Query()
+		for k, v := range query {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var r *bytes.Reader
+	if body != nil {
+		octets, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(octets)
+	} else {
+		r = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, u.String(), r)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	SetToken(s.Token, req)
+
+	hc := newClient(u.Scheme, s.InsecureSkipVerify)
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckError(resp); err != nil {
+		return err
+	}
+
+	if res == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+func replicationIDPath(id influxdb.ID) string {
+	return path.Join(replicationsPath, id.String())
+}
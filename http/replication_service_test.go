@@ -0,0 +1,163 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	httpMock "github.com/influxdata/influxdb/http/mock"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap"
+)
+
+func TestReplicationHandler_handleGetReplication(t *testing.T) {
+	svc := mock.NewReplicationService()
+	svc.FindReplicationByIDFn = func(ctx context.Context, id platform.ID) (*platform.Replication, error) {
+		if id != 1 {
+			return nil, &platform.Error{Code: platform.ENotFound, Msg: platform.ErrReplicationNotFound}
+		}
+		return &platform.Replication{
+			ID:             1,
+			OrgID:          2,
+			Name:           "edge",
+			SourceBucketID: 3,
+			RemoteURL:      "https://cloud.example.com",
+			RemoteToken:    "secret",
+			RemoteOrgID:    4,
+			RemoteBucketID: 5,
+			MaxQueueSize:   1024,
+		}, nil
+	}
+
+	h := NewReplicationHandler(&ReplicationBackend{
+		Logger:             zap.NewNop(),
+		ReplicationService: svc,
+	})
+
+	r := httptest.NewRequest("GET", "http://any.url/api/v2/replications/0000000000000001", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	res := w.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 {
+		t.Fatalf("unexpected status code: %d: %s", res.StatusCode, body)
+	}
+	if strings.Contains(string(body), "secret") {
+		t.Fatalf("response includes the remote token: %s", body)
+	}
+	want := `
+{
+  "id": "0000000000000001",
+  "orgID": "0000000000000002",
+  "name": "edge",
+  "sourceBucketID": "0000000000000003",
+  "remoteURL": "https://cloud.example.com",
+  "remoteOrgID": "0000000000000004",
+  "remoteBucketID": "0000000000000005",
+  "maxQueueSizeBytes": 1024,
+  "links": {
+    "self": "/api/v2/replications/0000000000000001",
+    "org": "/api/v2/orgs/0000000000000002",
+    "sourceBucket": "/api/v2/buckets/0000000000000003"
+  }
+}
+`
+	if eq, diff, _ := jsonEqual(string(body), want); !eq {
+		t.Errorf("unexpected response body -got/+want\n%s", diff)
+	}
+
+	r = httptest.NewRequest("GET", "http://any.url/api/v2/replications/0000000000000002", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Result().StatusCode; got != 404 {
+		t.Errorf("unexpected status code for a missing replication: %d", got)
+	}
+}
+
+func TestReplicationService(t *testing.T) {
+	var created *platform.Replication
+	svc := mock.NewReplicationService()
+	svc.CreateReplicationFn = func(ctx context.Context, r *platform.Replication) error {
+		r.ID = 1
+		r.MaxQueueSize = platform.DefaultReplicationMaxQueueSize
+		created = r
+		return nil
+	}
+	svc.FindReplicationsFn = func(ctx context.Context, filter platform.ReplicationFilter) ([]*platform.Replication, int, error) {
+		if filter.OrgID == nil || *filter.OrgID != 2 {
+			t.Errorf("unexpected filter: %+v", filter)
+		}
+		return []*platform.Replication{created}, 1, nil
+	}
+	svc.UpdateReplicationFn = func(ctx context.Context, id platform.ID, upd platform.ReplicationUpdate) (*platform.Replication, error) {
+		if err := upd.Apply(created); err != nil {
+			return nil, err
+		}
+		return created, nil
+	}
+
+	h := NewReplicationHandler(&ReplicationBackend{
+		Logger:             zap.NewNop(),
+		ReplicationService: svc,
+	})
+	server := httptest.NewServer(httpMock.NewAuthMiddlewareHandler(h, &platform.Authorization{Token: "tok"}))
+	defer server.Close()
+
+	client := &ReplicationService{Addr: server.URL, Token: "tok"}
+	ctx := context.Background()
+
+	r := &platform.Replication{
+		OrgID:          2,
+		Name:           "edge",
+		SourceBucketID: 3,
+		RemoteURL:      "https://cloud.example.com",
+		RemoteToken:    "secret",
+		RemoteOrgID:    4,
+		RemoteBucketID: 5,
+		Measurements:   []string{"cpu"},
+	}
+	if err := client.CreateReplication(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != 1 || r.MaxQueueSize != platform.DefaultReplicationMaxQueueSize {
+		t.Fatalf("unexpected created replication: %+v", r)
+	}
+	if created.RemoteToken != "secret" {
+		t.Fatalf("remote token was not sent: %+v", created)
+	}
+
+	orgID := platform.ID(2)
+	rs, n, err := client.FindReplications(ctx, platform.ReplicationFilter{OrgID: &orgID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := *r
+	want.RemoteToken = ""
+	if n != 1 || !reflect.DeepEqual(rs, []*platform.Replication{&want}) {
+		t.Fatalf("unexpected replications: %+v", rs)
+	}
+
+	name := "edge2"
+	updated, err := client.UpdateReplication(ctx, r.ID, platform.ReplicationUpdate{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != name {
+		t.Fatalf("unexpected updated replication: %+v", updated)
+	}
+
+	badURL := "ftp://cloud.example.com"
+	_, err = client.UpdateReplication(ctx, r.ID, platform.ReplicationUpdate{RemoteURL: &badURL})
+	if platform.ErrorCode(err) != platform.EInvalid {
+		t.Fatalf("expected an invalid error, got %v", err)
+	}
+
+	if err := client.DeleteReplication(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,403 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"github.com/influxdata/influxdb"
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+)
+
+// RoleBackend is all services and associated parameters required to
+// construct the RoleHandler.
+type RoleBackend struct {
+	Logger *zap.Logger
+
+	RoleService influxdb.RoleService
+}
+
+// NewRoleBackend returns a new instance of RoleBackend.
+func NewRoleBackend(b *APIBackend) *RoleBackend {
+	return &RoleBackend{
+		Logger: b.Logger.With(zap.String("handler", "role")),
+
+		RoleService: b.RoleService,
+	}
+}
+
+// RoleHandler represents an HTTP API handler for roles and their assignments.
+type RoleHandler struct {
+	*httprouter.Router
+	Logger *zap.Logger
+
+	RoleService influxdb.RoleService
+}
+
+const (
+	rolesPath          = "/api/v2/roles"
+	rolesIDPath        = "/api/v2/roles/:id"
+	rolesMembersPath   = "/api/v2/roles/:id/members"
+	rolesMembersIDPath = "/api/v2/roles/:id/members/:subjectType/:subjectID"
+)
+
+// NewRoleHandler returns a new instance of RoleHandler.
+func NewRoleHandler(b *RoleBackend) *RoleHandler {
+	h := &RoleHandler{
+		Router: NewRouter(),
+		Logger: b.Logger,
+
+		RoleService: b.RoleService,
+	}
+
+	h.HandlerFunc("POST", rolesPath, h.handlePostRole)
+	h.HandlerFunc("GET", rolesPath, h.handleGetRoles)
+	h.HandlerFunc("GET", rolesIDPath, h.handleGetRole)
+	h.HandlerFunc("PATCH", rolesIDPath, h.handlePatchRole)
+	h.HandlerFunc("DELETE", rolesIDPath, h.handleDeleteRole)
+
+	h.HandlerFunc("POST", rolesMembersPath, h.handlePostRoleMember)
+	h.HandlerFunc("GET", rolesMembersPath, h.handleGetRoleMembers)
+	h.HandlerFunc("DELETE", rolesMembersIDPath, h.handleDeleteRoleMember)
+	return h
+}
+
+type roleLinks struct {
+	Self         string `json:"self"`
+	Members      string `json:"members"`
+	Organization string `json:"org"`
+}
+
+type roleResponse struct {
+	*influxdb.Role
+	Links roleLinks `json:"links"`
+}
+
+func newRoleResponse(r *influxdb.Role) *roleResponse {
+	return &roleResponse{
+		Role: r,
+		Links: roleLinks{
+			Self:         roleIDPath(r.ID),
+			Members:      path.Join(roleIDPath(r.ID), "members"),
+			Organization: path.Join(organizationsPath, r.OrgID.String()),
+		},
+	}
+}
+
+type rolesResponse struct {
+	Links *influxdb.PagingLinks `json:"links"`
+	Roles []*roleResponse       `json:"roles"`
+}
+
+func newRolesResponse(rs []*influxdb.Role) *rolesResponse {
+	res := &rolesResponse{
+		Links: &influxdb.PagingLinks{
+			Self: rolesPath,
+		},
+		Roles: make([]*roleResponse, 0, len(rs)),
+	}
+	for _, r := range rs {
+		res.Roles = append(res.Roles, newRoleResponse(r))
+	}
+	return res
+}
+
+type roleMemberLinks struct {
+	Self    string `json:"self"`
+	Role    string `json:"role"`
+	Subject string `json:"subject"`
+}
+
+type roleMemberResponse struct {
+	*influxdb.RoleMapping
+	Links roleMemberLinks `json:"links"`
+}
+
+func newRoleMemberResponse(m *influxdb.RoleMapping) *roleMemberResponse {
+	subject := path.Join(usersPath, m.SubjectID.String())
+	if m.SubjectType == influxdb.AuthorizationRoleSubject {
+		subject = path.Join("/api/v2/authorizations", m.SubjectID.String())
+	}
+	return &roleMemberResponse{
+		RoleMapping: m,
+		Links: roleMemberLinks{
+			Self:    path.Join(roleIDPath(m.RoleID), "members", string(m.SubjectType), m.SubjectID.String()),
+			Role:    roleIDPath(m.RoleID),
+			Subject: subject,
+		},
+	}
+}
+
+type roleMembersResponse struct {
+	Links   map[string]string     `json:"links"`
+	Members []*roleMemberResponse `json:"members"`
+}
+
+// handlePostRole is the HTTP handler for the POST /api/v2/roles route.
+func (h *RoleHandler) handlePostRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	role := &influxdb.Role{}
+	if err := json.NewDecoder(r.Body).Decode(role); err != nil {
+		EncodeError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode role",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.RoleService.CreateRole(ctx, role); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, newRoleResponse(role)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetRoles is the HTTP handler for the GET /api/v2/roles route.
+func (h *RoleHandler) handleGetRoles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := decodeRoleFilter(ctx, r)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	rs, _, err := h.RoleService.FindRoles(ctx, *filter)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newRolesResponse(rs)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetRole is the HTTP handler for the GET /api/v2/roles/:id route.
+func (h *RoleHandler) handleGetRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeRoleIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	role, err := h.RoleService.FindRoleByID(ctx, id)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newRoleResponse(role)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handlePatchRole is the HTTP handler for the PATCH /api/v2/roles/:id route.
+func (h *RoleHandler) handlePatchRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeRoleIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	var upd influxdb.RoleUpdate
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		EncodeError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode role update",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	role, err := h.RoleService.UpdateRole(ctx, id, upd)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newRoleResponse(role)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleDeleteRole is the HTTP handler for the DELETE /api/v2/roles/:id route.
+func (h *RoleHandler) handleDeleteRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeRoleIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.RoleService.DeleteRole(ctx, id); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePostRoleMember is the HTTP handler for the POST /api/v2/roles/:id/members route.
+func (h *RoleHandler) handlePostRoleMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeRoleIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	m := &influxdb.RoleMapping{}
+	if err := json.NewDecoder(r.Body).Decode(m); err != nil {
+		EncodeError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode role member",
+			Err:  err,
+		}, w)
+		return
+	}
+	m.RoleID = id
+
+	if err := h.RoleService.CreateRoleMapping(ctx, m); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, newRoleMemberResponse(m)); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleGetRoleMembers is the HTTP handler for the GET /api/v2/roles/:id/members route.
+func (h *RoleHandler) handleGetRoleMembers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeRoleIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	filter := influxdb.RoleMappingFilter{
+		RoleID:      &id,
+		SubjectType: influxdb.RoleSubjectType(r.URL.Query().Get("subjectType")),
+	}
+	if filter.SubjectType != "" {
+		if err := filter.SubjectType.Valid(); err != nil {
+			EncodeError(ctx, err, w)
+			return
+		}
+	}
+
+	ms, _, err := h.RoleService.FindRoleMappings(ctx, filter)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	res := &roleMembersResponse{
+		Links: map[string]string{
+			"self": path.Join(roleIDPath(id), "members"),
+			"role": roleIDPath(id),
+		},
+		Members: make([]*roleMemberResponse, 0, len(ms)),
+	}
+	for _, m := range ms {
+		res.Members = append(res.Members, newRoleMemberResponse(m))
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleDeleteRoleMember is the HTTP handler for the DELETE /api/v2/roles/:id/members/:subjectType/:subjectID route.
+func (h *RoleHandler) handleDeleteRoleMember(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeRoleIDRequest(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	params := httprouter.ParamsFromContext(ctx)
+	subjectType := influxdb.RoleSubjectType(params.ByName("subjectType"))
+	if err := subjectType.Valid(); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+	var subjectID influxdb.ID
+	if err := subjectID.DecodeFromString(params.ByName("subjectID")); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.RoleService.DeleteRoleMapping(ctx, id, subjectType, subjectID); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeRoleIDRequest(ctx context.Context) (influxdb.ID, error) {
+	params := httprouter.ParamsFromContext(ctx)
+	id := params.ByName("id")
+	if id == "" {
+		return 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "url missing id",
+		}
+	}
+
+	var i influxdb.ID
+	if err := i.DecodeFromString(id); err != nil {
+		return 0, err
+	}
+	return i, nil
+}
+
+func decodeRoleFilter(ctx context.Context, r *http.Request) (*influxdb.RoleFilter, error) {
+	f := &influxdb.RoleFilter{}
+
+	q := r.URL.Query()
+	if orgID := q.Get("orgID"); orgID != "" {
+		id, err := influxdb.IDFromString(orgID)
+		if err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "orgID is invalid",
+				Err:  err,
+			}
+		}
+		f.OrgID = id
+	}
+	if name := q.Get("name"); name != "" {
+		f.Name = &name
+	}
+	return f, nil
+}
+
+func roleIDPath(id influxdb.ID) string {
+	return path.Join(rolesPath, id.String())
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap"
+)
+
+func TestRoleHandler_handleGetRole(t *testing.T) {
+	orgID := platform.ID(2)
+	svc := mock.NewRoleService()
+	svc.FindRoleByIDFn = func(ctx context.Context, id platform.ID) (*platform.Role, error) {
+		if id != 1 {
+			return nil, &platform.Error{Code: platform.ENotFound, Msg: platform.ErrRoleNotFound}
+		}
+		return &platform.Role{
+			ID:    1,
+			OrgID: 2,
+			Name:  "reader",
+			Permissions: []platform.Permission{
+				{
+					Action: platform.ReadAction,
+					Resource: platform.Resource{
+						Type:  platform.BucketsResourceType,
+						OrgID: &orgID,
+					},
+				},
+			},
+		}, nil
+	}
+
+	h := NewRoleHandler(&RoleBackend{
+		Logger:      zap.NewNop(),
+		RoleService: svc,
+	})
+
+	r := httptest.NewRequest("GET", "http://any.url/api/v2/roles/0000000000000001", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	res := w.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 {
+		t.Fatalf("unexpected status code: %d: %s", res.StatusCode, body)
+	}
+	want := `
+{
+  "id": "0000000000000001",
+  "orgID": "0000000000000002",
+  "name": "reader",
+  "permissions": [
+    {
+      "action": "read",
+      "resource": {
+        "type": "buckets",
+        "orgID": "0000000000000002"
+      }
+    }
+  ],
+  "links": {
+    "self": "/api/v2/roles/0000000000000001",
+    "members": "/api/v2/roles/0000000000000001/members",
+    "org": "/api/v2/orgs/0000000000000002"
+  }
+}
+`
+	if eq, diff, _ := jsonEqual(string(body), want); !eq {
+		t.Errorf("unexpected response body -got/+want\n%s", diff)
+	}
+
+	r = httptest.NewRequest("GET", "http://any.url/api/v2/roles/0000000000000002", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Result().StatusCode; got != 404 {
+		t.Errorf("unexpected status code for a missing role: %d", got)
+	}
+}
+
+func TestRoleHandler_Members(t *testing.T) {
+	var created, deleted *platform.RoleMapping
+	svc := mock.NewRoleService()
+	svc.CreateRoleMappingFn = func(ctx context.Context, m *platform.RoleMapping) error {
+		created = m
+		return nil
+	}
+	svc.DeleteRoleMappingFn = func(ctx context.Context, roleID platform.ID, subjectType platform.RoleSubjectType, subjectID platform.ID) error {
+		deleted = &platform.RoleMapping{RoleID: roleID, SubjectType: subjectType, SubjectID: subjectID}
+		return nil
+	}
+
+	h := NewRoleHandler(&RoleBackend{
+		Logger:      zap.NewNop(),
+		RoleService: svc,
+	})
+
+	r := httptest.NewRequest("POST", "http://any.url/api/v2/roles/0000000000000001/members",
+		strings.NewReader(`{"subjectType":"authorization","subjectID":"0000000000000003"}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Result().StatusCode; got != 201 {
+		t.Fatalf("unexpected status code assigning a role: %d", got)
+	}
+	want := platform.RoleMapping{RoleID: 1, SubjectType: platform.AuthorizationRoleSubject, SubjectID: 3}
+	if created == nil || *created != want {
+		t.Fatalf("unexpected assignment: %+v", created)
+	}
+
+	r = httptest.NewRequest("DELETE", "http://any.url/api/v2/roles/0000000000000001/members/authorization/0000000000000003", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Result().StatusCode; got != 204 {
+		t.Fatalf("unexpected status code unassigning a role: %d", got)
+	}
+	if deleted == nil || *deleted != want {
+		t.Fatalf("unexpected unassignment: %+v", deleted)
+	}
+
+	r = httptest.NewRequest("DELETE", "http://any.url/api/v2/roles/0000000000000001/members/bucket/0000000000000003", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Result().StatusCode; got != 400 {
+		t.Fatalf("unexpected status code unassigning from an unknown subject: %d", got)
+	}
+}
@@ -2,7 +2,10 @@ package http
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
+	"time"
 
 	platform "github.com/influxdata/influxdb"
 	"github.com/julienschmidt/httprouter"
@@ -16,6 +19,11 @@ type SessionBackend struct {
 
 	PasswordsService platform.PasswordsService
 	SessionService   platform.SessionService
+	UserService      platform.UserService
+
+	// MFAService verifies the codes of users enrolled in multi-factor
+	// authentication. Codes are not required if it is nil.
+	MFAService platform.MFAService
 }
 
 // NewSessionBackend creates a new SessionBackend with associated logger.
@@ -25,6 +33,8 @@ func NewSessionBackend(b *APIBackend) *SessionBackend {
 
 		PasswordsService: b.PasswordsService,
 		SessionService:   b.SessionService,
+		UserService:      b.UserService,
+		MFAService:       b.MFAService,
 	}
 }
 
@@ -35,6 +45,8 @@ type SessionHandler struct {
 
 	PasswordsService platform.PasswordsService
 	SessionService   platform.SessionService
+	UserService      platform.UserService
+	MFAService       platform.MFAService
 }
 
 // NewSessionHandler returns a new instance of SessionHandler.
@@ -45,6 +57,8 @@ func NewSessionHandler(b *SessionBackend) *SessionHandler {
 
 		PasswordsService: b.PasswordsService,
 		SessionService:   b.SessionService,
+		UserService:      b.UserService,
+		MFAService:       b.MFAService,
 	}
 
 	h.HandlerFunc("POST", "/api/v2/signin", h.handleSignin)
@@ -68,19 +82,66 @@ func (h *SessionHandler) handleSignin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.verifyMFA(ctx, req); err != nil {
+		// Clients are told when a code is required or when they are locked
+		// out, but not why a code is invalid.
+		switch platform.ErrorMessage(err) {
+		case platform.ErrMFACodeRequired, platform.ErrMFALocked:
+			EncodeError(ctx, err, w)
+		default:
+			UnauthorizedError(ctx, w)
+		}
+		return
+	}
+
 	s, e := h.SessionService.CreateSession(ctx, req.Username)
 	if e != nil {
 		UnauthorizedError(ctx, w)
 		return
 	}
+	recordSessionClient(ctx, h.Logger, h.SessionService, s, r)
 
 	encodeCookieSession(w, s)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// verifyMFA verifies the multi-factor authentication code of the request if
+// the user is enrolled.
+func (h *SessionHandler) verifyMFA(ctx context.Context, req *signinRequest) error {
+	if h.MFAService == nil {
+		return nil
+	}
+
+	u, err := h.UserService.FindUser(ctx, platform.UserFilter{Name: &req.Username})
+	if err != nil {
+		return err
+	}
+	m, err := h.MFAService.FindMFA(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if !m.Enrolled {
+		return nil
+	}
+
+	if req.Code == "" {
+		return &platform.Error{
+			Code: platform.EUnauthorized,
+			Msg:  platform.ErrMFACodeRequired,
+		}
+	}
+	return h.MFAService.VerifyMFA(ctx, u.ID, req.Code)
+}
+
 type signinRequest struct {
 	Username string
 	Password string
+	Code     string
+}
+
+type signinRequestBody struct {
+	// Code is the multi-factor authentication code of users enrolled.
+	Code string `json:"code"`
 }
 
 func decodeSigninRequest(ctx context.Context, r *http.Request) (*signinRequest, *platform.Error) {
@@ -92,9 +153,18 @@ func decodeSigninRequest(ctx context.Context, r *http.Request) (*signinRequest,
 		}
 	}
 
+	var body signinRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, &platform.Error{
+			Code: platform.EInvalid,
+			Err:  err,
+		}
+	}
+
 	return &signinRequest{
 		Username: u,
 		Password: p,
+		Code:     body.Code,
 	}, nil
 }
 
@@ -161,3 +231,12 @@ func SetCookieSession(key string, r *http.Request) {
 
 	r.AddCookie(c)
 }
+
+// recordSessionClient records the client signing in with the new session s.
+// Failing to record it does not fail signing in.
+func recordSessionClient(ctx context.Context, log *zap.Logger, ss platform.SessionService, s *platform.Session, r *http.Request) {
+	touchSession(s, r, time.Now())
+	if err := ss.RenewSession(ctx, s, s.ExpiresAt); err != nil {
+		log.Warn("Failed to record client of session", zap.String("sessionID", s.ID.String()), zap.Error(err))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -57,6 +58,9 @@ func TestSessionHandler_handleSignin(t *testing.T) {
 							UserID:    platform.ID(1),
 						}, nil
 					},
+					RenewSessionFn: func(context.Context, *platform.Session, time.Time) error {
+						return nil
+					},
 				},
 				PasswordsService: &mock.PasswordsService{
 					ComparePasswordFn: func(context.Context, string, string) error {
@@ -99,3 +103,66 @@ func TestSessionHandler_handleSignin(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionHandler_handleSigninMFA(t *testing.T) {
+	b := NewMockSessionBackend()
+	b.PasswordsService = &mock.PasswordsService{
+		ComparePasswordFn: func(context.Context, string, string) error {
+			return nil
+		},
+	}
+	b.SessionService = &mock.SessionService{
+		CreateSessionFn: func(context.Context, string) (*platform.Session, error) {
+			return &platform.Session{Key: "abc123xyz", UserID: platform.ID(1)}, nil
+		},
+		RenewSessionFn: func(context.Context, *platform.Session, time.Time) error {
+			return nil
+		},
+	}
+	users := mock.NewUserService()
+	users.FindUserFn = func(ctx context.Context, filter platform.UserFilter) (*platform.User, error) {
+		return &platform.User{ID: 1, Name: *filter.Name}, nil
+	}
+	b.UserService = users
+	mfa := mock.NewMFAService()
+	mfa.FindMFAFn = func(ctx context.Context, userID platform.ID) (*platform.MFA, error) {
+		return &platform.MFA{UserID: userID, Enrolled: true}, nil
+	}
+	mfa.VerifyMFAFn = func(ctx context.Context, userID platform.ID, code string) error {
+		if code != "123456" {
+			return &platform.Error{Code: platform.EUnauthorized, Msg: platform.ErrMFAInvalidCode}
+		}
+		return nil
+	}
+	b.MFAService = mfa
+	h := platformhttp.NewSessionHandler(b)
+
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		cookie string
+	}{
+		{name: "code required", code: http.StatusUnauthorized},
+		{name: "invalid code", body: `{"code":"000000"}`, code: http.StatusUnauthorized},
+		{name: "valid code", body: `{"code":"123456"}`, code: http.StatusNoContent, cookie: "session=abc123xyz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "http://localhost:9999/api/v2/signin", strings.NewReader(tt.body))
+			r.SetBasicAuth("user1", "supersecret")
+			h.ServeHTTP(w, r)
+
+			if got, want := w.Code, tt.code; got != want {
+				t.Errorf("bad status code: got %d want %d", got, want)
+			}
+			if got, want := w.Header().Get("Set-Cookie"), tt.cookie; got != want {
+				t.Errorf("unexpected session cookie: got %q want %q", got, want)
+			}
+			if tt.body == "" && !strings.Contains(w.Body.String(), platform.ErrMFACodeRequired) {
+				t.Errorf("expected the response to tell a code is required: %s", w.Body.String())
+			}
+		})
+	}
+}
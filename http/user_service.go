@@ -21,6 +21,14 @@ type UserBackend struct {
 	UserService             influxdb.UserService
 	UserOperationLogService influxdb.UserOperationLogService
 	PasswordsService        influxdb.PasswordsService
+
+	// MFAService manages the multi-factor authentication of users. Its
+	// routes are not served if it is nil.
+	MFAService influxdb.MFAService
+
+	// UserSessionService lists and revokes the sessions of users. Its
+	// routes are not served if it is nil.
+	UserSessionService influxdb.UserSessionService
 }
 
 // NewUserBackend creates a UserBackend using information in the APIBackend.
@@ -40,6 +48,8 @@ type UserHandler struct {
 	UserService             influxdb.UserService
 	UserOperationLogService influxdb.UserOperationLogService
 	PasswordsService        influxdb.PasswordsService
+	MFAService              influxdb.MFAService
+	UserSessionService      influxdb.UserSessionService
 }
 
 const (
@@ -60,6 +70,8 @@ func NewUserHandler(b *UserBackend) *UserHandler {
 		UserService:             b.UserService,
 		UserOperationLogService: b.UserOperationLogService,
 		PasswordsService:        b.PasswordsService,
+		MFAService:              b.MFAService,
+		UserSessionService:      b.UserSessionService,
 	}
 
 	h.HandlerFunc("POST", usersPath, h.handlePostUser)
@@ -73,6 +85,23 @@ func NewUserHandler(b *UserBackend) *UserHandler {
 	h.HandlerFunc("GET", mePath, h.handleGetMe)
 	h.HandlerFunc("PUT", mePasswordPath, h.handlePutUserPassword)
 
+	if h.MFAService != nil {
+		h.HandlerFunc("GET", usersMFAPath, h.handleGetMFA)
+		h.HandlerFunc("DELETE", usersMFAPath, h.handleDeleteUserMFA)
+		h.HandlerFunc("GET", meMFAPath, h.handleGetMFA)
+		h.HandlerFunc("DELETE", meMFAPath, h.handleDeleteMeMFA)
+		h.HandlerFunc("POST", meMFATOTPPath, h.handlePostTOTP)
+		h.HandlerFunc("POST", meMFATOTPConfirmPath, h.handlePostTOTPConfirm)
+		h.HandlerFunc("POST", meMFARecoveryCodesPath, h.handlePostRecoveryCodes)
+	}
+
+	if h.UserSessionService != nil {
+		h.HandlerFunc("GET", usersSessionsPath, h.handleGetSessions)
+		h.HandlerFunc("DELETE", usersSessionsIDPath, h.handleDeleteSession)
+		h.HandlerFunc("GET", meSessionsPath, h.handleGetSessions)
+		h.HandlerFunc("DELETE", meSessionsIDPath, h.handleDeleteSession)
+	}
+
 	return h
 }
 
@@ -757,3 +786,22 @@ func newUserLogResponse(id influxdb.ID, es []*influxdb.OperationLogEntry) *opera
 		Logs: logs,
 	}
 }
+
+// decodeUserOrMeID returns the user of the route, or the user of the
+// authorizer of the request for the /api/v2/me routes.
+func decodeUserOrMeID(ctx context.Context) (influxdb.ID, error) {
+	params := httprouter.ParamsFromContext(ctx)
+	if id := params.ByName("id"); id != "" {
+		var i influxdb.ID
+		if err := i.DecodeFromString(id); err != nil {
+			return 0, err
+		}
+		return i, nil
+	}
+
+	a, err := icontext.GetAuthorizer(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return a.GetUserID(), nil
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	icontext "github.com/influxdata/influxdb/context"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	usersSessionsPath   = "/api/v2/users/:id/sessions"
+	usersSessionsIDPath = "/api/v2/users/:id/sessions/:sessionID"
+	meSessionsPath      = "/api/v2/me/sessions"
+	meSessionsIDPath    = "/api/v2/me/sessions/:sessionID"
+)
+
+// sessionResponse leaves out the key and the permissions of the session.
+type sessionResponse struct {
+	Links      map[string]string `json:"links"`
+	ID         influxdb.ID       `json:"id"`
+	UserID     influxdb.ID       `json:"userID"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	ExpiresAt  time.Time         `json:"expiresAt"`
+	LastSeenAt *time.Time        `json:"lastSeenAt,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	UserAgent  string            `json:"userAgent,omitempty"`
+	// Current is true for the session the request was made with.
+	Current bool `json:"current"`
+}
+
+func newSessionResponse(s *influxdb.Session, current bool) *sessionResponse {
+	return &sessionResponse{
+		Links: map[string]string{
+			"self": fmt.Sprintf("/api/v2/users/%s/sessions/%s", s.UserID, s.ID),
+			"user": fmt.Sprintf("/api/v2/users/%s", s.UserID),
+		},
+		ID:         s.ID,
+		UserID:     s.UserID,
+		CreatedAt:  s.CreatedAt,
+		ExpiresAt:  s.ExpiresAt,
+		LastSeenAt: s.LastSeenAt,
+		IP:         s.IP,
+		UserAgent:  s.UserAgent,
+		Current:    current,
+	}
+}
+
+type sessionsResponse struct {
+	Links    map[string]string  `json:"links"`
+	Sessions []*sessionResponse `json:"sessions"`
+}
+
+// handleGetSessions is the HTTP handler for the GET /api/v2/users/:id/sessions and
+// GET /api/v2/me/sessions routes.
+func (h *UserHandler) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	ss, err := h.UserSessionService.FindUserSessions(ctx, id)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	var current influxdb.ID
+	if a, err := icontext.GetAuthorizer(ctx); err == nil && a.Kind() == influxdb.SessionAuthorizionKind {
+		current = a.Identifier()
+	}
+
+	res := &sessionsResponse{
+		Links: map[string]string{
+			"self": fmt.Sprintf("/api/v2/users/%s/sessions", id),
+			"user": fmt.Sprintf("/api/v2/users/%s", id),
+		},
+		Sessions: make([]*sessionResponse, 0, len(ss)),
+	}
+	for _, s := range ss {
+		res.Sessions = append(res.Sessions, newSessionResponse(s, s.ID == current))
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// handleDeleteSession is the HTTP handler for the DELETE /api/v2/users/:id/sessions/:sessionID
+// and DELETE /api/v2/me/sessions/:sessionID routes.
+func (h *UserHandler) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeUserOrMeID(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	var sessionID influxdb.ID
+	if err := sessionID.DecodeFromString(httprouter.ParamsFromContext(ctx).ByName("sessionID")); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	if err := h.UserSessionService.DeleteUserSession(ctx, id, sessionID); err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap"
+)
+
+func TestUserHandler_Sessions(t *testing.T) {
+	lastSeen := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc := mock.NewUserSessionService()
+	svc.FindUserSessionsFn = func(ctx context.Context, userID platform.ID) ([]*platform.Session, error) {
+		return []*platform.Session{
+			{
+				ID:         10,
+				Key:        "secret-key",
+				UserID:     userID,
+				CreatedAt:  time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC),
+				ExpiresAt:  time.Date(2019, 5, 1, 13, 0, 0, 0, time.UTC),
+				LastSeenAt: &lastSeen,
+				IP:         "192.0.2.1",
+				UserAgent:  "curl/7.64.1",
+			},
+			{
+				ID:        11,
+				Key:       "other-secret-key",
+				UserID:    userID,
+				CreatedAt: time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC),
+				ExpiresAt: time.Date(2019, 5, 1, 1, 0, 0, 0, time.UTC),
+			},
+		}, nil
+	}
+	var deleted platform.ID
+	svc.DeleteUserSessionFn = func(ctx context.Context, userID, sessionID platform.ID) error {
+		if userID != 1 {
+			return &platform.Error{Code: platform.ENotFound, Msg: platform.ErrSessionNotFound}
+		}
+		deleted = sessionID
+		return nil
+	}
+
+	h := NewUserHandler(&UserBackend{
+		Logger:             zap.NewNop(),
+		UserService:        mock.NewUserService(),
+		UserSessionService: svc,
+	})
+	session := &platform.Session{ID: 10, UserID: 1}
+
+	r := httptest.NewRequest("GET", "http://any.url/api/v2/me/sessions", nil)
+	r = r.WithContext(pcontext.SetAuthorizer(r.Context(), session))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	res := w.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 {
+		t.Fatalf("unexpected status code: %d: %s", res.StatusCode, body)
+	}
+	want := `
+{
+  "links": {
+    "self": "/api/v2/users/0000000000000001/sessions",
+    "user": "/api/v2/users/0000000000000001"
+  },
+  "sessions": [
+    {
+      "links": {
+        "self": "/api/v2/users/0000000000000001/sessions/000000000000000a",
+        "user": "/api/v2/users/0000000000000001"
+      },
+      "id": "000000000000000a",
+      "userID": "0000000000000001",
+      "createdAt": "2019-05-01T00:00:00Z",
+      "expiresAt": "2019-05-01T13:00:00Z",
+      "lastSeenAt": "2019-05-01T12:00:00Z",
+      "ip": "192.0.2.1",
+      "userAgent": "curl/7.64.1",
+      "current": true
+    },
+    {
+      "links": {
+        "self": "/api/v2/users/0000000000000001/sessions/000000000000000b",
+        "user": "/api/v2/users/0000000000000001"
+      },
+      "id": "000000000000000b",
+      "userID": "0000000000000001",
+      "createdAt": "2019-05-01T00:00:00Z",
+      "expiresAt": "2019-05-01T01:00:00Z",
+      "current": false
+    }
+  ]
+}
+`
+	if eq, diff, _ := jsonEqual(string(body), want); !eq {
+		t.Errorf("unexpected response body -got/+want\n%s", diff)
+	}
+
+	r = httptest.NewRequest("DELETE", "http://any.url/api/v2/users/0000000000000001/sessions/000000000000000b", nil)
+	r = r.WithContext(pcontext.SetAuthorizer(r.Context(), session))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if res := w.Result(); res.StatusCode != 204 {
+		body, _ := ioutil.ReadAll(res.Body)
+		t.Fatalf("unexpected status code revoking session: %d: %s", res.StatusCode, body)
+	}
+	if deleted != 11 {
+		t.Errorf("expected session 000000000000000b to be revoked, got %s", deleted)
+	}
+
+	r = httptest.NewRequest("DELETE", "http://any.url/api/v2/users/0000000000000002/sessions/000000000000000b", nil)
+	r = r.WithContext(pcontext.SetAuthorizer(r.Context(), session))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if res := w.Result(); res.StatusCode != 404 {
+		t.Fatalf("expected session of another user not to be found, got %d", res.StatusCode)
+	}
+}
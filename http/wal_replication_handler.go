@@ -0,0 +1,185 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.uber.org/zap"
+
+	platform "github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/storage"
+)
+
+// WALReplicationBackend is all services and associated parameters required to
+// construct the WALReplicationHandler.
+type WALReplicationBackend struct {
+	Logger *zap.Logger
+
+	// MaxBatchSize is the maximum size in bytes of a batch of entries. If zero,
+	// DefaultMaxWALBatchSize is used.
+	MaxBatchSize int64
+
+	WALApplier storage.WALApplier
+}
+
+// DefaultMaxWALBatchSize is the default maximum size in bytes of a batch of
+// replicated WAL entries. Senders make batches of a few megabytes, which may be
+// exceeded by the size of the last entry in a batch.
+const DefaultMaxWALBatchSize = 64 * 1024 * 1024
+
+// NewWALReplicationBackend returns a new instance of WALReplicationBackend.
+func NewWALReplicationBackend(b *APIBackend) *WALReplicationBackend {
+	return &WALReplicationBackend{
+		Logger: b.Logger.With(zap.String("handler", "wal_replication")),
+
+		MaxBatchSize: b.MaxWALBatchSize,
+
+		WALApplier: b.WALApplier,
+	}
+}
+
+// WALReplicationHandler receives WAL entries replicated from another server.
+type WALReplicationHandler struct {
+	*httprouter.Router
+
+	Logger *zap.Logger
+
+	MaxBatchSize int64
+
+	WALApplier storage.WALApplier
+}
+
+const (
+	replicationWALPath = "/api/v2/replication/wal"
+)
+
+// NewWALReplicationHandler creates a new handler at /api/v2/replication/wal to
+// apply replicated WAL entries.
+func NewWALReplicationHandler(b *WALReplicationBackend) *WALReplicationHandler {
+	h := &WALReplicationHandler{
+		Router: NewRouter(),
+		Logger: b.Logger,
+
+		MaxBatchSize: b.MaxBatchSize,
+
+		WALApplier: b.WALApplier,
+	}
+
+	h.HandlerFunc("POST", replicationWALPath, h.handleReplicateWAL)
+	return h
+}
+
+func (h *WALReplicationHandler) handleReplicateWAL(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "WALReplicationHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	defer r.Body.Close()
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		EncodeError(ctx, err, w)
+		return
+	}
+
+	// WAL entries may belong to any bucket in any organization.
+	p, err := platform.NewGlobalPermission(platform.WriteAction, platform.BucketsResourceType)
+	if err != nil {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EInternal,
+			Op:   "http/handleReplicateWAL",
+			Msg:  fmt.Sprintf("unable to create permission: %v", err),
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if !a.Allowed(*p) {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EForbidden,
+			Op:   "http/handleReplicateWAL",
+			Msg:  "insufficient permissions for WAL replication",
+		}, w)
+		return
+	}
+
+	if h.WALApplier == nil {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.ENotFound,
+			Op:   "http/handleReplicateWAL",
+			Msg:  "WAL replication is not supported",
+		}, w)
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBatchSize()))
+	if err != nil {
+		EncodeError(ctx, &platform.Error{
+			Code: platform.EInvalid,
+			Op:   "http/handleReplicateWAL",
+			Msg:  fmt.Sprintf("unable to read data: %v", err),
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.WALApplier.ApplyWAL(ctx, data); err != nil {
+		h.Logger.Error("Error applying replicated WAL entries", zap.Error(err))
+		EncodeError(ctx, &platform.Error{
+			Code: platform.ErrorCode(err),
+			Op:   "http/handleReplicateWAL",
+			Msg:  fmt.Sprintf("unable to apply WAL entries: %v", err),
+			Err:  err,
+		}, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WALReplicationHandler) maxBatchSize() int64 {
+	if h.MaxBatchSize > 0 {
+		return h.MaxBatchSize
+	}
+	return DefaultMaxWALBatchSize
+}
+
+// WALReplicationService sends WAL entries to be applied by a remote server.
+type WALReplicationService struct {
+	Addr               string
+	Token              string
+	InsecureSkipVerify bool
+}
+
+// ReplicateWAL sends WAL entries, encoded as they are in a WAL segment.
+func (s *WALReplicationService) ReplicateWAL(ctx context.Context, data []byte) error {
+	u, err := newURL(s.Addr, replicationWALPath)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	SetToken(s.Token, req)
+
+	hc := newClient(u.Scheme, s.InsecureSkipVerify)
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return CheckError(resp)
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"go.uber.org/zap"
+)
+
+type walApplierFunc func(ctx context.Context, data []byte) error
+
+func (fn walApplierFunc) ApplyWAL(ctx context.Context, data []byte) error {
+	return fn(ctx, data)
+}
+
+func TestWALReplicationHandler_handleReplicateWAL(t *testing.T) {
+	write, err := platform.NewGlobalPermission(platform.WriteAction, platform.BucketsResourceType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucketWrite, err := platform.NewPermissionAtID(platform.ID(2), platform.WriteAction, platform.BucketsResourceType, platform.ID(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		permissions []platform.Permission
+		maxSize     int64
+		applyErr    error
+		wantErr     string
+		applied     bool
+	}{
+		{
+			name:        "apply entries",
+			permissions: []platform.Permission{*write},
+			applied:     true,
+		},
+		{
+			name:        "invalid entries",
+			permissions: []platform.Permission{*write},
+			applyErr:    &platform.Error{Code: platform.EInvalid, Msg: "invalid WAL entry"},
+			wantErr:     platform.EInvalid,
+			applied:     true,
+		},
+		{
+			name:        "batch too large",
+			permissions: []platform.Permission{*write},
+			maxSize:     4,
+			wantErr:     platform.EInvalid,
+		},
+		{
+			name:        "insufficient permissions",
+			permissions: []platform.Permission{*bucketWrite},
+			wantErr:     platform.EForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []byte
+			h := NewWALReplicationHandler(&WALReplicationBackend{
+				Logger:       zap.NewNop(),
+				MaxBatchSize: tt.maxSize,
+				WALApplier: walApplierFunc(func(ctx context.Context, data []byte) error {
+					got = data
+					return tt.applyErr
+				}),
+			})
+
+			auth := &platform.Authorization{Status: platform.Active, Permissions: tt.permissions}
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.ServeHTTP(w, r.WithContext(pcontext.SetAuthorizer(r.Context(), auth)))
+			}))
+			defer s.Close()
+
+			data := []byte("entries")
+			err := (&WALReplicationService{Addr: s.URL}).ReplicateWAL(context.Background(), data)
+			if code := platform.ErrorCode(err); err != nil && code != tt.wantErr || err == nil && tt.wantErr != "" {
+				t.Fatalf("unexpected error: got %v, exp code %q", err, tt.wantErr)
+			}
+			if tt.applied != (got != nil) || got != nil && !bytes.Equal(got, data) {
+				t.Fatalf("unexpected entries applied: %q", got)
+			}
+		})
+	}
+}
@@ -3,13 +3,19 @@ package http
 import (
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	platform "github.com/influxdata/influxdb"
@@ -28,6 +34,20 @@ type WriteBackend struct {
 	PointsWriter        storage.PointsWriter
 	BucketService       platform.BucketService
 	OrganizationService platform.OrganizationService
+
+	// MaxBodySize is the maximum size in bytes of the body of a write, after
+	// it is decompressed. MaxPoints is the maximum number of points in a write.
+	// Neither is limited if zero.
+	MaxBodySize int64
+	MaxPoints   int
+
+	// WriteBatchSize is the number of points of a write that are written to
+	// storage at once. If zero, DefaultWriteBatchSize is used.
+	WriteBatchSize int
+
+	// MaxFuture is how far in the future the time of a point may be. Points
+	// further in the future are rejected. It is not limited if zero.
+	MaxFuture time.Duration
 }
 
 // NewWriteBackend returns a new instance of WriteBackend.
@@ -38,6 +58,11 @@ func NewWriteBackend(b *APIBackend) *WriteBackend {
 		PointsWriter:        b.PointsWriter,
 		BucketService:       b.BucketService,
 		OrganizationService: b.OrganizationService,
+
+		MaxBodySize:    b.MaxWriteBodySize,
+		MaxPoints:      b.MaxWritePoints,
+		WriteBatchSize: b.WriteBatchSize,
+		MaxFuture:      b.MaxWriteFuture,
 	}
 }
 
@@ -51,8 +76,36 @@ type WriteHandler struct {
 	OrganizationService platform.OrganizationService
 
 	PointsWriter storage.PointsWriter
+
+	MaxBodySize    int64
+	MaxPoints      int
+	WriteBatchSize int
+	MaxFuture      time.Duration
+
+	now     func() time.Time
+	metrics *writeMetrics
 }
 
+// DefaultWriteBatchSize is the default number of points collected from a write
+// before they are written. A write is parsed completely before any of its
+// points are written, so that it writes nothing if a line cannot be parsed; the
+// memory it uses is bounded by MaxBodySize and MaxPoints, which is why the
+// server limits both by default. Partial writes are instead parsed and written
+// in batches as the body is read, so the memory they use does not depend on
+// their size, and a client sending data faster than it can be written is
+// slowed down.
+const DefaultWriteBatchSize = 5000
+
+const (
+	// DefaultMaxWriteBodySize is the default maximum size in bytes of the
+	// body of a write, after decompression.
+	DefaultMaxWriteBodySize = 25000000
+
+	// DefaultMaxWritePoints is the default maximum number of points in a
+	// write.
+	DefaultMaxWritePoints = 1000000
+)
+
 const (
 	writePath            = "/api/v2/write"
 	errInvalidGzipHeader = "gzipped HTTP body contains an invalid header"
@@ -68,6 +121,14 @@ func NewWriteHandler(b *WriteBackend) *WriteHandler {
 		PointsWriter:        b.PointsWriter,
 		BucketService:       b.BucketService,
 		OrganizationService: b.OrganizationService,
+
+		MaxBodySize:    b.MaxBodySize,
+		MaxPoints:      b.MaxPoints,
+		WriteBatchSize: b.WriteBatchSize,
+		MaxFuture:      b.MaxFuture,
+
+		now:     time.Now,
+		metrics: newWriteMetrics(),
 	}
 
 	h.HandlerFunc("POST", writePath, h.handleWrite)
@@ -111,57 +172,20 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 
 	logger := h.Logger.With(zap.String("org", req.Org), zap.String("bucket", req.Bucket))
 
-	var org *platform.Organization
-	if id, err := platform.IDFromString(req.Org); err == nil {
-		// Decoded ID successfully. Make sure it's a real org.
-		o, err := h.OrganizationService.FindOrganizationByID(ctx, *id)
-		if err == nil {
-			org = o
-		} else if platform.ErrorCode(err) != platform.ENotFound {
-			EncodeError(ctx, err, w)
-			return
-		}
-	}
-	if org == nil {
-		o, err := h.OrganizationService.FindOrganization(ctx, platform.OrganizationFilter{Name: &req.Org})
-		if err != nil {
-			logger.Info("Failed to find organization", zap.Error(err))
-			EncodeError(ctx, err, w)
-			return
-		}
-
-		org = o
-	}
-
-	var bucket *platform.Bucket
-	if id, err := platform.IDFromString(req.Bucket); err == nil {
-		// Decoded ID successfully. Make sure it's a real bucket.
-		b, err := h.BucketService.FindBucket(ctx, platform.BucketFilter{
-			OrganizationID: &org.ID,
-			ID:             id,
-		})
-		if err == nil {
-			bucket = b
-		} else if platform.ErrorCode(err) != platform.ENotFound {
-			EncodeError(ctx, err, w)
-			return
-		}
+	org, err := findOrganization(ctx, h.OrganizationService, req.Org)
+	if err != nil {
+		logger.Info("Failed to find organization", zap.Error(err))
+		EncodeError(ctx, err, w)
+		return
 	}
 
-	if bucket == nil {
-		b, err := h.BucketService.FindBucket(ctx, platform.BucketFilter{
-			OrganizationID: &org.ID,
-			Name:           &req.Bucket,
-		})
-		if err != nil {
-			EncodeError(ctx, &platform.Error{
-				Op:  "http/handleWrite",
-				Err: err,
-			}, w)
-			return
-		}
-
-		bucket = b
+	bucket, err := findBucket(ctx, h.BucketService, org.ID, req.Bucket)
+	if err != nil {
+		EncodeError(ctx, &platform.Error{
+			Op:  "http/handleWrite",
+			Err: err,
+		}, w)
+		return
 	}
 
 	p, err := platform.NewPermissionAtID(bucket.ID, platform.WriteAction, platform.BucketsResourceType, org.ID)
@@ -184,57 +208,529 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO(jeff): we should be publishing with the org and bucket instead of
-	// parsing, rewriting, and publishing, but the interface isn't quite there yet.
-	// be sure to remove this when it is there!
-	data, err := ioutil.ReadAll(in)
+	records, err := newRecordReader(r.Header.Get("Content-Type"), in, h.MaxBodySize, req.Precision)
 	if err != nil {
-		logger.Error("Error reading body", zap.Error(err))
-		EncodeError(ctx, &platform.Error{
-			Code: platform.EInternal,
-			Op:   "http/handleWrite",
-			Msg:  fmt.Sprintf("unable to read data: %v", err),
-			Err:  err,
-		}, w)
+		EncodeError(ctx, err, w)
 		return
 	}
 
-	points, err := models.ParsePointsWithPrecision(data, time.Now(), req.Precision)
-	if err != nil {
-		logger.Error("Error parsing points", zap.Error(err))
+	// Default tags of the request take precedence over those of the bucket.
+	defaults := make(map[string]string, len(bucket.DefaultTags)+len(req.DefaultTags))
+	for k, v := range bucket.DefaultTags {
+		defaults[k] = v
+	}
+	for k, v := range req.DefaultTags {
+		defaults[k] = v
+	}
+	defaultTags := models.NewTags(defaults)
+
+	bounds := h.timeBounds(bucket)
+
+	if req.Partial {
+		h.writePartial(w, r, logger, org.ID, bucket.ID, defaultTags, bounds, records)
+		return
+	}
+
+	// The whole body is parsed before any point is written, so that a write
+	// with a line that cannot be parsed writes nothing. The points are then
+	// written in batches.
+	var (
+		batches  [][]models.Point
+		lines    []int // the number of points in each batch before they were exploded
+		batch    []models.Point
+		pending  int // the number of points in batch before they were exploded
+		points   int
+		dropped  *tsdb.PartialWriteError
+		noWrites = func(err *platform.Error) *platform.Error { return err }
+	)
+
+	for {
+		chunk, err := records.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			h.encodeReadError(w, r, logger, err, noWrites)
+			return
+		}
+
+		pts, err := chunkPoints(chunk)
+		if err != nil {
+			logger.Error("Error parsing points", zap.Error(err))
+			EncodeError(ctx, &platform.Error{
+				Code: platform.EInvalid,
+				Op:   "http/handleWrite",
+				Msg:  fmt.Sprintf("unable to parse points: %v", err),
+				Err:  err,
+			}, w)
+			return
+		}
+
+		if h.MaxPoints > 0 && points+pending+len(pts) > h.MaxPoints {
+			EncodeError(ctx, &platform.Error{
+				Code: platform.EInvalid,
+				Op:   "http/handleWrite",
+				Msg:  fmt.Sprintf("request exceeds the maximum of %d points", h.MaxPoints),
+			}, w)
+			return
+		}
+
+		for _, pt := range pts {
+			if reason, label := bounds.check(pt); reason != "" {
+				// Points outside the bounds are dropped like those the engine drops.
+				h.metrics.pointsRejected.WithLabelValues(org.ID.String(), bucket.ID.String(), label).Inc()
+				if dropped == nil {
+					dropped = &tsdb.PartialWriteError{Reason: reason}
+				}
+				dropped.Dropped++
+				dropped.DroppedKeys = append(dropped.DroppedKeys, pt.Key())
+				dropped.DroppedReasons = append(dropped.DroppedReasons, reason)
+				continue
+			}
+
+			addDefaultTags(pt, defaultTags)
+			exploded, err := tsdb.ExplodePoints(org.ID, bucket.ID, []models.Point{pt})
+			if err != nil {
+				logger.Error("Error exploding points", zap.Error(err))
+				EncodeError(ctx, &platform.Error{
+					Code: platform.EInternal,
+					Op:   "http/handleWrite",
+					Msg:  fmt.Sprintf("unable to convert points to internal structures: %v", err),
+					Err:  err,
+				}, w)
+				return
+			}
+			batch, pending = append(batch, exploded...), pending+1
+
+			if len(batch) >= h.batchSize() {
+				batches, lines = append(batches, batch), append(lines, pending)
+				points += pending
+				batch, pending = nil, 0
+			}
+		}
+	}
+	if len(batch) > 0 {
+		batches, lines = append(batches, batch), append(lines, pending)
+	}
+
+	// Only the storage failing part way through leaves earlier batches written.
+	written := 0
+	writeErr := func(err *platform.Error) *platform.Error {
+		if written > 0 {
+			err.Msg = fmt.Sprintf("%s; %d points were written before the error", err.Msg, written)
+		}
+		return err
+	}
+	for i, batch := range batches {
+		err := h.PointsWriter.WritePoints(ctx, batch)
+		if perr, ok := err.(tsdb.PartialWriteError); ok {
+			// Keep writing, and report the points dropped from all batches.
+			if dropped == nil {
+				dropped = &tsdb.PartialWriteError{Reason: perr.Reason}
+			}
+			dropped.Dropped += perr.Dropped
+			dropped.DroppedKeys = append(dropped.DroppedKeys, perr.DroppedKeys...)
+			dropped.DroppedReasons = append(dropped.DroppedReasons, perr.DroppedReasons...)
+		} else if err != nil {
+			h.encodeWriteError(w, r, logger, err, writeErr)
+			return
+		}
+		written += lines[i]
+	}
+
+	if dropped != nil {
+		logger.Info("Points dropped from write", zap.Error(*dropped))
 		EncodeError(ctx, &platform.Error{
 			Code: platform.EInvalid,
 			Op:   "http/handleWrite",
-			Msg:  fmt.Sprintf("unable to parse points: %v", err),
-			Err:  err,
+			Msg:  dropped.Error(),
+			Err:  *dropped,
 		}, w)
 		return
 	}
 
-	exploded, err := tsdb.ExplodePoints(org.ID, bucket.ID, points)
-	if err != nil {
-		logger.Error("Error exploding points", zap.Error(err))
-		EncodeError(ctx, &platform.Error{
-			Code: platform.EInternal,
-			Op:   "http/handleWrite",
-			Msg:  fmt.Sprintf("unable to convert points to internal structures: %v", err),
-			Err:  err,
-		}, w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// timeBounds is the range of times of the points a write accepts.
+type timeBounds struct {
+	min, max  int64
+	retention time.Duration
+	future    time.Duration
+}
+
+const (
+	rejectReasonRetention = "retention"
+	rejectReasonFuture    = "future"
+)
+
+// timeBounds returns the range of times of the points accepted by a bucket:
+// those within its retention period, and not beyond the maximum future time.
+func (h *WriteHandler) timeBounds(bucket *platform.Bucket) timeBounds {
+	now := h.now()
+	b := timeBounds{
+		min:       models.MinNanoTime,
+		max:       models.MaxNanoTime,
+		retention: bucket.RetentionPeriod,
+		future:    h.MaxFuture,
+	}
+	if b.retention > 0 {
+		b.min = now.Add(-b.retention).UnixNano()
+	}
+	if b.future > 0 {
+		b.max = now.Add(b.future).UnixNano()
+	}
+	return b
+}
+
+// check returns why a point is outside the bounds and the label of the reason
+// in metrics, or empty strings if it is within them.
+func (b timeBounds) check(pt models.Point) (reason, label string) {
+	switch t := pt.UnixNano(); {
+	case t < b.min:
+		return fmt.Sprintf("point time %s is outside the retention period of %s", pt.Time().UTC().Format(time.RFC3339Nano), b.retention), rejectReasonRetention
+	case t > b.max:
+		return fmt.Sprintf("point time %s is more than %s in the future", pt.Time().UTC().Format(time.RFC3339Nano), b.future), rejectReasonFuture
+	}
+	return "", ""
+}
+
+// addDefaultTags adds the tags the point does not already have to it.
+func addDefaultTags(pt models.Point, tags models.Tags) {
+	for _, t := range tags {
+		if !pt.HasTag(t.Key) {
+			pt.AddTag(string(t.Key), string(t.Value))
+		}
+	}
+}
+
+// chunkPoints returns the points of the records, or an error listing the records
+// that could not be converted.
+func chunkPoints(records []writeRecord) ([]models.Point, error) {
+	points := make([]models.Point, 0, len(records))
+	var failed []string
+	for _, rec := range records {
+		if rec.err == nil {
+			points = append(points, rec.point)
+		} else if len(rec.text) > 0 {
+			failed = append(failed, fmt.Sprintf("unable to parse '%s': %v", rec.text, rec.err))
+		} else {
+			failed = append(failed, fmt.Sprintf("unable to parse record %d: %v", rec.line, rec.err))
+		}
+	}
+	if len(failed) > 0 {
+		return nil, errors.New(strings.Join(failed, "\n"))
+	}
+	return points, nil
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (h *WriteHandler) PrometheusCollectors() []prometheus.Collector {
+	return h.metrics.PrometheusCollectors()
+}
+
+// batchSize returns the number of points to collect before writing them.
+func (h *WriteHandler) batchSize() int {
+	if h.WriteBatchSize > 0 {
+		return h.WriteBatchSize
+	}
+	return DefaultWriteBatchSize
+}
+
+// encodeReadError responds with the error reading the body of a write.
+func (h *WriteHandler) encodeReadError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, err error, wrap func(*platform.Error) *platform.Error) {
+	ctx := r.Context()
+	if err == errBodyTooLarge {
+		res := &lineProtocolLengthError{
+			Code:      platform.EInvalid,
+			Message:   wrap(&platform.Error{Msg: fmt.Sprintf("request body exceeds the maximum size of %d bytes", h.MaxBodySize)}).Msg,
+			MaxLength: h.MaxBodySize,
+		}
+		w.Header().Set(PlatformErrorCodeHeader, res.Code)
+		if err := encodeResponse(ctx, w, http.StatusRequestEntityTooLarge, res); err != nil {
+			logEncodingError(logger, r, err)
+		}
 		return
 	}
 
-	if err := h.PointsWriter.WritePoints(ctx, exploded); err != nil {
-		logger.Error("Error writing points", zap.Error(err))
-		EncodeError(ctx, &platform.Error{
-			Code: platform.EInternal,
-			Op:   "http/handleWrite",
-			Msg:  fmt.Sprintf("unable to write points to database: %v", err),
-			Err:  err,
-		}, w)
+	logger.Error("Error reading body", zap.Error(err))
+	EncodeError(ctx, wrap(&platform.Error{
+		Code: platform.ErrorCode(err),
+		Op:   "http/handleWrite",
+		Msg:  fmt.Sprintf("unable to read data: %v", err),
+		Err:  err,
+	}), w)
+}
+
+// encodeWriteError responds with the error writing a batch of points.
+func (h *WriteHandler) encodeWriteError(w http.ResponseWriter, r *http.Request, logger *zap.Logger, err error, wrap func(*platform.Error) *platform.Error) {
+	logger.Error("Error writing points", zap.Error(err))
+	EncodeError(r.Context(), wrap(&platform.Error{
+		Code: platform.EInternal,
+		Op:   "http/handleWrite",
+		Msg:  fmt.Sprintf("unable to write points to database: %v", err),
+		Err:  err,
+	}), w)
+}
+
+// lineProtocolLengthError is the response to a write with a body that is too large.
+type lineProtocolLengthError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	MaxLength int64  `json:"maxLength"`
+}
+
+// writePartial writes the valid lines read from lines and responds with the lines
+// that were rejected and why. Lines are rejected if they cannot be parsed, if
+// their time is outside bounds, or if any of their fields are dropped by the
+// storage engine.
+func (h *WriteHandler) writePartial(w http.ResponseWriter, r *http.Request, logger *zap.Logger, orgID, bucketID platform.ID, defaultTags models.Tags, bounds timeBounds, records recordReader) {
+	ctx := r.Context()
+
+	var (
+		parsed   []rejectedLine // lines in the batch, without a reason
+		owners   []int          // the index in parsed of the line of each point in the batch
+		batch    []models.Point
+		written  int
+		rejected rejectedLines
+		writeErr = func(err *platform.Error) *platform.Error {
+			if written > 0 {
+				err.Msg = fmt.Sprintf("%s; %d lines were written before the error", err.Msg, written)
+			}
+			return err
+		}
+	)
+
+	// The engine reports dropped points by series key, so every line in the batch
+	// with a point in a dropped series is rejected.
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		dropped := make(map[int]string)
+		if err := h.PointsWriter.WritePoints(ctx, batch); err != nil {
+			perr, ok := err.(tsdb.PartialWriteError)
+			if !ok {
+				return err
+			}
+			logger.Info("Points dropped from write", zap.Error(err))
+
+			reasons := make(map[string]string, len(perr.DroppedKeys))
+			for i, key := range perr.DroppedKeys {
+				reasons[string(key)] = perr.Reason
+				if i < len(perr.DroppedReasons) {
+					reasons[string(key)] = perr.DroppedReasons[i]
+				}
+			}
+			for i, pt := range batch {
+				if reason, ok := reasons[string(pt.Key())]; ok {
+					if _, ok := dropped[owners[i]]; !ok {
+						dropped[owners[i]] = reason
+					}
+				}
+			}
+		}
+
+		for i, reason := range dropped {
+			line := parsed[i]
+			line.Reason = reason
+			rejected.add(line)
+		}
+		written += len(parsed) - len(dropped)
+		parsed, owners, batch = nil, nil, nil
+		return nil
+	}
+
+	for {
+		chunk, err := records.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			h.encodeReadError(w, r, logger, err, writeErr)
+			return
+		}
+
+		var (
+			tooMany  bool
+			flushErr error
+		)
+		for _, rec := range chunk {
+			err := rec.err
+			if err == nil && h.MaxPoints > 0 && written+len(parsed) >= h.MaxPoints {
+				tooMany = true
+				break
+			}
+			if err == nil {
+				if reason, label := bounds.check(rec.point); reason != "" {
+					h.metrics.pointsRejected.WithLabelValues(orgID.String(), bucketID.String(), label).Inc()
+					rejected.add(rejectedLine{Line: rec.line, Text: rejectedText(rec.text), Reason: reason})
+					continue
+				}
+
+				addDefaultTags(rec.point, defaultTags)
+
+				var pts []models.Point
+				if pts, err = tsdb.ExplodePoints(orgID, bucketID, []models.Point{rec.point}); err == nil {
+					for range pts {
+						owners = append(owners, len(parsed))
+					}
+					parsed = append(parsed, rejectedLine{Line: rec.line, Text: rejectedText(rec.text)})
+					batch = append(batch, pts...)
+
+					if len(batch) >= h.batchSize() {
+						if flushErr = flush(); flushErr != nil {
+							break
+						}
+					}
+					continue
+				}
+			}
+			rejected.add(rejectedLine{Line: rec.line, Text: rejectedText(rec.text), Reason: err.Error()})
+		}
+		if tooMany {
+			EncodeError(ctx, writeErr(&platform.Error{
+				Code: platform.EInvalid,
+				Op:   "http/handleWrite",
+				Msg:  fmt.Sprintf("request exceeds the maximum of %d points", h.MaxPoints),
+			}), w)
+			return
+		} else if flushErr != nil {
+			h.encodeWriteError(w, r, logger, flushErr, writeErr)
+			return
+		}
+	}
+
+	if err := flush(); err != nil {
+		h.encodeWriteError(w, r, logger, err, writeErr)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if rejected.count == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	rejected.truncate()
+
+	// Only respond with an error if none of the lines were written.
+	res := &partialWriteResponse{
+		Written:       written,
+		Rejected:      rejected.lines,
+		RejectedCount: rejected.count,
+	}
+	code := http.StatusOK
+	if res.Written == 0 {
+		code = http.StatusBadRequest
+	}
+
+	if err := encodeResponse(ctx, w, code, res); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+// partialWriteResponse is the response to a partial write that rejected lines.
+// Only the first maxRejectedLines rejected lines are listed.
+type partialWriteResponse struct {
+	Written       int            `json:"written"`
+	Rejected      []rejectedLine `json:"rejected"`
+	RejectedCount int            `json:"rejectedCount"`
+}
+
+const (
+	// maxRejectedLines is the maximum number of rejected lines listed in the
+	// response to a partial write.
+	maxRejectedLines = 100
+
+	// maxRejectedTextSize is the maximum size in bytes of the text and of the
+	// reason of a rejected line in the response. Longer ones are cut short.
+	maxRejectedTextSize = 1024
+)
+
+// rejectedLines are the first lines rejected by a partial write, ordered by line,
+// so that the response to a large bad write stays small.
+type rejectedLines struct {
+	lines []rejectedLine
+	count int
+}
+
+// add records a rejected line, cutting its reason short if needed.
+func (a *rejectedLines) add(line rejectedLine) {
+	a.count++
+	line.Reason = truncateRejected(line.Reason)
+	a.lines = append(a.lines, line)
+	if len(a.lines) >= 2*maxRejectedLines {
+		a.truncate()
+	}
+}
+
+// truncate sorts the lines and keeps the first maxRejectedLines of them.
+func (a *rejectedLines) truncate() {
+	sort.Slice(a.lines, func(i, j int) bool { return a.lines[i].Line < a.lines[j].Line })
+	if len(a.lines) > maxRejectedLines {
+		a.lines = a.lines[:maxRejectedLines]
+	}
+}
+
+// rejectedText returns the text of a rejected line, cut short if needed.
+func rejectedText(text []byte) string {
+	if len(text) > maxRejectedTextSize {
+		text = text[:maxRejectedTextSize+1]
+	}
+	return truncateRejected(string(text))
+}
+
+// truncateRejected cuts s short at a rune boundary if it is longer than
+// maxRejectedTextSize.
+func truncateRejected(s string) string {
+	if len(s) <= maxRejectedTextSize {
+		return s
+	}
+	i := maxRejectedTextSize
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return s[:i] + "..."
+}
+
+// rejectedLine is a line of line protocol that was not written.
+type rejectedLine struct {
+	Line   int    `json:"line"`
+	Text   string `json:"text"`
+	Reason string `json:"reason"`
+}
+
+// findOrganization returns the organization with the ID or name org.
+func findOrganization(ctx context.Context, svc platform.OrganizationService, org string) (*platform.Organization, error) {
+	if id, err := platform.IDFromString(org); err == nil {
+		// Decoded ID successfully. Make sure it's a real org.
+		o, err := svc.FindOrganizationByID(ctx, *id)
+		if err == nil {
+			return o, nil
+		} else if platform.ErrorCode(err) != platform.ENotFound {
+			return nil, err
+		}
+	}
+	return svc.FindOrganization(ctx, platform.OrganizationFilter{Name: &org})
+}
+
+// findBucket returns the bucket with the ID or name bucket in the organization.
+func findBucket(ctx context.Context, svc platform.BucketService, orgID platform.ID, bucket string) (*platform.Bucket, error) {
+	if id, err := platform.IDFromString(bucket); err == nil {
+		// Decoded ID successfully. Make sure it's a real bucket.
+		b, err := svc.FindBucket(ctx, platform.BucketFilter{
+			OrganizationID: &orgID,
+			ID:             id,
+		})
+		if err == nil {
+			return b, nil
+		} else if platform.ErrorCode(err) != platform.ENotFound {
+			return nil, err
+		}
+	}
+	return svc.FindBucket(ctx, platform.BucketFilter{
+		OrganizationID: &orgID,
+		Name:           &bucket,
+	})
 }
 
 func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest, error) {
@@ -252,17 +748,101 @@ func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest
 		}
 	}
 
+	var partial bool
+	if v := qp.Get("partial"); v != "" {
+		var err error
+		if partial, err = strconv.ParseBool(v); err != nil {
+			return nil, &platform.Error{
+				Code: platform.EInvalid,
+				Op:   "http/decodeWriteRequest",
+				Msg:  fmt.Sprintf("invalid partial value %q: expected true or false", v),
+			}
+		}
+	}
+
+	tags, err := decodeDefaultTags(r.Header.Get(defaultTagsHeader), qp["tag"])
+	if err != nil {
+		return nil, err
+	}
+
 	return &postWriteRequest{
-		Bucket:    qp.Get("bucket"),
-		Org:       qp.Get("org"),
-		Precision: p,
+		Bucket:      qp.Get("bucket"),
+		Org:         qp.Get("org"),
+		Precision:   p,
+		Partial:     partial,
+		DefaultTags: tags,
 	}, nil
 }
 
+// defaultTagsHeader is the header of a write request listing default tags.
+const defaultTagsHeader = "Influx-Default-Tags"
+
+// decodeDefaultTags returns the default tags of a write request, from the
+// comma separated key=value pairs of the header and the key=value query
+// parameters. Keys and values in the header may be percent-encoded, and the
+// query parameters take precedence.
+func decodeDefaultTags(header string, params []string) (map[string]string, error) {
+	var pairs []string
+	if header != "" {
+		for _, pair := range strings.Split(header, ",") {
+			k, v := splitDefaultTag(strings.TrimSpace(pair))
+			var err error
+			if k, err = url.PathUnescape(k); err == nil {
+				v, err = url.PathUnescape(v)
+			}
+			if err != nil {
+				return nil, &platform.Error{
+					Code: platform.EInvalid,
+					Op:   "http/decodeWriteRequest",
+					Msg:  fmt.Sprintf("invalid default tag %q: %v", pair, err),
+				}
+			}
+			pairs = append(pairs, k, v)
+		}
+	}
+	for _, param := range params {
+		k, v := splitDefaultTag(param)
+		pairs = append(pairs, k, v)
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	tags := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		tags[pairs[i]] = pairs[i+1]
+	}
+	if err := platform.ValidateDefaultTags(tags); err != nil {
+		return nil, &platform.Error{
+			Code: platform.EInvalid,
+			Op:   "http/decodeWriteRequest",
+			Msg:  fmt.Sprintf("invalid default tags: %s", platform.ErrorMessage(err)),
+			Err:  err,
+		}
+	}
+	return tags, nil
+}
+
+// splitDefaultTag splits key=value at the first equals sign.
+func splitDefaultTag(s string) (key, value string) {
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
 type postWriteRequest struct {
 	Org       string
 	Bucket    string
 	Precision string
+
+	// Partial writes the valid lines of a request and reports the rest,
+	// instead of rejecting the request if any line cannot be parsed.
+	Partial bool
+
+	// DefaultTags are added to the points that do not have tags with the
+	// same keys.
+	DefaultTags map[string]string
 }
 
 // WriteService sends data over HTTP to influxdb via line protocol.
@@ -271,6 +851,10 @@ type WriteService struct {
 	Token              string
 	Precision          string
 	InsecureSkipVerify bool
+
+	// DefaultTags are added to the points written that do not have tags with
+	// the same keys.
+	DefaultTags map[string]string
 }
 
 var _ platform.WriteService = (*WriteService)(nil)
@@ -303,6 +887,7 @@ func (s *WriteService) Write(ctx context.Context, orgID, bucketID platform.ID, r
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctx)
 
 	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
 	req.Header.Set("Content-Encoding", "gzip")
@@ -322,6 +907,9 @@ func (s *WriteService) Write(ctx context.Context, orgID, bucketID platform.ID, r
 	params.Set("org", string(org))
 	params.Set("bucket", string(bucket))
 	params.Set("precision", string(precision))
+	for _, t := range models.NewTags(s.DefaultTags) {
+		params.Add("tag", string(t.Key)+"="+string(t.Value))
+	}
 	req.URL.RawQuery = params.Encode()
 
 	hc := newClient(u.Scheme, s.InsecureSkipVerify)
@@ -7,10 +7,21 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	platform "github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/prom"
+	"github.com/influxdata/influxdb/kit/prom/promtest"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
 )
 
 func TestWriteService_Write(t *testing.T) {
@@ -69,3 +80,634 @@ func TestWriteService_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteHandler_handleWrite_PartialWrite(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	p, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orgSvc := mock.NewOrganizationService()
+	orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+		return &platform.Organization{ID: id}, nil
+	}
+	bucketSvc := mock.NewBucketService()
+	bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+		return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID}, nil
+	}
+	pointsWriter := &mock.PointsWriter{}
+	pointsWriter.ForceError(tsdb.PartialWriteError{Reason: "max-series-per-bucket limit exceeded: (1/1)", Dropped: 1})
+
+	h := NewWriteHandler(&WriteBackend{
+		Logger:              zap.NewNop(),
+		PointsWriter:        pointsWriter,
+		BucketService:       bucketSvc,
+		OrganizationService: orgSvc,
+	})
+
+	r := httptest.NewRequest("POST", "/api/v2/write?org="+orgID.String()+"&bucket="+bucketID.String(), strings.NewReader("m,t1=v1 f1=2"))
+	r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: []platform.Permission{*p}}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: got %d, exp %d", w.Code, http.StatusBadRequest)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "max-series-per-bucket limit exceeded") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestWriteHandler_handleWrite_Partial(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	p, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := models.ParsePointsString(`m,t=c f="x" 3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflict, err := tsdb.ExplodePoints(orgID, bucketID, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const reason = `field type conflict: input field "f" on measurement "m" is type string, already exists as type float`
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		res    string
+	}{
+		{
+			name:   "all lines written",
+			body:   "m,t=a f=1 1\nm,t=b f=2 2",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "some lines rejected",
+			body:   "m,t=a f=1 1\nm,t=b f= 2\n\nm,t=c f=\"x\" 3\nm,t=d f=4 4\n",
+			status: http.StatusOK,
+			res: `{
+  "written": 2,
+  "rejected": [
+    {"line": 2, "text": "m,t=b f= 2", "reason": "missing field value"},
+    {"line": 4, "text": "m,t=c f=\"x\" 3", "reason": "` + strings.Replace(reason, `"`, `\"`, -1) + `"}
+  ],
+  "rejectedCount": 2
+}`,
+		},
+		{
+			name:   "all lines rejected",
+			body:   "m,t=b f= 2",
+			status: http.StatusBadRequest,
+			res: `{
+  "written": 0,
+  "rejected": [
+    {"line": 1, "text": "m,t=b f= 2", "reason": "missing field value"}
+  ],
+  "rejectedCount": 1
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgSvc := mock.NewOrganizationService()
+			orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+				return &platform.Organization{ID: id}, nil
+			}
+			bucketSvc := mock.NewBucketService()
+			bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+				return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID}, nil
+			}
+
+			pointsWriter := &mock.PointsWriter{}
+			if strings.Contains(tt.body, "m,t=c") {
+				pointsWriter.ForceError(tsdb.PartialWriteError{
+					Reason:         reason,
+					Dropped:        1,
+					DroppedKeys:    [][]byte{conflict[0].Key()},
+					DroppedReasons: []string{reason},
+				})
+			}
+
+			h := NewWriteHandler(&WriteBackend{
+				Logger:              zap.NewNop(),
+				PointsWriter:        pointsWriter,
+				BucketService:       bucketSvc,
+				OrganizationService: orgSvc,
+			})
+
+			r := httptest.NewRequest("POST", "/api/v2/write?partial=true&org="+orgID.String()+"&bucket="+bucketID.String(), strings.NewReader(tt.body))
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: []platform.Permission{*p}}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("unexpected status: got %d, exp %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.res != "" {
+				if eq, diff, _ := jsonEqual(w.Body.String(), tt.res); !eq {
+					t.Fatalf("unexpected body: %s", diff)
+				}
+			}
+		})
+	}
+}
+
+// batchRecorder is a PointsWriter that records the size of each batch written.
+type batchRecorder struct {
+	batches []int
+}
+
+func (b *batchRecorder) WritePoints(ctx context.Context, points []models.Point) error {
+	b.batches = append(b.batches, len(points))
+	return nil
+}
+
+func TestRejectedLines(t *testing.T) {
+	var a rejectedLines
+	long := strings.Repeat("é", maxRejectedTextSize)
+	for i := 250; i > 0; i-- {
+		a.add(rejectedLine{Line: i, Text: rejectedText([]byte(long)), Reason: long})
+	}
+	a.truncate()
+
+	if a.count != 250 {
+		t.Fatalf("unexpected count of rejected lines: %d", a.count)
+	}
+	if len(a.lines) != maxRejectedLines {
+		t.Fatalf("expected %d lines to be listed, got %d", maxRejectedLines, len(a.lines))
+	}
+	for i, line := range a.lines {
+		if line.Line != i+1 {
+			t.Fatalf("expected the first lines to be listed in order, got line %d at %d", line.Line, i)
+		}
+		if len(line.Text) > maxRejectedTextSize+len("...") || !utf8.ValidString(line.Text) {
+			t.Fatalf("unexpected text of rejected line: %d bytes", len(line.Text))
+		}
+		if len(line.Reason) > maxRejectedTextSize+len("...") || !utf8.ValidString(line.Reason) {
+			t.Fatalf("unexpected reason of rejected line: %d bytes", len(line.Reason))
+		}
+	}
+}
+
+func TestWriteHandler_handleWrite_Limits(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	p, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each line has two fields, so is exploded into two points.
+	body := strings.Repeat("m,t=a f=1,g=2 1\n", 25)
+
+	tests := []struct {
+		name    string
+		backend WriteBackend
+		partial bool
+		status  int
+		batches []int
+	}{
+		{
+			name:    "batches",
+			backend: WriteBackend{WriteBatchSize: 10},
+			status:  http.StatusNoContent,
+			batches: []int{10, 10, 10, 10, 10},
+		},
+		{
+			name:    "batches rounded up to whole lines",
+			backend: WriteBackend{WriteBatchSize: 15},
+			status:  http.StatusNoContent,
+			batches: []int{16, 16, 16, 2},
+		},
+		{
+			name:    "partial batches",
+			backend: WriteBackend{WriteBatchSize: 20},
+			partial: true,
+			status:  http.StatusNoContent,
+			batches: []int{20, 20, 10},
+		},
+		{
+			name:    "body within limit",
+			backend: WriteBackend{MaxBodySize: int64(len(body)), MaxPoints: 25},
+			status:  http.StatusNoContent,
+			batches: []int{50},
+		},
+		{
+			name:    "body too large",
+			backend: WriteBackend{MaxBodySize: int64(len(body)) - 1},
+			status:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "too many points",
+			backend: WriteBackend{MaxPoints: 24},
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "too many points in partial write",
+			backend: WriteBackend{MaxPoints: 24},
+			partial: true,
+			status:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgSvc := mock.NewOrganizationService()
+			orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+				return &platform.Organization{ID: id}, nil
+			}
+			bucketSvc := mock.NewBucketService()
+			bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+				return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID}, nil
+			}
+			pointsWriter := &batchRecorder{}
+
+			b := tt.backend
+			b.Logger = zap.NewNop()
+			b.PointsWriter = pointsWriter
+			b.BucketService = bucketSvc
+			b.OrganizationService = orgSvc
+			h := NewWriteHandler(&b)
+
+			url := "/api/v2/write?org=" + orgID.String() + "&bucket=" + bucketID.String()
+			if tt.partial {
+				url += "&partial=true"
+			}
+			r := httptest.NewRequest("POST", url, strings.NewReader(body))
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: []platform.Permission{*p}}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("unexpected status: got %d, exp %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if !reflect.DeepEqual(pointsWriter.batches, tt.batches) {
+				t.Fatalf("unexpected batches: got %v, exp %v", pointsWriter.batches, tt.batches)
+			}
+		})
+	}
+}
+
+func TestWriteHandler_handleWrite_ParseErrorWritesNothing(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	p, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orgSvc := mock.NewOrganizationService()
+	orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+		return &platform.Organization{ID: id}, nil
+	}
+	bucketSvc := mock.NewBucketService()
+	bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+		return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID}, nil
+	}
+	pointsWriter := &batchRecorder{}
+
+	h := NewWriteHandler(&WriteBackend{
+		Logger:              zap.NewNop(),
+		PointsWriter:        pointsWriter,
+		BucketService:       bucketSvc,
+		OrganizationService: orgSvc,
+		WriteBatchSize:      10,
+	})
+
+	// The bad line follows several batches of points.
+	body := strings.Repeat("m,t=a f=1 1\n", 25) + "m,t=a f=\n"
+	r := httptest.NewRequest("POST", "/api/v2/write?org="+orgID.String()+"&bucket="+bucketID.String(), strings.NewReader(body))
+	r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: []platform.Permission{*p}}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: got %d, exp %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "written before") {
+		t.Fatalf("unexpected error message: %s", w.Body.String())
+	}
+	if len(pointsWriter.batches) != 0 {
+		t.Fatalf("expected no points to be written, got batches %v", pointsWriter.batches)
+	}
+}
+
+func TestWriteHandler_handleWrite_Formats(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	p, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		points      int
+	}{
+		{
+			name:        "line protocol",
+			contentType: "text/plain; charset=utf-8",
+			body:        "cpu,host=a value=1,idle=2 1",
+			status:      http.StatusNoContent,
+			points:      2,
+		},
+		{
+			name:        "csv",
+			contentType: "text/csv",
+			body: `#datatype,string,long,dateTime:RFC3339,double,string,string,string
+#group,false,false,false,false,true,true,true
+,result,table,_time,_value,_field,_measurement,host
+,,0,2019-01-01T00:00:00Z,1,value,cpu,a
+,,0,2019-01-01T00:00:01Z,2,value,cpu,a
+`,
+			status: http.StatusNoContent,
+			points: 2,
+		},
+		{
+			name:        "invalid csv",
+			contentType: "text/csv",
+			body: `#datatype,string,double
+,_measurement,value
+,cpu,x
+`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `[{"measurement": "cpu", "tags": {"host": "a"}, "fields": {"value": 1, "idle": 2}, "time": "2019-01-01T00:00:00Z"}]`,
+			status:      http.StatusNoContent,
+			points:      2,
+		},
+		{
+			name:        "malformed json",
+			contentType: "application/json",
+			body:        `[{"measurement": `,
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgSvc := mock.NewOrganizationService()
+			orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+				return &platform.Organization{ID: id}, nil
+			}
+			bucketSvc := mock.NewBucketService()
+			bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+				return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID}, nil
+			}
+			pointsWriter := &mock.PointsWriter{}
+
+			h := NewWriteHandler(&WriteBackend{
+				Logger:              zap.NewNop(),
+				PointsWriter:        pointsWriter,
+				BucketService:       bucketSvc,
+				OrganizationService: orgSvc,
+			})
+
+			r := httptest.NewRequest("POST", "/api/v2/write?org="+orgID.String()+"&bucket="+bucketID.String(), strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: []platform.Permission{*p}}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("unexpected status: got %d, exp %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if len(pointsWriter.Points) != tt.points {
+				t.Fatalf("unexpected points written: got %d, exp %d", len(pointsWriter.Points), tt.points)
+			}
+		})
+	}
+}
+
+func TestWriteHandler_handleWrite_DefaultTags(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	p, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		header     string
+		bucketTags map[string]string
+		body       string
+		status     int
+		tags       []string
+	}{
+		{
+			name:   "query",
+			query:  "&tag=region%3Dus-west&tag=host%3Db",
+			body:   "cpu,host=a value=1 1\nmem free=2 1",
+			status: http.StatusNoContent,
+			tags:   []string{"host=a,region=us-west", "host=b,region=us-west"},
+		},
+		{
+			name:   "header",
+			header: "region=us-west, dc=east%2C1",
+			body:   "cpu value=1 1",
+			status: http.StatusNoContent,
+			tags:   []string{"dc=east\\,1,region=us-west"},
+		},
+		{
+			name:       "request tags take precedence over bucket tags",
+			query:      "&tag=region%3Dus-east",
+			bucketTags: map[string]string{"region": "us-west", "env": "prod"},
+			body:       "cpu value=1 1",
+			status:     http.StatusNoContent,
+			tags:       []string{"env=prod,region=us-east"},
+		},
+		{
+			name:       "partial",
+			query:      "&partial=true",
+			bucketTags: map[string]string{"region": "us-west"},
+			body:       "cpu value=1 1\ncpu value=",
+			status:     http.StatusOK,
+			tags:       []string{"region=us-west"},
+		},
+		{
+			name:   "invalid tag",
+			query:  "&tag=region",
+			body:   "cpu value=1 1",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgSvc := mock.NewOrganizationService()
+			orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+				return &platform.Organization{ID: id}, nil
+			}
+			bucketSvc := mock.NewBucketService()
+			bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+				return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID, DefaultTags: tt.bucketTags}, nil
+			}
+			pointsWriter := &mock.PointsWriter{}
+
+			h := NewWriteHandler(&WriteBackend{
+				Logger:              zap.NewNop(),
+				PointsWriter:        pointsWriter,
+				BucketService:       bucketSvc,
+				OrganizationService: orgSvc,
+			})
+
+			r := httptest.NewRequest("POST", "/api/v2/write?org="+orgID.String()+"&bucket="+bucketID.String()+tt.query, strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set("Influx-Default-Tags", tt.header)
+			}
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: []platform.Permission{*p}}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("unexpected status: got %d, exp %d: %s", w.Code, tt.status, w.Body.String())
+			}
+
+			var tags []string
+			for _, pt := range pointsWriter.Points {
+				var external models.Tags
+				for _, tag := range pt.Tags() {
+					if string(tag.Key) != models.MeasurementTagKey && string(tag.Key) != models.FieldKeyTagKey {
+						external = append(external, tag)
+					}
+				}
+				tags = append(tags, string(external.HashKey()[1:]))
+			}
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Fatalf("unexpected tags: got %q, exp %q", tags, tt.tags)
+			}
+		})
+	}
+}
+
+func TestWriteHandler_handleWrite_TimeBounds(t *testing.T) {
+	const (
+		orgID    = platform.ID(1)
+		bucketID = platform.ID(2)
+	)
+
+	p, err := platform.NewPermissionAtID(bucketID, platform.WriteAction, platform.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the time at 10000s, points from 6400s to 13600s are accepted.
+	const body = "m f=1 3000\nm f=2 10000\nm f=3 20000"
+	tests := []struct {
+		name    string
+		partial bool
+		status  int
+		res     string
+	}{
+		{
+			name:   "rejected",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:    "partial",
+			partial: true,
+			status:  http.StatusOK,
+			res: `{
+  "written": 1,
+  "rejected": [
+    {"line": 1, "text": "m f=1 3000", "reason": "point time 1970-01-01T00:50:00Z is outside the retention period of 1h0m0s"},
+    {"line": 3, "text": "m f=3 20000", "reason": "point time 1970-01-01T05:33:20Z is more than 1h0m0s in the future"}
+  ],
+  "rejectedCount": 2
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgSvc := mock.NewOrganizationService()
+			orgSvc.FindOrganizationByIDF = func(ctx context.Context, id platform.ID) (*platform.Organization, error) {
+				return &platform.Organization{ID: id}, nil
+			}
+			bucketSvc := mock.NewBucketService()
+			bucketSvc.FindBucketFn = func(ctx context.Context, filter platform.BucketFilter) (*platform.Bucket, error) {
+				return &platform.Bucket{ID: *filter.ID, OrganizationID: *filter.OrganizationID, RetentionPeriod: time.Hour}, nil
+			}
+			pointsWriter := &mock.PointsWriter{}
+
+			h := NewWriteHandler(&WriteBackend{
+				Logger:              zap.NewNop(),
+				PointsWriter:        pointsWriter,
+				BucketService:       bucketSvc,
+				OrganizationService: orgSvc,
+				MaxFuture:           time.Hour,
+			})
+			h.now = func() time.Time { return time.Unix(10000, 0) }
+
+			reg := prom.NewRegistry()
+			reg.MustRegister(h.PrometheusCollectors()...)
+
+			r := httptest.NewRequest("POST", "/api/v2/write?precision=s&partial="+strconv.FormatBool(tt.partial)+"&org="+orgID.String()+"&bucket="+bucketID.String(), strings.NewReader(body))
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), &platform.Authorization{Status: platform.Active, Permissions: []platform.Permission{*p}}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("unexpected status: got %d, exp %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.res != "" {
+				if eq, diff, _ := jsonEqual(w.Body.String(), tt.res); !eq {
+					t.Fatalf("unexpected body: %s", diff)
+				}
+			}
+
+			if got := len(pointsWriter.Points); got != 1 {
+				t.Fatalf("unexpected number of points written: %d", got)
+			} else if v := pointsWriter.Points[0].UnixNano(); v != 10000*int64(time.Second) {
+				t.Fatalf("unexpected point written at %d", v)
+			}
+
+			mfs := promtest.MustGather(t, reg)
+			for _, reason := range []string{"retention", "future"} {
+				m := promtest.MustFindMetric(t, mfs, "http_write_points_rejected_total", map[string]string{
+					"org_id":    orgID.String(),
+					"bucket_id": bucketID.String(),
+					"reason":    reason,
+				})
+				if got := m.GetCounter().GetValue(); got != 1 {
+					t.Fatalf("unexpected %s rejections: %v", reason, got)
+				}
+			}
+		})
+	}
+}
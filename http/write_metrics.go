@@ -0,0 +1,29 @@
+package http
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// writeMetrics is a set of metrics concerned with tracking data about writes.
+type writeMetrics struct {
+	pointsRejected *prometheus.CounterVec
+}
+
+func newWriteMetrics() *writeMetrics {
+	const namespace = "http"
+	const writeSubsystem = "write"
+
+	return &writeMetrics{
+		pointsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: writeSubsystem,
+			Name:      "points_rejected_total",
+			Help:      "Number of written points rejected because of their time, by org/bucket id and reason.",
+		}, []string{"org_id", "bucket_id", "reason"}),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *writeMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.pointsRejected,
+	}
+}
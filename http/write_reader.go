@@ -0,0 +1,193 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/write"
+)
+
+// Content types accepted by the write handler in addition to line protocol.
+const (
+	writeContentTypeCSV  = "text/csv"
+	writeContentTypeJSON = "application/json"
+)
+
+// writeRecordChunkSize is the number of CSV records or JSON points a record
+// reader returns at once.
+const writeRecordChunkSize = 1000
+
+// errBodyTooLarge is returned when reading more than the maximum size of a write.
+var errBodyTooLarge = errors.New("request body too large")
+
+// writeRecord is a line of line protocol, CSV record or JSON point in the body of
+// a write, with either the point it was converted to or the error converting it.
+type writeRecord struct {
+	line  int // the line number of the record, or the index of a JSON point, from 1
+	text  []byte
+	point models.Point
+	err   error
+}
+
+// recordReader reads the records of a write in chunks.
+type recordReader interface {
+	// next returns the next chunk of records, or io.EOF if there are none.
+	// The error is for the body as a whole; errors in a record are returned
+	// with the record.
+	next() ([]writeRecord, error)
+}
+
+// newRecordReader returns a reader of the records in r, in the format given by
+// the content type. Bodies in unknown formats are read as line protocol, which
+// allows clients that do not set a content type to keep writing. Reading more
+// than maxSize bytes returns errBodyTooLarge if maxSize is greater than zero.
+func newRecordReader(contentType string, r io.Reader, maxSize int64, precision string) (recordReader, error) {
+	if maxSize > 0 {
+		r = &sizeLimitReader{r: r, n: maxSize}
+	}
+
+	var mediaType string
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  fmt.Sprintf("invalid content type %q", contentType),
+				Err:  err,
+			}
+		}
+	}
+
+	now := time.Now()
+	switch mediaType {
+	case writeContentTypeCSV:
+		cr := write.NewCSVReader(r)
+		cr.SetPrecision(precision)
+		cr.SetDefaultTime(now)
+		return &csvRecordReader{r: cr}, nil
+	case writeContentTypeJSON:
+		jr := write.NewJSONReader(r)
+		jr.SetPrecision(precision)
+		jr.SetDefaultTime(now)
+		return &jsonRecordReader{r: jr}, nil
+	default:
+		return &lineProtocolReader{lines: newLineReader(r), now: now, precision: precision}, nil
+	}
+}
+
+// lineProtocolReader reads records from line protocol.
+type lineProtocolReader struct {
+	lines     *lineReader
+	now       time.Time
+	precision string
+}
+
+func (r *lineProtocolReader) next() ([]writeRecord, error) {
+	chunk, first, err := r.lines.next()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []writeRecord
+	models.ParseLinesWithPrecision(chunk, r.now, r.precision, func(n int, text []byte, pt models.Point, err error) {
+		records = append(records, writeRecord{line: first + n - 1, text: text, point: pt, err: err})
+	})
+	return records, nil
+}
+
+// csvRecordReader reads records from annotated CSV.
+type csvRecordReader struct {
+	r *write.CSVReader
+}
+
+func (r *csvRecordReader) next() ([]writeRecord, error) {
+	var records []writeRecord
+	for len(records) < writeRecordChunkSize {
+		pt, err := r.r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		rec := writeRecord{line: r.r.Line(), point: pt}
+		if cerr, ok := err.(*write.CSVError); ok {
+			rec.line, rec.err = cerr.Line, cerr.Err
+		} else if err != nil {
+			return nil, err
+		}
+
+		if record := r.r.Record(); record != nil {
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			_ = w.Write(record)
+			w.Flush()
+			rec.text = bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+		}
+		records = append(records, rec)
+	}
+
+	if len(records) == 0 {
+		return nil, io.EOF
+	}
+	return records, nil
+}
+
+// jsonRecordReader reads records from JSON points.
+type jsonRecordReader struct {
+	r *write.JSONReader
+}
+
+func (r *jsonRecordReader) next() ([]writeRecord, error) {
+	var records []writeRecord
+	for len(records) < writeRecordChunkSize {
+		pt, err := r.r.Read()
+		if err == io.EOF {
+			break
+		}
+
+		rec := writeRecord{line: r.r.Index(), point: pt}
+		if jerr, ok := err.(*write.JSONError); ok {
+			rec.err = jerr.Err
+		} else if err == errBodyTooLarge {
+			return nil, err
+		} else if err != nil {
+			return nil, &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  err.Error(),
+				Err:  err,
+			}
+		}
+		rec.text = append([]byte(nil), r.r.Raw()...)
+		records = append(records, rec)
+	}
+
+	if len(records) == 0 {
+		return nil, io.EOF
+	}
+	return records, nil
+}
+
+// sizeLimitReader reads from r, returning errBodyTooLarge if r has more than n
+// bytes left to read.
+type sizeLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	// Read one byte more than the limit to find out if it is exceeded.
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.r.Read(p)
+	if r.n -= int64(n); r.n < 0 {
+		return n, errBodyTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestSizeLimitReader(t *testing.T) {
+	body := strings.Repeat("cpu value=1 1\n", 100)
+
+	r := &sizeLimitReader{r: strings.NewReader(body), n: int64(len(body))}
+	if b, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	} else if string(b) != body {
+		t.Fatal("unexpected body")
+	}
+
+	r = &sizeLimitReader{r: strings.NewReader(body), n: int64(len(body) - 1)}
+	if _, err := ioutil.ReadAll(r); err != errBodyTooLarge {
+		t.Fatalf("got %v, expected %v", err, errBodyTooLarge)
+	}
+}
+
+func TestNewRecordReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []writeRecord
+	}{
+		{
+			name: "line protocol",
+			body: "cpu value=1 1\n\ncpu value= 2\n",
+			want: []writeRecord{
+				{line: 1, text: []byte("cpu value=1 1")},
+				{line: 3, text: []byte("cpu value= 2"), err: errAny},
+			},
+		},
+		{
+			name:        "csv",
+			contentType: "text/csv; charset=utf-8",
+			body: `#datatype,string,long,dateTime:RFC3339,double,string,string
+,result,table,_time,_value,_field,_measurement
+,,0,1970-01-01T00:00:01Z,1,value,cpu
+,,0,1970-01-01T00:00:02Z,x,value,cpu
+`,
+			want: []writeRecord{
+				{line: 3, text: []byte(",,0,1970-01-01T00:00:01Z,1,value,cpu")},
+				{line: 4, text: []byte(",,0,1970-01-01T00:00:02Z,x,value,cpu"), err: errAny},
+			},
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `[{"measurement": "cpu", "fields": {"value": 1}}, {"measurement": "cpu"}]`,
+			want: []writeRecord{
+				{line: 1, text: []byte(`{"measurement": "cpu", "fields": {"value": 1}}`)},
+				{line: 2, text: []byte(`{"measurement": "cpu"}`), err: errAny},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, err := newRecordReader(tt.contentType, strings.NewReader(tt.body), 0, "s")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []writeRecord
+			for {
+				chunk, err := rr.next()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, chunk...)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d records, expected %d", len(got), len(tt.want))
+			}
+			for i, rec := range got {
+				exp := tt.want[i]
+				if rec.line != exp.line || string(rec.text) != string(exp.text) {
+					t.Errorf("record %d: got line %d %q, expected line %d %q", i, rec.line, rec.text, exp.line, exp.text)
+				}
+				if (rec.err != nil) != (exp.err != nil) || (rec.err == nil) == (rec.point == nil) {
+					t.Errorf("record %d: unexpected point %v and error %v", i, rec.point, rec.err)
+				}
+			}
+		})
+	}
+
+	if _, err := newRecordReader("text/csv; charset", strings.NewReader(""), 0, ""); err == nil {
+		t.Fatal("expected an error for an invalid content type")
+	}
+}
+
+// errAny marks a record that is expected to have an error.
+var errAny = io.ErrUnexpectedEOF
@@ -2,11 +2,41 @@ package inmem
 
 import (
 	"context"
+	"time"
 
 	platform "github.com/influxdata/influxdb"
 )
 
+// storedAuthorization is an authorization as it is stored, with a salted
+// hash of its token in place of the token.
+type storedAuthorization struct {
+	platform.Authorization
+	TokenHash string
+
+	// The token replaced by the last rotation is accepted until
+	// PreviousTokenExpiresAt.
+	PreviousTokenHash      string
+	PreviousTokenExpiresAt time.Time
+}
+
+// matchToken reports whether token is the token of the authorization, or
+// the token it replaced if that is still accepted at now.
+func (a *storedAuthorization) matchToken(token string, now time.Time) bool {
+	if platform.CompareTokenHash(a.TokenHash, token) {
+		return true
+	}
+	return now.Before(a.PreviousTokenExpiresAt) && platform.CompareTokenHash(a.PreviousTokenHash, token)
+}
+
 func (s *Service) loadAuthorization(ctx context.Context, id platform.ID) (*platform.Authorization, *platform.Error) {
+	a, pe := s.loadStoredAuthorization(ctx, id)
+	if pe != nil {
+		return nil, pe
+	}
+	return &a.Authorization, nil
+}
+
+func (s *Service) loadStoredAuthorization(ctx context.Context, id platform.ID) (*storedAuthorization, *platform.Error) {
 	i, ok := s.authorizationKV.Load(id.String())
 	if !ok {
 		return nil, &platform.Error{
@@ -15,7 +45,7 @@ func (s *Service) loadAuthorization(ctx context.Context, id platform.ID) (*platf
 		}
 	}
 
-	a, ok := i.(platform.Authorization)
+	a, ok := i.(storedAuthorization)
 	if !ok {
 		return nil, &platform.Error{
 			Code: platform.EInternal,
@@ -31,11 +61,29 @@ func (s *Service) loadAuthorization(ctx context.Context, id platform.ID) (*platf
 }
 
 // PutAuthorization overwrites the authorization with the contents of a.
+// The token of a is stored hashed; if it is empty the stored hash is kept.
 func (s *Service) PutAuthorization(ctx context.Context, a *platform.Authorization) error {
 	if a.Status == "" {
 		a.Status = platform.Active
 	}
-	s.authorizationKV.Store(a.ID.String(), *a)
+
+	sa := storedAuthorization{Authorization: *a}
+	sa.Token = ""
+	if a.Token != "" {
+		hash, err := platform.HashToken(a.Token)
+		if err != nil {
+			return &platform.Error{
+				Code: platform.EInternal,
+				Err:  err,
+			}
+		}
+		sa.TokenHash = hash
+	} else if prev, pe := s.loadStoredAuthorization(ctx, a.ID); pe == nil {
+		sa.TokenHash = prev.TokenHash
+		sa.PreviousTokenHash, sa.PreviousTokenExpiresAt = prev.PreviousTokenHash, prev.PreviousTokenExpiresAt
+	}
+
+	s.authorizationKV.Store(a.ID.String(), sa)
 	return nil
 }
 
@@ -73,26 +121,26 @@ func (s *Service) FindAuthorizationByToken(ctx context.Context, t string) (*plat
 	return as[0], nil
 }
 
-func filterAuthorizationsFn(filter platform.AuthorizationFilter) func(a *platform.Authorization) bool {
+func filterAuthorizationsFn(filter platform.AuthorizationFilter, now time.Time) func(a *storedAuthorization) bool {
 	if filter.ID != nil {
-		return func(a *platform.Authorization) bool {
+		return func(a *storedAuthorization) bool {
 			return a.ID == *filter.ID
 		}
 	}
 
 	if filter.Token != nil {
-		return func(a *platform.Authorization) bool {
-			return a.Token == *filter.Token
+		return func(a *storedAuthorization) bool {
+			return a.matchToken(*filter.Token, now)
 		}
 	}
 
 	if filter.UserID != nil {
-		return func(a *platform.Authorization) bool {
+		return func(a *storedAuthorization) bool {
 			return a.UserID == *filter.UserID
 		}
 	}
 
-	return func(a *platform.Authorization) bool { return true }
+	return func(a *storedAuthorization) bool { return true }
 }
 
 // FindAuthorizations returns all authorizations matching the filter.
@@ -122,9 +170,9 @@ func (s *Service) FindAuthorizations(ctx context.Context, filter platform.Author
 		filter.UserID = &u.ID
 	}
 	var err error
-	filterF := filterAuthorizationsFn(filter)
+	filterF := filterAuthorizationsFn(filter, s.time())
 	s.authorizationKV.Range(func(k, v interface{}) bool {
-		a, ok := v.(platform.Authorization)
+		a, ok := v.(storedAuthorization)
 		if !ok {
 			err = &platform.Error{
 				Code: platform.EInternal,
@@ -135,7 +183,7 @@ func (s *Service) FindAuthorizations(ctx context.Context, filter platform.Author
 		}
 
 		if filterF(&a) {
-			as = append(as, &a)
+			as = append(as, &a.Authorization)
 		}
 
 		return true
@@ -148,7 +196,8 @@ func (s *Service) FindAuthorizations(ctx context.Context, filter platform.Author
 	return as, len(as), nil
 }
 
-// CreateAuthorization sets a.Token and a.ID and creates an platform.Authorization
+// CreateAuthorization sets a.Token and a.ID and creates an platform.Authorization.
+// Only a hash of the token is stored, so a.Token is the only time the token can be read.
 func (s *Service) CreateAuthorization(ctx context.Context, a *platform.Authorization) error {
 	op := OpPrefix + platform.OpCreateAuthorization
 
@@ -220,3 +269,65 @@ func (s *Service) SetAuthorizationStatus(ctx context.Context, id platform.ID, st
 	a.Status = status
 	return s.PutAuthorization(ctx, a)
 }
+
+// UpdateAuthorization updates the status, description, expiry or last use
+// of an authorization associated with id.
+func (s *Service) UpdateAuthorization(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+	op := OpPrefix + platform.OpUpdateAuthorization
+	a, pe := s.loadStoredAuthorization(ctx, id)
+	if pe != nil {
+		pe.Op = op
+		return nil, pe
+	}
+
+	if err := upd.Apply(&a.Authorization); err != nil {
+		return nil, &platform.Error{
+			Err: err,
+			Op:  op,
+		}
+	}
+
+	s.authorizationKV.Store(id.String(), *a)
+	return &a.Authorization, nil
+}
+
+// RotateAuthorization replaces the token of an authorization associated with
+// id with a new one, returned in the Token of the result. The old token keeps
+// working for the grace period.
+func (s *Service) RotateAuthorization(ctx context.Context, id platform.ID, grace time.Duration) (*platform.Authorization, error) {
+	op := OpPrefix + platform.OpRotateAuthorization
+	a, pe := s.loadStoredAuthorization(ctx, id)
+	if pe != nil {
+		pe.Op = op
+		return nil, pe
+	}
+
+	token, err := s.TokenGenerator.Token()
+	if err != nil {
+		return nil, &platform.Error{
+			Err: err,
+			Op:  op,
+		}
+	}
+
+	hash, err := platform.HashToken(token)
+	if err != nil {
+		return nil, &platform.Error{
+			Code: platform.EInternal,
+			Err:  err,
+			Op:   op,
+		}
+	}
+
+	a.PreviousTokenHash, a.PreviousTokenExpiresAt = "", time.Time{}
+	if grace > 0 {
+		a.PreviousTokenHash, a.PreviousTokenExpiresAt = a.TokenHash, s.time().Add(grace)
+	}
+	a.TokenHash = hash
+
+	s.authorizationKV.Store(id.String(), *a)
+
+	res := a.Authorization
+	res.Token = token
+	return &res, nil
+}
@@ -236,6 +236,13 @@ func (s *Service) FindBuckets(ctx context.Context, filter platform.BucketFilter,
 
 // CreateBucket creates a new bucket and sets b.ID with the new identifier.
 func (s *Service) CreateBucket(ctx context.Context, b *platform.Bucket) error {
+	if err := platform.ValidateDefaultTags(b.DefaultTags); err != nil {
+		return &platform.Error{
+			Err: err,
+			Op:  OpPrefix + platform.OpCreateBucket,
+		}
+	}
+
 	if b.OrganizationID.Valid() {
 		_, pe := s.FindOrganizationByID(ctx, b.OrganizationID)
 		if pe != nil {
@@ -294,6 +301,16 @@ func (s *Service) UpdateBucket(ctx context.Context, id platform.ID, upd platform
 		b.RetentionPeriod = *upd.RetentionPeriod
 	}
 
+	if upd.DefaultTags != nil {
+		if err := platform.ValidateDefaultTags(*upd.DefaultTags); err != nil {
+			return nil, &platform.Error{
+				Err: err,
+				Op:  OpPrefix + platform.OpUpdateBucket,
+			}
+		}
+		b.DefaultTags = *upd.DefaultTags
+	}
+
 	b0, err := s.FindBucket(ctx, platform.BucketFilter{
 		Name: upd.Name,
 	})
@@ -218,6 +218,10 @@ func (s *Service) UpdateOrganization(ctx context.Context, id platform.ID, upd pl
 		o.Name = *upd.Name
 	}
 
+	if upd.RequireMFA != nil {
+		o.RequireMFA = *upd.RequireMFA
+	}
+
 	s.organizationKV.Store(o.ID.String(), o)
 
 	return o, nil
@@ -0,0 +1,224 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb"
+)
+
+var (
+	auditLogBucket = []byte("auditlogv1")
+)
+
+var _ influxdb.AuditLogService = (*Service)(nil)
+
+func (s *Service) initializeAuditLog(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(auditLogBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// auditEventKey is the time of an event followed by its ID, so that events
+// are ordered by time and events recorded at the same time do not collide.
+func auditEventKey(t time.Time, id influxdb.ID) ([]byte, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, err
+	}
+	k := make([]byte, 8, 8+len(encodedID))
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return append(k, encodedID...), nil
+}
+
+// auditEventTimeKey is the prefix of the keys of events recorded at t.
+func auditEventTimeKey(t time.Time) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return k
+}
+
+// AppendAuditEvent records an event, and sets its ID and, if it is zero, its
+// time.
+func (s *Service) AppendAuditEvent(ctx context.Context, e *influxdb.AuditEvent) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		e.ID = s.IDGenerator.ID()
+		if e.Time.IsZero() {
+			e.Time = s.time()
+		}
+		e.Time = e.Time.UTC()
+
+		k, err := auditEventKey(e.Time, e.ID)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Err:  err,
+			}
+		}
+		v, err := json.Marshal(e)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+
+		b, err := tx.Bucket(auditLogBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put(k, v)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpAppendAuditEvent,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// FindAuditEvents returns the events that match filter, oldest first unless
+// opt is descending, and the total count of matching events.
+func (s *Service) FindAuditEvents(ctx context.Context, filter influxdb.AuditEventFilter, opt ...influxdb.FindOptions) ([]*influxdb.AuditEvent, int, error) {
+	es := []*influxdb.AuditEvent{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return s.forEachAuditEvent(ctx, tx, filter.Since, filter.Until, func(e *influxdb.AuditEvent) bool {
+			if auditEventMatches(e, filter) {
+				es = append(es, e)
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return nil, 0, &influxdb.Error{
+			Op:  influxdb.OpFindAuditEvents,
+			Err: err,
+		}
+	}
+
+	total := len(es)
+	if len(opt) > 0 {
+		o := opt[0]
+		if o.Descending {
+			for i, j := 0, len(es)-1; i < j; i, j = i+1, j-1 {
+				es[i], es[j] = es[j], es[i]
+			}
+		}
+		if o.Offset > 0 {
+			if o.Offset >= len(es) {
+				es = es[:0]
+			} else {
+				es = es[o.Offset:]
+			}
+		}
+		if o.Limit > 0 && o.Limit < len(es) {
+			es = es[:o.Limit]
+		}
+	}
+	return es, total, nil
+}
+
+func auditEventMatches(e *influxdb.AuditEvent, filter influxdb.AuditEventFilter) bool {
+	if filter.Action != "" && e.Action != filter.Action {
+		return false
+	}
+	if filter.ResourceType != "" && e.ResourceType != filter.ResourceType {
+		return false
+	}
+	if filter.ResourceID != nil && e.ResourceID != *filter.ResourceID {
+		return false
+	}
+	if filter.UserID != nil && e.UserID != *filter.UserID {
+		return false
+	}
+	if filter.OrgID != nil && e.OrgID != *filter.OrgID {
+		return false
+	}
+	return true
+}
+
+// forEachAuditEvent iterates through the events recorded from since until
+// until, oldest first, while fn returns true. since and until are not bounds
+// when they are zero.
+func (s *Service) forEachAuditEvent(ctx context.Context, tx Tx, since, until time.Time, fn func(*influxdb.AuditEvent) bool) error {
+	b, err := tx.Bucket(auditLogBucket)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	// Seek is not used to skip to since, as not every store seeks to the
+	// keys following one that is missing.
+	var start, end []byte
+	if !since.IsZero() {
+		start = auditEventTimeKey(since)
+	}
+	if !until.IsZero() {
+		end = auditEventTimeKey(until)
+	}
+
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		if start != nil && bytes.Compare(k, start) < 0 {
+			continue
+		}
+		if end != nil && bytes.Compare(k, end) >= 0 {
+			break
+		}
+		e := &influxdb.AuditEvent{}
+		if err := json.Unmarshal(v, e); err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Msg:  "unable to unmarshal audit event",
+				Err:  err,
+			}
+		}
+		if !fn(e) {
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteAuditEventsBefore removes the events recorded before t, and returns
+// how many were removed.
+func (s *Service) DeleteAuditEventsBefore(ctx context.Context, t time.Time) (int, error) {
+	var n int
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(auditLogBucket)
+		if err != nil {
+			return err
+		}
+		cur, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		// Keys are collected first as deleting while iterating is not
+		// supported by every store.
+		end := auditEventTimeKey(t)
+		var keys [][]byte
+		for k, _ := cur.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = cur.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(keys)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
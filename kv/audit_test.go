@@ -0,0 +1,137 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/mock"
+)
+
+func TestBoltAuditLog(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+	testAuditLog(s, t)
+}
+
+func TestInmemAuditLog(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+	testAuditLog(s, t)
+}
+
+func testAuditLog(s kv.Store, t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(s)
+	svc.IDGenerator = mock.NewIDGenerator("0000000000000001", t)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing audit log: %v", err)
+	}
+
+	base := time.Date(2006, 5, 4, 1, 2, 3, 0, time.UTC)
+	orgID := influxdb.ID(10)
+	events := []*influxdb.AuditEvent{
+		{Time: base, Action: influxdb.AuditActionCreate, ResourceType: "buckets", OrgID: orgID},
+		// Events recorded at the same time must not overwrite each other.
+		{Time: base, Action: influxdb.AuditActionUpdate, ResourceType: "buckets", OrgID: orgID},
+		{Time: base.Add(time.Minute), Action: influxdb.AuditActionDelete, ResourceType: "labels"},
+		{Time: base.Add(2 * time.Minute), Action: influxdb.AuditActionWrite, ResourceType: "write", OrgID: orgID},
+	}
+	for i, e := range events {
+		svc.IDGenerator = mock.NewIDGenerator(influxdb.ID(i+1).String(), t)
+		if err := svc.AppendAuditEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actions := func(es []*influxdb.AuditEvent) []string {
+		var as []string
+		for _, e := range es {
+			as = append(as, e.Action)
+		}
+		return as
+	}
+	equal := func(a, b []string) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		name   string
+		filter influxdb.AuditEventFilter
+		opt    []influxdb.FindOptions
+		want   []string
+		total  int
+	}{
+		{
+			name:  "all oldest first",
+			want:  []string{"create", "update", "delete", "write"},
+			total: 4,
+		},
+		{
+			name:   "by org",
+			filter: influxdb.AuditEventFilter{OrgID: &orgID},
+			want:   []string{"create", "update", "write"},
+			total:  3,
+		},
+		{
+			name:   "by resource type",
+			filter: influxdb.AuditEventFilter{ResourceType: "labels"},
+			want:   []string{"delete"},
+			total:  1,
+		},
+		{
+			name:   "by time",
+			filter: influxdb.AuditEventFilter{Since: base.Add(time.Second), Until: base.Add(2 * time.Minute)},
+			want:   []string{"delete"},
+			total:  1,
+		},
+		{
+			name:  "paged newest first",
+			opt:   []influxdb.FindOptions{{Descending: true, Offset: 1, Limit: 2}},
+			want:  []string{"delete", "update"},
+			total: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, total, err := svc.FindAuditEvents(ctx, tt.filter, tt.opt...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := actions(es); !equal(got, tt.want) || total != tt.total {
+				t.Fatalf("got %v (%d), want %v (%d)", got, total, tt.want, tt.total)
+			}
+		})
+	}
+
+	n, err := svc.DeleteAuditEventsBefore(ctx, base.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 events deleted, got %d", n)
+	}
+	es, _, err := svc.FindAuditEvents(ctx, influxdb.AuditEventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(es); !equal(got, []string{"delete", "write"}) {
+		t.Fatalf("unexpected events after delete: %v", got)
+	}
+}
@@ -1,20 +1,64 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	influxdb "github.com/influxdata/influxdb"
+	"go.uber.org/zap"
 )
 
 var (
 	authBucket = []byte("authorizationsv1")
-	authIndex  = []byte("authorizationindexv1")
+	// authIndex indexed authorizations by their token before tokens were
+	// hashed. It is emptied by the migration in initializeAuths.
+	authIndex = []byte("authorizationindexv1")
+	// authPrefixIndex indexes authorizations by the prefix of their token
+	// followed by their ID.
+	authPrefixIndex = []byte("authorizationprefixindexv1")
 )
 
 var _ influxdb.AuthorizationService = (*Service)(nil)
 
+// storedAuthorization is an authorization as it is stored: its token is
+// replaced by a salted hash and the prefix it is indexed by.
+type storedAuthorization struct {
+	influxdb.Authorization
+	TokenHash   string `json:"tokenHash,omitempty"`
+	TokenPrefix string `json:"tokenPrefix,omitempty"`
+
+	// The token replaced by the last rotation is accepted until
+	// PreviousTokenExpiresAt.
+	PreviousTokenHash      string     `json:"previousTokenHash,omitempty"`
+	PreviousTokenPrefix    string     `json:"previousTokenPrefix,omitempty"`
+	PreviousTokenExpiresAt *time.Time `json:"previousTokenExpiresAt,omitempty"`
+}
+
+// tokenPrefixes returns the prefixes the authorization is indexed by.
+func (a *storedAuthorization) tokenPrefixes() []string {
+	var prefixes []string
+	if a.TokenHash != "" {
+		prefixes = append(prefixes, a.TokenPrefix)
+	}
+	if a.PreviousTokenHash != "" && a.PreviousTokenPrefix != a.TokenPrefix {
+		prefixes = append(prefixes, a.PreviousTokenPrefix)
+	}
+	return prefixes
+}
+
+// matchToken reports whether token is the token of the authorization, or
+// the token it replaced if that is still accepted at now.
+func (a *storedAuthorization) matchToken(token string, now time.Time) bool {
+	if influxdb.CompareTokenHash(a.TokenHash, token) {
+		return true
+	}
+	return a.PreviousTokenExpiresAt != nil && now.Before(*a.PreviousTokenExpiresAt) &&
+		influxdb.CompareTokenHash(a.PreviousTokenHash, token)
+}
+
 func (s *Service) initializeAuths(ctx context.Context, tx Tx) error {
 	if _, err := tx.Bucket(authBucket); err != nil {
 		return err
@@ -22,6 +66,67 @@ func (s *Service) initializeAuths(ctx context.Context, tx Tx) error {
 	if _, err := authIndexBucket(tx); err != nil {
 		return err
 	}
+	if _, err := authPrefixIndexBucket(tx); err != nil {
+		return err
+	}
+	return s.hashAuthorizationTokens(ctx, tx)
+}
+
+// hashAuthorizationTokens migrates authorizations stored with their token
+// in the clear: it replaces the token with its hash, moves the index entry
+// to the prefix index, and empties the old token index. It does nothing
+// once every token is hashed.
+func (s *Service) hashAuthorizationTokens(ctx context.Context, tx Tx) error {
+	b, err := tx.Bucket(authBucket)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var plain []*storedAuthorization
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		a := &storedAuthorization{}
+		if err := decodeAuthorization(v, a); err != nil {
+			return err
+		}
+		if a.Token != "" {
+			plain = append(plain, a)
+		}
+	}
+
+	for _, a := range plain {
+		if err := s.putAuthorization(ctx, tx, &a.Authorization); err != nil {
+			return err
+		}
+	}
+
+	idx, err := authIndexBucket(tx)
+	if err != nil {
+		return err
+	}
+
+	cur, err = idx.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	for k, _ := cur.First(); k != nil; k, _ = cur.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		if err := idx.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	if len(plain) > 0 {
+		s.Logger.Info("Hashed authorization tokens", zap.Int("count", len(plain)))
+	}
 	return nil
 }
 
@@ -46,6 +151,14 @@ func (s *Service) FindAuthorizationByID(ctx context.Context, id influxdb.ID) (*i
 }
 
 func (s *Service) findAuthorizationByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.Authorization, error) {
+	a, err := s.findStoredAuthorization(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &a.Authorization, nil
+}
+
+func (s *Service) findStoredAuthorization(ctx context.Context, tx Tx, id influxdb.ID) (*storedAuthorization, error) {
 	encodedID, err := id.Encode()
 	if err != nil {
 		return nil, &influxdb.Error{
@@ -71,7 +184,7 @@ func (s *Service) findAuthorizationByID(ctx context.Context, tx Tx, id influxdb.
 		return nil, err
 	}
 
-	a := &influxdb.Authorization{}
+	a := &storedAuthorization{}
 	if err := decodeAuthorization(v, a); err != nil {
 		return nil, &influxdb.Error{
 			Code: influxdb.EInvalid,
@@ -104,49 +217,72 @@ func (s *Service) FindAuthorizationByToken(ctx context.Context, n string) (*infl
 }
 
 func (s *Service) findAuthorizationByToken(ctx context.Context, tx Tx, n string) (*influxdb.Authorization, error) {
-	idx, err := authIndexBucket(tx)
+	notFound := &influxdb.Error{
+		Code: influxdb.ENotFound,
+		Msg:  "authorization not found",
+	}
+	if n == "" {
+		return nil, notFound
+	}
+
+	idx, err := authPrefixIndexBucket(tx)
 	if err != nil {
 		return nil, err
 	}
 
-	a, err := idx.Get(authIndexKey(n))
-	if IsNotFound(err) {
-		return nil, &influxdb.Error{
-			Code: influxdb.ENotFound,
-			Msg:  "authorization not found",
-		}
+	cur, err := idx.Cursor()
+	if err != nil {
+		return nil, err
 	}
 
-	var id influxdb.ID
-	if err := id.Decode(a); err != nil {
-		return nil, &influxdb.Error{
-			Code: influxdb.EInvalid,
-			Err:  err,
+	// Tokens shorter than the prefix length are their own prefix, so
+	// only the keys of exactly this prefix and an ID are candidates.
+	prefix := []byte(influxdb.TokenPrefix(n))
+	for k, v := cur.Seek(prefix); bytes.HasPrefix(k, prefix); k, v = cur.Next() {
+		if len(k) != len(prefix)+influxdb.IDLength {
+			continue
+		}
+
+		var id influxdb.ID
+		if err := id.Decode(v); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Err:  err,
+			}
+		}
+
+		a, err := s.findStoredAuthorization(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if a.matchToken(n, s.time()) {
+			return &a.Authorization, nil
 		}
 	}
-	return s.findAuthorizationByID(ctx, tx, id)
+
+	return nil, notFound
 }
 
-func filterAuthorizationsFn(filter influxdb.AuthorizationFilter) func(a *influxdb.Authorization) bool {
+func filterAuthorizationsFn(filter influxdb.AuthorizationFilter, now time.Time) func(a *storedAuthorization) bool {
 	if filter.ID != nil {
-		return func(a *influxdb.Authorization) bool {
+		return func(a *storedAuthorization) bool {
 			return a.ID == *filter.ID
 		}
 	}
 
 	if filter.Token != nil {
-		return func(a *influxdb.Authorization) bool {
-			return a.Token == *filter.Token
+		return func(a *storedAuthorization) bool {
+			return a.matchToken(*filter.Token, now)
 		}
 	}
 
 	if filter.UserID != nil {
-		return func(a *influxdb.Authorization) bool {
+		return func(a *storedAuthorization) bool {
 			return a.UserID == *filter.UserID
 		}
 	}
 
-	return func(a *influxdb.Authorization) bool { return true }
+	return func(a *storedAuthorization) bool { return true }
 }
 
 // FindAuthorizations retrives all authorizations that match an arbitrary authorization filter.
@@ -205,10 +341,10 @@ func (s *Service) findAuthorizations(ctx context.Context, tx Tx, f influxdb.Auth
 	}
 
 	as := []*influxdb.Authorization{}
-	filterFn := filterAuthorizationsFn(f)
-	err := s.forEachAuthorization(ctx, tx, func(a *influxdb.Authorization) bool {
+	filterFn := filterAuthorizationsFn(f, s.time())
+	err := s.forEachAuthorization(ctx, tx, func(a *storedAuthorization) bool {
 		if filterFn(a) {
-			as = append(as, a)
+			as = append(as, &a.Authorization)
 		}
 		return true
 	})
@@ -220,6 +356,7 @@ func (s *Service) findAuthorizations(ctx context.Context, tx Tx, f influxdb.Auth
 }
 
 // CreateAuthorization creates a influxdb authorization and sets b.ID, and b.UserID if not provided.
+// Only a hash of the token is stored, so a.Token is the only time the token can be read.
 func (s *Service) CreateAuthorization(ctx context.Context, a *influxdb.Authorization) error {
 	return s.kv.Update(ctx, func(tx Tx) error {
 		return s.createAuthorization(ctx, tx, a)
@@ -241,10 +378,6 @@ func (s *Service) createAuthorization(ctx context.Context, tx Tx, a *influxdb.Au
 		return influxdb.ErrUnableToCreateToken
 	}
 
-	if err := s.uniqueAuthToken(ctx, tx, a); err != nil {
-		return err
-	}
-
 	if a.Token == "" {
 		token, err := s.TokenGenerator.Token()
 		if err != nil {
@@ -255,6 +388,10 @@ func (s *Service) createAuthorization(ctx context.Context, tx Tx, a *influxdb.Au
 		a.Token = token
 	}
 
+	if err := s.uniqueAuthToken(ctx, tx, a); err != nil {
+		return err
+	}
+
 	a.ID = s.IDGenerator.ID()
 
 	if err := s.putAuthorization(ctx, tx, a); err != nil {
@@ -265,13 +402,14 @@ func (s *Service) createAuthorization(ctx context.Context, tx Tx, a *influxdb.Au
 }
 
 // PutAuthorization will put a authorization without setting an ID.
+// The token of a is stored hashed; if it is empty the stored hash is kept.
 func (s *Service) PutAuthorization(ctx context.Context, a *influxdb.Authorization) error {
 	return s.kv.Update(ctx, func(tx Tx) error {
 		return s.putAuthorization(ctx, tx, a)
 	})
 }
 
-func encodeAuthorization(a *influxdb.Authorization) ([]byte, error) {
+func encodeAuthorization(a *storedAuthorization) ([]byte, error) {
 	switch a.Status {
 	case influxdb.Active, influxdb.Inactive:
 	case "":
@@ -287,6 +425,38 @@ func encodeAuthorization(a *influxdb.Authorization) ([]byte, error) {
 }
 
 func (s *Service) putAuthorization(ctx context.Context, tx Tx, a *influxdb.Authorization) error {
+	if a.Status == "" {
+		a.Status = influxdb.Active
+	}
+
+	prev, err := s.findStoredAuthorization(ctx, tx, a.ID)
+	if err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return err
+	}
+
+	sa := &storedAuthorization{Authorization: *a}
+	sa.Token = ""
+	if a.Token != "" {
+		hash, err := influxdb.HashToken(a.Token)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		sa.TokenHash, sa.TokenPrefix = hash, influxdb.TokenPrefix(a.Token)
+	} else if prev != nil {
+		sa.TokenHash, sa.TokenPrefix = prev.TokenHash, prev.TokenPrefix
+		sa.PreviousTokenHash, sa.PreviousTokenPrefix = prev.PreviousTokenHash, prev.PreviousTokenPrefix
+		sa.PreviousTokenExpiresAt = prev.PreviousTokenExpiresAt
+	}
+
+	return s.putStoredAuthorization(ctx, tx, sa, prev)
+}
+
+// putStoredAuthorization stores a in place of prev, which is nil if a is new,
+// and indexes it by the prefixes of its tokens.
+func (s *Service) putStoredAuthorization(ctx context.Context, tx Tx, a, prev *storedAuthorization) error {
 	v, err := encodeAuthorization(a)
 	if err != nil {
 		return &influxdb.Error{
@@ -303,15 +473,32 @@ func (s *Service) putAuthorization(ctx context.Context, tx Tx, a *influxdb.Autho
 		}
 	}
 
-	idx, err := authIndexBucket(tx)
+	idx, err := authPrefixIndexBucket(tx)
 	if err != nil {
 		return err
 	}
 
-	if err := idx.Put(authIndexKey(a.Token), encodedID); err != nil {
-		return &influxdb.Error{
-			Code: influxdb.EInternal,
-			Err:  err,
+	prefixes := a.tokenPrefixes()
+	if prev != nil {
+		for _, p := range prev.tokenPrefixes() {
+			if containsString(prefixes, p) {
+				continue
+			}
+			if err := idx.Delete(authPrefixIndexKey(p, encodedID)); err != nil {
+				return &influxdb.Error{
+					Code: influxdb.EInternal,
+					Err:  err,
+				}
+			}
+		}
+	}
+
+	for _, p := range prefixes {
+		if err := idx.Put(authPrefixIndexKey(p, encodedID), encodedID); err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
 		}
 	}
 
@@ -329,11 +516,22 @@ func (s *Service) putAuthorization(ctx context.Context, tx Tx, a *influxdb.Autho
 	return nil
 }
 
-func authIndexKey(n string) []byte {
-	return []byte(n)
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func authPrefixIndexKey(prefix string, encodedID []byte) []byte {
+	k := make([]byte, 0, len(prefix)+len(encodedID))
+	k = append(k, prefix...)
+	return append(k, encodedID...)
 }
 
-func decodeAuthorization(b []byte, a *influxdb.Authorization) error {
+func decodeAuthorization(b []byte, a *storedAuthorization) error {
 	if err := json.Unmarshal(b, a); err != nil {
 		return err
 	}
@@ -344,7 +542,7 @@ func decodeAuthorization(b []byte, a *influxdb.Authorization) error {
 }
 
 // forEachAuthorization will iterate through all authorizations while fn returns true.
-func (s *Service) forEachAuthorization(ctx context.Context, tx Tx, fn func(*influxdb.Authorization) bool) error {
+func (s *Service) forEachAuthorization(ctx context.Context, tx Tx, fn func(*storedAuthorization) bool) error {
 	b, err := tx.Bucket(authBucket)
 	if err != nil {
 		return err
@@ -356,7 +554,7 @@ func (s *Service) forEachAuthorization(ctx context.Context, tx Tx, fn func(*infl
 	}
 
 	for k, v := cur.First(); k != nil; k, v = cur.Next() {
-		a := &influxdb.Authorization{}
+		a := &storedAuthorization{}
 
 		if err := decodeAuthorization(v, a); err != nil {
 			return err
@@ -377,25 +575,28 @@ func (s *Service) DeleteAuthorization(ctx context.Context, id influxdb.ID) error
 }
 
 func (s *Service) deleteAuthorization(ctx context.Context, tx Tx, id influxdb.ID) error {
-	a, err := s.findAuthorizationByID(ctx, tx, id)
+	a, err := s.findStoredAuthorization(ctx, tx, id)
 	if err != nil {
 		return err
 	}
 
-	idx, err := authIndexBucket(tx)
+	encodedID, err := id.Encode()
 	if err != nil {
-		return err
-	}
-
-	if err := idx.Delete(authIndexKey(a.Token)); err != nil {
 		return &influxdb.Error{
 			Err: err,
 		}
 	}
-	encodedID, err := id.Encode()
+
+	idx, err := authPrefixIndexBucket(tx)
 	if err != nil {
-		return &influxdb.Error{
-			Err: err,
+		return err
+	}
+
+	for _, p := range a.tokenPrefixes() {
+		if err := idx.Delete(authPrefixIndexKey(p, encodedID)); err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
 		}
 	}
 
@@ -409,7 +610,11 @@ func (s *Service) deleteAuthorization(ctx context.Context, tx Tx, id influxdb.ID
 			Err: err,
 		}
 	}
-	return nil
+
+	return s.deleteRoleMappings(ctx, tx, influxdb.RoleMappingFilter{
+		SubjectType: influxdb.AuthorizationRoleSubject,
+		SubjectID:   &id,
+	})
 }
 
 // SetAuthorizationStatus updates the status of the authorization. Useful
@@ -421,7 +626,7 @@ func (s *Service) SetAuthorizationStatus(ctx context.Context, id influxdb.ID, st
 }
 
 func (s *Service) updateAuthorization(ctx context.Context, tx Tx, id influxdb.ID, status influxdb.Status) error {
-	a, err := s.findAuthorizationByID(ctx, tx, id)
+	a, err := s.findStoredAuthorization(ctx, tx, id)
 	if err != nil {
 		return err
 	}
@@ -454,6 +659,82 @@ func (s *Service) updateAuthorization(ctx context.Context, tx Tx, id influxdb.ID
 	return nil
 }
 
+// UpdateAuthorization updates the status, description, expiry or last use
+// of the authorization.
+func (s *Service) UpdateAuthorization(ctx context.Context, id influxdb.ID, upd *influxdb.AuthorizationUpdate) (*influxdb.Authorization, error) {
+	var a *influxdb.Authorization
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		prev, err := s.findStoredAuthorization(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		sa := *prev
+		if err := upd.Apply(&sa.Authorization); err != nil {
+			return err
+		}
+
+		if err := s.putStoredAuthorization(ctx, tx, &sa, prev); err != nil {
+			return err
+		}
+
+		a = &sa.Authorization
+		return nil
+	})
+
+	return a, err
+}
+
+// RotateAuthorization replaces the token of the authorization with a new
+// one, returned in the Token of the result. The old token keeps working
+// for the grace period.
+func (s *Service) RotateAuthorization(ctx context.Context, id influxdb.ID, grace time.Duration) (*influxdb.Authorization, error) {
+	var a *influxdb.Authorization
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		prev, err := s.findStoredAuthorization(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		token, err := s.TokenGenerator.Token()
+		if err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		if err := s.uniqueAuthToken(ctx, tx, &influxdb.Authorization{Token: token}); err != nil {
+			return err
+		}
+
+		hash, err := influxdb.HashToken(token)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+
+		sa := *prev
+		sa.TokenHash, sa.TokenPrefix = hash, influxdb.TokenPrefix(token)
+		sa.PreviousTokenHash, sa.PreviousTokenPrefix, sa.PreviousTokenExpiresAt = "", "", nil
+		if grace > 0 {
+			expiresAt := s.time().Add(grace)
+			sa.PreviousTokenHash, sa.PreviousTokenPrefix = prev.TokenHash, prev.TokenPrefix
+			sa.PreviousTokenExpiresAt = &expiresAt
+		}
+
+		if err := s.putStoredAuthorization(ctx, tx, &sa, prev); err != nil {
+			return err
+		}
+
+		a = &sa.Authorization
+		a.Token = token
+		return nil
+	})
+
+	return a, err
+}
+
 func authIndexBucket(tx Tx) (Bucket, error) {
 	b, err := tx.Bucket([]byte(authIndex))
 	if err != nil {
@@ -463,6 +744,15 @@ func authIndexBucket(tx Tx) (Bucket, error) {
 	return b, nil
 }
 
+func authPrefixIndexBucket(tx Tx) (Bucket, error) {
+	b, err := tx.Bucket(authPrefixIndex)
+	if err != nil {
+		return nil, UnexpectedAuthIndexError(err)
+	}
+
+	return b, nil
+}
+
 // UnexpectedAuthIndexError is used when the error comes from an internal system.
 func UnexpectedAuthIndexError(err error) *influxdb.Error {
 	return &influxdb.Error{
@@ -473,12 +763,15 @@ func UnexpectedAuthIndexError(err error) *influxdb.Error {
 }
 
 func (s *Service) uniqueAuthToken(ctx context.Context, tx Tx, a *influxdb.Authorization) error {
-	err := s.unique(ctx, tx, authIndex, authIndexKey(a.Token))
-	if err == NotUniqueError {
+	_, err := s.findAuthorizationByToken(ctx, tx, a.Token)
+	if err == nil {
 		// by returning a generic error we are trying to hide when
 		// a token is non-unique.
 		return influxdb.ErrUnableToCreateToken
 	}
+	if influxdb.ErrorCode(err) == influxdb.ENotFound {
+		return nil
+	}
 	// otherwise, this is some sort of internal server error and we
 	// should provide some debugging information.
 	return err
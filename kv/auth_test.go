@@ -1,7 +1,9 @@
 package kv_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"testing"
 
 	"github.com/influxdata/influxdb"
@@ -91,3 +93,93 @@ func initAuthorizationService(s kv.Store, f influxdbtesting.AuthorizationFields,
 		}
 	}
 }
+
+func TestService_HashAuthorizationTokens(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+
+	ctx := context.Background()
+	svc := kv.NewService(s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing authorization service: %v", err)
+	}
+
+	// Store an authorization as it was stored before tokens were hashed.
+	legacy := &influxdb.Authorization{
+		ID:     influxdbtesting.MustIDBase16("020f755c3c082000"),
+		OrgID:  influxdbtesting.MustIDBase16("41a9f7288d4e2d64"),
+		UserID: influxdbtesting.MustIDBase16("020f755c3c082000"),
+		Token:  "legacy-token",
+		Status: influxdb.Active,
+	}
+	err = s.Update(ctx, func(tx kv.Tx) error {
+		v, err := json.Marshal(legacy)
+		if err != nil {
+			return err
+		}
+		id, _ := legacy.ID.Encode()
+		b, err := tx.Bucket([]byte("authorizationsv1"))
+		if err != nil {
+			return err
+		}
+		if err := b.Put(id, v); err != nil {
+			return err
+		}
+		idx, err := tx.Bucket([]byte("authorizationindexv1"))
+		if err != nil {
+			return err
+		}
+		return idx.Put([]byte(legacy.Token), id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrating twice leaves the authorization as migrating once does.
+	for i := 0; i < 2; i++ {
+		if err := svc.Initialize(ctx); err != nil {
+			t.Fatalf("error initializing authorization service: %v", err)
+		}
+
+		a, err := svc.FindAuthorizationByToken(ctx, "legacy-token")
+		if err != nil {
+			t.Fatalf("failed to find migrated authorization by token: %v", err)
+		}
+		if a.ID != legacy.ID || a.Token != "" {
+			t.Fatalf("unexpected migrated authorization: %+v", a)
+		}
+		if _, err := svc.FindAuthorizationByToken(ctx, "legacy-toke"); influxdb.ErrorCode(err) != influxdb.ENotFound {
+			t.Fatalf("expected not found for a prefix of the token, got %v", err)
+		}
+	}
+
+	err = s.View(ctx, func(tx kv.Tx) error {
+		id, _ := legacy.ID.Encode()
+		b, err := tx.Bucket([]byte("authorizationsv1"))
+		if err != nil {
+			return err
+		}
+		v, err := b.Get(id)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(v, []byte(legacy.Token)) {
+			t.Errorf("token stored in the clear: %s", v)
+		}
+
+		idx, err := tx.Bucket([]byte("authorizationindexv1"))
+		if err != nil {
+			return err
+		}
+		if _, err := idx.Get([]byte(legacy.Token)); !kv.IsNotFound(err) {
+			t.Errorf("expected token index entry to be removed, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
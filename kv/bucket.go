@@ -384,6 +384,10 @@ func (s *Service) CreateBucket(ctx context.Context, b *influxdb.Bucket) error {
 }
 
 func (s *Service) createBucket(ctx context.Context, tx Tx, b *influxdb.Bucket) error {
+	if err := influxdb.ValidateDefaultTags(b.DefaultTags); err != nil {
+		return err
+	}
+
 	if b.OrganizationID.Valid() {
 		span, ctx := tracing.StartSpanFromContext(ctx)
 		defer span.Finish()
@@ -621,6 +625,13 @@ func (s *Service) updateBucket(ctx context.Context, tx Tx, id influxdb.ID, upd i
 		b.RetentionPeriod = *upd.RetentionPeriod
 	}
 
+	if upd.DefaultTags != nil {
+		if err := influxdb.ValidateDefaultTags(*upd.DefaultTags); err != nil {
+			return nil, err
+		}
+		b.DefaultTags = *upd.DefaultTags
+	}
+
 	if upd.Name != nil {
 		b0, err := s.findBucketByName(ctx, tx, b.OrganizationID, *upd.Name)
 		if err == nil && b0.ID != id {
@@ -0,0 +1,450 @@
+package kv
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	influxdb "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/totp"
+)
+
+var (
+	mfaBucket = []byte("mfav1")
+)
+
+var _ influxdb.MFAService = (*Service)(nil)
+
+const (
+	// mfaIssuer is the issuer of TOTP secrets shown by authenticator apps.
+	mfaIssuer = "InfluxDB"
+
+	// recoveryCodeCount is the number of recovery codes generated.
+	recoveryCodeCount = 10
+
+	// mfaMaxFailures is the number of invalid codes after which codes are
+	// not verified for mfaLockout.
+	mfaMaxFailures = 5
+	mfaLockout     = 5 * time.Minute
+)
+
+// mfa is the multi-factor authentication of a user as stored. Recovery codes
+// are stored hashed.
+type mfa struct {
+	UserID        influxdb.ID `json:"userID"`
+	Secret        string      `json:"secret,omitempty"`
+	PendingSecret string      `json:"pendingSecret,omitempty"`
+	EnrolledAt    time.Time   `json:"enrolledAt,omitempty"`
+	RecoveryCodes []string    `json:"recoveryCodes,omitempty"`
+	// LastStep is the time step of the last code used, so that codes
+	// cannot be used twice.
+	LastStep    int64     `json:"lastStep,omitempty"`
+	Failures    int       `json:"failures,omitempty"`
+	LockedUntil time.Time `json:"lockedUntil,omitempty"`
+}
+
+func (m *mfa) enrolled() bool {
+	return m.Secret != ""
+}
+
+func (m *mfa) status() *influxdb.MFA {
+	status := &influxdb.MFA{
+		UserID:        m.UserID,
+		Enrolled:      m.enrolled(),
+		RecoveryCodes: len(m.RecoveryCodes),
+	}
+	if m.enrolled() {
+		enrolledAt := m.EnrolledAt
+		status.EnrolledAt = &enrolledAt
+	}
+	return status
+}
+
+func (s *Service) initializeMFA(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(mfaBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindMFA returns the multi-factor authentication status of a user.
+func (s *Service) FindMFA(ctx context.Context, userID influxdb.ID) (*influxdb.MFA, error) {
+	var status *influxdb.MFA
+	err := s.kv.View(ctx, func(tx Tx) error {
+		m, err := s.findMFA(ctx, tx, userID)
+		if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			status = &influxdb.MFA{UserID: userID}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		status = m.status()
+		return nil
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindMFA,
+			Err: err,
+		}
+	}
+	return status, nil
+}
+
+func (s *Service) findMFA(ctx context.Context, tx Tx, userID influxdb.ID) (*mfa, error) {
+	k, err := userID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(mfaBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(k)
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  influxdb.ErrMFANotEnrolled,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mfa{}
+	if err := json.Unmarshal(v, m); err != nil {
+		return nil, &influxdb.Error{
+			Err: err,
+		}
+	}
+	return m, nil
+}
+
+func (s *Service) putMFA(ctx context.Context, tx Tx, m *mfa) error {
+	k, err := m.UserID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	v, err := json.Marshal(m)
+	if err != nil {
+		return &influxdb.Error{
+			Err: err,
+		}
+	}
+
+	b, err := tx.Bucket(mfaBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(k, v)
+}
+
+// EnrollTOTP generates a new TOTP secret for a user. It replaces the current
+// secret of the user once confirmed.
+func (s *Service) EnrollTOTP(ctx context.Context, userID influxdb.ID) (*influxdb.TOTPEnrollment, error) {
+	var e *influxdb.TOTPEnrollment
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		u, err := s.findUserByID(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		m, err := s.findMFA(ctx, tx, userID)
+		if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			m, err = &mfa{UserID: userID}, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		m.PendingSecret = secret
+		if err := s.putMFA(ctx, tx, m); err != nil {
+			return err
+		}
+
+		e = &influxdb.TOTPEnrollment{
+			Secret: secret,
+			URL:    totp.URL(mfaIssuer, u.Name, secret),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpEnrollTOTP,
+			Err: err,
+		}
+	}
+	return e, nil
+}
+
+// ConfirmTOTP enrolls a user with the secret generated by EnrollTOTP if code
+// is valid, and returns new recovery codes.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID influxdb.ID, code string) ([]string, error) {
+	var codes []string
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		m, err := s.findMFA(ctx, tx, userID)
+		if err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+			return err
+		}
+		if m == nil || m.PendingSecret == "" {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "multi-factor authentication enrollment has not been started",
+			}
+		}
+
+		now := s.time()
+		step, ok := totp.Validate(m.PendingSecret, strings.TrimSpace(code), now)
+		if !ok {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  influxdb.ErrMFAInvalidCode,
+			}
+		}
+
+		codes, err = m.generateRecoveryCodes()
+		if err != nil {
+			return err
+		}
+		m.Secret, m.PendingSecret = m.PendingSecret, ""
+		m.EnrolledAt = now
+		m.LastStep = step
+		m.Failures, m.LockedUntil = 0, time.Time{}
+		return s.putMFA(ctx, tx, m)
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpConfirmTOTP,
+			Err: err,
+		}
+	}
+	return codes, nil
+}
+
+// VerifyMFA returns an error if code is neither a valid TOTP code nor an
+// unused recovery code of an enrolled user. After too many invalid codes,
+// codes are not verified for a while.
+func (s *Service) VerifyMFA(ctx context.Context, userID influxdb.ID, code string) error {
+	var verr error
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		m, err := s.findMFA(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if !m.enrolled() {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  influxdb.ErrMFANotEnrolled,
+			}
+		}
+
+		// The failures counted by verifyMFA are stored even though the code
+		// is invalid.
+		verr = s.verifyMFA(m, code)
+		return s.putMFA(ctx, tx, m)
+	})
+	if err == nil {
+		err = verr
+	}
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpVerifyMFA,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+func (s *Service) verifyMFA(m *mfa, code string) error {
+	now := s.time()
+	if now.Before(m.LockedUntil) {
+		return &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  influxdb.ErrMFALocked,
+		}
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(m.Secret, code, now); ok && step > m.LastStep {
+		m.LastStep = step
+		m.Failures = 0
+		return nil
+	}
+
+	h := hashRecoveryCode(code)
+	for i, c := range m.RecoveryCodes {
+		if c == h {
+			m.RecoveryCodes = append(m.RecoveryCodes[:i], m.RecoveryCodes[i+1:]...)
+			m.Failures = 0
+			return nil
+		}
+	}
+
+	m.Failures++
+	if m.Failures >= mfaMaxFailures {
+		m.Failures = 0
+		m.LockedUntil = now.Add(mfaLockout)
+	}
+	return &influxdb.Error{
+		Code: influxdb.EUnauthorized,
+		Msg:  influxdb.ErrMFAInvalidCode,
+	}
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of an enrolled user.
+func (s *Service) GenerateRecoveryCodes(ctx context.Context, userID influxdb.ID) ([]string, error) {
+	var codes []string
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		m, err := s.findMFA(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if !m.enrolled() {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  influxdb.ErrMFANotEnrolled,
+			}
+		}
+
+		codes, err = m.generateRecoveryCodes()
+		if err != nil {
+			return err
+		}
+		return s.putMFA(ctx, tx, m)
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpGenerateRecoveryCodes,
+			Err: err,
+		}
+	}
+	return codes, nil
+}
+
+// DeleteMFA removes the multi-factor authentication of a user.
+func (s *Service) DeleteMFA(ctx context.Context, userID influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findMFA(ctx, tx, userID); err != nil {
+			return err
+		}
+		return s.deleteMFA(ctx, tx, userID)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteMFA,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+func (s *Service) deleteMFA(ctx context.Context, tx Tx, userID influxdb.ID) error {
+	k, err := userID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(mfaBucket)
+	if err != nil {
+		return err
+	}
+	if err := b.Delete(k); err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// mfaPermissions removes the permissions of the organizations requiring
+// multi-factor authentication from ps, if userID is not enrolled.
+func (s *Service) mfaPermissions(ctx context.Context, tx Tx, userID influxdb.ID, ps []influxdb.Permission) ([]influxdb.Permission, error) {
+	m, err := s.findMFA(ctx, tx, userID)
+	if err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, err
+	}
+	if m != nil && m.enrolled() {
+		return ps, nil
+	}
+
+	required := make(map[influxdb.ID]bool)
+	requiresMFA := func(orgID influxdb.ID) (bool, error) {
+		if r, ok := required[orgID]; ok {
+			return r, nil
+		}
+		o, err := s.findOrganizationByID(ctx, tx, orgID)
+		if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		required[orgID] = o.RequireMFA
+		return o.RequireMFA, nil
+	}
+
+	filtered := make([]influxdb.Permission, 0, len(ps))
+	for _, p := range ps {
+		orgID := p.Resource.OrgID
+		if orgID == nil && p.Resource.Type == influxdb.OrgsResourceType {
+			orgID = p.Resource.ID
+		}
+		if orgID != nil {
+			r, err := requiresMFA(*orgID)
+			if err != nil {
+				return nil, err
+			}
+			if r {
+				continue
+			}
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered, nil
+}
+
+// generateRecoveryCodes replaces the recovery codes of m, and returns them.
+func (m *mfa) generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			return nil, err
+		}
+		c := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		c = c[:4] + "-" + c[4:]
+		codes = append(codes, c)
+		hashes = append(hashes, hashRecoveryCode(c))
+	}
+	m.RecoveryCodes = hashes
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring its case and dashes.
+// Recovery codes are random, so they don't need to be hashed with bcrypt.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.Replace(code, "-", "", -1))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
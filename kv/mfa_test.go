@@ -0,0 +1,230 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/totp"
+)
+
+func TestBoltMFAService(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+	testMFAService(s, t)
+}
+
+func TestInmemMFAService(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+	testMFAService(s, t)
+}
+
+func testMFAService(s kv.Store, t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1500000000, 0)
+	svc := kv.NewService(s)
+	svc.WithTime(func() time.Time { return now })
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing mfa service: %v", err)
+	}
+
+	user := &influxdb.User{Name: "alice"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.VerifyMFA(ctx, user.ID, "123456"); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not enrolled error, got %v", err)
+	}
+
+	e, err := svc.EnrollTOTP(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := svc.FindMFA(ctx, user.ID); err != nil || m.Enrolled {
+		t.Fatalf("expected user not to be enrolled before confirming: %+v, %v", m, err)
+	}
+	if _, err := svc.ConfirmTOTP(ctx, user.ID, "000000"); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+
+	code, err := totp.Code(e.Secret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryCodes, err := svc.ConfirmTOTP(ctx, user.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := svc.FindMFA(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Enrolled || m.RecoveryCodes != len(recoveryCodes) {
+		t.Fatalf("unexpected mfa status: %+v", m)
+	}
+
+	// Codes cannot be used twice.
+	if err := svc.VerifyMFA(ctx, user.ID, code); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+		t.Fatalf("expected used code to be rejected, got %v", err)
+	}
+	now = now.Add(totp.Period * time.Second)
+	code, err = totp.Code(e.Secret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.VerifyMFA(ctx, user.ID, code); err != nil {
+		t.Fatal(err)
+	}
+
+	// Recovery codes can only be used once.
+	if err := svc.VerifyMFA(ctx, user.ID, recoveryCodes[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.VerifyMFA(ctx, user.ID, recoveryCodes[0]); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+		t.Fatalf("expected used recovery code to be rejected, got %v", err)
+	}
+	if m, err := svc.FindMFA(ctx, user.ID); err != nil || m.RecoveryCodes != len(recoveryCodes)-1 {
+		t.Fatalf("expected a recovery code to be used: %+v, %v", m, err)
+	}
+
+	// Too many invalid codes lock the user out for a while.
+	for i := 0; i < 4; i++ {
+		if err := svc.VerifyMFA(ctx, user.ID, "000000"); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			t.Fatalf("expected invalid code error, got %v", err)
+		}
+	}
+	if err := svc.VerifyMFA(ctx, user.ID, recoveryCodes[1]); influxdb.ErrorCode(err) != influxdb.EForbidden {
+		t.Fatalf("expected user to be locked out, got %v", err)
+	}
+	now = now.Add(10 * time.Minute)
+	if err := svc.VerifyMFA(ctx, user.ID, recoveryCodes[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := svc.GenerateRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.VerifyMFA(ctx, user.ID, recoveryCodes[2]); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+		t.Fatalf("expected replaced recovery code to be rejected, got %v", err)
+	}
+	if err := svc.VerifyMFA(ctx, user.ID, codes[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.DeleteMFA(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := svc.FindMFA(ctx, user.ID); err != nil || m.Enrolled {
+		t.Fatalf("expected user not to be enrolled: %+v, %v", m, err)
+	}
+	if err := svc.DeleteMFA(ctx, user.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not enrolled error, got %v", err)
+	}
+}
+
+func TestInmemMFARequiredByOrg(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+
+	ctx := context.Background()
+	svc := kv.NewService(s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing mfa service: %v", err)
+	}
+
+	org := &influxdb.Organization{Name: "acme"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	user := &influxdb.User{Name: "alice"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{
+		UserID:       user.ID,
+		UserType:     influxdb.Member,
+		ResourceType: influxdb.OrgsResourceType,
+		ResourceID:   org.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	readBuckets, err := influxdb.NewPermission(influxdb.ReadAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readMe := influxdb.Permission{
+		Action:   influxdb.ReadAction,
+		Resource: influxdb.Resource{Type: influxdb.UsersResourceType, ID: &user.ID},
+	}
+
+	// A role grants the member a permission its membership does not.
+	writeBuckets, err := influxdb.NewPermission(influxdb.WriteAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	role := &influxdb.Role{OrgID: org.ID, Name: "writer", Permissions: []influxdb.Permission{*writeBuckets}}
+	if err := svc.CreateRole(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateRoleMapping(ctx, &influxdb.RoleMapping{RoleID: role.ID, SubjectType: influxdb.UserRoleSubject, SubjectID: user.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	sess, err := svc.CreateSession(ctx, user.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := func() (bool, bool) {
+		t.Helper()
+		sn, err := svc.FindSession(ctx, sess.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sn.Allowed(*readBuckets) != sn.Allowed(*writeBuckets) {
+			t.Fatalf("expected the permissions of the role to follow the membership: %v", sn.Permissions)
+		}
+		return sn.Allowed(*readBuckets), sn.Allowed(readMe)
+	}
+
+	if org, me := allowed(); !org || !me {
+		t.Fatalf("expected member to be allowed before mfa is required: %v, %v", org, me)
+	}
+
+	requireMFA := true
+	if _, err := svc.UpdateOrganization(ctx, org.ID, influxdb.OrganizationUpdate{RequireMFA: &requireMFA}); err != nil {
+		t.Fatal(err)
+	}
+	if org, me := allowed(); org || !me {
+		t.Fatalf("expected member not enrolled to only be allowed to manage themselves: %v, %v", org, me)
+	}
+
+	e, err := svc.EnrollTOTP(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.Code(e.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ConfirmTOTP(ctx, user.ID, code); err != nil {
+		t.Fatal(err)
+	}
+	if org, me := allowed(); !org || !me {
+		t.Fatalf("expected enrolled member to be allowed: %v, %v", org, me)
+	}
+}
@@ -394,6 +394,10 @@ func (s *Service) updateOrganization(ctx context.Context, tx Tx, id influxdb.ID,
 		o.Name = *upd.Name
 	}
 
+	if upd.RequireMFA != nil {
+		o.RequireMFA = *upd.RequireMFA
+	}
+
 	if err := s.appendOrganizationEventToLog(ctx, tx, o.ID, organizationUpdatedEvent); err != nil {
 		return nil, &influxdb.Error{
 			Err: err,
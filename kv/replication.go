@@ -0,0 +1,237 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+
+	influxdb "github.com/influxdata/influxdb"
+)
+
+var (
+	replicationBucket = []byte("replicationsv1")
+)
+
+var _ influxdb.ReplicationService = (*Service)(nil)
+
+func (s *Service) initializeReplications(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(replicationBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindReplicationByID retrieves a replication by id.
+func (s *Service) FindReplicationByID(ctx context.Context, id influxdb.ID) (*influxdb.Replication, error) {
+	var r *influxdb.Replication
+	err := s.kv.View(ctx, func(tx Tx) error {
+		rep, err := s.findReplicationByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		r = rep
+		return nil
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindReplicationByID,
+			Err: err,
+		}
+	}
+	return r, nil
+}
+
+func (s *Service) findReplicationByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.Replication, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(replicationBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  influxdb.ErrReplicationNotFound,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalReplication(v)
+}
+
+// FindReplications retrieves all replications that match the filter.
+func (s *Service) FindReplications(ctx context.Context, filter influxdb.ReplicationFilter) ([]*influxdb.Replication, int, error) {
+	rs := []*influxdb.Replication{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return s.forEachReplication(ctx, tx, func(r *influxdb.Replication) bool {
+			if filter.OrgID != nil && r.OrgID != *filter.OrgID {
+				return true
+			}
+			if filter.SourceBucketID != nil && r.SourceBucketID != *filter.SourceBucketID {
+				return true
+			}
+			rs = append(rs, r)
+			return true
+		})
+	})
+	if err != nil {
+		return nil, 0, &influxdb.Error{
+			Op:  influxdb.OpFindReplications,
+			Err: err,
+		}
+	}
+	return rs, len(rs), nil
+}
+
+// CreateReplication creates a replication and sets r.ID.
+func (s *Service) CreateReplication(ctx context.Context, r *influxdb.Replication) error {
+	if r.MaxQueueSize == 0 {
+		r.MaxQueueSize = influxdb.DefaultReplicationMaxQueueSize
+	}
+	if err := r.Valid(); err != nil {
+		return err
+	}
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		r.ID = s.IDGenerator.ID()
+		return s.putReplication(ctx, tx, r)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateReplication,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// PutReplication will put a replication without setting an ID.
+func (s *Service) PutReplication(ctx context.Context, r *influxdb.Replication) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return s.putReplication(ctx, tx, r)
+	})
+}
+
+func (s *Service) putReplication(ctx context.Context, tx Tx, r *influxdb.Replication) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := r.ID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(replicationBucket)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(encodedID, v)
+}
+
+// forEachReplication will iterate through all replications while fn returns true.
+func (s *Service) forEachReplication(ctx context.Context, tx Tx, fn func(*influxdb.Replication) bool) error {
+	b, err := tx.Bucket(replicationBucket)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		r, err := unmarshalReplication(v)
+		if err != nil {
+			return err
+		}
+		if !fn(r) {
+			break
+		}
+	}
+	return nil
+}
+
+// UpdateReplication updates a replication according the parameters set on upd.
+func (s *Service) UpdateReplication(ctx context.Context, id influxdb.ID, upd influxdb.ReplicationUpdate) (*influxdb.Replication, error) {
+	var r *influxdb.Replication
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		rep, err := s.findReplicationByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := upd.Apply(rep); err != nil {
+			return err
+		}
+		if err := s.putReplication(ctx, tx, rep); err != nil {
+			return err
+		}
+		r = rep
+		return nil
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpUpdateReplication,
+			Err: err,
+		}
+	}
+	return r, nil
+}
+
+// DeleteReplication deletes a replication.
+func (s *Service) DeleteReplication(ctx context.Context, id influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findReplicationByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		encodedID, err := id.Encode()
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.Bucket(replicationBucket)
+		if err != nil {
+			return err
+		}
+		return b.Delete(encodedID)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteReplication,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// unmarshalReplication turns the stored byte slice in the kv into a *influxdb.Replication.
+func unmarshalReplication(v []byte) (*influxdb.Replication, error) {
+	r := &influxdb.Replication{}
+	if err := json.Unmarshal(v, r); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  "unable to unmarshal replication",
+			Err:  err,
+		}
+	}
+	return r, nil
+}
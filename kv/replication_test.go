@@ -0,0 +1,66 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestBoltReplicationService(t *testing.T) {
+	influxdbtesting.ReplicationService(initBoltReplicationService, t)
+}
+
+func TestInmemReplicationService(t *testing.T) {
+	influxdbtesting.ReplicationService(initInmemReplicationService, t)
+}
+
+func initBoltReplicationService(f influxdbtesting.ReplicationFields, t *testing.T) (influxdb.ReplicationService, string, func()) {
+	s, closeBolt, err := NewTestBoltStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, op, closeSvc := initReplicationService(s, f, t)
+	return svc, op, func() {
+		closeSvc()
+		closeBolt()
+	}
+}
+
+func initInmemReplicationService(f influxdbtesting.ReplicationFields, t *testing.T) (influxdb.ReplicationService, string, func()) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, op, closeSvc := initReplicationService(s, f, t)
+	return svc, op, func() {
+		closeSvc()
+		closeStore()
+	}
+}
+
+func initReplicationService(s kv.Store, f influxdbtesting.ReplicationFields, t *testing.T) (influxdb.ReplicationService, string, func()) {
+	svc := kv.NewService(s)
+	svc.IDGenerator = f.IDGenerator
+
+	ctx := context.Background()
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing replication service: %v", err)
+	}
+	for _, r := range f.Replications {
+		if err := svc.PutReplication(ctx, r); err != nil {
+			t.Fatalf("failed to populate replications: %v", err)
+		}
+	}
+	return svc, kv.OpPrefix, func() {
+		for _, r := range f.Replications {
+			if err := svc.DeleteReplication(ctx, r.ID); err != nil {
+				t.Logf("failed to remove replication: %v", err)
+			}
+		}
+	}
+}
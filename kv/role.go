@@ -0,0 +1,536 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	influxdb "github.com/influxdata/influxdb"
+)
+
+var (
+	roleBucket        = []byte("rolesv1")
+	roleIndexBucket   = []byte("roleindexv1")
+	roleMappingBucket = []byte("rolemappingsv1")
+)
+
+var _ influxdb.RoleService = (*Service)(nil)
+
+func (s *Service) initializeRoles(ctx context.Context, tx Tx) error {
+	for _, b := range [][]byte{roleBucket, roleIndexBucket, roleMappingBucket} {
+		if _, err := tx.Bucket(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roleIndexKey is a combination of the orgID and the role name.
+func roleIndexKey(r *influxdb.Role) ([]byte, error) {
+	orgID, err := r.OrgID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	return append(orgID, r.Name...), nil
+}
+
+// roleMappingKey is a combination of the subject and the role, so that the
+// roles of a subject are listed together.
+func roleMappingKey(roleID influxdb.ID, subjectType influxdb.RoleSubjectType, subjectID influxdb.ID) ([]byte, error) {
+	encodedRoleID, err := roleID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	encodedSubjectID, err := subjectID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	k := append([]byte(subjectType+"/"), encodedSubjectID...)
+	return append(k, encodedRoleID...), nil
+}
+
+// FindRoleByID retrieves a role by id.
+func (s *Service) FindRoleByID(ctx context.Context, id influxdb.ID) (*influxdb.Role, error) {
+	var r *influxdb.Role
+	err := s.kv.View(ctx, func(tx Tx) error {
+		role, err := s.findRoleByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		r = role
+		return nil
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindRoleByID,
+			Err: err,
+		}
+	}
+	return r, nil
+}
+
+func (s *Service) findRoleByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.Role, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(roleBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  influxdb.ErrRoleNotFound,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalRole(v)
+}
+
+// FindRoles retrieves all roles that match the filter.
+func (s *Service) FindRoles(ctx context.Context, filter influxdb.RoleFilter, opt ...influxdb.FindOptions) ([]*influxdb.Role, int, error) {
+	rs := []*influxdb.Role{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		if filter.ID != nil {
+			r, err := s.findRoleByID(ctx, tx, *filter.ID)
+			if err != nil {
+				return err
+			}
+			if (filter.OrgID == nil || r.OrgID == *filter.OrgID) && (filter.Name == nil || r.Name == *filter.Name) {
+				rs = append(rs, r)
+			}
+			return nil
+		}
+
+		return s.forEachRole(ctx, tx, func(r *influxdb.Role) bool {
+			if filter.OrgID != nil && r.OrgID != *filter.OrgID {
+				return true
+			}
+			if filter.Name != nil && r.Name != *filter.Name {
+				return true
+			}
+			rs = append(rs, r)
+			return true
+		})
+	})
+	if err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, 0, &influxdb.Error{
+			Op:  influxdb.OpFindRoles,
+			Err: err,
+		}
+	}
+
+	total := len(rs)
+	if len(opt) > 0 {
+		o := opt[0]
+		if o.Offset > 0 {
+			if o.Offset >= len(rs) {
+				rs = rs[:0]
+			} else {
+				rs = rs[o.Offset:]
+			}
+		}
+		if o.Limit > 0 && o.Limit < len(rs) {
+			rs = rs[:o.Limit]
+		}
+	}
+	return rs, total, nil
+}
+
+// CreateRole creates a role and sets r.ID.
+func (s *Service) CreateRole(ctx context.Context, r *influxdb.Role) error {
+	if err := r.Valid(); err != nil {
+		return err
+	}
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if err := s.uniqueRoleName(ctx, tx, r); err != nil {
+			return err
+		}
+		r.ID = s.IDGenerator.ID()
+		return s.putRole(ctx, tx, r)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateRole,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// PutRole will put a role without setting an ID.
+func (s *Service) PutRole(ctx context.Context, r *influxdb.Role) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return s.putRole(ctx, tx, r)
+	})
+}
+
+func (s *Service) uniqueRoleName(ctx context.Context, tx Tx, r *influxdb.Role) error {
+	k, err := roleIndexKey(r)
+	if err != nil {
+		return err
+	}
+	idx, err := tx.Bucket(roleIndexBucket)
+	if err != nil {
+		return err
+	}
+	_, err = idx.Get(k)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &influxdb.Error{
+		Code: influxdb.EConflict,
+		Msg:  fmt.Sprintf("role with name %s already exists", r.Name),
+	}
+}
+
+func (s *Service) putRole(ctx context.Context, tx Tx, r *influxdb.Role) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := r.ID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	k, err := roleIndexKey(r)
+	if err != nil {
+		return err
+	}
+
+	idx, err := tx.Bucket(roleIndexBucket)
+	if err != nil {
+		return err
+	}
+	if err := idx.Put(k, encodedID); err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(roleBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(encodedID, v)
+}
+
+// forEachRole will iterate through all roles while fn returns true.
+func (s *Service) forEachRole(ctx context.Context, tx Tx, fn func(*influxdb.Role) bool) error {
+	b, err := tx.Bucket(roleBucket)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		r, err := unmarshalRole(v)
+		if err != nil {
+			return err
+		}
+		if !fn(r) {
+			break
+		}
+	}
+	return nil
+}
+
+// UpdateRole updates a role according the parameters set on upd.
+func (s *Service) UpdateRole(ctx context.Context, id influxdb.ID, upd influxdb.RoleUpdate) (*influxdb.Role, error) {
+	var r *influxdb.Role
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		role, err := s.findRoleByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if upd.Name != nil && *upd.Name != role.Name {
+			renamed := *role
+			renamed.Name = *upd.Name
+			if err := s.uniqueRoleName(ctx, tx, &renamed); err != nil {
+				return err
+			}
+			if err := s.deleteRoleIndex(ctx, tx, role); err != nil {
+				return err
+			}
+		}
+
+		if err := upd.Apply(role); err != nil {
+			return err
+		}
+		if err := s.putRole(ctx, tx, role); err != nil {
+			return err
+		}
+		r = role
+		return nil
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpUpdateRole,
+			Err: err,
+		}
+	}
+	return r, nil
+}
+
+func (s *Service) deleteRoleIndex(ctx context.Context, tx Tx, r *influxdb.Role) error {
+	k, err := roleIndexKey(r)
+	if err != nil {
+		return err
+	}
+	idx, err := tx.Bucket(roleIndexBucket)
+	if err != nil {
+		return err
+	}
+	return idx.Delete(k)
+}
+
+// DeleteRole deletes a role and its assignments.
+func (s *Service) DeleteRole(ctx context.Context, id influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		r, err := s.findRoleByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.deleteRoleMappings(ctx, tx, influxdb.RoleMappingFilter{RoleID: &id}); err != nil {
+			return err
+		}
+		if err := s.deleteRoleIndex(ctx, tx, r); err != nil {
+			return err
+		}
+
+		encodedID, err := id.Encode()
+		if err != nil {
+			return err
+		}
+		b, err := tx.Bucket(roleBucket)
+		if err != nil {
+			return err
+		}
+		return b.Delete(encodedID)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteRole,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// FindRoleMappings retrieves the assignments of roles that match the filter.
+func (s *Service) FindRoleMappings(ctx context.Context, filter influxdb.RoleMappingFilter) ([]*influxdb.RoleMapping, int, error) {
+	var ms []*influxdb.RoleMapping
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		ms, err = s.findRoleMappings(ctx, tx, filter)
+		return err
+	})
+	if err != nil {
+		return nil, 0, &influxdb.Error{
+			Op:  influxdb.OpFindRoleMappings,
+			Err: err,
+		}
+	}
+	return ms, len(ms), nil
+}
+
+func (s *Service) findRoleMappings(ctx context.Context, tx Tx, filter influxdb.RoleMappingFilter) ([]*influxdb.RoleMapping, error) {
+	b, err := tx.Bucket(roleMappingBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	ms := []*influxdb.RoleMapping{}
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		m := &influxdb.RoleMapping{}
+		if err := json.Unmarshal(v, m); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Msg:  "unable to unmarshal role assignment",
+				Err:  err,
+			}
+		}
+		if filter.RoleID != nil && m.RoleID != *filter.RoleID {
+			continue
+		}
+		if filter.SubjectType != "" && m.SubjectType != filter.SubjectType {
+			continue
+		}
+		if filter.SubjectID != nil && m.SubjectID != *filter.SubjectID {
+			continue
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// CreateRoleMapping assigns a role to a user, or to an authorization of the
+// organization of the role.
+func (s *Service) CreateRoleMapping(ctx context.Context, m *influxdb.RoleMapping) error {
+	if err := m.Valid(); err != nil {
+		return err
+	}
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		r, err := s.findRoleByID(ctx, tx, m.RoleID)
+		if err != nil {
+			return err
+		}
+
+		switch m.SubjectType {
+		case influxdb.UserRoleSubject:
+			if _, err := s.findUserByID(ctx, tx, m.SubjectID); err != nil {
+				return err
+			}
+		case influxdb.AuthorizationRoleSubject:
+			a, err := s.findAuthorizationByID(ctx, tx, m.SubjectID)
+			if err != nil {
+				return err
+			}
+			// Tokens only hold permissions of their organization.
+			if a.OrgID != r.OrgID {
+				return &influxdb.Error{
+					Code: influxdb.EInvalid,
+					Msg:  fmt.Sprintf("role of org id %s cannot be assigned to a token of org id %s", r.OrgID, a.OrgID),
+				}
+			}
+		}
+
+		k, err := roleMappingKey(m.RoleID, m.SubjectType, m.SubjectID)
+		if err != nil {
+			return err
+		}
+		b, err := tx.Bucket(roleMappingBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := b.Get(k); err == nil {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  fmt.Sprintf("role is already assigned to %s %s", m.SubjectType, m.SubjectID),
+			}
+		} else if !IsNotFound(err) {
+			return err
+		}
+
+		v, err := json.Marshal(m)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		return b.Put(k, v)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateRoleMapping,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// DeleteRoleMapping removes the assignment of a role.
+func (s *Service) DeleteRoleMapping(ctx context.Context, roleID influxdb.ID, subjectType influxdb.RoleSubjectType, subjectID influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		k, err := roleMappingKey(roleID, subjectType, subjectID)
+		if err != nil {
+			return err
+		}
+		b, err := tx.Bucket(roleMappingBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := b.Get(k); IsNotFound(err) {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  influxdb.ErrRoleMappingNotFound,
+			}
+		} else if err != nil {
+			return err
+		}
+		return b.Delete(k)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteRoleMapping,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// deleteRoleMappings removes the assignments of roles that match filter.
+func (s *Service) deleteRoleMappings(ctx context.Context, tx Tx, filter influxdb.RoleMappingFilter) error {
+	ms, err := s.findRoleMappings(ctx, tx, filter)
+	if err != nil {
+		return err
+	}
+	b, err := tx.Bucket(roleMappingBucket)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		k, err := roleMappingKey(m.RoleID, m.SubjectType, m.SubjectID)
+		if err != nil {
+			return err
+		}
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalRole turns the stored byte slice in the kv into a *influxdb.Role.
+func unmarshalRole(v []byte) (*influxdb.Role, error) {
+	r := &influxdb.Role{}
+	if err := json.Unmarshal(v, r); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  "unable to unmarshal role",
+			Err:  err,
+		}
+	}
+	return r, nil
+}
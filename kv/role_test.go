@@ -0,0 +1,156 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+)
+
+func TestBoltRoleService(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+	testRoleService(s, t)
+}
+
+func TestInmemRoleService(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+	testRoleService(s, t)
+}
+
+func testRoleService(s kv.Store, t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing role service: %v", err)
+	}
+
+	org := &influxdb.Organization{Name: "acme"}
+	other := &influxdb.Organization{Name: "other"}
+	for _, o := range []*influxdb.Organization{org, other} {
+		if err := svc.CreateOrganization(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := &influxdb.User{Name: "alice"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	auth := &influxdb.Authorization{OrgID: org.ID, UserID: user.ID}
+	otherAuth := &influxdb.Authorization{OrgID: other.ID, UserID: user.ID}
+	for _, a := range []*influxdb.Authorization{auth, otherAuth} {
+		if err := svc.CreateAuthorization(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readBuckets, err := influxdb.NewPermission(influxdb.ReadAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherBuckets, err := influxdb.NewPermission(influxdb.ReadAction, influxdb.BucketsResourceType, other.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Permissions of other orgs are rejected.
+	if err := svc.CreateRole(ctx, &influxdb.Role{OrgID: org.ID, Name: "bad", Permissions: []influxdb.Permission{*otherBuckets}}); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid error, got %v", err)
+	}
+
+	role := &influxdb.Role{OrgID: org.ID, Name: "reader", Permissions: []influxdb.Permission{*readBuckets}}
+	if err := svc.CreateRole(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if !role.ID.Valid() {
+		t.Fatal("expected the role to have an id")
+	}
+	if err := svc.CreateRole(ctx, &influxdb.Role{OrgID: org.ID, Name: "reader"}); influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	// Names are unique per org.
+	if err := svc.CreateRole(ctx, &influxdb.Role{OrgID: other.ID, Name: "reader"}); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "viewer"
+	updated, err := svc.UpdateRole(ctx, role.ID, influxdb.RoleUpdate{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != name || len(updated.Permissions) != 1 {
+		t.Fatalf("unexpected role: %+v", updated)
+	}
+	rs, n, err := svc.FindRoles(ctx, influxdb.RoleFilter{OrgID: &org.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || rs[0].Name != name {
+		t.Fatalf("unexpected roles: %+v", rs)
+	}
+	// The old name is free again.
+	if err := svc.CreateRole(ctx, &influxdb.Role{OrgID: org.ID, Name: "reader"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []*influxdb.RoleMapping{
+		{RoleID: role.ID, SubjectType: influxdb.UserRoleSubject, SubjectID: user.ID},
+		{RoleID: role.ID, SubjectType: influxdb.AuthorizationRoleSubject, SubjectID: auth.ID},
+	} {
+		if err := svc.CreateRoleMapping(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = svc.CreateRoleMapping(ctx, &influxdb.RoleMapping{RoleID: role.ID, SubjectType: influxdb.UserRoleSubject, SubjectID: user.ID})
+	if influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	err = svc.CreateRoleMapping(ctx, &influxdb.RoleMapping{RoleID: role.ID, SubjectType: influxdb.AuthorizationRoleSubject, SubjectID: otherAuth.ID})
+	if influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid error assigning to a token of another org, got %v", err)
+	}
+
+	ms, n, err := svc.FindRoleMappings(ctx, influxdb.RoleMappingFilter{SubjectType: influxdb.UserRoleSubject, SubjectID: &user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || ms[0].RoleID != role.ID {
+		t.Fatalf("unexpected mappings: %+v", ms)
+	}
+
+	// Deleting a token removes its assignments.
+	if err := svc.DeleteAuthorization(ctx, auth.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, n, _ := svc.FindRoleMappings(ctx, influxdb.RoleMappingFilter{RoleID: &role.ID}); n != 1 {
+		t.Fatalf("expected 1 mapping, got %d", n)
+	}
+
+	if err := svc.DeleteRoleMapping(ctx, role.ID, influxdb.UserRoleSubject, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteRoleMapping(ctx, role.ID, influxdb.UserRoleSubject, user.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if err := svc.CreateRoleMapping(ctx, &influxdb.RoleMapping{RoleID: role.ID, SubjectType: influxdb.UserRoleSubject, SubjectID: user.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteRole(ctx, role.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FindRoleByID(ctx, role.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if _, n, _ := svc.FindRoleMappings(ctx, influxdb.RoleMappingFilter{}); n != 0 {
+		t.Fatalf("expected the assignments of the role to be removed, got %d", n)
+	}
+}
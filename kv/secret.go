@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/base64"
 	"errors"
-	"fmt"
 
 	"github.com/influxdata/influxdb"
 )
@@ -120,7 +119,8 @@ func (s *Service) getSecretKeys(ctx context.Context, tx Tx, orgID influxdb.ID) (
 	}
 
 	if id != orgID {
-		return nil, fmt.Errorf("organization has no secret keys")
+		// The cursor is past the keyspace of orgID, which has no secrets.
+		return []string{}, nil
 	}
 
 	keys := []string{key}
@@ -208,11 +208,12 @@ func decodeSecretKey(key []byte) (influxdb.ID, string, error) {
 func decodeSecretValue(val []byte) (string, error) {
 	// store the secret value base64 encoded so that it's marginally better than plaintext
 	v := make([]byte, base64.StdEncoding.DecodedLen(len(val)))
-	if _, err := base64.StdEncoding.Decode(v, val); err != nil {
+	n, err := base64.StdEncoding.Decode(v, val)
+	if err != nil {
 		return "", err
 	}
 
-	return string(v), nil
+	return string(v[:n]), nil
 }
 
 func encodeSecretValue(v string) []byte {
@@ -60,3 +60,25 @@ func initSecretService(s kv.Store, f influxdbtesting.SecretServiceFields, t *tes
 
 	return svc, func() {}
 }
+
+func TestSecretService_LoadSecretLength(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+
+	svc, _ := initSecretService(s, influxdbtesting.SecretServiceFields{}, t)
+	ctx := context.Background()
+	// The base64 encoding of the value is padded.
+	if err := svc.PutSecret(ctx, influxdb.ID(1), "api_key", "abcd"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := svc.LoadSecret(ctx, influxdb.ID(1), "api_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "abcd" {
+		t.Fatalf("unexpected secret value: %q", v)
+	}
+}
@@ -45,6 +45,10 @@ func NewService(kv Store) *Service {
 // Initialize creates Buckets needed.
 func (s *Service) Initialize(ctx context.Context) error {
 	return s.kv.Update(ctx, func(tx Tx) error {
+		if err := s.initializeAuditLog(ctx, tx); err != nil {
+			return err
+		}
+
 		if err := s.initializeAuths(ctx, tx); err != nil {
 			return err
 		}
@@ -69,6 +73,10 @@ func (s *Service) Initialize(ctx context.Context) error {
 			return err
 		}
 
+		if err := s.initializeMFA(ctx, tx); err != nil {
+			return err
+		}
+
 		if err := s.initializeOnboarding(ctx, tx); err != nil {
 			return err
 		}
@@ -81,6 +89,14 @@ func (s *Service) Initialize(ctx context.Context) error {
 			return err
 		}
 
+		if err := s.initializeReplications(ctx, tx); err != nil {
+			return err
+		}
+
+		if err := s.initializeRoles(ctx, tx); err != nil {
+			return err
+		}
+
 		if err := s.initializeScraperTargets(ctx, tx); err != nil {
 			return err
 		}
@@ -105,6 +121,10 @@ func (s *Service) Initialize(ctx context.Context) error {
 			return err
 		}
 
+		if err := s.initializeUserIdentities(ctx, tx); err != nil {
+			return err
+		}
+
 		if err := s.initializeVariables(ctx, tx); err != nil {
 			return err
 		}
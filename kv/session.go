@@ -13,6 +13,7 @@ var (
 )
 
 var _ influxdb.SessionService = (*Service)(nil)
+var _ influxdb.UserSessionService = (*Service)(nil)
 
 func (s *Service) initializeSessions(ctx context.Context, tx Tx) error {
 	if _, err := tx.Bucket([]byte(sessionBucket)); err != nil {
@@ -29,6 +30,18 @@ func (s *Service) RenewSession(ctx context.Context, session *influxdb.Session, n
 		}
 	}
 	return s.kv.Update(ctx, func(tx Tx) error {
+		// A session revoked while it was in use must not be put back.
+		b, err := tx.Bucket(sessionBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := b.Get([]byte(session.Key)); IsNotFound(err) {
+			return &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  influxdb.ErrSessionNotFound,
+			}
+		}
+
 		session.ExpiresAt = newExpiration
 		if err := s.putSession(ctx, tx, session); err != nil {
 			return &influxdb.Error{
@@ -125,10 +138,43 @@ func (s *Service) findSession(ctx context.Context, tx Tx, key string) (*influxdb
 		ps = append(ps, a.Permissions...)
 	}
 
+	rps, err := s.userRolePermissions(ctx, tx, sn.UserID)
+	if err != nil {
+		return nil, err
+	}
+	ps = append(ps, rps...)
+
+	// Role permissions are filtered too, so that a member who is not
+	// enrolled cannot access an organization requiring MFA through a role.
+	ps, err = s.mfaPermissions(ctx, tx, sn.UserID, ps)
+	if err != nil {
+		return nil, err
+	}
+
 	sn.Permissions = ps
 	return sn, nil
 }
 
+// userRolePermissions returns the permissions of the roles assigned to
+// userID.
+func (s *Service) userRolePermissions(ctx context.Context, tx Tx, userID influxdb.ID) ([]influxdb.Permission, error) {
+	f := influxdb.RoleMappingFilter{SubjectType: influxdb.UserRoleSubject, SubjectID: &userID}
+	ms, err := s.findRoleMappings(ctx, tx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var ps []influxdb.Permission
+	for _, m := range ms {
+		r, err := s.findRoleByID(ctx, tx, m.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, r.Permissions...)
+	}
+	return ps, nil
+}
+
 // PutSession puts the session at key.
 func (s *Service) PutSession(ctx context.Context, sn *influxdb.Session) error {
 	return s.kv.Update(ctx, func(tx Tx) error {
@@ -226,3 +272,86 @@ func (s *Service) createSession(ctx context.Context, tx Tx, user string) (*influ
 
 	return sn, nil
 }
+
+// FindUserSessions returns the unexpired sessions of a user.
+func (s *Service) FindUserSessions(ctx context.Context, userID influxdb.ID) ([]*influxdb.Session, error) {
+	var ss []*influxdb.Session
+	err := s.kv.View(ctx, func(tx Tx) error {
+		sns, err := s.findUserSessions(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		ss = sns
+		return nil
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindUserSessions,
+			Err: err,
+		}
+	}
+	return ss, nil
+}
+
+// findUserSessions scans all sessions, as they are keyed by their key.
+func (s *Service) findUserSessions(ctx context.Context, tx Tx, userID influxdb.ID) ([]*influxdb.Session, error) {
+	b, err := tx.Bucket(sessionBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ss := []*influxdb.Session{}
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		sn := &influxdb.Session{}
+		if err := json.Unmarshal(v, sn); err != nil {
+			return nil, &influxdb.Error{
+				Err: err,
+			}
+		}
+		if sn.UserID != userID || !now.Before(sn.ExpiresAt) {
+			continue
+		}
+		ss = append(ss, sn)
+	}
+	return ss, nil
+}
+
+// DeleteUserSession revokes the session of a user with the id sessionID.
+func (s *Service) DeleteUserSession(ctx context.Context, userID, sessionID influxdb.ID) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		ss, err := s.findUserSessions(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, sn := range ss {
+			if sn.ID != sessionID {
+				continue
+			}
+
+			b, err := tx.Bucket(sessionBucket)
+			if err != nil {
+				return err
+			}
+			return b.Delete([]byte(sn.Key))
+		}
+
+		return &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  influxdb.ErrSessionNotFound,
+		}
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteUserSession,
+			Err: err,
+		}
+	}
+	return nil
+}
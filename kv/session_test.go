@@ -71,3 +71,73 @@ func initSessionService(s kv.Store, f influxdbtesting.SessionFields, t *testing.
 		}
 	}
 }
+
+func TestInmemUserSessionService(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+
+	ctx := context.Background()
+	svc := kv.NewService(s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing session service: %v", err)
+	}
+
+	alice := &influxdb.User{Name: "alice"}
+	bob := &influxdb.User{Name: "bob"}
+	for _, u := range []*influxdb.User{alice, bob} {
+		if err := svc.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	laptop, err := svc.CreateSession(ctx, alice.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := svc.CreateSession(ctx, alice.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := svc.CreateSession(ctx, alice.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ExpireSession(ctx, old.Key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateSession(ctx, bob.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	ss, err := svc.FindUserSessions(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 2 {
+		t.Fatalf("expected the 2 unexpired sessions of alice, got %d", len(ss))
+	}
+
+	if err := svc.DeleteUserSession(ctx, bob.ID, laptop.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected session of another user not to be found, got %v", err)
+	}
+	if err := svc.DeleteUserSession(ctx, alice.ID, laptop.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FindSession(ctx, laptop.Key); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected revoked session not to be found, got %v", err)
+	}
+	if err := svc.RenewSession(ctx, laptop, laptop.ExpiresAt); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected revoked session not to be renewed, got %v", err)
+	}
+
+	ss, err = svc.FindUserSessions(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 1 || ss[0].ID != phone.ID {
+		t.Fatalf("expected only the phone session to be left, got %+v", ss)
+	}
+}
@@ -426,6 +426,21 @@ func (s *Service) deleteUser(ctx context.Context, tx Tx, id influxdb.ID) error {
 		return err
 	}
 
+	if err := s.deleteRoleMappings(ctx, tx, influxdb.RoleMappingFilter{
+		SubjectType: influxdb.UserRoleSubject,
+		SubjectID:   &id,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.deleteMFA(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := s.deleteUserIdentities(ctx, tx, id); err != nil {
+		return err
+	}
+
 	return nil
 }
 
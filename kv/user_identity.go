@@ -0,0 +1,149 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/influxdata/influxdb"
+)
+
+var (
+	userIdentityBucket = []byte("useridentitiesv1")
+)
+
+var _ influxdb.UserIdentityService = (*Service)(nil)
+
+func (s *Service) initializeUserIdentities(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(userIdentityBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// userIdentityKey is the key of the identity of a subject of a provider.
+// Providers are URLs and do not contain a NUL byte.
+func userIdentityKey(provider, subject string) []byte {
+	k := make([]byte, 0, len(provider)+1+len(subject))
+	k = append(k, provider...)
+	k = append(k, 0)
+	return append(k, subject...)
+}
+
+// FindUserIdentity returns the link of an account of a provider to a user.
+func (s *Service) FindUserIdentity(ctx context.Context, provider, subject string) (*influxdb.UserIdentity, error) {
+	var i *influxdb.UserIdentity
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		i, err = s.findUserIdentity(ctx, tx, provider, subject)
+		return err
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindUserIdentity,
+			Err: err,
+		}
+	}
+	return i, nil
+}
+
+func (s *Service) findUserIdentity(ctx context.Context, tx Tx, provider, subject string) (*influxdb.UserIdentity, error) {
+	b, err := tx.Bucket(userIdentityBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(userIdentityKey(provider, subject))
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  "user identity not found",
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	i := &influxdb.UserIdentity{}
+	if err := json.Unmarshal(v, i); err != nil {
+		return nil, &influxdb.Error{
+			Err: err,
+		}
+	}
+	return i, nil
+}
+
+// CreateUserIdentity links an account of a provider to a user.
+func (s *Service) CreateUserIdentity(ctx context.Context, i *influxdb.UserIdentity) error {
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if i.Provider == "" || i.Subject == "" {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "provider and subject are required",
+			}
+		}
+		if _, err := s.findUserByID(ctx, tx, i.UserID); err != nil {
+			return err
+		}
+		if _, err := s.findUserIdentity(ctx, tx, i.Provider, i.Subject); err == nil {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  "the account is already linked to a user",
+			}
+		} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+			return err
+		}
+
+		v, err := json.Marshal(i)
+		if err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+
+		b, err := tx.Bucket(userIdentityBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put(userIdentityKey(i.Provider, i.Subject), v)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateUserIdentity,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// deleteUserIdentities removes the links of a user to their accounts.
+func (s *Service) deleteUserIdentities(ctx context.Context, tx Tx, userID influxdb.ID) error {
+	b, err := tx.Bucket(userIdentityBucket)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		i := &influxdb.UserIdentity{}
+		if err := json.Unmarshal(v, i); err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		if i.UserID == userID {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+	}
+
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
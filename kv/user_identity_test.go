@@ -0,0 +1,78 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+)
+
+func TestBoltUserIdentityService(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+	testUserIdentityService(s, t)
+}
+
+func TestInmemUserIdentityService(t *testing.T) {
+	s, closeStore, err := NewTestInmemStore()
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeStore()
+	testUserIdentityService(s, t)
+}
+
+func testUserIdentityService(s kv.Store, t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing user identity service: %v", err)
+	}
+
+	user := &influxdb.User{Name: "alice"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	const issuer = "https://idp.example.com"
+	if _, err := svc.FindUserIdentity(ctx, issuer, "123"); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if err := svc.CreateUserIdentity(ctx, &influxdb.UserIdentity{Provider: issuer, Subject: "123", UserID: user.ID}); err != nil {
+		t.Fatal(err)
+	}
+	i, err := svc.FindUserIdentity(ctx, issuer, "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.UserID != user.ID {
+		t.Fatalf("got user %s, expected %s", i.UserID, user.ID)
+	}
+
+	// The subject is only linked within its provider.
+	if _, err := svc.FindUserIdentity(ctx, "https://other.example.com", "123"); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error for another provider, got %v", err)
+	}
+
+	other := &influxdb.User{Name: "bob"}
+	if err := svc.CreateUser(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	err = svc.CreateUserIdentity(ctx, &influxdb.UserIdentity{Provider: issuer, Subject: "123", UserID: other.ID})
+	if influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("expected conflict linking a linked account, got %v", err)
+	}
+
+	// Deleting the user removes their links.
+	if err := svc.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FindUserIdentity(ctx, issuer, "123"); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error after deleting the user, got %v", err)
+	}
+}
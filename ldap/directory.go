@@ -0,0 +1,266 @@
+// Package ldap authenticates users against an LDAP directory.
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	goldap "gopkg.in/ldap.v2"
+)
+
+// Defaults of directories.
+const (
+	DefaultUserFilter     = "(uid=%s)"
+	DefaultGroupAttribute = "memberOf"
+	DefaultGroupFilter    = "(member=%s)"
+	DefaultTimeout        = 10 * time.Second
+)
+
+// ErrIncorrectPassword is returned when a user is unknown to the directory
+// or their password is incorrect, without telling which.
+var ErrIncorrectPassword = &influxdb.Error{
+	Code: influxdb.EForbidden,
+	Msg:  "your username or password is incorrect",
+}
+
+// Config configures a directory.
+type Config struct {
+	// URL is the address of the directory, as ldap://host[:port] or
+	// ldaps://host[:port].
+	URL string
+
+	// StartTLS upgrades ldap:// connections to TLS.
+	StartTLS           bool
+	InsecureSkipVerify bool
+
+	// BindDN and BindPassword authenticate the searches of users and their
+	// groups. Searches are anonymous if BindDN is empty.
+	BindDN       string
+	BindPassword string
+
+	// UserBaseDN is where users are searched with UserFilter, in which %s is
+	// replaced by the name of the user.
+	UserBaseDN string
+	UserFilter string
+
+	// The groups of users are listed by the GroupAttribute of their entry,
+	// unless GroupBaseDN is set, where they are searched with GroupFilter,
+	// in which %s is replaced by the DN of the user.
+	GroupAttribute string
+	GroupBaseDN    string
+	GroupFilter    string
+
+	// Timeout limits connecting to the directory and each request to it.
+	Timeout time.Duration
+}
+
+// conn is the part of an LDAP connection used to authenticate users.
+type conn interface {
+	Bind(username, password string) error
+	Search(req *goldap.SearchRequest) (*goldap.SearchResult, error)
+	Close()
+}
+
+// Directory authenticates users against an LDAP directory.
+type Directory struct {
+	config Config
+	dial   func() (conn, error)
+}
+
+// NewDirectory returns a directory for a configuration, with the defaults
+// of the settings that are not set.
+func NewDirectory(c Config) (*Directory, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, fmt.Errorf("unsupported directory URL %q, expected ldap:// or ldaps://", c.URL)
+	}
+	if c.UserBaseDN == "" {
+		return nil, fmt.Errorf("user base DN is required")
+	}
+
+	if c.UserFilter == "" {
+		c.UserFilter = DefaultUserFilter
+	}
+	if c.GroupAttribute == "" {
+		c.GroupAttribute = DefaultGroupAttribute
+	}
+	if c.GroupFilter == "" {
+		c.GroupFilter = DefaultGroupFilter
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+
+	d := &Directory{config: c}
+	d.dial = func() (conn, error) {
+		return d.dialURL(u)
+	}
+	return d, nil
+}
+
+func (d *Directory) dialURL(u *url.URL) (conn, error) {
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "389"
+		if u.Scheme == "ldaps" {
+			port = "636"
+		}
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: d.config.InsecureSkipVerify,
+	}
+
+	nc, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), d.config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "ldaps" {
+		tc := tls.Client(nc, tlsConfig)
+		tc.SetDeadline(time.Now().Add(d.config.Timeout))
+		if err := tc.Handshake(); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		tc.SetDeadline(time.Time{})
+		nc = tc
+	}
+
+	c := goldap.NewConn(nc, u.Scheme == "ldaps")
+	c.SetTimeout(d.config.Timeout)
+	c.Start()
+	if u.Scheme == "ldap" && d.config.StartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Entry is the entry of a user authenticated by a directory.
+type Entry struct {
+	DN string
+	// Groups are the DNs of the groups of the user.
+	Groups []string
+}
+
+// Authenticate checks the password of a user by binding as them, and
+// returns their entry.
+func (d *Directory) Authenticate(ctx context.Context, name, password string) (*Entry, error) {
+	// Binding without a password is an anonymous bind, which would succeed.
+	if name == "" || password == "" {
+		return nil, ErrIncorrectPassword
+	}
+
+	c, err := d.dial()
+	if err != nil {
+		return nil, unavailableError(err)
+	}
+	defer c.Close()
+
+	if err := d.bindSearch(c); err != nil {
+		return nil, err
+	}
+
+	attrs := []string{"dn"}
+	if d.config.GroupBaseDN == "" {
+		attrs = append(attrs, d.config.GroupAttribute)
+	}
+	res, err := c.Search(goldap.NewSearchRequest(
+		d.config.UserBaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(d.config.UserFilter, goldap.EscapeFilter(name)),
+		attrs, nil,
+	))
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, unavailableError(err)
+	}
+	if res == nil || len(res.Entries) != 1 {
+		return nil, ErrIncorrectPassword
+	}
+	user := res.Entries[0]
+
+	if err := c.Bind(user.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrIncorrectPassword
+		}
+		return nil, unavailableError(err)
+	}
+
+	e := &Entry{DN: user.DN}
+	if d.config.GroupBaseDN == "" {
+		e.Groups = user.GetAttributeValues(d.config.GroupAttribute)
+	} else {
+		// The user may not be allowed to search groups.
+		if err := d.bindSearch(c); err != nil {
+			return nil, err
+		}
+		res, err := c.Search(goldap.NewSearchRequest(
+			d.config.GroupBaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, 0, false,
+			fmt.Sprintf(d.config.GroupFilter, goldap.EscapeFilter(user.DN)),
+			[]string{"dn"}, nil,
+		))
+		if err != nil {
+			return nil, unavailableError(err)
+		}
+		for _, g := range res.Entries {
+			e.Groups = append(e.Groups, g.DN)
+		}
+	}
+	return e, nil
+}
+
+// bindSearch binds as the account that searches the directory.
+func (d *Directory) bindSearch(c conn) error {
+	if d.config.BindDN == "" {
+		return nil
+	}
+	if err := c.Bind(d.config.BindDN, d.config.BindPassword); err != nil {
+		return unavailableError(err)
+	}
+	return nil
+}
+
+// equalDN reports whether two DNs are the same, ignoring the case of their
+// attributes and the spaces around them.
+func equalDN(a, b string) bool {
+	pa, err := goldap.ParseDN(a)
+	if err != nil {
+		return false
+	}
+	pb, err := goldap.ParseDN(b)
+	if err != nil || len(pa.RDNs) != len(pb.RDNs) {
+		return false
+	}
+	for i, rdn := range pa.RDNs {
+		if len(rdn.Attributes) != len(pb.RDNs[i].Attributes) {
+			return false
+		}
+		for j, attr := range rdn.Attributes {
+			other := pb.RDNs[i].Attributes[j]
+			if !strings.EqualFold(strings.TrimSpace(attr.Type), strings.TrimSpace(other.Type)) ||
+				!strings.EqualFold(strings.TrimSpace(attr.Value), strings.TrimSpace(other.Value)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func unavailableError(err error) error {
+	return &influxdb.Error{
+		Code: influxdb.EUnavailable,
+		Msg:  "unable to reach the directory",
+		Err:  err,
+	}
+}
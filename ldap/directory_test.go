@@ -0,0 +1,204 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	goldap "gopkg.in/ldap.v2"
+)
+
+// fakeDirectory is a directory of users and groups, that understands
+// filters matching a single attribute.
+type fakeDirectory struct {
+	passwords   map[string]string
+	users       []*goldap.Entry
+	groupBaseDN string
+	groups      []*goldap.Entry
+	dials       int
+}
+
+func newFakeDirectory() *fakeDirectory {
+	return &fakeDirectory{
+		passwords: map[string]string{
+			"cn=reader,dc=acme":          "reader",
+			"uid=jane,ou=users,dc=acme":  "secret",
+			"uid=john,ou=users,dc=acme":  "secret",
+			"uid=jane,ou=others,dc=acme": "other",
+		},
+		users: []*goldap.Entry{
+			goldap.NewEntry("uid=jane,ou=users,dc=acme", map[string][]string{
+				"uid":      {"jane"},
+				"memberOf": {"cn=devs,ou=groups,dc=acme", "cn=admins,ou=groups,dc=acme"},
+			}),
+			goldap.NewEntry("uid=john,ou=users,dc=acme", map[string][]string{
+				"uid": {"john"},
+			}),
+		},
+		groupBaseDN: "ou=groups,dc=acme",
+		groups: []*goldap.Entry{
+			goldap.NewEntry("cn=ops,ou=groups,dc=acme", map[string][]string{
+				"member": {"uid=jane,ou=users,dc=acme", "uid=john,ou=users,dc=acme"},
+			}),
+			goldap.NewEntry("cn=devs,ou=groups,dc=acme", map[string][]string{
+				"member": {"uid=jane,ou=users,dc=acme"},
+			}),
+		},
+	}
+}
+
+func (f *fakeDirectory) dial() (conn, error) {
+	f.dials++
+	return f, nil
+}
+
+func (f *fakeDirectory) Bind(username, password string) error {
+	if p, ok := f.passwords[username]; !ok || p != password {
+		return goldap.NewError(goldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	return nil
+}
+
+func (f *fakeDirectory) Search(req *goldap.SearchRequest) (*goldap.SearchResult, error) {
+	filter := strings.TrimSuffix(strings.TrimPrefix(req.Filter, "("), ")")
+	i := strings.Index(filter, "=")
+	attr, value := filter[:i], filter[i+1:]
+
+	entries := f.users
+	if req.BaseDN == f.groupBaseDN {
+		entries = f.groups
+	}
+	res := &goldap.SearchResult{}
+	for _, e := range entries {
+		for _, v := range e.GetAttributeValues(attr) {
+			if v == value {
+				res.Entries = append(res.Entries, e)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeDirectory) Close() {}
+
+func newTestDirectory(t *testing.T, f *fakeDirectory, c Config) *Directory {
+	t.Helper()
+	c.URL = "ldap://localhost"
+	if c.UserBaseDN == "" {
+		c.UserBaseDN = "ou=users,dc=acme"
+	}
+	d, err := NewDirectory(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.dial = f.dial
+	return d
+}
+
+func TestDirectory_Authenticate(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		user     string
+		password string
+		want     *Entry
+		wantCode string
+	}{
+		{
+			name:     "groups of the user entry",
+			config:   Config{BindDN: "cn=reader,dc=acme", BindPassword: "reader"},
+			user:     "jane",
+			password: "secret",
+			want: &Entry{
+				DN:     "uid=jane,ou=users,dc=acme",
+				Groups: []string{"cn=devs,ou=groups,dc=acme", "cn=admins,ou=groups,dc=acme"},
+			},
+		},
+		{
+			name:     "groups searched",
+			config:   Config{GroupBaseDN: "ou=groups,dc=acme"},
+			user:     "john",
+			password: "secret",
+			want: &Entry{
+				DN:     "uid=john,ou=users,dc=acme",
+				Groups: []string{"cn=ops,ou=groups,dc=acme"},
+			},
+		},
+		{
+			name:     "incorrect password",
+			user:     "jane",
+			password: "other",
+			wantCode: influxdb.EForbidden,
+		},
+		{
+			name:     "unknown user",
+			user:     "joe",
+			password: "secret",
+			wantCode: influxdb.EForbidden,
+		},
+		{
+			name:     "search account refused",
+			config:   Config{BindDN: "cn=reader,dc=acme", BindPassword: "wrong"},
+			user:     "jane",
+			password: "secret",
+			wantCode: influxdb.EUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDirectory(t, newFakeDirectory(), tt.config)
+			got, err := d.Authenticate(context.Background(), tt.user, tt.password)
+			if code := influxdb.ErrorCode(err); code != tt.wantCode {
+				t.Fatalf("unexpected error code %q: %v", code, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unexpected entry: got %+v, exp %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirectory_AuthenticateEmptyPassword(t *testing.T) {
+	f := newFakeDirectory()
+	f.passwords["uid=jane,ou=users,dc=acme"] = ""
+	d := newTestDirectory(t, f, Config{})
+
+	// An empty password would be an anonymous bind.
+	if _, err := d.Authenticate(context.Background(), "jane", ""); influxdb.ErrorCode(err) != influxdb.EForbidden {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+	if f.dials != 0 {
+		t.Fatal("expected the directory not to be reached")
+	}
+}
+
+func TestNewDirectory(t *testing.T) {
+	if _, err := NewDirectory(Config{URL: "http://localhost", UserBaseDN: "dc=acme"}); err == nil {
+		t.Fatal("expected error for a URL that is not LDAP")
+	}
+	if _, err := NewDirectory(Config{URL: "ldaps://localhost"}); err == nil {
+		t.Fatal("expected error without a user base DN")
+	}
+}
+
+func TestEqualDN(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "cn=admins,ou=groups,dc=acme", b: "cn=admins,ou=groups,dc=acme", want: true},
+		{a: "CN=Admins, OU=groups,dc=acme", b: "cn=admins,ou=groups,dc=acme", want: true},
+		{a: "cn=admins,ou=others,dc=acme", b: "cn=admins,ou=groups,dc=acme"},
+		{a: "cn=admins", b: "cn=admins,ou=groups,dc=acme"},
+		{a: "admins", b: "cn=admins,ou=groups,dc=acme"},
+	}
+	for _, tt := range tests {
+		if got := equalDN(tt.a, tt.b); got != tt.want {
+			t.Errorf("equalDN(%q, %q) = %v, exp %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package ldap
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/membership"
+)
+
+// ErrDirectoryPassword is returned when setting the password of a user of
+// the directory.
+var ErrDirectoryPassword = &influxdb.Error{
+	Code: influxdb.EMethodNotAllowed,
+	Msg:  "passwords of directory users are managed by the directory",
+}
+
+var _ influxdb.PasswordsService = (*PasswordsService)(nil)
+
+// PasswordsService checks the passwords of users against a directory. Users
+// are created and linked to their directory entry the first time they sign
+// in, and their membership of organizations is synced with their groups every
+// time they do.
+//
+// Directory entries only sign in as the users linked to them, so that entries
+// cannot take over local users with the same name.
+type PasswordsService struct {
+	Directory *Directory
+
+	// LocalUsers are the names of the users whose passwords are checked
+	// against Local instead of the directory, such as the initial operator
+	// account, so that they can sign in whether the directory knows them
+	// or is available.
+	LocalUsers []string
+	Local      influxdb.PasswordsService
+
+	// Membership syncs the membership of users in organizations with their
+	// groups, which are named by their DN in its mappings. It is not synced
+	// if it is nil.
+	Membership *membership.Syncer
+
+	UserService         influxdb.UserService
+	UserIdentityService influxdb.UserIdentityService
+}
+
+func (s *PasswordsService) isLocal(name string) bool {
+	for _, n := range s.LocalUsers {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPassword overrides the password of a local user.
+func (s *PasswordsService) SetPassword(ctx context.Context, name string, password string) error {
+	if !s.isLocal(name) {
+		return ErrDirectoryPassword
+	}
+	return s.Local.SetPassword(ctx, name, password)
+}
+
+// ComparePassword checks the password of a user, against the directory
+// unless they are local.
+func (s *PasswordsService) ComparePassword(ctx context.Context, name string, password string) error {
+	if s.isLocal(name) {
+		return s.Local.ComparePassword(ctx, name, password)
+	}
+
+	e, err := s.Directory.Authenticate(ctx, name, password)
+	if err != nil {
+		return err
+	}
+
+	u, err := s.user(ctx, name, e.DN)
+	if err != nil {
+		return err
+	}
+
+	if s.Membership != nil {
+		return s.Membership.Sync(ctx, u.ID, s.groups(e.Groups))
+	}
+	return nil
+}
+
+// user returns the user with the name linked to the directory entry with the
+// DN, creating and linking them if there is no user with the name yet.
+func (s *PasswordsService) user(ctx context.Context, name, dn string) (*influxdb.User, error) {
+	notLinked := &influxdb.Error{
+		Code: influxdb.EForbidden,
+		Msg:  influxdb.ErrUserNotLinked,
+	}
+
+	i, err := s.UserIdentityService.FindUserIdentity(ctx, s.Directory.config.URL, dn)
+	if err == nil {
+		u, err := s.UserService.FindUserByID(ctx, i.UserID)
+		if err != nil {
+			return nil, err
+		}
+		// The session is started for the user with the name signed in with.
+		if u.Name != name {
+			return nil, notLinked
+		}
+		return u, nil
+	} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, err
+	}
+
+	if _, err := s.UserService.FindUser(ctx, influxdb.UserFilter{Name: &name}); err == nil {
+		return nil, notLinked
+	} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, err
+	}
+
+	u := &influxdb.User{Name: name}
+	if err := s.UserService.CreateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	if err := s.UserIdentityService.CreateUserIdentity(ctx, &influxdb.UserIdentity{
+		Provider: s.Directory.config.URL,
+		Subject:  dn,
+		UserID:   u.ID,
+	}); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// groups returns the groups of the mappings of Membership that are one of
+// the DNs. Groups are matched by their full DN, as groups elsewhere in the
+// directory may have the same common name.
+func (s *PasswordsService) groups(dns []string) []string {
+	var groups []string
+	for _, m := range s.Membership.Mappings {
+		for _, dn := range dns {
+			if equalDN(m.Group, dn) {
+				groups = append(groups, m.Group)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// CompareAndSetPassword checks the password of a local user and if it
+// matches updates it.
+func (s *PasswordsService) CompareAndSetPassword(ctx context.Context, name string, old string, new string) error {
+	if !s.isLocal(name) {
+		return ErrDirectoryPassword
+	}
+	return s.Local.CompareAndSetPassword(ctx, name, old, new)
+}
@@ -0,0 +1,111 @@
+package ldap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/membership"
+)
+
+func TestPasswordsService(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	acme := &influxdb.Organization{Name: "acme"}
+	if err := svc.CreateOrganization(ctx, acme); err != nil {
+		t.Fatal(err)
+	}
+	dev := &influxdb.Organization{Name: "dev"}
+	if err := svc.CreateOrganization(ctx, dev); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateUser(ctx, &influxdb.User{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetPassword(ctx, "admin", "password"); err != nil {
+		t.Fatal(err)
+	}
+	john := &influxdb.User{Name: "john"}
+	if err := svc.CreateUser(ctx, john); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &PasswordsService{
+		Directory:  newTestDirectory(t, newFakeDirectory(), Config{}),
+		LocalUsers: []string{"admin"},
+		Local:      svc,
+		Membership: &membership.Syncer{
+			Mappings: []membership.Mapping{
+				{Group: "CN=Admins,ou=groups,dc=acme", Org: "acme", Role: influxdb.Owner},
+				// Groups are not matched by their common name.
+				{Group: "devs", Org: "dev", Role: influxdb.Member},
+			},
+			OrganizationService:        svc,
+			UserResourceMappingService: svc,
+		},
+		UserService:         svc,
+		UserIdentityService: svc,
+	}
+
+	// Local users fall through to local passwords.
+	if err := s.ComparePassword(ctx, "admin", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ComparePassword(ctx, "admin", "wrong"); err == nil {
+		t.Fatal("expected error for an incorrect local password")
+	}
+
+	// Users of the directory are created and granted the roles of their groups.
+	if err := s.ComparePassword(ctx, "jane", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	u, err := svc.FindUser(ctx, influxdb.UserFilter{Name: strPtr("jane")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, _, err := svc.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{UserID: u.ID, ResourceID: acme.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].UserType != influxdb.Owner {
+		t.Fatalf("unexpected memberships: %v", ms)
+	}
+	ms, _, err = svc.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{UserID: u.ID, ResourceID: dev.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 0 {
+		t.Fatalf("unexpected memberships granted by common name: %v", ms)
+	}
+
+	if err := s.ComparePassword(ctx, "jane", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ComparePassword(ctx, "jane", "wrong"); influxdb.ErrorCode(err) != influxdb.EForbidden {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+
+	// Directory entries do not sign in as users that were not created for them.
+	if err := s.ComparePassword(ctx, "john", "secret"); influxdb.ErrorCode(err) != influxdb.EForbidden {
+		t.Fatalf("expected forbidden error for a user not linked to the directory, got %v", err)
+	}
+
+	// Passwords are only set locally for local users.
+	if err := s.SetPassword(ctx, "jane", "password"); influxdb.ErrorCode(err) != influxdb.EMethodNotAllowed {
+		t.Fatalf("expected method not allowed error, got %v", err)
+	}
+	if err := s.CompareAndSetPassword(ctx, "admin", "password", "new password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ComparePassword(ctx, "admin", "new password"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,116 @@
+// Package membership grants users roles in organizations from the groups
+// they belong to in an external identity provider or directory.
+package membership
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+)
+
+// Mapping grants the members of a group a role in an organization.
+type Mapping struct {
+	Group string
+	Org   string
+	Role  influxdb.UserType
+}
+
+// ParseMapping parses a mapping written as group=org, granting the member
+// role, or group=org:role.
+func ParseMapping(s string) (Mapping, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 || i == len(s)-1 {
+		return Mapping{}, fmt.Errorf("invalid group mapping %q, expected group=org[:role]", s)
+	}
+
+	m := Mapping{
+		Group: s[:i],
+		Org:   s[i+1:],
+		Role:  influxdb.Member,
+	}
+	if j := strings.LastIndex(m.Org, ":"); j >= 0 {
+		m.Org, m.Role = m.Org[:j], influxdb.UserType(m.Org[j+1:])
+	}
+	if m.Org == "" {
+		return Mapping{}, fmt.Errorf("invalid group mapping %q, expected group=org[:role]", s)
+	}
+	if err := m.Role.Valid(); err != nil {
+		return Mapping{}, fmt.Errorf("invalid group mapping %q: %v", s, err)
+	}
+	return m, nil
+}
+
+// Syncer syncs the membership of users in organizations with their groups.
+// The membership of the organizations named by its mappings is managed by
+// the groups: users are granted the highest role of their groups, and
+// removed from the organizations none of their groups map to.
+type Syncer struct {
+	Mappings []Mapping
+
+	OrganizationService        influxdb.OrganizationService
+	UserResourceMappingService influxdb.UserResourceMappingService
+}
+
+// Sync grants a user in groups their roles in the organizations of the
+// mappings.
+func (s *Syncer) Sync(ctx context.Context, userID influxdb.ID, groups []string) error {
+	member := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		member[g] = true
+	}
+
+	var orgs []string
+	roles := make(map[string]influxdb.UserType)
+	for _, m := range s.Mappings {
+		if _, ok := roles[m.Org]; !ok {
+			orgs = append(orgs, m.Org)
+			roles[m.Org] = ""
+		}
+		if member[m.Group] && roles[m.Org] != influxdb.Owner {
+			roles[m.Org] = m.Role
+		}
+	}
+
+	for _, name := range orgs {
+		o, err := s.OrganizationService.FindOrganization(ctx, influxdb.OrganizationFilter{Name: &name})
+		if err != nil {
+			return &influxdb.Error{
+				Msg: fmt.Sprintf("failed to find organization %q of group mappings", name),
+				Err: err,
+			}
+		}
+
+		ms, _, err := s.UserResourceMappingService.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{
+			ResourceID:   o.ID,
+			ResourceType: influxdb.OrgsResourceType,
+			UserID:       userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		role := roles[name]
+		if len(ms) > 0 {
+			if ms[0].UserType == role {
+				continue
+			}
+			if err := s.UserResourceMappingService.DeleteUserResourceMapping(ctx, o.ID, userID); err != nil {
+				return err
+			}
+		}
+		if role == "" {
+			continue
+		}
+		if err := s.UserResourceMappingService.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{
+			UserID:       userID,
+			UserType:     role,
+			ResourceType: influxdb.OrgsResourceType,
+			ResourceID:   o.ID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
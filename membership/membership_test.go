@@ -0,0 +1,113 @@
+package membership_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/membership"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    membership.Mapping
+		wantErr bool
+	}{
+		{in: "devs=acme", want: membership.Mapping{Group: "devs", Org: "acme", Role: influxdb.Member}},
+		{in: "admins=acme:owner", want: membership.Mapping{Group: "admins", Org: "acme", Role: influxdb.Owner}},
+		{in: "cn=ops,dc=acme=acme:member", want: membership.Mapping{Group: "cn=ops,dc=acme", Org: "acme", Role: influxdb.Member}},
+		{in: "devs", wantErr: true},
+		{in: "=acme", wantErr: true},
+		{in: "devs=", wantErr: true},
+		{in: "devs=:owner", wantErr: true},
+		{in: "devs=acme:admin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := membership.ParseMapping(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected mapping: got %+v, exp %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	acme := &influxdb.Organization{Name: "acme"}
+	ops := &influxdb.Organization{Name: "ops"}
+	for _, o := range []*influxdb.Organization{acme, ops} {
+		if err := svc.CreateOrganization(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u := &influxdb.User{Name: "jane"}
+	if err := svc.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &membership.Syncer{
+		Mappings: []membership.Mapping{
+			{Group: "devs", Org: "acme", Role: influxdb.Member},
+			{Group: "admins", Org: "acme", Role: influxdb.Owner},
+			{Group: "devs", Org: "ops", Role: influxdb.Member},
+		},
+		OrganizationService:        svc,
+		UserResourceMappingService: svc,
+	}
+
+	roles := func() map[influxdb.ID]influxdb.UserType {
+		ms, _, err := svc.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{
+			UserID:       u.ID,
+			ResourceType: influxdb.OrgsResourceType,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := make(map[influxdb.ID]influxdb.UserType)
+		for _, m := range ms {
+			r[m.ResourceID] = m.UserType
+		}
+		return r
+	}
+
+	if err := s.Sync(ctx, u.ID, []string{"devs", "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if r := roles(); len(r) != 2 || r[acme.ID] != influxdb.Member || r[ops.ID] != influxdb.Member {
+		t.Fatalf("unexpected roles: %v", r)
+	}
+
+	// The highest role of the groups of the user is granted.
+	if err := s.Sync(ctx, u.ID, []string{"admins", "devs"}); err != nil {
+		t.Fatal(err)
+	}
+	if r := roles(); len(r) != 2 || r[acme.ID] != influxdb.Owner || r[ops.ID] != influxdb.Member {
+		t.Fatalf("unexpected roles: %v", r)
+	}
+
+	// Users are removed from the organizations none of their groups map to.
+	if err := s.Sync(ctx, u.ID, []string{"admins"}); err != nil {
+		t.Fatal(err)
+	}
+	if r := roles(); len(r) != 1 || r[acme.ID] != influxdb.Owner {
+		t.Fatalf("unexpected roles: %v", r)
+	}
+
+	s.Mappings = append(s.Mappings, membership.Mapping{Group: "devs", Org: "missing", Role: influxdb.Member})
+	if err := s.Sync(ctx, u.ID, nil); err == nil {
+		t.Fatal("expected error for a mapping to a missing organization")
+	}
+}
@@ -0,0 +1,78 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// ErrMFANotEnrolled is an error message when a user is not enrolled in
+	// multi-factor authentication.
+	ErrMFANotEnrolled = "user is not enrolled in multi-factor authentication"
+
+	// ErrMFACodeRequired is an error message when signing in requires a
+	// multi-factor authentication code.
+	ErrMFACodeRequired = "multi-factor authentication code is required"
+
+	// ErrMFAInvalidCode is an error message when a multi-factor
+	// authentication code is not valid.
+	ErrMFAInvalidCode = "multi-factor authentication code is invalid"
+
+	// ErrMFALocked is an error message when too many invalid codes were
+	// given for a user.
+	ErrMFALocked = "too many invalid multi-factor authentication codes; try again later"
+)
+
+// ops for multi-factor authentication errors.
+const (
+	OpFindMFA               = "FindMFA"
+	OpEnrollTOTP            = "EnrollTOTP"
+	OpConfirmTOTP           = "ConfirmTOTP"
+	OpVerifyMFA             = "VerifyMFA"
+	OpGenerateRecoveryCodes = "GenerateRecoveryCodes"
+	OpDeleteMFA             = "DeleteMFA"
+)
+
+// MFA is the multi-factor authentication status of a user. Users enrolled
+// sign in with a code of their authenticator app, or with one of their
+// recovery codes, in addition to their password.
+type MFA struct {
+	UserID     ID         `json:"userID"`
+	Enrolled   bool       `json:"enrolled"`
+	EnrolledAt *time.Time `json:"enrolledAt,omitempty"`
+	// RecoveryCodes is the number of unused recovery codes.
+	RecoveryCodes int `json:"recoveryCodes"`
+}
+
+// TOTPEnrollment is a time-based one-time password secret generated for a
+// user, to add to their authenticator app.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// URL is the otpauth URL of the secret, usually shown as a QR code.
+	URL string `json:"url"`
+}
+
+// MFAService manages the multi-factor authentication of users.
+type MFAService interface {
+	// FindMFA returns the multi-factor authentication status of a user.
+	FindMFA(ctx context.Context, userID ID) (*MFA, error)
+
+	// EnrollTOTP generates a new TOTP secret for a user. It replaces the
+	// current secret of the user once confirmed.
+	EnrollTOTP(ctx context.Context, userID ID) (*TOTPEnrollment, error)
+
+	// ConfirmTOTP enrolls a user with the secret generated by EnrollTOTP if
+	// code is valid, and returns new recovery codes.
+	ConfirmTOTP(ctx context.Context, userID ID, code string) ([]string, error)
+
+	// VerifyMFA returns an error if code is neither a valid TOTP code nor an
+	// unused recovery code of an enrolled user. Recovery codes can only be
+	// used once.
+	VerifyMFA(ctx context.Context, userID ID, code string) error
+
+	// GenerateRecoveryCodes replaces the recovery codes of an enrolled user.
+	GenerateRecoveryCodes(ctx context.Context, userID ID) ([]string, error)
+
+	// DeleteMFA removes the multi-factor authentication of a user.
+	DeleteMFA(ctx context.Context, userID ID) error
+}
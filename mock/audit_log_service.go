@@ -0,0 +1,35 @@
+package mock
+
+import (
+	"context"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+var _ platform.AuditLogService = (*AuditLogService)(nil)
+
+// AuditLogService is a mock implementation of platform.AuditLogService.
+type AuditLogService struct {
+	AppendAuditEventFn func(context.Context, *platform.AuditEvent) error
+	FindAuditEventsFn  func(context.Context, platform.AuditEventFilter, ...platform.FindOptions) ([]*platform.AuditEvent, int, error)
+}
+
+// NewAuditLogService returns a mock of AuditLogService where its methods will return zero values.
+func NewAuditLogService() *AuditLogService {
+	return &AuditLogService{
+		AppendAuditEventFn: func(context.Context, *platform.AuditEvent) error { return nil },
+		FindAuditEventsFn: func(context.Context, platform.AuditEventFilter, ...platform.FindOptions) ([]*platform.AuditEvent, int, error) {
+			return nil, 0, nil
+		},
+	}
+}
+
+// AppendAuditEvent records an event.
+func (s *AuditLogService) AppendAuditEvent(ctx context.Context, e *platform.AuditEvent) error {
+	return s.AppendAuditEventFn(ctx, e)
+}
+
+// FindAuditEvents returns a list of events that match filter.
+func (s *AuditLogService) FindAuditEvents(ctx context.Context, filter platform.AuditEventFilter, opt ...platform.FindOptions) ([]*platform.AuditEvent, int, error) {
+	return s.FindAuditEventsFn(ctx, filter, opt...)
+}
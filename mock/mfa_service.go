@@ -0,0 +1,61 @@
+package mock
+
+import (
+	"context"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+var _ platform.MFAService = (*MFAService)(nil)
+
+// MFAService is a mock implementation of platform.MFAService.
+type MFAService struct {
+	FindMFAFn               func(context.Context, platform.ID) (*platform.MFA, error)
+	EnrollTOTPFn            func(context.Context, platform.ID) (*platform.TOTPEnrollment, error)
+	ConfirmTOTPFn           func(context.Context, platform.ID, string) ([]string, error)
+	VerifyMFAFn             func(context.Context, platform.ID, string) error
+	GenerateRecoveryCodesFn func(context.Context, platform.ID) ([]string, error)
+	DeleteMFAFn             func(context.Context, platform.ID) error
+}
+
+// NewMFAService returns a mock of MFAService where its methods will return zero values.
+func NewMFAService() *MFAService {
+	return &MFAService{
+		FindMFAFn:               func(context.Context, platform.ID) (*platform.MFA, error) { return nil, nil },
+		EnrollTOTPFn:            func(context.Context, platform.ID) (*platform.TOTPEnrollment, error) { return nil, nil },
+		ConfirmTOTPFn:           func(context.Context, platform.ID, string) ([]string, error) { return nil, nil },
+		VerifyMFAFn:             func(context.Context, platform.ID, string) error { return nil },
+		GenerateRecoveryCodesFn: func(context.Context, platform.ID) ([]string, error) { return nil, nil },
+		DeleteMFAFn:             func(context.Context, platform.ID) error { return nil },
+	}
+}
+
+// FindMFA returns the multi-factor authentication status of a user.
+func (s *MFAService) FindMFA(ctx context.Context, userID platform.ID) (*platform.MFA, error) {
+	return s.FindMFAFn(ctx, userID)
+}
+
+// EnrollTOTP generates a new TOTP secret for a user.
+func (s *MFAService) EnrollTOTP(ctx context.Context, userID platform.ID) (*platform.TOTPEnrollment, error) {
+	return s.EnrollTOTPFn(ctx, userID)
+}
+
+// ConfirmTOTP enrolls a user with the secret generated by EnrollTOTP.
+func (s *MFAService) ConfirmTOTP(ctx context.Context, userID platform.ID, code string) ([]string, error) {
+	return s.ConfirmTOTPFn(ctx, userID, code)
+}
+
+// VerifyMFA verifies a code of an enrolled user.
+func (s *MFAService) VerifyMFA(ctx context.Context, userID platform.ID, code string) error {
+	return s.VerifyMFAFn(ctx, userID, code)
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of an enrolled user.
+func (s *MFAService) GenerateRecoveryCodes(ctx context.Context, userID platform.ID) ([]string, error) {
+	return s.GenerateRecoveryCodesFn(ctx, userID)
+}
+
+// DeleteMFA removes the multi-factor authentication of a user.
+func (s *MFAService) DeleteMFA(ctx context.Context, userID platform.ID) error {
+	return s.DeleteMFAFn(ctx, userID)
+}
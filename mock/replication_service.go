@@ -0,0 +1,58 @@
+package mock
+
+import (
+	"context"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+var _ platform.ReplicationService = (*ReplicationService)(nil)
+
+// ReplicationService is a mock implementation of platform.ReplicationService.
+type ReplicationService struct {
+	FindReplicationByIDFn func(context.Context, platform.ID) (*platform.Replication, error)
+	FindReplicationsFn    func(context.Context, platform.ReplicationFilter) ([]*platform.Replication, int, error)
+	CreateReplicationFn   func(context.Context, *platform.Replication) error
+	UpdateReplicationFn   func(context.Context, platform.ID, platform.ReplicationUpdate) (*platform.Replication, error)
+	DeleteReplicationFn   func(context.Context, platform.ID) error
+}
+
+// NewReplicationService returns a mock of ReplicationService where its methods will return zero values.
+func NewReplicationService() *ReplicationService {
+	return &ReplicationService{
+		FindReplicationByIDFn: func(context.Context, platform.ID) (*platform.Replication, error) { return nil, nil },
+		FindReplicationsFn: func(context.Context, platform.ReplicationFilter) ([]*platform.Replication, int, error) {
+			return nil, 0, nil
+		},
+		CreateReplicationFn: func(context.Context, *platform.Replication) error { return nil },
+		UpdateReplicationFn: func(context.Context, platform.ID, platform.ReplicationUpdate) (*platform.Replication, error) {
+			return nil, nil
+		},
+		DeleteReplicationFn: func(context.Context, platform.ID) error { return nil },
+	}
+}
+
+// FindReplicationByID returns a single replication by ID.
+func (s *ReplicationService) FindReplicationByID(ctx context.Context, id platform.ID) (*platform.Replication, error) {
+	return s.FindReplicationByIDFn(ctx, id)
+}
+
+// FindReplications returns a list of replications that match filter.
+func (s *ReplicationService) FindReplications(ctx context.Context, filter platform.ReplicationFilter) ([]*platform.Replication, int, error) {
+	return s.FindReplicationsFn(ctx, filter)
+}
+
+// CreateReplication creates a new replication.
+func (s *ReplicationService) CreateReplication(ctx context.Context, r *platform.Replication) error {
+	return s.CreateReplicationFn(ctx, r)
+}
+
+// UpdateReplication updates a single replication with changeset.
+func (s *ReplicationService) UpdateReplication(ctx context.Context, id platform.ID, upd platform.ReplicationUpdate) (*platform.Replication, error) {
+	return s.UpdateReplicationFn(ctx, id, upd)
+}
+
+// DeleteReplication removes a replication by ID.
+func (s *ReplicationService) DeleteReplication(ctx context.Context, id platform.ID) error {
+	return s.DeleteReplicationFn(ctx, id)
+}
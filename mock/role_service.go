@@ -0,0 +1,81 @@
+package mock
+
+import (
+	"context"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+var _ platform.RoleService = (*RoleService)(nil)
+
+// RoleService is a mock implementation of platform.RoleService.
+type RoleService struct {
+	FindRoleByIDFn      func(context.Context, platform.ID) (*platform.Role, error)
+	FindRolesFn         func(context.Context, platform.RoleFilter, ...platform.FindOptions) ([]*platform.Role, int, error)
+	CreateRoleFn        func(context.Context, *platform.Role) error
+	UpdateRoleFn        func(context.Context, platform.ID, platform.RoleUpdate) (*platform.Role, error)
+	DeleteRoleFn        func(context.Context, platform.ID) error
+	FindRoleMappingsFn  func(context.Context, platform.RoleMappingFilter) ([]*platform.RoleMapping, int, error)
+	CreateRoleMappingFn func(context.Context, *platform.RoleMapping) error
+	DeleteRoleMappingFn func(context.Context, platform.ID, platform.RoleSubjectType, platform.ID) error
+}
+
+// NewRoleService returns a mock of RoleService where its methods will return zero values.
+func NewRoleService() *RoleService {
+	return &RoleService{
+		FindRoleByIDFn: func(context.Context, platform.ID) (*platform.Role, error) { return nil, nil },
+		FindRolesFn: func(context.Context, platform.RoleFilter, ...platform.FindOptions) ([]*platform.Role, int, error) {
+			return nil, 0, nil
+		},
+		CreateRoleFn: func(context.Context, *platform.Role) error { return nil },
+		UpdateRoleFn: func(context.Context, platform.ID, platform.RoleUpdate) (*platform.Role, error) {
+			return nil, nil
+		},
+		DeleteRoleFn: func(context.Context, platform.ID) error { return nil },
+		FindRoleMappingsFn: func(context.Context, platform.RoleMappingFilter) ([]*platform.RoleMapping, int, error) {
+			return nil, 0, nil
+		},
+		CreateRoleMappingFn: func(context.Context, *platform.RoleMapping) error { return nil },
+		DeleteRoleMappingFn: func(context.Context, platform.ID, platform.RoleSubjectType, platform.ID) error { return nil },
+	}
+}
+
+// FindRoleByID returns a single role by ID.
+func (s *RoleService) FindRoleByID(ctx context.Context, id platform.ID) (*platform.Role, error) {
+	return s.FindRoleByIDFn(ctx, id)
+}
+
+// FindRoles returns a list of roles that match filter.
+func (s *RoleService) FindRoles(ctx context.Context, filter platform.RoleFilter, opt ...platform.FindOptions) ([]*platform.Role, int, error) {
+	return s.FindRolesFn(ctx, filter, opt...)
+}
+
+// CreateRole creates a new role.
+func (s *RoleService) CreateRole(ctx context.Context, r *platform.Role) error {
+	return s.CreateRoleFn(ctx, r)
+}
+
+// UpdateRole updates a single role with changeset.
+func (s *RoleService) UpdateRole(ctx context.Context, id platform.ID, upd platform.RoleUpdate) (*platform.Role, error) {
+	return s.UpdateRoleFn(ctx, id, upd)
+}
+
+// DeleteRole removes a role by ID.
+func (s *RoleService) DeleteRole(ctx context.Context, id platform.ID) error {
+	return s.DeleteRoleFn(ctx, id)
+}
+
+// FindRoleMappings returns a list of assignments of roles that match filter.
+func (s *RoleService) FindRoleMappings(ctx context.Context, filter platform.RoleMappingFilter) ([]*platform.RoleMapping, int, error) {
+	return s.FindRoleMappingsFn(ctx, filter)
+}
+
+// CreateRoleMapping assigns a role.
+func (s *RoleService) CreateRoleMapping(ctx context.Context, m *platform.RoleMapping) error {
+	return s.CreateRoleMappingFn(ctx, m)
+}
+
+// DeleteRoleMapping removes the assignment of a role.
+func (s *RoleService) DeleteRoleMapping(ctx context.Context, roleID platform.ID, subjectType platform.RoleSubjectType, subjectID platform.ID) error {
+	return s.DeleteRoleMappingFn(ctx, roleID, subjectType, subjectID)
+}
@@ -49,3 +49,29 @@ func (s *SessionService) ExpireSession(ctx context.Context, key string) error {
 func (s *SessionService) RenewSession(ctx context.Context, session *platform.Session, expiredAt time.Time) error {
 	return s.RenewSessionFn(ctx, session, expiredAt)
 }
+
+var _ platform.UserSessionService = (*UserSessionService)(nil)
+
+// UserSessionService is a mock implementation of platform.UserSessionService.
+type UserSessionService struct {
+	FindUserSessionsFn  func(context.Context, platform.ID) ([]*platform.Session, error)
+	DeleteUserSessionFn func(context.Context, platform.ID, platform.ID) error
+}
+
+// NewUserSessionService returns a mock of UserSessionService where its methods will return zero values.
+func NewUserSessionService() *UserSessionService {
+	return &UserSessionService{
+		FindUserSessionsFn:  func(context.Context, platform.ID) ([]*platform.Session, error) { return nil, nil },
+		DeleteUserSessionFn: func(context.Context, platform.ID, platform.ID) error { return nil },
+	}
+}
+
+// FindUserSessions returns the unexpired sessions of a user.
+func (s *UserSessionService) FindUserSessions(ctx context.Context, userID platform.ID) ([]*platform.Session, error) {
+	return s.FindUserSessionsFn(ctx, userID)
+}
+
+// DeleteUserSession revokes the session of a user with the id sessionID.
+func (s *UserSessionService) DeleteUserSession(ctx context.Context, userID, sessionID platform.ID) error {
+	return s.DeleteUserSessionFn(ctx, userID, sessionID)
+}
@@ -187,6 +187,8 @@ func (t FieldType) String() string {
 		return "String"
 	case Empty:
 		return "Empty"
+	case Unsigned:
+		return "Unsigned"
 	default:
 		return "<unknown>"
 	}
@@ -362,12 +364,38 @@ func ValidPrecision(precision string) bool {
 // This can have the unintended effect preventing buf from being garbage collected.
 func ParsePointsWithPrecision(buf []byte, defaultTime time.Time, precision string) ([]Point, error) {
 	points := make([]Point, 0, bytes.Count(buf, []byte{'\n'})+1)
+	var failed []string
+	ParseLinesWithPrecision(buf, defaultTime, precision, func(line int, text []byte, pt Point, err error) {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("unable to parse '%s': %v", string(text), err))
+		} else {
+			points = append(points, pt)
+		}
+	})
+	if len(failed) > 0 {
+		return points, fmt.Errorf("%s", strings.Join(failed, "\n"))
+	}
+	return points, nil
+
+}
+
+// ParseLinesWithPrecision parses each line of buf like ParsePointsWithPrecision, and
+// calls fn with the line number, starting at 1, and text of the line along with
+// either the point or the error parsing it. Blank lines and comments are skipped.
+//
+// NOTE: the text and points refer to subslices of buf.
+func ParseLinesWithPrecision(buf []byte, defaultTime time.Time, precision string, fn func(line int, text []byte, pt Point, err error)) {
 	var (
-		pos    int
-		block  []byte
-		failed []string
+		pos   int
+		block []byte
+
+		// The line number of the block, and the position it was counted up to.
+		line, counted = 1, 0
 	)
 	for pos < len(buf) {
+		line += bytes.Count(buf[counted:pos], []byte{'\n'})
+		counted = pos
+
 		pos, block = scanLine(buf, pos)
 		pos++
 
@@ -393,18 +421,30 @@ func ParsePointsWithPrecision(buf []byte, defaultTime time.Time, precision strin
 		}
 
 		pt, err := parsePoint(block[start:], defaultTime, precision)
-		if err != nil {
-			failed = append(failed, fmt.Sprintf("unable to parse '%s': %v", string(block[start:]), err))
-		} else {
-			points = append(points, pt)
+		fn(line, block[start:], pt, err)
+	}
+}
+
+// CompleteLines returns the length of the longest prefix of buf that holds only
+// complete lines, each ending with a newline that is not part of a quoted field
+// value. It allows line protocol read from a stream to be parsed a piece at a time
+// without splitting any of its points.
+func CompleteLines(buf []byte) int {
+	var n int
+	for n < len(buf) {
+		end, _ := scanLine(buf, n)
+		if end >= len(buf) {
+			break
 		}
 
+		// An escaped newline at the end of buf is only skipped by scanLine once
+		// the character after it has been read.
+		if end == len(buf)-1 && end > n && buf[end-1] == '\\' {
+			break
+		}
+		n = end + 1
 	}
-	if len(failed) > 0 {
-		return points, fmt.Errorf("%s", strings.Join(failed, "\n"))
-	}
-	return points, nil
-
+	return n
 }
 
 func parsePoint(buf []byte, defaultTime time.Time, precision string) (Point, error) {
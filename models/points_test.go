@@ -1930,6 +1930,61 @@ cpu,host=serverA,region=us-east value=1.0 946730096789012345`,
 	}
 }
 
+func TestParseLinesWithPrecision(t *testing.T) {
+	batch := `# comment
+
+cpu value=1 1
+cpu value= 2
+  mem text="multiple
+lines" 3
+disk free=4i 4
+`
+	type line struct {
+		line int
+		text string
+		err  bool
+	}
+
+	var got []line
+	models.ParseLinesWithPrecision([]byte(batch), time.Now().UTC(), "", func(n int, text []byte, pt models.Point, err error) {
+		if (pt == nil) == (err == nil) {
+			t.Errorf("line %d: expected either a point or an error, got %v and %v", n, pt, err)
+		}
+		got = append(got, line{line: n, text: string(text), err: err != nil})
+	})
+
+	exp := []line{
+		{line: 3, text: "cpu value=1 1"},
+		{line: 4, text: "cpu value= 2", err: true},
+		{line: 5, text: "mem text=\"multiple\nlines\" 3"},
+		{line: 7, text: "disk free=4i 4"},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected lines:\n got %+v\n exp %+v", got, exp)
+	}
+}
+
+func TestCompleteLines(t *testing.T) {
+	tests := []struct {
+		buf string
+		exp int
+	}{
+		{buf: "", exp: 0},
+		{buf: "cpu value=1 1", exp: 0},
+		{buf: "cpu value=1 1\n", exp: 14},
+		{buf: "cpu value=1 1\ncpu value=2 2", exp: 14},
+		{buf: "cpu value=1 1\nmem text=\"multiple\n", exp: 14},
+		{buf: "cpu value=1 1\nmem text=\"multiple\nlines\" 3\n", exp: 42},
+		{buf: "cpu value=1 1\nmem text=\"escaped\\\n", exp: 14},
+	}
+
+	for _, tt := range tests {
+		if got := models.CompleteLines([]byte(tt.buf)); got != tt.exp {
+			t.Errorf("CompleteLines(%q) = %d, expected %d", tt.buf, got, tt.exp)
+		}
+	}
+}
+
 func TestNewPointEscaped(t *testing.T) {
 	// commas
 	pt := models.MustNewPoint("cpu,main", models.NewTags(map[string]string{"tag,bar": "value"}), models.Fields{"name,bar": 1.0}, time.Unix(0, 0))
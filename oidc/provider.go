@@ -0,0 +1,273 @@
+// Package oidc signs users in with an OpenID Connect identity provider,
+// through the authorization code flow.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
+)
+
+// Config configures an identity provider.
+type Config struct {
+	// Issuer is the URL of the identity provider. Its endpoints are
+	// discovered from it.
+	Issuer string
+
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the URL the identity provider redirects users to once
+	// they signed in, the /api/v2/signin/oidc/callback route of influxd.
+	RedirectURL string
+
+	// Scopes are the scopes requested besides openid.
+	Scopes []string
+
+	// Client is used to reach the identity provider. http.DefaultClient is
+	// used if it is nil.
+	Client *http.Client
+}
+
+// keysRefetchInterval is how often the keys of an identity provider may be
+// fetched again when a token is signed by an unknown key.
+const keysRefetchInterval = time.Minute
+
+// Provider is an identity provider that users sign in with.
+type Provider struct {
+	issuer   string
+	clientID string
+	jwksURL  string
+	client   *http.Client
+	config   oauth2.Config
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time // when keys were last fetched
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider discovers the identity provider of c.
+func NewProvider(ctx context.Context, c Config) (*Provider, error) {
+	if c.Issuer == "" || c.ClientID == "" || c.RedirectURL == "" {
+		return nil, errors.New("issuer, client ID and redirect URL are required")
+	}
+
+	p := &Provider{
+		issuer:   c.Issuer,
+		clientID: c.ClientID,
+		client:   c.Client,
+	}
+	if p.client == nil {
+		p.client = http.DefaultClient
+	}
+
+	var d discovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(c.Issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider: %v", err)
+	}
+	if d.Issuer != c.Issuer {
+		return nil, fmt.Errorf("identity provider issuer %q does not match %q", d.Issuer, c.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("identity provider configuration is missing endpoints")
+	}
+	p.jwksURL = d.JWKSURI
+
+	scopes := []string{"openid"}
+	for _, s := range c.Scopes {
+		if s != "openid" {
+			scopes = append(scopes, s)
+		}
+	}
+	p.config = oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RedirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  d.AuthorizationEndpoint,
+			TokenURL: d.TokenEndpoint,
+		},
+	}
+	return p, nil
+}
+
+// AuthCodeURL returns the URL to redirect users to for them to sign in.
+// The state is passed back to the callback, and the nonce is claimed by the
+// ID token of the user.
+func (p *Provider) AuthCodeURL(state, nonce string) string {
+	return p.config.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce))
+}
+
+// Exchange exchanges the code passed to the callback for the ID token of
+// the user, and returns its claims once verified.
+func (p *Provider) Exchange(ctx context.Context, code, nonce string) (Claims, error) {
+	tok, err := p.config.Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client), code)
+	if err != nil {
+		return nil, err
+	}
+	raw, _ := tok.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errors.New("token response has no ID token")
+	}
+	return p.verify(ctx, raw, nonce)
+}
+
+// verify checks the signature, issuer, audience, expiry and nonce of an ID
+// token.
+func (p *Provider) verify(ctx context.Context, raw, nonce string) (Claims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unsupported signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	}); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %v", err)
+	}
+
+	c := Claims(claims)
+	if _, ok := c["exp"]; !ok {
+		return nil, errors.New("ID token has no expiry")
+	}
+	if c.String("iss") != p.issuer {
+		return nil, errors.New("ID token was issued by another issuer")
+	}
+	if !c.has("aud", p.clientID) {
+		return nil, errors.New("ID token was issued to another client")
+	}
+	if c.String("nonce") != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+	return c, nil
+}
+
+// key returns the public key of the identity provider with an ID.
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+
+	// The identity provider may have rotated its keys since they were fetched.
+	// They are fetched at most once per keysRefetchInterval, so that tokens
+	// signed by unknown keys cannot make requests to the identity provider
+	// at will.
+	if !p.fetched.IsZero() && time.Since(p.fetched) < keysRefetchInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	p.fetched = time.Now()
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.jwksURL, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch identity provider keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	p.keys = keys
+
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Claims are the claims of an ID token.
+type Claims map[string]interface{}
+
+// String returns a string claim, or an empty string if it is not one.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns a claim that is a list of strings, or a single string.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		ss := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+		return ss
+	case []string:
+		return v
+	}
+	return nil
+}
+
+func (c Claims) has(name, value string) bool {
+	for _, s := range c.Strings(name) {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/oidc/oidctest"
+)
+
+// signIn follows the redirect of the identity provider to the callback,
+// and returns the code it is passed.
+func signIn(t *testing.T, p *Provider, state, nonce string) string {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(p.AuthCodeURL(state, nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loc.Query().Get("state"); got != state {
+		t.Fatalf("unexpected state: got %q, exp %q", got, state)
+	}
+	return loc.Query().Get("code")
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	idp := oidctest.NewServer("influxdb", "secret")
+	defer idp.Close()
+	idp.SetClaims(map[string]interface{}{
+		"sub":                "1",
+		"preferred_username": "jane",
+		"groups":             []string{"devs", "admins"},
+	})
+
+	ctx := context.Background()
+	p, err := NewProvider(ctx, Config{
+		Issuer:       idp.URL,
+		ClientID:     "influxdb",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:9999/api/v2/signin/oidc/callback",
+		Scopes:       []string{"profile"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := signIn(t, p, "state", "nonce")
+	claims, err := p.Exchange(ctx, code, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := claims.String("preferred_username"); got != "jane" {
+		t.Fatalf("unexpected username: %q", got)
+	}
+	if got := claims.Strings("groups"); len(got) != 2 || got[0] != "devs" || got[1] != "admins" {
+		t.Fatalf("unexpected groups: %v", got)
+	}
+
+	// Codes are used once.
+	if _, err := p.Exchange(ctx, code, "nonce"); err == nil {
+		t.Fatal("expected error exchanging a code twice")
+	}
+
+	code = signIn(t, p, "state", "nonce")
+	if _, err := p.Exchange(ctx, code, "other"); err == nil {
+		t.Fatal("expected error for a nonce that does not match")
+	}
+}
+
+func TestProvider_verify(t *testing.T) {
+	idp := oidctest.NewServer("influxdb", "secret")
+	defer idp.Close()
+
+	p, err := NewProvider(context.Background(), Config{
+		Issuer:      idp.URL,
+		ClientID:    "influxdb",
+		RedirectURL: "http://localhost:9999/api/v2/signin/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(update func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   idp.URL,
+			"aud":   []string{"other", "influxdb"},
+			"sub":   "1",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce",
+		}
+		if update != nil {
+			update(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			token: idp.IDToken(claims(nil)),
+		},
+		{
+			name:    "expired",
+			token:   idp.IDToken(claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			token:   idp.IDToken(claims(func(c map[string]interface{}) { delete(c, "exp") })),
+			wantErr: true,
+		},
+		{
+			name:    "other issuer",
+			token:   idp.IDToken(claims(func(c map[string]interface{}) { c["iss"] = "http://example.com" })),
+			wantErr: true,
+		},
+		{
+			name:    "other audience",
+			token:   idp.IDToken(claims(func(c map[string]interface{}) { c["aud"] = "other" })),
+			wantErr: true,
+		},
+		{
+			name:    "tampered",
+			token:   idp.IDToken(claims(nil))[:20] + "x" + idp.IDToken(claims(nil))[21:],
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.verify(context.Background(), tt.token, "nonce")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// countingTransport counts the requests made to the path.
+type countingTransport struct {
+	path string
+
+	mu sync.Mutex
+	n  int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path == t.path {
+		t.mu.Lock()
+		t.n++
+		t.mu.Unlock()
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func (t *countingTransport) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.n
+}
+
+func TestProvider_key(t *testing.T) {
+	idp := oidctest.NewServer("influxdb", "secret")
+	defer idp.Close()
+
+	ctx := context.Background()
+	keys := &countingTransport{path: "/keys"}
+	p, err := NewProvider(ctx, Config{
+		Issuer:      idp.URL,
+		ClientID:    "influxdb",
+		RedirectURL: "http://localhost:9999/api/v2/signin/oidc/callback",
+		Client:      &http.Client{Transport: keys},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := idp.IDToken(map[string]interface{}{
+		"iss":   idp.URL,
+		"aud":   "influxdb",
+		"sub":   "1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "nonce",
+	})
+	if _, err := p.verify(ctx, token, "nonce"); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys.count(); got != 1 {
+		t.Fatalf("got %d requests for keys, expected 1", got)
+	}
+
+	// Unknown keys do not make the keys be fetched again right away.
+	for i := 0; i < 3; i++ {
+		if _, err := p.key(ctx, "unknown"); err == nil {
+			t.Fatal("expected error for an unknown key")
+		}
+	}
+	if _, err := p.verify(ctx, token, "nonce"); err != nil {
+		t.Fatal(err)
+	}
+	if got := keys.count(); got != 1 {
+		t.Fatalf("got %d requests for keys, expected 1", got)
+	}
+
+	// They are once the interval passed.
+	p.fetched = p.fetched.Add(-keysRefetchInterval)
+	if _, err := p.key(ctx, "unknown"); err == nil {
+		t.Fatal("expected error for an unknown key")
+	}
+	if got := keys.count(); got != 2 {
+		t.Fatalf("got %d requests for keys, expected 2", got)
+	}
+}
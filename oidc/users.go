@@ -0,0 +1,110 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/membership"
+)
+
+// Default claims of users.
+const (
+	DefaultUsernameClaim = "preferred_username"
+	DefaultGroupsClaim   = "groups"
+)
+
+// UserProvisioner maps the claims of users signing in to users, and to
+// their membership of organizations.
+//
+// Users are linked to their account by the issuer and subject of their
+// tokens the first time they sign in, when a user is created for them. An
+// account is never linked to a user that already exists, so that accounts
+// of the identity provider cannot take over local users with the same name.
+type UserProvisioner struct {
+	// UsernameClaim names the claim holding the name of users, and
+	// GroupsClaim the claim listing their groups. The defaults are used if
+	// they are empty.
+	UsernameClaim string
+	GroupsClaim   string
+
+	// Membership syncs the membership of users in organizations with their
+	// groups.
+	Membership *membership.Syncer
+
+	UserService         influxdb.UserService
+	UserIdentityService influxdb.UserIdentityService
+}
+
+// Provision returns the user linked to the account of claims, creating them
+// if the account is not linked yet, and updates their membership of
+// organizations.
+func (p *UserProvisioner) Provision(ctx context.Context, claims Claims) (*influxdb.User, error) {
+	issuer, subject := claims.String("iss"), claims.String("sub")
+	if issuer == "" || subject == "" {
+		return nil, &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  "ID token has no iss or sub claim",
+		}
+	}
+
+	var u *influxdb.User
+	i, err := p.UserIdentityService.FindUserIdentity(ctx, issuer, subject)
+	switch {
+	case err == nil:
+		u, err = p.UserService.FindUserByID(ctx, i.UserID)
+	case influxdb.ErrorCode(err) == influxdb.ENotFound:
+		u, err = p.create(ctx, claims, issuer, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Membership != nil {
+		groupsClaim := p.GroupsClaim
+		if groupsClaim == "" {
+			groupsClaim = DefaultGroupsClaim
+		}
+		if err := p.Membership.Sync(ctx, u.ID, claims.Strings(groupsClaim)); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// create creates a user for an account and links them to it.
+func (p *UserProvisioner) create(ctx context.Context, claims Claims, issuer, subject string) (*influxdb.User, error) {
+	usernameClaim := p.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = DefaultUsernameClaim
+	}
+	name := claims.String(usernameClaim)
+	if name == "" {
+		return nil, &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  fmt.Sprintf("ID token has no %s claim", usernameClaim),
+		}
+	}
+
+	if _, err := p.UserService.FindUser(ctx, influxdb.UserFilter{Name: &name}); err == nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  influxdb.ErrUserNotLinked,
+		}
+	} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, err
+	}
+
+	u := &influxdb.User{Name: name}
+	if err := p.UserService.CreateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	if err := p.UserIdentityService.CreateUserIdentity(ctx, &influxdb.UserIdentity{
+		Provider: issuer,
+		Subject:  subject,
+		UserID:   u.ID,
+	}); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,90 @@
+package oidc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/membership"
+	"github.com/influxdata/influxdb/oidc"
+)
+
+func TestUserProvisioner_Provision(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	acme := &influxdb.Organization{Name: "acme"}
+	if err := svc.CreateOrganization(ctx, acme); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &oidc.UserProvisioner{
+		GroupsClaim: "roles",
+		Membership: &membership.Syncer{
+			Mappings:                   []membership.Mapping{{Group: "devs", Org: "acme", Role: influxdb.Member}},
+			OrganizationService:        svc,
+			UserResourceMappingService: svc,
+		},
+		UserService:         svc,
+		UserIdentityService: svc,
+	}
+
+	const issuer = "https://idp.example.com"
+	u, err := p.Provision(ctx, oidc.Claims{"iss": issuer, "sub": "1", "preferred_username": "jane", "roles": []interface{}{"devs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "jane" || !u.ID.Valid() {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+	ms, _, err := svc.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{UserID: u.ID, ResourceID: acme.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].UserType != influxdb.Member {
+		t.Fatalf("unexpected memberships: %v", ms)
+	}
+
+	// The account is linked to the user, whatever their name.
+	again, err := p.Provision(ctx, oidc.Claims{"iss": issuer, "sub": "1", "preferred_username": "jane.doe", "roles": "devs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != u.ID {
+		t.Fatalf("expected the existing user, got %+v", again)
+	}
+
+	// Other accounts cannot sign in as users that exist, even if they were
+	// created from the identity provider.
+	local := &influxdb.User{Name: "admin"}
+	if err := svc.CreateUser(ctx, local); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"admin", "jane"} {
+		_, err = p.Provision(ctx, oidc.Claims{"iss": issuer, "sub": "2", "preferred_username": name, "roles": "devs"})
+		if influxdb.ErrorCode(err) != influxdb.EForbidden {
+			t.Fatalf("expected forbidden error signing in as %q, got %v", name, err)
+		}
+	}
+	ms, _, err = svc.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{UserID: local.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 0 {
+		t.Fatalf("expected the memberships of the local user to be unchanged, got %v", ms)
+	}
+
+	_, err = p.Provision(ctx, oidc.Claims{"iss": issuer, "sub": "3", "email": "jane@example.com"})
+	if influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+		t.Fatalf("expected unauthorized error without a username, got %v", err)
+	}
+	_, err = p.Provision(ctx, oidc.Claims{"iss": issuer, "preferred_username": "john"})
+	if influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+		t.Fatalf("expected unauthorized error without a subject, got %v", err)
+	}
+}
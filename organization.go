@@ -6,6 +6,10 @@ import "context"
 type Organization struct {
 	ID   ID     `json:"id,omitempty"`
 	Name string `json:"name"`
+	// RequireMFA is set by owners to require members to enroll in
+	// multi-factor authentication. Sessions of members not enrolled are not
+	// granted the permissions of the organization.
+	RequireMFA bool `json:"requireMFA,omitempty"`
 }
 
 // ops for orgs error and orgs op logs.
@@ -44,7 +48,8 @@ type OrganizationService interface {
 // OrganizationUpdate represents updates to a organization.
 // Only fields which are set are updated.
 type OrganizationUpdate struct {
-	Name *string
+	Name       *string
+	RequireMFA *bool
 }
 
 // OrganizationFilter represents a set of filter that restrict the returned results.
@@ -124,6 +124,36 @@ func (s *AuthorizationService) SetAuthorizationStatus(ctx context.Context, id pl
 	return s.AuthorizationService.SetAuthorizationStatus(ctx, id, status)
 }
 
+// UpdateAuthorization updates the status, description, expiry or last use of
+// the authorization, records function call latency, and counts function calls.
+func (s *AuthorizationService) UpdateAuthorization(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (a *platform.Authorization, err error) {
+	defer func(start time.Time) {
+		labels := prometheus.Labels{
+			"method": "UpdateAuthorization",
+			"error":  fmt.Sprint(err != nil),
+		}
+		s.requestCount.With(labels).Add(1)
+		s.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	return s.AuthorizationService.UpdateAuthorization(ctx, id, upd)
+}
+
+// RotateAuthorization replaces the token of the authorization, records
+// function call latency, and counts function calls.
+func (s *AuthorizationService) RotateAuthorization(ctx context.Context, id platform.ID, grace time.Duration) (a *platform.Authorization, err error) {
+	defer func(start time.Time) {
+		labels := prometheus.Labels{
+			"method": "RotateAuthorization",
+			"error":  fmt.Sprint(err != nil),
+		}
+		s.requestCount.With(labels).Add(1)
+		s.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	return s.AuthorizationService.RotateAuthorization(ctx, id, grace)
+}
+
 // PrometheusCollectors returns all authorization service prometheus collectors.
 func (s *AuthorizationService) PrometheusCollectors() []prometheus.Collector {
 	return []prometheus.Collector{
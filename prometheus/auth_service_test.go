@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/prom"
@@ -42,6 +43,14 @@ func (a *authzSvc) SetAuthorizationStatus(context.Context, platform.ID, platform
 	return a.Err
 }
 
+func (a *authzSvc) UpdateAuthorization(context.Context, platform.ID, *platform.AuthorizationUpdate) (*platform.Authorization, error) {
+	return nil, a.Err
+}
+
+func (a *authzSvc) RotateAuthorization(context.Context, platform.ID, time.Duration) (*platform.Authorization, error) {
+	return nil, a.Err
+}
+
 func TestAuthorizationService_Metrics(t *testing.T) {
 	a := new(authzSvc)
 
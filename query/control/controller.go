@@ -11,6 +11,7 @@ import (
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb/secrets"
 )
 
 // orgLabel is the metric label to use in the controller
@@ -19,6 +20,10 @@ const orgLabel = "org"
 // Controller implements AsyncQueryService by consuming a control.Controller.
 type Controller struct {
 	c *control.Controller
+
+	// SecretService is read by the calls of secrets.get in scripts.
+	// They return empty strings if it is nil.
+	SecretService platform.SecretService
 }
 
 // NewController creates a new Controller specific to platform.
@@ -37,17 +42,37 @@ func (c *Controller) Query(ctx context.Context, req *query.Request) (flux.Query,
 	ctx = query.ContextWithRequest(ctx, req)
 	// Set the org label value for controller metrics
 	ctx = context.WithValue(ctx, orgLabel, req.OrganizationID.String())
-	q, err := c.c.Query(ctx, req.Compiler)
-	if err != nil {
-		// If the controller reports an error, it's usually because of a syntax error
-		// or other problem that the client must fix.
-		return q, &platform.Error{
-			Code: platform.EInvalid,
-			Msg:  err.Error(),
+
+	if c.SecretService == nil {
+		q, err := c.c.Query(ctx, req.Compiler)
+		if err != nil {
+			return q, queryError(err)
 		}
+		return q, nil
+	}
+
+	// Secrets are read with the authorization of the request, and redacted
+	// from the errors of the query.
+	var auth platform.Authorizer
+	if req.Authorization != nil {
+		auth = req.Authorization
+	}
+	compiler := secrets.NewCompiler(req.Compiler, req.OrganizationID, auth, c.SecretService)
+	q, err := c.c.Query(ctx, compiler)
+	if err != nil {
+		return q, queryError(compiler.Redact(err))
 	}
 
-	return q, nil
+	return &secrets.Query{Query: q, Compiler: compiler}, nil
+}
+
+// queryError reports an error of the controller to the client. It's usually
+// because of a syntax error or other problem that the client must fix.
+func queryError(err error) error {
+	return &platform.Error{
+		Code: platform.EInvalid,
+		Msg:  err.Error(),
+	}
 }
 
 // PrometheusCollectors satisifies the prom.PrometheusCollector interface.
@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/lang"
+	platform "github.com/influxdata/influxdb"
+)
+
+// Compiler wraps the compiler of a query so that the calls of secrets.get in
+// its script read the secrets of the organization executing it. Scripts are
+// compiled from a copy of their AST, so the values of secrets are not part of
+// the request, which is logged.
+type Compiler struct {
+	flux.Compiler
+
+	OrgID         platform.ID
+	Authorizer    platform.Authorizer
+	SecretService platform.SecretService
+
+	mu      sync.Mutex
+	secrets []string
+}
+
+// NewCompiler returns a Compiler reading the secrets of orgID from s for c,
+// if auth is allowed to read them.
+func NewCompiler(c flux.Compiler, orgID platform.ID, auth platform.Authorizer, s platform.SecretService) *Compiler {
+	return &Compiler{
+		Compiler:      c,
+		OrgID:         orgID,
+		Authorizer:    auth,
+		SecretService: s,
+	}
+}
+
+// Compile resolves the secrets of a Flux script or AST and compiles it.
+// Specs are compiled already and are returned as is.
+func (c *Compiler) Compile(ctx context.Context) (*flux.Spec, error) {
+	var (
+		pkg *ast.Package
+		now time.Time
+	)
+	switch cc := c.Compiler.(type) {
+	case lang.FluxCompiler:
+		pkg = parse(cc.Query)
+	case *lang.FluxCompiler:
+		pkg = parse(cc.Query)
+	case lang.ASTCompiler:
+		pkg, now = copyAST(cc.AST), cc.Now
+	case *lang.ASTCompiler:
+		pkg, now = copyAST(cc.AST), cc.Now
+	}
+	if pkg == nil {
+		return c.Compiler.Compile(ctx)
+	}
+
+	secrets, err := Resolve(ctx, pkg, c.OrgID, c.Authorizer, c.SecretService)
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		return c.Compiler.Compile(ctx)
+	}
+
+	c.mu.Lock()
+	c.secrets = secrets
+	c.mu.Unlock()
+
+	if now.IsZero() {
+		now = time.Now()
+	}
+	spec, err := flux.CompileAST(ctx, pkg, now)
+	return spec, c.Redact(err)
+}
+
+// Redact replaces the values of the secrets read by Compile in the message
+// of err.
+func (c *Compiler) Redact(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Redact(err, c.secrets)
+}
+
+// parse returns the AST of a script, or nil if it cannot be parsed, in which
+// case compiling it reports the error.
+func parse(q string) *ast.Package {
+	pkg, err := flux.Parse(q)
+	if err != nil {
+		return nil
+	}
+	return pkg
+}
+
+func copyAST(pkg *ast.Package) *ast.Package {
+	if pkg == nil {
+		return nil
+	}
+	return pkg.Copy().(*ast.Package)
+}
+
+// Query wraps a query compiled by a Compiler, and redacts the secrets read
+// from its errors, which are logged and recorded in the logs of task runs.
+type Query struct {
+	flux.Query
+	Compiler *Compiler
+}
+
+// Err reports any error the query encountered, with secrets redacted.
+func (q *Query) Err() error {
+	return q.Compiler.Redact(q.Query.Err())
+}
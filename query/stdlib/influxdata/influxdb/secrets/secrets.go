@@ -0,0 +1,257 @@
+// Package secrets implements the Flux secrets.get function, which reads the
+// secrets of the organization executing a script from a SecretService.
+//
+// Flux builtins are evaluated without a context, so the secrets are read by
+// the query service before the script is compiled: Resolve sets the value of
+// every call of secrets.get in the AST of the script. Outside of a query
+// executed for an organization, such as when a script is only analyzed,
+// secrets.get returns an empty string.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+	platform "github.com/influxdata/influxdb"
+)
+
+const (
+	// PackagePath is the import path of the Flux package.
+	PackagePath = "influxdata/influxdb/secrets"
+
+	// GetKind is the name of the function reading a secret.
+	GetKind = "get"
+
+	// valueParam is the parameter Resolve sets to the value of the secret.
+	valueParam = "_value"
+
+	// Redacted replaces the values of secrets in errors.
+	Redacted = "[REDACTED]"
+)
+
+var getSignature = semantic.FunctionPolySignature{
+	Parameters: map[string]semantic.PolyType{
+		"key":      semantic.String,
+		valueParam: semantic.String,
+	},
+	Required: semantic.LabelSet{"key"},
+	Return:   semantic.String,
+}
+
+func init() {
+	flux.RegisterPackageValue(PackagePath, GetKind, values.NewFunction(GetKind, semantic.NewFunctionPolyType(getSignature), get, false))
+}
+
+func get(args values.Object) (values.Value, error) {
+	a := interpreter.NewArguments(args)
+	if _, err := a.GetRequiredString("key"); err != nil {
+		return nil, err
+	}
+	v, _, err := a.GetString(valueParam)
+	if err != nil {
+		return nil, err
+	}
+	return values.NewString(v), nil
+}
+
+// Resolve sets the value of every call of secrets.get in pkg to the secret of
+// orgID read from s, and returns the values set. The keys of the calls must
+// be string literals, secrets.get can only be called directly, and auth
+// must be allowed to read the secrets of orgID.
+// pkg is modified in place, so callers should pass a copy of the AST of a
+// request.
+func Resolve(ctx context.Context, pkg *ast.Package, orgID platform.ID, auth platform.Authorizer, s platform.SecretService) ([]string, error) {
+	calls, err := findGetCalls(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	p, err := platform.NewPermission(platform.ReadAction, platform.SecretsResourceType, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if auth == nil || !auth.Allowed(*p) {
+		return nil, &platform.Error{
+			Code: platform.EUnauthorized,
+			Msg:  fmt.Sprintf("%s is unauthorized", p),
+		}
+	}
+
+	var resolved []string
+	secrets := make(map[string]string)
+	for _, call := range calls {
+		key, err := getKey(call)
+		if err != nil {
+			return nil, err
+		}
+
+		v, ok := secrets[key]
+		if !ok {
+			v, err = s.LoadSecret(ctx, orgID, key)
+			if err != nil {
+				return nil, &platform.Error{
+					Msg: fmt.Sprintf("cannot read secret %q", key),
+					Err: err,
+				}
+			}
+			secrets[key] = v
+			if v != "" {
+				resolved = append(resolved, v)
+			}
+		}
+		setValue(call, v)
+	}
+	return resolved, nil
+}
+
+// Redact replaces the values of secrets in the message of err. The code and
+// operation of platform errors are kept, so that they are still reported to
+// clients properly.
+func Redact(err error, secrets []string) error {
+	if err == nil || len(secrets) == 0 || !containsAny(err.Error(), secrets) {
+		return err
+	}
+	e, ok := err.(*platform.Error)
+	if !ok {
+		return errors.New(redact(err.Error(), secrets))
+	}
+	return &platform.Error{
+		Code: e.Code,
+		Op:   e.Op,
+		Msg:  redact(e.Msg, secrets),
+		Err:  Redact(e.Err, secrets),
+	}
+}
+
+func containsAny(s string, secrets []string) bool {
+	for _, v := range secrets {
+		if strings.Contains(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(s string, secrets []string) string {
+	for _, v := range secrets {
+		s = strings.Replace(s, v, Redacted, -1)
+	}
+	return s
+}
+
+// findGetCalls returns the calls of secrets.get in pkg, by any name the
+// package is imported as. Any other use of the package, such as assigning
+// secrets.get to a variable or passing it to a function, is rejected, since
+// its calls could not be resolved.
+func findGetCalls(pkg *ast.Package) ([]*ast.CallExpression, error) {
+	var calls []*ast.CallExpression
+	for _, f := range pkg.Files {
+		name := ""
+		for _, imp := range f.Imports {
+			if imp.Path == nil || imp.Path.Value != PackagePath {
+				continue
+			}
+			name = "secrets"
+			if imp.As != nil {
+				name = imp.As.Name
+			}
+		}
+		if name == "" {
+			continue
+		}
+
+		// Identifiers that are not references to the package: the object of
+		// the direct calls, the properties of member expressions and the
+		// keys of object literals.
+		ignored := make(map[*ast.Identifier]bool)
+		indirect := false
+		v := ast.CreateVisitor(func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.CallExpression:
+				m, ok := n.Callee.(*ast.MemberExpression)
+				if !ok || m.Property.Key() != GetKind {
+					return
+				}
+				if id, ok := m.Object.(*ast.Identifier); ok && id.Name == name {
+					ignored[id] = true
+					calls = append(calls, n)
+				}
+			case *ast.MemberExpression:
+				if id, ok := n.Property.(*ast.Identifier); ok {
+					ignored[id] = true
+				}
+			case *ast.ObjectExpression:
+				for _, p := range n.Properties {
+					// The key of a shorthand property, as in {secrets},
+					// is also its value.
+					if id, ok := p.Key.(*ast.Identifier); ok && p.Value != nil {
+						ignored[id] = true
+					}
+				}
+			case *ast.Identifier:
+				if n.Name == name && !ignored[n] {
+					indirect = true
+				}
+			}
+		})
+		for _, s := range f.Body {
+			ast.Walk(v, s)
+		}
+		if indirect {
+			return nil, &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  "secrets.get can only be called directly",
+			}
+		}
+	}
+	return calls, nil
+}
+
+func arguments(call *ast.CallExpression) *ast.ObjectExpression {
+	if len(call.Arguments) != 1 {
+		return nil
+	}
+	obj, _ := call.Arguments[0].(*ast.ObjectExpression)
+	return obj
+}
+
+func getKey(call *ast.CallExpression) (string, error) {
+	if obj := arguments(call); obj != nil {
+		for _, p := range obj.Properties {
+			if p.Key.Key() != "key" {
+				continue
+			}
+			if s, ok := p.Value.(*ast.StringLiteral); ok {
+				return s.Value, nil
+			}
+		}
+	}
+	return "", &platform.Error{
+		Code: platform.EInvalid,
+		Msg:  "secrets.get requires the key to be a string literal",
+	}
+}
+
+func setValue(call *ast.CallExpression, v string) {
+	obj := arguments(call)
+	props := obj.Properties[:0]
+	for _, p := range obj.Properties {
+		if p.Key.Key() != valueParam {
+			props = append(props, p)
+		}
+	}
+	obj.Properties = append(props, &ast.Property{
+		Key:   &ast.Identifier{Name: valueParam},
+		Value: &ast.StringLiteral{Value: v},
+	})
+}
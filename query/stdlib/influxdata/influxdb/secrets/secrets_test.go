@@ -0,0 +1,189 @@
+package secrets_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/lang"
+	_ "github.com/influxdata/flux/stdlib"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb/secrets"
+)
+
+func init() {
+	flux.FinalizeBuiltIns()
+}
+
+const orgID = platform.ID(1)
+
+func newSecretService() *mock.SecretService {
+	s := mock.NewSecretService()
+	s.LoadSecretFn = func(ctx context.Context, id platform.ID, k string) (string, error) {
+		if id == orgID && k == "token" {
+			return "s3cr3t", nil
+		}
+		return "", &platform.Error{Code: platform.ENotFound, Msg: "secret not found"}
+	}
+	return s
+}
+
+func newAuthorization(actions ...platform.Action) *platform.Authorization {
+	id := orgID
+	a := &platform.Authorization{Status: platform.Active}
+	for _, action := range actions {
+		a.Permissions = append(a.Permissions, platform.Permission{
+			Action:   action,
+			Resource: platform.Resource{Type: platform.SecretsResourceType, OrgID: &id},
+		})
+	}
+	return a
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		auth    *platform.Authorization
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "no secrets",
+			script: `x = "token"`,
+			auth:   newAuthorization(),
+		},
+		{
+			name: "secret",
+			script: `import "influxdata/influxdb/secrets"
+x = secrets.get(key: "token")`,
+			auth: newAuthorization(platform.ReadAction),
+			want: []string{"s3cr3t"},
+		},
+		{
+			name: "aliased import",
+			script: `import s "influxdata/influxdb/secrets"
+x = s.get(key: "token")
+y = s.get(key: "token")`,
+			auth: newAuthorization(platform.ReadAction),
+			want: []string{"s3cr3t"},
+		},
+		{
+			name: "not allowed",
+			script: `import "influxdata/influxdb/secrets"
+x = secrets.get(key: "token")`,
+			auth:    newAuthorization(platform.WriteAction),
+			wantErr: platform.EUnauthorized,
+		},
+		{
+			name: "key is not a literal",
+			script: `import "influxdata/influxdb/secrets"
+k = "token"
+x = secrets.get(key: k)`,
+			auth:    newAuthorization(platform.ReadAction),
+			wantErr: platform.EInvalid,
+		},
+		{
+			name: "aliased function",
+			script: `import "influxdata/influxdb/secrets"
+g = secrets.get
+x = g(key: "token")`,
+			auth:    newAuthorization(platform.ReadAction),
+			wantErr: platform.EInvalid,
+		},
+		{
+			name: "function passed as a value",
+			script: `import "influxdata/influxdb/secrets"
+call = (fn) => fn(key: "token")
+x = call(fn: secrets.get)`,
+			auth:    newAuthorization(platform.ReadAction),
+			wantErr: platform.EInvalid,
+		},
+		{
+			name: "package shadowed by a parameter",
+			script: `import "influxdata/influxdb/secrets"
+f = (secrets) => secrets.get(key: "token")
+x = f(secrets: {get: (key, _value="") => _value})`,
+			auth:    newAuthorization(platform.ReadAction),
+			wantErr: platform.EInvalid,
+		},
+		{
+			name: "package in a shorthand property",
+			script: `import "influxdata/influxdb/secrets"
+o = {secrets}
+x = o.secrets.get(key: "token")`,
+			auth:    newAuthorization(platform.ReadAction),
+			wantErr: platform.EInvalid,
+		},
+		{
+			name: "missing secret",
+			script: `import "influxdata/influxdb/secrets"
+x = secrets.get(key: "password")`,
+			auth:    newAuthorization(platform.ReadAction),
+			wantErr: platform.ENotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, err := flux.Parse(tt.script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := secrets.Resolve(context.Background(), pkg, orgID, tt.auth, newSecretService())
+			if tt.wantErr != "" {
+				if code := platform.ErrorCode(err); code != tt.wantErr {
+					t.Fatalf("unexpected error code: got %q, want %q: %v", code, tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("unexpected secrets: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompiler(t *testing.T) {
+	script := `import "influxdata/influxdb/secrets"
+
+x = int(v: secrets.get(key: "token"))`
+
+	c := secrets.NewCompiler(lang.FluxCompiler{Query: script}, orgID, newAuthorization(platform.ReadAction), newSecretService())
+	_, err := c.Compile(context.Background())
+	if err == nil {
+		t.Fatal("expected an error converting the secret to an int")
+	}
+	if strings.Contains(err.Error(), "s3cr3t") || !strings.Contains(err.Error(), secrets.Redacted) {
+		t.Fatalf("secret was not redacted from error: %v", err)
+	}
+
+	// Compiling without resolving the secrets, as when a script is only
+	// analyzed, reads empty secrets.
+	if _, err := flux.Compile(context.Background(), `import "influxdata/influxdb/secrets"
+x = secrets.get(key: "token")`, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	err := &platform.Error{Code: platform.EInvalid, Msg: `invalid token "s3cr3t"`}
+	got := secrets.Redact(err, []string{"s3cr3t"})
+	if got.Error() != `<invalid> invalid token "[REDACTED]"` {
+		t.Fatalf("unexpected redacted error: %v", got)
+	}
+	if code := platform.ErrorCode(got); code != platform.EInvalid {
+		t.Fatalf("unexpected error code: %q", code)
+	}
+
+	other := errors.New("unrelated")
+	if got := secrets.Redact(other, []string{"s3cr3t"}); got != other {
+		t.Fatalf("unexpected error: %v", got)
+	}
+}
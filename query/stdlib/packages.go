@@ -3,6 +3,7 @@ package stdlib
 // Import all stdlib packages
 import (
 	_ "github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	_ "github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb/secrets"
 	_ "github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb/v1"
 	_ "github.com/influxdata/influxdb/query/stdlib/testing"
 )
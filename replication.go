@@ -0,0 +1,185 @@
+package influxdb
+
+import (
+	"context"
+	"net/url"
+)
+
+const (
+	// ErrReplicationNotFound is an error message when a replication does not exist.
+	ErrReplicationNotFound = "replication not found"
+
+	// DefaultReplicationMaxQueueSize is the default maximum number of bytes of
+	// points queued on disk for a replication.
+	DefaultReplicationMaxQueueSize = 64 * 1024 * 1024
+)
+
+// Replication forwards the points written to a bucket to a bucket of a remote
+// instance. Points are queued on disk until the remote has accepted them.
+//
+// The token used to write to the remote is stored as a secret of the
+// organization by the replication service, rather than with the replication,
+// so RemoteToken is empty in the replications it returns.
+type Replication struct {
+	ID             ID     `json:"id,omitempty"`
+	OrgID          ID     `json:"orgID"`
+	Name           string `json:"name"`
+	Description    string `json:"description,omitempty"`
+	SourceBucketID ID     `json:"sourceBucketID"`
+
+	RemoteURL          string `json:"remoteURL"`
+	RemoteToken        string `json:"remoteToken"`
+	RemoteOrgID        ID     `json:"remoteOrgID"`
+	RemoteBucketID     ID     `json:"remoteBucketID"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+
+	// Measurements restricts the points forwarded to those of the measurements.
+	// All points are forwarded if it is empty.
+	Measurements []string `json:"measurements,omitempty"`
+
+	// MaxQueueSize is the maximum number of bytes of points queued for the
+	// remote. Points written while the queue is full are not forwarded.
+	MaxQueueSize int64 `json:"maxQueueSizeBytes"`
+}
+
+// Valid returns an error if the replication is not valid.
+func (r *Replication) Valid() error {
+	if r.Name == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "replication name is required",
+		}
+	}
+	if !r.OrgID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "replication organization ID is invalid",
+		}
+	}
+	if !r.SourceBucketID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "replication source bucket ID is invalid",
+		}
+	}
+	if u, err := url.Parse(r.RemoteURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "replication remote URL must be an http or https URL",
+		}
+	}
+	if !r.RemoteOrgID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "replication remote organization ID is invalid",
+		}
+	}
+	if !r.RemoteBucketID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "replication remote bucket ID is invalid",
+		}
+	}
+	if r.MaxQueueSize < 0 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "replication maximum queue size must not be negative",
+		}
+	}
+	return nil
+}
+
+// MatchesMeasurement returns whether points of the measurement are forwarded.
+func (r *Replication) MatchesMeasurement(name string) bool {
+	if len(r.Measurements) == 0 {
+		return true
+	}
+	for _, m := range r.Measurements {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ops for replications.
+const (
+	OpFindReplicationByID = "FindReplicationByID"
+	OpFindReplications    = "FindReplications"
+	OpCreateReplication   = "CreateReplication"
+	OpUpdateReplication   = "UpdateReplication"
+	OpDeleteReplication   = "DeleteReplication"
+)
+
+// ReplicationService is a service for managing replications.
+type ReplicationService interface {
+	// FindReplicationByID returns a single replication by ID.
+	FindReplicationByID(ctx context.Context, id ID) (*Replication, error)
+
+	// FindReplications returns a list of replications that match filter and
+	// the total count of matching replications.
+	FindReplications(ctx context.Context, filter ReplicationFilter) ([]*Replication, int, error)
+
+	// CreateReplication creates a new replication and sets r.ID with the new
+	// identifier.
+	CreateReplication(ctx context.Context, r *Replication) error
+
+	// UpdateReplication updates a single replication with changeset.
+	// Returns the new replication state after update.
+	UpdateReplication(ctx context.Context, id ID, upd ReplicationUpdate) (*Replication, error)
+
+	// DeleteReplication removes a replication by ID.
+	DeleteReplication(ctx context.Context, id ID) error
+}
+
+// ReplicationFilter represents a set of filters that restrict the returned
+// replications.
+type ReplicationFilter struct {
+	OrgID          *ID
+	SourceBucketID *ID
+}
+
+// ReplicationUpdate represents updates to a replication.
+type ReplicationUpdate struct {
+	Name               *string   `json:"name,omitempty"`
+	Description        *string   `json:"description,omitempty"`
+	RemoteURL          *string   `json:"remoteURL,omitempty"`
+	RemoteToken        *string   `json:"remoteToken,omitempty"`
+	RemoteOrgID        *ID       `json:"remoteOrgID,omitempty"`
+	RemoteBucketID     *ID       `json:"remoteBucketID,omitempty"`
+	InsecureSkipVerify *bool     `json:"insecureSkipVerify,omitempty"`
+	Measurements       *[]string `json:"measurements,omitempty"`
+	MaxQueueSize       *int64    `json:"maxQueueSizeBytes,omitempty"`
+}
+
+// Apply applies an update to a replication.
+func (u ReplicationUpdate) Apply(r *Replication) error {
+	if u.Name != nil {
+		r.Name = *u.Name
+	}
+	if u.Description != nil {
+		r.Description = *u.Description
+	}
+	if u.RemoteURL != nil {
+		r.RemoteURL = *u.RemoteURL
+	}
+	if u.RemoteToken != nil {
+		r.RemoteToken = *u.RemoteToken
+	}
+	if u.RemoteOrgID != nil {
+		r.RemoteOrgID = *u.RemoteOrgID
+	}
+	if u.RemoteBucketID != nil {
+		r.RemoteBucketID = *u.RemoteBucketID
+	}
+	if u.InsecureSkipVerify != nil {
+		r.InsecureSkipVerify = *u.InsecureSkipVerify
+	}
+	if u.Measurements != nil {
+		r.Measurements = *u.Measurements
+	}
+	if u.MaxQueueSize != nil {
+		r.MaxQueueSize = *u.MaxQueueSize
+	}
+	return r.Valid()
+}
@@ -0,0 +1,66 @@
+package replication
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// metrics are the metrics of the replications, labeled by replication ID.
+type metrics struct {
+	queueBytes    *prometheus.GaugeVec
+	pointsQueued  *prometheus.CounterVec
+	pointsDropped *prometheus.CounterVec
+	pointsSent    *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	const namespace = "replication"
+
+	return &metrics{
+		queueBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queue_bytes",
+			Help:      "Number of bytes of points queued on disk to be forwarded to the remote.",
+		}, []string{"replication_id"}),
+		pointsQueued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "points_queued_total",
+			Help:      "Number of points queued to be forwarded to the remote.",
+		}, []string{"replication_id"}),
+		pointsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "points_dropped_total",
+			Help:      "Number of points not forwarded to the remote, split out by whether they could not be queued or the remote rejected them.",
+		}, []string{"replication_id", "reason"}),
+		pointsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "points_sent_total",
+			Help:      "Number of points accepted by the remote.",
+		}, []string{"replication_id"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Number of failed attempts to forward points to the remote, or to read them from the queue.",
+		}, []string{"replication_id"}),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *metrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.queueBytes,
+		m.pointsQueued,
+		m.pointsDropped,
+		m.pointsSent,
+		m.errors,
+	}
+}
+
+// remove deletes the metrics of a deleted replication.
+func (m *metrics) remove(id string) {
+	m.queueBytes.DeleteLabelValues(id)
+	m.pointsQueued.DeleteLabelValues(id)
+	m.pointsSent.DeleteLabelValues(id)
+	m.errors.DeleteLabelValues(id)
+	for _, reason := range []string{dropReasonQueueFull, dropReasonQueueError, dropReasonRejected} {
+		m.pointsDropped.DeleteLabelValues(id, reason)
+	}
+}
@@ -0,0 +1,137 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// PointsWriter writes points, and queues those written to the source bucket of
+// a replication to be forwarded to its remote.
+type PointsWriter struct {
+	PointsWriter storage.PointsWriter
+	Service      *Service
+}
+
+// NewPointsWriter returns a PointsWriter that writes points to w.
+func (s *Service) NewPointsWriter(w storage.PointsWriter) *PointsWriter {
+	return &PointsWriter{PointsWriter: w, Service: s}
+}
+
+// WritePoints writes exploded points, then queues the points that were
+// written for the replications of their buckets.
+func (w *PointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	err := w.PointsWriter.WritePoints(ctx, points)
+	perr, partial := err.(tsdb.PartialWriteError)
+	if err != nil && !partial {
+		return err
+	}
+
+	var dropped map[string]struct{}
+	if partial {
+		dropped = make(map[string]struct{}, len(perr.DroppedKeys))
+		for _, key := range perr.DroppedKeys {
+			dropped[string(key)] = struct{}{}
+		}
+	}
+
+	w.enqueue(points, dropped)
+	return err
+}
+
+// replicationBatch is the line protocol queued for a stream by a write.
+type replicationBatch struct {
+	lines []byte
+	n     int
+}
+
+// enqueue converts points back to line protocol and queues them for the
+// replications of their buckets, skipping the dropped series keys.
+func (w *PointsWriter) enqueue(points []models.Point, dropped map[string]struct{}) {
+	var (
+		name    []byte
+		streams []*stream
+		batches map[*stream]*replicationBatch
+		line    []byte
+	)
+	for _, pt := range points {
+		if !bytes.Equal(pt.Name(), name) {
+			name = pt.Name()
+			streams = nil
+			// The name of an exploded point is its encoded organization and bucket.
+			var ob [16]byte
+			if len(name) == len(ob) {
+				copy(ob[:], name)
+				streams = w.Service.streamsFor(tsdb.DecodeName(ob))
+			}
+		}
+		if len(streams) == 0 {
+			continue
+		}
+		if _, ok := dropped[string(pt.Key())]; ok {
+			continue
+		}
+
+		measurement, l, ok := appendLine(line[:0], pt)
+		if !ok {
+			continue
+		}
+		line = l
+
+		for _, st := range streams {
+			if r, _ := st.config(); !r.MatchesMeasurement(measurement) {
+				continue
+			}
+			if batches == nil {
+				batches = make(map[*stream]*replicationBatch)
+			}
+			b, ok := batches[st]
+			if !ok {
+				b = &replicationBatch{}
+				batches[st] = b
+			}
+			b.lines = append(b.lines, line...)
+			b.n++
+		}
+	}
+
+	for st, b := range batches {
+		st.enqueue(b.lines, b.n)
+	}
+}
+
+// appendLine appends the line protocol of an exploded point, with its original
+// measurement and field key, to buf. It returns the measurement of the point,
+// and false if the point is not an exploded point.
+func appendLine(buf []byte, pt models.Point) (string, []byte, bool) {
+	var measurement []byte
+	ptTags := pt.Tags()
+	tags := make(models.Tags, 0, len(ptTags))
+	for _, t := range ptTags {
+		switch {
+		case bytes.Equal(t.Key, models.MeasurementTagKeyBytes):
+			measurement = t.Value
+		case bytes.Equal(t.Key, models.FieldKeyTagKeyBytes):
+		default:
+			tags = append(tags, t)
+		}
+	}
+	if len(measurement) == 0 {
+		return "", buf, false
+	}
+
+	fields, err := pt.Fields()
+	if err != nil {
+		return "", buf, false
+	}
+
+	p, err := models.NewPoint(string(measurement), tags, fields, pt.Time())
+	if err != nil {
+		return "", buf, false
+	}
+	buf = p.AppendString(buf)
+	return string(measurement), append(buf, '\n'), true
+}
@@ -0,0 +1,478 @@
+package replication
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrQueueFull is returned when appending a record would grow a queue beyond
+// its maximum size.
+var ErrQueueFull = errors.New("replication queue is full")
+
+// DefaultSegmentSize is the size beyond which a new queue segment is started.
+const DefaultSegmentSize = 10 * 1024 * 1024
+
+const (
+	segmentFileExtension = "seg"
+	positionFileName     = "position"
+
+	// Each record is stored as its length, its data and the checksum of its data.
+	recordHeaderSize  = 4
+	recordTrailerSize = 4
+)
+
+type queueSegment struct {
+	id   uint64
+	size int64
+}
+
+type queuePosition struct {
+	segment uint64
+	offset  int64
+}
+
+// Queue is a durable first-in first-out queue of records. Records are appended
+// to segment files in a directory, and segments are deleted once all of their
+// records have been consumed. The position of the next record to read is saved
+// in the directory, so a queue carries on where it left off when reopened.
+type Queue struct {
+	mu          sync.Mutex
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	segments []queueSegment // the segments on disk, in order
+	active   *os.File       // the last segment, opened for appends
+
+	read   queuePosition   // the position of the next record to read
+	peeked []queuePosition // the position after each record returned by the last Peek
+	size   int64           // the size of the unread records, including their framing
+
+	notify chan struct{}
+}
+
+// NewQueue returns a queue stored in dir holding at most maxSize bytes of
+// records. There is no limit if maxSize is zero.
+func NewQueue(dir string, maxSize int64) *Queue {
+	return &Queue{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: DefaultSegmentSize,
+		notify:      make(chan struct{}, 1),
+	}
+}
+
+// Open opens the queue, creating its directory if it does not exist. A record
+// only partially written to the last segment, because of a crash, is discarded.
+func (q *Queue) Open() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return err
+	}
+
+	ids, err := q.segmentIDs()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		ids = []uint64{1}
+		if err := ioutil.WriteFile(q.segmentPath(1), nil, 0600); err != nil {
+			return err
+		}
+	}
+
+	q.segments = q.segments[:0]
+	for i, id := range ids {
+		fi, err := os.Stat(q.segmentPath(id))
+		if err != nil {
+			return err
+		}
+		size := fi.Size()
+		if i == len(ids)-1 {
+			if size, err = q.repairSegment(id, size); err != nil {
+				return err
+			}
+		}
+		q.segments = append(q.segments, queueSegment{id: id, size: size})
+	}
+
+	if err := q.loadPosition(); err != nil {
+		return err
+	}
+	if err := q.removeConsumedSegments(); err != nil {
+		return err
+	}
+
+	last := q.segments[len(q.segments)-1]
+	if q.active, err = os.OpenFile(q.segmentPath(last.id), os.O_WRONLY|os.O_APPEND, 0600); err != nil {
+		return err
+	}
+
+	q.size = -q.read.offset
+	for _, s := range q.segments {
+		q.size += s.size
+	}
+	return nil
+}
+
+// Close closes the queue.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active == nil {
+		return nil
+	}
+	err := q.active.Close()
+	q.active = nil
+	return err
+}
+
+// Remove closes the queue and deletes its directory.
+func (q *Queue) Remove() error {
+	if err := q.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(q.dir)
+}
+
+// Size returns the number of bytes of unread records in the queue.
+func (q *Queue) Size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// SetMaxSize sets the maximum number of bytes of records in the queue. It does
+// not remove records if the queue is already larger.
+func (q *Queue) SetMaxSize(maxSize int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxSize = maxSize
+}
+
+// Notify returns a channel that receives a value after records are appended.
+func (q *Queue) Notify() <-chan struct{} {
+	return q.notify
+}
+
+// Append durably adds a record to the end of the queue. It returns
+// ErrQueueFull, and does not add the record, if the queue would grow beyond
+// its maximum size.
+func (q *Queue) Append(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active == nil {
+		return errors.New("replication queue is closed")
+	}
+
+	n := int64(recordHeaderSize + len(data) + recordTrailerSize)
+	if q.maxSize > 0 && q.size+n > q.maxSize {
+		return ErrQueueFull
+	}
+
+	if q.segments[len(q.segments)-1].size >= q.segmentSize {
+		if err := q.newSegment(); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, n)
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[recordHeaderSize:], data)
+	binary.BigEndian.PutUint32(buf[n-recordTrailerSize:], crc32.ChecksumIEEE(data))
+
+	last := &q.segments[len(q.segments)-1]
+	if _, err := q.active.Write(buf); err != nil {
+		// Do not leave part of the record behind to be appended to.
+		q.active.Truncate(last.size)
+		return err
+	}
+	if err := q.active.Sync(); err != nil {
+		return err
+	}
+	last.size += n
+	q.size += n
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Peek returns the records at the front of the queue without removing them,
+// up to maxBytes of data. At least one record is returned unless the queue is
+// empty. Records are removed by calling Advance.
+func (q *Queue) Peek(maxBytes int) ([][]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.peeked = q.peeked[:0]
+
+	var (
+		records [][]byte
+		n       int
+		pos     = q.read
+	)
+	for i, s := range q.segments {
+		if s.id < pos.segment {
+			continue
+		}
+		if s.id > pos.segment {
+			pos = queuePosition{segment: s.id}
+		}
+		if pos.offset >= s.size {
+			continue
+		}
+
+		f, err := os.Open(q.segmentPath(s.id))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		// Only read up to the size of the segment known to hold whole records.
+		r := bufio.NewReader(io.LimitReader(f, s.size-pos.offset))
+		for pos.offset < s.size && (len(records) == 0 || n < maxBytes) {
+			data, err := readRecord(r, s.size-pos.offset)
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("replication queue segment %d at offset %d: %v", s.id, pos.offset, err)
+			}
+			records = append(records, data)
+			n += len(data)
+			pos.offset += int64(recordHeaderSize + len(data) + recordTrailerSize)
+			q.peeked = append(q.peeked, pos)
+		}
+		f.Close()
+
+		if n >= maxBytes || i == len(q.segments)-1 {
+			break
+		}
+	}
+	return records, nil
+}
+
+// Advance removes the first n records returned by the last call to Peek from
+// the queue.
+func (q *Queue) Advance(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if n <= 0 {
+		return nil
+	}
+	if n > len(q.peeked) {
+		return fmt.Errorf("cannot advance replication queue by %d records, only %d were peeked", n, len(q.peeked))
+	}
+
+	pos := q.peeked[n-1]
+	for _, s := range q.segments {
+		if s.id < q.read.segment || s.id > pos.segment {
+			continue
+		}
+		start, end := int64(0), s.size
+		if s.id == q.read.segment {
+			start = q.read.offset
+		}
+		if s.id == pos.segment {
+			end = pos.offset
+		}
+		q.size -= end - start
+	}
+	q.read = pos
+	q.peeked = q.peeked[:0]
+
+	if err := q.savePosition(); err != nil {
+		return err
+	}
+	return q.removeConsumedSegments()
+}
+
+// newSegment closes the active segment and starts a new one.
+func (q *Queue) newSegment() error {
+	id := q.segments[len(q.segments)-1].id + 1
+	f, err := os.OpenFile(q.segmentPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if err := q.active.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	q.active = f
+	q.segments = append(q.segments, queueSegment{id: id})
+	return nil
+}
+
+// removeConsumedSegments deletes the segments before the read position, and
+// the segment at the read position if all of its records have been read and
+// it is not the last segment.
+func (q *Queue) removeConsumedSegments() error {
+	for len(q.segments) > 1 {
+		s := q.segments[0]
+		if s.id > q.read.segment || (s.id == q.read.segment && q.read.offset < s.size) {
+			break
+		}
+		if err := os.Remove(q.segmentPath(s.id)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		q.segments = q.segments[1:]
+		if s.id == q.read.segment {
+			q.size -= s.size - q.read.offset
+			q.read = queuePosition{segment: q.segments[0].id}
+		}
+	}
+	return nil
+}
+
+// repairSegment truncates a segment after its last whole record and returns
+// its new size.
+func (q *Queue) repairSegment(id uint64, size int64) (int64, error) {
+	f, err := os.OpenFile(q.segmentPath(id), os.O_RDWR, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var valid int64
+	r := bufio.NewReader(f)
+	for valid < size {
+		data, err := readRecord(r, size-valid)
+		if err != nil {
+			break
+		}
+		valid += int64(recordHeaderSize + len(data) + recordTrailerSize)
+	}
+	if valid == size {
+		return size, nil
+	}
+	if err := f.Truncate(valid); err != nil {
+		return 0, err
+	}
+	return valid, f.Sync()
+}
+
+// loadPosition reads the read position of the queue. The position is the
+// start of the first segment if it was never saved, or its segment no longer
+// exists.
+func (q *Queue) loadPosition() error {
+	first := q.segments[0]
+	q.read = queuePosition{segment: first.id}
+
+	buf, err := ioutil.ReadFile(filepath.Join(q.dir, positionFileName))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(buf) != 16 {
+		return fmt.Errorf("replication queue position file is corrupt")
+	}
+
+	pos := queuePosition{
+		segment: binary.BigEndian.Uint64(buf[0:8]),
+		offset:  int64(binary.BigEndian.Uint64(buf[8:16])),
+	}
+	for _, s := range q.segments {
+		if s.id == pos.segment {
+			if pos.offset > s.size {
+				pos.offset = s.size
+			}
+			q.read = pos
+			break
+		}
+	}
+	return nil
+}
+
+// savePosition atomically replaces the saved read position of the queue.
+func (q *Queue) savePosition() error {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[0:8], q.read.segment)
+	binary.BigEndian.PutUint64(buf[8:16], uint64(q.read.offset))
+
+	path := filepath.Join(q.dir, positionFileName)
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf[:]); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// segmentIDs returns the IDs of the segment files in the queue directory, in order.
+func (q *Queue) segmentIDs() ([]uint64, error) {
+	names, err := filepath.Glob(filepath.Join(q.dir, "*."+segmentFileExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(names))
+	for _, name := range names {
+		base := strings.TrimSuffix(filepath.Base(name), "."+segmentFileExtension)
+		id, err := strconv.ParseUint(base, 16, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (q *Queue) segmentPath(id uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%016x.%s", id, segmentFileExtension))
+}
+
+// readRecord reads a record of at most max bytes, including its framing, and
+// verifies its checksum.
+func readRecord(r io.Reader, max int64) ([]byte, error) {
+	var hdr [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	n := int64(binary.BigEndian.Uint32(hdr[:]))
+	if recordHeaderSize+n+recordTrailerSize > max {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	data := make([]byte, n+recordTrailerSize)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	if binary.BigEndian.Uint32(data[n:]) != crc32.ChecksumIEEE(data[:n]) {
+		return nil, errors.New("record checksum mismatch")
+	}
+	return data[:n], nil
+}
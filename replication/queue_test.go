@@ -0,0 +1,134 @@
+package replication
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replication-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	open := func() *Queue {
+		q := NewQueue(dir, 0)
+		q.segmentSize = 30
+		if err := q.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	peek := func(q *Queue, maxBytes int) []string {
+		records, err := q.Peek(maxBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+		return got
+	}
+
+	q := open()
+	for i := 0; i < 6; i++ {
+		if err := q.Append([]byte(fmt.Sprintf("record%d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, exp := q.Size(), int64(6*15); got != exp {
+		t.Fatalf("unexpected size: got %d, exp %d", got, exp)
+	}
+
+	// Peeking does not remove records, and spans segments.
+	if got, exp := peek(q, 1), []string{"record0"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected records: got %v, exp %v", got, exp)
+	}
+	if got, exp := peek(q, 21), []string{"record0", "record1", "record2"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected records: got %v, exp %v", got, exp)
+	}
+	if err := q.Advance(3); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := q.Size(), int64(3*15); got != exp {
+		t.Fatalf("unexpected size after advancing: got %d, exp %d", got, exp)
+	}
+
+	// Consumed segments are deleted.
+	ids, err := q.segmentIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := ids, []uint64{2, 3}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected segments: got %v, exp %v", got, exp)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A partially written record is discarded when the queue is reopened.
+	f, err := os.OpenFile(filepath.Join(dir, "0000000000000003.seg"), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 7, 'r', 'e'}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// The queue carries on from where it left off.
+	q = open()
+	defer q.Close()
+	if err := q.Append([]byte("record6")); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := peek(q, 1024), []string{"record3", "record4", "record5", "record6"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected records after reopening: got %v, exp %v", got, exp)
+	}
+	if err := q.Advance(4); err != nil {
+		t.Fatal(err)
+	}
+	if got := peek(q, 1024); len(got) != 0 {
+		t.Fatalf("unexpected records in empty queue: %v", got)
+	}
+	if got := q.Size(); got != 0 {
+		t.Fatalf("unexpected size of empty queue: %d", got)
+	}
+}
+
+func TestQueue_Full(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replication-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q := NewQueue(dir, 20)
+	if err := q.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	if err := q.Append([]byte("record0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Append([]byte("record1")); err != ErrQueueFull {
+		t.Fatalf("expected queue to be full, got %v", err)
+	}
+
+	if _, err := q.Peek(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Advance(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Append([]byte("record1")); err != nil {
+		t.Fatal(err)
+	}
+}
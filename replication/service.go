@@ -0,0 +1,329 @@
+// Package replication forwards the points written to buckets to buckets of
+// remote instances.
+package replication
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultBatchSize is the default maximum number of bytes of line protocol
+	// sent to a remote in a single write.
+	DefaultBatchSize = 1024 * 1024
+
+	// DefaultMaxBackoff is the default maximum time to wait before retrying a
+	// write that failed.
+	DefaultMaxBackoff = time.Minute
+)
+
+// Service is an influxdb.ReplicationService that forwards the points written
+// to the source bucket of each replication to its remote. Points are held in a
+// queue on disk, one per replication, until the remote accepts them.
+type Service struct {
+	influxdb.ReplicationService
+
+	// SecretService stores the tokens of the remotes, so that they are not
+	// stored with the replications.
+	SecretService influxdb.SecretService
+
+	path   string
+	Logger *zap.Logger
+
+	BatchSize  int
+	MaxBackoff time.Duration
+
+	// NewWriter returns the WriteService used to forward the points of a
+	// replication. It writes to the remote through its /api/v2/write endpoint
+	// by default.
+	NewWriter func(r *influxdb.Replication) influxdb.WriteService
+
+	mu      sync.RWMutex
+	streams map[influxdb.ID]*stream
+	metrics *metrics
+}
+
+// NewService returns a Service that stores replications in s, the tokens of
+// their remotes in secrets, and their queues in directories under path.
+func NewService(s influxdb.ReplicationService, secrets influxdb.SecretService, path string) *Service {
+	return &Service{
+		ReplicationService: s,
+		SecretService:      secrets,
+		path:               path,
+		Logger:             zap.NewNop(),
+		BatchSize:          DefaultBatchSize,
+		MaxBackoff:         DefaultMaxBackoff,
+		NewWriter:          newHTTPWriter,
+		streams:            make(map[influxdb.ID]*stream),
+		metrics:            newMetrics(),
+	}
+}
+
+func newHTTPWriter(r *influxdb.Replication) influxdb.WriteService {
+	return &http.WriteService{
+		Addr:               r.RemoteURL,
+		Token:              r.RemoteToken,
+		InsecureSkipVerify: r.InsecureSkipVerify,
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.Logger = log.With(zap.String("service", "replication"))
+}
+
+// Open starts forwarding the points queued for existing replications, and
+// deletes the queues of replications that no longer exist.
+func (s *Service) Open(ctx context.Context) error {
+	if err := os.MkdirAll(s.path, 0700); err != nil {
+		return err
+	}
+
+	rs, _, err := s.ReplicationService.FindReplications(ctx, influxdb.ReplicationFilter{})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rs {
+		if err := s.loadToken(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range rs {
+		if err := s.startStream(r); err != nil {
+			return err
+		}
+	}
+
+	fis, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+	for _, fi := range fis {
+		id, err := influxdb.IDFromString(fi.Name())
+		if err != nil || !fi.IsDir() {
+			continue
+		}
+		if _, ok := s.streams[*id]; !ok {
+			s.Logger.Info("Removing queue of deleted replication", zap.String("replication_id", fi.Name()))
+			if err := os.RemoveAll(filepath.Join(s.path, fi.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close stops forwarding points. Queued points are forwarded once the service
+// is opened again.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for id, st := range s.streams {
+		st.stop()
+		if err := st.queue.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.streams, id)
+	}
+	return firstErr
+}
+
+// CreateReplication creates a replication and starts forwarding the points
+// written to its source bucket.
+func (s *Service) CreateReplication(ctx context.Context, r *influxdb.Replication) error {
+	token := r.RemoteToken
+	r.RemoteToken = ""
+	if err := s.ReplicationService.CreateReplication(ctx, r); err != nil {
+		return err
+	}
+
+	if err := s.SecretService.PutSecret(ctx, r.OrgID, tokenSecretKey(r.ID), token); err != nil {
+		s.deleteReplication(ctx, r.ID)
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Op:   influxdb.OpCreateReplication,
+			Msg:  "unable to store replication remote token",
+			Err:  err,
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *r
+	c.RemoteToken = token
+	if err := s.startStream(&c); err != nil {
+		// Do not leave behind a replication that does not forward points.
+		s.deleteReplication(ctx, r.ID)
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Op:   influxdb.OpCreateReplication,
+			Msg:  "unable to open replication queue",
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// deleteReplication deletes a replication that could not be created.
+func (s *Service) deleteReplication(ctx context.Context, id influxdb.ID) {
+	if err := s.ReplicationService.DeleteReplication(ctx, id); err != nil {
+		s.Logger.Error("Failed to delete replication", zap.Error(err))
+	}
+}
+
+// UpdateReplication updates a replication. Points already queued are forwarded
+// to the updated remote.
+func (s *Service) UpdateReplication(ctx context.Context, id influxdb.ID, upd influxdb.ReplicationUpdate) (*influxdb.Replication, error) {
+	token := upd.RemoteToken
+	upd.RemoteToken = nil
+	r, err := s.ReplicationService.UpdateReplication(ctx, id, upd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *r
+	if token != nil {
+		if err := s.SecretService.PutSecret(ctx, r.OrgID, tokenSecretKey(r.ID), *token); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Op:   influxdb.OpUpdateReplication,
+				Msg:  "unable to store replication remote token",
+				Err:  err,
+			}
+		}
+		c.RemoteToken = *token
+	} else if err := s.loadToken(ctx, &c); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st, ok := s.streams[id]; ok {
+		st.update(&c, s.NewWriter(&c))
+	}
+	return r, nil
+}
+
+// DeleteReplication deletes a replication, the token of its remote and the
+// points queued for it.
+func (s *Service) DeleteReplication(ctx context.Context, id influxdb.ID) error {
+	r, err := s.ReplicationService.FindReplicationByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.ReplicationService.DeleteReplication(ctx, id); err != nil {
+		return err
+	}
+	if err := s.SecretService.DeleteSecret(ctx, r.OrgID, tokenSecretKey(id)); err != nil {
+		s.Logger.Error("Failed to delete replication remote token", zap.String("replication_id", id.String()), zap.Error(err))
+	}
+
+	s.mu.Lock()
+	st, ok := s.streams[id]
+	delete(s.streams, id)
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	st.stop()
+	s.metrics.remove(st.id)
+	if err := st.queue.Remove(); err != nil {
+		s.Logger.Error("Failed to remove replication queue", zap.String("replication_id", st.id), zap.Error(err))
+	}
+	return nil
+}
+
+// tokenSecretKey returns the key of the secret of the organization of a
+// replication holding the token of its remote.
+func tokenSecretKey(id influxdb.ID) string {
+	return "replication_" + id.String() + "_remote_token"
+}
+
+// loadToken sets the token of the remote of r from its secret. Tokens stored
+// with replications, before they were stored as secrets, are moved to secrets.
+func (s *Service) loadToken(ctx context.Context, r *influxdb.Replication) error {
+	if r.RemoteToken != "" {
+		if err := s.SecretService.PutSecret(ctx, r.OrgID, tokenSecretKey(r.ID), r.RemoteToken); err != nil {
+			return err
+		}
+		empty := ""
+		if _, err := s.ReplicationService.UpdateReplication(ctx, r.ID, influxdb.ReplicationUpdate{RemoteToken: &empty}); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	token, err := s.SecretService.LoadSecret(ctx, r.OrgID, tokenSecretKey(r.ID))
+	if influxdb.ErrorCode(err) == influxdb.ENotFound {
+		// Points are queued until a token is set.
+		s.Logger.Warn("Replication remote token not found", zap.String("replication_id", r.ID.String()))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	r.RemoteToken = token
+	return nil
+}
+
+// startStream opens the queue of a replication and starts forwarding its
+// points. s.mu must be held.
+func (s *Service) startStream(r *influxdb.Replication) error {
+	id := r.ID.String()
+	q := NewQueue(filepath.Join(s.path, id), r.MaxQueueSize)
+	if err := q.Open(); err != nil {
+		return err
+	}
+
+	st := &stream{
+		r:          r,
+		writer:     s.NewWriter(r),
+		id:         id,
+		queue:      q,
+		logger:     s.Logger.With(zap.String("replication_id", id)),
+		metrics:    s.metrics,
+		batchSize:  s.BatchSize,
+		maxBackoff: s.MaxBackoff,
+	}
+	s.metrics.queueBytes.WithLabelValues(id).Set(float64(q.Size()))
+	s.streams[r.ID] = st
+	st.start()
+	return nil
+}
+
+// streamsFor returns the streams of the replications of a bucket.
+func (s *Service) streamsFor(orgID, bucketID influxdb.ID) []*stream {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var streams []*stream
+	for _, st := range s.streams {
+		if r, _ := st.config(); r.OrgID == orgID && r.SourceBucketID == bucketID {
+			streams = append(streams, st)
+		}
+	}
+	return streams
+}
+
+// PrometheusCollectors returns the metrics of the replications.
+func (s *Service) PrometheusCollectors() []prometheus.Collector {
+	return s.metrics.PrometheusCollectors()
+}
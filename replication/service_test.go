@@ -0,0 +1,231 @@
+package replication_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/replication"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+func TestService(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	store := kv.NewService(inmem.NewKVStore())
+	if err := store.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := &remoteRecorder{}
+	open := func() *replication.Service {
+		s := replication.NewService(store, store, dir)
+		s.MaxBackoff = 10 * time.Millisecond
+		s.NewWriter = func(r *influxdb.Replication) influxdb.WriteService {
+			if r.RemoteOrgID != 3 || r.RemoteBucketID != 4 || r.RemoteToken != "tok" {
+				t.Errorf("unexpected remote: %+v", r)
+			}
+			return remote
+		}
+		if err := s.Open(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := open()
+	r := &influxdb.Replication{
+		OrgID:          1,
+		Name:           "edge",
+		SourceBucketID: 2,
+		RemoteURL:      "http://cloud.example.com",
+		RemoteToken:    "tok",
+		RemoteOrgID:    3,
+		RemoteBucketID: 4,
+		Measurements:   []string{"cpu"},
+	}
+	if err := s.CreateReplication(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+
+	// The token of the remote is stored as a secret, not with the replication.
+	if stored, err := store.FindReplicationByID(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	} else if stored.RemoteToken != "" {
+		t.Fatalf("expected remote token not to be stored with the replication, got %q", stored.RemoteToken)
+	}
+	keys, err := store.GetSecretKeys(ctx, r.OrgID)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 1 {
+		t.Fatalf("expected the remote token to be stored as a secret, got %v", keys)
+	}
+	if v, err := store.LoadSecret(ctx, r.OrgID, keys[0]); err != nil || v != "tok" {
+		t.Fatalf("unexpected remote token secret: %q, %v", v, err)
+	}
+
+	// Points of other buckets or measurements, and dropped points, are not forwarded.
+	engine := &droppingWriter{drop: "cpu,host=b"}
+	w := s.NewPointsWriter(engine)
+	writePoints(t, w, 1, 2, "cpu,host=a value=1,other=2 10\ncpu,host=b value=3 10\nmem value=4 10")
+	writePoints(t, w, 1, 5, "cpu,host=a value=5 10")
+
+	if got, exp := remote.wait(t, 2), []string{"cpu,host=a other=2 10", "cpu,host=a value=1 10"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected forwarded points: got %v, exp %v", got, exp)
+	}
+
+	// Points are forwarded after the remote is back, even across restarts.
+	remote.setFail(true)
+	writePoints(t, w, 1, 2, "cpu,host=a value=6 20")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	remote.setFail(false)
+
+	s = open()
+	if got, exp := remote.wait(t, 3)[2], "cpu,host=a value=6 20"; got != exp {
+		t.Fatalf("unexpected forwarded point after restart: got %q, exp %q", got, exp)
+	}
+
+	// Deleting a replication deletes its queue.
+	if err := s.DeleteReplication(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, r.ID.String())); !os.IsNotExist(err) {
+		t.Fatalf("expected queue to be deleted, got %v", err)
+	}
+	if keys, err := store.GetSecretKeys(ctx, r.OrgID); err != nil || len(keys) != 0 {
+		t.Fatalf("expected remote token secret to be deleted, got %v, %v", keys, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens stored with replications are moved to secrets.
+	legacy := &influxdb.Replication{
+		OrgID:          1,
+		Name:           "legacy",
+		SourceBucketID: 2,
+		RemoteURL:      "http://cloud.example.com",
+		RemoteToken:    "tok",
+		RemoteOrgID:    3,
+		RemoteBucketID: 4,
+	}
+	if err := store.CreateReplication(ctx, legacy); err != nil {
+		t.Fatal(err)
+	}
+	s = open()
+	if stored, err := store.FindReplicationByID(ctx, legacy.ID); err != nil {
+		t.Fatal(err)
+	} else if stored.RemoteToken != "" {
+		t.Fatalf("expected remote token to be moved to a secret, got %q", stored.RemoteToken)
+	}
+	if keys, err := store.GetSecretKeys(ctx, legacy.OrgID); err != nil || len(keys) != 1 {
+		t.Fatalf("expected the remote token to be stored as a secret, got %v, %v", keys, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writePoints(t *testing.T, w *replication.PointsWriter, orgID, bucketID influxdb.ID, lines string) {
+	t.Helper()
+	points, err := models.ParsePointsString(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exploded, err := tsdb.ExplodePoints(orgID, bucketID, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WritePoints(context.Background(), exploded); err != nil {
+		if _, ok := err.(tsdb.PartialWriteError); !ok {
+			t.Fatal(err)
+		}
+	}
+}
+
+// droppingWriter is a storage.PointsWriter that drops the points of a series.
+type droppingWriter struct {
+	drop string
+}
+
+func (w *droppingWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	var perr tsdb.PartialWriteError
+	for _, pt := range points {
+		if strings.Contains(string(pt.Key()), w.drop) {
+			perr.Dropped++
+			perr.DroppedKeys = append(perr.DroppedKeys, pt.Key())
+		}
+	}
+	if perr.Dropped > 0 {
+		return perr
+	}
+	return nil
+}
+
+// remoteRecorder is an influxdb.WriteService that records the lines it is sent.
+type remoteRecorder struct {
+	mu    sync.Mutex
+	fail  bool
+	lines []string
+}
+
+func (r *remoteRecorder) setFail(fail bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fail = fail
+}
+
+func (r *remoteRecorder) Write(ctx context.Context, orgID, bucketID influxdb.ID, data io.Reader) error {
+	b, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("remote unavailable")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	sort.Strings(lines)
+	r.lines = append(r.lines, lines...)
+	return nil
+}
+
+// wait returns the lines once there are at least n.
+func (r *remoteRecorder) wait(t *testing.T, n int) []string {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		r.mu.Lock()
+		lines := append([]string(nil), r.lines...)
+		r.mu.Unlock()
+		if len(lines) >= n {
+			return lines
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d lines, got %v", n, lines)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,162 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"go.uber.org/zap"
+)
+
+const (
+	dropReasonQueueFull  = "queue_full"
+	dropReasonQueueError = "queue_error"
+	dropReasonRejected   = "rejected"
+)
+
+// stream queues the points of a replication and forwards them to its remote.
+type stream struct {
+	mu     sync.RWMutex
+	r      *influxdb.Replication
+	writer influxdb.WriteService
+
+	id      string
+	queue   *Queue
+	logger  *zap.Logger
+	metrics *metrics
+
+	batchSize  int
+	maxBackoff time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// config returns the replication and the writer to forward its points with.
+func (s *stream) config() (*influxdb.Replication, influxdb.WriteService) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.r, s.writer
+}
+
+// update replaces the configuration of the stream. Points already queued are
+// forwarded to the new remote.
+func (s *stream) update(r *influxdb.Replication, w influxdb.WriteService) {
+	s.mu.Lock()
+	s.r, s.writer = r, w
+	s.mu.Unlock()
+	s.queue.SetMaxSize(r.MaxQueueSize)
+}
+
+// enqueue adds lines of line protocol to the queue.
+func (s *stream) enqueue(lines []byte, n int) {
+	err := s.queue.Append(lines)
+	s.metrics.queueBytes.WithLabelValues(s.id).Set(float64(s.queue.Size()))
+	switch err {
+	case nil:
+		s.metrics.pointsQueued.WithLabelValues(s.id).Add(float64(n))
+	case ErrQueueFull:
+		s.metrics.pointsDropped.WithLabelValues(s.id, dropReasonQueueFull).Add(float64(n))
+		s.logger.Warn("Replication queue is full, dropping points", zap.Int("points", n))
+	default:
+		s.metrics.pointsDropped.WithLabelValues(s.id, dropReasonQueueError).Add(float64(n))
+		s.logger.Error("Failed to queue points for replication", zap.Error(err))
+	}
+}
+
+func (s *stream) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx)
+	}()
+}
+
+// stop stops forwarding points. Points in the queue are kept.
+func (s *stream) stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// run forwards batches of queued points until ctx is canceled. Batches are
+// retried with increasing delays until the remote accepts them, unless the
+// remote rejects their points as invalid.
+func (s *stream) run(ctx context.Context) {
+	var backoff time.Duration
+	for {
+		if backoff > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+		}
+
+		records, err := s.queue.Peek(s.batchSize)
+		if err != nil {
+			s.logger.Error("Failed to read replication queue", zap.Error(err))
+			s.metrics.errors.WithLabelValues(s.id).Inc()
+			backoff = s.nextBackoff(backoff)
+			continue
+		}
+		if len(records) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.queue.Notify():
+			}
+			backoff = 0
+			continue
+		}
+
+		var n int
+		readers := make([]io.Reader, len(records))
+		for i, record := range records {
+			n += bytes.Count(record, []byte{'\n'})
+			readers[i] = bytes.NewReader(record)
+		}
+
+		r, w := s.config()
+		if err := w.Write(ctx, r.RemoteOrgID, r.RemoteBucketID, io.MultiReader(readers...)); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if influxdb.ErrorCode(err) != influxdb.EInvalid {
+				s.logger.Warn("Failed to forward points to remote", zap.String("remote", r.RemoteURL), zap.Error(err))
+				s.metrics.errors.WithLabelValues(s.id).Inc()
+				backoff = s.nextBackoff(backoff)
+				continue
+			}
+			// Points the remote will never accept must not block those after them.
+			s.logger.Warn("Remote rejected points, dropping them", zap.String("remote", r.RemoteURL), zap.Int("points", n), zap.Error(err))
+			s.metrics.pointsDropped.WithLabelValues(s.id, dropReasonRejected).Add(float64(n))
+		} else {
+			s.metrics.pointsSent.WithLabelValues(s.id).Add(float64(n))
+		}
+		backoff = 0
+
+		if err := s.queue.Advance(len(records)); err != nil {
+			s.logger.Error("Failed to advance replication queue", zap.Error(err))
+			s.metrics.errors.WithLabelValues(s.id).Inc()
+			backoff = s.nextBackoff(backoff)
+		}
+		s.metrics.queueBytes.WithLabelValues(s.id).Set(float64(s.queue.Size()))
+	}
+}
+
+func (s *stream) nextBackoff(d time.Duration) time.Duration {
+	if d == 0 {
+		d = time.Second
+	} else {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+	return d
+}
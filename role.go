@@ -0,0 +1,189 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// ErrRoleNotFound is an error message when a role does not exist.
+	ErrRoleNotFound = "role not found"
+
+	// ErrRoleMappingNotFound is an error message when a role is not
+	// assigned to a subject.
+	ErrRoleMappingNotFound = "role assignment not found"
+)
+
+// Role is a named set of permissions of an organization. Roles are assigned
+// to users, whose sessions are granted their permissions, and to tokens.
+// The permissions of roles are looked up on each request, so changing a role
+// changes the permissions of everyone holding it.
+type Role struct {
+	ID          ID           `json:"id,omitempty"`
+	OrgID       ID           `json:"orgID"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Valid returns an error if the role is not valid. Every permission of a
+// role must be scoped to its organization.
+func (r *Role) Valid() error {
+	if r.Name == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "role name is required",
+		}
+	}
+	if !r.OrgID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "role organization ID is invalid",
+		}
+	}
+	for _, p := range r.Permissions {
+		if err := p.Valid(); err != nil {
+			return &Error{
+				Code: EInvalid,
+				Err:  err,
+			}
+		}
+		inOrg := p.Resource.OrgID != nil && *p.Resource.OrgID == r.OrgID
+		isOrg := p.Resource.Type == OrgsResourceType && p.Resource.ID != nil && *p.Resource.ID == r.OrgID
+		if !inOrg && !isOrg {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("permission %s is not for org id %s", p, r.OrgID),
+			}
+		}
+	}
+	return nil
+}
+
+// RoleSubjectType is the type of what a role is assigned to.
+type RoleSubjectType string
+
+const (
+	// UserRoleSubject is a user, whose sessions hold the role.
+	UserRoleSubject RoleSubjectType = "user"
+	// AuthorizationRoleSubject is an authorization, or token.
+	AuthorizationRoleSubject RoleSubjectType = "authorization"
+)
+
+// Valid returns an error if the subject type is unknown.
+func (t RoleSubjectType) Valid() error {
+	switch t {
+	case UserRoleSubject, AuthorizationRoleSubject:
+		return nil
+	}
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("unknown role subject type %q, expected user or authorization", t),
+	}
+}
+
+// RoleMapping assigns a role to a user or an authorization.
+type RoleMapping struct {
+	RoleID      ID              `json:"roleID"`
+	SubjectType RoleSubjectType `json:"subjectType"`
+	SubjectID   ID              `json:"subjectID"`
+}
+
+// Valid returns an error if the mapping is not valid.
+func (m *RoleMapping) Valid() error {
+	if !m.RoleID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "role ID is invalid",
+		}
+	}
+	if err := m.SubjectType.Valid(); err != nil {
+		return err
+	}
+	if !m.SubjectID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "role subject ID is invalid",
+		}
+	}
+	return nil
+}
+
+// ops for roles.
+const (
+	OpFindRoleByID      = "FindRoleByID"
+	OpFindRoles         = "FindRoles"
+	OpCreateRole        = "CreateRole"
+	OpUpdateRole        = "UpdateRole"
+	OpDeleteRole        = "DeleteRole"
+	OpFindRoleMappings  = "FindRoleMappings"
+	OpCreateRoleMapping = "CreateRoleMapping"
+	OpDeleteRoleMapping = "DeleteRoleMapping"
+)
+
+// RoleService is a service for managing roles and their assignments.
+type RoleService interface {
+	// FindRoleByID returns a single role by ID.
+	FindRoleByID(ctx context.Context, id ID) (*Role, error)
+
+	// FindRoles returns a list of roles that match filter and the total
+	// count of matching roles.
+	FindRoles(ctx context.Context, filter RoleFilter, opt ...FindOptions) ([]*Role, int, error)
+
+	// CreateRole creates a new role and sets r.ID with the new identifier.
+	CreateRole(ctx context.Context, r *Role) error
+
+	// UpdateRole updates a single role with changeset.
+	// Returns the new role state after update.
+	UpdateRole(ctx context.Context, id ID, upd RoleUpdate) (*Role, error)
+
+	// DeleteRole removes a role by ID, and its assignments.
+	DeleteRole(ctx context.Context, id ID) error
+
+	// FindRoleMappings returns the assignments of roles that match filter
+	// and their total count.
+	FindRoleMappings(ctx context.Context, filter RoleMappingFilter) ([]*RoleMapping, int, error)
+
+	// CreateRoleMapping assigns a role to a user or an authorization of
+	// the organization of the role.
+	CreateRoleMapping(ctx context.Context, m *RoleMapping) error
+
+	// DeleteRoleMapping removes the assignment of a role.
+	DeleteRoleMapping(ctx context.Context, roleID ID, subjectType RoleSubjectType, subjectID ID) error
+}
+
+// RoleFilter represents a set of filters that restrict the returned roles.
+type RoleFilter struct {
+	ID    *ID
+	OrgID *ID
+	Name  *string
+}
+
+// RoleMappingFilter represents a set of filters that restrict the returned
+// assignments of roles.
+type RoleMappingFilter struct {
+	RoleID      *ID
+	SubjectType RoleSubjectType
+	SubjectID   *ID
+}
+
+// RoleUpdate represents updates to a role.
+type RoleUpdate struct {
+	Name        *string       `json:"name,omitempty"`
+	Description *string       `json:"description,omitempty"`
+	Permissions *[]Permission `json:"permissions,omitempty"`
+}
+
+// Apply applies an update to a role.
+func (u RoleUpdate) Apply(r *Role) error {
+	if u.Name != nil {
+		r.Name = *u.Name
+	}
+	if u.Description != nil {
+		r.Description = *u.Description
+	}
+	if u.Permissions != nil {
+		r.Permissions = *u.Permissions
+	}
+	return r.Valid()
+}
@@ -23,6 +23,10 @@ var (
 	OpCreateSession = "CreateSession"
 	// OpRenewSession = "RenewSession"
 	OpRenewSession = "RenewSession"
+	// OpFindUserSessions represents the operation that lists the active sessions of a user.
+	OpFindUserSessions = "FindUserSessions"
+	// OpDeleteUserSession represents the operation that revokes a session of a user.
+	OpDeleteUserSession = "DeleteUserSession"
 )
 
 // SessionAuthorizionKind defines the type of authorizer
@@ -37,6 +41,11 @@ type Session struct {
 	ExpiresAt   time.Time    `json:"expiresAt"`
 	UserID      ID           `json:"userID,omitempty"`
 	Permissions []Permission `json:"permissions,omitempty"`
+	// IP and UserAgent are those of the client that last used the session,
+	// at LastSeenAt.
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"userAgent,omitempty"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
 }
 
 // Expired returns an error if the session is expired.
@@ -91,3 +100,13 @@ type SessionService interface {
 	CreateSession(ctx context.Context, user string) (*Session, error)
 	RenewSession(ctx context.Context, session *Session, newExpiration time.Time) error
 }
+
+// UserSessionService manages the active sessions of users, so that they can
+// be reviewed and revoked, for example after a laptop is lost.
+type UserSessionService interface {
+	// FindUserSessions returns the unexpired sessions of a user.
+	FindUserSessions(ctx context.Context, userID ID) ([]*Session, error)
+
+	// DeleteUserSession revokes the session of a user with the id sessionID.
+	DeleteUserSession(ctx context.Context, userID, sessionID ID) error
+}
@@ -3,9 +3,12 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
 
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxql"
 )
 
 // BucketDeleter defines the behaviour of deleting a bucket.
@@ -13,6 +16,37 @@ type BucketDeleter interface {
 	DeleteBucket(platform.ID, platform.ID) error
 }
 
+// SeriesDeleter defines the behaviour of deleting series from a bucket.
+type SeriesDeleter interface {
+	DeleteSeries(orgID, bucketID platform.ID, predicate influxql.Expr) error
+}
+
+// SchemaReader defines the behaviour of reading the schema of a bucket.
+type SchemaReader interface {
+	MeasurementNames(req SchemaRequest) ([]string, error)
+	MeasurementFields(req SchemaRequest) ([]tsi1.MeasurementField, error)
+	TagKeys(req SchemaRequest) ([]string, error)
+	TagValues(req SchemaRequest, key string) ([]string, error)
+}
+
+// SchemaRequest selects the series of a bucket whose schema is read.
+type SchemaRequest struct {
+	OrgID    platform.ID
+	BucketID platform.ID
+
+	// Start and Stop restrict the schema to series with data between them. A zero
+	// value leaves that end of the range unbounded.
+	Start time.Time
+	Stop  time.Time
+
+	// Predicate restricts the schema to series matching it. It may refer to tags,
+	// and to the measurement and field using the _measurement and _field keys.
+	Predicate influxql.Expr
+
+	// Limit is the maximum number of results returned, if greater than zero.
+	Limit int
+}
+
 // BucketService wraps an existing platform.BucketService implementation.
 //
 // BucketService ensures that when a bucket is deleted, all stored data
@@ -11,11 +11,12 @@ import (
 
 // Default configuration values.
 const (
-	DefaultRetentionInterval       = time.Hour
-	DefaultSeriesFileDirectoryName = "_series"
-	DefaultIndexDirectoryName      = "index"
-	DefaultWALDirectoryName        = "wal"
-	DefaultEngineDirectoryName     = "data"
+	DefaultRetentionInterval        = time.Hour
+	DefaultSeriesFileDirectoryName  = "_series"
+	DefaultIndexDirectoryName       = "index"
+	DefaultWALDirectoryName         = "wal"
+	DefaultEngineDirectoryName      = "data"
+	DefaultReplicationDirectoryName = "replication"
 )
 
 // Config holds the configuration for an Engine.
@@ -37,6 +38,9 @@ type Config struct {
 	// Index config.
 	Index     tsi1.Config `toml:"index"`
 	IndexPath string      `toml:"index-path"` // Overrides the default path.
+
+	// WAL replication config.
+	ReplicationPath string `toml:"replication-path"` // Overrides the default path.
 }
 
 // NewConfig initialises a new config for an Engine.
@@ -80,3 +84,12 @@ func (c Config) GetEnginePath(base string) string {
 	}
 	return filepath.Join(base, DefaultEngineDirectoryName)
 }
+
+// GetReplicationPath returns the path to the WAL replication positions and
+// archived WAL segments.
+func (c Config) GetReplicationPath(base string) string {
+	if c.ReplicationPath != "" {
+		return c.ReplicationPath
+	}
+	return filepath.Join(base, DefaultReplicationDirectoryName)
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -26,6 +28,12 @@ import (
 // Static objects to prevent small allocs.
 var timeBytes = []byte("time")
 
+// Keys used to refer to the measurement and field of a series in predicates.
+const (
+	measurementKey = "_measurement"
+	fieldKey       = "_field"
+)
+
 // ErrEngineClosed is returned when a caller attempts to use the engine while
 // it's closed.
 var ErrEngineClosed = errors.New("engine is closed")
@@ -42,6 +50,7 @@ type Engine struct {
 	sfile             *tsdb.SeriesFile
 	engine            *tsm1.Engine
 	wal               *wal.WAL
+	replication       *wal.Replication
 	retentionEnforcer *retentionEnforcer
 
 	defaultMetricLabels prometheus.Labels
@@ -104,6 +113,17 @@ func WithCompactionPlanner(planner tsm1.CompactionPlanner) Option {
 	}
 }
 
+// WithWALReplication makes the engine replicate the writes, bucket deletes and
+// series deletes in its WAL to the remotes. WAL segments are kept until every
+// remote has been sent them.
+func WithWALReplication(remotes ...wal.ReplicationRemote) Option {
+	return func(e *Engine) {
+		path := e.config.GetReplicationPath(e.path)
+		e.wal.WithArchive(filepath.Join(path, DefaultWALDirectoryName))
+		e.replication = wal.NewReplication(e.wal, path, remotes)
+	}
+}
+
 // NewEngine initialises a new storage engine, including a series file, index and
 // TSM engine.
 func NewEngine(path string, c Config, options ...Option) *Engine {
@@ -163,6 +183,9 @@ func (e *Engine) WithLogger(log *zap.Logger) {
 	e.index.WithLogger(e.logger)
 	e.engine.WithLogger(e.logger)
 	e.wal.WithLogger(e.logger)
+	if e.replication != nil {
+		e.replication.WithLogger(e.logger)
+	}
 	e.retentionEnforcer.WithLogger(e.logger)
 }
 
@@ -175,6 +198,9 @@ func (e *Engine) PrometheusCollectors() []prometheus.Collector {
 	metrics = append(metrics, tsm1.PrometheusCollectors()...)
 	metrics = append(metrics, wal.PrometheusCollectors()...)
 	metrics = append(metrics, e.retentionEnforcer.PrometheusCollectors()...)
+	if e.replication != nil {
+		metrics = append(metrics, e.replication.PrometheusCollectors()...)
+	}
 	return metrics
 }
 
@@ -205,6 +231,12 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 		return err
 	}
 
+	if e.replication != nil {
+		if err := e.replication.Open(ctx); err != nil {
+			return err
+		}
+	}
+
 	e.closing = make(chan struct{})
 
 	// TODO(edd) background tasks will be run in priority order via a scheduler.
@@ -250,6 +282,9 @@ func (e *Engine) replayWAL() error {
 
 		case *wal.DeleteBucketRangeWALEntry:
 			return e.deleteBucketRangeLocked(en.OrgID, en.BucketID, en.Min, en.Max)
+
+		case *wal.DeleteSeriesWALEntry:
+			return e.engine.DeleteSeries(en.Keys)
 		}
 
 		return nil
@@ -324,6 +359,9 @@ func (e *Engine) Close() error {
 	e.closing = nil
 
 	var ch closeHelper
+	if e.replication != nil {
+		ch.Close(e.replication)
+	}
 	ch.Close(e.engine)
 	ch.Close(e.wal)
 	ch.Close(e.index)
@@ -363,30 +401,51 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
-	collection, j := tsdb.NewSeriesCollection(points), 0
+	collection := tsdb.NewSeriesCollection(points)
+	validatePoints(collection)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	// Convert the collection to values for adding to the WAL/Cache.
+	values, err := tsm1.CollectionToValues(collection)
+	if err != nil {
+		return err
+	}
+
+	// Add the write to the WAL to be replayed if there is a crash or shutdown.
+	if _, err := e.wal.WriteMulti(ctx, values); err != nil {
+		return err
+	}
+
+	return e.writePointsLocked(ctx, collection, values)
+}
+
+// validatePoints drops the points in the collection that are missing the
+// measurement or field tags, or that have invalid tags or field keys.
+func validatePoints(collection *tsdb.SeriesCollection) {
+	j := 0
 
 	// dropPoint should be called whenever there is reason to drop a point from
 	// the batch.
-	dropPoint := func(key []byte, reason string) {
-		if collection.Reason == "" {
-			collection.Reason = reason
-		}
-		collection.Dropped++
-		collection.DroppedKeys = append(collection.DroppedKeys, key)
-	}
+	dropPoint := collection.DropIndex
 
 	for iter := collection.Iterator(); iter.Next(); {
 		tags := iter.Tags()
 
 		// Not enough tags present.
 		if tags.Len() < 2 {
-			dropPoint(iter.Key(), fmt.Sprintf("missing required tags: parsed tags: %q", tags))
+			dropPoint(iter.Index(), fmt.Sprintf("missing required tags: parsed tags: %q", tags))
 			continue
 		}
 
 		// First tag key is not measurement tag.
 		if !bytes.Equal(tags[0].Key, models.MeasurementTagKeyBytes) {
-			dropPoint(iter.Key(), fmt.Sprintf("missing required measurement tag as first tag, got: %q", tags[0].Key))
+			dropPoint(iter.Index(), fmt.Sprintf("missing required measurement tag as first tag, got: %q", tags[0].Key))
 			continue
 		}
 
@@ -394,26 +453,26 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 
 		// Last tag key is not field tag.
 		if !bytes.Equal(fkey, models.FieldKeyTagKeyBytes) {
-			dropPoint(iter.Key(), fmt.Sprintf("missing required field key tag as last tag, got: %q", tags[0].Key))
+			dropPoint(iter.Index(), fmt.Sprintf("missing required field key tag as last tag, got: %q", tags[0].Key))
 			continue
 		}
 
 		// The value representing the underlying field key is invalid if it's "time".
 		if bytes.Equal(fval, timeBytes) {
-			dropPoint(iter.Key(), fmt.Sprintf("invalid field key: input field %q is invalid", timeBytes))
+			dropPoint(iter.Index(), fmt.Sprintf("invalid field key: input field %q is invalid", timeBytes))
 			continue
 		}
 
 		// Filter out any tags with key equal to "time": they are invalid.
 		if tags.Get(timeBytes) != nil {
-			dropPoint(iter.Key(), fmt.Sprintf("invalid tag key: input tag %q on measurement %q is invalid", timeBytes, iter.Name()))
+			dropPoint(iter.Index(), fmt.Sprintf("invalid tag key: input tag %q on measurement %q is invalid", timeBytes, iter.Name()))
 			continue
 		}
 
 		// Drop any point with invalid unicode characters in any of the tag keys or values.
 		// This will also cover validating the value used to represent the field key.
 		if !models.ValidTagTokens(tags) {
-			dropPoint(iter.Key(), fmt.Sprintf("key contains invalid unicode: %q", iter.Key()))
+			dropPoint(iter.Index(), fmt.Sprintf("key contains invalid unicode: %q", iter.Key()))
 			continue
 		}
 
@@ -421,26 +480,6 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 		j++
 	}
 	collection.Truncate(j)
-
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-
-	if e.closing == nil {
-		return ErrEngineClosed
-	}
-
-	// Convert the collection to values for adding to the WAL/Cache.
-	values, err := tsm1.CollectionToValues(collection)
-	if err != nil {
-		return err
-	}
-
-	// Add the write to the WAL to be replayed if there is a crash or shutdown.
-	if _, err := e.wal.WriteMulti(ctx, values); err != nil {
-		return err
-	}
-
-	return e.writePointsLocked(ctx, collection, values)
 }
 
 // writePointsLocked does the work of writing points and must be called under some sort of lock.
@@ -475,6 +514,85 @@ func (e *Engine) writePointsLocked(ctx context.Context, collection *tsdb.SeriesC
 	return collection.PartialWriteError()
 }
 
+// ApplyWAL applies WAL entries replicated from another engine, encoded as they
+// are in a WAL segment. Entries are added to the WAL of the engine as well, so
+// that they are not lost if it crashes.
+//
+// Replicated points are not trusted: they are validated as they are by
+// WritePoints, and are subject to the same series limits and field type checks.
+func (e *Engine) ApplyWAL(ctx context.Context, data []byte) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	r := wal.NewWALSegmentReader(ioutil.NopCloser(bytes.NewReader(data)))
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			return &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  fmt.Sprintf("invalid WAL entry: %v", err),
+				Err:  err,
+			}
+		}
+
+		switch en := entry.(type) {
+		case *wal.WriteWALEntry:
+			collection := tsdb.NewSeriesCollection(tsm1.ValuesToPoints(en.Values))
+			validatePoints(collection)
+
+			values, err := tsm1.CollectionToValues(collection)
+			if err != nil {
+				return err
+			}
+			if _, err := e.wal.WriteMulti(ctx, values); err != nil {
+				return err
+			}
+
+			// Points dropped here were accepted by the sender, so the engines differ
+			// in their series or field types. The other points are still applied.
+			if err := e.writePointsLocked(ctx, collection, values); err != nil {
+				perr, ok := err.(tsdb.PartialWriteError)
+				if !ok {
+					return err
+				}
+				e.logger.Warn("Dropped replicated points",
+					zap.Int("dropped", perr.Dropped),
+					zap.String("reason", perr.Reason))
+			}
+
+		case *wal.DeleteBucketRangeWALEntry:
+			if _, err := e.wal.DeleteBucketRange(en.OrgID, en.BucketID, en.Min, en.Max); err != nil {
+				return err
+			}
+			if err := e.deleteBucketRangeLocked(en.OrgID, en.BucketID, en.Min, en.Max); err != nil {
+				return err
+			}
+
+		case *wal.DeleteSeriesWALEntry:
+			if _, err := e.wal.DeleteSeries(en.Keys); err != nil {
+				return err
+			}
+			if err := e.engine.DeleteSeries(en.Keys); err != nil {
+				return err
+			}
+
+		default:
+			return &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  fmt.Sprintf("unsupported WAL entry type: %v", entry.Type()),
+			}
+		}
+	}
+	return nil
+}
+
 // AcquireSegments closes the current WAL segment, gets the set of all the currently closed
 // segments, and calls the callback. It does all of this under the lock on the engine.
 func (e *Engine) AcquireSegments(ctx context.Context, fn func(segs []string) error) error {
@@ -541,6 +659,161 @@ func (e *Engine) deleteBucketRangeLocked(orgID, bucketID platform.ID, min, max i
 	return e.engine.DeleteBucketRange(name, min, max)
 }
 
+// DeleteSeries removes all data for the series in the bucket matching predicate
+// from the storage engine, along with the series in the index and series file.
+// The predicate may refer to tags, and to the measurement and field using the
+// _measurement and _field keys.
+func (e *Engine) DeleteSeries(orgID, bucketID platform.ID, predicate influxql.Expr) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	expr, err := rewriteSeriesPredicate(predicate)
+	if err != nil {
+		return err
+	}
+
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	name := models.EscapeMeasurement(encoded[:])
+
+	keys, err := e.engine.SeriesKeysByExpr(name, expr)
+	if err != nil {
+		return err
+	} else if len(keys) == 0 {
+		return nil
+	}
+
+	// Add the delete to the WAL to be replayed if there is a crash or shutdown.
+	if _, err := e.wal.DeleteSeries(keys); err != nil {
+		return err
+	}
+
+	return e.engine.DeleteSeries(keys)
+}
+
+// rewriteSeriesPredicate returns a copy of expr with references to _measurement
+// and _field replaced with the tag keys they are stored under in the index.
+func rewriteSeriesPredicate(expr influxql.Expr) (influxql.Expr, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	var err error
+	expr = influxql.RewriteExpr(influxql.CloneExpr(expr), func(expr influxql.Expr) influxql.Expr {
+		ref, ok := expr.(*influxql.VarRef)
+		if !ok {
+			return expr
+		}
+		switch ref.Val {
+		case measurementKey:
+			return &influxql.VarRef{Val: models.MeasurementTagKey, Type: influxql.Tag}
+		case fieldKey:
+			return &influxql.VarRef{Val: models.FieldKeyTagKey, Type: influxql.Tag}
+		case "time", "_time", "_value":
+			err = &platform.Error{
+				Code: platform.EInvalid,
+				Msg:  fmt.Sprintf("series predicates cannot refer to %q", ref.Val),
+			}
+		}
+		return expr
+	})
+	return expr, err
+}
+
+// MeasurementNames returns the sorted names of the measurements in the bucket.
+func (e *Engine) MeasurementNames(req SchemaRequest) ([]string, error) {
+	return e.TagValues(req, measurementKey)
+}
+
+// MeasurementFields returns the type of every field in each measurement of the
+// bucket, sorted by measurement and then by field.
+func (e *Engine) MeasurementFields(req SchemaRequest) ([]tsi1.MeasurementField, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	name, min, max, expr, err := e.schemaArgs(req)
+	if err != nil {
+		return nil, err
+	}
+	return e.engine.MeasurementFields(name, min, max, expr, req.Limit)
+}
+
+// TagKeys returns the sorted tag keys in the bucket, excluding _measurement and
+// _field.
+func (e *Engine) TagKeys(req SchemaRequest) ([]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	name, min, max, expr, err := e.schemaArgs(req)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := e.engine.TagKeys(name, min, max, expr, req.Limit)
+	return bytesToStrings(keys), err
+}
+
+// TagValues returns the sorted values of the tag key in the bucket. The key may
+// be _measurement or _field to list the measurements or fields.
+func (e *Engine) TagValues(req SchemaRequest, key string) ([]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	name, min, max, expr, err := e.schemaArgs(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key {
+	case measurementKey:
+		key = models.MeasurementTagKey
+	case fieldKey:
+		key = models.FieldKeyTagKey
+	}
+	values, err := e.engine.TagValues(name, []byte(key), min, max, expr, req.Limit)
+	return bytesToStrings(values), err
+}
+
+// schemaArgs returns the escaped bucket name, time range and rewritten predicate
+// for the request.
+func (e *Engine) schemaArgs(req SchemaRequest) (name []byte, min, max int64, expr influxql.Expr, err error) {
+	if expr, err = rewriteSeriesPredicate(req.Predicate); err != nil {
+		return nil, 0, 0, nil, err
+	}
+
+	min, max = math.MinInt64, math.MaxInt64
+	if !req.Start.IsZero() {
+		min = req.Start.UnixNano()
+	}
+	if !req.Stop.IsZero() {
+		max = req.Stop.UnixNano()
+	}
+
+	encoded := tsdb.EncodeName(req.OrgID, req.BucketID)
+	return models.EscapeMeasurement(encoded[:]), min, max, expr, nil
+}
+
+func bytesToStrings(a [][]byte) []string {
+	if a == nil {
+		return nil
+	}
+	s := make([]string, len(a))
+	for i := range a {
+		s[i] = string(a[i])
+	}
+	return s
+}
+
 // SeriesCardinality returns the number of series in the engine.
 func (e *Engine) SeriesCardinality() int64 {
 	e.mu.RLock()
@@ -2,17 +2,23 @@ package storage_test
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/storage/wal"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/value"
+	"github.com/influxdata/influxql"
 )
 
 func TestEngine_WriteAndIndex(t *testing.T) {
@@ -226,6 +232,174 @@ func TestEngine_DeleteBucket(t *testing.T) {
 	}
 }
 
+func TestEngine_DeleteSeries(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	pts, err := models.ParsePointsString(`cpu,host=a value=1 1
+cpu,host=b value=2,value2=3 1
+mem,host=b free=4 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Write1xPoints(pts); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := engine.SeriesCardinality(), int64(4); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+
+	if err := engine.DeleteSeries(engine.org, engine.bucket, influxql.MustParseExpr(`time > 0`)); err == nil {
+		t.Fatal("expected error for predicate on time")
+	}
+
+	expr := influxql.MustParseExpr(`_measurement = 'cpu' AND host = 'b'`)
+	if err := engine.DeleteSeries(engine.org, engine.bucket, expr); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := engine.SeriesCardinality(), int64(2); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+
+	// Replaying the WAL must not bring the series back.
+	engine.Engine.Close() // Don't remove the data
+	engine.MustOpen()
+
+	if got, exp := engine.SeriesCardinality(), int64(2); got != exp {
+		t.Fatalf("got %d series, exp %d series in index after reopen", got, exp)
+	}
+
+	expr = influxql.MustParseExpr(`_field = 'free'`)
+	if err := engine.DeleteSeries(engine.org, engine.bucket, expr); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+}
+
+func TestEngine_SeriesLimits(t *testing.T) {
+	config := storage.NewConfig()
+	config.Index.MaxSeriesPerBucket = 2
+
+	engine := NewEngine(config)
+	defer engine.Close()
+	engine.MustOpen()
+
+	pts, err := models.ParsePointsString(`cpu,host=a value=1 1
+cpu,host=b value=2 1
+cpu,host=c value=3 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = engine.Write1xPoints(pts)
+	if perr, ok := err.(tsdb.PartialWriteError); !ok {
+		t.Fatal("expected partial write error. got:", err)
+	} else if perr.Dropped != 1 {
+		t.Fatalf("got %d dropped, exp 1", perr.Dropped)
+	}
+
+	if got, exp := engine.SeriesCardinality(), int64(2); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+}
+
+func TestEngine_FieldTypeConflict(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	pts, err := models.ParsePointsString(`cpu,host=a value=1 1
+mem,host=a value="full" 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Write1xPoints(pts); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new series may not change the type of an existing field.
+	pts, err = models.ParsePointsString(`cpu,host=b value="high" 2
+cpu,host=b idle=true 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = engine.Write1xPoints(pts)
+	if perr, ok := err.(tsdb.PartialWriteError); !ok {
+		t.Fatal("expected partial write error. got:", err)
+	} else if perr.Dropped != 1 {
+		t.Fatalf("got %d dropped, exp 1", perr.Dropped)
+	} else if exp := `field type conflict: input field "value" on measurement "cpu" is type string, already exists as type float`; perr.Reason != exp {
+		t.Fatalf("unexpected reason: got %q, exp %q", perr.Reason, exp)
+	}
+
+	fields, err := engine.MeasurementFields(storage.SchemaRequest{OrgID: engine.org, BucketID: engine.bucket})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, f := range fields {
+		got = append(got, fmt.Sprintf("%s.%s:%s", f.Measurement, f.Field, f.Type))
+	}
+	if exp := []string{"cpu.idle:Boolean", "cpu.value:Float", "mem.value:String"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected fields: got %v, exp %v", got, exp)
+	}
+}
+
+func TestEngine_Schema(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	pts, err := models.ParsePointsString(`cpu,host=a usage=1 1000000000
+cpu,host=b idle=2 2000000000
+mem,host=c used=3i 3000000000`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Write1xPoints(pts); err != nil {
+		t.Fatal(err)
+	}
+
+	req := storage.SchemaRequest{
+		OrgID:     engine.org,
+		BucketID:  engine.bucket,
+		Start:     time.Unix(2, 0),
+		Predicate: influxql.MustParseExpr(`_measurement = 'cpu' OR host = 'c'`),
+	}
+
+	if got, err := engine.MeasurementNames(req); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"cpu", "mem"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected measurements: got %v, exp %v", got, exp)
+	}
+
+	if got, err := engine.TagValues(req, "_field"); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"idle", "used"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected fields: got %v, exp %v", got, exp)
+	}
+
+	req.Limit = 1
+	if got, err := engine.TagValues(req, "host"); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"b"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected tag values: got %v, exp %v", got, exp)
+	}
+
+	req.Predicate = influxql.MustParseExpr(`_value > 1`)
+	if _, err := engine.TagKeys(req); err == nil {
+		t.Fatal("expected error for predicate on _value")
+	}
+}
+
 func TestEngine_OpenClose(t *testing.T) {
 	engine := NewDefaultEngine()
 	engine.MustOpen()
@@ -319,6 +493,94 @@ func TestEngine_WriteConflictingBatch(t *testing.T) {
 	}
 }
 
+func TestEngine_WALReplication(t *testing.T) {
+	standby := NewDefaultEngine()
+	defer standby.Close()
+	standby.MustOpen()
+
+	engine := NewEngine(storage.NewConfig(), storage.WithWALReplication(wal.ReplicationRemote{
+		Name:   "standby",
+		Client: walApplierClient{standby.Engine},
+	}))
+	defer engine.Close()
+	engine.MustOpen()
+
+	waitFor := func(cond func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !cond(); {
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for replication")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	pt := models.MustNewPoint(
+		"cpu",
+		models.NewTags(map[string]string{"host": "server"}),
+		map[string]interface{}{"value": 1.0, "value2": 2.0},
+		time.Unix(1, 2),
+	)
+	if err := engine.Write1xPoints([]models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool { return standby.SeriesCardinality() == 2 })
+
+	if err := engine.DeleteSeries(engine.org, engine.bucket, influxql.MustParseExpr(`_field = 'value2'`)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool { return standby.SeriesCardinality() == 1 })
+
+	if err := engine.DeleteBucket(engine.org, engine.bucket); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool { return standby.SeriesCardinality() == 0 })
+}
+
+func TestEngine_ApplyWAL_Invalid(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	// The series key is missing the measurement and field tags.
+	data := encodeWALEntry(t, &wal.WriteWALEntry{
+		Values: map[string][]value.Value{
+			"cpu,host=a#!~#value": {value.NewFloatValue(1, 1)},
+		},
+	})
+	if err := engine.ApplyWAL(context.Background(), data); err != nil {
+		t.Fatal(err)
+	}
+	if got := engine.SeriesCardinality(); got != 0 {
+		t.Fatalf("got %d series, expected the invalid series to be dropped", got)
+	}
+}
+
+// encodeWALEntry returns the entry encoded as it is in a WAL segment.
+func encodeWALEntry(t *testing.T, entry wal.WALEntry) []byte {
+	t.Helper()
+
+	b, err := entry.Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := snappy.Encode(nil, b)
+
+	data := make([]byte, 5, 5+len(compressed))
+	data[0] = byte(entry.Type())
+	binary.BigEndian.PutUint32(data[1:5], uint32(len(compressed)))
+	return append(data, compressed...)
+}
+
+// walApplierClient is a wal.ReplicationClient that applies entries to a WALApplier.
+type walApplierClient struct {
+	storage.WALApplier
+}
+
+func (c walApplierClient) ReplicateWAL(ctx context.Context, data []byte) error {
+	return c.ApplyWAL(ctx, data)
+}
+
 func BenchmarkDeleteBucket(b *testing.B) {
 	var engine *Engine
 	setup := func(card int) {
@@ -370,10 +632,10 @@ type Engine struct {
 }
 
 // NewEngine create a new wrapper around a storage engine.
-func NewEngine(c storage.Config) *Engine {
+func NewEngine(c storage.Config, options ...storage.Option) *Engine {
 	path, _ := ioutil.TempDir("", "storage_engine_test")
 
-	engine := storage.NewEngine(path, c)
+	engine := storage.NewEngine(path, c, options...)
 
 	org, err := influxdb.IDFromString("3131313131313131")
 	if err != nil {
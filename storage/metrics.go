@@ -64,3 +64,47 @@ func (rm *retentionMetrics) PrometheusCollectors() []prometheus.Collector {
 		rm.CheckDuration,
 	}
 }
+
+const writeBufferSubsystem = "write_buffer" // sub-system associated with metrics for combining writes.
+
+// writeBufferMetrics is a set of metrics concerned with tracking data about combined writes.
+type writeBufferMetrics struct {
+	Writes      *prometheus.CounterVec
+	Batches     prometheus.Counter
+	BatchWrites prometheus.Histogram
+}
+
+func newWriteBufferMetrics() *writeBufferMetrics {
+	return &writeBufferMetrics{
+		Writes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: writeBufferSubsystem,
+			Name:      "writes_total",
+			Help:      "Number of writes received, by whether they were buffered or written directly.",
+		}, []string{"mode"}),
+
+		Batches: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: writeBufferSubsystem,
+			Name:      "batches_total",
+			Help:      "Number of combined writes made.",
+		}),
+
+		BatchWrites: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: writeBufferSubsystem,
+			Name:      "batch_writes",
+			Help:      "Number of buffered writes in each combined write.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *writeBufferMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.Writes,
+		m.Batches,
+		m.BatchWrites,
+	}
+}
@@ -10,3 +10,9 @@ import (
 type PointsWriter interface {
 	WritePoints(context.Context, []models.Point) error
 }
+
+// WALApplier describes the ability to apply WAL entries replicated from another
+// storage engine.
+type WALApplier interface {
+	ApplyWAL(ctx context.Context, data []byte) error
+}
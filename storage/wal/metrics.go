@@ -89,3 +89,63 @@ func (m *walMetrics) PrometheusCollectors() []prometheus.Collector {
 		m.Writes,
 	}
 }
+
+const replicationSubsystem = "wal_replication" // sub-system associated with metrics for WAL replication.
+
+// replicationMetrics are a set of metrics concerned with tracking the
+// replication of the WAL to remotes.
+type replicationMetrics struct {
+	SentEntries *prometheus.CounterVec
+	SentBytes   *prometheus.CounterVec
+	Errors      *prometheus.CounterVec
+	LagBytes    *prometheus.GaugeVec
+	LastSent    *prometheus.GaugeVec
+}
+
+// newReplicationMetrics initialises the prometheus metrics for tracking WAL replication.
+func newReplicationMetrics() *replicationMetrics {
+	names := []string{"remote"}
+	return &replicationMetrics{
+		SentEntries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: replicationSubsystem,
+			Name:      "sent_entries_total",
+			Help:      "Number of WAL entries sent to the remote.",
+		}, names),
+		SentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: replicationSubsystem,
+			Name:      "sent_bytes_total",
+			Help:      "Number of bytes of WAL entries sent to the remote.",
+		}, names),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: replicationSubsystem,
+			Name:      "errors_total",
+			Help:      "Number of failed attempts to send WAL entries to the remote.",
+		}, names),
+		LagBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: replicationSubsystem,
+			Name:      "lag_bytes",
+			Help:      "Number of bytes of WAL segments not yet sent to the remote.",
+		}, names),
+		LastSent: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: replicationSubsystem,
+			Name:      "last_sent_timestamp_seconds",
+			Help:      "Unix time at which WAL entries were last sent to the remote.",
+		}, names),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *replicationMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.SentEntries,
+		m.SentBytes,
+		m.Errors,
+		m.LagBytes,
+		m.LastSent,
+	}
+}
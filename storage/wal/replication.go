@@ -0,0 +1,499 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Default configuration values for a Replication.
+const (
+	DefaultReplicationBatchSize    = 4 * 1024 * 1024
+	DefaultReplicationPollInterval = time.Second
+	DefaultReplicationMaxBackoff   = time.Minute
+)
+
+// ReplicationClient sends WAL entries to a remote server.
+type ReplicationClient interface {
+	// ReplicateWAL sends entries encoded as they are in a segment file. The
+	// remote applies them in order, and they may be sent again if the position
+	// after them could not be saved.
+	ReplicateWAL(ctx context.Context, data []byte) error
+}
+
+// ReplicationRemote is a server that the WAL is replicated to.
+type ReplicationRemote struct {
+	// Name identifies the remote in its position file and metrics. It must be
+	// unique and must not change, or replication to the remote starts again.
+	Name   string
+	Client ReplicationClient
+}
+
+// ReplicationPosition is the position in the WAL up to which entries have been
+// sent to a remote.
+type ReplicationPosition struct {
+	SegmentID int   `json:"segmentID"`
+	Offset    int64 `json:"offset"`
+}
+
+// Replication streams write and bucket delete entries from a WAL to remote
+// servers, such as a standby that applies them to its own engine.
+//
+// Both closed segments and the segment being written are read, so that remotes
+// stay close behind. The WAL must archive removed segments, which a Replication
+// deletes once every remote has been sent them. The position of each remote is
+// saved in a file, so that replication carries on where it stopped after a
+// restart. Remotes that cannot be reached are retried with backoff, and the
+// archive grows until they catch up.
+//
+// A new remote is sent the segments that are still in the WAL, so it should
+// start from a copy of the data.
+type Replication struct {
+	// BatchSize is the number of bytes of entries sent in each request. Larger
+	// entries are sent on their own.
+	BatchSize int
+
+	// PollInterval is how often the WAL is checked for new entries once a
+	// remote has been sent all of them.
+	PollInterval time.Duration
+
+	// MaxBackoff is the longest time waited before retrying a remote.
+	MaxBackoff time.Duration
+
+	wal      *WAL
+	path     string
+	replicas []*replica
+
+	mu      sync.Mutex // guards the positions of the replicas
+	cancel  context.CancelFunc
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	logger  *zap.Logger
+	metrics *replicationMetrics
+}
+
+// replica is the state of replication to a remote.
+type replica struct {
+	ReplicationRemote
+	pos ReplicationPosition
+}
+
+// segment is a WAL segment file.
+type segment struct {
+	id   int
+	path string
+}
+
+// NewReplication returns a Replication of w to the remotes, keeping their
+// positions in the directory at path.
+func NewReplication(w *WAL, path string, remotes []ReplicationRemote) *Replication {
+	r := &Replication{
+		BatchSize:    DefaultReplicationBatchSize,
+		PollInterval: DefaultReplicationPollInterval,
+		MaxBackoff:   DefaultReplicationMaxBackoff,
+
+		wal:     w,
+		path:    path,
+		logger:  zap.NewNop(),
+		metrics: newReplicationMetrics(),
+	}
+	for _, remote := range remotes {
+		r.replicas = append(r.replicas, &replica{ReplicationRemote: remote})
+	}
+	return r
+}
+
+// WithLogger sets the logger of the Replication.
+func (r *Replication) WithLogger(log *zap.Logger) {
+	r.logger = log.With(zap.String("service", "wal-replication"))
+}
+
+// Open loads the position of each remote and starts sending entries to them.
+// The WAL must be open.
+func (r *Replication) Open(ctx context.Context) error {
+	if r.wal.ArchivePath() == "" {
+		return fmt.Errorf("WAL replication requires a WAL archive")
+	}
+	if err := os.MkdirAll(r.path, 0777); err != nil {
+		return err
+	}
+
+	segments, err := r.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, rep := range r.replicas {
+		pos, err := r.loadPosition(rep.Name)
+		if os.IsNotExist(err) {
+			// Start from the oldest entries that are still available.
+			if len(segments) > 0 {
+				pos.SegmentID = segments[0].id
+			}
+		} else if err != nil {
+			return err
+		}
+		rep.pos = pos
+	}
+
+	ctx, r.cancel = context.WithCancel(context.Background())
+	r.closing = make(chan struct{})
+	for _, rep := range r.replicas {
+		r.wg.Add(1)
+		go func(rep *replica) {
+			defer r.wg.Done()
+			r.run(ctx, rep)
+		}(rep)
+	}
+	return nil
+}
+
+// Close stops sending entries to the remotes.
+func (r *Replication) Close() error {
+	if r.closing == nil {
+		return nil
+	}
+	close(r.closing)
+	r.cancel()
+	r.wg.Wait()
+	r.closing = nil
+	return nil
+}
+
+// Position returns the position up to which entries have been sent to the
+// named remote.
+func (r *Replication) Position(name string) (ReplicationPosition, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rep := range r.replicas {
+		if rep.Name == name {
+			return rep.pos, true
+		}
+	}
+	return ReplicationPosition{}, false
+}
+
+// run sends entries to the remote until the Replication is closed.
+func (r *Replication) run(ctx context.Context, rep *replica) {
+	var backoff time.Duration
+	for {
+		sent, err := r.ship(ctx, rep)
+		wait := r.PollInterval
+		if err != nil {
+			if backoff *= 2; backoff == 0 {
+				backoff = time.Second
+			}
+			if backoff > r.MaxBackoff {
+				backoff = r.MaxBackoff
+			}
+			wait = backoff
+
+			r.metrics.Errors.WithLabelValues(rep.Name).Inc()
+			r.logger.Info("Failed to replicate WAL",
+				zap.String("remote", rep.Name),
+				zap.Duration("retry_in", wait),
+				zap.Error(err))
+		} else {
+			backoff = 0
+			if sent {
+				wait = 0
+			}
+		}
+		r.updateLag(rep)
+
+		if wait == 0 {
+			select {
+			case <-r.closing:
+				return
+			default:
+				continue
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-r.closing:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// ship sends the next batch of entries to the remote, and reports whether it
+// made progress.
+func (r *Replication) ship(ctx context.Context, rep *replica) (bool, error) {
+	segments, err := r.segments()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	pos := rep.pos
+	r.mu.Unlock()
+
+	for i, seg := range segments {
+		if seg.id < pos.SegmentID {
+			continue
+		} else if seg.id > pos.SegmentID {
+			pos = ReplicationPosition{SegmentID: seg.id}
+		}
+
+		// The segment was listed after a later one was created, so it is closed
+		// and will not grow any further.
+		closed := i < len(segments)-1
+
+		data, n, offset, eof, err := r.readSegment(seg, pos.Offset, closed)
+		if err != nil {
+			return false, err
+		}
+
+		if len(data) > 0 {
+			if err := rep.Client.ReplicateWAL(ctx, data); err != nil {
+				return false, err
+			}
+			r.metrics.SentEntries.WithLabelValues(rep.Name).Add(float64(n))
+			r.metrics.SentBytes.WithLabelValues(rep.Name).Add(float64(len(data)))
+			r.metrics.LastSent.WithLabelValues(rep.Name).SetToCurrentTime()
+		}
+
+		next := ReplicationPosition{SegmentID: seg.id, Offset: offset}
+		if eof && closed {
+			next = ReplicationPosition{SegmentID: segments[i+1].id}
+		}
+		if next == rep.pos {
+			return false, nil
+		}
+		if err := r.setPosition(rep, next); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// readSegment reads the entries of the segment from the offset until at least
+// BatchSize bytes are read, returning the entries to be sent, their number, and
+// the offset after them. Entries other than writes, bucket deletes and series
+// deletes are skipped. eof is set if the end of the segment was reached.
+//
+// The end of the segment being written may hold an entry that is only partly
+// written, which is read once it is complete. A corrupt closed segment is only
+// read up to the corruption, as it is when the WAL is loaded.
+func (r *Replication) readSegment(seg segment, offset int64, closed bool) (data []byte, n int, end int64, eof bool, err error) {
+	f, err := os.Open(seg.path)
+	if os.IsNotExist(err) {
+		// The segment was moved into the archive, so read it next time.
+		return nil, 0, offset, false, nil
+	} else if err != nil {
+		return nil, 0, offset, false, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, offset, false, err
+	}
+
+	var buf bytes.Buffer
+	br := bufio.NewReader(f)
+	end = offset
+	for buf.Len() < r.BatchSize {
+		var hdr [5]byte
+		if _, err := io.ReadFull(br, hdr[:]); err == io.EOF {
+			return buf.Bytes(), n, end, true, nil
+		} else if err == io.ErrUnexpectedEOF {
+			return buf.Bytes(), n, end, r.truncated(seg, end, closed, err), nil
+		} else if err != nil {
+			return nil, 0, offset, false, err
+		}
+
+		body := make([]byte, binary.BigEndian.Uint32(hdr[1:5]))
+		if _, err := io.ReadFull(br, body); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return buf.Bytes(), n, end, r.truncated(seg, end, closed, err), nil
+		} else if err != nil {
+			return nil, 0, offset, false, err
+		}
+
+		if _, err := snappy.DecodedLen(body); err != nil {
+			if !closed {
+				return nil, 0, offset, false, fmt.Errorf("%v in %s at %d", ErrWALCorrupt, seg.path, end)
+			}
+			return buf.Bytes(), n, end, r.truncated(seg, end, closed, err), nil
+		}
+		end += int64(len(hdr) + len(body))
+
+		switch WalEntryType(hdr[0]) {
+		case WriteWALEntryType, DeleteBucketRangeWALEntryType, DeleteSeriesWALEntryType:
+			buf.Write(hdr[:])
+			buf.Write(body)
+			n++
+		}
+	}
+	return buf.Bytes(), n, end, false, nil
+}
+
+// truncated reports whether reading a segment that ends at offset with an
+// incomplete entry has reached the end of the segment.
+func (r *Replication) truncated(seg segment, offset int64, closed bool, err error) bool {
+	if closed {
+		r.logger.Info("Skipping corrupt end of WAL segment",
+			zap.String("path", seg.path),
+			zap.Int64("pos", offset),
+			zap.Error(err))
+	}
+	return closed
+}
+
+// segments returns the archived and current segments of the WAL, in order.
+func (r *Replication) segments() ([]segment, error) {
+	// List the WAL before the archive, so that segments archived in between are
+	// still found.
+	current, err := SegmentFileNames(r.wal.Path())
+	if err != nil {
+		return nil, err
+	}
+	archived, err := SegmentFileNames(r.wal.ArchivePath())
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+	for _, names := range [][]string{archived, current} {
+		for _, name := range names {
+			id, err := idFromFileName(name)
+			if err != nil {
+				return nil, err
+			}
+			if len(segments) > 0 && segments[len(segments)-1].id >= id {
+				continue // listed in both, and now in the archive
+			}
+			segments = append(segments, segment{id: id, path: name})
+		}
+	}
+	return segments, nil
+}
+
+// setPosition saves the position of the remote, and removes archived segments
+// that every remote has been sent.
+func (r *Replication) setPosition(rep *replica, pos ReplicationPosition) error {
+	if err := r.savePosition(rep.Name, pos); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	rep.pos = pos
+	min := pos.SegmentID
+	for _, rep := range r.replicas {
+		if rep.pos.SegmentID < min {
+			min = rep.pos.SegmentID
+		}
+	}
+	r.mu.Unlock()
+
+	archived, err := SegmentFileNames(r.wal.ArchivePath())
+	if err != nil {
+		return err
+	}
+	for _, name := range archived {
+		if id, err := idFromFileName(name); err != nil {
+			return err
+		} else if id >= min {
+			break
+		}
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// positionPath returns the path of the position file of the remote.
+func (r *Replication) positionPath(name string) string {
+	return filepath.Join(r.path, name+".position")
+}
+
+func (r *Replication) loadPosition(name string) (ReplicationPosition, error) {
+	var pos ReplicationPosition
+	b, err := ioutil.ReadFile(r.positionPath(name))
+	if err != nil {
+		return pos, err
+	}
+	if err := json.Unmarshal(b, &pos); err != nil {
+		return pos, fmt.Errorf("invalid position file for remote %q: %v", name, err)
+	}
+	return pos, nil
+}
+
+// savePosition writes the position to a temporary file which replaces the
+// position file, so that the file is never partly written.
+func (r *Replication) savePosition(name string, pos ReplicationPosition) error {
+	b, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+
+	path := r.positionPath(name)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// updateLag sets the number of bytes of segments the remote has not been sent.
+func (r *Replication) updateLag(rep *replica) {
+	segments, err := r.segments()
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	pos := rep.pos
+	r.mu.Unlock()
+
+	var lag int64
+	for _, seg := range segments {
+		if seg.id < pos.SegmentID {
+			continue
+		}
+		stat, err := os.Stat(seg.path)
+		if err != nil {
+			continue
+		}
+		lag += stat.Size()
+		if seg.id == pos.SegmentID {
+			lag -= pos.Offset
+		}
+	}
+	r.metrics.LagBytes.WithLabelValues(rep.Name).Set(float64(lag))
+}
+
+// PrometheusCollectors returns the metrics of the Replication.
+func (r *Replication) PrometheusCollectors() []prometheus.Collector {
+	return r.metrics.PrometheusCollectors()
+}
@@ -0,0 +1,156 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/tsdb/value"
+)
+
+func TestReplication(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	w := NewWAL(filepath.Join(dir, "wal"))
+	w.WithArchive(filepath.Join(dir, "archive"))
+	if err := w.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	values := func(v float64) map[string][]value.Value {
+		return map[string][]value.Value{"cpu,host=A#!~#value": {value.NewValue(1, v)}}
+	}
+	if _, err := w.WriteMulti(ctx, values(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.DeleteSeries([][]byte{[]byte("cpu,host=B")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.DeleteBucketRange(influxdb.ID(1), influxdb.ID(2), 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	open := func(client *replicationRecorder) *Replication {
+		r := NewReplication(w, filepath.Join(dir, "replication"), []ReplicationRemote{{Name: "standby", Client: client}})
+		r.PollInterval = 10 * time.Millisecond
+		r.MaxBackoff = 10 * time.Millisecond
+		if err := r.Open(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// Entries are sent once the remote is available.
+	client := &replicationRecorder{fail: 2}
+	r := open(client)
+	entries := client.wait(t, 3)
+	if got, ok := entries[0].(*WriteWALEntry); !ok || !reflect.DeepEqual(got.Values, values(1)) {
+		t.Fatalf("unexpected first entry: %#v", entries[0])
+	}
+	if got, ok := entries[1].(*DeleteSeriesWALEntry); !ok || !reflect.DeepEqual(got.Keys, [][]byte{[]byte("cpu,host=B")}) {
+		t.Fatalf("unexpected second entry: %#v", entries[1])
+	}
+	exp := &DeleteBucketRangeWALEntry{OrgID: 1, BucketID: 2, Min: 3, Max: 4}
+	if got, ok := entries[2].(*DeleteBucketRangeWALEntry); !ok || *got != *exp {
+		t.Fatalf("unexpected third entry: %#v", entries[2])
+	}
+
+	// Removed segments are archived, and deleted once they have been sent.
+	if err := w.CloseSegment(); err != nil {
+		t.Fatal(err)
+	}
+	closed, err := w.ClosedSegments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Remove(ctx, closed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteMulti(ctx, values(2)); err != nil {
+		t.Fatal(err)
+	}
+	entries = client.wait(t, 4)
+	if got, ok := entries[3].(*WriteWALEntry); !ok || !reflect.DeepEqual(got.Values, values(2)) {
+		t.Fatalf("unexpected fourth entry: %#v", entries[3])
+	}
+	waitFor(t, func() bool {
+		archived, err := SegmentFileNames(w.ArchivePath())
+		return err == nil && len(archived) == 0
+	})
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replication carries on from the saved position.
+	if _, err := w.WriteMulti(ctx, values(3)); err != nil {
+		t.Fatal(err)
+	}
+	client = &replicationRecorder{}
+	r = open(client)
+	defer r.Close()
+	entries = client.wait(t, 1)
+	if got, ok := entries[0].(*WriteWALEntry); !ok || !reflect.DeepEqual(got.Values, values(3)) {
+		t.Fatalf("unexpected entry after reopening: %#v", entries[0])
+	}
+}
+
+// replicationRecorder is a ReplicationClient that records the entries it is sent.
+type replicationRecorder struct {
+	mu      sync.Mutex
+	fail    int // the number of requests that fail before one succeeds
+	entries []WALEntry
+}
+
+func (c *replicationRecorder) ReplicateWAL(ctx context.Context, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fail > 0 {
+		c.fail--
+		return errors.New("remote unavailable")
+	}
+
+	r := NewWALSegmentReader(ioutil.NopCloser(bytes.NewReader(data)))
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			return err
+		}
+		c.entries = append(c.entries, entry)
+	}
+	return nil
+}
+
+// wait returns the entries once there are at least n.
+func (c *replicationRecorder) wait(t *testing.T, n int) []WALEntry {
+	t.Helper()
+	var entries []WALEntry
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entries = append(entries[:0], c.entries...)
+		return len(entries) >= n
+	})
+	return entries
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -64,6 +64,9 @@ const (
 
 	// DeleteBucketRangeWALEntryType indicates a delete bucket range entry.
 	DeleteBucketRangeWALEntryType WalEntryType = 0x04
+
+	// DeleteSeriesWALEntryType indicates a delete series entry.
+	DeleteSeriesWALEntryType WalEntryType = 0x05
 )
 
 var (
@@ -91,6 +94,9 @@ type WAL struct {
 	path    string
 	enabled bool
 
+	// archivePath is the directory removed segments are moved to, if set.
+	archivePath string
+
 	// write variables
 	currentSegmentID     int
 	currentSegmentWriter *WALSegmentWriter
@@ -156,6 +162,21 @@ func (l *WAL) SetDefaultMetricLabels(labels prometheus.Labels) {
 	}
 }
 
+// WithArchive makes the WAL move segments into the given directory when they are
+// removed, rather than deleting them, so that they can still be read by a
+// Replication. It should be called before the WAL is opened.
+func (l *WAL) WithArchive(path string) {
+	l.archivePath = path
+}
+
+// ArchivePath returns the directory removed segments are moved to, or the empty
+// string if they are deleted.
+func (l *WAL) ArchivePath() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.archivePath
+}
+
 // Path returns the directory the log was initialized with.
 func (l *WAL) Path() string {
 	l.mu.RLock()
@@ -191,6 +212,11 @@ func (l *WAL) Open(ctx context.Context) error {
 	if err := os.MkdirAll(l.path, 0777); err != nil {
 		return err
 	}
+	if l.archivePath != "" {
+		if err := os.MkdirAll(l.archivePath, 0777); err != nil {
+			return err
+		}
+	}
 
 	segments, err := SegmentFileNames(l.path)
 	if err != nil {
@@ -230,6 +256,24 @@ func (l *WAL) Open(ctx context.Context) error {
 		}
 	}
 
+	// Segments that have been archived keep their IDs, so new segments must
+	// be numbered after them.
+	if l.archivePath != "" {
+		archived, err := SegmentFileNames(l.archivePath)
+		if err != nil {
+			return err
+		}
+		if len(archived) > 0 {
+			id, err := idFromFileName(archived[len(archived)-1])
+			if err != nil {
+				return err
+			}
+			if id > l.currentSegmentID {
+				l.currentSegmentID = id
+			}
+		}
+	}
+
 	var totalOldDiskSize int64
 	for _, seg := range segments {
 		stat, err := os.Stat(seg)
@@ -369,7 +413,8 @@ func (l *WAL) ClosedSegments() ([]string, error) {
 	return closedFiles, nil
 }
 
-// Remove deletes the given segment file paths from disk and cleans up any associated objects.
+// Remove deletes the given segment file paths from disk, or moves them to the
+// archive if there is one, and cleans up any associated objects.
 func (l *WAL) Remove(ctx context.Context, files []string) error {
 	if !l.enabled {
 		return nil
@@ -383,7 +428,11 @@ func (l *WAL) Remove(ctx context.Context, files []string) error {
 
 	for i, fn := range files {
 		span.LogKV(fmt.Sprintf("path-%d", i), fn)
-		os.RemoveAll(fn)
+		if l.archivePath == "" {
+			os.RemoveAll(fn)
+		} else if err := os.Rename(fn, filepath.Join(l.archivePath, filepath.Base(fn))); err != nil {
+			return err
+		}
 	}
 
 	// Refresh the on-disk size stats
@@ -539,6 +588,24 @@ func (l *WAL) DeleteBucketRange(orgID, bucketID influxdb.ID, min, max int64) (in
 	return id, nil
 }
 
+// DeleteSeries deletes all data for the series keys, returning the segment ID for
+// the operation.
+func (l *WAL) DeleteSeries(keys [][]byte) (int, error) {
+	if !l.enabled {
+		return -1, nil
+	}
+
+	entry := &DeleteSeriesWALEntry{
+		Keys: keys,
+	}
+
+	id, err := l.writeToLog(entry)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
 // Close will finish any flush that is currently in progress and close file handles.
 func (l *WAL) Close() error {
 	l.mu.Lock()
@@ -1057,6 +1124,68 @@ func (w *DeleteBucketRangeWALEntry) Type() WalEntryType {
 	return DeleteBucketRangeWALEntryType
 }
 
+// DeleteSeriesWALEntry represents the deletion of all data for a set of series.
+type DeleteSeriesWALEntry struct {
+	Keys [][]byte
+}
+
+// MarshalBinary returns a binary representation of the entry in a new byte slice.
+func (w *DeleteSeriesWALEntry) MarshalBinary() ([]byte, error) {
+	b := make([]byte, w.MarshalSize())
+	return w.Encode(b)
+}
+
+// UnmarshalBinary deserializes the byte slice into w.
+func (w *DeleteSeriesWALEntry) UnmarshalBinary(b []byte) error {
+	w.Keys = w.Keys[:0]
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return ErrWALCorrupt
+		}
+		n := int(binary.BigEndian.Uint32(b[:4]))
+		b = b[4:]
+
+		if n > len(b) {
+			return ErrWALCorrupt
+		}
+		w.Keys = append(w.Keys, append([]byte(nil), b[:n]...))
+		b = b[n:]
+	}
+	return nil
+}
+
+// MarshalSize returns the number of bytes the entry takes when marshaled.
+func (w *DeleteSeriesWALEntry) MarshalSize() int {
+	var sz int
+	for _, k := range w.Keys {
+		sz += 4 + len(k)
+	}
+	return sz
+}
+
+// Encode converts the entry into a byte stream using b if it is large enough.
+// If b is too small, a newly allocated slice is returned.
+func (w *DeleteSeriesWALEntry) Encode(b []byte) ([]byte, error) {
+	sz := w.MarshalSize()
+	if len(b) < sz {
+		b = make([]byte, sz)
+	}
+
+	var n int
+	for _, k := range w.Keys {
+		binary.BigEndian.PutUint32(b[n:], uint32(len(k)))
+		n += 4
+		n += copy(b[n:], k)
+	}
+
+	return b[:sz], nil
+}
+
+// Type returns DeleteSeriesWALEntryType.
+func (w *DeleteSeriesWALEntry) Type() WalEntryType {
+	return DeleteSeriesWALEntryType
+}
+
 // WALSegmentWriter writes WAL segments.
 type WALSegmentWriter struct {
 	bw   *bufio.Writer
@@ -1202,6 +1331,8 @@ func (r *WALSegmentReader) Next() bool {
 		}
 	case DeleteBucketRangeWALEntryType:
 		r.entry = &DeleteBucketRangeWALEntry{}
+	case DeleteSeriesWALEntryType:
+		r.entry = &DeleteSeriesWALEntry{}
 	default:
 		r.err = fmt.Errorf("unknown wal entry type: %v", entryType)
 		return true
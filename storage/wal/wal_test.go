@@ -471,6 +471,35 @@ func TestDeleteBucketRangeWALEntry_UnmarshalBinary(t *testing.T) {
 	}
 }
 
+func TestDeleteSeriesWALEntry_UnmarshalBinary(t *testing.T) {
+	in := &DeleteSeriesWALEntry{
+		Keys: [][]byte{[]byte("cpu,host=a#!~#value"), []byte("mem,host=b#!~#free")},
+	}
+
+	b, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error, got %v", err)
+	}
+
+	out := &DeleteSeriesWALEntry{}
+	if err := out.UnmarshalBinary(b); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v, expected %+v", out, in)
+	}
+
+	for i := 1; i < len(b); i++ {
+		if i == 4+len(in.Keys[0]) {
+			continue // truncated on an entry boundary
+		}
+		if err := out.UnmarshalBinary(b[:i]); err != ErrWALCorrupt {
+			t.Fatalf("expected corrupt error for length %d, got %v", i, err)
+		}
+	}
+}
+
 func TestWriteWALSegment_UnmarshalBinary_DeleteBucketRangeWALCorrupt(t *testing.T) {
 	w := &DeleteBucketRangeWALEntry{
 		OrgID:    influxdb.ID(1),
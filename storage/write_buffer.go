@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Default configuration values for a WriteBuffer.
+const (
+	DefaultWriteBufferMaxPoints = 10000
+)
+
+// ErrWriteBufferClosed is returned when writing to a WriteBuffer that is closed.
+var ErrWriteBufferClosed = errors.New("write buffer closed")
+
+// WriteBuffer is a PointsWriter that combines concurrent writes into larger
+// writes to another PointsWriter, such as an Engine, so that many small writes
+// share the cost of each write to the WAL.
+//
+// A write waits for at most the maximum latency for other writes to join it,
+// and a combined write is made as soon as it holds the maximum number of points.
+// Writes arriving while a combined write is in progress are combined into the
+// next one. Each call to WritePoints returns only once its points have been
+// written, with an error covering only its own points.
+type WriteBuffer struct {
+	w          PointsWriter
+	maxLatency time.Duration
+	maxPoints  int
+
+	mu      sync.RWMutex
+	writes  chan *bufferedWrite
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	metrics *writeBufferMetrics
+}
+
+// bufferedWrite is a call to WritePoints waiting to be combined.
+type bufferedWrite struct {
+	ctx    context.Context
+	points []models.Point
+	done   chan error
+}
+
+// NewWriteBuffer returns a WriteBuffer writing to w. Writes with maxPoints or
+// more points are not combined with others. If maxPoints is not positive,
+// DefaultWriteBufferMaxPoints is used.
+func NewWriteBuffer(w PointsWriter, maxLatency time.Duration, maxPoints int) *WriteBuffer {
+	if maxPoints <= 0 {
+		maxPoints = DefaultWriteBufferMaxPoints
+	}
+
+	b := &WriteBuffer{
+		w:          w,
+		maxLatency: maxLatency,
+		maxPoints:  maxPoints,
+		writes:     make(chan *bufferedWrite),
+		closing:    make(chan struct{}),
+		metrics:    newWriteBufferMetrics(),
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.run()
+	}()
+	return b
+}
+
+// WritePoints writes the points along with any concurrent writes, and returns
+// once they have been written.
+func (b *WriteBuffer) WritePoints(ctx context.Context, points []models.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	select {
+	case <-b.closing:
+		return ErrWriteBufferClosed
+	default:
+	}
+
+	// Large writes gain nothing from being combined.
+	if len(points) >= b.maxPoints {
+		b.metrics.Writes.WithLabelValues("direct").Inc()
+		return b.w.WritePoints(ctx, points)
+	}
+
+	write := &bufferedWrite{ctx: ctx, points: points, done: make(chan error, 1)}
+	select {
+	case b.writes <- write:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	b.metrics.Writes.WithLabelValues("buffered").Inc()
+
+	// Once the points have been handed over they are written, so wait for the
+	// result even if the context is canceled.
+	return <-write.done
+}
+
+// Close writes any waiting points and stops the buffer. Writes made after Close
+// return ErrWriteBufferClosed.
+func (b *WriteBuffer) Close() error {
+	b.mu.Lock()
+	select {
+	case <-b.closing:
+	default:
+		close(b.closing)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// run combines writes until the buffer is closed.
+func (b *WriteBuffer) run() {
+	for {
+		var pending []*bufferedWrite
+		select {
+		case write := <-b.writes:
+			pending = append(pending, write)
+		case <-b.closing:
+			return
+		}
+
+		// Take any writes that are already waiting, then wait for more until the
+		// maximum latency has passed.
+		n := len(pending[0].points)
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if b.maxLatency > 0 {
+			timer = time.NewTimer(b.maxLatency)
+			timeout = timer.C
+		}
+	collect:
+		for n < b.maxPoints {
+			select {
+			case write := <-b.writes:
+				pending = append(pending, write)
+				n += len(write.points)
+				continue
+			default:
+			}
+			if timeout == nil {
+				break
+			}
+
+			select {
+			case write := <-b.writes:
+				pending = append(pending, write)
+				n += len(write.points)
+			case <-timeout:
+				break collect
+			case <-b.closing:
+				break collect
+			}
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		b.flush(pending, n)
+	}
+}
+
+// flush writes the points of the pending writes and returns the result to each.
+func (b *WriteBuffer) flush(pending []*bufferedWrite, n int) {
+	b.metrics.Batches.Inc()
+	b.metrics.BatchWrites.Observe(float64(len(pending)))
+
+	if len(pending) == 1 {
+		pending[0].done <- b.w.WritePoints(pending[0].ctx, pending[0].points)
+		return
+	}
+
+	points := make([]models.Point, 0, n)
+	for _, write := range pending {
+		points = append(points, write.points...)
+	}
+
+	// The writes share a context that is not canceled by any one of them.
+	err := b.w.WritePoints(context.Background(), points)
+	perr, ok := err.(tsdb.PartialWriteError)
+	offset := 0
+	for _, write := range pending {
+		if ok {
+			write.done <- partialWriteErrorFor(perr, write.points, offset)
+		} else {
+			write.done <- err
+		}
+		offset += len(write.points)
+	}
+}
+
+// partialWriteErrorFor returns the part of err that applies to the points, or nil
+// if none of them were dropped. The points start at offset in the write that err
+// was returned for.
+//
+// Drops are matched to the points by their position in the write when err reports
+// them, as points of the same series key may be dropped in one write and not in
+// another. Otherwise they are matched by series key.
+func partialWriteErrorFor(err tsdb.PartialWriteError, points []models.Point, offset int) error {
+	reasons := make(map[string]string, len(err.DroppedKeys))
+	for i, key := range err.DroppedKeys {
+		reasons[string(key)] = err.Reason
+		if i < len(err.DroppedReasons) {
+			reasons[string(key)] = err.DroppedReasons[i]
+		}
+	}
+
+	var perr tsdb.PartialWriteError
+	dropped := make(map[string]string)
+	if err.DroppedIndexes != nil {
+		for _, index := range err.DroppedIndexes {
+			if index < offset || index >= offset+len(points) {
+				continue
+			}
+			key := points[index-offset].Key()
+			reason, ok := reasons[string(key)]
+			if !ok {
+				reason = err.Reason
+			}
+			dropped[string(key)] = reason
+			perr.Dropped++
+			perr.DroppedIndexes = append(perr.DroppedIndexes, index-offset)
+		}
+	} else {
+		for _, pt := range points {
+			if reason, ok := reasons[string(pt.Key())]; ok {
+				dropped[string(pt.Key())] = reason
+				perr.Dropped++
+			}
+		}
+	}
+	if perr.Dropped == 0 {
+		return nil
+	}
+
+	for key := range dropped {
+		perr.DroppedKeys = append(perr.DroppedKeys, []byte(key))
+	}
+	bytesutil.Sort(perr.DroppedKeys)
+	for _, key := range perr.DroppedKeys {
+		perr.DroppedReasons = append(perr.DroppedReasons, dropped[string(key)])
+	}
+	perr.Reason = perr.DroppedReasons[0]
+	return perr
+}
+
+// PrometheusCollectors returns the metrics of the buffer.
+func (b *WriteBuffer) PrometheusCollectors() []prometheus.Collector {
+	return b.metrics.PrometheusCollectors()
+}
@@ -0,0 +1,181 @@
+package storage_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// gatedPointsWriter records the number of points in each write, and makes each
+// write wait for a value on gate.
+type gatedPointsWriter struct {
+	gate chan struct{}
+
+	mu     sync.Mutex
+	writes []int
+	err    func(points []models.Point) error
+}
+
+func (w *gatedPointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	<-w.gate
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, len(points))
+	if w.err != nil {
+		return w.err(points)
+	}
+	return nil
+}
+
+func TestWriteBuffer(t *testing.T) {
+	pw := &gatedPointsWriter{gate: make(chan struct{})}
+	buf := storage.NewWriteBuffer(pw, 20*time.Millisecond, 10)
+	defer buf.Close()
+
+	// Drop the series with the key "cpu,host=b".
+	pw.err = func(points []models.Point) error {
+		for _, p := range points {
+			if string(p.Key()) == "cpu,host=b" {
+				return tsdb.PartialWriteError{
+					Reason:         "test",
+					Dropped:        1,
+					DroppedKeys:    [][]byte{p.Key()},
+					DroppedReasons: []string{"test reason"},
+				}
+			}
+		}
+		return nil
+	}
+
+	write := func(s string) <-chan error {
+		ch := make(chan error, 1)
+		go func() {
+			points, err := models.ParsePointsString(s)
+			if err != nil {
+				panic(err)
+			}
+			ch <- buf.WritePoints(context.Background(), points)
+		}()
+		return ch
+	}
+
+	// The first write is held up once it is made, so the writes that follow it
+	// are combined.
+	first := write("cpu,host=a value=1 1")
+	time.Sleep(50 * time.Millisecond)
+	second, third := write("cpu,host=b value=2 2"), write("cpu,host=c value=3 3\ncpu,host=c value=4 4")
+	time.Sleep(10 * time.Millisecond)
+
+	pw.gate <- struct{}{}
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	pw.gate <- struct{}{}
+
+	if err, ok := (<-second).(tsdb.PartialWriteError); !ok {
+		t.Fatalf("expected a partial write error, got %v", err)
+	} else if exp := (tsdb.PartialWriteError{
+		Reason:         "test reason",
+		Dropped:        1,
+		DroppedKeys:    [][]byte{[]byte("cpu,host=b")},
+		DroppedReasons: []string{"test reason"},
+	}); !reflect.DeepEqual(err, exp) {
+		t.Fatalf("got %#v, expected %#v", err, exp)
+	}
+	if err := <-third; err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes of the maximum size or more are made directly.
+	close(pw.gate)
+	if err := <-write("cpu value=1 1\ncpu value=2 2\ncpu value=3 3\ncpu value=4 4\ncpu value=5 5\ncpu value=6 6\ncpu value=7 7\ncpu value=8 8\ncpu value=9 9\ncpu value=10 10"); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []int{1, 3, 10}; !reflect.DeepEqual(pw.writes, exp) {
+		t.Fatalf("got writes of %v points, expected %v", pw.writes, exp)
+	}
+
+	if err := buf.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := <-write("cpu value=1 1"); err != storage.ErrWriteBufferClosed {
+		t.Fatalf("got %v, expected %v", err, storage.ErrWriteBufferClosed)
+	}
+}
+
+func TestWriteBuffer_DroppedIndexes(t *testing.T) {
+	pw := &gatedPointsWriter{gate: make(chan struct{})}
+	buf := storage.NewWriteBuffer(pw, 20*time.Millisecond, 10)
+	defer buf.Close()
+
+	// Drop the points with an integer value, as if they conflicted with the
+	// float values of the series written before them.
+	pw.err = func(points []models.Point) error {
+		var perr tsdb.PartialWriteError
+		for i, p := range points {
+			if iter := p.FieldIterator(); iter.Next() && iter.Type() == models.Integer {
+				perr.Reason = "conflicting field type"
+				perr.Dropped = 1
+				perr.DroppedKeys = [][]byte{p.Key()}
+				perr.DroppedReasons = []string{"conflicting field type"}
+				perr.DroppedIndexes = append(perr.DroppedIndexes, i)
+			}
+		}
+		if perr.Dropped == 0 {
+			return nil
+		}
+		return perr
+	}
+
+	write := func(s string) <-chan error {
+		ch := make(chan error, 1)
+		go func() {
+			points, err := models.ParsePointsString(s)
+			if err != nil {
+				panic(err)
+			}
+			ch <- buf.WritePoints(context.Background(), points)
+		}()
+		return ch
+	}
+
+	first := write("cpu,host=a value=1 1")
+	time.Sleep(50 * time.Millisecond)
+	second := write("cpu,host=b value=2 2")
+	time.Sleep(5 * time.Millisecond)
+	third := write("cpu,host=a value=3 3\ncpu,host=b value=4i 4")
+	time.Sleep(10 * time.Millisecond)
+
+	close(pw.gate)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+
+	// Both combined writes write to "cpu,host=b", but only the point of the
+	// third was dropped.
+	if err := <-second; err != nil {
+		t.Fatalf("expected no error for the written points, got %v", err)
+	}
+	if err, ok := (<-third).(tsdb.PartialWriteError); !ok {
+		t.Fatalf("expected a partial write error, got %v", err)
+	} else if exp := (tsdb.PartialWriteError{
+		Reason:         "conflicting field type",
+		Dropped:        1,
+		DroppedKeys:    [][]byte{[]byte("cpu,host=b")},
+		DroppedReasons: []string{"conflicting field type"},
+		DroppedIndexes: []int{1},
+	}); !reflect.DeepEqual(err, exp) {
+		t.Fatalf("got %#v, expected %#v", err, exp)
+	}
+
+	if exp := []int{1, 3}; !reflect.DeepEqual(pw.writes, exp) {
+		t.Fatalf("got writes of %v points, expected %v", pw.writes, exp)
+	}
+}
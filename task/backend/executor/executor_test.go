@@ -70,16 +70,20 @@ func (s *fakeQueryService) Query(ctx context.Context, req *query.Request) (flux.
 		return nil, err
 	}
 
-	sc, ok := req.Compiler.(lang.SpecCompiler)
+	ac, ok := req.Compiler.(lang.ASTCompiler)
 	if !ok {
-		return nil, fmt.Errorf("fakeQueryService only supports the SpecCompiler, got %T", req.Compiler)
+		return nil, fmt.Errorf("fakeQueryService only supports the ASTCompiler, got %T", req.Compiler)
+	}
+	spec, err := ac.Compile(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	fq := &fakeQuery{
 		wait:  make(chan struct{}),
 		ready: make(chan map[string]flux.Result),
 	}
-	s.queries[makeSpecString(sc.Spec)] = fq
+	s.queries[makeSpecString(spec)] = fq
 
 	go fq.run(ctx)
 
@@ -3,8 +3,10 @@ package testing
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	platform "github.com/influxdata/influxdb"
@@ -65,6 +67,14 @@ func AuthorizationService(
 			name: "UpdateAuthorizationStatus",
 			fn:   UpdateAuthorizationStatus,
 		},
+		{
+			name: "UpdateAuthorization",
+			fn:   UpdateAuthorization,
+		},
+		{
+			name: "RotateAuthorization",
+			fn:   RotateAuthorization,
+		},
 		{
 			name: "FindAuthorizations",
 			fn:   FindAuthorizations,
@@ -147,7 +157,6 @@ func CreateAuthorization(
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
 						Status:      platform.Active,
-						Token:       "supersecret",
 						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
 						Description: "already existing auth",
 					},
@@ -155,7 +164,6 @@ func CreateAuthorization(
 						ID:          MustIDBase16(authTwoID),
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
-						Token:       "rand",
 						Status:      platform.Active,
 						Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 						Description: "new auth",
@@ -209,14 +217,12 @@ func CreateAuthorization(
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
 						Status:      platform.Active,
-						Token:       "supersecret",
 						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
 					},
 					{
 						ID:          MustIDBase16(authTwoID),
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
-						Token:       "rand",
 						Status:      platform.Active,
 						Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 					},
@@ -270,7 +276,6 @@ func CreateAuthorization(
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
 						Status:      platform.Active,
-						Token:       "supersecret",
 						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
 						Description: "already existing auth",
 					},
@@ -325,7 +330,6 @@ func CreateAuthorization(
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
 						Status:      platform.Active,
-						Token:       "supersecret",
 						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
 						Description: "already existing auth",
 					},
@@ -349,6 +353,12 @@ func CreateAuthorization(
 
 			defer s.DeleteAuthorization(ctx, tt.args.authorization.ID)
 
+			// Only a hash of the token is stored, so the created
+			// authorization is the only place it can be read.
+			if tt.wants.err == nil && tt.args.authorization.Token == "" {
+				t.Error("expected created authorization to have a token")
+			}
+
 			authorizations, _, err := s.FindAuthorizations(ctx, platform.AuthorizationFilter{})
 			if err != nil {
 				t.Fatalf("failed to retrieve authorizations: %v", err)
@@ -424,7 +434,6 @@ func FindAuthorizationByID(
 					UserID:      MustIDBase16(userTwoID),
 					OrgID:       MustIDBase16(orgOneID),
 					Status:      platform.Active,
-					Token:       "rand2",
 					Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 				},
 			},
@@ -530,7 +539,6 @@ func UpdateAuthorizationStatus(
 					ID:          MustIDBase16(authTwoID),
 					UserID:      MustIDBase16(userTwoID),
 					OrgID:       MustIDBase16(orgOneID),
-					Token:       "rand2",
 					Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 					Status:      platform.Inactive,
 				},
@@ -686,6 +694,248 @@ func UpdateAuthorizationStatus(
 	}
 }
 
+// UpdateAuthorization testing
+func UpdateAuthorization(
+	init func(AuthorizationFields, *testing.T) (platform.AuthorizationService, string, func()),
+	t *testing.T,
+) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastUsedAt := time.Date(2029, 6, 1, 0, 0, 0, 0, time.UTC)
+	description := "updated"
+	inactive := platform.Inactive
+	unknown := platform.Status("unknown")
+
+	type args struct {
+		id  platform.ID
+		upd *platform.AuthorizationUpdate
+	}
+	type wants struct {
+		err           error
+		authorization *platform.Authorization
+	}
+
+	fields := AuthorizationFields{
+		Users: []*platform.User{
+			{
+				Name: "cooluser",
+				ID:   MustIDBase16(userOneID),
+			},
+		},
+		Orgs: []*platform.Organization{
+			{
+				Name: "o1",
+				ID:   MustIDBase16(orgOneID),
+			},
+		},
+		Authorizations: []*platform.Authorization{
+			{
+				ID:          MustIDBase16(authOneID),
+				UserID:      MustIDBase16(userOneID),
+				OrgID:       MustIDBase16(orgOneID),
+				Token:       "rand1",
+				Description: "auth",
+				Permissions: allUsersPermission(MustIDBase16(orgOneID)),
+				ExpiresAt:   &expiresAt,
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		args  args
+		wants wants
+	}{
+		{
+			name: "update status, description and last use",
+			args: args{
+				id: MustIDBase16(authOneID),
+				upd: &platform.AuthorizationUpdate{
+					Status:      &inactive,
+					Description: &description,
+					LastUsedAt:  &lastUsedAt,
+				},
+			},
+			wants: wants{
+				authorization: &platform.Authorization{
+					ID:          MustIDBase16(authOneID),
+					UserID:      MustIDBase16(userOneID),
+					OrgID:       MustIDBase16(orgOneID),
+					Status:      platform.Inactive,
+					Description: "updated",
+					Permissions: allUsersPermission(MustIDBase16(orgOneID)),
+					ExpiresAt:   &expiresAt,
+					LastUsedAt:  &lastUsedAt,
+				},
+			},
+		},
+		{
+			name: "remove expiry",
+			args: args{
+				id: MustIDBase16(authOneID),
+				upd: &platform.AuthorizationUpdate{
+					ExpiresAt: &time.Time{},
+				},
+			},
+			wants: wants{
+				authorization: &platform.Authorization{
+					ID:          MustIDBase16(authOneID),
+					UserID:      MustIDBase16(userOneID),
+					OrgID:       MustIDBase16(orgOneID),
+					Status:      platform.Active,
+					Description: "auth",
+					Permissions: allUsersPermission(MustIDBase16(orgOneID)),
+				},
+			},
+		},
+		{
+			name: "update with unknown status",
+			args: args{
+				id: MustIDBase16(authOneID),
+				upd: &platform.AuthorizationUpdate{
+					Status: &unknown,
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Op:   platform.OpUpdateAuthorization,
+					Msg:  "unknown authorization status",
+				},
+			},
+		},
+		{
+			name: "update id not exists",
+			args: args{
+				id:  MustIDBase16(authThreeID),
+				upd: &platform.AuthorizationUpdate{Description: &description},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.ENotFound,
+					Op:   platform.OpUpdateAuthorization,
+					Msg:  "authorization not found",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, opPrefix, done := init(fields, t)
+			defer done()
+			ctx := context.Background()
+
+			authorization, err := s.UpdateAuthorization(ctx, tt.args.id, tt.args.upd)
+			diffPlatformErrors(tt.name, err, tt.wants.err, opPrefix, t)
+			if tt.wants.err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(authorization, tt.wants.authorization, authorizationCmpOptions...); diff != "" {
+				t.Errorf("updated authorization is different -got/+want\ndiff %s", diff)
+			}
+
+			authorization, err = s.FindAuthorizationByID(ctx, tt.args.id)
+			if err != nil {
+				t.Fatalf("failed to find updated authorization: %v", err)
+			}
+			if diff := cmp.Diff(authorization, tt.wants.authorization, authorizationCmpOptions...); diff != "" {
+				t.Errorf("found authorization is different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// RotateAuthorization testing
+func RotateAuthorization(
+	init func(AuthorizationFields, *testing.T) (platform.AuthorizationService, string, func()),
+	t *testing.T,
+) {
+	var n int
+	fields := AuthorizationFields{
+		TokenGenerator: &mock.TokenGenerator{
+			TokenFn: func() (string, error) {
+				n++
+				return fmt.Sprintf("rotated%d", n), nil
+			},
+		},
+		Users: []*platform.User{
+			{
+				Name: "cooluser",
+				ID:   MustIDBase16(userOneID),
+			},
+		},
+		Orgs: []*platform.Organization{
+			{
+				Name: "o1",
+				ID:   MustIDBase16(orgOneID),
+			},
+		},
+		Authorizations: []*platform.Authorization{
+			{
+				ID:          MustIDBase16(authOneID),
+				UserID:      MustIDBase16(userOneID),
+				OrgID:       MustIDBase16(orgOneID),
+				Token:       "rand1",
+				Permissions: allUsersPermission(MustIDBase16(orgOneID)),
+			},
+		},
+	}
+
+	s, opPrefix, done := init(fields, t)
+	defer done()
+	ctx := context.Background()
+
+	found := func(token string) bool {
+		t.Helper()
+		a, err := s.FindAuthorizationByToken(ctx, token)
+		if platform.ErrorCode(err) == platform.ENotFound {
+			return false
+		}
+		if err != nil {
+			t.Fatalf("failed to find authorization by token %q: %v", token, err)
+		}
+		if a.ID != MustIDBase16(authOneID) {
+			t.Fatalf("found authorization %s by token %q", a.ID, token)
+		}
+		return true
+	}
+
+	// The old token keeps working for the grace period.
+	a, err := s.RotateAuthorization(ctx, MustIDBase16(authOneID), time.Hour)
+	if err != nil {
+		t.Fatalf("failed to rotate authorization: %v", err)
+	}
+	if a.Token != "rotated1" {
+		t.Fatalf("expected rotated token %q, got %q", "rotated1", a.Token)
+	}
+	if diff := cmp.Diff(a.Permissions, allUsersPermission(MustIDBase16(orgOneID))); diff != "" {
+		t.Errorf("rotated permissions are different -got/+want\ndiff %s", diff)
+	}
+	if !found("rotated1") || !found("rand1") {
+		t.Fatal("expected both the new and the old token to be accepted")
+	}
+
+	// Without a grace period only the new token works, and the token
+	// replaced by an earlier rotation is dropped.
+	if _, err := s.RotateAuthorization(ctx, MustIDBase16(authOneID), 0); err != nil {
+		t.Fatalf("failed to rotate authorization: %v", err)
+	}
+	if !found("rotated2") {
+		t.Fatal("expected the new token to be accepted")
+	}
+	if found("rotated1") || found("rand1") {
+		t.Fatal("expected the old tokens to be rejected")
+	}
+
+	_, err = s.RotateAuthorization(ctx, MustIDBase16(authThreeID), 0)
+	diffPlatformErrors("rotate id not exists", err, &platform.Error{
+		Code: platform.ENotFound,
+		Op:   platform.OpRotateAuthorization,
+		Msg:  "authorization not found",
+	}, opPrefix, t)
+}
+
 // FindAuthorizationByToken testing
 func FindAuthorizationByToken(
 	init func(AuthorizationFields, *testing.T) (platform.AuthorizationService, string, func()),
@@ -769,7 +1019,6 @@ func FindAuthorizationByToken(
 					UserID:      MustIDBase16(userOneID),
 					OrgID:       MustIDBase16(orgTwoID),
 					Status:      platform.Inactive,
-					Token:       "rand1",
 					Permissions: allUsersPermission(MustIDBase16(orgTwoID)),
 				},
 			},
@@ -856,7 +1105,6 @@ func FindAuthorizations(
 						ID:          MustIDBase16(authOneID),
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
-						Token:       "rand1",
 						Status:      platform.Active,
 						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
 					},
@@ -864,7 +1112,6 @@ func FindAuthorizations(
 						ID:          MustIDBase16(authTwoID),
 						UserID:      MustIDBase16(userTwoID),
 						OrgID:       MustIDBase16(orgOneID),
-						Token:       "rand2",
 						Status:      platform.Active,
 						Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 					},
@@ -925,7 +1172,6 @@ func FindAuthorizations(
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
 						Status:      platform.Active,
-						Token:       "rand1",
 						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
 					},
 					{
@@ -933,7 +1179,6 @@ func FindAuthorizations(
 						UserID:      MustIDBase16(userOneID),
 						OrgID:       MustIDBase16(orgOneID),
 						Status:      platform.Active,
-						Token:       "rand3",
 						Permissions: deleteUsersPermission(MustIDBase16(orgOneID)),
 					},
 				},
@@ -998,7 +1243,6 @@ func FindAuthorizations(
 						ID:          MustIDBase16(authTwoID),
 						UserID:      MustIDBase16(userTwoID),
 						OrgID:       MustIDBase16(orgOneID),
-						Token:       "rand2",
 						Status:      platform.Active,
 						Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 					},
@@ -1098,7 +1342,6 @@ func DeleteAuthorization(
 						UserID:      MustIDBase16(userTwoID),
 						OrgID:       MustIDBase16(orgOneID),
 						Status:      platform.Active,
-						Token:       "rand2",
 						Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 					},
 				},
@@ -1153,7 +1396,6 @@ func DeleteAuthorization(
 					{
 						ID:          MustIDBase16(authOneID),
 						UserID:      MustIDBase16(userOneID),
-						Token:       "rand1",
 						Status:      platform.Active,
 						OrgID:       MustIDBase16(orgOneID),
 						Permissions: allUsersPermission(MustIDBase16(orgOneID)),
@@ -1162,7 +1404,6 @@ func DeleteAuthorization(
 						ID:          MustIDBase16(authTwoID),
 						UserID:      MustIDBase16(userTwoID),
 						OrgID:       MustIDBase16(orgOneID),
-						Token:       "rand2",
 						Status:      platform.Active,
 						Permissions: createUsersPermission(MustIDBase16(orgOneID)),
 					},
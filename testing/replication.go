@@ -0,0 +1,495 @@
+package testing
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+)
+
+const (
+	replicationOneID    = "020f755c3c082001"
+	replicationTwoID    = "020f755c3c082002"
+	replicationOrgOneID = "61726920617a696f"
+	replicationOrgTwoID = "61726920617a6970"
+	replicationBucketID = "020f755c3c082100"
+)
+
+var replicationCmpOptions = cmp.Options{
+	cmp.Transformer("Sort", func(in []*platform.Replication) []*platform.Replication {
+		out := append([]*platform.Replication(nil), in...) // Copy input to avoid mutating it
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].ID.String() > out[j].ID.String()
+		})
+		return out
+	}),
+}
+
+// ReplicationFields will include the IDGenerator, and replications
+type ReplicationFields struct {
+	IDGenerator  platform.IDGenerator
+	Replications []*platform.Replication
+}
+
+// newTestReplication returns a valid replication with the ID in the org.
+func newTestReplication(id, orgID, name string) *platform.Replication {
+	return &platform.Replication{
+		ID:             MustIDBase16(id),
+		OrgID:          MustIDBase16(orgID),
+		Name:           name,
+		SourceBucketID: MustIDBase16(replicationBucketID),
+		RemoteURL:      "http://remote:9999",
+		RemoteToken:    "token",
+		RemoteOrgID:    MustIDBase16(orgID),
+		RemoteBucketID: MustIDBase16(replicationBucketID),
+		MaxQueueSize:   platform.DefaultReplicationMaxQueueSize,
+	}
+}
+
+// ReplicationService tests all the service functions.
+func ReplicationService(
+	init func(ReplicationFields, *testing.T) (platform.ReplicationService, string, func()),
+	t *testing.T,
+) {
+	tests := []struct {
+		name string
+		fn   func(init func(ReplicationFields, *testing.T) (platform.ReplicationService, string, func()),
+			t *testing.T)
+	}{
+		{
+			name: "CreateReplication",
+			fn:   CreateReplication,
+		},
+		{
+			name: "FindReplicationByID",
+			fn:   FindReplicationByID,
+		},
+		{
+			name: "FindReplications",
+			fn:   FindReplications,
+		},
+		{
+			name: "UpdateReplication",
+			fn:   UpdateReplication,
+		},
+		{
+			name: "DeleteReplication",
+			fn:   DeleteReplication,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(init, t)
+		})
+	}
+}
+
+// CreateReplication testing
+func CreateReplication(
+	init func(ReplicationFields, *testing.T) (platform.ReplicationService, string, func()),
+	t *testing.T,
+) {
+	type args struct {
+		replication *platform.Replication
+	}
+	type wants struct {
+		err          error
+		replications []*platform.Replication
+	}
+
+	withoutID := func(r *platform.Replication) *platform.Replication {
+		r.ID = 0
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		fields ReplicationFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "create replication with the default queue size",
+			fields: ReplicationFields{
+				IDGenerator:  mock.NewIDGenerator(replicationOneID, t),
+				Replications: []*platform.Replication{},
+			},
+			args: args{
+				replication: func() *platform.Replication {
+					r := withoutID(newTestReplication(replicationOneID, replicationOrgOneID, "edge"))
+					r.MaxQueueSize = 0
+					return r
+				}(),
+			},
+			wants: wants{
+				replications: []*platform.Replication{
+					newTestReplication(replicationOneID, replicationOrgOneID, "edge"),
+				},
+			},
+		},
+		{
+			name: "create replication without a remote URL",
+			fields: ReplicationFields{
+				IDGenerator:  mock.NewIDGenerator(replicationOneID, t),
+				Replications: []*platform.Replication{},
+			},
+			args: args{
+				replication: func() *platform.Replication {
+					r := withoutID(newTestReplication(replicationOneID, replicationOrgOneID, "edge"))
+					r.RemoteURL = "remote:9999"
+					return r
+				}(),
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "replication remote URL must be an http or https URL",
+				},
+				replications: []*platform.Replication{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, opPrefix, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.CreateReplication(ctx, tt.args.replication)
+			diffPlatformErrors(tt.name, err, tt.wants.err, opPrefix, t)
+			if err == nil {
+				defer s.DeleteReplication(ctx, tt.args.replication.ID)
+			}
+
+			replications, _, err := s.FindReplications(ctx, platform.ReplicationFilter{})
+			if err != nil {
+				t.Fatalf("failed to retrieve replications: %v", err)
+			}
+			if diff := cmp.Diff(replications, tt.wants.replications, replicationCmpOptions...); diff != "" {
+				t.Errorf("replications are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindReplicationByID testing
+func FindReplicationByID(
+	init func(ReplicationFields, *testing.T) (platform.ReplicationService, string, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id platform.ID
+	}
+	type wants struct {
+		err         error
+		replication *platform.Replication
+	}
+
+	tests := []struct {
+		name   string
+		fields ReplicationFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find replication by ID",
+			fields: ReplicationFields{
+				Replications: []*platform.Replication{
+					newTestReplication(replicationOneID, replicationOrgOneID, "edge"),
+					newTestReplication(replicationTwoID, replicationOrgOneID, "site"),
+				},
+			},
+			args: args{
+				id: MustIDBase16(replicationTwoID),
+			},
+			wants: wants{
+				replication: newTestReplication(replicationTwoID, replicationOrgOneID, "site"),
+			},
+		},
+		{
+			name: "find missing replication",
+			fields: ReplicationFields{
+				Replications: []*platform.Replication{},
+			},
+			args: args{
+				id: MustIDBase16(replicationOneID),
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.ENotFound,
+					Op:   platform.OpFindReplicationByID,
+					Msg:  platform.ErrReplicationNotFound,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, opPrefix, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			replication, err := s.FindReplicationByID(ctx, tt.args.id)
+			diffPlatformErrors(tt.name, err, tt.wants.err, opPrefix, t)
+
+			if diff := cmp.Diff(replication, tt.wants.replication); diff != "" {
+				t.Errorf("replications are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindReplications testing
+func FindReplications(
+	init func(ReplicationFields, *testing.T) (platform.ReplicationService, string, func()),
+	t *testing.T,
+) {
+	type args struct {
+		filter platform.ReplicationFilter
+	}
+	type wants struct {
+		err          error
+		replications []*platform.Replication
+	}
+
+	fields := ReplicationFields{
+		Replications: []*platform.Replication{
+			newTestReplication(replicationOneID, replicationOrgOneID, "edge"),
+			newTestReplication(replicationTwoID, replicationOrgTwoID, "site"),
+		},
+	}
+
+	tests := []struct {
+		name   string
+		fields ReplicationFields
+		args   args
+		wants  wants
+	}{
+		{
+			name:   "find all replications",
+			fields: fields,
+			wants: wants{
+				replications: []*platform.Replication{
+					newTestReplication(replicationOneID, replicationOrgOneID, "edge"),
+					newTestReplication(replicationTwoID, replicationOrgTwoID, "site"),
+				},
+			},
+		},
+		{
+			name:   "find replications by org",
+			fields: fields,
+			args: args{
+				filter: platform.ReplicationFilter{OrgID: idPtr(MustIDBase16(replicationOrgTwoID))},
+			},
+			wants: wants{
+				replications: []*platform.Replication{
+					newTestReplication(replicationTwoID, replicationOrgTwoID, "site"),
+				},
+			},
+		},
+		{
+			name:   "find replications by source bucket",
+			fields: fields,
+			args: args{
+				filter: platform.ReplicationFilter{SourceBucketID: idPtr(MustIDBase16(replicationOneID))},
+			},
+			wants: wants{
+				replications: []*platform.Replication{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, opPrefix, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			replications, _, err := s.FindReplications(ctx, tt.args.filter)
+			diffPlatformErrors(tt.name, err, tt.wants.err, opPrefix, t)
+
+			if diff := cmp.Diff(replications, tt.wants.replications, replicationCmpOptions...); diff != "" {
+				t.Errorf("replications are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// UpdateReplication testing
+func UpdateReplication(
+	init func(ReplicationFields, *testing.T) (platform.ReplicationService, string, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id  platform.ID
+		upd platform.ReplicationUpdate
+	}
+	type wants struct {
+		err         error
+		replication *platform.Replication
+	}
+
+	name := "central"
+	measurements := []string{"cpu", "mem"}
+	invalidURL := "ftp://remote"
+
+	tests := []struct {
+		name   string
+		fields ReplicationFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "update name and measurements",
+			fields: ReplicationFields{
+				Replications: []*platform.Replication{
+					newTestReplication(replicationOneID, replicationOrgOneID, "edge"),
+				},
+			},
+			args: args{
+				id: MustIDBase16(replicationOneID),
+				upd: platform.ReplicationUpdate{
+					Name:         &name,
+					Measurements: &measurements,
+				},
+			},
+			wants: wants{
+				replication: func() *platform.Replication {
+					r := newTestReplication(replicationOneID, replicationOrgOneID, "central")
+					r.Measurements = []string{"cpu", "mem"}
+					return r
+				}(),
+			},
+		},
+		{
+			name: "update with invalid remote URL",
+			fields: ReplicationFields{
+				Replications: []*platform.Replication{
+					newTestReplication(replicationOneID, replicationOrgOneID, "edge"),
+				},
+			},
+			args: args{
+				id: MustIDBase16(replicationOneID),
+				upd: platform.ReplicationUpdate{
+					RemoteURL: &invalidURL,
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Op:   platform.OpUpdateReplication,
+					Msg:  "replication remote URL must be an http or https URL",
+				},
+			},
+		},
+		{
+			name: "update missing replication",
+			fields: ReplicationFields{
+				Replications: []*platform.Replication{},
+			},
+			args: args{
+				id: MustIDBase16(replicationOneID),
+				upd: platform.ReplicationUpdate{
+					Name: &name,
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.ENotFound,
+					Op:   platform.OpUpdateReplication,
+					Msg:  platform.ErrReplicationNotFound,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, opPrefix, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			replication, err := s.UpdateReplication(ctx, tt.args.id, tt.args.upd)
+			diffPlatformErrors(tt.name, err, tt.wants.err, opPrefix, t)
+
+			if diff := cmp.Diff(replication, tt.wants.replication); diff != "" {
+				t.Errorf("replications are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// DeleteReplication testing
+func DeleteReplication(
+	init func(ReplicationFields, *testing.T) (platform.ReplicationService, string, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id platform.ID
+	}
+	type wants struct {
+		err          error
+		replications []*platform.Replication
+	}
+
+	tests := []struct {
+		name   string
+		fields ReplicationFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "delete replication by ID",
+			fields: ReplicationFields{
+				Replications: []*platform.Replication{
+					newTestReplication(replicationOneID, replicationOrgOneID, "edge"),
+					newTestReplication(replicationTwoID, replicationOrgOneID, "site"),
+				},
+			},
+			args: args{
+				id: MustIDBase16(replicationOneID),
+			},
+			wants: wants{
+				replications: []*platform.Replication{
+					newTestReplication(replicationTwoID, replicationOrgOneID, "site"),
+				},
+			},
+		},
+		{
+			name: "delete missing replication",
+			fields: ReplicationFields{
+				Replications: []*platform.Replication{},
+			},
+			args: args{
+				id: MustIDBase16(replicationOneID),
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.ENotFound,
+					Op:   platform.OpDeleteReplication,
+					Msg:  platform.ErrReplicationNotFound,
+				},
+				replications: []*platform.Replication{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, opPrefix, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.DeleteReplication(ctx, tt.args.id)
+			diffPlatformErrors(tt.name, err, tt.wants.err, opPrefix, t)
+
+			replications, _, err := s.FindReplications(ctx, platform.ReplicationFilter{})
+			if err != nil {
+				t.Fatalf("failed to retrieve replications: %v", err)
+			}
+			if diff := cmp.Diff(replications, tt.wants.replications, replicationCmpOptions...); diff != "" {
+				t.Errorf("replications are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
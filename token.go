@@ -1,7 +1,65 @@
 package influxdb
 
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+)
+
 // TokenGenerator represents a generator for API tokens.
 type TokenGenerator interface {
 	// Token generates a new API token.
 	Token() (string, error)
 }
+
+// TokenPrefixLength is the number of leading characters of a token that are
+// stored in the clear to look up its authorization.
+const TokenPrefixLength = 8
+
+const (
+	tokenHashScheme   = "sha256"
+	tokenHashSaltSize = 16
+)
+
+// TokenPrefix returns the prefix of a token that its authorization is
+// indexed by.
+func TokenPrefix(token string) string {
+	if len(token) > TokenPrefixLength {
+		return token[:TokenPrefixLength]
+	}
+	return token
+}
+
+// HashToken returns a salted hash of a token, to be stored in place of the
+// token. Tokens are random and long, so a single round of SHA-256 keeps
+// them safe at rest while leaving token lookups on every request cheap.
+func HashToken(token string) (string, error) {
+	salt := make([]byte, tokenHashSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return encodeTokenHash(salt, token), nil
+}
+
+// CompareTokenHash reports whether token is the token that hash was
+// computed from by HashToken.
+func CompareTokenHash(hash, token string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 3 || parts[0] != tokenHashScheme {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(encodeTokenHash(salt, token)), []byte(hash)) == 1
+}
+
+func encodeTokenHash(salt []byte, token string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(token))
+	return tokenHashScheme + "$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,48 @@
+package influxdb_test
+
+import (
+	"strings"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+func TestHashToken(t *testing.T) {
+	token := "my-secret-token"
+	hash, err := platform.HashToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(hash, token) {
+		t.Fatalf("hash %q contains the token", hash)
+	}
+	if !platform.CompareTokenHash(hash, token) {
+		t.Fatal("expected hash to match its token")
+	}
+	for _, other := range []string{"", "my-secret-toke", "my-secret-token2"} {
+		if platform.CompareTokenHash(hash, other) {
+			t.Errorf("expected hash not to match %q", other)
+		}
+	}
+
+	again, err := platform.HashToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == hash {
+		t.Fatal("expected hashes of the same token to be salted differently")
+	}
+
+	if platform.CompareTokenHash("", token) || platform.CompareTokenHash("md5$abc$def", token) {
+		t.Fatal("expected malformed hashes not to match")
+	}
+}
+
+func TestTokenPrefix(t *testing.T) {
+	if got := platform.TokenPrefix("abcdefghijkl"); got != "abcdefgh" {
+		t.Fatalf("unexpected prefix %q", got)
+	}
+	if got := platform.TokenPrefix("abc"); got != "abc" {
+		t.Fatalf("unexpected prefix %q", got)
+	}
+}
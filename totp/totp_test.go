@@ -0,0 +1,72 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/totp"
+)
+
+// secret is the secret of the test vectors of RFC 6238.
+var secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totp.Code(secret, totp.Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("unexpected code at %d: got %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := totp.Code(secret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if step, ok := totp.Validate(secret, code, now); !ok || step != totp.Step(now) {
+		t.Fatalf("expected code to be valid at step %d, got %d, %v", totp.Step(now), step, ok)
+	}
+	if _, ok := totp.Validate(secret, code, now.Add(totp.Period*time.Second)); !ok {
+		t.Fatal("expected code of the previous step to be valid")
+	}
+	if _, ok := totp.Validate(secret, code, now.Add(3*totp.Period*time.Second)); ok {
+		t.Fatal("expected code to have expired")
+	}
+	if _, ok := totp.Validate(secret, "12345", now); ok {
+		t.Fatal("expected short code to be invalid")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	s, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := totp.Code(s, 1); err != nil {
+		t.Fatalf("generated secret is invalid: %v", err)
+	}
+
+	u := totp.URL("InfluxDB", "jane", s)
+	if !strings.HasPrefix(u, "otpauth://totp/InfluxDB:jane?") || !strings.Contains(u, "secret="+s) {
+		t.Fatalf("unexpected url: %s", u)
+	}
+}
@@ -21,6 +21,13 @@ type PartialWriteError struct {
 
 	// A sorted slice of series keys that were dropped.
 	DroppedKeys [][]byte
+
+	// The reason each of DroppedKeys was dropped.
+	DroppedReasons []string
+
+	// The sorted positions of the dropped points in the write, if they are known.
+	// Points of a dropped series key may not all have been dropped.
+	DroppedIndexes []int
 }
 
 func (e PartialWriteError) Error() string {
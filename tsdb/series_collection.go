@@ -1,6 +1,7 @@
 package tsdb
 
 import (
+	"sort"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -20,10 +21,18 @@ type SeriesCollection struct {
 	Types      []models.FieldType
 	SeriesIDs  []SeriesID
 
-	// Keeps track of invalid entries.
-	Dropped     uint64
-	DroppedKeys [][]byte
-	Reason      string
+	// Indexes holds the position of each entry among the points the collection
+	// was made from, so that dropped entries can be traced back to their point.
+	Indexes []int
+
+	// Keeps track of invalid entries. DroppedReasons holds the reason each of
+	// DroppedKeys was dropped, and DroppedIndexes the positions of the dropped
+	// entries that are known.
+	Dropped        uint64
+	DroppedKeys    [][]byte
+	DroppedReasons []string
+	DroppedIndexes []int
+	Reason         string
 
 	// Used by the concurrent iterators to stage drops. Inefficient, but should be
 	// very infrequently used.
@@ -34,21 +43,23 @@ type SeriesCollection struct {
 type seriesCollectionState struct {
 	mu     sync.Mutex
 	reason string
-	index  map[int]struct{}
+	index  map[int]string
 }
 
 // NewSeriesCollection builds a SeriesCollection from a slice of points. It does some filtering
 // of invalid points.
 func NewSeriesCollection(points []models.Point) *SeriesCollection {
 	out := &SeriesCollection{
-		Points: append([]models.Point(nil), points...),
-		Keys:   make([][]byte, 0, len(points)),
-		Names:  make([][]byte, 0, len(points)),
-		Tags:   make([]models.Tags, 0, len(points)),
-		Types:  make([]models.FieldType, 0, len(points)),
+		Points:  append([]models.Point(nil), points...),
+		Keys:    make([][]byte, 0, len(points)),
+		Names:   make([][]byte, 0, len(points)),
+		Tags:    make([]models.Tags, 0, len(points)),
+		Types:   make([]models.FieldType, 0, len(points)),
+		Indexes: make([]int, 0, len(points)),
 	}
 
-	for _, pt := range points {
+	for i, pt := range points {
+		out.Indexes = append(out.Indexes, i)
 		out.Keys = append(out.Keys, pt.Key())
 		out.Names = append(out.Names, pt.Name())
 		out.Tags = append(out.Tags, pt.Tags())
@@ -83,6 +94,8 @@ func (s *SeriesCollection) Length() int {
 		return len(s.Types)
 	case s.SeriesIDs != nil:
 		return len(s.SeriesIDs)
+	case s.Indexes != nil:
+		return len(s.Indexes)
 	default:
 		return 0
 	}
@@ -115,6 +128,9 @@ func (s *SeriesCollection) Copy(dst, src int) {
 	if n := uint(len(s.SeriesIDs)); udst < n && usrc < n {
 		s.SeriesIDs[udst] = s.SeriesIDs[usrc]
 	}
+	if n := uint(len(s.Indexes)); udst < n && usrc < n {
+		s.Indexes[udst] = s.Indexes[usrc]
+	}
 }
 
 // Swap will swap the elements at i and j in all slices that can: x[i], x[j] = x[j], x[i].
@@ -144,6 +160,9 @@ func (s *SeriesCollection) Swap(i, j int) {
 	if n := uint(len(s.SeriesIDs)); ui < n && uj < n {
 		s.SeriesIDs[ui], s.SeriesIDs[uj] = s.SeriesIDs[uj], s.SeriesIDs[ui]
 	}
+	if n := uint(len(s.Indexes)); ui < n && uj < n {
+		s.Indexes[ui], s.Indexes[uj] = s.Indexes[uj], s.Indexes[ui]
+	}
 }
 
 // Truncate will truncate all of the slices that can down to length: x = x[:length].
@@ -170,6 +189,9 @@ func (s *SeriesCollection) Truncate(length int) {
 	if ulength < uint(len(s.SeriesIDs)) {
 		s.SeriesIDs = s.SeriesIDs[:ulength]
 	}
+	if ulength < uint(len(s.Indexes)) {
+		s.Indexes = s.Indexes[:ulength]
+	}
 }
 
 // Advance will advance all of the slices that can length elements: x = x[length:].
@@ -196,18 +218,51 @@ func (s *SeriesCollection) Advance(length int) {
 	if ulength < uint(len(s.SeriesIDs)) {
 		s.SeriesIDs = s.SeriesIDs[ulength:]
 	}
+	if ulength < uint(len(s.Indexes)) {
+		s.Indexes = s.Indexes[ulength:]
+	}
 }
 
 // InvalidateAll causes all of the entries to become invalid.
 func (s *SeriesCollection) InvalidateAll(reason string) {
+	for i := range s.Keys {
+		s.DropIndex(i, reason)
+	}
 	if s.Reason == "" {
 		s.Reason = reason
 	}
-	s.Dropped += uint64(len(s.Keys))
-	s.DroppedKeys = append(s.DroppedKeys, s.Keys...)
 	s.Truncate(0)
 }
 
+// Drop records that the entry with the key was dropped for the reason. It does not
+// remove the entry from the collection. Only the first reason is kept as the Reason
+// for the collection.
+func (s *SeriesCollection) Drop(key []byte, reason string) {
+	if s.Reason == "" {
+		s.Reason = reason
+	}
+	s.Dropped++
+	s.DroppedKeys = append(s.DroppedKeys, key)
+	s.DroppedReasons = append(s.DroppedReasons, reason)
+}
+
+// DropIndex records that the entry at index was dropped for the reason, along
+// with its position among the points the collection was made from. It does not
+// remove the entry from the collection.
+func (s *SeriesCollection) DropIndex(index int, reason string) {
+	if index < len(s.Indexes) {
+		s.DroppedIndexes = append(s.DroppedIndexes, s.Indexes[index])
+	}
+	if index < len(s.Keys) {
+		s.Drop(s.Keys[index], reason)
+		return
+	}
+	if s.Reason == "" {
+		s.Reason = reason
+	}
+	s.Dropped++
+}
+
 // ApplyConcurrentDrops will remove all of the dropped values during concurrent iteration. It should
 // not be called concurrently with any calls to Invalid.
 func (s *SeriesCollection) ApplyConcurrentDrops() {
@@ -216,15 +271,14 @@ func (s *SeriesCollection) ApplyConcurrentDrops() {
 		return
 	}
 
+	if s.Reason == "" {
+		s.Reason = state.reason
+	}
+
 	length, j := s.Length(), 0
 	for i := 0; i < length; i++ {
-		if _, ok := state.index[i]; ok {
-			s.Dropped++
-
-			if i < len(s.Keys) {
-				s.DroppedKeys = append(s.DroppedKeys, s.Keys[i])
-			}
-
+		if reason, ok := state.index[i]; ok {
+			s.DropIndex(i, reason)
 			continue
 		}
 
@@ -233,10 +287,6 @@ func (s *SeriesCollection) ApplyConcurrentDrops() {
 	}
 	s.Truncate(j)
 
-	if s.Reason == "" {
-		s.Reason = state.reason
-	}
-
 	// clear concurrent state
 	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&s.state)), nil)
 }
@@ -266,9 +316,11 @@ func (s *SeriesCollection) invalidIndex(index int, reason string) {
 
 	state.mu.Lock()
 	if state.index == nil {
-		state.index = make(map[int]struct{})
+		state.index = make(map[int]string)
+	}
+	if _, ok := state.index[index]; !ok {
+		state.index[index] = reason
 	}
-	state.index[index] = struct{}{}
 	if state.reason == "" {
 		state.reason = reason
 	}
@@ -281,11 +333,38 @@ func (s *SeriesCollection) PartialWriteError() error {
 	if s.Dropped == 0 {
 		return nil
 	}
+
+	// Keep the first reason each key was dropped, in the order of the sorted keys.
+	reasons := make(map[string]string, len(s.DroppedReasons))
+	for i, reason := range s.DroppedReasons {
+		if _, ok := reasons[string(s.DroppedKeys[i])]; !ok {
+			reasons[string(s.DroppedKeys[i])] = reason
+		}
+	}
+
 	droppedKeys := bytesutil.SortDedup(s.DroppedKeys)
+	droppedReasons := make([]string, len(droppedKeys))
+	for i, key := range droppedKeys {
+		if reason, ok := reasons[string(key)]; ok {
+			droppedReasons[i] = reason
+		} else {
+			droppedReasons[i] = s.Reason
+		}
+	}
+
+	// Positions are only reported if they are known for every dropped entry.
+	var droppedIndexes []int
+	if len(s.DroppedIndexes) == int(s.Dropped) {
+		droppedIndexes = append(droppedIndexes, s.DroppedIndexes...)
+		sort.Ints(droppedIndexes)
+	}
+
 	return PartialWriteError{
-		Reason:      s.Reason,
-		Dropped:     len(droppedKeys),
-		DroppedKeys: droppedKeys,
+		Reason:         s.Reason,
+		Dropped:        len(droppedKeys),
+		DroppedKeys:    droppedKeys,
+		DroppedReasons: droppedReasons,
+		DroppedIndexes: droppedIndexes,
 	}
 }
 
@@ -118,9 +118,10 @@ func TestSeriesCollection(t *testing.T) {
 		collection.InvalidateAll("test reason")
 		assertEqual(t, "length", collection.Length(), 0)
 		assertEqual(t, "error", collection.PartialWriteError(), PartialWriteError{
-			Reason:      "test reason",
-			Dropped:     3,
-			DroppedKeys: bs("ka", "kb", "kc"),
+			Reason:         "test reason",
+			Dropped:        3,
+			DroppedKeys:    bs("ka", "kb", "kc"),
+			DroppedReasons: []string{"test reason", "test reason", "test reason"},
 		})
 	})
 
@@ -130,7 +131,7 @@ func TestSeriesCollection(t *testing.T) {
 		// invalidate half the entries
 		for iter := collection.Iterator(); iter.Next(); {
 			if iter.Index()%2 == 0 {
-				iter.Invalid("test reason")
+				iter.Invalid("test reason " + string(iter.Key()))
 			}
 		}
 
@@ -141,9 +142,10 @@ func TestSeriesCollection(t *testing.T) {
 		collection.ApplyConcurrentDrops()
 		assertEqual(t, "length", collection.Length(), 1)
 		assertEqual(t, "error", collection.PartialWriteError(), PartialWriteError{
-			Reason:      "test reason",
-			Dropped:     2,
-			DroppedKeys: bs("ka", "kc"),
+			Reason:         "test reason ka",
+			Dropped:        2,
+			DroppedKeys:    bs("ka", "kc"),
+			DroppedReasons: []string{"test reason ka", "test reason kc"},
 		})
 	})
 }
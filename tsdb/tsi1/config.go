@@ -25,6 +25,16 @@ type Config struct {
 	// The cache uses an LRU strategy for eviction. Setting the value to 0 will
 	// disable the cache.
 	SeriesIDSetCacheSize uint64
+
+	// MaxSeriesPerBucket is the maximum number of series a bucket may contain.
+	// Writes that would create new series beyond the limit are dropped. Setting
+	// the value to 0 disables the limit.
+	MaxSeriesPerBucket int `toml:"max-series-per-bucket"`
+
+	// MaxValuesPerTag is the maximum number of values a tag key may have within
+	// a bucket. Writes that would add new tag values beyond the limit are dropped.
+	// Setting the value to 0 disables the limit.
+	MaxValuesPerTag int `toml:"max-values-per-tag"`
 }
 
 // NewConfig returns a new Config.
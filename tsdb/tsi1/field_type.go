@@ -0,0 +1,409 @@
+package tsi1
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// fieldTypeKey identifies a field within a measurement of a bucket.
+type fieldTypeKey struct {
+	name        string // bucket name
+	measurement string
+	field       string
+}
+
+// fieldTypeCache caches the type of each field that has been written to or looked
+// up in the index. The index and series file are the source of truth, so entries
+// are only removed when series are dropped and never need to be persisted.
+//
+// Each bucket has its own lock, so that writes to different buckets do not wait
+// on each other.
+type fieldTypeCache struct {
+	mu      sync.RWMutex
+	buckets map[string]*bucketFieldTypes
+}
+
+// bucketFieldTypes are the cached field types of a bucket.
+type bucketFieldTypes struct {
+	// create is held by writes creating fields in the bucket, from checking
+	// the types of the fields until their series are created and the types
+	// are cached, so that concurrent writes cannot give a field two types.
+	create sync.Mutex
+
+	mu sync.Mutex
+	// gen is incremented whenever types are removed, so that types looked up in
+	// the index before series were dropped are not cached afterwards.
+	gen   uint64
+	types map[fieldTypeKey]models.FieldType
+}
+
+func newFieldTypeCache() *fieldTypeCache {
+	return &fieldTypeCache{buckets: make(map[string]*bucketFieldTypes)}
+}
+
+// bucket returns the cached field types of the bucket, creating them if needed.
+func (c *fieldTypeCache) bucket(name string) *bucketFieldTypes {
+	c.mu.RLock()
+	b := c.buckets[name]
+	c.mu.RUnlock()
+	if b != nil {
+		return b
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b = c.buckets[name]; b == nil {
+		b = &bucketFieldTypes{types: make(map[fieldTypeKey]models.FieldType)}
+		c.buckets[name] = b
+	}
+	return b
+}
+
+// get returns the cached type of the field, and the generation of the bucket to
+// pass to set if it is not cached.
+func (c *fieldTypeCache) get(k fieldTypeKey) (models.FieldType, bool, uint64) {
+	b := c.bucket(k.name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	typ, ok := b.types[k]
+	return typ, ok, b.gen
+}
+
+// set caches the type of the field, unless types of the bucket were removed since
+// generation gen.
+func (c *fieldTypeCache) set(k fieldTypeKey, typ models.FieldType, gen uint64) {
+	b := c.bucket(k.name)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.gen == gen {
+		b.types[k] = typ
+	}
+}
+
+// deleteSeries removes the cached type of the field the series key belongs to.
+func (c *fieldTypeCache) deleteSeries(key []byte) {
+	name, tags := models.ParseKeyBytes(key)
+	k, ok := newFieldTypeKey(name, tags)
+	if !ok {
+		return
+	}
+
+	b := c.bucket(k.name)
+	b.mu.Lock()
+	delete(b.types, k)
+	b.gen++
+	b.mu.Unlock()
+}
+
+// deleteMeasurement removes the cached types of all fields in the bucket.
+func (c *fieldTypeCache) deleteMeasurement(name []byte) {
+	b := c.bucket(string(name))
+	b.mu.Lock()
+	b.types = make(map[fieldTypeKey]models.FieldType)
+	b.gen++
+	b.mu.Unlock()
+}
+
+// newFieldTypeKey returns the key of the field the series tags belong to. It
+// returns false if the tags do not include a measurement and field.
+func newFieldTypeKey(name []byte, tags models.Tags) (fieldTypeKey, bool) {
+	m, f := tags.Get(models.MeasurementTagKeyBytes), tags.Get(models.FieldKeyTagKeyBytes)
+	if m == nil || f == nil {
+		return fieldTypeKey{}, false
+	}
+	return fieldTypeKey{name: string(name), measurement: string(m), field: string(f)}, true
+}
+
+// fieldTypeWrite holds the locks of the buckets in which a write creates fields,
+// and the types of the fields it creates.
+type fieldTypeWrite struct {
+	cache   *fieldTypeCache
+	buckets []*bucketFieldTypes
+	// gens are the generations of the buckets of fields missing from the cache.
+	gens    map[fieldTypeKey]uint64
+	created map[fieldTypeKey]models.FieldType
+}
+
+// commit caches the types of the fields created by the series of the collection.
+// It must be called once the series are created, before unlock.
+func (w *fieldTypeWrite) commit(collection *tsdb.SeriesCollection) {
+	if len(w.created) == 0 {
+		return
+	}
+	for iter := collection.Iterator(); iter.Next(); {
+		k, ok := newFieldTypeKey(iter.Name(), iter.Tags())
+		if !ok {
+			continue
+		}
+		if typ, ok := w.created[k]; ok && typ == iter.Type() {
+			w.cache.set(k, typ, w.gens[k])
+			delete(w.created, k)
+		}
+	}
+}
+
+// unlock releases the locks of the buckets.
+func (w *fieldTypeWrite) unlock() {
+	for j := len(w.buckets) - 1; j >= 0; j-- {
+		w.buckets[j].create.Unlock()
+	}
+	w.buckets = nil
+}
+
+// checkFieldTypes marks series in the collection as invalid if the type of their
+// field differs from the type the field already has in the measurement, either
+// in the index or earlier in the collection.
+//
+// Fields found in the cache already exist, so their types cannot change. If some
+// fields are missing from the cache, the buckets of those fields are locked until
+// the returned write is unlocked, and the fields are looked up again in the cache
+// and then in the index. The caller creates the series and commits the write
+// while holding the locks, so that concurrent writes creating the same field
+// cannot give it different types. Only types of series that were created are
+// cached, so that series dropped later in the write do not claim a type.
+func (i *Index) checkFieldTypes(collection *tsdb.SeriesCollection) (*fieldTypeWrite, error) {
+	w := &fieldTypeWrite{cache: i.fieldTypes}
+	if len(collection.Types) == 0 {
+		return w, nil
+	}
+
+	// Types of the fields of the collection that exist in the index.
+	types := make(map[fieldTypeKey]models.FieldType)
+	missing := make(map[fieldTypeKey]struct{})
+
+	for iter := collection.Iterator(); iter.Next(); {
+		k, ok := newFieldTypeKey(iter.Name(), iter.Tags())
+		if !ok {
+			continue
+		}
+		if _, ok := types[k]; ok {
+			continue
+		} else if _, ok := missing[k]; ok {
+			continue
+		}
+
+		if typ, ok, _ := i.fieldTypes.get(k); ok {
+			types[k] = typ
+		} else {
+			missing[k] = struct{}{}
+		}
+	}
+
+	if len(missing) > 0 {
+		// Buckets are locked in order, so that writes to several buckets do not
+		// deadlock.
+		var names []string
+		seen := make(map[string]bool)
+		for k := range missing {
+			if !seen[k.name] {
+				seen[k.name] = true
+				names = append(names, k.name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b := i.fieldTypes.bucket(name)
+			b.create.Lock()
+			w.buckets = append(w.buckets, b)
+		}
+
+		w.gens = make(map[fieldTypeKey]uint64, len(missing))
+		for k := range missing {
+			// Another write may have created the field while waiting for the lock.
+			typ, ok, gen := i.fieldTypes.get(k)
+			if !ok {
+				var err error
+				typ, ok, err = i.fieldType([]byte(k.name), []byte(k.measurement), []byte(k.field))
+				if err != nil {
+					w.unlock()
+					return nil, err
+				} else if ok {
+					i.fieldTypes.set(k, typ, gen)
+				}
+			}
+			if ok {
+				types[k] = typ
+			} else {
+				w.gens[k] = gen
+			}
+		}
+	}
+
+	// Types of fields that are new to the index, as set by this collection.
+	w.created = make(map[fieldTypeKey]models.FieldType)
+
+	for iter := collection.Iterator(); iter.Next(); {
+		k, ok := newFieldTypeKey(iter.Name(), iter.Tags())
+		if !ok {
+			continue
+		}
+
+		typ, ok := types[k]
+		if !ok {
+			typ, ok = w.created[k]
+		}
+
+		if !ok {
+			w.created[k] = iter.Type()
+		} else if typ != iter.Type() {
+			iter.Invalid(fmt.Sprintf("field type conflict: input field %q on measurement %q is type %s, already exists as type %s",
+				k.field, k.measurement, fieldTypeName(iter.Type()), fieldTypeName(typ)))
+		}
+	}
+
+	collection.ApplyConcurrentDrops()
+	return w, nil
+}
+
+// fieldType returns the type of the field in the measurement from the first of its
+// series in the index. It returns false if the field does not exist.
+func (i *Index) fieldType(name, measurement, field []byte) (models.FieldType, bool, error) {
+	mitr, err := i.tagValueSeriesIDIterator(name, models.MeasurementTagKeyBytes, measurement)
+	if err != nil {
+		return 0, false, err
+	}
+	fitr, err := i.tagValueSeriesIDIterator(name, models.FieldKeyTagKeyBytes, field)
+	if err != nil {
+		if mitr != nil {
+			mitr.Close()
+		}
+		return 0, false, err
+	}
+
+	itr := tsdb.IntersectSeriesIDIterators(mitr, fitr)
+	if itr == nil {
+		return 0, false, nil
+	}
+	itr, err = tsdb.FilterUndeletedSeriesIDIterator(i.sfile, itr)
+	if err != nil {
+		return 0, false, err
+	}
+	defer itr.Close()
+
+	for {
+		elem, err := itr.Next()
+		if err != nil {
+			return 0, false, err
+		} else if elem.SeriesID.IsZero() {
+			return 0, false, nil
+		}
+
+		key := i.sfile.SeriesKey(elem.SeriesID)
+		if len(key) == 0 {
+			continue
+		}
+		if id := i.sfile.SeriesIDTypedBySeriesKey(key); id.HasType() {
+			return id.Type(), true, nil
+		}
+	}
+}
+
+// MeasurementField describes the type of a field within a measurement.
+type MeasurementField struct {
+	Measurement []byte
+	Field       []byte
+	Type        models.FieldType
+}
+
+// MeasurementFields returns the fields of every measurement in the bucket with the
+// given name, sorted by measurement and then by field.
+func (i *Index) MeasurementFields(name []byte) ([]MeasurementField, error) {
+	measurements, err := i.tagValues(name, models.MeasurementTagKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var a []MeasurementField
+	for _, m := range measurements {
+		fields, err := i.measurementFields(name, m)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, fields...)
+	}
+
+	sort.Slice(a, func(i, j int) bool {
+		if c := bytes.Compare(a[i].Measurement, a[j].Measurement); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(a[i].Field, a[j].Field) < 0
+	})
+	return a, nil
+}
+
+// measurementFields returns the fields of the measurement, with the type of the
+// first of their series in the index.
+func (i *Index) measurementFields(name, measurement []byte) ([]MeasurementField, error) {
+	itr, err := i.tagValueSeriesIDIterator(name, models.MeasurementTagKeyBytes, measurement)
+	if err != nil {
+		return nil, err
+	}
+	itr, err = tsdb.FilterUndeletedSeriesIDIterator(i.sfile, itr)
+	if err != nil {
+		return nil, err
+	} else if itr == nil {
+		return nil, nil
+	}
+	defer itr.Close()
+
+	var a []MeasurementField
+	seen := make(map[string]struct{})
+	for {
+		elem, err := itr.Next()
+		if err != nil {
+			return nil, err
+		} else if elem.SeriesID.IsZero() {
+			return a, nil
+		}
+
+		key := i.sfile.SeriesKey(elem.SeriesID)
+		if len(key) == 0 {
+			continue
+		}
+		_, tags := tsdb.ParseSeriesKey(key)
+		f := tags.Get(models.FieldKeyTagKeyBytes)
+		if f == nil {
+			continue
+		} else if _, ok := seen[string(f)]; ok {
+			continue
+		}
+
+		if id := i.sfile.SeriesIDTypedBySeriesKey(key); id.HasType() {
+			seen[string(f)] = struct{}{}
+			a = append(a, MeasurementField{Measurement: measurement, Field: append([]byte(nil), f...), Type: id.Type()})
+		}
+	}
+}
+
+// tagValues returns the values of the tag key in the measurement.
+func (i *Index) tagValues(name, key []byte) ([][]byte, error) {
+	itr, err := i.TagValueIterator(name, key)
+	if err != nil {
+		return nil, err
+	} else if itr == nil {
+		return nil, nil
+	}
+	defer itr.Close()
+
+	var a [][]byte
+	for {
+		v, err := itr.Next()
+		if err != nil {
+			return nil, err
+		} else if v == nil {
+			return a, nil
+		}
+		a = append(a, append([]byte(nil), v...))
+	}
+}
+
+// fieldTypeName returns the name of the field type as it is written in errors.
+func fieldTypeName(typ models.FieldType) string {
+	return strings.ToLower(typ.String())
+}
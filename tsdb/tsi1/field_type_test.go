@@ -0,0 +1,45 @@
+package tsi1
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestFieldTypeCache_SetAfterDelete(t *testing.T) {
+	c := newFieldTypeCache()
+	k := fieldTypeKey{name: "bucket", measurement: "cpu", field: "value"}
+
+	_, ok, gen := c.get(k)
+	if ok {
+		t.Fatal("expected field type not to be cached")
+	}
+
+	// The series of the field are dropped while its type is looked up.
+	c.deleteSeries(models.MakeKey([]byte("bucket"), models.NewTags(map[string]string{
+		models.MeasurementTagKey: "cpu",
+		models.FieldKeyTagKey:    "value",
+	})))
+	c.set(k, models.Float, gen)
+	if _, ok, _ := c.get(k); ok {
+		t.Fatal("expected type looked up before the series were dropped not to be cached")
+	}
+
+	_, _, gen = c.get(k)
+	c.set(k, models.Integer, gen)
+	if typ, ok, _ := c.get(k); !ok || typ != models.Integer {
+		t.Fatalf("unexpected cached type: %v, %v", typ, ok)
+	}
+
+	// Other buckets are not affected by dropping a measurement.
+	other := fieldTypeKey{name: "other", measurement: "cpu", field: "value"}
+	_, _, gen = c.get(other)
+	c.set(other, models.Boolean, gen)
+	c.deleteMeasurement([]byte("bucket"))
+	if _, ok, _ := c.get(k); ok {
+		t.Fatal("expected types of dropped measurement to be removed")
+	}
+	if _, ok, _ := c.get(other); !ok {
+		t.Fatal("expected types of other buckets to be kept")
+	}
+}
@@ -108,6 +108,7 @@ type Index struct {
 	defaultLabels prometheus.Labels
 
 	tagValueCache    *TagValueSeriesIDCache
+	fieldTypes       *fieldTypeCache
 	partitionMetrics *partitionMetrics // Maintain a single set of partition metrics to be shared by partition.
 	metricsEnabled   bool
 
@@ -138,6 +139,7 @@ func (i *Index) UniqueReferenceID() uintptr {
 func NewIndex(sfile *tsdb.SeriesFile, c Config, options ...IndexOption) *Index {
 	idx := &Index{
 		tagValueCache:    NewTagValueSeriesIDCache(c.SeriesIDSetCacheSize),
+		fieldTypes:       newFieldTypeCache(),
 		partitionMetrics: newPartitionMetrics(nil),
 		metricsEnabled:   true,
 		maxLogFileSize:   int64(c.MaxIndexLogFileSize),
@@ -608,8 +610,9 @@ func (i *Index) DropMeasurement(name []byte) error {
 		}()
 	}
 
-	// Remove any cached bitmaps for the measurement.
+	// Remove any cached bitmaps and field types for the measurement.
 	i.tagValueCache.DeleteMeasurement(name)
+	i.fieldTypes.deleteMeasurement(name)
 
 	// Check for error
 	for i := 0; i < cap(errC); i++ {
@@ -622,9 +625,24 @@ func (i *Index) DropMeasurement(name []byte) error {
 
 // CreateSeriesListIfNotExists creates a list of series if they doesn't exist in bulk.
 func (i *Index) CreateSeriesListIfNotExists(collection *tsdb.SeriesCollection) error {
+	// Drop any series whose field type conflicts with the type already stored for
+	// the field in its measurement. Writes creating the same fields wait for each
+	// other until the series are created.
+	fieldTypes, err := i.checkFieldTypes(collection)
+	if err != nil {
+		return err
+	}
+	defer fieldTypes.unlock()
+
+	// Drop any new series that would exceed the configured limits before they are
+	// assigned an id in the series file.
+	if err := i.checkSeriesLimits(collection); err != nil {
+		return err
+	}
+
 	// Create the series list on the series file first. This validates all of the types for
 	// the collection.
-	err := i.sfile.CreateSeriesListIfNotExists(collection)
+	err = i.sfile.CreateSeriesListIfNotExists(collection)
 	if err != nil {
 		return err
 	}
@@ -713,9 +731,139 @@ func (i *Index) CreateSeriesListIfNotExists(collection *tsdb.SeriesCollection) e
 		}
 	}
 
+	fieldTypes.commit(collection)
 	return nil
 }
 
+// checkSeriesLimits marks series in the collection as invalid if creating them would
+// exceed the MaxSeriesPerBucket or MaxValuesPerTag limits. Series that already exist
+// are always accepted. The limits are checked without blocking concurrent writers, so
+// they may be exceeded slightly when several batches create series at the same time.
+func (i *Index) checkSeriesLimits(collection *tsdb.SeriesCollection) error {
+	maxSeries, maxValues := i.config.MaxSeriesPerBucket, i.config.MaxValuesPerTag
+	if maxSeries <= 0 && maxValues <= 0 {
+		return nil
+	}
+
+	var stats MeasurementCardinalityStats
+	if maxSeries > 0 {
+		stats = i.MeasurementCardinalityStats()
+	}
+
+	var (
+		buf       = make([]byte, 1024)
+		newKeys   = make(map[string]struct{})
+		newSeries = make(map[string]int)
+
+		// Number of values for each bucket and tag key, and the values added by
+		// this collection, keyed by bucket name followed by the tag key.
+		valueN    = make(map[string]int)
+		newValues = make(map[string]map[string]struct{})
+	)
+
+	for iter := collection.Iterator(); iter.Next(); {
+		name, tags := iter.Name(), iter.Tags()
+		if !i.sfile.SeriesID(name, tags, buf).IsZero() {
+			continue
+		}
+
+		key := string(models.MakeKey(name, tags))
+		if _, ok := newKeys[key]; ok {
+			continue
+		}
+
+		if maxSeries > 0 {
+			if n := stats[string(name)] + newSeries[string(name)]; n >= maxSeries {
+				iter.Invalid(fmt.Sprintf("max-series-per-bucket limit exceeded: (%d/%d)", n, maxSeries))
+				continue
+			}
+		}
+
+		if maxValues > 0 {
+			reason, err := i.checkTagValueLimit(name, tags, maxValues, valueN, newValues)
+			if err != nil {
+				return err
+			} else if reason != "" {
+				iter.Invalid(reason)
+				continue
+			}
+		}
+
+		newKeys[key] = struct{}{}
+		newSeries[string(name)]++
+	}
+
+	collection.ApplyConcurrentDrops()
+	return nil
+}
+
+// checkTagValueLimit returns a non-empty reason if any tag of a new series would add a
+// value beyond maxValues. The valueN and newValues maps are shared between calls for the
+// same collection and are updated once the series is accepted.
+func (i *Index) checkTagValueLimit(name []byte, tags models.Tags, maxValues int, valueN map[string]int, newValues map[string]map[string]struct{}) (string, error) {
+	var added []string
+	for _, t := range tags {
+		// The measurement and field are stored as tags, but are not limited.
+		if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+			continue
+		}
+
+		k := string(name) + string(t.Key)
+		if _, ok := newValues[k][string(t.Value)]; ok {
+			continue
+		} else if ok, err := i.HasTagValue(name, t.Key, t.Value); err != nil {
+			return "", err
+		} else if ok {
+			continue
+		}
+
+		n, ok := valueN[k]
+		if !ok {
+			var err error
+			if n, err = i.tagValueN(name, t.Key); err != nil {
+				return "", err
+			}
+			valueN[k] = n
+		}
+
+		if n+len(newValues[k]) >= maxValues {
+			return fmt.Sprintf("max-values-per-tag limit exceeded (%d/%d): tag=%q value=%q",
+				n+len(newValues[k]), maxValues, t.Key, t.Value), nil
+		}
+		added = append(added, k, string(t.Value))
+	}
+
+	for j := 0; j < len(added); j += 2 {
+		if newValues[added[j]] == nil {
+			newValues[added[j]] = make(map[string]struct{})
+		}
+		newValues[added[j]][added[j+1]] = struct{}{}
+	}
+	return "", nil
+}
+
+// tagValueN returns the number of values for the tag key in the measurement.
+func (i *Index) tagValueN(name, key []byte) (int, error) {
+	itr, err := i.TagValueIterator(name, key)
+	if err != nil {
+		return 0, err
+	} else if itr == nil {
+		return 0, nil
+	}
+	defer itr.Close()
+
+	var n int
+	for {
+		v, err := itr.Next()
+		if err != nil {
+			return 0, err
+		} else if v == nil {
+			return n, nil
+		}
+		n++
+	}
+}
+
 // InitializeSeries is a no-op. This only applies to the in-memory index.
 func (i *Index) InitializeSeries(*tsdb.SeriesCollection) error {
 	return nil
@@ -729,6 +877,9 @@ func (i *Index) DropSeries(seriesID tsdb.SeriesID, key []byte, cascade bool) err
 		return err
 	}
 
+	// The field may no longer exist, so its type must be looked up again.
+	i.fieldTypes.deleteSeries(key)
+
 	if !cascade {
 		return nil
 	}
@@ -1185,7 +1336,8 @@ func (i *Index) FileSet() (*FileSet, error) {
 	return NewFileSet(i.sfile, files)
 }
 
-// SetFieldName is a no-op on this index.
+// SetFieldName is a no-op on this index. Field types are tracked as series are
+// created; see CreateSeriesListIfNotExists.
 func (i *Index) SetFieldName(measurement []byte, name string) {}
 
 // Rebuild rebuilds an index. It's a no-op for this index.
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -408,6 +409,194 @@ func TestIndex_MeasurementCardinalityStats(t *testing.T) {
 	})
 }
 
+func TestIndex_SeriesLimits(t *testing.T) {
+	t.Run("MaxSeriesPerBucket", func(t *testing.T) {
+		c := tsi1.NewConfig()
+		c.MaxSeriesPerBucket = 2
+		idx := MustOpenIndex(1, c)
+		defer idx.Close()
+
+		if err := idx.CreateSeriesSliceIfNotExists([]Series{
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"region": "east"})},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		collection := NewSeriesCollection([]Series{
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"region": "east"})},
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"region": "west"})},
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"region": "north"})},
+			{Name: []byte("mem"), Tags: models.NewTags(map[string]string{"region": "east"})},
+		})
+		if err := idx.CreateSeriesListIfNotExists(collection); err != nil {
+			t.Fatal(err)
+		} else if collection.Dropped != 1 {
+			t.Fatalf("unexpected dropped: %d", collection.Dropped)
+		} else if exp := "max-series-per-bucket limit exceeded: (2/2)"; collection.Reason != exp {
+			t.Fatalf("unexpected reason: got %q, exp %q", collection.Reason, exp)
+		}
+
+		if diff := cmp.Diff(idx.MeasurementCardinalityStats(), tsi1.MeasurementCardinalityStats{"cpu": 2, "mem": 1}); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("MaxValuesPerTag", func(t *testing.T) {
+		c := tsi1.NewConfig()
+		c.MaxValuesPerTag = 2
+		idx := MustOpenIndex(1, c)
+		defer idx.Close()
+
+		collection := NewSeriesCollection([]Series{
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"host": "a", "region": "east"})},
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"host": "b", "region": "east"})},
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"host": "c", "region": "east"})},
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"host": "a", "region": "west"})},
+		})
+		if err := idx.CreateSeriesListIfNotExists(collection); err != nil {
+			t.Fatal(err)
+		} else if collection.Dropped != 1 {
+			t.Fatalf("unexpected dropped: %d", collection.Dropped)
+		} else if exp := `max-values-per-tag limit exceeded (2/2): tag="host" value="c"`; collection.Reason != exp {
+			t.Fatalf("unexpected reason: got %q, exp %q", collection.Reason, exp)
+		}
+
+		// Existing tag values are accepted on new series.
+		collection = NewSeriesCollection([]Series{
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"host": "b", "region": "west"})},
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"host": "d", "region": "west"})},
+		})
+		if err := idx.CreateSeriesListIfNotExists(collection); err != nil {
+			t.Fatal(err)
+		} else if collection.Dropped != 1 {
+			t.Fatalf("unexpected dropped: %d", collection.Dropped)
+		}
+
+		if diff := cmp.Diff(idx.MeasurementCardinalityStats(), tsi1.MeasurementCardinalityStats{"cpu": 4}); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestIndex_FieldTypes(t *testing.T) {
+	idx := MustOpenIndex(1, tsi1.NewConfig())
+	defer idx.Close()
+
+	series := func(m, f string, typ models.FieldType, tags ...string) Series {
+		kv := map[string]string{"\x00": m, "\xff": f}
+		for j := 0; j < len(tags); j += 2 {
+			kv[tags[j]] = tags[j+1]
+		}
+		return Series{Name: []byte("bucket"), Tags: models.NewTags(kv), Type: typ}
+	}
+
+	if err := idx.CreateSeriesSliceIfNotExists([]Series{
+		series("cpu", "usage", models.Float, "host", "a"),
+		series("cpu", "count", models.Integer, "host", "a"),
+		series("mem", "usage", models.Integer, "host", "a"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same field may have different types in different measurements, but not
+	// in different series of the same measurement.
+	collection := NewSeriesCollection([]Series{
+		series("cpu", "usage", models.Float, "host", "b"),
+		series("cpu", "usage", models.String, "host", "c"),
+		series("cpu", "idle", models.Boolean, "host", "b"),
+		series("cpu", "idle", models.Float, "host", "c"),
+	})
+	if err := idx.CreateSeriesListIfNotExists(collection); err != nil {
+		t.Fatal(err)
+	} else if collection.Dropped != 2 {
+		t.Fatalf("unexpected dropped: %d", collection.Dropped)
+	} else if exp := `field type conflict: input field "usage" on measurement "cpu" is type string, already exists as type float`; collection.Reason != exp {
+		t.Fatalf("unexpected reason: got %q, exp %q", collection.Reason, exp)
+	}
+
+	exp := []tsi1.MeasurementField{
+		{Measurement: []byte("cpu"), Field: []byte("count"), Type: models.Integer},
+		{Measurement: []byte("cpu"), Field: []byte("idle"), Type: models.Boolean},
+		{Measurement: []byte("cpu"), Field: []byte("usage"), Type: models.Float},
+		{Measurement: []byte("mem"), Field: []byte("usage"), Type: models.Integer},
+	}
+	if fields, err := idx.MeasurementFields([]byte("bucket")); err != nil {
+		t.Fatal(err)
+	} else if diff := cmp.Diff(fields, exp); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// Once every series of a field is dropped, it may be written with a new type.
+	for _, host := range []string{"a", "b"} {
+		s := series("cpu", "usage", models.Float, "host", host)
+		sid := idx.SeriesFile.SeriesID(s.Name, s.Tags, nil)
+		if err := idx.DropSeries(sid, models.MakeKey(s.Name, s.Tags), true); err != nil {
+			t.Fatal(err)
+		} else if err := idx.SeriesFile.DeleteSeriesID(sid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	collection = NewSeriesCollection([]Series{series("cpu", "usage", models.String, "host", "a")})
+	if err := idx.CreateSeriesListIfNotExists(collection); err != nil {
+		t.Fatal(err)
+	} else if collection.Dropped != 0 {
+		t.Fatalf("unexpected dropped: %d (%s)", collection.Dropped, collection.Reason)
+	}
+}
+
+func TestIndex_FieldTypes_Concurrent(t *testing.T) {
+	idx := MustOpenIndex(4, tsi1.NewConfig())
+	defer idx.Close()
+
+	// Writes creating the same field with different types at the same time
+	// must not both succeed.
+	for n := 0; n < 20; n++ {
+		field := fmt.Sprintf("f%d", n)
+
+		var wg sync.WaitGroup
+		accepted := make([]models.FieldType, 8)
+		errs := make([]error, len(accepted))
+		for j := range accepted {
+			typ := models.Float
+			if j%2 == 1 {
+				typ = models.Integer
+			}
+			wg.Add(1)
+			go func(j int, typ models.FieldType) {
+				defer wg.Done()
+				collection := NewSeriesCollection([]Series{{
+					Name: []byte("bucket"),
+					Tags: models.NewTags(map[string]string{"\x00": "cpu", "\xff": field, "host": fmt.Sprint(j)}),
+					Type: typ,
+				}})
+				if errs[j] = idx.CreateSeriesListIfNotExists(collection); errs[j] == nil && collection.Dropped == 0 {
+					accepted[j] = typ
+				} else {
+					accepted[j] = models.Empty
+				}
+			}(j, typ)
+		}
+		wg.Wait()
+
+		typ := models.Empty
+		for j, got := range accepted {
+			if errs[j] != nil {
+				t.Fatal(errs[j])
+			} else if got == models.Empty {
+				continue
+			} else if typ == models.Empty {
+				typ = got
+			} else if got != typ {
+				t.Fatalf("field %s was created with types %s and %s", field, typ, got)
+			}
+		}
+		if typ == models.Empty {
+			t.Fatalf("no write of field %s succeeded", field)
+		}
+	}
+}
+
 // Index is a test wrapper for tsi1.Index.
 type Index struct {
 	*tsi1.Index
@@ -418,7 +607,7 @@ type Index struct {
 // NewIndex returns a new instance of Index at a temporary path.
 func NewIndex(partitionN uint64, c tsi1.Config) *Index {
 	idx := &Index{
-		Config:     tsi1.NewConfig(),
+		Config:     c,
 		SeriesFile: NewSeriesFile(),
 	}
 	idx.Index = tsi1.NewIndex(idx.SeriesFile.SeriesFile, idx.Config, tsi1.WithPath(MustTempDir()))
@@ -505,6 +694,11 @@ func (idx *Index) Run(t *testing.T, fn func(t *testing.T)) {
 
 // CreateSeriesSliceIfNotExists creates multiple series at a time.
 func (idx *Index) CreateSeriesSliceIfNotExists(a []Series) error {
+	return idx.CreateSeriesListIfNotExists(NewSeriesCollection(a))
+}
+
+// NewSeriesCollection returns a collection of the series in a.
+func NewSeriesCollection(a []Series) *tsdb.SeriesCollection {
 	collection := &tsdb.SeriesCollection{
 		Keys:  make([][]byte, 0, len(a)),
 		Names: make([][]byte, 0, len(a)),
@@ -518,5 +712,5 @@ func (idx *Index) CreateSeriesSliceIfNotExists(a []Series) error {
 		collection.Tags = append(collection.Tags, s.Tags)
 		collection.Types = append(collection.Types, s.Type)
 	}
-	return idx.CreateSeriesListIfNotExists(collection)
+	return collection
 }
@@ -593,6 +593,27 @@ func (c *Cache) DeleteBucketRange(name []byte, min, max int64) {
 	c.tracker.SetMemBytes(uint64(c.Size()))
 }
 
+// Delete removes all values for the given keys from the cache.
+func (c *Cache) Delete(keys [][]byte) {
+	c.init()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total uint64
+	for _, k := range keys {
+		e := c.store.entry(k)
+		if e == nil {
+			continue
+		}
+		total += uint64(e.size()) + uint64(len(k))
+		c.store.remove(k)
+	}
+
+	c.tracker.DecCacheSize(total)
+	c.tracker.SetMemBytes(uint64(c.Size()))
+}
+
 // SetMaxSize updates the memory limit of the cache.
 func (c *Cache) SetMaxSize(size uint64) {
 	c.mu.Lock()
@@ -652,6 +673,10 @@ func (cl *CacheLoader) Load(cache *Cache) error {
 
 			cache.DeleteBucketRange(name, en.Min, en.Max)
 			return nil
+
+		case *wal.DeleteSeriesWALEntry:
+			cache.Delete(seriesFieldKeys(en.Keys))
+			return nil
 		}
 
 		return nil
@@ -0,0 +1,109 @@
+package tsm1
+
+import (
+	"math"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+// SeriesKeysByExpr returns the sorted keys of all series in the bucket that match
+// expr. The name must be the escaped bucket name, and expr must refer to the
+// measurement and field using the tag keys they are stored under in the index.
+func (e *Engine) SeriesKeysByExpr(name []byte, expr influxql.Expr) ([][]byte, error) {
+	// The TSI index and Series File do not store series data in escaped form.
+	name = models.UnescapeMeasurement(name)
+
+	itr, err := e.index.MeasurementSeriesByExprIterator(name, expr)
+	if err != nil {
+		return nil, err
+	} else if itr == nil {
+		return nil, nil
+	}
+	defer itr.Close()
+
+	var keys [][]byte
+	for {
+		elem, err := itr.Next()
+		if err != nil {
+			return nil, err
+		} else if elem.SeriesID.IsZero() {
+			break
+		}
+
+		skey := e.sfile.SeriesKey(elem.SeriesID)
+		if len(skey) == 0 {
+			continue
+		}
+		sname, tags := tsdb.ParseSeriesKey(skey)
+		keys = append(keys, models.MakeKey(sname, tags))
+	}
+
+	bytesutil.Sort(keys)
+	return keys, nil
+}
+
+// DeleteSeries removes all TSM data belonging to the series keys, and removes the
+// series from the index and series file. The keys are series keys in the form
+// returned by SeriesKeysByExpr.
+func (e *Engine) DeleteSeries(keys [][]byte) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// TODO(jeff): like DeleteBucketRange, writes to the series being deleted are not
+	// blocked and may recreate the series while the delete is in progress.
+
+	// Ensure that the index does not compact away the series we're going to delete
+	// before we're done with them.
+	e.index.DisableCompactions()
+	defer e.index.EnableCompactions()
+	e.index.Wait()
+
+	// Disable and abort running level compactions so that the tombstones added to
+	// existing tsm files are not removed and the deleted series do not re-appear.
+	e.disableLevelCompactions(true)
+	defer e.enableLevelCompactions(true)
+
+	e.sfile.DisableCompactions()
+	defer e.sfile.EnableCompactions()
+
+	fieldKeys := seriesFieldKeys(keys)
+	if err := e.FileStore.DeleteRange(fieldKeys, math.MinInt64, math.MaxInt64); err != nil {
+		return err
+	}
+	e.Cache.Delete(fieldKeys)
+
+	buf := make([]byte, 1024)
+	for _, key := range keys {
+		name, tags := models.ParseKeyBytes(key)
+		sid := e.sfile.SeriesID(name, tags, buf)
+		if sid.IsZero() {
+			continue
+		}
+
+		// Remove the series from the index before the series file.
+		if err := e.index.DropSeries(sid, key, true); err != nil {
+			return err
+		}
+		if err := e.sfile.DeleteSeriesID(sid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seriesFieldKeys returns the sorted TSM keys for the series keys. Each series is
+// stored under a single TSM key made of the series key and its field.
+func seriesFieldKeys(keys [][]byte) [][]byte {
+	fieldKeys := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		_, tags := models.ParseKeyBytes(key)
+		field := tags.Get(models.FieldKeyTagKeyBytes)
+		fieldKeys = append(fieldKeys, SeriesFieldKeyBytes(string(key), string(field)))
+	}
+	bytesutil.Sort(fieldKeys)
+	return fieldKeys
+}
@@ -0,0 +1,317 @@
+package tsm1
+
+import (
+	"bytes"
+	"math"
+	"sort"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxql"
+)
+
+// The schema functions below read the measurements, fields and tags of a bucket.
+// The name must be the escaped bucket name, and expr must refer to the measurement
+// and field using the tag keys they are stored under in the index, as with
+// SeriesKeysByExpr. Only series with data between min and max are included, and at
+// most limit results are returned if limit is greater than zero.
+//
+// When there is no predicate and the time range is unbounded the schema is read
+// directly from the index. Otherwise, the matching series are read from the index
+// and filtered by the keys in the cache and TSM files that have data in the range.
+
+// MeasurementNames returns the sorted names of the measurements in the bucket.
+func (e *Engine) MeasurementNames(name []byte, min, max int64, expr influxql.Expr, limit int) ([][]byte, error) {
+	return e.TagValues(name, models.MeasurementTagKeyBytes, min, max, expr, limit)
+}
+
+// TagKeys returns the sorted tag keys in the bucket. The keys the measurement and
+// field are stored under are not included.
+func (e *Engine) TagKeys(name []byte, min, max int64, expr influxql.Expr, limit int) ([][]byte, error) {
+	var keys [][]byte
+	if ids, err := e.schemaSeriesIDs(name, min, max, expr); err != nil {
+		return nil, err
+	} else if ids == nil {
+		itr, err := e.index.TagKeyIterator(models.UnescapeMeasurement(name))
+		if err != nil {
+			return nil, err
+		} else if itr == nil {
+			return nil, nil
+		}
+		defer itr.Close()
+
+		for {
+			key, err := itr.Next()
+			if err != nil {
+				return nil, err
+			} else if key == nil {
+				break
+			}
+			keys = append(keys, append([]byte(nil), key...))
+		}
+	} else {
+		set := make(map[string]struct{})
+		ids.ForEach(func(id tsdb.SeriesID) {
+			_, tags := tsdb.ParseSeriesKey(e.sfile.SeriesKey(id))
+			for _, t := range tags {
+				set[string(t.Key)] = struct{}{}
+			}
+		})
+		keys = sortedBytes(set)
+	}
+
+	a := keys[:0]
+	for _, key := range keys {
+		if bytes.Equal(key, models.MeasurementTagKeyBytes) || bytes.Equal(key, models.FieldKeyTagKeyBytes) {
+			continue
+		}
+		a = append(a, key)
+	}
+	return limitBytes(a, limit), nil
+}
+
+// TagValues returns the sorted values of the tag key in the bucket.
+func (e *Engine) TagValues(name, key []byte, min, max int64, expr influxql.Expr, limit int) ([][]byte, error) {
+	ids, err := e.schemaSeriesIDs(name, min, max, expr)
+	if err != nil {
+		return nil, err
+	} else if ids != nil {
+		set := make(map[string]struct{})
+		ids.ForEach(func(id tsdb.SeriesID) {
+			_, tags := tsdb.ParseSeriesKey(e.sfile.SeriesKey(id))
+			if v := tags.Get(key); v != nil {
+				set[string(v)] = struct{}{}
+			}
+		})
+		return limitBytes(sortedBytes(set), limit), nil
+	}
+
+	itr, err := e.index.TagValueIterator(models.UnescapeMeasurement(name), key)
+	if err != nil {
+		return nil, err
+	} else if itr == nil {
+		return nil, nil
+	}
+	defer itr.Close()
+
+	var values [][]byte
+	for limit <= 0 || len(values) < limit {
+		v, err := itr.Next()
+		if err != nil {
+			return nil, err
+		} else if v == nil {
+			break
+		}
+		values = append(values, append([]byte(nil), v...))
+	}
+	return values, nil
+}
+
+// MeasurementFields returns the fields of each measurement in the bucket, sorted by
+// measurement and then by field.
+func (e *Engine) MeasurementFields(name []byte, min, max int64, expr influxql.Expr, limit int) ([]tsi1.MeasurementField, error) {
+	ids, err := e.schemaSeriesIDs(name, min, max, expr)
+	if err != nil {
+		return nil, err
+	} else if ids == nil {
+		fields, err := e.index.MeasurementFields(models.UnescapeMeasurement(name))
+		if err != nil {
+			return nil, err
+		}
+		if limit > 0 && len(fields) > limit {
+			fields = fields[:limit]
+		}
+		return fields, nil
+	}
+
+	type measurementField struct{ m, f string }
+	set := make(map[measurementField]models.FieldType)
+	ids.ForEach(func(id tsdb.SeriesID) {
+		skey := e.sfile.SeriesKey(id)
+		_, tags := tsdb.ParseSeriesKey(skey)
+		m, f := tags.Get(models.MeasurementTagKeyBytes), tags.Get(models.FieldKeyTagKeyBytes)
+		if m == nil || f == nil {
+			return
+		}
+		if tid := e.sfile.SeriesIDTypedBySeriesKey(skey); tid.HasType() {
+			set[measurementField{m: string(m), f: string(f)}] = tid.Type()
+		}
+	})
+
+	fields := make([]tsi1.MeasurementField, 0, len(set))
+	for k, typ := range set {
+		fields = append(fields, tsi1.MeasurementField{Measurement: []byte(k.m), Field: []byte(k.f), Type: typ})
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		if c := bytes.Compare(fields[i].Measurement, fields[j].Measurement); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(fields[i].Field, fields[j].Field) < 0
+	})
+	if limit > 0 && len(fields) > limit {
+		fields = fields[:limit]
+	}
+	return fields, nil
+}
+
+// schemaSeriesIDs returns the ids of the series in the bucket that match expr and
+// have data between min and max. It returns nil if neither filter applies, in which
+// case every series in the bucket matches.
+func (e *Engine) schemaSeriesIDs(name []byte, min, max int64, expr influxql.Expr) (*tsdb.SeriesIDSet, error) {
+	unbounded := min == math.MinInt64 && max == math.MaxInt64
+	if expr == nil && unbounded {
+		return nil, nil
+	}
+
+	itr, err := e.index.MeasurementSeriesByExprIterator(models.UnescapeMeasurement(name), expr)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := tsdb.NewSeriesIDSet()
+	if itr == nil {
+		return ids, nil
+	}
+	defer itr.Close()
+
+	var inRange *tsdb.SeriesIDSet
+	if !unbounded {
+		if inRange, err = e.seriesIDsInTimeRange(name, min, max); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		elem, err := itr.Next()
+		if err != nil {
+			return nil, err
+		} else if elem.SeriesID.IsZero() {
+			return ids, nil
+		}
+		if inRange == nil || inRange.Contains(elem.SeriesID) {
+			ids.AddNoLock(elem.SeriesID)
+		}
+	}
+}
+
+// seriesIDsInTimeRange returns the ids of the series in the bucket with data in the
+// cache or TSM files between min and max. Data in TSM files is checked at the block
+// level, so a series may be included if one of its blocks spans the time range
+// without having any values within it.
+func (e *Engine) seriesIDsInTimeRange(name []byte, min, max int64) (*tsdb.SeriesIDSet, error) {
+	keys := make(map[string]struct{})
+	addKey := func(key []byte) {
+		if len(key) <= len(name) || key[len(name)] != ',' {
+			return // the key belongs to another bucket sharing the prefix
+		}
+		seriesKey, _ := SeriesAndFieldFromCompositeKey(key)
+		if _, ok := keys[string(seriesKey)]; !ok {
+			keys[string(seriesKey)] = struct{}{}
+		}
+	}
+
+	var (
+		err        error
+		tombstones []TimeRange
+	)
+	e.FileStore.ForEachFile(func(f TSMFile) bool {
+		if !f.OverlapsTimeRange(min, max) {
+			return true
+		}
+
+		itr := f.Iterator(name)
+		for itr.Next() {
+			key := itr.Key()
+			if !bytes.HasPrefix(key, name) {
+				break
+			}
+
+			tombstones = f.TombstoneRange(key, tombstones[:0])
+			if entriesInTimeRange(itr.Entries(), tombstones, min, max) {
+				addKey(key)
+			}
+		}
+		err = itr.Err()
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_ = e.Cache.ApplyEntryFn(func(key []byte, entry *entry) error {
+		if !bytes.HasPrefix(key, name) {
+			return nil
+		}
+
+		entry.mu.RLock()
+		defer entry.mu.RUnlock()
+		for _, v := range entry.values {
+			if ts := v.UnixNano(); ts >= min && ts <= max {
+				addKey(key)
+				break
+			}
+		}
+		return nil
+	})
+
+	ids := tsdb.NewSeriesIDSet()
+	buf := make([]byte, 1024)
+	for key := range keys {
+		sname, tags := models.ParseKeyBytes([]byte(key))
+		if id := e.sfile.SeriesID(sname, tags, buf); !id.IsZero() {
+			ids.AddNoLock(id)
+		}
+	}
+	return ids, nil
+}
+
+// entriesInTimeRange returns true if any of the index entries hold data between min
+// and max that is not covered by the tombstones.
+func entriesInTimeRange(entries []IndexEntry, tombstones []TimeRange, min, max int64) bool {
+	var clipped []IndexEntry
+	for _, entry := range entries {
+		if !entry.OverlapsTimeRange(min, max) {
+			continue
+		}
+		if entry.MinTime < min {
+			entry.MinTime = min
+		}
+		if entry.MaxTime > max {
+			entry.MaxTime = max
+		}
+		clipped = append(clipped, entry)
+	}
+
+	if len(clipped) == 0 {
+		return false
+	} else if len(tombstones) == 0 {
+		return true
+	}
+
+	sort.Slice(tombstones, func(i, j int) bool { return tombstones[i].Less(tombstones[j]) })
+	merger := timeRangeMerger{
+		sorted: tombstones[1:],
+		single: tombstones[0],
+	}
+	return !timeRangesCoverEntries(merger, clipped)
+}
+
+// sortedBytes returns the sorted members of set.
+func sortedBytes(set map[string]struct{}) [][]byte {
+	a := make([][]byte, 0, len(set))
+	for k := range set {
+		a = append(a, []byte(k))
+	}
+	bytesutil.Sort(a)
+	return a
+}
+
+// limitBytes returns at most the first limit elements of a if limit is greater than zero.
+func limitBytes(a [][]byte, limit int) [][]byte {
+	if limit > 0 && len(a) > limit {
+		return a[:limit]
+	}
+	return a
+}
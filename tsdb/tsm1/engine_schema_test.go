@@ -0,0 +1,133 @@
+package tsm1_test
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxql"
+)
+
+func TestEngine_Schema(t *testing.T) {
+	e, err := NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	org, bucket := influxdb.ID(1), influxdb.ID(2)
+	encoded := tsdb.EncodeName(org, bucket)
+	name := models.EscapeMeasurement(encoded[:])
+
+	write := func(s string) {
+		t.Helper()
+		points, err := tsdb.ExplodePoints(org, bucket, MustParsePointsString(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.writePoints(points...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Write some data to TSM files, remove part of it with a tombstone, and leave
+	// the rest in the cache.
+	write(`cpu,host=a,region=east usage=1 10
+cpu,host=b,region=west usage=2 20
+mem,host=a used=3i 30`)
+	if err := e.WriteSnapshot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	write(`disk,host=c free=4i 40`)
+
+	tags := models.NewTags(map[string]string{"\x00": "cpu", "\xff": "usage", "host": "a", "region": "east"})
+	key := tsm1.SeriesFieldKeyBytes(string(models.MakeKey(encoded[:], tags)), "usage")
+	if err := e.FileStore.DeleteRange([][]byte{key}, 0, 15); err != nil {
+		t.Fatal(err)
+	}
+
+	strs := func(a [][]byte) []string {
+		var s []string
+		for _, b := range a {
+			s = append(s, string(b))
+		}
+		return s
+	}
+
+	const min, max = math.MinInt64, math.MaxInt64
+	for _, tt := range []struct {
+		name     string
+		fn       func() ([][]byte, error)
+		expected []string
+	}{
+		{
+			name:     "measurements",
+			fn:       func() ([][]byte, error) { return e.MeasurementNames(name, min, max, nil, 0) },
+			expected: []string{"cpu", "disk", "mem"},
+		},
+		{
+			name:     "measurements in time range",
+			fn:       func() ([][]byte, error) { return e.MeasurementNames(name, 25, 100, nil, 0) },
+			expected: []string{"disk", "mem"},
+		},
+		{
+			name:     "tag keys",
+			fn:       func() ([][]byte, error) { return e.TagKeys(name, min, max, nil, 0) },
+			expected: []string{"host", "region"},
+		},
+		{
+			name: "tag keys with predicate",
+			fn: func() ([][]byte, error) {
+				expr := &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: models.MeasurementTagKey, Type: influxql.Tag},
+					RHS: &influxql.StringLiteral{Val: "mem"},
+				}
+				return e.TagKeys(name, min, max, expr, 0)
+			},
+			expected: []string{"host"},
+		},
+		{
+			name:     "tag values with limit",
+			fn:       func() ([][]byte, error) { return e.TagValues(name, []byte("host"), min, max, nil, 2) },
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "tag values excluding tombstoned data",
+			fn:       func() ([][]byte, error) { return e.TagValues(name, []byte("region"), 0, 100, nil, 0) },
+			expected: []string{"west"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn()
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(strs(got), tt.expected) {
+				t.Fatalf("got %v, expected %v", strs(got), tt.expected)
+			}
+		})
+	}
+
+	t.Run("fields with predicate", func(t *testing.T) {
+		fields, err := e.MeasurementFields(name, min, max, influxql.MustParseExpr(`host = 'a'`), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, f := range fields {
+			got = append(got, string(f.Measurement)+"."+string(f.Field)+":"+f.Type.String())
+		}
+		if exp := []string{"cpu.usage:Float", "mem.used:Integer"}; !reflect.DeepEqual(got, exp) {
+			t.Fatalf("got %v, expected %v", got, exp)
+		}
+	})
+}
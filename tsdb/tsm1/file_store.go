@@ -345,6 +345,31 @@ func (f *FileStore) Files() []TSMFile {
 	return f.files
 }
 
+// ForEachFile calls fn for each TSM file until fn returns false. The files are
+// referenced for the duration of the call, so they are not removed by compactions
+// while fn is using them.
+func (f *FileStore) ForEachFile(fn func(f TSMFile) bool) {
+	f.mu.RLock()
+	files := make([]TSMFile, len(f.files))
+	copy(files, f.files)
+	for _, r := range files {
+		r.Ref()
+	}
+	f.mu.RUnlock()
+
+	defer func() {
+		for _, r := range files {
+			r.Unref()
+		}
+	}()
+
+	for _, r := range files {
+		if !fn(r) {
+			return
+		}
+	}
+}
+
 // Free releases any resources held by the FileStore.  The resources will be re-acquired
 // if necessary if they are needed after freeing them.
 func (f *FileStore) Free() error {
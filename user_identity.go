@@ -0,0 +1,40 @@
+package influxdb
+
+import (
+	"context"
+)
+
+// ErrUserNotLinked is an error message when a user signs in through an external
+// identity provider or directory with the name of a user it did not create.
+const ErrUserNotLinked = "a user with this name exists and is not linked to this identity provider"
+
+// ops for user identity errors.
+const (
+	OpFindUserIdentity   = "FindUserIdentity"
+	OpCreateUserIdentity = "CreateUserIdentity"
+)
+
+// UserIdentity links a user to their account in an external identity provider
+// or directory, such as an OpenID Connect issuer or an LDAP directory. Users
+// signing in through a provider are only ever signed in as the user linked to
+// their account.
+type UserIdentity struct {
+	// Provider identifies the identity provider, such as the issuer of
+	// OpenID Connect tokens or the URL of a directory.
+	Provider string `json:"provider"`
+	// Subject identifies the account in the provider, such as the sub claim
+	// of tokens or the DN of a directory entry.
+	Subject string `json:"subject"`
+	UserID  ID     `json:"userID"`
+}
+
+// UserIdentityService manages the links of users to their accounts in
+// external identity providers.
+type UserIdentityService interface {
+	// FindUserIdentity returns the link of an account of a provider to a user.
+	FindUserIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error)
+
+	// CreateUserIdentity links an account of a provider to a user. An account
+	// can only be linked to one user.
+	CreateUserIdentity(ctx context.Context, i *UserIdentity) error
+}
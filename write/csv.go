@@ -0,0 +1,413 @@
+package write
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Annotated CSV column roles that do not correspond to a Flux data type.
+const (
+	csvMeasurement = "measurement"
+	csvTag         = "tag"
+	csvIgnored     = "ignored"
+
+	// csvAutoTime is the datatype of a mapped time column, which holds either
+	// integer or RFC3339 timestamps.
+	csvAutoTime = "dateTime:auto"
+)
+
+// CSVMapping maps the columns of CSV without annotations to the parts of
+// points, using the names in its header row.
+type CSVMapping struct {
+	// Measurement is the measurement of every point, unless MeasurementColumn
+	// is set.
+	Measurement       string
+	MeasurementColumn string
+
+	// TimeColumn holds integer or RFC3339 timestamps. Points without one are
+	// written at the default time.
+	TimeColumn string
+
+	TagColumns []string
+
+	// FieldColumns maps the columns that are fields to their datatypes: double,
+	// long, unsignedLong, boolean or string. If empty, every other column is a
+	// field, typed as a double or boolean when its value parses as one.
+	FieldColumns map[string]string
+}
+
+// Validate returns an error if the mapping cannot convert records to points.
+func (m *CSVMapping) Validate() error {
+	if m.Measurement == "" && m.MeasurementColumn == "" {
+		return fmt.Errorf("a measurement or measurement column is required")
+	}
+	for name, typ := range m.FieldColumns {
+		switch typ {
+		case "double", "long", "unsignedLong", "boolean", "string":
+		default:
+			return fmt.Errorf("unsupported datatype %q for column %q", typ, name)
+		}
+	}
+	return nil
+}
+
+// column returns the role and datatype of the column with a name.
+func (m *CSVMapping) column(name string) (csvRole, string) {
+	switch {
+	case name == "":
+		return roleSkip, ""
+	case name == m.MeasurementColumn:
+		return roleMeasurement, "string"
+	case name == m.TimeColumn:
+		return roleTime, csvAutoTime
+	}
+	for _, tag := range m.TagColumns {
+		if name == tag {
+			return roleTag, "string"
+		}
+	}
+	if len(m.FieldColumns) == 0 {
+		return roleField, ""
+	}
+	if typ, ok := m.FieldColumns[name]; ok {
+		return roleField, typ
+	}
+	return roleSkip, ""
+}
+
+// CSVError is returned by CSVReader when a record cannot be converted to a point.
+type CSVError struct {
+	Line int   // Line of the record that failed.
+	Err  error // The underlying error.
+}
+
+// Error implements the error interface.
+func (e *CSVError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// CSVReader converts Flux annotated CSV into points.
+//
+// Each record is converted using the most recent header row and annotations:
+//
+//   - the _measurement column, or a column with the "measurement" datatype, is the measurement;
+//   - the _time column, or a column with a "dateTime" datatype, is the timestamp;
+//   - the _field and _value columns form a single field, typed by the datatype of _value;
+//   - string columns in the group key, and columns with the "tag" datatype, are tags;
+//   - any other column not starting with an underscore is a field typed by its datatype.
+//
+// The result and table columns, columns starting with an underscore that are
+// not listed above, and columns with the "ignored" datatype are skipped.
+// Without a #datatype annotation every column is treated as a string.
+type CSVReader struct {
+	r         *csv.Reader
+	precision string
+	now       time.Time
+	mapping   *CSVMapping
+
+	header   []string
+	types    []string
+	groups   []string
+	defaults []string
+	inData   bool
+	columns  []csvColumn
+	record   []string
+}
+
+// NewCSVReader returns a CSVReader that reads from r.
+func NewCSVReader(r io.Reader) *CSVReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	return &CSVReader{r: cr, precision: "ns", now: time.Now()}
+}
+
+// SetPrecision sets the precision of timestamps written as integers.
+func (r *CSVReader) SetPrecision(precision string) { r.precision = precision }
+
+// SetDefaultTime sets the timestamp used for records without one.
+func (r *CSVReader) SetDefaultTime(t time.Time) { r.now = t }
+
+// SetMapping maps columns to the parts of points by the names in the header
+// row, instead of by annotations.
+func (r *CSVReader) SetMapping(m *CSVMapping) { r.mapping = m }
+
+// Line returns the line of the most recently read record.
+func (r *CSVReader) Line() int {
+	line, _ := r.r.FieldPos(0)
+	return line
+}
+
+// Record returns the most recently read data record. It is nil if the record
+// could not be read, and is only valid until the next call to Read.
+func (r *CSVReader) Record() []string {
+	return r.record
+}
+
+// Read returns the next point. Records that cannot be converted return a
+// *CSVError, and reading may continue after one. Read returns io.EOF when
+// there are no more records.
+func (r *CSVReader) Read() (models.Point, error) {
+	r.record = nil
+	for {
+		record, err := r.r.Read()
+		if err != nil {
+			if perr, ok := err.(*csv.ParseError); ok {
+				return nil, &CSVError{Line: perr.Line, Err: perr.Err}
+			}
+			return nil, err
+		}
+
+		if len(record) > 0 && strings.HasPrefix(record[0], "#") {
+			r.readAnnotation(record)
+			continue
+		}
+
+		if r.header == nil {
+			r.readHeader(record)
+			continue
+		}
+
+		r.inData, r.record = true, record
+		pt, err := r.point(record)
+		if err != nil {
+			return nil, &CSVError{Line: r.Line(), Err: err}
+		}
+		return pt, nil
+	}
+}
+
+func (r *CSVReader) readAnnotation(record []string) {
+	// Annotations following data rows start a new table with a new header.
+	if r.inData {
+		r.header, r.types, r.groups, r.defaults = nil, nil, nil, nil
+		r.inData = false
+	}
+
+	// Annotation rows are aligned with the header; the first column of the
+	// header is the unnamed annotation column.
+	values := copyStrings(record)
+	switch record[0] {
+	case "#datatype":
+		r.types = values
+	case "#group":
+		r.groups = values
+	case "#default":
+		r.defaults = values
+	}
+}
+
+func (r *CSVReader) readHeader(record []string) {
+	r.header = copyStrings(record)
+
+	r.columns = r.columns[:0]
+	for i, name := range r.header {
+		c := csvColumn{name: name, index: i, typ: "string", group: len(r.groups) == 0}
+		if i < len(r.types) && r.types[i] != "" {
+			c.typ = r.types[i]
+		}
+		if i < len(r.groups) {
+			c.group = r.groups[i] == "true"
+		}
+		if i < len(r.defaults) {
+			c.def = r.defaults[i]
+		}
+		if r.mapping != nil {
+			c.role, c.typ = r.mapping.column(name)
+		} else {
+			c.role = c.roleOf()
+		}
+		r.columns = append(r.columns, c)
+	}
+}
+
+func (r *CSVReader) point(record []string) (models.Point, error) {
+	var (
+		name       string
+		tags       models.Tags
+		fields     = make(models.Fields)
+		ts         = r.now
+		fieldKey   string
+		fieldValue *csvColumn
+		valueStr   string
+	)
+
+	for i := range r.columns {
+		c := &r.columns[i]
+		v := c.def
+		if c.index < len(record) && record[c.index] != "" {
+			v = record[c.index]
+		}
+
+		switch c.role {
+		case roleMeasurement:
+			name = v
+		case roleTime:
+			if v == "" {
+				continue
+			}
+			t, err := c.parseTime(v, r.precision)
+			if err != nil {
+				return nil, err
+			}
+			ts = t
+		case roleFieldKey:
+			fieldKey = v
+		case roleFieldValue:
+			fieldValue, valueStr = c, v
+		case roleTag:
+			if v != "" {
+				tags = append(tags, models.NewTag([]byte(c.name), []byte(v)))
+			}
+		case roleField:
+			if v == "" {
+				continue
+			}
+			fv, err := c.parseValue(v)
+			if err != nil {
+				return nil, err
+			}
+			fields[c.name] = fv
+		}
+	}
+
+	if fieldValue != nil && fieldKey != "" && valueStr != "" {
+		fv, err := fieldValue.parseValue(valueStr)
+		if err != nil {
+			return nil, err
+		}
+		fields[fieldKey] = fv
+	}
+
+	if name == "" && r.mapping != nil {
+		name = r.mapping.Measurement
+	}
+	if name == "" {
+		return nil, fmt.Errorf("missing measurement")
+	} else if len(fields) == 0 {
+		return nil, fmt.Errorf("missing fields")
+	}
+
+	sort.Sort(tags)
+	return models.NewPoint(name, tags, fields, ts)
+}
+
+// csvColumn describes a single column of a table.
+type csvColumn struct {
+	name  string
+	index int
+	typ   string
+	group bool
+	def   string
+	role  csvRole
+}
+
+type csvRole int
+
+const (
+	roleSkip csvRole = iota
+	roleMeasurement
+	roleTime
+	roleFieldKey
+	roleFieldValue
+	roleTag
+	roleField
+)
+
+func (c *csvColumn) roleOf() csvRole {
+	switch c.name {
+	case "", "result", "table", "_start", "_stop":
+		return roleSkip
+	case "_measurement":
+		return roleMeasurement
+	case "_time":
+		return roleTime
+	case "_field":
+		return roleFieldKey
+	case "_value":
+		return roleFieldValue
+	}
+
+	switch {
+	case c.typ == csvMeasurement:
+		return roleMeasurement
+	case c.typ == csvTag:
+		return roleTag
+	case c.typ == csvIgnored:
+		return roleSkip
+	case strings.HasPrefix(c.name, "_"):
+		return roleSkip
+	case strings.HasPrefix(c.typ, "dateTime"):
+		return roleTime
+	case c.typ == "string" && c.group:
+		return roleTag
+	default:
+		return roleField
+	}
+}
+
+func (c *csvColumn) parseTime(v, precision string) (time.Time, error) {
+	integer := c.typ == "long" || c.typ == "dateTime:number"
+	if c.typ == csvAutoTime {
+		_, err := strconv.ParseInt(v, 10, 64)
+		integer = err == nil
+	}
+	if integer {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q in column %q", v, c.name)
+		}
+		return time.Unix(0, n*models.GetPrecisionMultiplier(precision)), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q in column %q", v, c.name)
+	}
+	return t, nil
+}
+
+func (c *csvColumn) parseValue(v string) (interface{}, error) {
+	var (
+		fv  interface{}
+		err error
+	)
+	switch c.typ {
+	case "double":
+		fv, err = strconv.ParseFloat(v, 64)
+	case "long":
+		fv, err = strconv.ParseInt(v, 10, 64)
+	case "unsignedLong":
+		fv, err = strconv.ParseUint(v, 10, 64)
+	case "boolean":
+		fv, err = strconv.ParseBool(v)
+	case "string":
+		fv = v
+	case "":
+		// The datatype of unannotated columns is inferred from their values.
+		if f, ferr := strconv.ParseFloat(v, 64); ferr == nil {
+			fv = f
+		} else if strings.EqualFold(v, "true") || strings.EqualFold(v, "false") {
+			fv = strings.EqualFold(v, "true")
+		} else {
+			fv = v
+		}
+	default:
+		return nil, fmt.Errorf("unsupported datatype %q for column %q", c.typ, c.name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q in column %q", c.typ, v, c.name)
+	}
+	return fv, nil
+}
+
+func copyStrings(a []string) []string {
+	return append(make([]string, 0, len(a)), a...)
+}
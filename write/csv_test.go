@@ -0,0 +1,139 @@
+package write
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		mapping *CSVMapping
+		want    []string
+		wantErr []int
+	}{
+		{
+			name: "flux output",
+			input: `#datatype,string,long,dateTime:RFC3339Nano,double,string,string,string
+#group,false,false,false,false,true,true,true
+#default,_result,,,,,,
+,result,table,_time,_value,_field,_measurement,host
+,,0,1970-01-01T00:00:00.00000001Z,1,value,cpu,a
+,,1,1970-01-01T00:00:00.00000002Z,2,value,cpu,b
+
+#datatype,string,long,dateTime:RFC3339Nano,long,string,string
+#group,false,false,false,false,true,true
+#default,_result,,,,,
+,result,table,_time,_value,_field,_measurement
+,,2,1970-01-01T00:00:00.00000001Z,3,free,mem
+`,
+			want: []string{
+				"cpu,host=a value=1 10",
+				"cpu,host=b value=2 20",
+				"mem free=3i 10",
+			},
+		},
+		{
+			name: "column datatypes",
+			input: `#datatype,measurement,tag,double,boolean,dateTime:number,ignored
+,m,host,temp,ok,time,junk
+,cpu,a,1.5,true,10,x
+,cpu,b,,false,20,y
+`,
+			want: []string{
+				"cpu,host=a ok=true,temp=1.5 10",
+				"cpu,host=b ok=false 20",
+			},
+		},
+		{
+			name: "defaults and string fields",
+			input: `#datatype,string,string,string,long
+#group,false,true,false,false
+#default,,server1,,
+,_measurement,host,msg,_time
+,log,,hello,10
+,log,server2,world,20
+`,
+			want: []string{
+				`log,host=server1 msg="hello" 10`,
+				`log,host=server2 msg="world" 20`,
+			},
+		},
+		{
+			name: "invalid records are reported",
+			input: `#datatype,string,dateTime:RFC3339,double,string,string
+,_measurement,_time,_value,_field,t
+,cpu,1970-01-01T00:00:00Z,1,f,a
+,cpu,notatime,2,f,a
+,,1970-01-01T00:00:00Z,3,f,a
+,cpu,1970-01-01T00:00:00Z,x,f,a
+`,
+			want:    []string{"cpu,t=a f=1 0"},
+			wantErr: []int{4, 5, 6},
+		},
+		{
+			name: "mapped columns",
+			input: `time,host,usage,ok,note
+10,a,1.5,true,x
+1970-01-01T00:00:00.00000002Z,b,2,FALSE,
+,c,notanumber,maybe,y
+`,
+			mapping: &CSVMapping{Measurement: "cpu", TimeColumn: "time", TagColumns: []string{"host"}},
+			want: []string{
+				`cpu,host=a note="x",ok=true,usage=1.5 10`,
+				`cpu,host=b ok=false,usage=2 20`,
+				`cpu,host=c note="y",ok="maybe",usage="notanumber" 0`,
+			},
+		},
+		{
+			name: "mapped field datatypes",
+			input: `m,host,usage,ignored
+cpu,a,1,x
+,b,2,y
+cpu,c,z,y
+`,
+			mapping: &CSVMapping{MeasurementColumn: "m", TagColumns: []string{"host"}, FieldColumns: map[string]string{"usage": "long"}},
+			want:    []string{"cpu,host=a usage=1i 0"},
+			wantErr: []int{3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewCSVReader(strings.NewReader(tt.input))
+			r.SetDefaultTime(time.Unix(0, 0))
+			if tt.mapping != nil {
+				r.SetMapping(tt.mapping)
+			}
+
+			var got []string
+			var gotErr []int
+			for {
+				pt, err := r.Read()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					cerr, ok := err.(*CSVError)
+					if !ok {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					gotErr = append(gotErr, cerr.Line)
+					continue
+				}
+				got = append(got, pt.String())
+			}
+
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("unexpected points: %s", cmp.Diff(got, tt.want))
+			}
+			if !cmp.Equal(gotErr, tt.wantErr) {
+				t.Errorf("unexpected error lines: %s", cmp.Diff(gotErr, tt.wantErr))
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package write
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// JSONPoint is a point in the JSON write format.
+//
+// Field values may be numbers, which are written as floats, strings or booleans.
+// Integer and unsigned fields are written as an object giving the type and the
+// value as a string, such as {"type": "integer", "value": "10"}. The time may be
+// an RFC3339 string or an integer in the write precision, and defaults to the
+// time of the write.
+type JSONPoint struct {
+	Measurement string                     `json:"measurement"`
+	Tags        map[string]string          `json:"tags,omitempty"`
+	Fields      map[string]json.RawMessage `json:"fields"`
+	Time        json.RawMessage            `json:"time,omitempty"`
+}
+
+// jsonTypedValue is a field value with an explicit type.
+type jsonTypedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// JSONError is returned by JSONReader when a point cannot be converted.
+type JSONError struct {
+	Index int   // Index of the point that failed, starting at 1.
+	Err   error // The underlying error.
+}
+
+// Error implements the error interface.
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("point %d: %v", e.Index, e.Err)
+}
+
+// JSONReader converts JSON points into points. The input is a single JSONPoint,
+// an array of them, or a sequence of them such as one per line.
+type JSONReader struct {
+	r         *bufio.Reader
+	d         *json.Decoder
+	precision string
+	now       time.Time
+
+	array bool // whether the points are in an array
+	done  bool
+	index int
+	raw   json.RawMessage
+}
+
+// NewJSONReader returns a JSONReader that reads from r.
+func NewJSONReader(r io.Reader) *JSONReader {
+	return &JSONReader{r: bufio.NewReader(r), precision: "ns", now: time.Now()}
+}
+
+// SetPrecision sets the precision of timestamps written as integers.
+func (r *JSONReader) SetPrecision(precision string) { r.precision = precision }
+
+// SetDefaultTime sets the timestamp used for points without one.
+func (r *JSONReader) SetDefaultTime(t time.Time) { r.now = t }
+
+// Index returns the index of the most recently read point, starting at 1.
+func (r *JSONReader) Index() int { return r.index }
+
+// Raw returns the JSON of the most recently read point. It is only valid until
+// the next call to Read.
+func (r *JSONReader) Raw() []byte { return r.raw }
+
+// Read returns the next point. Points that cannot be converted return a
+// *JSONError, and reading may continue after one. Any other error, such as
+// invalid JSON, ends the input. Read returns io.EOF when there are no more points.
+func (r *JSONReader) Read() (models.Point, error) {
+	if r.d == nil {
+		if err := r.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.done {
+		return nil, io.EOF
+	} else if r.array && !r.d.More() {
+		if _, err := r.d.Token(); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		r.done = true
+		return nil, io.EOF
+	}
+
+	r.raw = r.raw[:0]
+	if err := r.d.Decode(&r.raw); err == io.EOF && !r.array {
+		return nil, io.EOF
+	} else if _, ok := err.(*json.SyntaxError); ok || err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	} else if err != nil {
+		return nil, err
+	}
+	r.index++
+
+	var p JSONPoint
+	if err := json.Unmarshal(r.raw, &p); err != nil {
+		return nil, &JSONError{Index: r.index, Err: err}
+	}
+	pt, err := r.point(&p)
+	if err != nil {
+		return nil, &JSONError{Index: r.index, Err: err}
+	}
+	return pt, nil
+}
+
+// start reads the opening bracket of an array of points, if there is one.
+func (r *JSONReader) start() error {
+	r.d = json.NewDecoder(r.r)
+	for {
+		b, err := r.r.Peek(1)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = r.r.ReadByte()
+			continue
+		case '[':
+			if _, err := r.d.Token(); err != nil {
+				return err
+			}
+			r.array = true
+		}
+		return nil
+	}
+}
+
+func (r *JSONReader) point(p *JSONPoint) (models.Point, error) {
+	if p.Measurement == "" {
+		return nil, fmt.Errorf("missing measurement")
+	} else if len(p.Fields) == 0 {
+		return nil, fmt.Errorf("missing fields")
+	}
+
+	fields := make(models.Fields, len(p.Fields))
+	for k, raw := range p.Fields {
+		v, err := jsonFieldValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for field %q: %v", k, err)
+		}
+		fields[k] = v
+	}
+
+	ts := r.now
+	if len(p.Time) > 0 && !bytes.Equal(p.Time, []byte("null")) {
+		var err error
+		if ts, err = jsonTime(p.Time, r.precision); err != nil {
+			return nil, err
+		}
+	}
+
+	return models.NewPoint(p.Measurement, models.NewTags(p.Tags), fields, ts)
+}
+
+// jsonFieldValue returns the value of a JSON field.
+func jsonFieldValue(raw json.RawMessage) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case float64, string, bool:
+		return v, nil
+	case map[string]interface{}:
+		var tv jsonTypedValue
+		if err := json.Unmarshal(raw, &tv); err != nil {
+			return nil, err
+		}
+		return tv.value()
+	default:
+		return nil, fmt.Errorf("expected a number, string, boolean or typed value")
+	}
+}
+
+// value returns the value converted to its type.
+func (tv *jsonTypedValue) value() (interface{}, error) {
+	// Values may be given with or without quotes.
+	s := string(tv.Value)
+	var str string
+	if err := json.Unmarshal(tv.Value, &str); err == nil {
+		s = str
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch tv.Type {
+	case "float":
+		v, err = strconv.ParseFloat(s, 64)
+	case "integer":
+		v, err = strconv.ParseInt(s, 10, 64)
+	case "unsigned":
+		v, err = strconv.ParseUint(s, 10, 64)
+	case "boolean":
+		v, err = strconv.ParseBool(s)
+	case "string":
+		v = s
+	default:
+		return nil, fmt.Errorf("unknown type %q", tv.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %s", tv.Type, tv.Value)
+	}
+	return v, nil
+}
+
+// jsonTime returns the time of a point, given as an RFC3339 string or an integer
+// in the precision.
+func jsonTime(raw json.RawMessage, precision string) (time.Time, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: expected RFC3339 format", s)
+		}
+		return t, nil
+	}
+
+	n, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %s: expected a string or integer", raw)
+	}
+	return time.Unix(0, n*models.GetPrecisionMultiplier(precision)), nil
+}
@@ -0,0 +1,106 @@
+package write
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		precision string
+		want      []string
+		wantErr   []int
+		fatal     bool
+	}{
+		{
+			name:  "single point",
+			input: `{"measurement": "cpu", "tags": {"host": "a"}, "fields": {"value": 1}, "time": 10}`,
+			want:  []string{"cpu,host=a value=1 10"},
+		},
+		{
+			name: "array of points",
+			input: `[
+  {"measurement": "cpu", "tags": {"host": "a", "dc": "x"}, "fields": {"value": 1.5, "ok": true}, "time": "1970-01-01T00:00:00.00000002Z"},
+  {"measurement": "mem", "fields": {"free": {"type": "integer", "value": "3"}, "total": {"type": "unsigned", "value": 4}, "msg": "hi"}}
+]`,
+			want: []string{
+				"cpu,dc=x,host=a ok=true,value=1.5 20",
+				`mem free=3i,msg="hi",total=4u 0`,
+			},
+		},
+		{
+			name:      "points per line with precision",
+			input:     "{\"measurement\": \"cpu\", \"fields\": {\"value\": 1}, \"time\": 1}\n{\"measurement\": \"cpu\", \"fields\": {\"value\": 2}, \"time\": 2}\n",
+			precision: "s",
+			want:      []string{"cpu value=1 1000000000", "cpu value=2 2000000000"},
+		},
+		{
+			name: "invalid points are reported",
+			input: `[
+  {"measurement": "cpu", "fields": {"value": 1}},
+  {"fields": {"value": 1}},
+  {"measurement": "cpu", "fields": {}},
+  {"measurement": "cpu", "fields": {"value": [1]}},
+  {"measurement": "cpu", "fields": {"value": {"type": "integer", "value": "x"}}},
+  {"measurement": "cpu", "fields": {"value": 1}, "time": "yesterday"},
+  "cpu value=1",
+  {"measurement": "cpu", "fields": {"value": 2}}
+]`,
+			want:    []string{"cpu value=1 0", "cpu value=2 0"},
+			wantErr: []int{2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:  "invalid JSON",
+			input: `[{"measurement": "cpu", "fields": {"value": 1}}, {"measurement": `,
+			want:  []string{"cpu value=1 0"},
+			fatal: true,
+		},
+		{
+			name:  "empty",
+			input: " \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewJSONReader(strings.NewReader(tt.input))
+			r.SetDefaultTime(time.Unix(0, 0))
+			if tt.precision != "" {
+				r.SetPrecision(tt.precision)
+			}
+
+			var got []string
+			var gotErr []int
+			var fatal bool
+			for {
+				pt, err := r.Read()
+				if err == io.EOF {
+					break
+				} else if jerr, ok := err.(*JSONError); ok {
+					gotErr = append(gotErr, jerr.Index)
+					continue
+				} else if err != nil {
+					fatal = true
+					break
+				}
+				got = append(got, pt.String())
+			}
+
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("unexpected points: %s", cmp.Diff(got, tt.want))
+			}
+			if !cmp.Equal(gotErr, tt.wantErr) {
+				t.Errorf("unexpected error indexes: %s", cmp.Diff(gotErr, tt.wantErr))
+			}
+			if fatal != tt.fatal {
+				t.Errorf("unexpected fatal error: got %v, expected %v", fatal, tt.fatal)
+			}
+		})
+	}
+}
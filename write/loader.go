@@ -0,0 +1,487 @@
+package write
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"golang.org/x/time/rate"
+)
+
+// Formats of the sources of a Loader.
+const (
+	FormatLineProtocol = "lp"
+	FormatCSV          = "csv"
+)
+
+const (
+	// DefaultMaxRetries is the default number of times a batch is retried.
+	DefaultMaxRetries = 5
+	// DefaultRetryInterval is the default time to wait before the first retry
+	// of a batch. It doubles with each retry.
+	DefaultRetryInterval = time.Second
+	// DefaultMaxRetryInterval is the default maximum time between retries.
+	DefaultMaxRetryInterval = 30 * time.Second
+)
+
+// Source is an input of a Loader.
+type Source struct {
+	// Name identifies the source in checkpoints and reports.
+	Name string
+	// Format is FormatLineProtocol or FormatCSV.
+	Format string
+	// Open returns the data of the source. Gzipped data is decompressed.
+	Open func() (io.ReadCloser, error)
+}
+
+// ReaderSource returns a Source that reads from r.
+func ReaderSource(name, format string, r io.Reader) Source {
+	return Source{
+		Name:   name,
+		Format: format,
+		Open:   func() (io.ReadCloser, error) { return ioutil.NopCloser(r), nil },
+	}
+}
+
+// FileSources returns the sources of the files at paths, in order. The files
+// in directories are added in lexical order, skipping hidden files. The format
+// of files is detected from their extension unless format is set.
+func FileSources(paths []string, format string) ([]Source, error) {
+	var sources []Source
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			sources = append(sources, fileSource(path, format))
+			continue
+		}
+
+		var files []string
+		err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if p != path && strings.HasPrefix(fi.Name(), ".") {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if fi.Mode().IsRegular() {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, p := range files {
+			sources = append(sources, fileSource(p, format))
+		}
+	}
+	return sources, nil
+}
+
+func fileSource(path, format string) Source {
+	if format == "" {
+		format = FormatLineProtocol
+		if filepath.Ext(strings.TrimSuffix(path, ".gz")) == ".csv" {
+			format = FormatCSV
+		}
+	}
+	return Source{
+		Name:   path,
+		Format: format,
+		Open:   func() (io.ReadCloser, error) { return os.Open(path) },
+	}
+}
+
+// LoadStats counts the progress of a Loader.
+type LoadStats struct {
+	Sources int   // Sources read completely.
+	Lines   int64 // Lines written.
+	Bytes   int64 // Bytes of line protocol written.
+	Batches int64 // Batches written.
+	Retries int64 // Writes of batches retried.
+	Skipped int64 // Lines that could not be parsed.
+}
+
+// Loader writes the points of many sources in batches. Batches that fail are
+// retried with increasing delays, and the position of the last batch written
+// can be recorded so that an interrupted load resumes where it stopped.
+//
+// Lines that cannot be parsed are skipped. Points are sent as line protocol
+// with nanosecond timestamps, so Service must write with that precision.
+type Loader struct {
+	Service platform.WriteService
+
+	// Precision is the precision of the integer timestamps of the sources.
+	Precision string
+	// CSVMapping maps the columns of CSV sources without annotations.
+	CSVMapping *CSVMapping
+
+	// MaxBatchBytes is the maximum size of a batch. If zero, DefaultMaxBytes
+	// is used.
+	MaxBatchBytes int
+	// MaxRetries is the number of times a failed batch is retried. If zero,
+	// DefaultMaxRetries is used; if negative, batches are not retried.
+	MaxRetries       int
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	// RateLimit is the maximum number of bytes written per second. Writes
+	// are not limited if zero.
+	RateLimit int
+
+	// CheckpointPath is the file recording the progress of the load. Sources
+	// and lines it records as written are skipped.
+	CheckpointPath string
+
+	// Skipped is called with each line that cannot be parsed.
+	Skipped func(source string, line int, err error)
+	// Progress is called after each batch is written.
+	Progress func(LoadStats)
+
+	stats      LoadStats
+	checkpoint checkpoint
+	limiter    *rate.Limiter
+}
+
+// checkpoint is the progress of a load, by the name of each source.
+type checkpoint struct {
+	// Done lists the sources that were written completely.
+	Done []string `json:"done"`
+	// Lines is the number of lines written of the other sources.
+	Lines map[string]int `json:"lines"`
+}
+
+// Load writes the points of the sources to a bucket.
+func (l *Loader) Load(ctx context.Context, orgID, bucketID platform.ID, sources []Source) (LoadStats, error) {
+	l.stats = LoadStats{}
+	if err := l.readCheckpoint(); err != nil {
+		return l.stats, err
+	}
+	if l.RateLimit > 0 {
+		burst := l.RateLimit
+		if n := l.maxBatchBytes(); n > burst {
+			burst = n
+		}
+		l.limiter = rate.NewLimiter(rate.Limit(l.RateLimit), burst)
+	}
+
+	for _, src := range sources {
+		if l.done(src.Name) {
+			l.stats.Sources++
+			continue
+		}
+		if err := l.load(ctx, orgID, bucketID, src); err != nil {
+			return l.stats, err
+		}
+		l.stats.Sources++
+	}
+	return l.stats, nil
+}
+
+// load writes the points of a source, after those already written.
+func (l *Loader) load(ctx context.Context, orgID, bucketID platform.ID, src Source) error {
+	rc, err := src.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r, err := decompress(rc)
+	if err != nil {
+		return fmt.Errorf("%s: %v", src.Name, err)
+	}
+
+	var next func() ([]byte, int, error)
+	switch src.Format {
+	case FormatLineProtocol, "":
+		next = l.lineProtocolReader(r)
+	case FormatCSV:
+		next = l.csvReader(r)
+	default:
+		return fmt.Errorf("%s: unsupported format %q", src.Name, src.Format)
+	}
+
+	var (
+		written = l.checkpoint.Lines[src.Name]
+		buf     []byte
+		lines   int
+		last    int
+	)
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+		if err := l.write(ctx, orgID, bucketID, buf); err != nil {
+			return fmt.Errorf("%s: failed to write lines %d to %d: %v", src.Name, written+1, last, err)
+		}
+		l.stats.Lines += int64(lines)
+		l.stats.Bytes += int64(len(buf))
+		l.stats.Batches++
+		written, buf, lines = last, buf[:0], 0
+		if err := l.saveCheckpoint(src.Name, written, false); err != nil {
+			return err
+		}
+		if l.Progress != nil {
+			l.Progress(l.stats)
+		}
+		return nil
+	}
+
+	skip := written
+	for {
+		line, n, err := next()
+		if err == io.EOF {
+			break
+		} else if perr, ok := err.(*lineError); ok {
+			if n > skip {
+				l.stats.Skipped++
+				if l.Skipped != nil {
+					l.Skipped(src.Name, n, perr.err)
+				}
+			}
+			continue
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", src.Name, err)
+		}
+		if n <= skip {
+			continue
+		}
+
+		if len(buf) > 0 && len(buf)+len(line) > l.maxBatchBytes() {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		buf, lines, last = append(buf, line...), lines+1, n
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return l.saveCheckpoint(src.Name, 0, true)
+}
+
+// lineError is returned by the readers of sources for lines that cannot be
+// parsed.
+type lineError struct {
+	err error
+}
+
+func (e *lineError) Error() string { return e.err.Error() }
+
+// lineProtocolReader returns a function returning each line of line protocol
+// of r, with nanosecond timestamps, and its line number.
+func (l *Loader) lineProtocolReader(r io.Reader) func() ([]byte, int, error) {
+	var (
+		br        = bufio.NewReader(r)
+		now       = time.Now()
+		precision = l.precision()
+		n         int
+		out       []byte
+	)
+	return func() ([]byte, int, error) {
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(line) == 0 && err != nil {
+				return nil, n, err
+			}
+			n++
+
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 || line[0] == '#' {
+				continue
+			}
+
+			points, perr := models.ParsePointsWithPrecision(line, now, precision)
+			if perr != nil {
+				return nil, n, &lineError{err: perr}
+			}
+			out = out[:0]
+			for _, pt := range points {
+				out = append(pt.AppendString(out), '\n')
+			}
+			return out, n, nil
+		}
+	}
+}
+
+// csvReader returns a function returning the line protocol of each record of
+// the CSV in r, and the line number of the record.
+func (l *Loader) csvReader(r io.Reader) func() ([]byte, int, error) {
+	cr := NewCSVReader(r)
+	cr.SetPrecision(l.precision())
+	if l.CSVMapping != nil {
+		cr.SetMapping(l.CSVMapping)
+	}
+
+	var out []byte
+	return func() ([]byte, int, error) {
+		pt, err := cr.Read()
+		if err == io.EOF {
+			return nil, 0, err
+		} else if cerr, ok := err.(*CSVError); ok {
+			return nil, cerr.Line, &lineError{err: cerr.Err}
+		} else if err != nil {
+			return nil, 0, err
+		}
+		out = append(pt.AppendString(out[:0]), '\n')
+		return out, cr.Line(), nil
+	}
+}
+
+// decompress returns the data of r, decompressed if it is gzipped.
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// write writes a batch, retrying failed writes that may succeed later.
+func (l *Loader) write(ctx context.Context, orgID, bucketID platform.ID, batch []byte) error {
+	if l.limiter != nil {
+		if err := l.limiter.WaitN(ctx, len(batch)); err != nil {
+			return err
+		}
+	}
+
+	var delay time.Duration
+	for retries := 0; ; retries++ {
+		err := l.Service.Write(ctx, orgID, bucketID, bytes.NewReader(batch))
+		if err == nil || ctx.Err() != nil || !retryable(err) || retries >= l.maxRetries() {
+			return err
+		}
+
+		delay = l.nextRetryInterval(delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		l.stats.Retries++
+	}
+}
+
+// retryable returns true if a failed write may succeed when retried: if the
+// server was unavailable or overloaded, rather than rejecting the request.
+func retryable(err error) bool {
+	switch platform.ErrorCode(err) {
+	case platform.EInvalid, platform.EUnprocessableEntity, platform.EEmptyValue, platform.EConflict,
+		platform.ENotFound, platform.EForbidden, platform.EUnauthorized, platform.EMethodNotAllowed:
+		return false
+	}
+	return true
+}
+
+func (l *Loader) nextRetryInterval(d time.Duration) time.Duration {
+	max := l.MaxRetryInterval
+	if max == 0 {
+		max = DefaultMaxRetryInterval
+	}
+	if d == 0 {
+		d = l.RetryInterval
+		if d == 0 {
+			d = DefaultRetryInterval
+		}
+	} else {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func (l *Loader) maxBatchBytes() int {
+	if l.MaxBatchBytes > 0 {
+		return l.MaxBatchBytes
+	}
+	return DefaultMaxBytes
+}
+
+func (l *Loader) maxRetries() int {
+	if l.MaxRetries == 0 {
+		return DefaultMaxRetries
+	}
+	return l.MaxRetries
+}
+
+func (l *Loader) precision() string {
+	if l.Precision == "" {
+		return "ns"
+	}
+	return l.Precision
+}
+
+// done returns true if the checkpoint records a source as written.
+func (l *Loader) done(name string) bool {
+	for _, d := range l.checkpoint.Done {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Loader) readCheckpoint() error {
+	l.checkpoint = checkpoint{Lines: make(map[string]int)}
+	if l.CheckpointPath == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(l.CheckpointPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &l.checkpoint); err != nil {
+		return fmt.Errorf("invalid checkpoint %s: %v", l.CheckpointPath, err)
+	}
+	if l.checkpoint.Lines == nil {
+		l.checkpoint.Lines = make(map[string]int)
+	}
+	return nil
+}
+
+// saveCheckpoint records the number of lines of a source that were written,
+// or that all of them were.
+func (l *Loader) saveCheckpoint(name string, lines int, done bool) error {
+	if done {
+		delete(l.checkpoint.Lines, name)
+		l.checkpoint.Done = append(l.checkpoint.Done, name)
+	} else {
+		l.checkpoint.Lines[name] = lines
+	}
+	if l.CheckpointPath == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(l.checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := l.CheckpointPath + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.CheckpointPath)
+}
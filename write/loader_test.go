@@ -0,0 +1,211 @@
+package write
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+)
+
+func TestLoader_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("m f=3 3\nm f=4 4\n"))
+	zw.Close()
+
+	files := map[string][]byte{
+		"data/a.lp":      []byte("# comment\nm f=1 1\n\nm f= 2\nm f=2 2"),
+		"data/b.lp.gz":   gz.Bytes(),
+		"data/c.csv":     []byte("time,f\n5,5\n6,x\n"),
+		"data/.hidden":   []byte("m f=0 0\n"),
+		"data/sub/d.txt": []byte("m f=7 7\n"),
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources, err := FileSources([]string{filepath.Join(dir, "data")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batches []string
+	svc := &mock.WriteService{
+		WriteF: func(ctx context.Context, org, bucket platform.ID, r io.Reader) error {
+			b, err := ioutil.ReadAll(r)
+			batches = append(batches, string(b))
+			return err
+		},
+	}
+
+	var skipped []string
+	l := &Loader{
+		Service:       svc,
+		Precision:     "s",
+		CSVMapping:    &CSVMapping{Measurement: "m", TimeColumn: "time", FieldColumns: map[string]string{"f": "double"}},
+		MaxBatchBytes: 40,
+		Skipped: func(source string, line int, err error) {
+			skipped = append(skipped, fmt.Sprintf("%s:%d", filepath.Base(source), line))
+		},
+	}
+	stats, err := l.Load(context.Background(), 1, 2, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{
+		"m f=1 1000000000\nm f=2 2000000000\n",
+		"m f=3 3000000000\nm f=4 4000000000\n",
+		"m f=5 5000000000\n",
+		"m f=7 7000000000\n",
+	}
+	if !cmp.Equal(batches, exp) {
+		t.Fatalf("unexpected batches: %s", cmp.Diff(batches, exp))
+	}
+	if exp := []string{"a.lp:4", "c.csv:3"}; !cmp.Equal(skipped, exp) {
+		t.Fatalf("unexpected skipped lines: %s", cmp.Diff(skipped, exp))
+	}
+	if exp := (LoadStats{Sources: 4, Lines: 6, Bytes: int64(len(strings.Join(exp, ""))), Batches: 4, Skipped: 2}); stats != exp {
+		t.Fatalf("unexpected stats: got %+v, exp %+v", stats, exp)
+	}
+}
+
+func TestLoader_Retry(t *testing.T) {
+	tests := []struct {
+		name    string
+		errs    []error
+		writes  int
+		wantErr bool
+	}{
+		{
+			name:   "unavailable",
+			errs:   []error{&platform.Error{Code: platform.EUnavailable}, errors.New("connection refused")},
+			writes: 3,
+		},
+		{
+			name:    "too many retries",
+			errs:    []error{&platform.Error{Code: platform.EInternal}, &platform.Error{Code: platform.EInternal}, &platform.Error{Code: platform.EInternal}},
+			writes:  3,
+			wantErr: true,
+		},
+		{
+			name:    "rejected",
+			errs:    []error{&platform.Error{Code: platform.EInvalid}},
+			writes:  1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var writes int
+			svc := &mock.WriteService{
+				WriteF: func(ctx context.Context, org, bucket platform.ID, r io.Reader) error {
+					writes++
+					if writes <= len(tt.errs) {
+						return tt.errs[writes-1]
+					}
+					return nil
+				},
+			}
+
+			l := &Loader{
+				Service:       svc,
+				MaxRetries:    2,
+				RetryInterval: time.Millisecond,
+			}
+			stats, err := l.Load(context.Background(), 1, 2, []Source{ReaderSource("-", FormatLineProtocol, strings.NewReader("m f=1 1\n"))})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if writes != tt.writes {
+				t.Fatalf("unexpected number of writes: got %d, exp %d", writes, tt.writes)
+			}
+			if exp := int64(tt.writes - 1); stats.Retries != exp {
+				t.Fatalf("unexpected retries: got %d, exp %d", stats.Retries, exp)
+			}
+		})
+	}
+}
+
+func TestLoader_Checkpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sources := func() []Source {
+		return []Source{
+			ReaderSource("a", FormatLineProtocol, strings.NewReader("m f=1 1\n")),
+			ReaderSource("b", FormatLineProtocol, strings.NewReader("m f=2 2\nm f=3 3\nm f=4 4\n")),
+		}
+	}
+
+	var (
+		lines []string
+		fail  bool
+	)
+	svc := &mock.WriteService{
+		WriteF: func(ctx context.Context, org, bucket platform.ID, r io.Reader) error {
+			b, _ := ioutil.ReadAll(r)
+			if fail && strings.Contains(string(b), "f=3") {
+				return &platform.Error{Code: platform.EInvalid, Msg: "rejected"}
+			}
+			lines = append(lines, strings.Fields(string(b))[1])
+			return nil
+		},
+	}
+
+	l := &Loader{
+		Service:        svc,
+		MaxBatchBytes:  1,
+		CheckpointPath: filepath.Join(dir, "checkpoint"),
+	}
+
+	// The load stops at the batch that fails.
+	fail = true
+	if _, err := l.Load(context.Background(), 1, 2, sources()); err == nil {
+		t.Fatal("expected error")
+	}
+	if exp := []string{"f=1", "f=2"}; !cmp.Equal(lines, exp) {
+		t.Fatalf("unexpected lines written: %s", cmp.Diff(lines, exp))
+	}
+
+	// Resuming skips what was written.
+	fail, lines = false, nil
+	stats, err := l.Load(context.Background(), 1, 2, sources())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"f=3", "f=4"}; !cmp.Equal(lines, exp) {
+		t.Fatalf("unexpected lines written after resuming: %s", cmp.Diff(lines, exp))
+	}
+	if stats.Sources != 2 || stats.Lines != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
@@ -2,6 +2,7 @@ package zap
 
 import (
 	"context"
+	"time"
 
 	platform "github.com/influxdata/influxdb"
 	"go.uber.org/zap"
@@ -80,3 +81,25 @@ func (s *AuthorizationService) SetAuthorizationStatus(ctx context.Context, id pl
 
 	return s.AuthorizationService.SetAuthorizationStatus(ctx, id, status)
 }
+
+// UpdateAuthorization updates an authorization, and logs any errors.
+func (s *AuthorizationService) UpdateAuthorization(ctx context.Context, id platform.ID, upd *platform.AuthorizationUpdate) (a *platform.Authorization, err error) {
+	defer func() {
+		if err != nil {
+			s.Logger.Info("error updating authorization", zap.Error(err))
+		}
+	}()
+
+	return s.AuthorizationService.UpdateAuthorization(ctx, id, upd)
+}
+
+// RotateAuthorization rotates the token of an authorization, and logs any errors.
+func (s *AuthorizationService) RotateAuthorization(ctx context.Context, id platform.ID, grace time.Duration) (a *platform.Authorization, err error) {
+	defer func() {
+		if err != nil {
+			s.Logger.Info("error rotating authorization", zap.Error(err))
+		}
+	}()
+
+	return s.AuthorizationService.RotateAuthorization(ctx, id, grace)
+}